@@ -0,0 +1,75 @@
+// Command consumer reads user events from Kafka and processes them in spans
+// that continue the API's traces
+package main
+
+import (
+	"context"
+	"log"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"arquivolivre.com.br/otel/internal/config"
+	"arquivolivre.com.br/otel/internal/events"
+	"arquivolivre.com.br/otel/internal/logging"
+)
+
+func main() {
+	logging.InitGlobalLogger()
+
+	cfg, err := config.Load()
+	if err != nil {
+		log.Fatalf("Failed to load configuration: %v", err)
+	}
+	if len(cfg.Kafka.Brokers) == 0 {
+		log.Fatal("KAFKA_BROKERS must be set")
+	}
+
+	telemetryCfg := config.GetTelemetryConfig()
+	if os.Getenv("OTEL_SERVICE_NAME") == "" {
+		telemetryCfg.ServiceName = "otel-example-consumer"
+	}
+	telemetryProvider, err := config.InitTelemetry(telemetryCfg)
+	if err != nil {
+		log.Fatalf("Failed to initialize telemetry: %v", err)
+	}
+	defer func() {
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+		if err := telemetryProvider.Shutdown(shutdownCtx); err != nil {
+			log.Printf("Error shutting down telemetry: %v", err)
+		}
+	}()
+	if telemetryCfg.EnableLogging && telemetryProvider.LoggerProvider != nil {
+		logging.SetupOtelBridge(telemetryProvider.LoggerProvider)
+	}
+
+	consumer, err := events.NewKafkaConsumer(cfg.Kafka.Brokers, cfg.Kafka.UserEventsTopic, cfg.Kafka.ConsumerGroup, handleUserEvent)
+	if err != nil {
+		log.Fatalf("Failed to create consumer: %v", err)
+	}
+	defer func() {
+		if err := consumer.Close(); err != nil {
+			log.Printf("Error closing consumer: %v", err)
+		}
+	}()
+
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	log.Printf("Consuming %s as %s", cfg.Kafka.UserEventsTopic, cfg.Kafka.ConsumerGroup)
+	if err := consumer.Run(ctx); err != nil {
+		log.Printf("Consumer stopped: %v", err)
+	}
+}
+
+func handleUserEvent(ctx context.Context, event events.UserEvent) error {
+	logging.LogInfo(ctx, "Processed user event", map[string]interface{}{
+		"event_type":  string(event.Type),
+		"user_id":     event.UserID,
+		"actor":       event.Actor,
+		"occurred_at": event.OccurredAt,
+	})
+	return nil
+}
@@ -0,0 +1,88 @@
+// Command enricher is a companion service called by the API so the example
+// produces traces that span two services
+package main
+
+import (
+	"context"
+	"errors"
+	"log"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"arquivolivre.com.br/otel/internal/config"
+	"arquivolivre.com.br/otel/internal/enrichment"
+	"arquivolivre.com.br/otel/internal/logging"
+	"arquivolivre.com.br/otel/internal/middleware"
+
+	"github.com/gin-gonic/gin"
+	"go.opentelemetry.io/contrib/instrumentation/github.com/gin-gonic/gin/otelgin"
+)
+
+const serviceName = "otel-example-enricher"
+
+func main() {
+	logging.InitGlobalLogger()
+
+	telemetryCfg := config.GetTelemetryConfig()
+	if os.Getenv("OTEL_SERVICE_NAME") == "" {
+		telemetryCfg.ServiceName = serviceName
+	}
+	telemetryProvider, err := config.InitTelemetry(telemetryCfg)
+	if err != nil {
+		log.Fatalf("Failed to initialize telemetry: %v", err)
+	}
+	defer func() {
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+		if err := telemetryProvider.Shutdown(shutdownCtx); err != nil {
+			log.Printf("Error shutting down telemetry: %v", err)
+		}
+	}()
+	if telemetryCfg.EnableLogging && telemetryProvider.LoggerProvider != nil {
+		logging.SetupOtelBridge(telemetryProvider.LoggerProvider)
+	}
+
+	gin.SetMode(gin.ReleaseMode)
+	router := gin.New()
+	router.Use(middleware.RequestID())
+	router.Use(logging.GetLogger().Middleware())
+	router.Use(middleware.Recovery())
+	router.Use(otelgin.Middleware(telemetryCfg.ServiceName))
+	router.GET("/enrich", enrichment.Handler)
+	router.GET("/health", func(c *gin.Context) { c.Status(http.StatusOK) })
+
+	addr := ":" + getEnv("ENRICHER_PORT", "8081")
+	server := &http.Server{
+		Addr:         addr,
+		Handler:      router,
+		ReadTimeout:  5 * time.Second,
+		WriteTimeout: 5 * time.Second,
+	}
+
+	go func() {
+		log.Printf("Starting enricher on %s", addr)
+		if err := server.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			log.Fatalf("Failed to start enricher: %v", err)
+		}
+	}()
+
+	quit := make(chan os.Signal, 1)
+	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
+	<-quit
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	if err := server.Shutdown(ctx); err != nil {
+		log.Printf("Enricher forced to shutdown: %v", err)
+	}
+}
+
+func getEnv(key, defaultValue string) string {
+	if value := os.Getenv(key); value != "" {
+		return value
+	}
+	return defaultValue
+}
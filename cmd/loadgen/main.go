@@ -0,0 +1,353 @@
+// Command loadgen drives realistic traffic against the API through the
+// instrumented client so the dashboards have something to show
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"log"
+	"math/rand/v2"
+	"os"
+	"os/signal"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"syscall"
+	"time"
+
+	"arquivolivre.com.br/otel/internal/config"
+	"arquivolivre.com.br/otel/internal/tenant"
+	"arquivolivre.com.br/otel/pkg/client"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const (
+	serviceName = "otel-example-loadgen"
+	// missingUserID is used to inject 404s
+	missingUserID = 2147483647
+)
+
+type options struct {
+	target    string
+	rps       float64
+	duration  time.Duration
+	workers   int
+	mix       string
+	errorRate float64
+	tenants   string
+}
+
+func main() {
+	opts := parseFlags()
+	mix, err := parseMix(opts.mix)
+	if err != nil {
+		log.Fatalf("Invalid mix: %v", err)
+	}
+	if opts.rps <= 0 {
+		log.Fatalf("Invalid rps %v: must be positive", opts.rps)
+	}
+	if opts.errorRate < 0 || opts.errorRate > 1 {
+		log.Fatalf("Invalid error rate %v: must be between 0 and 1", opts.errorRate)
+	}
+	tenants, err := parseTenants(opts.tenants)
+	if err != nil {
+		log.Fatalf("Invalid tenants: %v", err)
+	}
+
+	telemetryCfg := config.GetTelemetryConfig()
+	if os.Getenv("OTEL_SERVICE_NAME") == "" {
+		telemetryCfg.ServiceName = serviceName
+	}
+	telemetryProvider, err := config.InitTelemetry(telemetryCfg)
+	if err != nil {
+		log.Fatalf("Failed to initialize telemetry: %v", err)
+	}
+	defer func() {
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+		if err := telemetryProvider.Shutdown(shutdownCtx); err != nil {
+			log.Printf("Error shutting down telemetry: %v", err)
+		}
+	}()
+
+	// Retries would hide the injected errors from the dashboards
+	apiClient, err := client.New(opts.target,
+		client.WithRetries(0, 0),
+		client.WithUserAgent(serviceName),
+	)
+	if err != nil {
+		log.Fatalf("Failed to create client: %v", err)
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+	if opts.duration > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, opts.duration)
+		defer cancel()
+	}
+
+	gen := &generator{
+		client:    apiClient,
+		tracer:    otel.Tracer(serviceName),
+		mix:       mix,
+		errorRate: opts.errorRate,
+		tenants:   tenants,
+		stats:     newStats(),
+	}
+	log.Printf("Generating %.1f req/s against %s (mix %s, error rate %.2f)", opts.rps, opts.target, opts.mix, opts.errorRate)
+	started := time.Now()
+	gen.run(ctx, opts.rps, opts.workers)
+	gen.stats.print(time.Since(started))
+}
+
+func parseFlags() options {
+	var opts options
+	flag.StringVar(&opts.target, "target", getEnv("LOADGEN_TARGET", "http://localhost:8080"), "base URL of the API")
+	flag.Float64Var(&opts.rps, "rps", getEnvAsFloat("LOADGEN_RPS", 10), "requests per second")
+	flag.DurationVar(&opts.duration, "duration", getEnvAsDuration("LOADGEN_DURATION", time.Minute), "how long to run; 0 runs until interrupted")
+	flag.IntVar(&opts.workers, "workers", getEnvAsInt("LOADGEN_WORKERS", 8), "maximum concurrent requests")
+	flag.StringVar(&opts.mix, "mix", getEnv("LOADGEN_MIX", "list=35,get=35,create=15,update=10,delete=5"), "weighted endpoint mix")
+	flag.Float64Var(&opts.errorRate, "error-rate", getEnvAsFloat("LOADGEN_ERROR_RATE", 0.05), "fraction of requests sent invalid on purpose")
+	flag.StringVar(&opts.tenants, "tenants", getEnv("LOADGEN_TENANTS", "acme,globex,initech"), "comma-separated tenant IDs spread over the requests; empty sends none")
+	flag.Parse()
+	return opts
+}
+
+// generator issues operations at a fixed rate
+type generator struct {
+	client    *client.Client
+	tracer    trace.Tracer
+	mix       *endpointMix
+	errorRate float64
+	// tenants are picked at random for each request and sent as baggage
+	tenants []string
+	stats   *stats
+
+	mu  sync.Mutex
+	ids []int
+	seq atomic.Int64
+}
+
+func (g *generator) run(ctx context.Context, rps float64, workers int) {
+	if workers < 1 {
+		workers = 1
+	}
+	ticker := time.NewTicker(time.Duration(float64(time.Second) / rps))
+	defer ticker.Stop()
+
+	sem := make(chan struct{}, workers)
+	var wg sync.WaitGroup
+	for {
+		select {
+		case <-ctx.Done():
+			wg.Wait()
+			return
+		case <-ticker.C:
+		}
+		select {
+		case sem <- struct{}{}:
+		default:
+			// All workers are busy; drop the tick rather than queueing
+			g.stats.dropped.Add(1)
+			continue
+		}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			defer func() { <-sem }()
+			op := g.mix.pick(rand.IntN)
+			inject := rand.Float64() < g.errorRate
+			// Let in-flight requests finish after the deadline
+			reqCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), 10*time.Second)
+			defer cancel()
+			if len(g.tenants) > 0 {
+				reqCtx, _ = tenant.WithID(reqCtx, g.tenants[rand.IntN(len(g.tenants))])
+			}
+			g.stats.record(op, g.operation(reqCtx, op, inject))
+		}()
+	}
+}
+
+// operation runs op in a "loadgen <op>" span, so the requests of one
+// operation, like the read and write of an update, share a trace
+func (g *generator) operation(ctx context.Context, op string, inject bool) error {
+	ctx, span := g.tracer.Start(ctx, "loadgen "+op, trace.WithAttributes(
+		attribute.String("loadgen.operation", op),
+		attribute.Bool("loadgen.error_injected", inject),
+	))
+	defer span.End()
+	span.SetAttributes(tenant.Attributes(ctx)...)
+
+	err := g.do(ctx, op, inject)
+	if err != nil && !errors.Is(err, context.Canceled) {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	return err
+}
+
+func (g *generator) do(ctx context.Context, op string, inject bool) error {
+	switch op {
+	case opList:
+		// The API clamps bad paging, so list requests are never injected
+		_, err := g.client.ListUsers(ctx, client.ListOptions{Page: 1 + rand.IntN(3), Limit: 10})
+		return err
+	case opGet:
+		_, err := g.client.GetUser(ctx, g.targetID(inject))
+		return err
+	case opCreate:
+		n := g.seq.Add(1)
+		req := client.CreateUserRequest{
+			Name:  fmt.Sprintf("Load User %d", n),
+			Email: fmt.Sprintf("loadgen-%d-%d@example.com", time.Now().UnixNano(), n),
+		}
+		if inject {
+			req.Email = "not-an-email"
+		}
+		user, err := g.client.CreateUser(ctx, req)
+		if err == nil {
+			g.remember(user.ID)
+		}
+		return err
+	case opUpdate:
+		// Concurrent workers updating the same user produce version conflicts
+		user, err := g.client.GetUser(ctx, g.targetID(inject))
+		if err != nil {
+			return err
+		}
+		name := fmt.Sprintf("Load User %d", g.seq.Add(1))
+		_, err = g.client.UpdateUser(ctx, user.ID, client.UpdateUserRequest{Name: &name, Version: user.Version})
+		return err
+	case opDelete:
+		id, ok := g.take()
+		if inject || !ok {
+			id = missingUserID
+		}
+		return g.client.DeleteUser(ctx, id)
+	case opHealth:
+		return g.client.Health(ctx)
+	}
+	return fmt.Errorf("unknown operation %q", op)
+}
+
+// targetID returns a user created by this run, or a missing one to inject a 404
+func (g *generator) targetID(inject bool) int {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if inject || len(g.ids) == 0 {
+		return missingUserID
+	}
+	return g.ids[rand.IntN(len(g.ids))]
+}
+
+func (g *generator) remember(id int) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.ids = append(g.ids, id)
+}
+
+func (g *generator) take() (int, bool) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if len(g.ids) == 0 {
+		return 0, false
+	}
+	i := rand.IntN(len(g.ids))
+	id := g.ids[i]
+	g.ids[i] = g.ids[len(g.ids)-1]
+	g.ids = g.ids[:len(g.ids)-1]
+	return id, true
+}
+
+// stats counts outcomes per operation
+type stats struct {
+	mu      sync.Mutex
+	ok      map[string]int
+	failed  map[string]int
+	dropped atomic.Int64
+}
+
+func newStats() *stats {
+	return &stats{ok: make(map[string]int), failed: make(map[string]int)}
+}
+
+func (s *stats) record(op string, err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err != nil && !errors.Is(err, context.Canceled) {
+		s.failed[op]++
+		return
+	}
+	s.ok[op]++
+}
+
+func (s *stats) print(elapsed time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	ops := make([]string, 0, len(knownOps))
+	for op := range knownOps {
+		if s.ok[op]+s.failed[op] > 0 {
+			ops = append(ops, op)
+		}
+	}
+	sort.Strings(ops)
+	total := 0
+	for _, op := range ops {
+		log.Printf("%-7s ok=%d failed=%d", op, s.ok[op], s.failed[op])
+		total += s.ok[op] + s.failed[op]
+	}
+	log.Printf("Sent %d requests in %s (%.1f req/s), dropped %d ticks",
+		total, elapsed.Round(time.Millisecond), float64(total)/elapsed.Seconds(), s.dropped.Load())
+}
+
+// parseTenants splits a comma-separated list of tenant IDs
+func parseTenants(list string) ([]string, error) {
+	var tenants []string
+	for _, id := range strings.Split(list, ",") {
+		if id = strings.TrimSpace(id); id == "" {
+			continue
+		}
+		if !tenant.Valid(id) {
+			return nil, fmt.Errorf("invalid tenant ID %q", id)
+		}
+		tenants = append(tenants, id)
+	}
+	return tenants, nil
+}
+
+func getEnv(key, defaultValue string) string {
+	if value := os.Getenv(key); value != "" {
+		return value
+	}
+	return defaultValue
+}
+
+func getEnvAsInt(key string, defaultValue int) int {
+	if value, err := strconv.Atoi(os.Getenv(key)); err == nil {
+		return value
+	}
+	return defaultValue
+}
+
+func getEnvAsFloat(key string, defaultValue float64) float64 {
+	if value, err := strconv.ParseFloat(os.Getenv(key), 64); err == nil {
+		return value
+	}
+	return defaultValue
+}
+
+func getEnvAsDuration(key string, defaultValue time.Duration) time.Duration {
+	if value, err := time.ParseDuration(os.Getenv(key)); err == nil {
+		return value
+	}
+	return defaultValue
+}
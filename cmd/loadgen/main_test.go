@@ -0,0 +1,51 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"arquivolivre.com.br/otel/internal/tenant"
+	"arquivolivre.com.br/otel/pkg/client"
+	"arquivolivre.com.br/otel/pkg/oteltest"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+)
+
+func TestOperationTracesItsRequests(t *testing.T) {
+	rec := oteltest.Install(t)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/health" {
+			w.WriteHeader(http.StatusOK)
+			return
+		}
+		http.NotFound(w, r)
+	}))
+	defer server.Close()
+
+	apiClient, err := client.New(server.URL, client.WithRetries(0, 0))
+	require.NoError(t, err)
+	g := &generator{client: apiClient, tracer: otel.Tracer(serviceName), stats: newStats()}
+
+	ctx, err := tenant.WithID(context.Background(), "acme")
+	require.NoError(t, err)
+	require.NoError(t, g.operation(ctx, opHealth, false))
+	rec.AssertSpan(t, "loadgen health",
+		attribute.String("loadgen.operation", opHealth),
+		attribute.Bool("loadgen.error_injected", false),
+		attribute.String("tenant.id", "acme"),
+	)
+	rec.AssertChild(t, "client.Health", "loadgen health")
+
+	// Without users of its own, a get targets a missing user
+	require.Error(t, g.operation(context.Background(), opGet, true))
+	span := rec.AssertSpan(t, "loadgen get", attribute.Bool("loadgen.error_injected", true))
+	if assert.NotNil(t, span) {
+		assert.Equal(t, codes.Error, span.Status().Code)
+	}
+}
@@ -0,0 +1,81 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Operations the load generator knows how to perform
+const (
+	opList   = "list"
+	opGet    = "get"
+	opCreate = "create"
+	opUpdate = "update"
+	opDelete = "delete"
+	opHealth = "health"
+)
+
+var knownOps = map[string]bool{
+	opList: true, opGet: true, opCreate: true, opUpdate: true, opDelete: true, opHealth: true,
+}
+
+type weightedOp struct {
+	name   string
+	weight int
+}
+
+// endpointMix picks operations proportionally to their weights
+type endpointMix struct {
+	ops   []weightedOp
+	total int
+}
+
+// parseMix parses a mix such as "list=40,get=30,create=20,delete=10"
+func parseMix(spec string) (*endpointMix, error) {
+	m := &endpointMix{}
+	seen := make(map[string]bool)
+	for _, part := range strings.Split(spec, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		name, rawWeight, ok := strings.Cut(part, "=")
+		name = strings.TrimSpace(name)
+		if !ok {
+			return nil, fmt.Errorf("invalid mix entry %q: expected name=weight", part)
+		}
+		if !knownOps[name] {
+			return nil, fmt.Errorf("unknown operation %q in mix", name)
+		}
+		if seen[name] {
+			return nil, fmt.Errorf("operation %q listed twice in mix", name)
+		}
+		weight, err := strconv.Atoi(strings.TrimSpace(rawWeight))
+		if err != nil || weight < 0 {
+			return nil, fmt.Errorf("invalid weight for %q: %q", name, rawWeight)
+		}
+		seen[name] = true
+		if weight == 0 {
+			continue
+		}
+		m.ops = append(m.ops, weightedOp{name: name, weight: weight})
+		m.total += weight
+	}
+	if m.total == 0 {
+		return nil, fmt.Errorf("mix %q has no operation with a positive weight", spec)
+	}
+	return m, nil
+}
+
+// pick returns an operation name chosen by weight; intN is rand.IntN outside tests
+func (m *endpointMix) pick(intN func(int) int) string {
+	n := intN(m.total)
+	for _, op := range m.ops {
+		if n < op.weight {
+			return op.name
+		}
+		n -= op.weight
+	}
+	return m.ops[len(m.ops)-1].name
+}
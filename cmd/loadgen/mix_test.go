@@ -0,0 +1,31 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseMix(t *testing.T) {
+	m, err := parseMix("list=3, get=1,delete=0")
+	require.NoError(t, err)
+	assert.Equal(t, 4, m.total)
+	assert.Len(t, m.ops, 2)
+
+	for _, spec := range []string{"", "list", "list=x", "list=-1", "bogus=1", "list=1,list=2", "get=0"} {
+		_, err := parseMix(spec)
+		assert.Error(t, err, spec)
+	}
+}
+
+func TestEndpointMixPick(t *testing.T) {
+	m, err := parseMix("list=3,get=1")
+	require.NoError(t, err)
+
+	picked := map[string]int{}
+	for n := 0; n < m.total; n++ {
+		picked[m.pick(func(int) int { return n })]++
+	}
+	assert.Equal(t, map[string]int{"list": 3, "get": 1}, picked)
+}
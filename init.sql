@@ -9,8 +9,93 @@ CREATE TABLE IF NOT EXISTS users (
     name VARCHAR(100) NOT NULL,
     email VARCHAR(100) UNIQUE NOT NULL,
     bio TEXT,
+    -- Incremented by every update, for optimistic concurrency control
+    version INT NOT NULL DEFAULT 1,
+    created_by VARCHAR(255) NOT NULL DEFAULT 'system',
+    updated_by VARCHAR(255) NOT NULL DEFAULT 'system',
     created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
-    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+    -- Words of the name, email and bio for GET /api/users/search
+    FULLTEXT INDEX ft_users_search (name, email, bio)
+);
+
+-- Posts written by users
+CREATE TABLE IF NOT EXISTS posts (
+    id INT AUTO_INCREMENT PRIMARY KEY,
+    user_id INT NOT NULL,
+    title VARCHAR(200) NOT NULL,
+    body TEXT NOT NULL,
+    created_by VARCHAR(255) NOT NULL DEFAULT 'system',
+    updated_by VARCHAR(255) NOT NULL DEFAULT 'system',
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+    INDEX idx_posts_user_id (user_id),
+    CONSTRAINT fk_posts_user FOREIGN KEY (user_id) REFERENCES users (id) ON DELETE CASCADE
+);
+
+-- User events waiting to be relayed to Kafka
+CREATE TABLE IF NOT EXISTS outbox (
+    id BIGINT AUTO_INCREMENT PRIMARY KEY,
+    event_type VARCHAR(64) NOT NULL,
+    payload JSON NOT NULL,
+    headers JSON NOT NULL,
+    attempts INT NOT NULL DEFAULT 0,
+    last_error VARCHAR(1024) NULL,
+    created_at TIMESTAMP(3) NOT NULL DEFAULT CURRENT_TIMESTAMP(3),
+    sent_at TIMESTAMP(3) NULL,
+    INDEX idx_outbox_pending (sent_at, id)
+);
+
+-- Endpoints receiving signed user events; an empty events list subscribes
+-- to every event
+CREATE TABLE IF NOT EXISTS webhooks (
+    id INT AUTO_INCREMENT PRIMARY KEY,
+    url VARCHAR(2048) NOT NULL,
+    secret VARCHAR(255) NOT NULL,
+    events VARCHAR(255) NOT NULL DEFAULT '',
+    active BOOLEAN NOT NULL DEFAULT TRUE,
+    created_at TIMESTAMP(3) NOT NULL DEFAULT CURRENT_TIMESTAMP(3),
+    updated_at TIMESTAMP(3) NOT NULL DEFAULT CURRENT_TIMESTAMP(3) ON UPDATE CURRENT_TIMESTAMP(3)
+);
+
+-- Who changed which user or post, with the fields that changed and the
+-- trace of the request
+CREATE TABLE IF NOT EXISTS audit_log (
+    id BIGINT AUTO_INCREMENT PRIMARY KEY,
+    actor VARCHAR(255) NOT NULL,
+    client_id VARCHAR(255) NOT NULL DEFAULT '',
+    action VARCHAR(16) NOT NULL,
+    entity VARCHAR(32) NOT NULL,
+    entity_id INT NOT NULL,
+    changes JSON NOT NULL,
+    trace_id CHAR(32) NOT NULL DEFAULT '',
+    occurred_at TIMESTAMP(3) NOT NULL DEFAULT CURRENT_TIMESTAMP(3),
+    INDEX idx_audit_log_entity (entity, entity_id),
+    INDEX idx_audit_log_actor (actor)
+);
+
+-- Named roles and the users they are assigned to; names follow the
+-- resource:action format of the RBAC roles
+CREATE TABLE IF NOT EXISTS roles (
+    id INT AUTO_INCREMENT PRIMARY KEY,
+    name VARCHAR(100) NOT NULL UNIQUE,
+    description VARCHAR(255) NOT NULL DEFAULT '',
+    created_at TIMESTAMP(3) NOT NULL DEFAULT CURRENT_TIMESTAMP(3)
+);
+
+CREATE TABLE IF NOT EXISTS user_roles (
+    user_id INT NOT NULL,
+    role_id INT NOT NULL,
+    -- Email of the user when the role was assigned, which RolesOf matches
+    -- against the principal's subject
+    subject VARCHAR(255) NOT NULL DEFAULT '',
+    assigned_by VARCHAR(255) NOT NULL DEFAULT 'system',
+    assigned_at TIMESTAMP(3) NOT NULL DEFAULT CURRENT_TIMESTAMP(3),
+    PRIMARY KEY (user_id, role_id),
+    INDEX idx_user_roles_role_id (role_id),
+    INDEX idx_user_roles_subject (subject),
+    CONSTRAINT fk_user_roles_user FOREIGN KEY (user_id) REFERENCES users (id) ON DELETE CASCADE,
+    CONSTRAINT fk_user_roles_role FOREIGN KEY (role_id) REFERENCES roles (id) ON DELETE CASCADE
 );
 
 -- Insert some sample data
@@ -19,3 +104,6 @@ INSERT INTO users (name, email, bio) VALUES
     ('Jane Smith', 'jane@example.com', 'I am a salesperson'),
     ('Bob Johnson', 'bob@example.com', 'I am a manager');
 
+INSERT INTO posts (user_id, title, body) VALUES
+    (1, 'Hello, OpenTelemetry', 'Tracing every request end to end.'),
+    (2, 'Sales update', 'Q3 numbers are looking good.');
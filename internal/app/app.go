@@ -0,0 +1,689 @@
+// Package app wires the API's dependencies and runs the HTTP and gRPC servers
+package app
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"arquivolivre.com.br/otel/internal/audit"
+	"arquivolivre.com.br/otel/internal/auth"
+	"arquivolivre.com.br/otel/internal/avatars"
+	"arquivolivre.com.br/otel/internal/chaos"
+	"arquivolivre.com.br/otel/internal/client"
+	"arquivolivre.com.br/otel/internal/config"
+	"arquivolivre.com.br/otel/internal/database"
+	"arquivolivre.com.br/otel/internal/enrichment"
+	"arquivolivre.com.br/otel/internal/events"
+	"arquivolivre.com.br/otel/internal/features"
+	"arquivolivre.com.br/otel/internal/grpcapi"
+	"arquivolivre.com.br/otel/internal/handlers"
+	"arquivolivre.com.br/otel/internal/health"
+	"arquivolivre.com.br/otel/internal/jobs"
+	"arquivolivre.com.br/otel/internal/livemetrics"
+	"arquivolivre.com.br/otel/internal/logging"
+	"arquivolivre.com.br/otel/internal/middleware"
+	"arquivolivre.com.br/otel/internal/models"
+	"arquivolivre.com.br/otel/internal/notifications"
+	"arquivolivre.com.br/otel/internal/operations"
+	"arquivolivre.com.br/otel/internal/outbox"
+	"arquivolivre.com.br/otel/internal/prober"
+	"arquivolivre.com.br/otel/internal/repository"
+	"arquivolivre.com.br/otel/internal/roles"
+	"arquivolivre.com.br/otel/internal/scheduler"
+	"arquivolivre.com.br/otel/internal/seed"
+	"arquivolivre.com.br/otel/internal/validation"
+	"arquivolivre.com.br/otel/internal/webhooks"
+	"arquivolivre.com.br/otel/pkg/apperrors"
+	"arquivolivre.com.br/otel/pkg/cache"
+	apiclient "arquivolivre.com.br/otel/pkg/client"
+
+	"github.com/gin-gonic/gin"
+	"github.com/redis/go-redis/extra/redisotel/v9"
+	"github.com/redis/go-redis/v9"
+	otellog "go.opentelemetry.io/otel/log"
+	"google.golang.org/grpc"
+)
+
+// Serve runs the API until ctx is done, then shuts the server and its
+// background workers down gracefully
+func Serve(ctx context.Context, cfg *config.Config, telemetryProvider *config.TelemetryProvider) error {
+	if cfg.App.Environment == "production" {
+		gin.SetMode(gin.ReleaseMode)
+	}
+
+	if len(cfg.App.DisallowedEmailDomains) > 0 {
+		validation.SetDisallowedEmailDomains(cfg.App.DisallowedEmailDomains)
+	}
+
+	settings, err := config.LoadReloadable(os.Getenv)
+	if err != nil {
+		return fmt.Errorf("invalid configuration: %w", err)
+	}
+	rateLimits := handlers.RateLimits{
+		Read:  middleware.NewRateLimiter("read", settings.RateLimitReadRPS),
+		Write: middleware.NewRateLimiter("write", settings.RateLimitWriteRPS),
+	}
+	applySettings := newSettingsApplier(telemetryProvider, rateLimits)
+	if err := applySettings(settings); err != nil {
+		return fmt.Errorf("failed to apply configuration: %w", err)
+	}
+	if cfg.App.HotReload {
+		watcher, err := config.NewWatcher(cfg.App.ConfigFile, settings)
+		if err != nil {
+			return fmt.Errorf("failed to create config watcher: %w", err)
+		}
+		watcher.OnChange(applySettings)
+		watchCtx, stopWatching := context.WithCancel(ctx)
+		defer stopWatching()
+		go func() {
+			if err := watcher.Run(watchCtx); err != nil {
+				log.Printf("Config hot reload stopped: %v", err)
+			}
+		}()
+	}
+
+	flagProvider, err := features.NewProvider(cfg.App.FeatureFlagsFile, os.Environ())
+	if err != nil {
+		return fmt.Errorf("failed to load feature flags: %w", err)
+	}
+	if err := features.Init(flagProvider); err != nil {
+		return fmt.Errorf("failed to initialize feature flags: %w", err)
+	}
+
+	// Deferred calls stop the components in reverse order of start, sharing
+	// one SHUTDOWN_TIMEOUT budget
+	budget := &shutdownBudget{timeout: cfg.Server.ShutdownTimeout}
+
+	// The monitor outlives ctx so pool metrics cover the HTTP drain; it is
+	// stopped once the server has shut down
+	monitorCtx, cancelMonitor := context.WithCancel(context.WithoutCancel(ctx))
+	defer cancelMonitor()
+
+	// db stays nil with DB_DRIVER=memory; the components that need MySQL
+	// are rejected by the config validation or skipped
+	var db *database.DB
+	var memoryStore *repository.InMemoryUserStore
+	if cfg.Database.Driver == config.DriverMemory {
+		memoryStore = repository.NewInMemoryUserStore()
+		log.Println("Keeping users and posts in memory; they are lost on restart")
+	} else {
+		db, err = database.NewConnection(cfg)
+		if err != nil {
+			return fmt.Errorf("failed to connect to database: %w", err)
+		}
+		defer func() {
+			if err := db.Close(); err != nil {
+				log.Printf("Error closing database: %v", err)
+			}
+		}()
+
+		if cfg.App.SeedOnStartup > 0 && cfg.App.Environment == "development" {
+			// The seed is fixed, so restarts do not add more users
+			result, err := seed.Run(ctx, db, seed.Options{Users: cfg.App.SeedOnStartup, MaxPostsPerUser: 3, Seed: 1})
+			if err != nil {
+				log.Printf("Error seeding database after %d users: %v", result.Users, err)
+			} else {
+				log.Printf("Seeded %d fake users and %d posts", result.Users, result.Posts)
+			}
+		}
+
+		db.StartConnectionMonitoring(monitorCtx, 30*time.Second)
+	}
+
+	queue, err := jobs.NewQueue(jobs.Config{Workers: cfg.Jobs.Workers, BufferSize: cfg.Jobs.QueueSize})
+	if err != nil {
+		return fmt.Errorf("failed to create job queue: %w", err)
+	}
+	queue.Start()
+	defer func() {
+		shutdownCtx, cancel := budget.context()
+		defer cancel()
+		if err := queue.Shutdown(shutdownCtx); err != nil {
+			log.Printf("Error draining job queue: %v", err)
+		}
+	}()
+
+	var publisher events.Publisher = events.NoopPublisher{}
+	if len(cfg.Kafka.Brokers) > 0 && cfg.Kafka.PublishEvents {
+		kafkaPublisher, err := events.NewKafkaPublisher(cfg.Kafka.Brokers, cfg.Kafka.UserEventsTopic)
+		if err != nil {
+			return fmt.Errorf("failed to create Kafka publisher: %w", err)
+		}
+		publisher = kafkaPublisher
+	}
+	defer func() {
+		if err := publisher.Close(); err != nil {
+			log.Printf("Error closing event publisher: %v", err)
+		}
+	}()
+
+	var webhookStore webhooks.Store
+	if cfg.Webhooks.Enabled {
+		webhookStore = webhooks.NewMySQLStore(db)
+		dispatcher, err := webhooks.NewDispatcher(publisher, webhookStore, queue, webhooks.Config{
+			Timeout:     cfg.Webhooks.Timeout,
+			MaxAttempts: cfg.Webhooks.MaxAttempts,
+		})
+		if err != nil {
+			return fmt.Errorf("failed to create webhook dispatcher: %w", err)
+		}
+		publisher = dispatcher
+		log.Println("Delivering user events to registered webhooks")
+	}
+
+	userRepo := repository.NewUserRepository(db)
+	if cfg.Database.PreparedStatements {
+		userRepo = userRepo.WithPreparedStatements()
+		defer func() { _ = userRepo.Close() }()
+	}
+	userEvents := publisher
+	if cfg.Outbox.Enabled {
+		// User writes store their events in the outbox and the relay
+		// publishes them, so the handlers must not publish them again
+		relay, err := outbox.NewRelay(db, publisher, outbox.Config{
+			PollInterval: cfg.Outbox.PollInterval,
+			BatchSize:    cfg.Outbox.BatchSize,
+			MaxAttempts:  cfg.Outbox.MaxAttempts,
+		})
+		if err != nil {
+			return fmt.Errorf("failed to create outbox relay: %w", err)
+		}
+		userRepo = userRepo.WithOutbox()
+		userEvents = events.NoopPublisher{}
+
+		// Like the monitor, the relay keeps running until the server has
+		// drained so events of the last requests are still published
+		relayCtx, stopRelay := context.WithCancel(context.WithoutCancel(ctx))
+		relayDone := make(chan struct{})
+		go func() {
+			relay.Run(relayCtx)
+			close(relayDone)
+		}()
+		defer func() {
+			stopRelay()
+			<-relayDone
+		}()
+		log.Println("Publishing user events through the transactional outbox")
+	}
+
+	var sender notifications.Sender = notifications.LogSender{}
+	if cfg.SMTP.Addr != "" {
+		sender = notifications.NewSMTPSender(cfg.SMTP.Addr, cfg.SMTP.From, cfg.SMTP.Username, cfg.SMTP.Password)
+	}
+	notifier, err := notifications.NewService(sender)
+	if err != nil {
+		return fmt.Errorf("failed to create notification service: %w", err)
+	}
+
+	checks, err := newHealthChecks(cfg.Health, db, telemetryProvider)
+	if err != nil {
+		return fmt.Errorf("failed to register health checks: %w", err)
+	}
+
+	// Validated with the config; an unset date parses to the zero time
+	apiSunset, _ := time.Parse(time.DateOnly, cfg.App.APISunset)
+	services := handlers.Services{
+		Health:        checks,
+		Users:         userRepo,
+		Jobs:          queue,
+		Operations:    operations.NewManager(queue, cfg.Jobs.OperationRetention),
+		Events:        userEvents,
+		Notifier:      notifier,
+		Webhooks:      webhookStore,
+		AdminToken:    cfg.App.AdminToken,
+		Prometheus:    telemetryProvider.PrometheusHandler,
+		Telemetry:     telemetryProvider,
+		RateLimits:    rateLimits,
+		Bulk:          handlers.BulkLimits{MaxUsers: cfg.App.BulkMaxUsers, BatchSize: cfg.App.BulkBatchSize},
+		UntracedPaths: telemetryProvider.UntracedPaths,
+		APISunset:     apiSunset,
+		RBAC:          cfg.Auth.RBAC,
+		MetricsStream: livemetrics.Options{
+			Interval:   cfg.Server.MetricsStreamInterval,
+			MaxClients: cfg.Server.MetricsStreamMaxClients,
+			Done:       ctx.Done(),
+		},
+		RequestLimits: handlers.RequestLimits{
+			Read:  handlers.RequestLimit{Timeout: cfg.Server.ReadTimeout, MaxBodyBytes: int64(cfg.Server.ReadMaxBodyBytes)},
+			Write: handlers.RequestLimit{Timeout: cfg.Server.WriteTimeout, MaxBodyBytes: int64(cfg.Server.WriteMaxBodyBytes)},
+		},
+		JWT: middleware.JWTConfig{
+			Secret:   cfg.Auth.JWTSecret,
+			JWKSURL:  cfg.Auth.JWKSURL,
+			Issuer:   cfg.Auth.JWTIssuer,
+			Audience: cfg.Auth.JWTAudience,
+		},
+	}
+	// Validated to run without the outbox and prepared statements
+	switch cfg.Database.Access {
+	case config.AccessSQLX:
+		services.Users = repository.NewSqlxUserRepository(db)
+		log.Println("Reading and writing users through sqlx")
+	case config.AccessSQLC:
+		services.Users = repository.NewSqlcUserRepository(db)
+		log.Println("Reading and writing users through the sqlc queries")
+	}
+	if memoryStore != nil {
+		services.Users = memoryStore
+		services.Posts = memoryStore.Posts()
+	} else {
+		services.Roles = roles.NewMySQLStore(db)
+		services.Search = repository.NewUserSearch(db, repository.SearchMode(cfg.Database.SearchMode))
+	}
+	if !services.JWT.Enabled() {
+		log.Println("JWT_SECRET and JWT_JWKS_URL are unset; user writes are not authenticated")
+	}
+	if len(cfg.Auth.APIKeys) > 0 {
+		// Validated with the config
+		services.APIKeys, _ = auth.ParseAPIKeys(cfg.Auth.APIKeys)
+		log.Printf("Accepting API keys of %s", strings.Join(services.APIKeys.ClientIDs(), ", "))
+	}
+	if cfg.Cache.RedisAddr != "" {
+		userCache, ping, err := newRedisCache(cfg.Cache, "users", cfg.Cache.UserTTL)
+		if err != nil {
+			return fmt.Errorf("failed to create user cache: %w", err)
+		}
+		if err := checks.Register("cache", ping, health.WithTimeout(cfg.Health.CheckTimeout)); err != nil {
+			return fmt.Errorf("failed to register health checks: %w", err)
+		}
+		defer func() {
+			if err := userCache.Close(); err != nil {
+				log.Printf("Error closing user cache: %v", err)
+			}
+		}()
+		services.Users = repository.NewCachedUserStore(services.Users, userCache, cfg.Cache.UserTTL)
+		log.Printf("Caching user lookups in Redis at %s for %s", cfg.Cache.RedisAddr, cfg.Cache.UserTTL)
+	}
+	if cfg.Audit.Enabled {
+		// Wraps the cache so every write is recorded once, whatever serves it
+		auditStore := audit.NewMySQLStore(db)
+		var auditLogs otellog.LoggerProvider
+		if telemetryProvider.LoggerProvider != nil {
+			auditLogs = telemetryProvider.LoggerProvider
+		}
+		recorder := audit.NewRecorder(auditStore, auditLogs)
+		services.Users = audit.WrapUserStore(services.Users, recorder)
+		services.Posts = audit.WrapPostStore(repository.NewPostRepository(db), recorder)
+		services.Audit = auditStore
+		log.Println("Recording user and post changes in the audit log")
+	}
+	idempotencyStore, err := newIdempotencyStore(cfg.Cache)
+	if err != nil {
+		return fmt.Errorf("failed to create idempotency store: %w", err)
+	}
+	defer func() {
+		if err := idempotencyStore.Close(); err != nil {
+			log.Printf("Error closing idempotency store: %v", err)
+		}
+	}()
+	services.Idempotency = idempotencyStore
+	services.IdempotencyTTL = cfg.Cache.IdempotencyTTL
+	if cfg.App.EnricherURL != "" {
+		services.Enricher = enrichment.NewClient(cfg.App.EnricherURL, 2*time.Second)
+	}
+	if cfg.App.ExternalServiceURL != "" {
+		services.External = handlers.NewExternalHandler(cfg.App.ExternalServiceURL, client.New())
+	}
+	if cfg.Storage.Endpoint != "" {
+		avatarStorage, err := avatars.NewS3Storage(avatars.S3Config{
+			Endpoint:  cfg.Storage.Endpoint,
+			Bucket:    cfg.Storage.Bucket,
+			Region:    cfg.Storage.Region,
+			AccessKey: cfg.Storage.AccessKey,
+			SecretKey: cfg.Storage.SecretKey,
+			UseSSL:    cfg.Storage.UseSSL,
+		})
+		if err != nil {
+			return fmt.Errorf("failed to create avatar storage: %w", err)
+		}
+		services.Avatars = avatarStorage
+		services.AvatarMaxBytes = int64(cfg.Storage.AvatarMaxBytes)
+		log.Printf("Storing avatars in bucket %s at %s", cfg.Storage.Bucket, cfg.Storage.Endpoint)
+	}
+	if cfg.App.ChaosEnabled {
+		services.Chaos, err = chaos.NewController()
+		if err != nil {
+			return fmt.Errorf("failed to create chaos controller: %w", err)
+		}
+		log.Println("Chaos fault injection is enabled")
+	}
+
+	// Started once the user store is wired, so the tasks' writes go through
+	// the same cache, audit log and outbox as the API's
+	sched, err := newScheduler(cfg.Scheduler, db, services.Users, userEvents)
+	if err != nil {
+		return fmt.Errorf("failed to configure scheduler: %w", err)
+	}
+	sched.Start()
+	defer func() {
+		shutdownCtx, cancel := budget.context()
+		defer cancel()
+		if err := sched.Stop(shutdownCtx); err != nil {
+			log.Printf("Error stopping scheduler: %v", err)
+		}
+	}()
+
+	router := handlers.SetupRoutes(db, services)
+
+	if cfg.Server.GRPCPort != "" {
+		grpcServer := grpcapi.NewServer(grpcapi.NewUserService(services.Users, userEvents), services.JWT, services.RBAC, services.Roles)
+		grpcAddr := net.JoinHostPort(cfg.Server.Host, cfg.Server.GRPCPort)
+		grpcLn, err := net.Listen("tcp", grpcAddr)
+		if err != nil {
+			return fmt.Errorf("failed to start gRPC server: %w", err)
+		}
+		log.Printf("Starting gRPC server on %s", grpcAddr)
+		go func() {
+			if err := grpcServer.Serve(grpcLn); err != nil {
+				log.Printf("gRPC server failed: %v", err)
+			}
+		}()
+		defer stopGRPCServer(grpcServer, budget)
+	}
+
+	server := newHTTPServer(cfg.Server, router)
+
+	ln, err := net.Listen("tcp", server.Addr)
+	if err != nil {
+		return fmt.Errorf("failed to start server: %w", err)
+	}
+	log.Printf("Starting server on %s", server.Addr)
+	if err := runServer(ctx, server, ln, budget); err != nil {
+		return err
+	}
+	cancelMonitor()
+	return nil
+}
+
+// serverTimeoutMargin is how much longer than the request timeouts the
+// server keeps a connection open, so that middleware.Timeout answers 503
+// before the server drops the connection
+const serverTimeoutMargin = 5 * time.Second
+
+// newHTTPServer creates the API server for handler. Its read and write
+// deadlines outlast the longest of REQUEST_READ_TIMEOUT and
+// REQUEST_WRITE_TIMEOUT by serverTimeoutMargin, and are off when either is,
+// as a request may then run for as long as it needs. Headers must still
+// arrive within 15s
+func newHTTPServer(cfg config.ServerConfig, handler http.Handler) *http.Server {
+	var timeout time.Duration
+	if cfg.ReadTimeout > 0 && cfg.WriteTimeout > 0 {
+		timeout = max(cfg.ReadTimeout, cfg.WriteTimeout) + serverTimeoutMargin
+	}
+	return &http.Server{
+		Addr:              fmt.Sprintf("%s:%s", cfg.Host, cfg.Port),
+		Handler:           handler,
+		ReadHeaderTimeout: 15 * time.Second,
+		ReadTimeout:       timeout,
+		WriteTimeout:      timeout,
+		IdleTimeout:       60 * time.Second,
+	}
+}
+
+// runServer serves on ln until ctx is done, then stops accepting connections
+// and waits for in-flight requests within the shutdown budget. Connections
+// still open when it runs out are closed
+func runServer(ctx context.Context, server *http.Server, ln net.Listener, budget *shutdownBudget) error {
+	serveErr := make(chan error, 1)
+	go func() {
+		if err := server.Serve(ln); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			serveErr <- err
+		}
+		close(serveErr)
+	}()
+
+	select {
+	case err := <-serveErr:
+		return fmt.Errorf("server failed: %w", err)
+	case <-ctx.Done():
+	}
+
+	log.Println("Shutting down server...")
+	shutdownCtx, cancel := budget.context()
+	defer cancel()
+	if err := server.Shutdown(shutdownCtx); err != nil {
+		_ = server.Close()
+		return fmt.Errorf("server forced to shutdown: %w", err)
+	}
+
+	log.Println("Server exited")
+	return nil
+}
+
+// stopGRPCServer waits for in-flight calls within the shutdown budget, then
+// closes the connections still open
+func stopGRPCServer(server *grpc.Server, budget *shutdownBudget) {
+	shutdownCtx, cancel := budget.context()
+	defer cancel()
+
+	stopped := make(chan struct{})
+	go func() {
+		server.GracefulStop()
+		close(stopped)
+	}()
+	select {
+	case <-stopped:
+	case <-shutdownCtx.Done():
+		server.Stop()
+		log.Println("gRPC server forced to shutdown")
+	}
+}
+
+// shutdownBudget shares one timeout between the shutdown steps; the clock
+// starts with the first step
+type shutdownBudget struct {
+	timeout  time.Duration
+	deadline time.Time
+}
+
+func (b *shutdownBudget) context() (context.Context, context.CancelFunc) {
+	if b.deadline.IsZero() {
+		b.deadline = time.Now().Add(b.timeout)
+	}
+	return context.WithDeadline(context.Background(), b.deadline)
+}
+
+// newSettingsApplier returns the hook applying reloadable settings to the
+// logger, the list handlers, the trace sampler and the rate limiters
+func newSettingsApplier(telemetryProvider *config.TelemetryProvider, rateLimits handlers.RateLimits) config.ReloadHook {
+	return func(settings config.Reloadable) error {
+		if err := logging.SetLevel(settings.LogLevel); err != nil {
+			return err
+		}
+		if err := handlers.SetPaginationLimits(handlers.PaginationLimits{
+			Default: settings.PageSizeDefault,
+			Max:     settings.PageSizeMax,
+		}); err != nil {
+			return err
+		}
+		telemetryProvider.Sampler.SetRatio(settings.TraceSampleRatio)
+		rateLimits.Read.SetRate(settings.RateLimitReadRPS)
+		rateLimits.Write.SetRate(settings.RateLimitWriteRPS)
+		return nil
+	}
+}
+
+// newRedisCache connects to Redis with command tracing and wraps it with the
+// cache spans and hit/miss metrics of cache.Instrument, labelled name. The
+// returned checker pings Redis directly so health checks stay out of the
+// cache metrics
+func newRedisCache(cfg config.CacheConfig, name string, ttl time.Duration) (cache.Cache, health.Checker, error) {
+	client := redis.NewClient(&redis.Options{Addr: cfg.RedisAddr, Password: cfg.RedisPassword})
+	if err := redisotel.InstrumentTracing(client); err != nil {
+		_ = client.Close()
+		return nil, nil, fmt.Errorf("failed to instrument Redis client: %w", err)
+	}
+	redisCache, err := cache.Instrument(name, cache.NewRedis(client, "otel-example:", ttl))
+	if err != nil {
+		_ = client.Close()
+		return nil, nil, err
+	}
+	ping := health.CheckerFunc(func(ctx context.Context) error {
+		return client.Ping(ctx).Err()
+	})
+	return redisCache, ping, nil
+}
+
+// newIdempotencyStore keeps the responses of idempotent user creates in
+// Redis when REDIS_ADDR is set, so retries are answered by any instance, and
+// in memory otherwise
+func newIdempotencyStore(cfg config.CacheConfig) (cache.Cache, error) {
+	if cfg.RedisAddr != "" {
+		store, _, err := newRedisCache(cfg, "idempotency", cfg.IdempotencyTTL)
+		return store, err
+	}
+	return cache.Instrument("idempotency", cache.NewMemory(cache.MemoryOptions{
+		MaxEntries: 10000,
+		DefaultTTL: cfg.IdempotencyTTL,
+	}))
+}
+
+// newHealthChecks registers the checks of /health and /ready. Only the
+// primary database is critical; the read replica, the collectors, failing
+// OTLP exports and the disk degrade the service
+func newHealthChecks(cfg config.HealthConfig, db *database.DB, telemetryProvider *config.TelemetryProvider) (*health.Registry, error) {
+	checks := health.NewRegistry()
+	timeout := health.WithTimeout(cfg.CheckTimeout)
+	if db != nil {
+		if err := checks.Register("database", handlers.DatabaseCheck(db), timeout, health.Critical()); err != nil {
+			return nil, err
+		}
+		// Reads fail while the replica is down, but writes still work
+		if replica := db.Replica(); replica != nil {
+			if err := checks.Register("database_replica", handlers.DatabaseCheck(replica), timeout); err != nil {
+				return nil, err
+			}
+		}
+	}
+	for _, addr := range telemetryProvider.CollectorAddrs {
+		if err := checks.Register("otlp:"+addr, health.Dial(addr), timeout); err != nil {
+			return nil, err
+		}
+	}
+	exports := health.CheckerFunc(func(context.Context) error { return telemetryProvider.ExportError() })
+	if err := checks.Register("otlp-export", exports, timeout); err != nil {
+		return nil, err
+	}
+	minFree := uint64(cfg.DiskMinFreeMB) << 20
+	if err := checks.Register("disk", health.DiskSpace(cfg.DiskPath, minFree), timeout); err != nil {
+		return nil, err
+	}
+	return checks, nil
+}
+
+// staleCanaryAge is the age after which a synthetic canary user is assumed
+// to be left over from a failed probe; probes delete theirs within seconds
+const staleCanaryAge = time.Hour
+
+// deleteStaleCanaries deletes the canary users created before cutoff one at
+// a time through users, publishing user.deleted for each like the API does,
+// and returns how many it deleted
+func deleteStaleCanaries(ctx context.Context, users repository.UserStore, publisher events.Publisher, cutoff time.Time) (int, error) {
+	// Collected first so no read is held open while deleting
+	var ids []int
+	filter := models.UserFilter{Query: prober.CanaryEmailPrefix, CreatedBefore: cutoff}
+	err := users.Stream(ctx, filter, func(user *models.User) error {
+		if prober.IsCanaryEmail(user.Email) {
+			ids = append(ids, user.ID)
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	deleted := 0
+	for _, id := range ids {
+		err := users.Delete(ctx, id)
+		if errors.Is(err, apperrors.ErrNotFound) {
+			// Deleted by its probe in the meantime
+			continue
+		}
+		if err != nil {
+			return deleted, err
+		}
+		deleted++
+		err = publisher.PublishUserEvent(ctx, events.UserEvent{
+			Type:       events.UserDeleted,
+			UserID:     id,
+			Actor:      auth.Actor(ctx),
+			OccurredAt: time.Now().UTC(),
+		})
+		if err != nil {
+			logging.LogError(ctx, err, "Failed to publish user event", map[string]interface{}{
+				"event_type": string(events.UserDeleted),
+				"user_id":    id,
+			})
+		}
+	}
+	return deleted, nil
+}
+
+// newScheduler registers the periodic maintenance tasks. users and
+// userEvents are the store and publisher the API writes users with
+func newScheduler(cfg config.SchedulerConfig, db *database.DB, users repository.UserStore, userEvents events.Publisher) (*scheduler.Scheduler, error) {
+	sched, err := scheduler.New(cfg.RunTimeout)
+	if err != nil {
+		return nil, err
+	}
+
+	var probeAuth []apiclient.Option
+	if cfg.SyntheticProbeToken != "" {
+		probeAuth = append(probeAuth, apiclient.WithBearerToken(cfg.SyntheticProbeToken))
+	}
+	if cfg.SyntheticProbeAPIKey != "" {
+		probeAuth = append(probeAuth, apiclient.WithAPIKey(cfg.SyntheticProbeAPIKey))
+	}
+	probe, err := prober.New(cfg.SyntheticProbeURL, probeAuth...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create synthetic prober: %w", err)
+	}
+
+	tasks := []scheduler.Task{
+		{
+			Name:     "synthetic-probe",
+			Schedule: cfg.SyntheticProbe,
+			Run:      probe.Run,
+		},
+		{
+			Name:     "stale-user-cleanup",
+			Schedule: cfg.StaleUserCleanup,
+			Run: func(ctx context.Context) error {
+				deleted, err := deleteStaleCanaries(ctx, users, userEvents, time.Now().Add(-staleCanaryAge))
+				if deleted > 0 {
+					logging.LogInfo(ctx, "Deleted stale canary users", map[string]interface{}{"count": deleted})
+				}
+				return err
+			},
+		},
+	}
+	// DB_DRIVER=memory leaves no database to maintain
+	if db != nil {
+		userRepo := repository.NewUserRepository(db)
+		tasks = append(tasks, scheduler.Task{
+			Name:     "user-count-warmup",
+			Schedule: cfg.UserCountWarmup,
+			Run: func(ctx context.Context) error {
+				count, err := userRepo.Count(ctx, models.UserFilter{})
+				if err != nil {
+					return err
+				}
+				logging.LogInfo(ctx, "Warmed up user count", map[string]interface{}{"count": count})
+				return nil
+			},
+		})
+	}
+	for _, task := range tasks {
+		if err := sched.Register(task); err != nil {
+			return nil, err
+		}
+	}
+	return sched, nil
+}
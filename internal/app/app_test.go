@@ -0,0 +1,200 @@
+package app
+
+import (
+	"context"
+	"io"
+	"net"
+	"net/http"
+	"testing"
+	"time"
+
+	"arquivolivre.com.br/otel/internal/auth"
+	"arquivolivre.com.br/otel/internal/config"
+	"arquivolivre.com.br/otel/internal/events"
+	"arquivolivre.com.br/otel/internal/middleware"
+	"arquivolivre.com.br/otel/internal/models"
+	"arquivolivre.com.br/otel/internal/repository"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRunServerDrainsInFlightRequests(t *testing.T) {
+	started := make(chan struct{})
+	release := make(chan struct{})
+	server := &http.Server{Handler: http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		close(started)
+		<-release
+		_, _ = io.WriteString(w, "done")
+	})}
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+
+	ctx, stop := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() { done <- runServer(ctx, server, ln, &shutdownBudget{timeout: 5 * time.Second}) }()
+
+	type result struct {
+		body string
+		err  error
+	}
+	response := make(chan result, 1)
+	go func() {
+		resp, err := http.Get("http://" + ln.Addr().String())
+		if err != nil {
+			response <- result{err: err}
+			return
+		}
+		defer func() { _ = resp.Body.Close() }()
+		body, err := io.ReadAll(resp.Body)
+		response <- result{body: string(body), err: err}
+	}()
+
+	<-started
+	stop()
+
+	select {
+	case err := <-done:
+		t.Fatalf("server stopped before the in-flight request finished: %v", err)
+	case <-time.After(100 * time.Millisecond):
+	}
+
+	_, err = net.DialTimeout("tcp", ln.Addr().String(), time.Second)
+	assert.Error(t, err, "new connections should be refused while draining")
+
+	close(release)
+	res := <-response
+	require.NoError(t, res.err)
+	assert.Equal(t, "done", res.body)
+	assert.NoError(t, <-done)
+}
+
+func TestRunServerForcesCloseAfterTimeout(t *testing.T) {
+	started := make(chan struct{})
+	server := &http.Server{Handler: http.HandlerFunc(func(_ http.ResponseWriter, r *http.Request) {
+		close(started)
+		<-r.Context().Done()
+	})}
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+
+	ctx, stop := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() { done <- runServer(ctx, server, ln, &shutdownBudget{timeout: 50 * time.Millisecond}) }()
+
+	go func() {
+		resp, err := http.Get("http://" + ln.Addr().String())
+		if err == nil {
+			_ = resp.Body.Close()
+		}
+	}()
+
+	<-started
+	stop()
+
+	select {
+	case err := <-done:
+		assert.ErrorIs(t, err, context.DeadlineExceeded)
+	case <-time.After(5 * time.Second):
+		t.Fatal("server did not stop after the shutdown timeout")
+	}
+}
+
+func TestShutdownBudgetIsShared(t *testing.T) {
+	budget := &shutdownBudget{timeout: time.Minute}
+
+	first, cancel := budget.context()
+	defer cancel()
+	second, cancel := budget.context()
+	defer cancel()
+
+	firstDeadline, _ := first.Deadline()
+	secondDeadline, _ := second.Deadline()
+	assert.Equal(t, firstDeadline, secondDeadline)
+}
+
+func TestHTTPServerOutlastsRequestTimeouts(t *testing.T) {
+	server := newHTTPServer(config.ServerConfig{ReadTimeout: 10 * time.Second, WriteTimeout: 30 * time.Second}, nil)
+	assert.Equal(t, 35*time.Second, server.WriteTimeout)
+	assert.Equal(t, 35*time.Second, server.ReadTimeout)
+
+	// Unbounded requests leave the connection without a deadline
+	server = newHTTPServer(config.ServerConfig{ReadTimeout: 10 * time.Second}, nil)
+	assert.Zero(t, server.WriteTimeout)
+	assert.Zero(t, server.ReadTimeout)
+}
+
+func TestTimedOutWriteGets503(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	cfg := config.ServerConfig{ReadTimeout: 50 * time.Millisecond, WriteTimeout: 50 * time.Millisecond}
+	r := gin.New()
+	r.Use(middleware.ErrorHandler())
+	r.POST("/users", middleware.Timeout(cfg.WriteTimeout), func(c *gin.Context) {
+		<-c.Request.Context().Done()
+		_ = c.Error(middleware.FromError(c.Request.Context().Err(), "Failed to create user"))
+	})
+
+	server := newHTTPServer(cfg, r)
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	go func() { _ = server.Serve(ln) }()
+	t.Cleanup(func() { _ = server.Close() })
+
+	resp, err := http.Post("http://"+ln.Addr().String()+"/users", "application/json", nil)
+	require.NoError(t, err, "the server must not drop the connection before the 503")
+	defer func() { _ = resp.Body.Close() }()
+	body, err := io.ReadAll(resp.Body)
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusServiceUnavailable, resp.StatusCode)
+	assert.Contains(t, string(body), models.ErrCodeRequestTimeout)
+}
+
+type recordingPublisher struct {
+	events []events.UserEvent
+}
+
+func (p *recordingPublisher) PublishUserEvent(_ context.Context, event events.UserEvent) error {
+	p.events = append(p.events, event)
+	return nil
+}
+
+func (p *recordingPublisher) Close() error { return nil }
+
+func TestDeleteStaleCanariesGoesThroughTheStore(t *testing.T) {
+	ctx := context.Background()
+	store := repository.NewInMemoryUserStore()
+	for _, req := range []models.CreateUserRequest{
+		{Name: "Synthetic Canary", Email: "synthetic-canary-1@example.com"},
+		{Name: "Ann", Email: "ann@example.com"},
+		{Name: "synthetic-canary-2", Email: "bob@example.com"},
+		{Name: "Synthetic Canary", Email: "synthetic-canary-3@example.com"},
+	} {
+		_, err := store.Create(ctx, req)
+		require.NoError(t, err)
+	}
+	publisher := &recordingPublisher{}
+
+	// Canaries created since the cutoff belong to a running probe
+	deleted, err := deleteStaleCanaries(ctx, store, publisher, time.Now().Add(-time.Minute))
+	require.NoError(t, err)
+	assert.Zero(t, deleted)
+
+	deleted, err = deleteStaleCanaries(ctx, store, publisher, time.Now().Add(time.Minute))
+	require.NoError(t, err)
+	assert.Equal(t, 2, deleted)
+
+	remaining, err := store.GetAll(ctx, models.UserFilter{}, 10, 0)
+	require.NoError(t, err)
+	var emails []string
+	for _, user := range remaining {
+		emails = append(emails, user.Email)
+	}
+	assert.ElementsMatch(t, []string{"ann@example.com", "bob@example.com"}, emails)
+
+	require.Len(t, publisher.events, 2)
+	for _, event := range publisher.events {
+		assert.Equal(t, events.UserDeleted, event.Type)
+		assert.Equal(t, auth.SystemActor, event.Actor)
+	}
+}
@@ -0,0 +1,124 @@
+// Package audit records who changed what. Every create, update and delete of
+// a user or post is stored in the audit_log table with the actor, the fields
+// that changed and the trace of the request, and emitted to the audit log
+// stream of the OpenTelemetry LoggerProvider.
+package audit
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"arquivolivre.com.br/otel/internal/models"
+)
+
+// Action is the kind of change recorded by an entry
+type Action string
+
+// Actions recorded in the audit log
+const (
+	ActionCreate Action = "create"
+	ActionUpdate Action = "update"
+	ActionDelete Action = "delete"
+)
+
+// Entities whose changes are recorded
+const (
+	EntityUser = "user"
+	EntityPost = "post"
+)
+
+// Entry is a change made to an entity
+type Entry struct {
+	ID int64 `json:"id"`
+	// Actor is the principal that made the change, or system
+	Actor string `json:"actor"`
+	// ClientID is the API client the change was made with, if any
+	ClientID string `json:"client_id,omitempty"`
+	Action   Action `json:"action"`
+	Entity   string `json:"entity"`
+	EntityID int    `json:"entity_id"`
+	// Changes holds the fields that changed by JSON name
+	Changes map[string]Change `json:"changes"`
+	// TraceID is the trace of the request that made the change
+	TraceID    string           `json:"trace_id,omitempty"`
+	OccurredAt models.Timestamp `json:"occurred_at"`
+}
+
+// Change is the value of a field before and after a change; Before is
+// omitted for creates and After for deletes
+type Change struct {
+	Before json.RawMessage `json:"before,omitempty"`
+	After  json.RawMessage `json:"after,omitempty"`
+}
+
+// Filter selects entries; empty fields match every entry
+type Filter struct {
+	Entity   string
+	EntityID int
+	Actor    string
+}
+
+// Store keeps audit entries
+type Store interface {
+	Insert(ctx context.Context, entry *Entry) error
+	// List returns a page of the entries matching filter, newest first
+	List(ctx context.Context, filter Filter, limit, offset int) ([]Entry, error)
+	Count(ctx context.Context, filter Filter) (int, error)
+}
+
+// ignoredFields are bookkeeping columns that change with every write and
+// are already covered by the entry's actor and time
+var ignoredFields = map[string]bool{
+	"created_at": true,
+	"updated_at": true,
+	"created_by": true,
+	"updated_by": true,
+	"version":    true,
+}
+
+// Diff returns the JSON fields whose values differ between before and after.
+// Either may be nil, for a create or a delete
+func Diff(before, after any) (map[string]Change, error) {
+	beforeFields, err := fields(before)
+	if err != nil {
+		return nil, err
+	}
+	afterFields, err := fields(after)
+	if err != nil {
+		return nil, err
+	}
+
+	changes := map[string]Change{}
+	for name, value := range beforeFields {
+		if ignoredFields[name] {
+			continue
+		}
+		if other, ok := afterFields[name]; !ok || !bytes.Equal(value, other) {
+			changes[name] = Change{Before: value, After: afterFields[name]}
+		}
+	}
+	for name, value := range afterFields {
+		if _, ok := beforeFields[name]; !ok && !ignoredFields[name] {
+			changes[name] = Change{After: value}
+		}
+	}
+	return changes, nil
+}
+
+// fields returns the JSON encoding of each field of v
+func fields(v any) (map[string]json.RawMessage, error) {
+	if v == nil {
+		return nil, nil
+	}
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode audited entity: %w", err)
+	}
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return nil, fmt.Errorf("failed to decode audited entity: %w", err)
+	}
+	return fields, nil
+}
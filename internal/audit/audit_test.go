@@ -0,0 +1,266 @@
+package audit
+
+import (
+	"context"
+	"encoding/json"
+	"maps"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"sync"
+	"testing"
+
+	"arquivolivre.com.br/otel/internal/auth"
+	"arquivolivre.com.br/otel/internal/database"
+	"arquivolivre.com.br/otel/internal/middleware"
+	"arquivolivre.com.br/otel/internal/models"
+	"arquivolivre.com.br/otel/internal/repository"
+	"arquivolivre.com.br/otel/pkg/apperrors"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	otellog "go.opentelemetry.io/otel/log"
+	sdklog "go.opentelemetry.io/otel/sdk/log"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+)
+
+// memStore keeps entries in memory
+type memStore struct {
+	entries []Entry
+}
+
+func (s *memStore) Insert(_ context.Context, entry *Entry) error {
+	entry.ID = int64(len(s.entries) + 1)
+	s.entries = append(s.entries, *entry)
+	return nil
+}
+
+func (s *memStore) List(_ context.Context, filter Filter, limit, offset int) ([]Entry, error) {
+	matching := []Entry{}
+	for i := len(s.entries) - 1; i >= 0; i-- {
+		entry := s.entries[i]
+		if (filter.Entity == "" || entry.Entity == filter.Entity) &&
+			(filter.EntityID == 0 || entry.EntityID == filter.EntityID) &&
+			(filter.Actor == "" || entry.Actor == filter.Actor) {
+			matching = append(matching, entry)
+		}
+	}
+	if offset > len(matching) {
+		return []Entry{}, nil
+	}
+	return matching[offset:min(offset+limit, len(matching))], nil
+}
+
+func (s *memStore) Count(ctx context.Context, filter Filter) (int, error) {
+	entries, err := s.List(ctx, filter, len(s.entries), 0)
+	return len(entries), err
+}
+
+// fakeUsers keeps users in memory; the reads it does not override are not
+// used by the audit store
+type fakeUsers struct {
+	repository.UserStore
+	users map[int]models.User
+}
+
+func (s *fakeUsers) GetByID(_ context.Context, id int) (*models.User, error) {
+	user, ok := s.users[id]
+	if !ok {
+		return nil, apperrors.NotFound("user not found")
+	}
+	return &user, nil
+}
+
+func (s *fakeUsers) Create(_ context.Context, req models.CreateUserRequest) (*models.User, error) {
+	user := models.User{ID: len(s.users) + 1, Name: req.Name, Email: req.Email, Bio: req.Bio, Version: 1, CreatedAt: models.Now()}
+	s.users[user.ID] = user
+	return &user, nil
+}
+
+func (s *fakeUsers) Update(_ context.Context, id int, req models.UpdateUserRequest) (*models.User, error) {
+	user := s.users[id]
+	if req.Name != nil {
+		user.Name = *req.Name
+	}
+	user.Version++
+	user.UpdatedAt = models.Now()
+	s.users[id] = user
+	return &user, nil
+}
+
+func (s *fakeUsers) Delete(_ context.Context, id int) error {
+	delete(s.users, id)
+	return nil
+}
+
+// logRecorder keeps the records emitted to a LoggerProvider
+type logRecorder struct {
+	mu      sync.Mutex
+	records []sdklog.Record
+}
+
+func (p *logRecorder) OnEmit(_ context.Context, record *sdklog.Record) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.records = append(p.records, record.Clone())
+	return nil
+}
+
+func (p *logRecorder) Enabled(context.Context, sdklog.EnabledParameters) bool { return true }
+func (p *logRecorder) Shutdown(context.Context) error                         { return nil }
+func (p *logRecorder) ForceFlush(context.Context) error                       { return nil }
+
+func TestDiff(t *testing.T) {
+	bio := "Engineer"
+	before := models.User{ID: 1, Name: "Ann", Email: "ann@example.com", Version: 1}
+	after := models.User{ID: 1, Name: "Ann Lee", Email: "ann@example.com", Bio: &bio, Version: 2, UpdatedAt: models.Now()}
+
+	changes, err := Diff(before, after)
+	require.NoError(t, err)
+	assert.Equal(t, map[string]Change{
+		"name": {Before: json.RawMessage(`"Ann"`), After: json.RawMessage(`"Ann Lee"`)},
+		"bio":  {After: json.RawMessage(`"Engineer"`)},
+	}, changes)
+
+	changes, err = Diff(nil, &before)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"email", "id", "name"}, slices.Sorted(maps.Keys(changes)))
+	assert.Nil(t, changes["name"].Before)
+
+	changes, err = Diff(&before, nil)
+	require.NoError(t, err)
+	assert.Equal(t, json.RawMessage(`"ann@example.com"`), changes["email"].Before)
+	assert.Nil(t, changes["email"].After)
+}
+
+func TestWrapUserStoreRecordsChanges(t *testing.T) {
+	logs := &logRecorder{}
+	store := &memStore{}
+	recorder := NewRecorder(store, sdklog.NewLoggerProvider(sdklog.WithProcessor(logs)))
+	users := WrapUserStore(&fakeUsers{users: map[int]models.User{}}, recorder)
+
+	tp := sdktrace.NewTracerProvider()
+	ctx, span := tp.Tracer("test").Start(context.Background(), "request")
+	defer span.End()
+	ctx = auth.WithPrincipal(ctx, auth.Principal{Subject: "alice"})
+	ctx = auth.WithClientID(ctx, "ci-bot")
+
+	created, err := users.Create(ctx, models.CreateUserRequest{Name: "Ann", Email: "ann@example.com"})
+	require.NoError(t, err)
+	name := "Ann Lee"
+	_, err = users.Update(ctx, created.ID, models.UpdateUserRequest{Name: &name})
+	require.NoError(t, err)
+	require.NoError(t, users.Delete(ctx, created.ID))
+
+	// Failed writes are not recorded
+	_, err = users.Update(ctx, 99, models.UpdateUserRequest{Name: &name})
+	assert.Error(t, err)
+
+	require.Len(t, store.entries, 3)
+	traceID := span.SpanContext().TraceID().String()
+	for i, action := range []Action{ActionCreate, ActionUpdate, ActionDelete} {
+		entry := store.entries[i]
+		assert.Equal(t, action, entry.Action)
+		assert.Equal(t, EntityUser, entry.Entity)
+		assert.Equal(t, created.ID, entry.EntityID)
+		assert.Equal(t, "alice", entry.Actor)
+		assert.Equal(t, "ci-bot", entry.ClientID)
+		assert.Equal(t, traceID, entry.TraceID)
+	}
+	assert.Equal(t, map[string]Change{
+		"name": {Before: json.RawMessage(`"Ann"`), After: json.RawMessage(`"Ann Lee"`)},
+	}, store.entries[1].Changes)
+
+	require.Len(t, logs.records, 3)
+	record := logs.records[1]
+	assert.Equal(t, "audit.user.update", record.EventName())
+	assert.Equal(t, "user 1 updated by alice", record.Body().AsString())
+	assert.Equal(t, span.SpanContext().TraceID(), record.TraceID())
+	attrs := map[string]string{}
+	record.WalkAttributes(func(kv otellog.KeyValue) bool {
+		attrs[string(kv.Key)] = kv.Value.String()
+		return true
+	})
+	assert.Equal(t, "alice", attrs["audit.actor"])
+	assert.Equal(t, "ci-bot", attrs[auth.ClientIDKey])
+	assert.JSONEq(t, `{"name":{"before":"Ann","after":"Ann Lee"}}`, attrs["audit.changes"])
+}
+
+func TestMySQLStore(t *testing.T) {
+	sqlDB, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer func() { _ = sqlDB.Close() }()
+	store := NewMySQLStore(&database.DB{DB: sqlDB})
+	ctx := context.Background()
+
+	entry := &Entry{
+		Actor:      "alice",
+		Action:     ActionUpdate,
+		Entity:     EntityPost,
+		EntityID:   7,
+		Changes:    map[string]Change{"title": {Before: json.RawMessage(`"a"`), After: json.RawMessage(`"b"`)}},
+		OccurredAt: models.Now(),
+	}
+	mock.ExpectExec("INSERT INTO audit_log").
+		WithArgs("alice", "", "update", "post", 7, []byte(`{"title":{"before":"a","after":"b"}}`), "", entry.OccurredAt).
+		WillReturnResult(sqlmock.NewResult(12, 1))
+	require.NoError(t, store.Insert(ctx, entry))
+	assert.Equal(t, int64(12), entry.ID)
+
+	mock.ExpectQuery(`SELECT .* FROM audit_log WHERE entity = \? AND actor = \? ORDER BY id DESC LIMIT \? OFFSET \?`).
+		WithArgs("post", "alice", 10, 20).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "actor", "client_id", "action", "entity", "entity_id", "changes", "trace_id", "occurred_at"}).
+			AddRow(12, "alice", "", "update", "post", 7, []byte(`{"title":{"before":"a","after":"b"}}`), "", entry.OccurredAt.Time))
+	entries, err := store.List(ctx, Filter{Entity: EntityPost, Actor: "alice"}, 10, 20)
+	require.NoError(t, err)
+	require.Len(t, entries, 1)
+	assert.Equal(t, ActionUpdate, entries[0].Action)
+	assert.Equal(t, entry.Changes, entries[0].Changes)
+
+	mock.ExpectQuery(`SELECT COUNT\(\*\) FROM audit_log$`).
+		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(3))
+	count, err := store.Count(ctx, Filter{})
+	require.NoError(t, err)
+	assert.Equal(t, 3, count)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestHandler(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	store := &memStore{}
+	for _, entry := range []Entry{
+		{Actor: "alice", Action: ActionCreate, Entity: EntityUser, EntityID: 1},
+		{Actor: "bob", Action: ActionUpdate, Entity: EntityUser, EntityID: 1},
+		{Actor: "alice", Action: ActionCreate, Entity: EntityPost, EntityID: 1},
+	} {
+		require.NoError(t, store.Insert(context.Background(), &entry))
+	}
+
+	r := gin.New()
+	r.Use(middleware.ErrorHandler())
+	NewHandler(store).Register(r.Group("/api/audit"))
+
+	get := func(path string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, path, nil))
+		return w
+	}
+
+	w := get("/api/audit?entity=user&entity_id=1&limit=1")
+	require.Equal(t, http.StatusOK, w.Code)
+	var page struct {
+		Data       []Entry `json:"data"`
+		Pagination struct {
+			Total int `json:"total"`
+		} `json:"pagination"`
+	}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &page))
+	require.Len(t, page.Data, 1)
+	assert.Equal(t, "bob", page.Data[0].Actor, "newest first")
+	assert.Equal(t, 2, page.Pagination.Total)
+
+	assert.Equal(t, http.StatusBadRequest, get("/api/audit?entity=webhook").Code)
+	assert.Equal(t, http.StatusBadRequest, get("/api/audit?entity_id=abc").Code)
+}
@@ -0,0 +1,71 @@
+package audit
+
+import (
+	"strconv"
+
+	"arquivolivre.com.br/otel/internal/middleware"
+	"arquivolivre.com.br/otel/pkg/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Page sizes of the audit log listing
+const (
+	defaultPageSize = 50
+	maxPageSize     = 200
+)
+
+// Handler serves the audit log over HTTP for administrators
+type Handler struct {
+	store Store
+}
+
+// NewHandler creates a handler for store
+func NewHandler(store Store) *Handler {
+	return &Handler{store: store}
+}
+
+// Register mounts the audit endpoints on group
+func (h *Handler) Register(group *gin.RouterGroup) {
+	group.GET("", h.ListEntries)
+}
+
+// ListEntries returns a page of entries, newest first, optionally filtered
+// by entity, entity_id and actor
+func (h *Handler) ListEntries(c *gin.Context) {
+	filter := Filter{Entity: c.Query("entity"), Actor: c.Query("actor")}
+	if filter.Entity != "" && filter.Entity != EntityUser && filter.Entity != EntityPost {
+		_ = c.Error(middleware.BadRequestError("entity must be user or post"))
+		return
+	}
+	if value := c.Query("entity_id"); value != "" {
+		id, err := strconv.Atoi(value)
+		if err != nil || id < 1 {
+			_ = c.Error(middleware.BadRequestError("entity_id must be a positive integer"))
+			return
+		}
+		filter.EntityID = id
+	}
+
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", strconv.Itoa(defaultPageSize)))
+	if page < 1 {
+		page = 1
+	}
+	if limit < 1 || limit > maxPageSize {
+		limit = defaultPageSize
+	}
+
+	ctx := c.Request.Context()
+	entries, err := h.store.List(ctx, filter, limit, (page-1)*limit)
+	if err != nil {
+		_ = c.Error(middleware.InternalError("Failed to retrieve audit log", err))
+		return
+	}
+	total, err := h.store.Count(ctx, filter)
+	if err != nil {
+		_ = c.Error(middleware.InternalError("Failed to count audit entries", err))
+		return
+	}
+	utils.SendPaginated(c, entries, page, limit, total)
+}
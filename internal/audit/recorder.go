@@ -0,0 +1,114 @@
+package audit
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"arquivolivre.com.br/otel/internal/auth"
+	"arquivolivre.com.br/otel/internal/logging"
+	"arquivolivre.com.br/otel/internal/models"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	otellog "go.opentelemetry.io/otel/log"
+	"go.opentelemetry.io/otel/log/noop"
+	"go.opentelemetry.io/otel/metric"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// LoggerName is the instrumentation scope of the audit log stream
+const LoggerName = "otel-example-api/audit"
+
+// Recorder stores audit entries and emits them as log records
+type Recorder struct {
+	store   Store
+	logger  otellog.Logger
+	entries metric.Int64Counter
+}
+
+// NewRecorder creates a recorder writing to store and to the audit logger of
+// provider; a nil provider only writes to store
+func NewRecorder(store Store, provider otellog.LoggerProvider) *Recorder {
+	if provider == nil {
+		provider = noop.NewLoggerProvider()
+	}
+	entries, _ := otel.Meter("otel-example-api").Int64Counter(
+		"audit_entries_total",
+		metric.WithDescription("Total number of audit entries recorded, by entity, action and result"),
+	)
+	return &Recorder{
+		store:   store,
+		logger:  provider.Logger(LoggerName),
+		entries: entries,
+	}
+}
+
+// Record stores the change of an entity made by the principal of ctx. before
+// is nil for creates and after for deletes. The change has already been
+// made, so failures are logged and counted rather than returned
+func (r *Recorder) Record(ctx context.Context, action Action, entity string, id int, before, after any) {
+	result := "recorded"
+	defer func() {
+		r.entries.Add(ctx, 1, metric.WithAttributes(
+			attribute.String("entity", entity),
+			attribute.String("action", string(action)),
+			attribute.String("result", result),
+		))
+	}()
+
+	changes, err := Diff(before, after)
+	if err != nil {
+		result = "failed"
+		logging.Module("audit").LogError(ctx, err, "Failed to compute audit changes", map[string]interface{}{
+			"entity": entity, "entity_id": id, "action": string(action),
+		})
+		return
+	}
+
+	entry := &Entry{
+		Actor:      auth.Actor(ctx),
+		ClientID:   auth.ClientID(ctx),
+		Action:     action,
+		Entity:     entity,
+		EntityID:   id,
+		Changes:    changes,
+		OccurredAt: models.Now(),
+	}
+	if sc := trace.SpanContextFromContext(ctx); sc.HasTraceID() {
+		entry.TraceID = sc.TraceID().String()
+	}
+
+	r.emit(ctx, entry)
+	// The entry outlives a request cancelled after its change was committed
+	if err := r.store.Insert(context.WithoutCancel(ctx), entry); err != nil {
+		result = "failed"
+		logging.Module("audit").LogError(ctx, err, "Failed to store audit entry", map[string]interface{}{
+			"entity": entity, "entity_id": id, "action": string(action),
+		})
+	}
+}
+
+// emit writes entry to the audit log stream; the record is correlated with
+// the span of ctx
+func (r *Recorder) emit(ctx context.Context, entry *Entry) {
+	changes, _ := json.Marshal(entry.Changes)
+
+	var record otellog.Record
+	record.SetTimestamp(entry.OccurredAt.Time)
+	record.SetSeverity(otellog.SeverityInfo)
+	record.SetSeverityText("INFO")
+	record.SetEventName("audit." + entry.Entity + "." + string(entry.Action))
+	record.SetBody(otellog.StringValue(fmt.Sprintf("%s %d %sd by %s", entry.Entity, entry.EntityID, entry.Action, entry.Actor)))
+	record.AddAttributes(
+		otellog.String("audit.actor", entry.Actor),
+		otellog.String("audit.action", string(entry.Action)),
+		otellog.String("audit.entity", entry.Entity),
+		otellog.Int("audit.entity_id", entry.EntityID),
+		otellog.String("audit.changes", string(changes)),
+	)
+	if entry.ClientID != "" {
+		record.AddAttributes(otellog.String(auth.ClientIDKey, entry.ClientID))
+	}
+	r.logger.Emit(ctx, record)
+}
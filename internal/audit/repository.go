@@ -0,0 +1,110 @@
+package audit
+
+import (
+	"context"
+
+	"arquivolivre.com.br/otel/internal/models"
+	"arquivolivre.com.br/otel/internal/repository"
+)
+
+// userStore records the user writes of the store it wraps
+type userStore struct {
+	repository.UserStore
+	recorder *Recorder
+}
+
+// WrapUserStore returns a store recording the creates, updates and deletes
+// made through next
+func WrapUserStore(next repository.UserStore, recorder *Recorder) repository.UserStore {
+	return &userStore{UserStore: next, recorder: recorder}
+}
+
+func (s *userStore) Create(ctx context.Context, req models.CreateUserRequest) (*models.User, error) {
+	user, err := s.UserStore.Create(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	s.recorder.Record(ctx, ActionCreate, EntityUser, user.ID, nil, user)
+	return user, nil
+}
+
+func (s *userStore) CreateBatch(ctx context.Context, reqs []models.CreateUserRequest) ([]repository.BatchResult, error) {
+	results, err := s.UserStore.CreateBatch(ctx, reqs)
+	for _, result := range results {
+		if result.User != nil {
+			s.recorder.Record(ctx, ActionCreate, EntityUser, result.User.ID, nil, result.User)
+		}
+	}
+	return results, err
+}
+
+func (s *userStore) Update(ctx context.Context, id int, req models.UpdateUserRequest) (*models.User, error) {
+	before, err := s.UserStore.GetByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	user, err := s.UserStore.Update(ctx, id, req)
+	if err != nil {
+		return nil, err
+	}
+	s.recorder.Record(ctx, ActionUpdate, EntityUser, id, before, user)
+	return user, nil
+}
+
+func (s *userStore) Delete(ctx context.Context, id int) error {
+	before, err := s.UserStore.GetByID(ctx, id)
+	if err != nil {
+		return err
+	}
+	if err := s.UserStore.Delete(ctx, id); err != nil {
+		return err
+	}
+	s.recorder.Record(ctx, ActionDelete, EntityUser, id, before, nil)
+	return nil
+}
+
+// postStore records the post writes of the store it wraps
+type postStore struct {
+	repository.PostStore
+	recorder *Recorder
+}
+
+// WrapPostStore returns a store recording the creates, updates and deletes
+// made through next
+func WrapPostStore(next repository.PostStore, recorder *Recorder) repository.PostStore {
+	return &postStore{PostStore: next, recorder: recorder}
+}
+
+func (s *postStore) Create(ctx context.Context, req models.CreatePostRequest) (*models.Post, error) {
+	post, err := s.PostStore.Create(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	s.recorder.Record(ctx, ActionCreate, EntityPost, post.ID, nil, post)
+	return post, nil
+}
+
+func (s *postStore) Update(ctx context.Context, id int, req models.UpdatePostRequest) (*models.Post, error) {
+	before, err := s.PostStore.GetByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	post, err := s.PostStore.Update(ctx, id, req)
+	if err != nil {
+		return nil, err
+	}
+	s.recorder.Record(ctx, ActionUpdate, EntityPost, id, before, post)
+	return post, nil
+}
+
+func (s *postStore) Delete(ctx context.Context, id int) error {
+	before, err := s.PostStore.GetByID(ctx, id)
+	if err != nil {
+		return err
+	}
+	if err := s.PostStore.Delete(ctx, id); err != nil {
+		return err
+	}
+	s.recorder.Record(ctx, ActionDelete, EntityPost, id, before, nil)
+	return nil
+}
@@ -0,0 +1,149 @@
+package audit
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"arquivolivre.com.br/otel/internal/database"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const entryColumns = "id, actor, client_id, action, entity, entity_id, changes, trace_id, occurred_at"
+
+// MySQLStore keeps entries in the audit_log table
+type MySQLStore struct {
+	db     *database.DB
+	tracer trace.Tracer
+}
+
+var _ Store = (*MySQLStore)(nil)
+
+// NewMySQLStore creates a store on db
+func NewMySQLStore(db *database.DB) *MySQLStore {
+	return &MySQLStore{
+		db:     db,
+		tracer: otel.Tracer("audit-store"),
+	}
+}
+
+// Insert stores entry and sets its ID
+func (s *MySQLStore) Insert(ctx context.Context, entry *Entry) error {
+	ctx, span := s.start(ctx, "AuditStore.Insert", "INSERT")
+	defer span.End()
+	ctx, cancel := s.db.WithQueryTimeout(ctx)
+	defer cancel()
+
+	changes, err := json.Marshal(entry.Changes)
+	if err != nil {
+		return fmt.Errorf("failed to encode audit changes: %w", err)
+	}
+
+	query := "INSERT INTO audit_log (actor, client_id, action, entity, entity_id, changes, trace_id, occurred_at) VALUES (?, ?, ?, ?, ?, ?, ?, ?)"
+	done := s.db.TrackQuery(ctx, "INSERT", "audit_log", query)
+	result, err := s.db.ExecContext(ctx, query, entry.Actor, entry.ClientID, string(entry.Action),
+		entry.Entity, entry.EntityID, changes, entry.TraceID, entry.OccurredAt)
+	done(err)
+	if err != nil {
+		return fmt.Errorf("failed to insert audit entry: %w", err)
+	}
+	id, err := result.LastInsertId()
+	if err != nil {
+		return fmt.Errorf("failed to get last insert id: %w", err)
+	}
+	entry.ID = id
+	return nil
+}
+
+// List returns a page of the entries matching filter, newest first
+func (s *MySQLStore) List(ctx context.Context, filter Filter, limit, offset int) ([]Entry, error) {
+	ctx, span := s.start(ctx, "AuditStore.List", "SELECT")
+	defer span.End()
+	ctx, cancel := s.db.WithQueryTimeout(ctx)
+	defer cancel()
+
+	where, args := filter.where()
+	query := "SELECT " + entryColumns + " FROM audit_log" + where + " ORDER BY id DESC LIMIT ? OFFSET ?"
+	done := s.db.TrackQuery(ctx, "SELECT", "audit_log", query)
+	rows, err := s.db.QueryContext(ctx, query, append(args, limit, offset)...)
+	done(err)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query audit log: %w", err)
+	}
+	defer func() { _ = rows.Close() }()
+
+	entries := []Entry{}
+	for rows.Next() {
+		var entry Entry
+		var action string
+		var changes []byte
+		if err := rows.Scan(&entry.ID, &entry.Actor, &entry.ClientID, &action, &entry.Entity,
+			&entry.EntityID, &changes, &entry.TraceID, &entry.OccurredAt); err != nil {
+			return nil, fmt.Errorf("failed to scan audit entry: %w", err)
+		}
+		entry.Action = Action(action)
+		if err := json.Unmarshal(changes, &entry.Changes); err != nil {
+			return nil, fmt.Errorf("failed to decode changes of audit entry %d: %w", entry.ID, err)
+		}
+		entries = append(entries, entry)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating over audit log: %w", err)
+	}
+
+	span.SetAttributes(attribute.Int("result.count", len(entries)))
+	return entries, nil
+}
+
+// Count returns the number of entries matching filter
+func (s *MySQLStore) Count(ctx context.Context, filter Filter) (int, error) {
+	ctx, span := s.start(ctx, "AuditStore.Count", "SELECT")
+	defer span.End()
+	ctx, cancel := s.db.WithQueryTimeout(ctx)
+	defer cancel()
+
+	where, args := filter.where()
+	query := "SELECT COUNT(*) FROM audit_log" + where
+	var count int
+	done := s.db.TrackQuery(ctx, "SELECT", "audit_log", query)
+	err := s.db.QueryRowContext(ctx, query, args...).Scan(&count)
+	done(err)
+	if err != nil {
+		return 0, fmt.Errorf("failed to count audit entries: %w", err)
+	}
+	return count, nil
+}
+
+// where returns the WHERE clause selecting the entries of f and its
+// arguments
+func (f Filter) where() (string, []interface{}) {
+	conditions := []string{}
+	args := []interface{}{}
+	if f.Entity != "" {
+		conditions = append(conditions, "entity = ?")
+		args = append(args, f.Entity)
+	}
+	if f.EntityID != 0 {
+		conditions = append(conditions, "entity_id = ?")
+		args = append(args, f.EntityID)
+	}
+	if f.Actor != "" {
+		conditions = append(conditions, "actor = ?")
+		args = append(args, f.Actor)
+	}
+	if len(conditions) == 0 {
+		return "", args
+	}
+	return " WHERE " + strings.Join(conditions, " AND "), args
+}
+
+func (s *MySQLStore) start(ctx context.Context, name, operation string) (context.Context, trace.Span) {
+	return s.tracer.Start(ctx, name, trace.WithAttributes(
+		attribute.String("db.operation", operation),
+		attribute.String("db.table", "audit_log"),
+	))
+}
@@ -0,0 +1,104 @@
+package auth
+
+import (
+	"context"
+	"crypto/sha256"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// ClientIDKey names the API client of a request in span, metric and log
+// attributes
+const ClientIDKey = "client.id"
+
+// APIKey is a key issued to an API client
+type APIKey struct {
+	// ClientID identifies the client in telemetry and is its principal
+	ClientID string
+	// DailyQuota is the number of requests the key may make per UTC day;
+	// zero means unlimited
+	DailyQuota int64
+	// Roles are granted to the client, like the roles claim of a token
+	Roles []string
+}
+
+// APIKeys are the keys accepted by the API. Keys are held as SHA-256 hashes,
+// so a lookup does not compare the secret byte by byte
+type APIKeys struct {
+	byHash map[[sha256.Size]byte]APIKey
+}
+
+// ParseAPIKeys reads keys written as client_id:key, optionally followed by
+// :daily_quota and :roles, with roles separated by |, such as
+// "ci-bot:s3cret:1000:users:write|posts:write". An empty quota is
+// unlimited. Client IDs and keys must be unique
+func ParseAPIKeys(entries []string) (*APIKeys, error) {
+	keys := &APIKeys{byHash: make(map[[sha256.Size]byte]APIKey, len(entries))}
+	clients := map[string]bool{}
+	for _, entry := range entries {
+		// Roles hold colons themselves, so they take the rest of the entry
+		parts := strings.SplitN(entry, ":", 4)
+		if len(parts) < 2 || parts[0] == "" || parts[1] == "" {
+			return nil, fmt.Errorf("API key %q must be client_id:key[:daily_quota[:roles]]", redactKey(entry))
+		}
+		key := APIKey{ClientID: parts[0]}
+		if len(parts) == 4 && parts[3] != "" {
+			key.Roles = strings.Split(parts[3], "|")
+		}
+		if len(parts) >= 3 && parts[2] != "" {
+			quota, err := strconv.ParseInt(parts[2], 10, 64)
+			if err != nil || quota < 0 {
+				return nil, fmt.Errorf("API key of client %q has an invalid daily quota %q", key.ClientID, parts[2])
+			}
+			key.DailyQuota = quota
+		}
+		if clients[key.ClientID] {
+			return nil, fmt.Errorf("client %q has more than one API key", key.ClientID)
+		}
+		hash := sha256.Sum256([]byte(parts[1]))
+		if _, ok := keys.byHash[hash]; ok {
+			return nil, fmt.Errorf("API key of client %q is already issued to another client", key.ClientID)
+		}
+		clients[key.ClientID] = true
+		keys.byHash[hash] = key
+	}
+	return keys, nil
+}
+
+// Lookup returns the key matching secret, if any
+func (k *APIKeys) Lookup(secret string) (APIKey, bool) {
+	key, ok := k.byHash[sha256.Sum256([]byte(secret))]
+	return key, ok
+}
+
+// ClientIDs returns the clients holding a key, sorted
+func (k *APIKeys) ClientIDs() []string {
+	ids := make([]string, 0, len(k.byHash))
+	for _, key := range k.byHash {
+		ids = append(ids, key.ClientID)
+	}
+	sort.Strings(ids)
+	return ids
+}
+
+// redactKey hides the key of an entry in error messages
+func redactKey(entry string) string {
+	clientID, _, _ := strings.Cut(entry, ":")
+	return clientID + ":***"
+}
+
+type clientIDKey struct{}
+
+// WithClientID returns a copy of ctx carrying the API client id
+func WithClientID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, clientIDKey{}, id)
+}
+
+// ClientID returns the API client of ctx, or "" when the request was not
+// made with an API key
+func ClientID(ctx context.Context) string {
+	id, _ := ctx.Value(clientIDKey{}).(string)
+	return id
+}
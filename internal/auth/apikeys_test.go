@@ -0,0 +1,45 @@
+package auth
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseAPIKeys(t *testing.T) {
+	keys, err := ParseAPIKeys([]string{"ci-bot:s3cret:1000", "mobile:other", "ops:third::users:write|posts:*"})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"ci-bot", "mobile", "ops"}, keys.ClientIDs())
+
+	key, ok := keys.Lookup("s3cret")
+	assert.True(t, ok)
+	assert.Equal(t, APIKey{ClientID: "ci-bot", DailyQuota: 1000}, key)
+	key, ok = keys.Lookup("other")
+	assert.True(t, ok)
+	assert.Equal(t, APIKey{ClientID: "mobile"}, key)
+	key, _ = keys.Lookup("third")
+	assert.Equal(t, APIKey{ClientID: "ops", Roles: []string{"users:write", "posts:*"}}, key)
+	_, ok = keys.Lookup("s3cre")
+	assert.False(t, ok)
+
+	for _, entries := range [][]string{
+		{"ci-bot"},
+		{":s3cret"},
+		{"ci-bot:s3cret:-1"},
+		{"ci-bot:s3cret:lots"},
+		{"ci-bot:a", "ci-bot:b"},
+		{"ci-bot:s3cret", "mobile:s3cret"},
+	} {
+		_, err := ParseAPIKeys(entries)
+		if assert.Error(t, err, "%v", entries) {
+			assert.NotContains(t, err.Error(), "s3cret")
+		}
+	}
+}
+
+func TestClientID(t *testing.T) {
+	assert.Empty(t, ClientID(context.Background()))
+	assert.Equal(t, "ci-bot", ClientID(WithClientID(context.Background(), "ci-bot")))
+}
@@ -0,0 +1,201 @@
+package auth
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"net/http"
+	"sync"
+	"time"
+
+	"go.opentelemetry.io/contrib/instrumentation/net/http/otelhttp"
+)
+
+const (
+	// jwksRefreshInterval limits how often the set is refetched, whether the
+	// last attempt succeeded or failed
+	jwksRefreshInterval = time.Minute
+	// jwksMaxAge is how long a fetched set is trusted; it is served while
+	// refetches fail until then
+	jwksMaxAge = time.Hour
+)
+
+// ErrUnknownKey is returned when no key of the set matches a token's key ID
+var ErrUnknownKey = errors.New("unknown signing key")
+
+// JWKS is a JSON Web Key Set fetched from a URL. Keys are cached and the set
+// is refetched when a token names a key ID it does not contain, or once it
+// is older than jwksMaxAge, so signing key rotation is picked up without a
+// restart. A single fetch runs at a time, outside the lock, and callers
+// needing its result wait for it while the others are served from the cache
+type JWKS struct {
+	url        string
+	httpClient *http.Client
+	now        func() time.Time
+
+	mu sync.Mutex
+	// keys is the last set fetched successfully, at fetchedAt
+	keys      map[string]any
+	fetchedAt time.Time
+	// attemptedAt is the start of the last fetch and lastErr its error
+	attemptedAt time.Time
+	lastErr     error
+	// fetching is closed when the fetch in progress, if any, ends
+	fetching chan struct{}
+}
+
+// NewJWKS creates a key set served at url; keys are fetched on first use
+func NewJWKS(url string, timeout time.Duration) *JWKS {
+	return &JWKS{
+		url: url,
+		httpClient: &http.Client{
+			Timeout:   timeout,
+			Transport: otelhttp.NewTransport(http.DefaultTransport),
+		},
+		now: time.Now,
+	}
+}
+
+// Key returns the public key with the given key ID
+func (k *JWKS) Key(ctx context.Context, kid string) (any, error) {
+	for {
+		k.mu.Lock()
+		if key, ok := k.keys[kid]; ok && k.now().Sub(k.fetchedAt) < jwksMaxAge {
+			k.mu.Unlock()
+			return key, nil
+		}
+		if fetching := k.fetching; fetching != nil {
+			k.mu.Unlock()
+			select {
+			case <-fetching:
+				continue
+			case <-ctx.Done():
+				return nil, ctx.Err()
+			}
+		}
+		if k.now().Sub(k.attemptedAt) < jwksRefreshInterval {
+			err := k.lastErr
+			k.mu.Unlock()
+			if err != nil {
+				return nil, err
+			}
+			return nil, ErrUnknownKey
+		}
+		fetching := make(chan struct{})
+		k.fetching = fetching
+		k.attemptedAt = k.now()
+		k.mu.Unlock()
+
+		// The fetch serves every waiting caller, so it must not end with
+		// the request that started it
+		keys, err := k.fetch(context.WithoutCancel(ctx))
+
+		k.mu.Lock()
+		k.lastErr = err
+		if err == nil {
+			k.keys = keys
+			k.fetchedAt = k.now()
+		}
+		k.fetching = nil
+		close(fetching)
+		k.mu.Unlock()
+	}
+}
+
+type jwk struct {
+	Kid string `json:"kid"`
+	Kty string `json:"kty"`
+	Use string `json:"use"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+	Crv string `json:"crv"`
+	X   string `json:"x"`
+	Y   string `json:"y"`
+}
+
+func (k *JWKS) fetch(ctx context.Context) (map[string]any, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, k.url, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := k.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("JWKS request failed: %w", err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("JWKS endpoint returned %d", resp.StatusCode)
+	}
+
+	var set struct {
+		Keys []jwk `json:"keys"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&set); err != nil {
+		return nil, fmt.Errorf("failed to decode JWKS: %w", err)
+	}
+
+	keys := make(map[string]any, len(set.Keys))
+	for _, key := range set.Keys {
+		if key.Use != "" && key.Use != "sig" {
+			continue
+		}
+		// Keys of unsupported types are skipped rather than failing the set
+		if public, err := key.publicKey(); err == nil {
+			keys[key.Kid] = public
+		}
+	}
+	return keys, nil
+}
+
+func (j jwk) publicKey() (any, error) {
+	switch j.Kty {
+	case "RSA":
+		n, err := decodeBigInt(j.N)
+		if err != nil {
+			return nil, err
+		}
+		e, err := decodeBigInt(j.E)
+		if err != nil {
+			return nil, err
+		}
+		return &rsa.PublicKey{N: n, E: int(e.Int64())}, nil
+	case "EC":
+		var curve elliptic.Curve
+		switch j.Crv {
+		case "P-256":
+			curve = elliptic.P256()
+		case "P-384":
+			curve = elliptic.P384()
+		case "P-521":
+			curve = elliptic.P521()
+		default:
+			return nil, fmt.Errorf("unsupported curve %q", j.Crv)
+		}
+		x, err := decodeBigInt(j.X)
+		if err != nil {
+			return nil, err
+		}
+		y, err := decodeBigInt(j.Y)
+		if err != nil {
+			return nil, err
+		}
+		return &ecdsa.PublicKey{Curve: curve, X: x, Y: y}, nil
+	}
+	return nil, fmt.Errorf("unsupported key type %q", j.Kty)
+}
+
+func decodeBigInt(s string) (*big.Int, error) {
+	b, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, err
+	}
+	return new(big.Int).SetBytes(b), nil
+}
@@ -0,0 +1,118 @@
+package auth
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// jwksServer serves an EC key as key-1 until down is set, and counts the
+// requests it gets; while block is set each request waits for it to close
+type jwksServer struct {
+	key      *ecdsa.PrivateKey
+	down     atomic.Bool
+	block    atomic.Pointer[chan struct{}]
+	requests atomic.Int32
+}
+
+func (s *jwksServer) ServeHTTP(w http.ResponseWriter, _ *http.Request) {
+	s.requests.Add(1)
+	if block := s.block.Load(); block != nil {
+		<-*block
+	}
+	if s.down.Load() {
+		w.WriteHeader(http.StatusServiceUnavailable)
+		return
+	}
+	_ = json.NewEncoder(w).Encode(map[string]any{"keys": []map[string]string{{
+		"kid": "key-1",
+		"kty": "EC",
+		"crv": "P-256",
+		"x":   base64.RawURLEncoding.EncodeToString(s.key.X.Bytes()),
+		"y":   base64.RawURLEncoding.EncodeToString(s.key.Y.Bytes()),
+	}}})
+}
+
+func newJWKSServer(t *testing.T) (*jwksServer, *JWKS, *time.Time) {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	server := &jwksServer{key: key}
+	srv := httptest.NewServer(server)
+	t.Cleanup(srv.Close)
+
+	now := time.Now()
+	jwks := NewJWKS(srv.URL, time.Second)
+	jwks.now = func() time.Time { return now }
+	return server, jwks, &now
+}
+
+func TestJWKSServesLastGoodSetWhileEndpointIsDown(t *testing.T) {
+	server, jwks, now := newJWKSServer(t)
+	ctx := context.Background()
+
+	_, err := jwks.Key(ctx, "key-1")
+	require.NoError(t, err)
+
+	server.down.Store(true)
+	*now = now.Add(2 * jwksRefreshInterval)
+	_, err = jwks.Key(ctx, "key-2")
+	assert.Error(t, err)
+	_, err = jwks.Key(ctx, "key-1")
+	assert.NoError(t, err, "known keys are served while refetches fail")
+
+	// Failed attempts are rate limited like successful ones
+	for range 3 {
+		_, err = jwks.Key(ctx, "key-2")
+		assert.Error(t, err)
+	}
+	assert.Equal(t, int32(2), server.requests.Load())
+
+	// Until the set expires
+	*now = now.Add(jwksMaxAge)
+	_, err = jwks.Key(ctx, "key-1")
+	assert.Error(t, err)
+	assert.Equal(t, int32(3), server.requests.Load())
+}
+
+func TestJWKSFetchesOutsideTheLock(t *testing.T) {
+	server, jwks, now := newJWKSServer(t)
+	ctx := context.Background()
+	_, err := jwks.Key(ctx, "key-1")
+	require.NoError(t, err)
+
+	block := make(chan struct{})
+	server.block.Store(&block)
+	*now = now.Add(2 * jwksRefreshInterval)
+	refetched := make(chan error, 1)
+	go func() {
+		_, err := jwks.Key(ctx, "key-2")
+		refetched <- err
+	}()
+	require.Eventually(t, func() bool { return server.requests.Load() == 2 }, time.Second, time.Millisecond)
+
+	// Known keys are served while the refetch hangs
+	_, err = jwks.Key(ctx, "key-1")
+	assert.NoError(t, err)
+
+	// Callers needing the refetch wait for it rather than starting another
+	waitCtx, cancel := context.WithTimeout(ctx, 20*time.Millisecond)
+	defer cancel()
+	_, err = jwks.Key(waitCtx, "key-3")
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+
+	close(block)
+	assert.ErrorIs(t, <-refetched, ErrUnknownKey)
+	assert.Equal(t, int32(2), server.requests.Load())
+}
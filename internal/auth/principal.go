@@ -0,0 +1,58 @@
+package auth
+
+import "context"
+
+// SystemActor is recorded as the actor for changes made without an
+// authenticated principal (seed data, background jobs, unauthenticated demos)
+const SystemActor = "system"
+
+// RoleAdmin grants access to administrative data such as audit fields
+const RoleAdmin = "admin"
+
+// Principal is the authenticated caller of a request
+type Principal struct {
+	Subject string
+	Roles   []string
+}
+
+// HasRole reports whether the principal has the given role
+func (p Principal) HasRole(role string) bool {
+	for _, r := range p.Roles {
+		if r == role {
+			return true
+		}
+	}
+	return false
+}
+
+// IsAdmin reports whether the principal has the admin role
+func (p Principal) IsAdmin() bool {
+	return p.HasRole(RoleAdmin)
+}
+
+type principalKey struct{}
+
+// WithPrincipal returns a copy of ctx carrying p
+func WithPrincipal(ctx context.Context, p Principal) context.Context {
+	return context.WithValue(ctx, principalKey{}, p)
+}
+
+// FromContext returns the principal stored in ctx, if any
+func FromContext(ctx context.Context) (Principal, bool) {
+	p, ok := ctx.Value(principalKey{}).(Principal)
+	return p, ok && p.Subject != ""
+}
+
+// Actor returns the subject of the principal in ctx, or SystemActor
+func Actor(ctx context.Context) string {
+	if p, ok := FromContext(ctx); ok {
+		return p.Subject
+	}
+	return SystemActor
+}
+
+// IsAdmin reports whether ctx carries an admin principal
+func IsAdmin(ctx context.Context) bool {
+	p, ok := FromContext(ctx)
+	return ok && p.IsAdmin()
+}
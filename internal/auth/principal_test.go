@@ -0,0 +1,30 @@
+package auth
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestActor(t *testing.T) {
+	assert.Equal(t, SystemActor, Actor(context.Background()))
+	assert.False(t, IsAdmin(context.Background()))
+
+	ctx := WithPrincipal(context.Background(), Principal{Subject: "alice", Roles: []string{"editor", RoleAdmin}})
+	assert.Equal(t, "alice", Actor(ctx))
+	assert.True(t, IsAdmin(ctx))
+
+	// A principal without a subject is treated as anonymous
+	ctx = WithPrincipal(context.Background(), Principal{Roles: []string{RoleAdmin}})
+	assert.Equal(t, SystemActor, Actor(ctx))
+	assert.False(t, IsAdmin(ctx))
+}
+
+func TestGrants(t *testing.T) {
+	assert.True(t, Principal{Subject: "ann", Roles: []string{RoleUsersWrite}}.Grants(RoleUsersWrite))
+	assert.False(t, Principal{Subject: "ann", Roles: []string{RoleUsersWrite}}.Grants(RolePostsWrite))
+	assert.True(t, Principal{Subject: "ann", Roles: []string{"posts:*"}}.Grants(RolePostsWrite))
+	assert.True(t, Principal{Subject: "ann", Roles: []string{RoleAdmin}}.Grants(RolePostsWrite))
+	assert.False(t, Principal{Subject: "ann"}.Grants(RoleUsersWrite))
+}
@@ -0,0 +1,22 @@
+package auth
+
+import "strings"
+
+// Roles required by the API's routes. A role is resource:action; a
+// principal with resource:* has every action on the resource, and admins
+// have every role
+const (
+	RoleUsersWrite = "users:write"
+	RolePostsWrite = "posts:write"
+	RoleRolesWrite = "roles:write"
+)
+
+// Grants reports whether the principal's roles include role, directly, by a
+// resource:* wildcard or by being an admin
+func (p Principal) Grants(role string) bool {
+	if p.IsAdmin() || p.HasRole(role) {
+		return true
+	}
+	resource, _, ok := strings.Cut(role, ":")
+	return ok && p.HasRole(resource+":*")
+}
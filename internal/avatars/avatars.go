@@ -0,0 +1,41 @@
+// Package avatars stores user avatars in an S3-compatible object store. An
+// upload is streamed from the multipart request to the store as it arrives,
+// so only the store client's part buffer is held in memory, whatever the
+// size of the file.
+package avatars
+
+import (
+	"context"
+	"io"
+	"strconv"
+)
+
+// contentTypes are the accepted image types; the type declared for the
+// file must match the one sniffed from its first bytes
+var contentTypes = map[string]bool{
+	"image/png":  true,
+	"image/jpeg": true,
+	"image/gif":  true,
+	"image/webp": true,
+}
+
+// Object is a stored avatar
+type Object struct {
+	Key         string `json:"key"`
+	ContentType string `json:"content_type"`
+	Size        int64  `json:"size"`
+	ETag        string `json:"etag"`
+}
+
+// Storage writes avatars to an object store
+type Storage interface {
+	// Put stores the contents of body under key, replacing any object
+	// there. The size of body is not known in advance
+	Put(ctx context.Context, key string, body io.Reader, contentType string) (*Object, error)
+}
+
+// Key returns the object key of the avatar of the user with userID; a new
+// upload replaces the previous avatar
+func Key(userID int) string {
+	return "users/" + strconv.Itoa(userID) + "/avatar"
+}
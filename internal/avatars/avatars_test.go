@@ -0,0 +1,176 @@
+package avatars
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"image"
+	"image/png"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"net/textproto"
+	"strings"
+	"testing"
+
+	"arquivolivre.com.br/otel/internal/middleware"
+	"arquivolivre.com.br/otel/internal/models"
+	"arquivolivre.com.br/otel/pkg/apperrors"
+	"arquivolivre.com.br/otel/pkg/oteltest"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/attribute"
+)
+
+// memStorage keeps the stored objects in memory
+type memStorage struct {
+	objects map[string][]byte
+}
+
+func (s *memStorage) Put(_ context.Context, key string, body io.Reader, contentType string) (*Object, error) {
+	data, err := io.ReadAll(body)
+	if err != nil {
+		return nil, err
+	}
+	s.objects[key] = data
+	return &Object{Key: key, ContentType: contentType, Size: int64(len(data)), ETag: "etag"}, nil
+}
+
+// users knows the user with ID 1 only
+type users struct{}
+
+func (users) GetByID(_ context.Context, id int) (*models.User, error) {
+	if id != 1 {
+		return nil, apperrors.NotFound("user not found")
+	}
+	return &models.User{ID: id}, nil
+}
+
+func pngImage(t *testing.T) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	require.NoError(t, png.Encode(&buf, image.NewRGBA(image.Rect(0, 0, 64, 64))))
+	return buf.Bytes()
+}
+
+// upload sends data as the avatar file of a multipart form declared as
+// contentType
+func upload(t *testing.T, r http.Handler, path, contentType string, data []byte) *httptest.ResponseRecorder {
+	t.Helper()
+	var body bytes.Buffer
+	form := multipart.NewWriter(&body)
+	require.NoError(t, form.WriteField("comment", "ignored"))
+	header := textproto.MIMEHeader{}
+	header.Set("Content-Disposition", `form-data; name="avatar"; filename="me.png"`)
+	header.Set("Content-Type", contentType)
+	part, err := form.CreatePart(header)
+	require.NoError(t, err)
+	_, _ = part.Write(data)
+	require.NoError(t, form.Close())
+
+	w := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodPost, path, &body)
+	req.Header.Set("Content-Type", form.FormDataContentType())
+	r.ServeHTTP(w, req)
+	return w
+}
+
+func TestUploadAvatar(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	rec := oteltest.Install(t)
+	storage := &memStorage{objects: map[string][]byte{}}
+	image := pngImage(t)
+
+	r := gin.New()
+	r.Use(middleware.NewTelemetryMiddleware("test-service").GinMiddleware())
+	r.Use(middleware.ErrorHandler())
+	NewHandler(storage, users{}, int64(len(image))).Register(r.Group("/api"), nil)
+
+	w := upload(t, r, "/api/users/1/avatar", "image/png", image)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	var resp struct {
+		Data Object `json:"data"`
+	}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	assert.Equal(t, Object{Key: "users/1/avatar", ContentType: "image/png", Size: int64(len(image)), ETag: "etag"}, resp.Data)
+	assert.Equal(t, image, storage.objects["users/1/avatar"])
+	rec.AssertHistogramCount(t, "user.avatar.size", []attribute.KeyValue{attribute.String("content_type", "image/png")}, 1)
+
+	// The declared type must be accepted and match the content
+	w = upload(t, r, "/api/users/1/avatar", "image/svg+xml", image)
+	assert.Equal(t, http.StatusUnsupportedMediaType, w.Code, w.Body.String())
+	w = upload(t, r, "/api/users/1/avatar", "image/jpeg", image)
+	assert.Equal(t, http.StatusUnsupportedMediaType, w.Code, w.Body.String())
+
+	w = upload(t, r, "/api/users/1/avatar", "image/png", append(image, 0))
+	assert.Equal(t, http.StatusRequestEntityTooLarge, w.Code, w.Body.String())
+
+	w = upload(t, r, "/api/users/2/avatar", "image/png", image)
+	assert.Equal(t, http.StatusNotFound, w.Code, w.Body.String())
+
+	w = httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodPost, "/api/users/1/avatar", strings.NewReader(`{}`))
+	req.Header.Set("Content-Type", "application/json")
+	r.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusUnsupportedMediaType, w.Code, w.Body.String())
+	assert.Len(t, storage.objects, 1)
+}
+
+func TestS3StoragePutsObject(t *testing.T) {
+	rec := oteltest.Install(t)
+
+	// An object of unknown size is uploaded in parts
+	var stored []byte
+	var path, contentType string
+	store := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		query := r.URL.Query()
+		switch {
+		case r.Method == http.MethodPost && query.Has("uploads"):
+			path, contentType = r.URL.Path, r.Header.Get("Content-Type")
+			_, _ = io.WriteString(w, `<InitiateMultipartUploadResult><Bucket>avatars</Bucket><Key>users/7/avatar</Key><UploadId>u1</UploadId></InitiateMultipartUploadResult>`)
+		case r.Method == http.MethodPut && query.Get("uploadId") == "u1":
+			part, _ := io.ReadAll(r.Body)
+			stored = append(stored, part...)
+			w.Header().Set("ETag", `"part1"`)
+		case r.Method == http.MethodPost && query.Get("uploadId") == "u1":
+			_, _ = io.WriteString(w, `<CompleteMultipartUploadResult><Bucket>avatars</Bucket><Key>users/7/avatar</Key><ETag>"abc123"</ETag></CompleteMultipartUploadResult>`)
+		default:
+			http.Error(w, "unexpected request", http.StatusBadRequest)
+		}
+	}))
+	defer store.Close()
+
+	storage, err := NewS3Storage(S3Config{
+		Endpoint:  strings.TrimPrefix(store.URL, "http://"),
+		Bucket:    "avatars",
+		Region:    "us-east-1",
+		AccessKey: "access",
+		SecretKey: "secret",
+	})
+	require.NoError(t, err)
+
+	object, err := storage.Put(context.Background(), Key(7), strings.NewReader("avatar bytes"), "image/png")
+	require.NoError(t, err)
+	assert.Equal(t, "/avatars/users/7/avatar", path)
+	assert.Equal(t, "image/png", contentType)
+	// Over plain HTTP the part is sent in signed chunks around the data
+	assert.Contains(t, string(stored), "\r\navatar bytes\r\n")
+	assert.Equal(t, "abc123", object.ETag)
+
+	span := rec.AssertSpan(t, "S3.PutObject",
+		attribute.String("rpc.method", "PutObject"),
+		attribute.String("aws.s3.bucket", "avatars"),
+		attribute.String("aws.s3.key", "users/7/avatar"),
+	)
+	// Each HTTP request of the upload is a client span below it
+	var children int
+	for _, s := range rec.Spans() {
+		if s.Parent().SpanID() == span.SpanContext().SpanID() {
+			children++
+		}
+	}
+	assert.Positive(t, children)
+}
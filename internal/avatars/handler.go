@@ -0,0 +1,176 @@
+package avatars
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"strconv"
+
+	"arquivolivre.com.br/otel/internal/middleware"
+	"arquivolivre.com.br/otel/internal/models"
+	"arquivolivre.com.br/otel/pkg/utils"
+
+	"github.com/gin-gonic/gin"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// FormField is the multipart field carrying the avatar
+const FormField = "avatar"
+
+// MultipartOverhead is the room left in a request body for the multipart
+// framing and other form fields around an avatar of the maximum size
+const MultipartOverhead = 64 << 10
+
+// sniffLen is how much of the file http.DetectContentType looks at
+const sniffLen = 512
+
+// Users looks up the owners of avatars
+type Users interface {
+	GetByID(ctx context.Context, id int) (*models.User, error)
+}
+
+// Handler receives avatar uploads over HTTP
+type Handler struct {
+	storage  Storage
+	users    Users
+	maxBytes int64
+	sizes    metric.Int64Histogram
+}
+
+// NewHandler creates a handler storing the avatars of users in storage,
+// each of at most maxBytes
+func NewHandler(storage Storage, users Users, maxBytes int64) *Handler {
+	sizes, _ := otel.Meter("otel-example-api").Int64Histogram(
+		"user.avatar.size",
+		metric.WithDescription("Size of the uploaded avatars"),
+		metric.WithUnit("By"),
+		metric.WithExplicitBucketBoundaries(16<<10, 64<<10, 256<<10, 512<<10, 1<<20, 2<<20, 5<<20),
+	)
+	return &Handler{storage: storage, users: users, maxBytes: maxBytes, sizes: sizes}
+}
+
+// Register mounts the upload endpoint on root behind the uploads middleware
+func (h *Handler) Register(root *gin.RouterGroup, uploads []gin.HandlerFunc) {
+	root.Group("", uploads...).POST("/users/:id/avatar", h.UploadAvatar)
+}
+
+// UploadAvatar streams the avatar field of a multipart/form-data request to
+// the storage. The declared type of the file must be an accepted image type
+// and match the type sniffed from its first bytes
+func (h *Handler) UploadAvatar(c *gin.Context) {
+	ctx := c.Request.Context()
+	span := trace.SpanFromContext(ctx)
+
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		_ = c.Error(middleware.BadRequestError("Invalid user ID"))
+		return
+	}
+	span.SetAttributes(attribute.Int("user.id", id))
+	if _, err := h.users.GetByID(ctx, id); err != nil {
+		_ = c.Error(middleware.FromError(err, "Failed to retrieve user"))
+		return
+	}
+
+	form, err := c.Request.MultipartReader()
+	if err != nil {
+		_ = c.Error(middleware.NewAPIError(http.StatusUnsupportedMediaType, models.ErrCodeInvalidRequest,
+			"Content-Type must be multipart/form-data"))
+		return
+	}
+	part, err := nextFile(form)
+	if err != nil {
+		_ = c.Error(middleware.BadRequestError("The avatar must be sent as a file in the " + FormField + " field"))
+		return
+	}
+	defer func() { _ = part.Close() }()
+
+	contentType, _, _ := mime.ParseMediaType(part.Header.Get("Content-Type"))
+	if !contentTypes[contentType] {
+		_ = c.Error(unsupportedType())
+		return
+	}
+	span.SetAttributes(attribute.String("avatar.content_type", contentType))
+
+	body := &limitedPart{body: http.MaxBytesReader(c.Writer, part, h.maxBytes)}
+	head := make([]byte, sniffLen)
+	n, err := io.ReadFull(body, head)
+	switch {
+	case body.exceeded:
+		_ = c.Error(h.tooLarge())
+		return
+	case err != nil && !errors.Is(err, io.EOF) && !errors.Is(err, io.ErrUnexpectedEOF):
+		_ = c.Error(middleware.BadRequestError("Failed to read the avatar"))
+		return
+	case n == 0:
+		_ = c.Error(middleware.BadRequestError("The avatar is empty"))
+		return
+	}
+	if sniffed := http.DetectContentType(head[:n]); sniffed != contentType {
+		span.SetAttributes(attribute.String("avatar.sniffed_content_type", sniffed))
+		_ = c.Error(unsupportedType())
+		return
+	}
+
+	object, err := h.storage.Put(ctx, Key(id), io.MultiReader(bytes.NewReader(head[:n]), body), contentType)
+	if body.exceeded {
+		_ = c.Error(h.tooLarge())
+		return
+	}
+	if err != nil {
+		_ = c.Error(middleware.InternalError("Failed to store avatar", err))
+		return
+	}
+
+	span.SetAttributes(attribute.Int64("avatar.size", object.Size))
+	h.sizes.Record(ctx, object.Size, metric.WithAttributes(attribute.String("content_type", contentType)))
+	utils.SendSuccess(c, object, "Avatar uploaded successfully")
+}
+
+// nextFile skips to the file part of FormField
+func nextFile(form *multipart.Reader) (*multipart.Part, error) {
+	for {
+		part, err := form.NextPart()
+		if err != nil {
+			return nil, err
+		}
+		if part.FormName() == FormField && part.FileName() != "" {
+			return part, nil
+		}
+		_ = part.Close()
+	}
+}
+
+func unsupportedType() *middleware.APIError {
+	return middleware.NewAPIError(http.StatusUnsupportedMediaType, models.ErrCodeInvalidRequest,
+		"The avatar must be a PNG, JPEG, GIF or WebP image")
+}
+
+func (h *Handler) tooLarge() *middleware.APIError {
+	return middleware.NewAPIError(http.StatusRequestEntityTooLarge, models.ErrCodePayloadTooLarge,
+		fmt.Sprintf("The avatar must be at most %d bytes", h.maxBytes))
+}
+
+// limitedPart remembers that the file went over its limit, which the
+// storage may report as a failed upload
+type limitedPart struct {
+	body     io.Reader
+	exceeded bool
+}
+
+func (p *limitedPart) Read(b []byte) (int, error) {
+	n, err := p.body.Read(b)
+	var tooLarge *http.MaxBytesError
+	if errors.As(err, &tooLarge) {
+		p.exceeded = true
+	}
+	return n, err
+}
@@ -0,0 +1,90 @@
+package avatars
+
+import (
+	"context"
+	"fmt"
+	"io"
+
+	"arquivolivre.com.br/otel/internal/client"
+
+	"github.com/minio/minio-go/v7"
+	"github.com/minio/minio-go/v7/pkg/credentials"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// partSize is the buffer of each part of an upload of unknown size, the
+// smallest part S3 accepts
+const partSize = 5 << 20
+
+// S3Config locates the bucket avatars are stored in
+type S3Config struct {
+	// Endpoint is the host:port of the store, e.g. minio:9000
+	Endpoint  string
+	Bucket    string
+	Region    string
+	AccessKey string
+	SecretKey string
+	UseSSL    bool
+}
+
+// S3Storage stores avatars in a bucket of an S3-compatible store. Each Put
+// is an S3.PutObject span, with a client span for every HTTP request the
+// upload makes below it
+type S3Storage struct {
+	client *minio.Client
+	bucket string
+	tracer trace.Tracer
+}
+
+var _ Storage = (*S3Storage)(nil)
+
+// NewS3Storage creates a storage on the bucket of cfg, which must exist
+func NewS3Storage(cfg S3Config) (*S3Storage, error) {
+	minioClient, err := minio.New(cfg.Endpoint, &minio.Options{
+		Creds:  credentials.NewStaticV4(cfg.AccessKey, cfg.SecretKey, ""),
+		Secure: cfg.UseSSL,
+		Region: cfg.Region,
+		// The store client retries on its own, so the HTTP client must not
+		Transport: client.New(client.WithRetries(0, 0)).Transport,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create S3 client: %w", err)
+	}
+	return &S3Storage{
+		client: minioClient,
+		bucket: cfg.Bucket,
+		tracer: otel.Tracer("avatar-storage"),
+	}, nil
+}
+
+// Put uploads body in parts of partSize, so at most one part is buffered
+func (s *S3Storage) Put(ctx context.Context, key string, body io.Reader, contentType string) (*Object, error) {
+	ctx, span := s.tracer.Start(ctx, "S3.PutObject", trace.WithAttributes(
+		attribute.String("rpc.system", "aws-api"),
+		attribute.String("rpc.service", "S3"),
+		attribute.String("rpc.method", "PutObject"),
+		attribute.String("aws.s3.bucket", s.bucket),
+		attribute.String("aws.s3.key", key),
+		attribute.String("server.address", s.client.EndpointURL().Host),
+	))
+	defer span.End()
+
+	info, err := s.client.PutObject(ctx, s.bucket, key, body, -1, minio.PutObjectOptions{
+		ContentType: contentType,
+		PartSize:    partSize,
+	})
+	if err != nil {
+		if status := minio.ToErrorResponse(err).StatusCode; status != 0 {
+			span.SetAttributes(attribute.Int("http.response.status_code", status))
+		}
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+		return nil, fmt.Errorf("failed to store avatar: %w", err)
+	}
+
+	span.SetAttributes(attribute.Int64("aws.s3.object.size", info.Size))
+	return &Object{Key: key, ContentType: contentType, Size: info.Size, ETag: info.ETag}, nil
+}
@@ -0,0 +1,106 @@
+// Package buildinfo describes the running build. The variables are set at
+// link time, e.g.
+//
+//	go build -ldflags "-X arquivolivre.com.br/otel/internal/buildinfo.Version=1.2.3"
+package buildinfo
+
+import (
+	"context"
+	"fmt"
+	"runtime"
+	"runtime/debug"
+	"sync"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+)
+
+// Set with -ldflags -X; Commit and Date fall back to the VCS stamp that the
+// go command embeds in binaries built from a checkout
+var (
+	Version = "dev"
+	Commit  = ""
+	Date    = ""
+)
+
+// Info is the build that is running
+type Info struct {
+	Version   string `json:"version"`
+	Commit    string `json:"commit"`
+	Date      string `json:"date"`
+	GoVersion string `json:"go_version"`
+}
+
+var (
+	once sync.Once
+	info Info
+)
+
+// Get returns the build information
+func Get() Info {
+	once.Do(func() {
+		info = Info{Version: Version, Commit: Commit, Date: Date, GoVersion: runtime.Version()}
+		if bi, ok := debug.ReadBuildInfo(); ok {
+			for _, setting := range bi.Settings {
+				switch {
+				case setting.Key == "vcs.revision" && info.Commit == "":
+					info.Commit = setting.Value
+				case setting.Key == "vcs.time" && info.Date == "":
+					info.Date = setting.Value
+				}
+			}
+		}
+		if info.Commit == "" {
+			info.Commit = "unknown"
+		}
+		if info.Date == "" {
+			info.Date = "unknown"
+		}
+	})
+	return info
+}
+
+// Attributes returns the build information as resource attributes; the
+// version is set as service.version by the telemetry resource
+func (i Info) Attributes() []attribute.KeyValue {
+	return []attribute.KeyValue{
+		attribute.String("vcs.revision", i.Commit),
+		attribute.String("service.build.commit", i.Commit),
+		attribute.String("service.build.date", i.Date),
+		attribute.String("service.build.go_version", i.GoVersion),
+	}
+}
+
+// Fields returns the build information as log fields
+func (i Info) Fields() map[string]interface{} {
+	return map[string]interface{}{
+		"version":    i.Version,
+		"commit":     i.Commit,
+		"build_date": i.Date,
+		"go_version": i.GoVersion,
+	}
+}
+
+// RegisterMetric reports the service.build_info gauge, always 1, labelled
+// with the build information so dashboards can show what code is running
+func RegisterMetric(meter metric.Meter) error {
+	i := Get()
+	attrs := metric.WithAttributes(
+		attribute.String("version", i.Version),
+		attribute.String("commit", i.Commit),
+		attribute.String("build_date", i.Date),
+		attribute.String("go_version", i.GoVersion),
+	)
+	_, err := meter.Int64ObservableGauge(
+		"service.build_info",
+		metric.WithDescription("Build information of the running service; always 1"),
+		metric.WithInt64Callback(func(_ context.Context, o metric.Int64Observer) error {
+			o.Observe(1, attrs)
+			return nil
+		}),
+	)
+	if err != nil {
+		return fmt.Errorf("failed to create build info metric: %w", err)
+	}
+	return nil
+}
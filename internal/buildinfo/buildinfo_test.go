@@ -0,0 +1,41 @@
+package buildinfo
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/attribute"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/metric/metricdata"
+)
+
+func TestGetFillsDefaults(t *testing.T) {
+	i := Get()
+	assert.Equal(t, Version, i.Version)
+	assert.NotEmpty(t, i.Commit)
+	assert.NotEmpty(t, i.Date)
+	assert.NotEmpty(t, i.GoVersion)
+	assert.Contains(t, i.Attributes(), attribute.String("service.build.commit", i.Commit))
+	assert.Contains(t, i.Attributes(), attribute.String("vcs.revision", i.Commit))
+}
+
+func TestRegisterMetric(t *testing.T) {
+	reader := sdkmetric.NewManualReader()
+	provider := sdkmetric.NewMeterProvider(sdkmetric.WithReader(reader))
+
+	require.NoError(t, RegisterMetric(provider.Meter("test")))
+
+	var rm metricdata.ResourceMetrics
+	require.NoError(t, reader.Collect(context.Background(), &rm))
+	require.Len(t, rm.ScopeMetrics, 1)
+	require.Len(t, rm.ScopeMetrics[0].Metrics, 1)
+	m := rm.ScopeMetrics[0].Metrics[0]
+	assert.Equal(t, "service.build_info", m.Name)
+	gauge := m.Data.(metricdata.Gauge[int64])
+	require.Len(t, gauge.DataPoints, 1)
+	assert.Equal(t, int64(1), gauge.DataPoints[0].Value)
+	version, _ := gauge.DataPoints[0].Attributes.Value("version")
+	assert.Equal(t, Version, version.AsString())
+}
@@ -0,0 +1,231 @@
+// Package chaos injects latency, server errors and database failures into
+// selected routes so alerting and error tracking can be exercised on demand
+package chaos
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand/v2"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"arquivolivre.com.br/otel/internal/middleware"
+	"arquivolivre.com.br/otel/internal/models"
+
+	"github.com/gin-gonic/gin"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// AllRoutes is the route of a rule applied to every route without its own rule
+const AllRoutes = "*"
+
+// Fault kinds recorded on spans and metrics
+const (
+	FaultLatency   = "latency"
+	FaultError     = "error"
+	FaultDBFailure = "db_failure"
+)
+
+// ErrInjectedDBFailure is returned by the store instead of running a query
+var ErrInjectedDBFailure = errors.New("chaos: injected database failure")
+
+// Rule configures the faults injected into one route; rates are fractions of
+// requests between 0 and 1
+type Rule struct {
+	Route         string  `json:"route" binding:"required"`
+	LatencyRate   float64 `json:"latency_rate"`
+	LatencyMS     int     `json:"latency_ms"`
+	ErrorRate     float64 `json:"error_rate"`
+	ErrorStatus   int     `json:"error_status,omitempty"`
+	DBFailureRate float64 `json:"db_failure_rate"`
+}
+
+// Validate checks the rates, latency and status of the rule
+func (r Rule) Validate() error {
+	for name, rate := range map[string]float64{
+		"latency_rate":    r.LatencyRate,
+		"error_rate":      r.ErrorRate,
+		"db_failure_rate": r.DBFailureRate,
+	} {
+		if rate < 0 || rate > 1 {
+			return fmt.Errorf("%s must be between 0 and 1", name)
+		}
+	}
+	if r.LatencyMS < 0 {
+		return errors.New("latency_ms must not be negative")
+	}
+	if r.ErrorStatus != 0 && (r.ErrorStatus < 500 || r.ErrorStatus > 599) {
+		return errors.New("error_status must be a 5xx status")
+	}
+	return nil
+}
+
+// Controller holds the active rules and applies them to requests
+type Controller struct {
+	mu    sync.RWMutex
+	rules map[string]Rule
+
+	// random returns a value in [0, 1); replaced in tests
+	random func() float64
+	tracer trace.Tracer
+
+	injections metric.Int64Counter
+}
+
+// NewController creates a controller with no active rules
+func NewController() (*Controller, error) {
+	meter := otel.Meter("chaos")
+	injections, err := meter.Int64Counter(
+		"chaos_injections_total",
+		metric.WithDescription("Total number of faults injected by chaos rules"),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create injections metric: %w", err)
+	}
+
+	return &Controller{
+		rules:      make(map[string]Rule),
+		random:     rand.Float64,
+		tracer:     otel.Tracer("chaos"),
+		injections: injections,
+	}, nil
+}
+
+// Set adds or replaces the rule for its route
+func (ctl *Controller) Set(rule Rule) error {
+	if err := rule.Validate(); err != nil {
+		return err
+	}
+	ctl.mu.Lock()
+	defer ctl.mu.Unlock()
+	ctl.rules[rule.Route] = rule
+	return nil
+}
+
+// Delete removes the rule for route and reports whether one existed
+func (ctl *Controller) Delete(route string) bool {
+	ctl.mu.Lock()
+	defer ctl.mu.Unlock()
+	_, ok := ctl.rules[route]
+	delete(ctl.rules, route)
+	return ok
+}
+
+// Reset removes every rule
+func (ctl *Controller) Reset() {
+	ctl.mu.Lock()
+	defer ctl.mu.Unlock()
+	ctl.rules = make(map[string]Rule)
+}
+
+// Rules returns the active rules sorted by route
+func (ctl *Controller) Rules() []Rule {
+	ctl.mu.RLock()
+	defer ctl.mu.RUnlock()
+	rules := make([]Rule, 0, len(ctl.rules))
+	for _, rule := range ctl.rules {
+		rules = append(rules, rule)
+	}
+	sort.Slice(rules, func(i, j int) bool { return rules[i].Route < rules[j].Route })
+	return rules
+}
+
+func (ctl *Controller) ruleFor(route string) (Rule, bool) {
+	ctl.mu.RLock()
+	defer ctl.mu.RUnlock()
+	if rule, ok := ctl.rules[route]; ok {
+		return rule, true
+	}
+	rule, ok := ctl.rules[AllRoutes]
+	return rule, ok
+}
+
+// Middleware injects the faults configured for the matched route. It must run
+// after middleware.ErrorHandler so injected errors are rendered like any other
+func (ctl *Controller) Middleware(skipPrefixes ...string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		route := c.FullPath()
+		if route == "" || hasAnyPrefix(route, skipPrefixes) {
+			c.Next()
+			return
+		}
+		rule, ok := ctl.ruleFor(route)
+		if !ok {
+			c.Next()
+			return
+		}
+
+		ctx := c.Request.Context()
+		if rule.LatencyMS > 0 && ctl.random() < rule.LatencyRate {
+			delay := time.Duration(rule.LatencyMS) * time.Millisecond
+			ctl.record(ctx, route, FaultLatency, attribute.Int64("chaos.latency_ms", int64(rule.LatencyMS)))
+			select {
+			case <-time.After(delay):
+			case <-ctx.Done():
+			}
+		}
+
+		if ctl.random() < rule.ErrorRate {
+			status := rule.ErrorStatus
+			if status == 0 {
+				status = http.StatusInternalServerError
+			}
+			ctl.record(ctx, route, FaultError, attribute.Int("http.response.status_code", status))
+			_ = c.Error(injectedError(status))
+			c.Abort()
+			return
+		}
+
+		if ctl.random() < rule.DBFailureRate {
+			c.Request = c.Request.WithContext(context.WithValue(ctx, dbFailureKey{}, route))
+		}
+		c.Next()
+	}
+}
+
+func (ctl *Controller) record(ctx context.Context, route, fault string, attrs ...attribute.KeyValue) {
+	attrs = append(attrs, attribute.String("chaos.fault", fault), attribute.String("http.route", route))
+	trace.SpanFromContext(ctx).AddEvent("chaos.injected", trace.WithAttributes(attrs...))
+	ctl.injections.Add(ctx, 1, metric.WithAttributes(
+		attribute.String("fault", fault),
+		attribute.String("route", route),
+	))
+}
+
+func injectedError(status int) *middleware.APIError {
+	code := models.ErrCodeInternal
+	if status == http.StatusServiceUnavailable {
+		code = models.ErrCodeServiceUnavailable
+	}
+	return middleware.NewAPIError(status, code, "Injected failure").
+		WithCause(fmt.Errorf("chaos: injected %d response", status))
+}
+
+type dbFailureKey struct{}
+
+// dbFailure returns ErrInjectedDBFailure when the middleware chose to fail
+// database calls for the request in ctx
+func (ctl *Controller) dbFailure(ctx context.Context) error {
+	route, ok := ctx.Value(dbFailureKey{}).(string)
+	if !ok {
+		return nil
+	}
+	ctl.record(ctx, route, FaultDBFailure)
+	return ErrInjectedDBFailure
+}
+
+func hasAnyPrefix(s string, prefixes []string) bool {
+	for _, prefix := range prefixes {
+		if strings.HasPrefix(s, prefix) {
+			return true
+		}
+	}
+	return false
+}
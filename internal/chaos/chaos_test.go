@@ -0,0 +1,169 @@
+package chaos
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"arquivolivre.com.br/otel/internal/middleware"
+	"arquivolivre.com.br/otel/internal/models"
+	"arquivolivre.com.br/otel/internal/repository"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type fakeStore struct{ calls int }
+
+func (s *fakeStore) GetAll(context.Context, models.UserFilter, int, int) ([]models.User, error) {
+	s.calls++
+	return []models.User{}, nil
+}
+func (s *fakeStore) Stream(context.Context, models.UserFilter, func(*models.User) error) error {
+	return nil
+}
+func (s *fakeStore) GetByID(context.Context, int) (*models.User, error) {
+	s.calls++
+	return &models.User{}, nil
+}
+func (s *fakeStore) Create(context.Context, models.CreateUserRequest) (*models.User, error) {
+	return nil, nil
+}
+func (s *fakeStore) CreateBatch(context.Context, []models.CreateUserRequest) ([]repository.BatchResult, error) {
+	return nil, nil
+}
+func (s *fakeStore) Update(context.Context, int, models.UpdateUserRequest) (*models.User, error) {
+	return nil, nil
+}
+func (s *fakeStore) Delete(context.Context, int) error                        { return nil }
+func (s *fakeStore) Count(context.Context, models.UserFilter) (int, error)    { return 0, nil }
+func (s *fakeStore) GetByEmail(context.Context, string) (*models.User, error) { return nil, nil }
+
+func newTestRouter(t *testing.T, always bool) (*gin.Engine, *Controller, *fakeStore) {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+	ctl, err := NewController()
+	require.NoError(t, err)
+	ctl.random = func() float64 {
+		if always {
+			return 0
+		}
+		return 0.999
+	}
+
+	store := &fakeStore{}
+	users := ctl.WrapUserStore(store)
+
+	r := gin.New()
+	r.Use(middleware.ErrorHandler())
+	r.Use(ctl.Middleware("/admin/"))
+	r.GET("/users/:id", func(c *gin.Context) {
+		if _, err := users.GetByID(c.Request.Context(), 1); err != nil {
+			_ = c.Error(middleware.InternalError("Failed to retrieve user", err))
+			return
+		}
+		c.Status(http.StatusOK)
+	})
+	admin := r.Group("/admin", middleware.AdminOnly("secret"))
+	NewHandler(ctl).Register(admin.Group("/chaos"))
+	return r, ctl, store
+}
+
+func serve(r http.Handler, method, path, body, token string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, path, strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	return w
+}
+
+func TestRuleValidate(t *testing.T) {
+	assert.NoError(t, Rule{Route: "*", ErrorRate: 1, ErrorStatus: 503}.Validate())
+	assert.Error(t, Rule{Route: "*", ErrorRate: 1.5}.Validate())
+	assert.Error(t, Rule{Route: "*", LatencyMS: -1}.Validate())
+	assert.Error(t, Rule{Route: "*", ErrorStatus: 404}.Validate())
+}
+
+func TestMiddlewareWithoutRulePassesThrough(t *testing.T) {
+	r, _, store := newTestRouter(t, true)
+
+	w := serve(r, http.MethodGet, "/users/1", "", "")
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, 1, store.calls)
+}
+
+func TestMiddlewareInjectsError(t *testing.T) {
+	r, ctl, store := newTestRouter(t, true)
+	require.NoError(t, ctl.Set(Rule{Route: "/users/:id", ErrorRate: 1, ErrorStatus: http.StatusServiceUnavailable}))
+
+	w := serve(r, http.MethodGet, "/users/1", "", "")
+
+	assert.Equal(t, http.StatusServiceUnavailable, w.Code)
+	assert.Contains(t, w.Body.String(), models.ErrCodeServiceUnavailable)
+	assert.Zero(t, store.calls)
+}
+
+func TestMiddlewareInjectsLatency(t *testing.T) {
+	r, ctl, _ := newTestRouter(t, true)
+	require.NoError(t, ctl.Set(Rule{Route: AllRoutes, LatencyRate: 1, LatencyMS: 20}))
+
+	start := time.Now()
+	w := serve(r, http.MethodGet, "/users/1", "", "")
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.GreaterOrEqual(t, time.Since(start), 20*time.Millisecond)
+}
+
+func TestMiddlewareInjectsDBFailure(t *testing.T) {
+	r, ctl, store := newTestRouter(t, true)
+	require.NoError(t, ctl.Set(Rule{Route: "/users/:id", DBFailureRate: 1}))
+
+	w := serve(r, http.MethodGet, "/users/1", "", "")
+
+	assert.Equal(t, http.StatusInternalServerError, w.Code)
+	assert.Zero(t, store.calls)
+}
+
+func TestMiddlewareRespectsRates(t *testing.T) {
+	r, ctl, store := newTestRouter(t, false)
+	require.NoError(t, ctl.Set(Rule{Route: AllRoutes, ErrorRate: 0.5, DBFailureRate: 0.5}))
+
+	w := serve(r, http.MethodGet, "/users/1", "", "")
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, 1, store.calls)
+}
+
+func TestHandlerManagesRules(t *testing.T) {
+	r, ctl, _ := newTestRouter(t, true)
+
+	w := serve(r, http.MethodPut, "/admin/chaos", `{"route":"*","error_rate":1}`, "")
+	assert.Equal(t, http.StatusForbidden, w.Code)
+
+	w = serve(r, http.MethodPut, "/admin/chaos", `{"route":"*","error_rate":2}`, "secret")
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	w = serve(r, http.MethodPut, "/admin/chaos", `{"route":"*","error_rate":1}`, "secret")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Len(t, ctl.Rules(), 1)
+
+	// Admin routes are never faulted, so the rule can always be removed
+	w = serve(r, http.MethodGet, "/admin/chaos", "", "secret")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), `"error_rate":1`)
+
+	w = serve(r, http.MethodDelete, "/admin/chaos?route=/missing", "", "secret")
+	assert.Equal(t, http.StatusNotFound, w.Code)
+
+	w = serve(r, http.MethodDelete, "/admin/chaos?route=*", "", "secret")
+	assert.Equal(t, http.StatusNoContent, w.Code)
+	assert.Empty(t, ctl.Rules())
+}
@@ -0,0 +1,60 @@
+package chaos
+
+import (
+	"arquivolivre.com.br/otel/internal/middleware"
+	"arquivolivre.com.br/otel/pkg/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Handler exposes the controller over HTTP for administrators
+type Handler struct {
+	ctl *Controller
+}
+
+// NewHandler creates a handler for ctl
+func NewHandler(ctl *Controller) *Handler {
+	return &Handler{ctl: ctl}
+}
+
+// Register mounts the chaos endpoints on group
+func (h *Handler) Register(group *gin.RouterGroup) {
+	group.GET("", h.ListRules)
+	group.PUT("", h.SetRule)
+	group.DELETE("", h.DeleteRules)
+}
+
+// ListRules returns the active rules
+func (h *Handler) ListRules(c *gin.Context) {
+	utils.SendSuccess(c, h.ctl.Rules())
+}
+
+// SetRule adds or replaces the rule for a route
+func (h *Handler) SetRule(c *gin.Context) {
+	var rule Rule
+	if err := c.ShouldBindJSON(&rule); err != nil {
+		_ = c.Error(middleware.BadRequestError("Invalid chaos rule: " + err.Error()))
+		return
+	}
+	if err := h.ctl.Set(rule); err != nil {
+		_ = c.Error(middleware.BadRequestError("Invalid chaos rule: " + err.Error()))
+		return
+	}
+	utils.SendSuccess(c, rule)
+}
+
+// DeleteRules removes the rule for the route query parameter, or every rule
+// when no route is given
+func (h *Handler) DeleteRules(c *gin.Context) {
+	route := c.Query("route")
+	if route == "" {
+		h.ctl.Reset()
+		utils.SendNoContent(c)
+		return
+	}
+	if !h.ctl.Delete(route) {
+		_ = c.Error(middleware.NotFoundError("No chaos rule for route " + route))
+		return
+	}
+	utils.SendNoContent(c)
+}
@@ -0,0 +1,82 @@
+package chaos
+
+import (
+	"context"
+
+	"arquivolivre.com.br/otel/internal/models"
+	"arquivolivre.com.br/otel/internal/repository"
+)
+
+// userStore fails database calls of requests the middleware selected
+type userStore struct {
+	next repository.UserStore
+	ctl  *Controller
+}
+
+// WrapUserStore returns a store that injects database failures chosen by ctl
+func (ctl *Controller) WrapUserStore(next repository.UserStore) repository.UserStore {
+	return &userStore{next: next, ctl: ctl}
+}
+
+func (s *userStore) GetAll(ctx context.Context, filter models.UserFilter, limit, offset int) ([]models.User, error) {
+	if err := s.ctl.dbFailure(ctx); err != nil {
+		return nil, err
+	}
+	return s.next.GetAll(ctx, filter, limit, offset)
+}
+
+func (s *userStore) Stream(ctx context.Context, filter models.UserFilter, fn func(*models.User) error) error {
+	if err := s.ctl.dbFailure(ctx); err != nil {
+		return err
+	}
+	return s.next.Stream(ctx, filter, fn)
+}
+
+func (s *userStore) GetByID(ctx context.Context, id int) (*models.User, error) {
+	if err := s.ctl.dbFailure(ctx); err != nil {
+		return nil, err
+	}
+	return s.next.GetByID(ctx, id)
+}
+
+func (s *userStore) Create(ctx context.Context, req models.CreateUserRequest) (*models.User, error) {
+	if err := s.ctl.dbFailure(ctx); err != nil {
+		return nil, err
+	}
+	return s.next.Create(ctx, req)
+}
+
+func (s *userStore) CreateBatch(ctx context.Context, reqs []models.CreateUserRequest) ([]repository.BatchResult, error) {
+	if err := s.ctl.dbFailure(ctx); err != nil {
+		return nil, err
+	}
+	return s.next.CreateBatch(ctx, reqs)
+}
+
+func (s *userStore) Update(ctx context.Context, id int, req models.UpdateUserRequest) (*models.User, error) {
+	if err := s.ctl.dbFailure(ctx); err != nil {
+		return nil, err
+	}
+	return s.next.Update(ctx, id, req)
+}
+
+func (s *userStore) Delete(ctx context.Context, id int) error {
+	if err := s.ctl.dbFailure(ctx); err != nil {
+		return err
+	}
+	return s.next.Delete(ctx, id)
+}
+
+func (s *userStore) Count(ctx context.Context, filter models.UserFilter) (int, error) {
+	if err := s.ctl.dbFailure(ctx); err != nil {
+		return 0, err
+	}
+	return s.next.Count(ctx, filter)
+}
+
+func (s *userStore) GetByEmail(ctx context.Context, email string) (*models.User, error) {
+	if err := s.ctl.dbFailure(ctx); err != nil {
+		return nil, err
+	}
+	return s.next.GetByEmail(ctx, email)
+}
@@ -0,0 +1,390 @@
+// Package cli is the command line interface of the API binary: serve,
+// migrate and seed share config loading and telemetry init
+package cli
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"time"
+
+	"arquivolivre.com.br/otel/internal/app"
+	"arquivolivre.com.br/otel/internal/buildinfo"
+	"arquivolivre.com.br/otel/internal/config"
+	"arquivolivre.com.br/otel/internal/database"
+	"arquivolivre.com.br/otel/internal/logging"
+	"arquivolivre.com.br/otel/internal/seed"
+
+	"github.com/spf13/cobra"
+	"go.opentelemetry.io/otel"
+)
+
+// skipBootstrap marks commands that need neither config nor telemetry
+const skipBootstrap = "skip-bootstrap"
+
+// env is the state shared by the commands after bootstrap
+type env struct {
+	cfg       *config.Config
+	telemetry *config.TelemetryProvider
+}
+
+// Execute runs the CLI and exits non-zero on failure
+func Execute() {
+	e := &env{}
+	err := newRootCommand(e).Execute()
+	e.shutdown()
+	if err != nil {
+		os.Exit(1)
+	}
+}
+
+func newRootCommand(e *env) *cobra.Command {
+	serve := newServeCommand(e)
+	root := &cobra.Command{
+		Use:          "otel-example",
+		Short:        "OpenTelemetry example API",
+		Long:         "OpenTelemetry example API. Without a subcommand it runs the server.",
+		SilenceUsage: true,
+		PersistentPreRunE: func(cmd *cobra.Command, _ []string) error {
+			if cmd.Annotations[skipBootstrap] == "true" {
+				return nil
+			}
+			return e.bootstrap()
+		},
+		RunE: serve.RunE,
+	}
+	root.AddCommand(serve, newMigrateCommand(e), newSeedCommand(e), newConfigCommand(), newTelemetryCommand(e), newVersionCommand(), newHealthcheckCommand())
+	return root
+}
+
+func newServeCommand(e *env) *cobra.Command {
+	var (
+		dryRun  bool
+		timeout time.Duration
+	)
+	cmd := &cobra.Command{
+		Use:   "serve",
+		Short: "Run the HTTP server",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, _ []string) error {
+			if dryRun {
+				return e.checkTelemetry(cmd, timeout)
+			}
+			ctx, stop := signal.NotifyContext(cmd.Context(), syscall.SIGINT, syscall.SIGTERM)
+			defer stop()
+			return app.Serve(ctx, e.cfg, e.telemetry)
+		},
+	}
+	cmd.Flags().BoolVar(&dryRun, "dry-run", false, "check telemetry export like 'telemetry check' and exit instead of serving")
+	cmd.Flags().DurationVar(&timeout, "timeout", 10*time.Second, "time allowed for the dry-run exports")
+	return cmd
+}
+
+func newTelemetryCommand(e *env) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "telemetry",
+		Short: "Inspect the OpenTelemetry export pipeline",
+		Args:  cobra.NoArgs,
+	}
+	var timeout time.Duration
+	check := &cobra.Command{
+		Use:   "check",
+		Short: "Send a test span, metric and log record and report each signal",
+		Long:  "Sends one span, one metric data point and one log record to the configured OTLP endpoint, reports per-signal success or failure and exits non-zero if an enabled signal could not be exported.",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, _ []string) error {
+			return e.checkTelemetry(cmd, timeout)
+		},
+	}
+	check.Flags().DurationVar(&timeout, "timeout", 10*time.Second, "time allowed for the exports")
+	cmd.AddCommand(check)
+	return cmd
+}
+
+// newMigrateCommand applies the pending migrations; "migrate up" is the
+// same as "migrate"
+func newMigrateCommand(e *env) *cobra.Command {
+	migrate := func(cmd *cobra.Command, _ []string) error {
+		return e.withDB(func(db *database.DB) error {
+			applied, err := db.Migrate(cmd.Context())
+			for _, version := range applied {
+				cmd.Printf("Applied migration %s\n", version)
+			}
+			if err != nil {
+				return err
+			}
+			if len(applied) == 0 {
+				cmd.Println("Database is up to date")
+			}
+			return nil
+		})
+	}
+	cmd := &cobra.Command{
+		Use:   "migrate",
+		Short: "Apply pending database migrations",
+		Args:  cobra.NoArgs,
+		RunE:  migrate,
+	}
+	cmd.AddCommand(&cobra.Command{
+		Use:   "up",
+		Short: "Apply pending database migrations",
+		Args:  cobra.NoArgs,
+		RunE:  migrate,
+	})
+	return cmd
+}
+
+func newSeedCommand(e *env) *cobra.Command {
+	var opts seed.Options
+	cmd := &cobra.Command{
+		Use:   "seed",
+		Short: "Insert the demo users and posts, and fake ones with --count",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, _ []string) error {
+			return e.withDB(func(db *database.DB) error {
+				if err := db.Seed(cmd.Context()); err != nil {
+					return err
+				}
+				if opts.Users > 0 {
+					opts.Progress = func(done, total int) {
+						cmd.Printf("Generated %d/%d users\n", done, total)
+					}
+					result, err := seed.Run(cmd.Context(), db, opts)
+					cmd.Printf("Inserted %d users and %d posts\n", result.Users, result.Posts)
+					if err != nil {
+						return err
+					}
+				}
+				cmd.Println("Database seeded")
+				return nil
+			})
+		},
+	}
+	cmd.Flags().IntVar(&opts.Users, "count", 0, "fake users to generate")
+	cmd.Flags().IntVar(&opts.MaxPostsPerUser, "posts", 3, "maximum fake posts per user")
+	cmd.Flags().Uint64Var(&opts.Seed, "seed", 1, "seed of the fake data; runs with the same seed generate the same users")
+	cmd.Flags().IntVar(&opts.BatchSize, "batch-size", seed.DefaultBatchSize, "users inserted per transaction")
+	return cmd
+}
+
+func newConfigCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "config",
+		Short: "Validate or print the effective configuration",
+		Args:  cobra.NoArgs,
+	}
+	cmd.AddCommand(&cobra.Command{
+		Use:         "validate",
+		Short:       "Exit non-zero when the configuration has missing or invalid values",
+		Args:        cobra.NoArgs,
+		Annotations: map[string]string{skipBootstrap: "true"},
+		RunE: func(cmd *cobra.Command, _ []string) error {
+			if _, _, err := loadConfig(); err != nil {
+				return err
+			}
+			cmd.Println("Configuration is valid")
+			return nil
+		},
+	})
+	cmd.AddCommand(&cobra.Command{
+		Use:         "print",
+		Short:       "Print the effective configuration with secrets masked",
+		Args:        cobra.NoArgs,
+		Annotations: map[string]string{skipBootstrap: "true"},
+		RunE: func(cmd *cobra.Command, _ []string) error {
+			cfg, telemetryCfg, err := loadConfig()
+			if cfg == nil {
+				return err
+			}
+			for _, setting := range config.Settings(cfg, telemetryCfg) {
+				cmd.Printf("%s=%s\n", setting.Key, setting.Value)
+			}
+			return err
+		},
+	})
+	return cmd
+}
+
+func newVersionCommand() *cobra.Command {
+	return &cobra.Command{
+		Use:         "version",
+		Short:       "Print build information",
+		Args:        cobra.NoArgs,
+		Annotations: map[string]string{skipBootstrap: "true"},
+		Run: func(cmd *cobra.Command, _ []string) {
+			info := buildinfo.Get()
+			cmd.Printf("version:    %s\n", info.Version)
+			cmd.Printf("commit:     %s\n", info.Commit)
+			cmd.Printf("built:      %s\n", info.Date)
+			cmd.Printf("go version: %s\n", info.GoVersion)
+		},
+	}
+}
+
+func newHealthcheckCommand() *cobra.Command {
+	port := os.Getenv("SERVER_PORT")
+	if port == "" {
+		port = "8080"
+	}
+	var (
+		url     string
+		timeout time.Duration
+	)
+	cmd := &cobra.Command{
+		Use:         "healthcheck",
+		Short:       "Exit non-zero unless the health endpoint answers 2xx",
+		Long:        "Performs a GET against the health endpoint, for container HEALTHCHECKs on images without curl or wget.",
+		Args:        cobra.NoArgs,
+		Annotations: map[string]string{skipBootstrap: "true"},
+		RunE: func(cmd *cobra.Command, _ []string) error {
+			return checkHealth(cmd.Context(), url, timeout)
+		},
+	}
+	cmd.Flags().StringVar(&url, "url", "http://localhost:"+port+"/health", "health endpoint to check")
+	cmd.Flags().DurationVar(&timeout, "timeout", 3*time.Second, "request timeout")
+	return cmd
+}
+
+func checkHealth(ctx context.Context, url string, timeout time.Duration) error {
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return fmt.Errorf("invalid health URL: %w", err)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("health check failed: %w", err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+	_, _ = io.Copy(io.Discard, resp.Body)
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("health check failed: %s returned %d", url, resp.StatusCode)
+	}
+	return nil
+}
+
+// bootstrap loads the configuration and initializes logging and telemetry
+func (e *env) bootstrap() error {
+	logging.InitGlobalLogger()
+	logger := logging.GetLogger()
+
+	cfg, telemetryCfg, err := loadConfig()
+	if err != nil {
+		return err
+	}
+	e.cfg = cfg
+
+	telemetryProvider, err := config.InitTelemetry(telemetryCfg)
+	if err != nil {
+		return fmt.Errorf("failed to initialize telemetry: %w", err)
+	}
+	e.telemetry = telemetryProvider
+
+	logger.WithFields(buildinfo.Get().Fields()).Info("Build information")
+	if err := buildinfo.RegisterMetric(otel.Meter("buildinfo")); err != nil {
+		return err
+	}
+
+	logger.WithFields(map[string]interface{}{
+		"service_name":            telemetryCfg.ServiceName,
+		"service_version":         telemetryCfg.ServiceVersion,
+		"tracing_enabled":         telemetryCfg.EnableTracing,
+		"metrics_enabled":         telemetryCfg.EnableMetrics,
+		"logging_enabled":         telemetryCfg.EnableLogging,
+		"runtime_metrics_enabled": telemetryCfg.EnableRuntimeMetrics,
+	}).Info("OpenTelemetry initialized successfully")
+
+	if telemetryCfg.EnableLogging && telemetryProvider.LoggerProvider != nil {
+		logging.SetupOtelBridge(telemetryProvider.LoggerProvider)
+		logger.Info("OpenTelemetry logging bridge configured")
+	} else {
+		logger.WithFields(map[string]interface{}{
+			"enable_logging":      telemetryCfg.EnableLogging,
+			"logger_provider_nil": telemetryProvider.LoggerProvider == nil,
+		}).Warn("OpenTelemetry logging bridge not configured")
+	}
+	return nil
+}
+
+// checkTelemetry exports a test record of each signal and fails when an
+// enabled signal could not be exported
+func (e *env) checkTelemetry(cmd *cobra.Command, timeout time.Duration) error {
+	ctx, cancel := context.WithTimeout(cmd.Context(), timeout)
+	defer cancel()
+
+	var failed []string
+	for _, check := range config.CheckTelemetry(ctx, e.telemetry) {
+		switch {
+		case !check.Enabled:
+			cmd.Printf("%-8s disabled\n", check.Signal)
+		case check.Err != nil:
+			cmd.Printf("%-8s failed: %v\n", check.Signal, check.Err)
+			failed = append(failed, check.Signal)
+		default:
+			cmd.Printf("%-8s ok\n", check.Signal)
+		}
+	}
+	if len(failed) > 0 {
+		return fmt.Errorf("telemetry export failed for %s", strings.Join(failed, ", "))
+	}
+	return nil
+}
+
+// loadConfig loads and validates the application and telemetry configuration.
+// Both are returned even when invalid so they can still be printed
+func loadConfig() (*config.Config, *config.TelemetryConfig, error) {
+	cfg, err := config.Load()
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to load configuration: %w", err)
+	}
+	telemetryCfg := config.GetTelemetryConfig()
+	if err := errors.Join(cfg.Validate(), telemetryCfg.Validate()); err != nil {
+		return cfg, telemetryCfg, fmt.Errorf("invalid configuration:\n%w", err)
+	}
+	return cfg, telemetryCfg, nil
+}
+
+// shutdown flushes telemetry and remote log outputs
+func (e *env) shutdown() {
+	if e.telemetry != nil {
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+		if err := e.telemetry.Shutdown(ctx); err != nil {
+			logging.GetLogger().WithFields(map[string]interface{}{
+				"error": err.Error(),
+			}).Error("Error shutting down telemetry")
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := logging.ShutdownRemoteOutputs(ctx); err != nil {
+		log.Printf("Error flushing remote log outputs: %v", err)
+	}
+}
+
+func (e *env) withDB(fn func(*database.DB) error) error {
+	if e.cfg.Database.Driver == config.DriverMemory {
+		return errors.New("DB_DRIVER=memory has no database; set DB_DRIVER=mysql")
+	}
+	db, err := database.NewConnection(e.cfg)
+	if err != nil {
+		return fmt.Errorf("failed to connect to database: %w", err)
+	}
+	defer func() {
+		if err := db.Close(); err != nil {
+			log.Printf("Error closing database: %v", err)
+		}
+	}()
+	return fn(db)
+}
@@ -0,0 +1,98 @@
+package cli
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"arquivolivre.com.br/otel/internal/buildinfo"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRootCommandHasSubcommands(t *testing.T) {
+	root := newRootCommand(&env{})
+
+	var names []string
+	for _, cmd := range root.Commands() {
+		names = append(names, cmd.Name())
+	}
+	assert.Subset(t, names, []string{"serve", "migrate", "seed", "config", "telemetry", "version", "healthcheck"})
+	assert.NotNil(t, root.RunE, "root command should serve by default")
+
+	up, _, err := root.Find([]string{"migrate", "up"})
+	require.NoError(t, err)
+	assert.Equal(t, "up", up.Name())
+}
+
+func TestVersionCommandSkipsBootstrap(t *testing.T) {
+	info := buildinfo.Get()
+	e := &env{}
+	root := newRootCommand(e)
+	var out bytes.Buffer
+	root.SetOut(&out)
+	root.SetArgs([]string{"version"})
+
+	require.NoError(t, root.Execute())
+	assert.Contains(t, out.String(), "version:    "+info.Version)
+	assert.Contains(t, out.String(), "commit:     "+info.Commit)
+	assert.Nil(t, e.cfg, "version must not load configuration")
+}
+
+func TestHealthcheckCommand(t *testing.T) {
+	status := http.StatusOK
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(status)
+	}))
+	defer server.Close()
+
+	run := func(args ...string) error {
+		e := &env{}
+		root := newRootCommand(e)
+		root.SetArgs(append([]string{"healthcheck"}, args...))
+		root.SetOut(&bytes.Buffer{})
+		root.SetErr(&bytes.Buffer{})
+		err := root.Execute()
+		assert.Nil(t, e.cfg, "healthcheck must not load configuration")
+		return err
+	}
+
+	assert.NoError(t, run("--url", server.URL))
+
+	status = http.StatusServiceUnavailable
+	assert.ErrorContains(t, run("--url", server.URL), "returned 503")
+
+	assert.Error(t, run("--url", "http://127.0.0.1:1/health", "--timeout", "200ms"))
+}
+
+func TestConfigPrintMasksSecrets(t *testing.T) {
+	t.Setenv("DB_PASSWORD", "db-secret")
+	t.Setenv("SERVER_PORT", "9090")
+
+	e := &env{}
+	root := newRootCommand(e)
+	var out bytes.Buffer
+	root.SetOut(&out)
+	root.SetArgs([]string{"config", "print"})
+
+	require.NoError(t, root.Execute())
+	assert.Contains(t, out.String(), "SERVER_PORT=9090\n")
+	assert.Contains(t, out.String(), "DB_PASSWORD=********\n")
+	assert.NotContains(t, out.String(), "db-secret")
+	assert.Nil(t, e.telemetry, "config print must not initialize telemetry")
+}
+
+func TestConfigValidateFailsOnInvalidValues(t *testing.T) {
+	t.Setenv("SERVER_PORT", "0")
+
+	root := newRootCommand(&env{})
+	root.SetOut(&bytes.Buffer{})
+	root.SetErr(&bytes.Buffer{})
+	root.SetArgs([]string{"config", "validate"})
+
+	err := root.Execute()
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "SERVER_PORT")
+}
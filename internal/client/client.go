@@ -0,0 +1,90 @@
+// Package client builds the HTTP clients the API uses to call other
+// services. Requests go through an otelhttp transport, so every attempt is a
+// client span and carries the caller's trace context and request ID
+// downstream; idempotent requests are retried on transient failures.
+package client
+
+import (
+	"net/http"
+	"time"
+
+	"arquivolivre.com.br/otel/pkg/utils"
+
+	"go.opentelemetry.io/contrib/instrumentation/net/http/otelhttp"
+)
+
+const (
+	defaultTimeout      = 5 * time.Second
+	defaultMaxRetries   = 2
+	defaultRetryBackoff = 100 * time.Millisecond
+	maxRetryBackoff     = 2 * time.Second
+)
+
+type options struct {
+	timeout      time.Duration
+	maxRetries   int
+	retryBackoff time.Duration
+	transport    http.RoundTripper
+}
+
+// Option configures a client created by New
+type Option func(*options)
+
+// WithTimeout bounds a whole call, retries included; zero disables the limit
+func WithTimeout(timeout time.Duration) Option {
+	return func(o *options) { o.timeout = timeout }
+}
+
+// WithRetries sets how many times idempotent requests are retried and the
+// initial backoff between attempts, which doubles after each retry
+func WithRetries(maxRetries int, backoff time.Duration) Option {
+	return func(o *options) {
+		o.maxRetries = maxRetries
+		o.retryBackoff = backoff
+	}
+}
+
+// WithTransport replaces http.DefaultTransport below the instrumentation,
+// e.g. to tune connection pooling
+func WithTransport(transport http.RoundTripper) Option {
+	return func(o *options) { o.transport = transport }
+}
+
+// New returns an http.Client for outbound calls. Requests must be built with
+// the incoming request's context so their spans join its trace
+func New(opts ...Option) *http.Client {
+	o := options{
+		timeout:      defaultTimeout,
+		maxRetries:   defaultMaxRetries,
+		retryBackoff: defaultRetryBackoff,
+		transport:    http.DefaultTransport,
+	}
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	// Retries wrap the instrumentation so each attempt gets its own span
+	var transport http.RoundTripper = requestIDTransport{next: otelhttp.NewTransport(o.transport)}
+	transport = &retryTransport{
+		next:       transport,
+		maxRetries: max(o.maxRetries, 0),
+		backoff:    o.retryBackoff,
+	}
+	return &http.Client{Timeout: o.timeout, Transport: transport}
+}
+
+// requestIDTransport forwards the request ID of the caller's context so
+// downstream logs can be joined with ours
+type requestIDTransport struct {
+	next http.RoundTripper
+}
+
+func (t requestIDTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	requestID := utils.RequestIDFromContext(req.Context())
+	if requestID == "" || req.Header.Get(utils.RequestIDHeader) != "" {
+		return t.next.RoundTrip(req)
+	}
+	req = req.Clone(req.Context())
+	req.Header.Set(utils.RequestIDHeader, requestID)
+	return t.next.RoundTrip(req)
+}
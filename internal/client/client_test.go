@@ -0,0 +1,179 @@
+package client
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"arquivolivre.com.br/otel/pkg/utils"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+)
+
+func newServer(t *testing.T, handler http.HandlerFunc) *httptest.Server {
+	t.Helper()
+	server := httptest.NewServer(handler)
+	t.Cleanup(server.Close)
+	return server
+}
+
+func withTracing(t *testing.T) (*sdktrace.TracerProvider, *tracetest.SpanRecorder) {
+	t.Helper()
+	recorder := tracetest.NewSpanRecorder()
+	tp := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+	prevTP, prevProp := otel.GetTracerProvider(), otel.GetTextMapPropagator()
+	otel.SetTracerProvider(tp)
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+	t.Cleanup(func() {
+		otel.SetTracerProvider(prevTP)
+		otel.SetTextMapPropagator(prevProp)
+		_ = tp.Shutdown(context.Background())
+	})
+	return tp, recorder
+}
+
+func get(t *testing.T, httpClient *http.Client, ctx context.Context, url string) (*http.Response, error) {
+	t.Helper()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	require.NoError(t, err)
+	resp, err := httpClient.Do(req)
+	if resp != nil {
+		t.Cleanup(func() { _ = resp.Body.Close() })
+	}
+	return resp, err
+}
+
+func TestPropagatesTraceContextAndRequestID(t *testing.T) {
+	tp, _ := withTracing(t)
+
+	var traceparent, requestID string
+	server := newServer(t, func(w http.ResponseWriter, r *http.Request) {
+		traceparent = r.Header.Get("traceparent")
+		requestID = r.Header.Get(utils.RequestIDHeader)
+	})
+
+	ctx, span := tp.Tracer("test").Start(context.Background(), "caller")
+	ctx = utils.ContextWithRequestID(ctx, "req-1")
+	resp, err := get(t, New(), ctx, server.URL)
+	span.End()
+
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Contains(t, traceparent, span.SpanContext().TraceID().String())
+	assert.Equal(t, "req-1", requestID)
+}
+
+func TestRetriesIdempotentRequests(t *testing.T) {
+	tp, recorder := withTracing(t)
+
+	var calls atomic.Int32
+	server := newServer(t, func(w http.ResponseWriter, r *http.Request) {
+		if calls.Add(1) < 3 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		_, _ = io.WriteString(w, "ok")
+	})
+
+	ctx, span := tp.Tracer("test").Start(context.Background(), "caller")
+	resp, err := get(t, New(WithRetries(2, time.Millisecond)), ctx, server.URL)
+	span.End()
+
+	require.NoError(t, err)
+	body, _ := io.ReadAll(resp.Body)
+	assert.Equal(t, "ok", string(body))
+	assert.Equal(t, int32(3), calls.Load())
+
+	// Each attempt is a client span and each retry an event on the caller
+	var clientSpans int
+	var retries []string
+	for _, s := range recorder.Ended() {
+		if s.SpanKind() == trace.SpanKindClient {
+			clientSpans++
+		}
+		if s.Name() == "caller" {
+			for _, event := range s.Events() {
+				retries = append(retries, event.Name)
+			}
+		}
+	}
+	assert.Equal(t, 3, clientSpans)
+	assert.Equal(t, []string{"retry", "retry"}, retries)
+}
+
+func TestGivesUpAfterMaxRetries(t *testing.T) {
+	var calls atomic.Int32
+	server := newServer(t, func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		w.WriteHeader(http.StatusBadGateway)
+	})
+
+	resp, err := get(t, New(WithRetries(1, time.Millisecond)), context.Background(), server.URL)
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusBadGateway, resp.StatusCode)
+	assert.Equal(t, int32(2), calls.Load())
+}
+
+func TestDoesNotRetryPostOrClientErrors(t *testing.T) {
+	var calls atomic.Int32
+	server := newServer(t, func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		if r.Method == http.MethodPost {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.WriteHeader(http.StatusNotFound)
+	})
+	httpClient := New(WithRetries(2, time.Millisecond))
+
+	resp, err := httpClient.Post(server.URL, "text/plain", strings.NewReader("body"))
+	require.NoError(t, err)
+	_ = resp.Body.Close()
+	assert.Equal(t, int32(1), calls.Load())
+
+	_, err = get(t, httpClient, context.Background(), server.URL)
+	require.NoError(t, err)
+	assert.Equal(t, int32(2), calls.Load())
+}
+
+func TestRetriesReplayRequestBody(t *testing.T) {
+	var calls atomic.Int32
+	var bodies []string
+	server := newServer(t, func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		bodies = append(bodies, string(body))
+		if calls.Add(1) == 1 {
+			w.WriteHeader(http.StatusInternalServerError)
+		}
+	})
+
+	req, err := http.NewRequest(http.MethodPut, server.URL, strings.NewReader("payload"))
+	require.NoError(t, err)
+	resp, err := New(WithRetries(1, time.Millisecond)).Do(req)
+	require.NoError(t, err)
+	_ = resp.Body.Close()
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, []string{"payload", "payload"}, bodies)
+}
+
+func TestTimeoutStopsRetries(t *testing.T) {
+	server := newServer(t, func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	})
+
+	start := time.Now()
+	_, err := get(t, New(WithTimeout(50*time.Millisecond), WithRetries(10, 40*time.Millisecond)), context.Background(), server.URL)
+	require.Error(t, err)
+	assert.Less(t, time.Since(start), time.Second)
+}
@@ -5,61 +5,374 @@ import (
 	"log"
 	"os"
 	"strconv"
+	"strings"
+	"time"
 
 	"github.com/joho/godotenv"
 )
 
 type Config struct {
-	Database DatabaseConfig
-	Server   ServerConfig
-	App      AppConfig
+	Database  DatabaseConfig
+	Server    ServerConfig
+	App       AppConfig
+	Jobs      JobsConfig
+	Scheduler SchedulerConfig
+	Kafka     KafkaConfig
+	Outbox    OutboxConfig
+	Webhooks  WebhooksConfig
+	Audit     AuditConfig
+	SMTP      SMTPConfig
+	Storage   StorageConfig
+	Auth      AuthConfig
+	Cache     CacheConfig
+	Health    HealthConfig
 }
 
+// Database drivers selected by DB_DRIVER
+const (
+	DriverMySQL  = "mysql"
+	DriverMemory = "memory"
+)
+
+// User data access selected by DB_ACCESS
+const (
+	AccessSQL  = "sql"
+	AccessSQLX = "sqlx"
+	AccessSQLC = "sqlc"
+)
+
+// User search modes selected by DB_SEARCH_MODE
+const (
+	SearchFullText = "fulltext"
+	SearchLike     = "like"
+)
+
 type DatabaseConfig struct {
+	// Driver is DriverMySQL, or DriverMemory to keep users and posts in
+	// process memory without a database
+	Driver   string
 	Host     string
 	Port     int
 	User     string
 	Password string
 	Name     string
 	DSN      string
+	// ReplicaHost and ReplicaPort locate a read replica, reached with the
+	// credentials of the primary; an empty host sends every query to the
+	// primary
+	ReplicaHost string
+	ReplicaPort int
+	ReplicaDSN  string
+	// QueryTimeout bounds each repository operation; zero disables it
+	QueryTimeout time.Duration
+	// SlowQueryThreshold is the duration from which queries are logged as
+	// slow; zero disables slow query reports
+	SlowQueryThreshold time.Duration
+	// ConnectMaxAttempts and ConnectMaxElapsed bound the retries of the
+	// initial connection; zero elapsed time leaves only the attempt limit
+	ConnectMaxAttempts int
+	ConnectMaxElapsed  time.Duration
+	// PreparedStatements prepares the user lookups by ID and email once and
+	// reuses them instead of sending each query on its own
+	PreparedStatements bool
+	// Access is AccessSQL to read and write users with database/sql, or
+	// AccessSQLX or AccessSQLC to do it through sqlx or the queries generated
+	// by sqlc on the same connection pool
+	Access string
+	// SearchMode is SearchFullText to search users through the FULLTEXT
+	// index of migration 008, or SearchLike to match substrings without it
+	SearchMode string
+}
+
+// dsn returns the MySQL DSN of the server at host and port. The session time
+// zone is pinned to UTC so TIMESTAMP columns are read and written as UTC wall
+// clocks (see models.Timestamp)
+func (d DatabaseConfig) dsn(host string, port int) string {
+	return fmt.Sprintf("%s:%s@tcp(%s:%d)/%s?charset=utf8mb4&parseTime=True&loc=Local&time_zone=%%27%%2B00%%3A00%%27",
+		d.User,
+		d.Password,
+		host,
+		port,
+		d.Name,
+	)
 }
 
 type ServerConfig struct {
 	Port string
 	Host string
+	// GRPCPort serves the gRPC user service; empty disables it
+	GRPCPort string
+	// ShutdownTimeout bounds the drain of in-flight requests and background
+	// workers on SIGTERM
+	ShutdownTimeout time.Duration
+	// ReadTimeout and WriteTimeout bound the requests of the read and write
+	// route groups, and ReadMaxBodyBytes and WriteMaxBodyBytes their bodies;
+	// zero leaves them unbounded
+	ReadTimeout       time.Duration
+	WriteTimeout      time.Duration
+	ReadMaxBodyBytes  int
+	WriteMaxBodyBytes int
+	// MetricsStreamInterval is the time between the snapshots sent by
+	// /events/metrics, which is not served when it is zero;
+	// MetricsStreamMaxClients bounds the streams open at once
+	MetricsStreamInterval   time.Duration
+	MetricsStreamMaxClients int
+}
+
+type JobsConfig struct {
+	Workers   int
+	QueueSize int
+	// OperationRetention is how long the status and result of a finished
+	// async operation stay available
+	OperationRetention time.Duration
+}
+
+// SchedulerConfig holds cron expressions for periodic tasks; "off" disables
+// a task
+type SchedulerConfig struct {
+	UserCountWarmup string
+	RunTimeout      time.Duration
+	// SyntheticProbe schedules the self-probe of SyntheticProbeURL; it is
+	// off by default
+	SyntheticProbe    string
+	SyntheticProbeURL string
+	// SyntheticProbeToken and SyntheticProbeAPIKey are sent by the probe, as
+	// a bearer token and an X-API-Key, when the API requires authentication
+	SyntheticProbeToken  string
+	SyntheticProbeAPIKey string
+	// StaleUserCleanup schedules the removal of canary users the synthetic
+	// probe failed to delete
+	StaleUserCleanup string
+}
+
+// SMTPConfig enables email delivery when Addr is set; otherwise emails are
+// only logged
+type SMTPConfig struct {
+	Addr     string
+	From     string
+	Username string
+	Password string
+}
+
+// StorageConfig enables avatar uploads to an S3-compatible object store,
+// such as MinIO, when Endpoint is set
+type StorageConfig struct {
+	// Endpoint is the host:port of the store
+	Endpoint  string
+	Bucket    string
+	Region    string
+	AccessKey string
+	SecretKey string
+	UseSSL    bool
+	// AvatarMaxBytes bounds the size of an uploaded avatar
+	AvatarMaxBytes int
+}
+
+// KafkaConfig enables user event publishing when Brokers is not empty and
+// PublishEvents is set
+type KafkaConfig struct {
+	Brokers         []string
+	UserEventsTopic string
+	ConsumerGroup   string
+	// PublishEvents turns user event publishing off while Brokers stays set
+	// for cmd/consumer
+	PublishEvents bool
+}
+
+// OutboxConfig enables the transactional outbox: user events are stored with
+// their change and published by a relay instead of by the request
+type OutboxConfig struct {
+	Enabled      bool
+	PollInterval time.Duration
+	BatchSize    int
+	MaxAttempts  int
+}
+
+// WebhooksConfig enables delivering user events to the webhooks registered
+// under /admin/webhooks
+type WebhooksConfig struct {
+	Enabled bool
+	// Timeout bounds each delivery attempt
+	Timeout     time.Duration
+	MaxAttempts int
+}
+
+// AuditConfig enables recording user and post changes in the audit_log
+// table and the audit log stream
+type AuditConfig struct {
+	Enabled bool
+}
+
+// CacheConfig enables the Redis cache of user lookups when RedisAddr is set
+type CacheConfig struct {
+	RedisAddr     string
+	RedisPassword string
+	UserTTL       time.Duration
+	// IdempotencyTTL is how long the response to a create with an
+	// Idempotency-Key is kept for replay
+	IdempotencyTTL time.Duration
+}
+
+// HealthConfig tunes the checks reported by /health and /ready
+type HealthConfig struct {
+	CheckTimeout time.Duration
+	// DiskPath is the path whose filesystem must keep DiskMinFreeMB free
+	DiskPath      string
+	DiskMinFreeMB int
+}
+
+// AuthConfig enables JWT authentication of user writes when JWTSecret
+// (HMAC) or JWKSURL (public keys) is set
+type AuthConfig struct {
+	JWTSecret   string
+	JWKSURL     string
+	JWTIssuer   string
+	JWTAudience string
+	// APIKeys are accepted in the X-API-Key header, each written as
+	// client_id:key[:daily_quota[:roles]]
+	APIKeys []string
+	// RBAC requires the users:write and posts:write roles on writes
+	RBAC bool
 }
 
 type AppConfig struct {
-	Environment string
-	LogLevel    string
+	Environment            string
+	LogLevel               string
+	DisallowedEmailDomains []string
+	FeatureFlagsFile       string
+	EnricherURL            string
+	ExternalServiceURL     string
+	AdminToken             string
+	ChaosEnabled           bool
+	// APISunset is the YYYY-MM-DD date announced for removing the
+	// unversioned /api routes
+	APISunset string
+	// BulkMaxUsers bounds the users of one POST /api/users/bulk, which
+	// inserts them BulkBatchSize per transaction
+	BulkMaxUsers  int
+	BulkBatchSize int
+	// SeedOnStartup fake users, with their posts, are generated when the
+	// server starts in development; see internal/seed
+	SeedOnStartup int
+	// ConfigFile is watched for reloadable settings when HotReload is set
+	ConfigFile string
+	HotReload  bool
 }
 
 func Load() (*Config, error) {
 	if err := godotenv.Load(); err != nil {
 		log.Println("No .env file found, using environment variables")
 	}
+	if err := loadFile(); err != nil {
+		return nil, err
+	}
 
 	cfg := &Config{}
 
+	cfg.Database.Driver = getEnv("DB_DRIVER", DriverMySQL)
 	cfg.Database.Host = getEnv("DB_HOST", "localhost")
 	cfg.Database.Port = getEnvAsInt("DB_PORT", 3306)
 	cfg.Database.User = getEnv("DB_USER", "root")
 	cfg.Database.Password = getEnv("DB_PASSWORD", "")
 	cfg.Database.Name = getEnv("DB_NAME", "otel_example")
+	cfg.Database.QueryTimeout = getEnvAsDuration("DB_QUERY_TIMEOUT", 5*time.Second)
+	cfg.Database.SlowQueryThreshold = time.Duration(getEnvAsInt("DB_SLOW_QUERY_MS", 500)) * time.Millisecond
+	cfg.Database.ConnectMaxAttempts = getEnvAsInt("DB_CONNECT_MAX_ATTEMPTS", 10)
+	cfg.Database.ConnectMaxElapsed = getEnvAsDuration("DB_CONNECT_MAX_ELAPSED", time.Minute)
+	cfg.Database.PreparedStatements = getEnvAsBool("DB_PREPARED_STATEMENTS", false)
+	cfg.Database.Access = getEnv("DB_ACCESS", AccessSQL)
+	cfg.Database.SearchMode = getEnv("DB_SEARCH_MODE", SearchFullText)
+	cfg.Database.ReplicaHost = getEnv("DB_REPLICA_HOST", "")
+	cfg.Database.ReplicaPort = getEnvAsInt("DB_REPLICA_PORT", cfg.Database.Port)
 
-	cfg.Database.DSN = fmt.Sprintf("%s:%s@tcp(%s:%d)/%s?charset=utf8mb4&parseTime=True&loc=Local",
-		cfg.Database.User,
-		cfg.Database.Password,
-		cfg.Database.Host,
-		cfg.Database.Port,
-		cfg.Database.Name,
-	)
+	cfg.Database.DSN = cfg.Database.dsn(cfg.Database.Host, cfg.Database.Port)
+	if cfg.Database.ReplicaHost != "" {
+		cfg.Database.ReplicaDSN = cfg.Database.dsn(cfg.Database.ReplicaHost, cfg.Database.ReplicaPort)
+	}
 
 	cfg.Server.Host = getEnv("SERVER_HOST", "0.0.0.0")
 	cfg.Server.Port = getEnv("SERVER_PORT", "8080")
+	cfg.Server.GRPCPort = getEnv("GRPC_PORT", "50051")
+	cfg.Server.ShutdownTimeout = getEnvAsDuration("SHUTDOWN_TIMEOUT", 30*time.Second)
+	cfg.Server.ReadTimeout = getEnvAsDuration("REQUEST_READ_TIMEOUT", 10*time.Second)
+	cfg.Server.WriteTimeout = getEnvAsDuration("REQUEST_WRITE_TIMEOUT", 30*time.Second)
+	cfg.Server.ReadMaxBodyBytes = getEnvAsInt("REQUEST_READ_MAX_BODY_BYTES", 4<<10)
+	cfg.Server.WriteMaxBodyBytes = getEnvAsInt("REQUEST_WRITE_MAX_BODY_BYTES", 1<<20)
+	cfg.Server.MetricsStreamInterval = getEnvAsDuration("METRICS_STREAM_INTERVAL", 5*time.Second)
+	cfg.Server.MetricsStreamMaxClients = getEnvAsInt("METRICS_STREAM_MAX_CLIENTS", 100)
 
 	cfg.App.Environment = getEnv("APP_ENV", "development")
 	cfg.App.LogLevel = getEnv("LOG_LEVEL", "info")
+	cfg.App.DisallowedEmailDomains = getEnvAsList("DISALLOWED_EMAIL_DOMAINS")
+	cfg.App.FeatureFlagsFile = getEnv("FEATURE_FLAGS_FILE", "")
+	cfg.App.EnricherURL = getEnv("ENRICHER_URL", "")
+	cfg.App.ExternalServiceURL = getEnv("EXTERNAL_SERVICE_URL", "")
+	cfg.App.AdminToken = getEnv("ADMIN_TOKEN", "")
+	cfg.App.ChaosEnabled = getEnvAsBool("CHAOS_ENABLED", false)
+	cfg.App.APISunset = getEnv("API_SUNSET", "")
+	cfg.App.BulkMaxUsers = getEnvAsInt("BULK_MAX_USERS", 100)
+	cfg.App.BulkBatchSize = getEnvAsInt("BULK_BATCH_SIZE", 25)
+	cfg.App.SeedOnStartup = getEnvAsInt("SEED_ON_STARTUP", 0)
+	cfg.App.ConfigFile = getEnv("CONFIG_FILE", ".env")
+	cfg.App.HotReload = getEnvAsBool("CONFIG_HOT_RELOAD", false)
+
+	cfg.Jobs.Workers = getEnvAsInt("JOB_WORKERS", 4)
+	cfg.Jobs.QueueSize = getEnvAsInt("JOB_QUEUE_SIZE", 100)
+	cfg.Jobs.OperationRetention = getEnvAsDuration("OPERATION_RETENTION", time.Hour)
+
+	cfg.Scheduler.UserCountWarmup = getEnv("SCHEDULE_USER_COUNT_WARMUP", "*/5 * * * *")
+	cfg.Scheduler.RunTimeout = time.Duration(getEnvAsInt("SCHEDULER_RUN_TIMEOUT_SECONDS", 60)) * time.Second
+	cfg.Scheduler.SyntheticProbe = getEnv("SCHEDULE_SYNTHETIC_PROBE", "off")
+	cfg.Scheduler.SyntheticProbeURL = getEnv("SYNTHETIC_PROBE_URL", "http://localhost:"+cfg.Server.Port)
+	cfg.Scheduler.SyntheticProbeToken = getEnv("SYNTHETIC_PROBE_TOKEN", "")
+	cfg.Scheduler.SyntheticProbeAPIKey = getEnv("SYNTHETIC_PROBE_API_KEY", "")
+	cfg.Scheduler.StaleUserCleanup = getEnv("SCHEDULE_STALE_USER_CLEANUP", "@every 1h")
+
+	cfg.Auth.JWTSecret = getEnv("JWT_SECRET", "")
+	cfg.Auth.JWKSURL = getEnv("JWT_JWKS_URL", "")
+	cfg.Auth.JWTIssuer = getEnv("JWT_ISSUER", "")
+	cfg.Auth.JWTAudience = getEnv("JWT_AUDIENCE", "")
+	cfg.Auth.APIKeys = getEnvAsList("API_KEYS")
+	cfg.Auth.RBAC = getEnvAsBool("RBAC_ENABLED", false)
+
+	cfg.Cache.RedisAddr = getEnv("REDIS_ADDR", "")
+	cfg.Cache.RedisPassword = getEnv("REDIS_PASSWORD", "")
+	cfg.Cache.UserTTL = getEnvAsDuration("USER_CACHE_TTL", time.Minute)
+	cfg.Cache.IdempotencyTTL = getEnvAsDuration("IDEMPOTENCY_KEY_TTL", 24*time.Hour)
+
+	cfg.Health.CheckTimeout = getEnvAsDuration("HEALTH_CHECK_TIMEOUT", 2*time.Second)
+	cfg.Health.DiskPath = getEnv("HEALTH_DISK_PATH", "/")
+	cfg.Health.DiskMinFreeMB = getEnvAsInt("HEALTH_DISK_MIN_FREE_MB", 100)
+
+	cfg.Kafka.Brokers = getEnvAsList("KAFKA_BROKERS")
+	cfg.Kafka.UserEventsTopic = getEnv("KAFKA_USER_EVENTS_TOPIC", "user-events")
+	cfg.Kafka.ConsumerGroup = getEnv("KAFKA_CONSUMER_GROUP", "user-events-consumer")
+	cfg.Kafka.PublishEvents = getEnvAsBool("KAFKA_PUBLISH_EVENTS", true)
+
+	cfg.Outbox.Enabled = getEnvAsBool("OUTBOX_ENABLED", false)
+	cfg.Outbox.PollInterval = getEnvAsDuration("OUTBOX_POLL_INTERVAL", time.Second)
+	cfg.Outbox.BatchSize = getEnvAsInt("OUTBOX_BATCH_SIZE", 100)
+	cfg.Outbox.MaxAttempts = getEnvAsInt("OUTBOX_MAX_ATTEMPTS", 10)
+
+	cfg.Webhooks.Enabled = getEnvAsBool("WEBHOOKS_ENABLED", false)
+	cfg.Webhooks.Timeout = getEnvAsDuration("WEBHOOK_TIMEOUT", 5*time.Second)
+	cfg.Webhooks.MaxAttempts = getEnvAsInt("WEBHOOK_MAX_ATTEMPTS", 5)
+
+	// The audit log is a MySQL table
+	cfg.Audit.Enabled = getEnvAsBool("AUDIT_ENABLED", cfg.Database.Driver != DriverMemory)
+
+	cfg.SMTP.Addr = getEnv("SMTP_ADDR", "")
+	cfg.SMTP.From = getEnv("SMTP_FROM", "noreply@example.com")
+	cfg.SMTP.Username = getEnv("SMTP_USERNAME", "")
+	cfg.SMTP.Password = getEnv("SMTP_PASSWORD", "")
+
+	cfg.Storage.Endpoint = getEnv("S3_ENDPOINT", "")
+	cfg.Storage.Bucket = getEnv("S3_BUCKET", "avatars")
+	cfg.Storage.Region = getEnv("S3_REGION", "us-east-1")
+	cfg.Storage.AccessKey = getEnv("S3_ACCESS_KEY", "")
+	cfg.Storage.SecretKey = getEnv("S3_SECRET_KEY", "")
+	cfg.Storage.UseSSL = getEnvAsBool("S3_USE_SSL", false)
+	cfg.Storage.AvatarMaxBytes = getEnvAsInt("AVATAR_MAX_BYTES", 2<<20)
 
 	return cfg, nil
 }
@@ -79,3 +392,45 @@ func getEnvAsInt(key string, defaultValue int) int {
 	}
 	return defaultValue
 }
+
+func getEnvAsBool(key string, defaultValue bool) bool {
+	if value := os.Getenv(key); value != "" {
+		if boolValue, err := strconv.ParseBool(value); err == nil {
+			return boolValue
+		}
+	}
+	return defaultValue
+}
+
+func getEnvAsFloat(key string, defaultValue float64) float64 {
+	if value := os.Getenv(key); value != "" {
+		if floatValue, err := strconv.ParseFloat(value, 64); err == nil {
+			return floatValue
+		}
+	}
+	return defaultValue
+}
+
+func getEnvAsDuration(key string, defaultValue time.Duration) time.Duration {
+	if value := os.Getenv(key); value != "" {
+		if duration, err := time.ParseDuration(value); err == nil {
+			return duration
+		}
+	}
+	return defaultValue
+}
+
+// getEnvAsMillis reads key as a whole number of milliseconds
+func getEnvAsMillis(key string, defaultValue time.Duration) time.Duration {
+	return time.Duration(getEnvAsInt(key, int(defaultValue.Milliseconds()))) * time.Millisecond
+}
+
+func getEnvAsList(key string) []string {
+	var values []string
+	for _, value := range strings.Split(os.Getenv(key), ",") {
+		if value = strings.TrimSpace(value); value != "" {
+			values = append(values, value)
+		}
+	}
+	return values
+}
@@ -2,6 +2,7 @@ package config
 
 import (
 	"os"
+	"strings"
 	"testing"
 )
 
@@ -15,6 +16,7 @@ func TestLoadReadsEnvAndBuildsDSN(t *testing.T) {
 	_ = os.Setenv("SERVER_PORT", "9090")
 	_ = os.Setenv("APP_ENV", "test")
 	_ = os.Setenv("LOG_LEVEL", "debug")
+	_ = os.Setenv("JOB_WORKERS", "2")
 	defer func() { os.Clearenv() }()
 
 	cfg, err := Load()
@@ -27,6 +29,12 @@ func TestLoadReadsEnvAndBuildsDSN(t *testing.T) {
 	if cfg.Database.DSN == "" {
 		t.Fatal("dsn should be built")
 	}
+	if cfg.Jobs.Workers != 2 || cfg.Jobs.QueueSize != 100 {
+		t.Fatalf("unexpected jobs cfg: %+v", cfg.Jobs)
+	}
+	if !strings.Contains(cfg.Database.DSN, "time_zone=%27%2B00%3A00%27") {
+		t.Fatalf("dsn should pin the session time zone to UTC: %s", cfg.Database.DSN)
+	}
 }
 
 func TestGetEnvHelpers(t *testing.T) {
@@ -0,0 +1,207 @@
+package config
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"reflect"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// defaultConfigYAML is read when CONFIG_YAML is unset; unlike an explicit
+// CONFIG_YAML it may be missing
+const defaultConfigYAML = "config.yaml"
+
+// fileConfig is the layout of the YAML config file. Each value stands for the
+// environment variable in its env tag and only applies while that variable is
+// unset or empty, so the environment and .env take precedence over the file
+type fileConfig struct {
+	Database struct {
+		Driver       string `yaml:"driver" env:"DB_DRIVER"`
+		Host         string `yaml:"host" env:"DB_HOST"`
+		Port         *int   `yaml:"port" env:"DB_PORT"`
+		User         string `yaml:"user" env:"DB_USER"`
+		Password     string `yaml:"password" env:"DB_PASSWORD"`
+		Name         string `yaml:"name" env:"DB_NAME"`
+		QueryTimeout string `yaml:"query_timeout" env:"DB_QUERY_TIMEOUT"`
+		SlowQueryMS  *int   `yaml:"slow_query_ms" env:"DB_SLOW_QUERY_MS"`
+		// Retries of the initial connection
+		ConnectMaxAttempts *int   `yaml:"connect_max_attempts" env:"DB_CONNECT_MAX_ATTEMPTS"`
+		ConnectMaxElapsed  string `yaml:"connect_max_elapsed" env:"DB_CONNECT_MAX_ELAPSED"`
+		PreparedStatements *bool  `yaml:"prepared_statements" env:"DB_PREPARED_STATEMENTS"`
+		Access             string `yaml:"access" env:"DB_ACCESS"`
+		SearchMode         string `yaml:"search_mode" env:"DB_SEARCH_MODE"`
+		// Read replica, see DatabaseConfig.ReplicaHost
+		ReplicaHost string `yaml:"replica_host" env:"DB_REPLICA_HOST"`
+		ReplicaPort *int   `yaml:"replica_port" env:"DB_REPLICA_PORT"`
+	} `yaml:"database"`
+	Server struct {
+		Host              string `yaml:"host" env:"SERVER_HOST"`
+		Port              *int   `yaml:"port" env:"SERVER_PORT"`
+		GRPCPort          *int   `yaml:"grpc_port" env:"GRPC_PORT"`
+		ShutdownTimeout   string `yaml:"shutdown_timeout" env:"SHUTDOWN_TIMEOUT"`
+		ReadTimeout       string `yaml:"read_timeout" env:"REQUEST_READ_TIMEOUT"`
+		WriteTimeout      string `yaml:"write_timeout" env:"REQUEST_WRITE_TIMEOUT"`
+		ReadMaxBodyBytes  *int   `yaml:"read_max_body_bytes" env:"REQUEST_READ_MAX_BODY_BYTES"`
+		WriteMaxBodyBytes *int   `yaml:"write_max_body_bytes" env:"REQUEST_WRITE_MAX_BODY_BYTES"`
+		// Snapshots of /events/metrics
+		MetricsStreamInterval   string `yaml:"metrics_stream_interval" env:"METRICS_STREAM_INTERVAL"`
+		MetricsStreamMaxClients *int   `yaml:"metrics_stream_max_clients" env:"METRICS_STREAM_MAX_CLIENTS"`
+	} `yaml:"server"`
+	App struct {
+		Environment            string   `yaml:"environment" env:"APP_ENV"`
+		LogLevel               string   `yaml:"log_level" env:"LOG_LEVEL"`
+		DisallowedEmailDomains []string `yaml:"disallowed_email_domains" env:"DISALLOWED_EMAIL_DOMAINS"`
+		FeatureFlagsFile       string   `yaml:"feature_flags_file" env:"FEATURE_FLAGS_FILE"`
+		EnricherURL            string   `yaml:"enricher_url" env:"ENRICHER_URL"`
+		ExternalServiceURL     string   `yaml:"external_service_url" env:"EXTERNAL_SERVICE_URL"`
+		AdminToken             string   `yaml:"admin_token" env:"ADMIN_TOKEN"`
+		ChaosEnabled           *bool    `yaml:"chaos_enabled" env:"CHAOS_ENABLED"`
+		APISunset              string   `yaml:"api_sunset" env:"API_SUNSET"`
+		BulkMaxUsers           *int     `yaml:"bulk_max_users" env:"BULK_MAX_USERS"`
+		BulkBatchSize          *int     `yaml:"bulk_batch_size" env:"BULK_BATCH_SIZE"`
+		SeedOnStartup          *int     `yaml:"seed_on_startup" env:"SEED_ON_STARTUP"`
+	} `yaml:"app"`
+	Jobs struct {
+		Workers            *int   `yaml:"workers" env:"JOB_WORKERS"`
+		QueueSize          *int   `yaml:"queue_size" env:"JOB_QUEUE_SIZE"`
+		OperationRetention string `yaml:"operation_retention" env:"OPERATION_RETENTION"`
+	} `yaml:"jobs"`
+	Kafka struct {
+		Brokers         []string `yaml:"brokers" env:"KAFKA_BROKERS"`
+		UserEventsTopic string   `yaml:"user_events_topic" env:"KAFKA_USER_EVENTS_TOPIC"`
+		ConsumerGroup   string   `yaml:"consumer_group" env:"KAFKA_CONSUMER_GROUP"`
+		PublishEvents   *bool    `yaml:"publish_events" env:"KAFKA_PUBLISH_EVENTS"`
+	} `yaml:"kafka"`
+	Outbox struct {
+		Enabled      *bool  `yaml:"enabled" env:"OUTBOX_ENABLED"`
+		PollInterval string `yaml:"poll_interval" env:"OUTBOX_POLL_INTERVAL"`
+		BatchSize    *int   `yaml:"batch_size" env:"OUTBOX_BATCH_SIZE"`
+		MaxAttempts  *int   `yaml:"max_attempts" env:"OUTBOX_MAX_ATTEMPTS"`
+	} `yaml:"outbox"`
+	Webhooks struct {
+		Enabled     *bool  `yaml:"enabled" env:"WEBHOOKS_ENABLED"`
+		Timeout     string `yaml:"timeout" env:"WEBHOOK_TIMEOUT"`
+		MaxAttempts *int   `yaml:"max_attempts" env:"WEBHOOK_MAX_ATTEMPTS"`
+	} `yaml:"webhooks"`
+	Audit struct {
+		Enabled *bool `yaml:"enabled" env:"AUDIT_ENABLED"`
+	} `yaml:"audit"`
+	SMTP struct {
+		Addr     string `yaml:"addr" env:"SMTP_ADDR"`
+		From     string `yaml:"from" env:"SMTP_FROM"`
+		Username string `yaml:"username" env:"SMTP_USERNAME"`
+		Password string `yaml:"password" env:"SMTP_PASSWORD"`
+	} `yaml:"smtp"`
+	Storage struct {
+		Endpoint       string `yaml:"endpoint" env:"S3_ENDPOINT"`
+		Bucket         string `yaml:"bucket" env:"S3_BUCKET"`
+		Region         string `yaml:"region" env:"S3_REGION"`
+		AccessKey      string `yaml:"access_key" env:"S3_ACCESS_KEY"`
+		SecretKey      string `yaml:"secret_key" env:"S3_SECRET_KEY"`
+		UseSSL         *bool  `yaml:"use_ssl" env:"S3_USE_SSL"`
+		AvatarMaxBytes *int   `yaml:"avatar_max_bytes" env:"AVATAR_MAX_BYTES"`
+	} `yaml:"storage"`
+	Auth struct {
+		JWTSecret   string   `yaml:"jwt_secret" env:"JWT_SECRET"`
+		JWKSURL     string   `yaml:"jwt_jwks_url" env:"JWT_JWKS_URL"`
+		JWTIssuer   string   `yaml:"jwt_issuer" env:"JWT_ISSUER"`
+		JWTAudience string   `yaml:"jwt_audience" env:"JWT_AUDIENCE"`
+		APIKeys     []string `yaml:"api_keys" env:"API_KEYS"`
+		RBAC        *bool    `yaml:"rbac_enabled" env:"RBAC_ENABLED"`
+	} `yaml:"auth"`
+	Cache struct {
+		RedisAddr      string `yaml:"redis_addr" env:"REDIS_ADDR"`
+		RedisPassword  string `yaml:"redis_password" env:"REDIS_PASSWORD"`
+		UserTTL        string `yaml:"user_ttl" env:"USER_CACHE_TTL"`
+		IdempotencyTTL string `yaml:"idempotency_ttl" env:"IDEMPOTENCY_KEY_TTL"`
+	} `yaml:"cache"`
+	Telemetry struct {
+		ServiceName string `yaml:"service_name" env:"OTEL_SERVICE_NAME"`
+		Protocol    string `yaml:"protocol" env:"OTEL_EXPORTER_OTLP_PROTOCOL"`
+		Endpoint    string `yaml:"endpoint" env:"OTEL_EXPORTER_OTLP_ENDPOINT"`
+		// Per-signal endpoints override Endpoint
+		TracesEndpoint  string `yaml:"traces_endpoint" env:"OTEL_EXPORTER_OTLP_TRACES_ENDPOINT"`
+		MetricsEndpoint string `yaml:"metrics_endpoint" env:"OTEL_EXPORTER_OTLP_METRICS_ENDPOINT"`
+		LogsEndpoint    string `yaml:"logs_endpoint" env:"OTEL_EXPORTER_OTLP_LOGS_ENDPOINT"`
+		FailFast        *bool  `yaml:"fail_fast" env:"OTEL_EXPORTER_OTLP_FAIL_FAST"`
+		// key=value pairs like OTEL_EXPORTER_OTLP_HEADERS
+		Headers        string `yaml:"headers" env:"OTEL_EXPORTER_OTLP_HEADERS"`
+		TracesHeaders  string `yaml:"traces_headers" env:"OTEL_EXPORTER_OTLP_TRACES_HEADERS"`
+		MetricsHeaders string `yaml:"metrics_headers" env:"OTEL_EXPORTER_OTLP_METRICS_HEADERS"`
+		LogsHeaders    string `yaml:"logs_headers" env:"OTEL_EXPORTER_OTLP_LOGS_HEADERS"`
+		// Milliseconds, like their variables
+		BatchTimeoutMS       *int     `yaml:"batch_timeout_ms" env:"OTEL_BSP_SCHEDULE_DELAY"`
+		BatchExportTimeoutMS *int     `yaml:"batch_export_timeout_ms" env:"OTEL_BSP_EXPORT_TIMEOUT"`
+		MaxQueueSize         *int     `yaml:"max_queue_size" env:"OTEL_BSP_MAX_QUEUE_SIZE"`
+		MaxExportBatchSize   *int     `yaml:"max_export_batch_size" env:"OTEL_BSP_MAX_EXPORT_BATCH_SIZE"`
+		MetricIntervalMS     *int     `yaml:"metric_interval_ms" env:"OTEL_METRIC_EXPORT_INTERVAL"`
+		MetricTimeoutMS      *int     `yaml:"metric_timeout_ms" env:"OTEL_METRIC_EXPORT_TIMEOUT"`
+		MetricsExporter      string   `yaml:"metrics_exporter" env:"OTEL_METRICS_EXPORTER"`
+		ExemplarFilter       string   `yaml:"exemplar_filter" env:"OTEL_METRICS_EXEMPLAR_FILTER"`
+		Sampler              string   `yaml:"sampler" env:"OTEL_TRACES_SAMPLER"`
+		SampleRatio          *float64 `yaml:"sample_ratio" env:"OTEL_TRACES_SAMPLER_ARG"`
+		UntracedPaths        []string `yaml:"untraced_paths" env:"OTEL_UNTRACED_PATHS"`
+	} `yaml:"telemetry"`
+}
+
+// loadFile applies the YAML config file to the environment variables that
+// are still unset. The file is CONFIG_YAML, or config.yaml when it exists.
+// Unknown keys and values of the wrong type are errors
+func loadFile() error {
+	path, explicit := os.LookupEnv("CONFIG_YAML")
+	if !explicit {
+		path = defaultConfigYAML
+	}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) && !explicit {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to read config file: %w", err)
+	}
+
+	var file fileConfig
+	decoder := yaml.NewDecoder(bytes.NewReader(data))
+	decoder.KnownFields(true)
+	if err := decoder.Decode(&file); err != nil && !errors.Is(err, io.EOF) {
+		return fmt.Errorf("invalid config file %s: %w", path, err)
+	}
+
+	for key, value := range file.env() {
+		if os.Getenv(key) == "" {
+			if err := os.Setenv(key, value); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// env returns the values set in the file keyed by environment variable
+func (f fileConfig) env() map[string]string {
+	values := map[string]string{}
+	sections := reflect.ValueOf(f)
+	for i := 0; i < sections.NumField(); i++ {
+		section := sections.Field(i)
+		for j := 0; j < section.NumField(); j++ {
+			key := section.Type().Field(j).Tag.Get("env")
+			field := section.Field(j)
+			switch {
+			case field.IsZero():
+			case field.Kind() == reflect.Pointer:
+				values[key] = fmt.Sprint(field.Elem().Interface())
+			case field.Kind() == reflect.Slice:
+				values[key] = strings.Join(field.Interface().([]string), ",")
+			default:
+				values[key] = field.String()
+			}
+		}
+	}
+	return values
+}
@@ -0,0 +1,93 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func writeConfigYAML(t *testing.T, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestLoadReadsYAMLWithEnvPrecedence(t *testing.T) {
+	os.Clearenv()
+	defer os.Clearenv()
+	_ = os.Setenv("CONFIG_YAML", writeConfigYAML(t, `
+database:
+  host: mysql
+  port: 3307
+server:
+  port: 8081
+app:
+  environment: staging
+  disallowed_email_domains: [example.org, test.org]
+jobs:
+  queue_size: 0
+telemetry:
+  sample_ratio: 0.25
+  traces_endpoint: tempo:4317
+`))
+	_ = os.Setenv("DB_HOST", "from-env")
+
+	cfg, err := Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.Database.Host != "from-env" {
+		t.Errorf("expected the environment to win, got %q", cfg.Database.Host)
+	}
+	if cfg.Database.Port != 3307 || cfg.Server.Port != "8081" || cfg.App.Environment != "staging" {
+		t.Errorf("expected file values, got %+v %+v %+v", cfg.Database, cfg.Server, cfg.App)
+	}
+	if strings.Join(cfg.App.DisallowedEmailDomains, ",") != "example.org,test.org" {
+		t.Errorf("unexpected domains %v", cfg.App.DisallowedEmailDomains)
+	}
+	if cfg.Jobs.QueueSize != 0 {
+		t.Errorf("expected an explicit zero queue size, got %d", cfg.Jobs.QueueSize)
+	}
+	telemetryCfg := GetTelemetryConfig()
+	if telemetryCfg.SampleRatio != 0.25 {
+		t.Errorf("expected sample ratio 0.25, got %v", telemetryCfg.SampleRatio)
+	}
+	if telemetryCfg.TracesEndpoint != "tempo:4317" || telemetryCfg.MetricsEndpoint != "" {
+		t.Errorf("expected only a traces endpoint, got %q and %q", telemetryCfg.TracesEndpoint, telemetryCfg.MetricsEndpoint)
+	}
+	if err := cfg.Validate(); err != nil {
+		t.Errorf("expected a valid config, got %v", err)
+	}
+}
+
+func TestLoadRejectsInvalidYAML(t *testing.T) {
+	tests := map[string]string{
+		"unknown key": "server:\n  prot: 8080\n",
+		"wrong type":  "server:\n  port: http\n",
+	}
+	for name, content := range tests {
+		t.Run(name, func(t *testing.T) {
+			os.Clearenv()
+			defer os.Clearenv()
+			_ = os.Setenv("CONFIG_YAML", writeConfigYAML(t, content))
+
+			if _, err := Load(); err == nil {
+				t.Fatal("expected an error")
+			}
+		})
+	}
+}
+
+func TestLoadRequiresExplicitYAMLFile(t *testing.T) {
+	os.Clearenv()
+	defer os.Clearenv()
+	_ = os.Setenv("CONFIG_YAML", filepath.Join(t.TempDir(), "missing.yaml"))
+
+	if _, err := Load(); err == nil {
+		t.Fatal("expected an error for a missing CONFIG_YAML")
+	}
+}
@@ -0,0 +1,227 @@
+package config
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/url"
+	"os"
+	"slices"
+	"strings"
+
+	"go.opentelemetry.io/otel/exporters/otlp/otlplog/otlploggrpc"
+	"go.opentelemetry.io/otel/exporters/otlp/otlplog/otlploghttp"
+	"go.opentelemetry.io/otel/exporters/otlp/otlpmetric/otlpmetricgrpc"
+	"go.opentelemetry.io/otel/exporters/otlp/otlpmetric/otlpmetrichttp"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracegrpc"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	sdklog "go.opentelemetry.io/otel/sdk/log"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+)
+
+// OTLP transport protocols selected by OTEL_EXPORTER_OTLP_PROTOCOL
+const (
+	ProtocolGRPC         = "grpc"
+	ProtocolHTTPProtobuf = "http/protobuf"
+)
+
+// Default collector endpoints of each protocol
+const (
+	defaultGRPCEndpoint = "localhost:4317"
+	defaultHTTPEndpoint = "localhost:4318"
+)
+
+// protocolName is the protocol as written in log messages
+func (cfg *TelemetryConfig) protocolName() string {
+	if cfg.Protocol == ProtocolHTTPProtobuf {
+		return "HTTP"
+	}
+	return "gRPC"
+}
+
+// signalEndpoint returns override when set and the shared endpoint otherwise.
+// Like the OTEL_EXPORTER_OTLP_ENDPOINT spec, a shared HTTP URL gets the
+// signal path appended while per-signal URLs are used as they are
+func (cfg *TelemetryConfig) signalEndpoint(override, path string) string {
+	if override != "" {
+		return override
+	}
+	if cfg.Protocol == ProtocolHTTPProtobuf && isURL(cfg.OTLPEndpoint) {
+		return strings.TrimSuffix(cfg.OTLPEndpoint, "/") + path
+	}
+	return cfg.OTLPEndpoint
+}
+
+// signalHeaders returns override when set and the shared headers otherwise;
+// like the OpenTelemetry exporters, per-signal headers replace the shared
+// ones instead of being merged with them
+func (cfg *TelemetryConfig) signalHeaders(override map[string]string) map[string]string {
+	if len(override) > 0 {
+		return override
+	}
+	return cfg.Headers
+}
+
+// parseHeaders parses OTEL_EXPORTER_OTLP_HEADERS: comma-separated key=value
+// pairs whose keys and values are URL-encoded, e.g.
+// "Authorization=Basic%20dXNlcjpwYXNz,X-Scope-OrgID=tenant". Errors leave
+// the values out, as they usually hold credentials
+func parseHeaders(value string) (map[string]string, error) {
+	headers := map[string]string{}
+	for i, pair := range strings.Split(value, ",") {
+		if strings.TrimSpace(pair) == "" {
+			continue
+		}
+		rawKey, rawValue, ok := strings.Cut(pair, "=")
+		if !ok {
+			return nil, fmt.Errorf("header %d is not a key=value pair", i+1)
+		}
+		key, err := url.PathUnescape(strings.TrimSpace(rawKey))
+		if err != nil || key == "" {
+			return nil, fmt.Errorf("header %d has an invalid key", i+1)
+		}
+		decoded, err := url.PathUnescape(strings.TrimSpace(rawValue))
+		if err != nil {
+			return nil, fmt.Errorf("header %q has an invalid value", key)
+		}
+		headers[key] = decoded
+	}
+	return headers, nil
+}
+
+// getEnvAsHeaders returns the headers of key; invalid values are reported
+// by Validate and yield no headers
+func getEnvAsHeaders(key string) map[string]string {
+	headers, err := parseHeaders(os.Getenv(key))
+	if err != nil {
+		return nil
+	}
+	return headers
+}
+
+// headerNames lists the header names of headers with their values masked
+func headerNames(headers map[string]string) string {
+	names := make([]string, 0, len(headers))
+	for name := range headers {
+		names = append(names, name+"="+maskedValue)
+	}
+	slices.Sort(names)
+	return strings.Join(names, ",")
+}
+
+// isURL reports whether endpoint has a scheme. URLs choose TLS from their
+// scheme; host:port endpoints are used without TLS
+func isURL(endpoint string) bool {
+	return strings.Contains(endpoint, "://")
+}
+
+// collectorAddrs returns the host:port of each collector the enabled OTLP
+// exporters send to, without duplicates
+func (cfg *TelemetryConfig) collectorAddrs() []string {
+	var endpoints []string
+	if cfg.EnableTracing {
+		endpoints = append(endpoints, cfg.signalEndpoint(cfg.TracesEndpoint, "/v1/traces"))
+	}
+	if cfg.EnableMetrics && cfg.MetricsExporter != MetricsExporterPrometheus {
+		endpoints = append(endpoints, cfg.signalEndpoint(cfg.MetricsEndpoint, "/v1/metrics"))
+	}
+	if cfg.EnableLogging {
+		endpoints = append(endpoints, cfg.signalEndpoint(cfg.LogsEndpoint, "/v1/logs"))
+	}
+
+	var addrs []string
+	for _, endpoint := range endpoints {
+		addr := endpointAddr(endpoint)
+		if addr != "" && !slices.Contains(addrs, addr) {
+			addrs = append(addrs, addr)
+		}
+	}
+	return addrs
+}
+
+// endpointAddr returns the host:port of an endpoint, defaulting the port of
+// URLs from their scheme
+func endpointAddr(endpoint string) string {
+	if !isURL(endpoint) {
+		return endpoint
+	}
+	u, err := url.Parse(endpoint)
+	if err != nil || u.Hostname() == "" {
+		return ""
+	}
+	port := u.Port()
+	if port == "" {
+		port = "80"
+		if u.Scheme == "https" {
+			port = "443"
+		}
+	}
+	return net.JoinHostPort(u.Hostname(), port)
+}
+
+func newTraceExporter(ctx context.Context, cfg *TelemetryConfig) (sdktrace.SpanExporter, error) {
+	endpoint := cfg.signalEndpoint(cfg.TracesEndpoint, "/v1/traces")
+	headers := cfg.signalHeaders(cfg.TracesHeaders)
+	if cfg.Protocol == ProtocolHTTPProtobuf {
+		opts := []otlptracehttp.Option{otlptracehttp.WithEndpoint(endpoint), otlptracehttp.WithInsecure()}
+		if isURL(endpoint) {
+			opts = []otlptracehttp.Option{otlptracehttp.WithEndpointURL(endpoint)}
+		}
+		opts = append(opts, otlptracehttp.WithHeaders(headers))
+		return otlptracehttp.New(ctx, opts...)
+	}
+	opts := []otlptracegrpc.Option{otlptracegrpc.WithEndpoint(endpoint), otlptracegrpc.WithInsecure()}
+	if isURL(endpoint) {
+		opts = []otlptracegrpc.Option{otlptracegrpc.WithEndpointURL(endpoint)}
+	}
+	opts = append(opts, otlptracegrpc.WithHeaders(headers))
+	return otlptracegrpc.New(ctx, opts...)
+}
+
+func newMetricExporter(ctx context.Context, cfg *TelemetryConfig) (sdkmetric.Exporter, error) {
+	endpoint := cfg.signalEndpoint(cfg.MetricsEndpoint, "/v1/metrics")
+	headers := cfg.signalHeaders(cfg.MetricsHeaders)
+	if cfg.Protocol == ProtocolHTTPProtobuf {
+		opts := []otlpmetrichttp.Option{otlpmetrichttp.WithEndpoint(endpoint), otlpmetrichttp.WithInsecure()}
+		if isURL(endpoint) {
+			opts = []otlpmetrichttp.Option{otlpmetrichttp.WithEndpointURL(endpoint)}
+		}
+		opts = append(opts, otlpmetrichttp.WithHeaders(headers))
+		return otlpmetrichttp.New(ctx, opts...)
+	}
+	opts := []otlpmetricgrpc.Option{otlpmetricgrpc.WithEndpoint(endpoint), otlpmetricgrpc.WithInsecure()}
+	if isURL(endpoint) {
+		opts = []otlpmetricgrpc.Option{otlpmetricgrpc.WithEndpointURL(endpoint)}
+	}
+	opts = append(opts, otlpmetricgrpc.WithHeaders(headers))
+	return otlpmetricgrpc.New(ctx, opts...)
+}
+
+func newLogExporter(ctx context.Context, cfg *TelemetryConfig) (sdklog.Exporter, error) {
+	endpoint := cfg.signalEndpoint(cfg.LogsEndpoint, "/v1/logs")
+	headers := cfg.signalHeaders(cfg.LogsHeaders)
+	if cfg.Protocol == ProtocolHTTPProtobuf {
+		opts := []otlploghttp.Option{otlploghttp.WithEndpoint(endpoint), otlploghttp.WithInsecure()}
+		if isURL(endpoint) {
+			opts = []otlploghttp.Option{otlploghttp.WithEndpointURL(endpoint)}
+		}
+		opts = append(opts, otlploghttp.WithHeaders(headers))
+		return otlploghttp.New(ctx, opts...)
+	}
+	opts := []otlploggrpc.Option{otlploggrpc.WithEndpoint(endpoint), otlploggrpc.WithInsecure()}
+	if isURL(endpoint) {
+		opts = []otlploggrpc.Option{otlploggrpc.WithEndpointURL(endpoint)}
+	}
+	opts = append(opts, otlploggrpc.WithHeaders(headers))
+	return otlploggrpc.New(ctx, opts...)
+}
+
+// validateProtocol checks OTEL_EXPORTER_OTLP_PROTOCOL
+func validateProtocol(protocol string) error {
+	switch protocol {
+	case ProtocolGRPC, ProtocolHTTPProtobuf:
+		return nil
+	}
+	return fmt.Errorf("OTEL_EXPORTER_OTLP_PROTOCOL: unsupported protocol %q, expected %s or %s", protocol, ProtocolGRPC, ProtocolHTTPProtobuf)
+}
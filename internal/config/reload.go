@@ -0,0 +1,250 @@
+package config
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+	"github.com/joho/godotenv"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+)
+
+// reloadDebounce coalesces the burst of events an editor produces on save
+const reloadDebounce = 200 * time.Millisecond
+
+// Reloadable holds the settings that can change without a restart
+type Reloadable struct {
+	LogLevel         string
+	PageSizeDefault  int
+	PageSizeMax      int
+	TraceSampleRatio float64
+	// RateLimitReadRPS and RateLimitWriteRPS limit the user endpoints in
+	// requests per second; 0 disables the limit
+	RateLimitReadRPS  float64
+	RateLimitWriteRPS float64
+}
+
+// LoadReloadable reads the reloadable settings through lookup, which returns
+// "" for unset keys
+func LoadReloadable(lookup func(string) string) (Reloadable, error) {
+	r := Reloadable{
+		LogLevel:          "info",
+		PageSizeDefault:   10,
+		PageSizeMax:       100,
+		TraceSampleRatio:  1,
+		RateLimitReadRPS:  100,
+		RateLimitWriteRPS: 10,
+	}
+	var errs []error
+	if value := lookup("LOG_LEVEL"); value != "" {
+		r.LogLevel = value
+	}
+	parseInt := func(key string, target *int) {
+		if value := lookup(key); value != "" {
+			parsed, err := strconv.Atoi(value)
+			if err != nil {
+				errs = append(errs, fmt.Errorf("%s: %q is not an integer", key, value))
+				return
+			}
+			*target = parsed
+		}
+	}
+	parseInt("PAGINATION_DEFAULT_LIMIT", &r.PageSizeDefault)
+	parseInt("PAGINATION_MAX_LIMIT", &r.PageSizeMax)
+	parseFloat := func(key string, target *float64) {
+		if value := lookup(key); value != "" {
+			parsed, err := strconv.ParseFloat(value, 64)
+			if err != nil {
+				errs = append(errs, fmt.Errorf("%s: %q is not a number", key, value))
+				return
+			}
+			*target = parsed
+		}
+	}
+	parseFloat("OTEL_TRACES_SAMPLER_ARG", &r.TraceSampleRatio)
+	parseFloat("RATE_LIMIT_READ_RPS", &r.RateLimitReadRPS)
+	parseFloat("RATE_LIMIT_WRITE_RPS", &r.RateLimitWriteRPS)
+	if len(errs) > 0 {
+		return r, errors.Join(errs...)
+	}
+	return r, r.Validate()
+}
+
+// Validate checks that the settings can be applied
+func (r Reloadable) Validate() error {
+	switch r.LogLevel {
+	case "debug", "info", "warn", "error":
+	default:
+		return fmt.Errorf("LOG_LEVEL: unsupported level %q", r.LogLevel)
+	}
+	if r.PageSizeDefault < 1 || r.PageSizeMax < r.PageSizeDefault {
+		return fmt.Errorf("PAGINATION_DEFAULT_LIMIT must be at least 1 and at most PAGINATION_MAX_LIMIT")
+	}
+	if r.TraceSampleRatio < 0 || r.TraceSampleRatio > 1 {
+		return fmt.Errorf("OTEL_TRACES_SAMPLER_ARG must be between 0 and 1")
+	}
+	if r.RateLimitReadRPS < 0 || r.RateLimitWriteRPS < 0 {
+		return fmt.Errorf("RATE_LIMIT_READ_RPS and RATE_LIMIT_WRITE_RPS must not be negative")
+	}
+	return nil
+}
+
+// ReloadHook applies reloadable settings; returning an error rejects them
+type ReloadHook func(Reloadable) error
+
+// Watcher reloads the reloadable settings when the config file changes
+type Watcher struct {
+	file string
+
+	mu      sync.Mutex
+	current Reloadable
+	hooks   []ReloadHook
+	version atomic.Int64
+
+	reloads metric.Int64Counter
+}
+
+// NewWatcher creates a watcher for file whose settings start as initial
+func NewWatcher(file string, initial Reloadable) (*Watcher, error) {
+	w := &Watcher{file: file, current: initial}
+	w.version.Store(1)
+
+	meter := otel.Meter("config")
+	reloads, err := meter.Int64Counter(
+		"config_reloads_total",
+		metric.WithDescription("Total number of configuration reloads by result"),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create reloads metric: %w", err)
+	}
+	w.reloads = reloads
+
+	_, err = meter.Int64ObservableGauge(
+		"config_version",
+		metric.WithDescription("Version of the applied configuration, incremented by every applied reload"),
+		metric.WithInt64Callback(func(_ context.Context, o metric.Int64Observer) error {
+			o.Observe(w.version.Load())
+			return nil
+		}),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create config version metric: %w", err)
+	}
+
+	return w, nil
+}
+
+// OnChange registers hook. Hooks run in registration order; when one rejects
+// a change, the hooks before it are re-run with the previous settings
+func (w *Watcher) OnChange(hook ReloadHook) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.hooks = append(w.hooks, hook)
+}
+
+// Current returns the applied settings
+func (w *Watcher) Current() Reloadable {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.current
+}
+
+// Version returns the applied configuration version, starting at 1
+func (w *Watcher) Version() int64 {
+	return w.version.Load()
+}
+
+// Reload reads the file and applies its settings. Keys missing from the file
+// fall back to the process environment
+func (w *Watcher) Reload(ctx context.Context) error {
+	values, err := godotenv.Read(w.file)
+	if err != nil {
+		w.record(ctx, "rejected")
+		return fmt.Errorf("failed to read %s: %w", w.file, err)
+	}
+	next, err := LoadReloadable(func(key string) string {
+		if value, ok := values[key]; ok {
+			return value
+		}
+		return os.Getenv(key)
+	})
+	if err != nil {
+		w.record(ctx, "rejected")
+		return fmt.Errorf("configuration change rejected: %w", err)
+	}
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if next == w.current {
+		w.record(ctx, "unchanged")
+		return nil
+	}
+	for i, hook := range w.hooks {
+		if err := hook(next); err != nil {
+			for _, applied := range w.hooks[:i] {
+				_ = applied(w.current)
+			}
+			w.record(ctx, "rejected")
+			return fmt.Errorf("configuration change rejected: %w", err)
+		}
+	}
+	w.current = next
+	version := w.version.Add(1)
+	w.record(ctx, "applied")
+	log.Printf("Applied configuration version %d from %s", version, w.file)
+	return nil
+}
+
+// Run watches the file until ctx is done and reloads it after each change
+func (w *Watcher) Run(ctx context.Context) error {
+	fsw, err := fsnotify.NewWatcher()
+	if err != nil {
+		return fmt.Errorf("failed to create file watcher: %w", err)
+	}
+	defer func() { _ = fsw.Close() }()
+
+	// Watch the directory so files replaced by editors are still noticed
+	if err := fsw.Add(filepath.Dir(w.file)); err != nil {
+		return fmt.Errorf("failed to watch %s: %w", w.file, err)
+	}
+	target := filepath.Clean(w.file)
+
+	var debounce <-chan time.Time
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case event, ok := <-fsw.Events:
+			if !ok {
+				return nil
+			}
+			if filepath.Clean(event.Name) == target && event.Op&(fsnotify.Write|fsnotify.Create|fsnotify.Rename) != 0 {
+				debounce = time.After(reloadDebounce)
+			}
+		case err, ok := <-fsw.Errors:
+			if !ok {
+				return nil
+			}
+			log.Printf("Config watcher error: %v", err)
+		case <-debounce:
+			debounce = nil
+			if err := w.Reload(ctx); err != nil {
+				log.Printf("Config reload failed: %v", err)
+			}
+		}
+	}
+}
+
+func (w *Watcher) record(ctx context.Context, result string) {
+	w.reloads.Add(ctx, 1, metric.WithAttributes(attribute.String("result", result)))
+}
@@ -0,0 +1,183 @@
+package config
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func lookupMap(values map[string]string) func(string) string {
+	return func(key string) string { return values[key] }
+}
+
+func TestLoadReloadable(t *testing.T) {
+	r, err := LoadReloadable(lookupMap(nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defaults := Reloadable{
+		LogLevel:          "info",
+		PageSizeDefault:   10,
+		PageSizeMax:       100,
+		TraceSampleRatio:  1,
+		RateLimitReadRPS:  100,
+		RateLimitWriteRPS: 10,
+	}
+	if r != defaults {
+		t.Fatalf("unexpected defaults: %+v", r)
+	}
+
+	invalid := []map[string]string{
+		{"LOG_LEVEL": "verbose"},
+		{"PAGINATION_DEFAULT_LIMIT": "ten"},
+		{"PAGINATION_DEFAULT_LIMIT": "50", "PAGINATION_MAX_LIMIT": "20"},
+		{"OTEL_TRACES_SAMPLER_ARG": "1.5"},
+		{"OTEL_TRACES_SAMPLER_ARG": "half"},
+		{"RATE_LIMIT_READ_RPS": "fast"},
+		{"RATE_LIMIT_WRITE_RPS": "-1"},
+	}
+	for _, values := range invalid {
+		if _, err := LoadReloadable(lookupMap(values)); err == nil {
+			t.Errorf("expected %v to be rejected", values)
+		}
+	}
+}
+
+func newTestWatcher(t *testing.T, contents string) (*Watcher, string) {
+	t.Helper()
+	file := filepath.Join(t.TempDir(), ".env")
+	if err := os.WriteFile(file, []byte(contents), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	initial, _ := LoadReloadable(lookupMap(nil))
+	w, err := NewWatcher(file, initial)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return w, file
+}
+
+func TestWatcherReloadAppliesAndRejects(t *testing.T) {
+	w, file := newTestWatcher(t, "LOG_LEVEL=debug\nPAGINATION_MAX_LIMIT=50\n")
+	var applied []Reloadable
+	w.OnChange(func(r Reloadable) error {
+		applied = append(applied, r)
+		return nil
+	})
+
+	if err := w.Reload(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if w.Version() != 2 || w.Current().LogLevel != "debug" || w.Current().PageSizeMax != 50 {
+		t.Fatalf("change not applied: version %d, %+v", w.Version(), w.Current())
+	}
+
+	// Unchanged files do not bump the version
+	if err := w.Reload(context.Background()); err != nil || w.Version() != 2 || len(applied) != 1 {
+		t.Fatalf("unexpected reload of unchanged file: %v, version %d", err, w.Version())
+	}
+
+	if err := os.WriteFile(file, []byte("LOG_LEVEL=loud\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Reload(context.Background()); err == nil {
+		t.Fatal("expected invalid settings to be rejected")
+	}
+	if w.Version() != 2 || w.Current().LogLevel != "debug" {
+		t.Fatalf("rejected change must keep the applied settings: %+v", w.Current())
+	}
+}
+
+func TestWatcherRollsBackWhenHookRejects(t *testing.T) {
+	w, _ := newTestWatcher(t, "LOG_LEVEL=warn\n")
+	var levels []string
+	w.OnChange(func(r Reloadable) error {
+		levels = append(levels, r.LogLevel)
+		return nil
+	})
+	w.OnChange(func(Reloadable) error { return errors.New("nope") })
+
+	if err := w.Reload(context.Background()); err == nil {
+		t.Fatal("expected hook error")
+	}
+	if len(levels) != 2 || levels[0] != "warn" || levels[1] != "info" {
+		t.Fatalf("first hook should be re-run with previous settings, got %v", levels)
+	}
+	if w.Version() != 1 {
+		t.Fatalf("version should not change, got %d", w.Version())
+	}
+}
+
+func TestWatcherRunReloadsOnWrite(t *testing.T) {
+	w, file := newTestWatcher(t, "LOG_LEVEL=info\n")
+	changed := make(chan Reloadable, 1)
+	w.OnChange(func(r Reloadable) error {
+		changed <- r
+		return nil
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	done := make(chan error, 1)
+	go func() { done <- w.Run(ctx) }()
+
+	// Give the watcher time to start before writing
+	time.Sleep(50 * time.Millisecond)
+	if err := os.WriteFile(file, []byte("LOG_LEVEL=error\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	select {
+	case r := <-changed:
+		if r.LogLevel != "error" {
+			t.Fatalf("unexpected settings: %+v", r)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("change was not picked up")
+	}
+
+	cancel()
+	if err := <-done; err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestDynamicSampler(t *testing.T) {
+	s := NewDynamicSampler(SamplerTraceIDRatio, 2)
+	if s.Ratio() != 1 {
+		t.Fatalf("ratio should be clamped to 1, got %v", s.Ratio())
+	}
+	s.SetRatio(0.25)
+	if s.Ratio() != 0.25 || s.Description() != "DynamicSampler{TraceIDRatioBased{0.25}}" {
+		t.Fatalf("unexpected sampler: %v %s", s.Ratio(), s.Description())
+	}
+}
+
+func TestDynamicSamplerKinds(t *testing.T) {
+	tests := []struct {
+		name string
+		want string
+	}{
+		{SamplerAlwaysOn, "DynamicSampler{AlwaysOnSampler}"},
+		{SamplerAlwaysOff, "DynamicSampler{AlwaysOffSampler}"},
+		{SamplerRatio, "DynamicSampler{TraceIDRatioBased{0.5}}"},
+		{SamplerParentBasedTraceIDRatio, "DynamicSampler{ParentBased{root:TraceIDRatioBased{0.5},remoteParentSampled:AlwaysOnSampler,remoteParentNotSampled:AlwaysOffSampler,localParentSampled:AlwaysOnSampler,localParentNotSampled:AlwaysOffSampler}}"},
+		{"unknown", "DynamicSampler{ParentBased{root:TraceIDRatioBased{0.5},remoteParentSampled:AlwaysOnSampler,remoteParentNotSampled:AlwaysOffSampler,localParentSampled:AlwaysOnSampler,localParentNotSampled:AlwaysOffSampler}}"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := NewDynamicSampler(tt.name, 0.5).Description(); got != tt.want {
+				t.Errorf("expected %s, got %s", tt.want, got)
+			}
+		})
+	}
+
+	s := NewDynamicSampler(SamplerAlwaysOff, 1)
+	s.SetRatio(0.5)
+	if s.Description() != "DynamicSampler{AlwaysOffSampler}" {
+		t.Errorf("SetRatio should keep the sampler kind, got %s", s.Description())
+	}
+}
@@ -0,0 +1,138 @@
+package config
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"sync/atomic"
+
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Samplers selected by OTEL_TRACES_SAMPLER, as named by the OpenTelemetry
+// specification; SamplerRatio is accepted as a short form of
+// SamplerTraceIDRatio
+const (
+	SamplerAlwaysOn                = "always_on"
+	SamplerAlwaysOff               = "always_off"
+	SamplerTraceIDRatio            = "traceidratio"
+	SamplerRatio                   = "ratio"
+	SamplerParentBasedAlwaysOn     = "parentbased_always_on"
+	SamplerParentBasedAlwaysOff    = "parentbased_always_off"
+	SamplerParentBasedTraceIDRatio = "parentbased_traceidratio"
+)
+
+// DynamicSampler samples traces with the configured sampler; the ratio of
+// the ratio samplers can be changed, and tracing turned off, while the tracer
+// provider is running
+type DynamicSampler struct {
+	current  atomic.Pointer[samplerHolder]
+	disabled atomic.Bool
+}
+
+type samplerHolder struct {
+	name    string
+	ratio   float64
+	sampler sdktrace.Sampler
+}
+
+// NewDynamicSampler creates the sampler called name with ratio clamped to
+// [0, 1]. Unknown names fall back to SamplerParentBasedTraceIDRatio
+func NewDynamicSampler(name string, ratio float64) *DynamicSampler {
+	if validateSampler(name) != nil {
+		name = SamplerParentBasedTraceIDRatio
+	}
+	s := &DynamicSampler{}
+	s.store(name, ratio)
+	return s
+}
+
+// SetRatio changes the fraction of sampled traces. Samplers that do not use
+// a ratio keep their decisions
+func (s *DynamicSampler) SetRatio(ratio float64) {
+	s.store(s.current.Load().name, ratio)
+}
+
+// Ratio returns the current sampling ratio
+func (s *DynamicSampler) Ratio() float64 {
+	return s.current.Load().ratio
+}
+
+// SetEnabled turns tracing on or off; while off every span is dropped
+func (s *DynamicSampler) SetEnabled(enabled bool) {
+	s.disabled.Store(!enabled)
+}
+
+// Enabled reports whether spans are sampled by the configured sampler
+func (s *DynamicSampler) Enabled() bool {
+	return !s.disabled.Load()
+}
+
+// ShouldSample implements sdktrace.Sampler. Spans started under
+// WithoutTracing, or while tracing is off, are dropped whatever the sampler
+func (s *DynamicSampler) ShouldSample(p sdktrace.SamplingParameters) sdktrace.SamplingResult {
+	if s.disabled.Load() || (p.ParentContext != nil && p.ParentContext.Value(untracedKey{}) != nil) {
+		return sdktrace.SamplingResult{
+			Decision:   sdktrace.Drop,
+			Tracestate: trace.SpanContextFromContext(p.ParentContext).TraceState(),
+		}
+	}
+	return s.current.Load().sampler.ShouldSample(p)
+}
+
+// Description implements sdktrace.Sampler
+func (s *DynamicSampler) Description() string {
+	return fmt.Sprintf("DynamicSampler{%s}", s.current.Load().sampler.Description())
+}
+
+type untracedKey struct{}
+
+// WithoutTracing returns a copy of ctx in which DynamicSampler drops every
+// span, e.g. the database and cache spans of a health check
+func WithoutTracing(ctx context.Context) context.Context {
+	return context.WithValue(ctx, untracedKey{}, true)
+}
+
+// sampleAll samples every trace until the returned func restores the
+// previous sampler
+func (s *DynamicSampler) sampleAll() (restore func()) {
+	previous := s.current.Swap(&samplerHolder{name: SamplerAlwaysOn, ratio: 1, sampler: sdktrace.AlwaysSample()})
+	return func() { s.current.Store(previous) }
+}
+
+func (s *DynamicSampler) store(name string, ratio float64) {
+	ratio = math.Max(0, math.Min(1, ratio))
+	s.current.Store(&samplerHolder{name: name, ratio: ratio, sampler: buildSampler(name, ratio)})
+}
+
+func buildSampler(name string, ratio float64) sdktrace.Sampler {
+	ratioSampler := sdktrace.AlwaysSample()
+	if ratio < 1 {
+		ratioSampler = sdktrace.TraceIDRatioBased(ratio)
+	}
+	switch name {
+	case SamplerAlwaysOn:
+		return sdktrace.AlwaysSample()
+	case SamplerAlwaysOff:
+		return sdktrace.NeverSample()
+	case SamplerTraceIDRatio, SamplerRatio:
+		return ratioSampler
+	case SamplerParentBasedAlwaysOn:
+		return sdktrace.ParentBased(sdktrace.AlwaysSample())
+	case SamplerParentBasedAlwaysOff:
+		return sdktrace.ParentBased(sdktrace.NeverSample())
+	default:
+		return sdktrace.ParentBased(ratioSampler)
+	}
+}
+
+// validateSampler checks OTEL_TRACES_SAMPLER
+func validateSampler(name string) error {
+	switch name {
+	case SamplerAlwaysOn, SamplerAlwaysOff, SamplerTraceIDRatio, SamplerRatio,
+		SamplerParentBasedAlwaysOn, SamplerParentBasedAlwaysOff, SamplerParentBasedTraceIDRatio:
+		return nil
+	}
+	return fmt.Errorf("OTEL_TRACES_SAMPLER: unsupported sampler %q", name)
+}
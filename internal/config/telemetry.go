@@ -4,32 +4,93 @@ import (
 	"context"
 	"fmt"
 	"log"
+	"net/http"
+	"os"
+	"strings"
+	"sync/atomic"
 	"time"
 
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
 	"go.opentelemetry.io/contrib/instrumentation/runtime"
 	"go.opentelemetry.io/otel"
-	"go.opentelemetry.io/otel/exporters/otlp/otlplog/otlploggrpc"
-	"go.opentelemetry.io/otel/exporters/otlp/otlpmetric/otlpmetricgrpc"
-	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracegrpc"
+	otelprom "go.opentelemetry.io/otel/exporters/prometheus"
 	"go.opentelemetry.io/otel/propagation"
 	sdklog "go.opentelemetry.io/otel/sdk/log"
 	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/metric/exemplar"
 	"go.opentelemetry.io/otel/sdk/resource"
 	sdktrace "go.opentelemetry.io/otel/sdk/trace"
 	semconv "go.opentelemetry.io/otel/semconv/v1.21.0"
+
+	"arquivolivre.com.br/otel/internal/buildinfo"
+	"arquivolivre.com.br/otel/internal/tenant"
 )
 
 const defaultEnabledValue = "true"
 
+// Metric exporters selected by OTEL_METRICS_EXPORTER
+const (
+	MetricsExporterOTLP       = "otlp"
+	MetricsExporterPrometheus = "prometheus"
+	MetricsExporterBoth       = "both"
+)
+
+// Exemplar filters selected by OTEL_METRICS_EXEMPLAR_FILTER
+const (
+	ExemplarFilterTraceBased = "trace_based"
+	ExemplarFilterAlwaysOn   = "always_on"
+	ExemplarFilterAlwaysOff  = "always_off"
+)
+
 type TelemetryConfig struct {
-	ServiceName          string
-	ServiceVersion       string
-	Environment          string
-	OTLPGRPCEndpoint     string
+	ServiceName    string
+	ServiceVersion string
+	Environment    string
+	// MetricsExporter is MetricsExporterOTLP, MetricsExporterPrometheus or
+	// MetricsExporterBoth
+	MetricsExporter string
+	// Protocol is ProtocolGRPC or ProtocolHTTPProtobuf
+	Protocol string
+	// OTLPEndpoint is shared by the signals without their own endpoint; each
+	// is either host:port or a URL
+	OTLPEndpoint    string
+	TracesEndpoint  string
+	MetricsEndpoint string
+	LogsEndpoint    string
+	// Headers are sent with every export, e.g. the credentials of a hosted
+	// backend; the headers of a signal replace them when set
+	Headers              map[string]string
+	TracesHeaders        map[string]string
+	MetricsHeaders       map[string]string
+	LogsHeaders          map[string]string
 	EnableMetrics        bool
 	EnableTracing        bool
 	EnableLogging        bool
 	EnableRuntimeMetrics bool
+	// Sampler is one of the OTEL_TRACES_SAMPLER names; SampleRatio is the
+	// fraction of traces sampled by the ratio samplers and can be changed at
+	// runtime through TelemetryProvider.Sampler
+	Sampler     string
+	SampleRatio float64
+	// UntracedPaths are request paths served without spans or HTTP metrics,
+	// such as probes and scrapes
+	UntracedPaths []string
+	// ExemplarFilter is one of the OTEL_METRICS_EXEMPLAR_FILTER names
+	ExemplarFilter string
+	// BatchTimeout, BatchExportTimeout, MaxQueueSize and MaxExportBatchSize
+	// tune the batch span processor; zero keeps the SDK default
+	BatchTimeout       time.Duration
+	BatchExportTimeout time.Duration
+	MaxQueueSize       int
+	MaxExportBatchSize int
+	// MetricInterval and MetricTimeout tune the periodic reader of the OTLP
+	// metric exporter; zero keeps defaultMetricInterval and the SDK timeout
+	MetricInterval time.Duration
+	MetricTimeout  time.Duration
+	// FailFast makes InitTelemetry fail when a collector of the OTLP
+	// exporters cannot be reached, instead of exporting in the background
+	FailFast bool
 }
 
 // TelemetryProvider holds the telemetry providers
@@ -37,7 +98,24 @@ type TelemetryProvider struct {
 	TracerProvider *sdktrace.TracerProvider
 	MeterProvider  *sdkmetric.MeterProvider
 	LoggerProvider *sdklog.LoggerProvider
-	Shutdown       func(context.Context) error
+	// PrometheusHandler serves the metrics in Prometheus text format when the
+	// Prometheus exporter is enabled; it is nil otherwise
+	PrometheusHandler http.Handler
+	Sampler           *DynamicSampler
+	// CollectorAddrs are the host:port of the collectors the OTLP exporters
+	// send to
+	CollectorAddrs []string
+	// UntracedPaths are the request paths to leave out of tracing and the
+	// HTTP metrics
+	UntracedPaths []string
+	Shutdown      func(context.Context) error
+
+	// metricsExport and logsExport switch the export of the metrics and
+	// logs; they are nil when the signal is disabled
+	metricsExport *atomic.Bool
+	logsExport    *atomic.Bool
+	// exports follow the OTLP exports of each enabled signal
+	exports map[string]*exportHealth
 }
 
 // InitTelemetry initializes OpenTelemetry with tracing and metrics
@@ -51,6 +129,7 @@ func InitTelemetry(cfg *TelemetryConfig) (*TelemetryProvider, error) {
 			semconv.ServiceVersion(cfg.ServiceVersion),
 			semconv.DeploymentEnvironment(cfg.Environment),
 		),
+		resource.WithAttributes(buildinfo.Get().Attributes()...),
 		resource.WithFromEnv(),
 		resource.WithProcess(),
 		resource.WithOS(),
@@ -64,11 +143,22 @@ func InitTelemetry(cfg *TelemetryConfig) (*TelemetryProvider, error) {
 	var shutdownFuncs []func(context.Context) error
 	var tracerProvider *sdktrace.TracerProvider
 	var meterProvider *sdkmetric.MeterProvider
+	var prometheusHandler http.Handler
 	var loggerProvider *sdklog.LoggerProvider
+	var metricsExport, logsExport *atomic.Bool
+	exports := map[string]*exportHealth{}
+	sampler := NewDynamicSampler(cfg.Sampler, cfg.SampleRatio)
+
+	if cfg.FailFast {
+		if err := probeCollectors(ctx, cfg.collectorAddrs()); err != nil {
+			return nil, err
+		}
+	}
 
 	// Initialize tracing if enabled
 	if cfg.EnableTracing {
-		tp, shutdown, err := initTracing(ctx, res, cfg)
+		exports[SignalTraces] = newExportHealth(SignalTraces)
+		tp, shutdown, err := initTracing(ctx, res, cfg, sampler, exports[SignalTraces])
 		if err != nil {
 			return nil, fmt.Errorf("failed to initialize tracing: %w", err)
 		}
@@ -87,11 +177,16 @@ func InitTelemetry(cfg *TelemetryConfig) (*TelemetryProvider, error) {
 
 	// Initialize metrics if enabled
 	if cfg.EnableMetrics {
-		mp, shutdown, err := initMetrics(ctx, res, cfg)
+		metricsExport = newExportSwitch()
+		if cfg.MetricsExporter != MetricsExporterPrometheus {
+			exports[SignalMetrics] = newExportHealth(SignalMetrics)
+		}
+		mp, handler, shutdown, err := initMetrics(ctx, res, cfg, metricsExport, exports[SignalMetrics])
 		if err != nil {
 			return nil, fmt.Errorf("failed to initialize metrics: %w", err)
 		}
 		meterProvider = mp
+		prometheusHandler = handler
 		shutdownFuncs = append(shutdownFuncs, shutdown)
 
 		// Set global meter provider
@@ -100,7 +195,9 @@ func InitTelemetry(cfg *TelemetryConfig) (*TelemetryProvider, error) {
 
 	// Initialize logging if enabled
 	if cfg.EnableLogging {
-		lp, shutdown, err := initLogging(ctx, res, cfg)
+		logsExport = newExportSwitch()
+		exports[SignalLogs] = newExportHealth(SignalLogs)
+		lp, shutdown, err := initLogging(ctx, res, cfg, logsExport, exports[SignalLogs])
 		if err != nil {
 			return nil, fmt.Errorf("failed to initialize logging: %w", err)
 		}
@@ -112,7 +209,8 @@ func InitTelemetry(cfg *TelemetryConfig) (*TelemetryProvider, error) {
 		// The logger provider is used directly by the bridge
 	}
 
-	// Combined shutdown function
+	// Combined shutdown function; providers are flushed in the order they were
+	// started, so logs emitted while flushing traces and metrics still go out
 	shutdown := func(ctx context.Context) error {
 		var errs []error
 		for _, fn := range shutdownFuncs {
@@ -127,51 +225,87 @@ func InitTelemetry(cfg *TelemetryConfig) (*TelemetryProvider, error) {
 	}
 
 	return &TelemetryProvider{
-		TracerProvider: tracerProvider,
-		MeterProvider:  meterProvider,
-		LoggerProvider: loggerProvider,
-		Shutdown:       shutdown,
+		TracerProvider:    tracerProvider,
+		MeterProvider:     meterProvider,
+		LoggerProvider:    loggerProvider,
+		PrometheusHandler: prometheusHandler,
+		Sampler:           sampler,
+		CollectorAddrs:    cfg.collectorAddrs(),
+		UntracedPaths:     cfg.UntracedPaths,
+		Shutdown:          shutdown,
+		metricsExport:     metricsExport,
+		logsExport:        logsExport,
+		exports:           exports,
 	}, nil
 }
 
-// initTracing initializes tracing with OTLP gRPC exporter
-func initTracing(ctx context.Context, res *resource.Resource, cfg *TelemetryConfig) (*sdktrace.TracerProvider, func(context.Context) error, error) {
-	otlpExporter, err := otlptracegrpc.New(ctx,
-		otlptracegrpc.WithEndpoint(cfg.OTLPGRPCEndpoint),
-		otlptracegrpc.WithInsecure(), // Use WithTLSClientConfig for secure connections
-	)
+// initTracing initializes tracing with an OTLP exporter whose exports are
+// followed by health
+func initTracing(ctx context.Context, res *resource.Resource, cfg *TelemetryConfig, sampler sdktrace.Sampler, health *exportHealth) (*sdktrace.TracerProvider, func(context.Context) error, error) {
+	otlpExporter, err := newTraceExporter(ctx, cfg)
 	if err != nil {
-		return nil, nil, fmt.Errorf("failed to create OTLP gRPC trace exporter: %w", err)
+		return nil, nil, fmt.Errorf("failed to create OTLP %s trace exporter: %w", cfg.protocolName(), err)
 	}
 
 	tracerProvider := sdktrace.NewTracerProvider(
-		sdktrace.WithBatcher(otlpExporter),
+		sdktrace.WithSpanProcessor(tenant.SpanProcessor{}),
+		sdktrace.WithBatcher(healthSpanExporter{SpanExporter: otlpExporter, health: health}, cfg.batchOptions()...),
 		sdktrace.WithResource(res),
-		sdktrace.WithSampler(sdktrace.AlwaysSample()),
+		sdktrace.WithSampler(sampler),
 	)
 
-	log.Println("OTLP gRPC trace exporter initialized for Grafana Tempo via Alloy")
+	log.Printf("OTLP %s trace exporter initialized for Grafana Tempo via Alloy", cfg.protocolName())
 	return tracerProvider, tracerProvider.Shutdown, nil
 }
 
-// initMetrics initializes metrics with OTLP gRPC exporter
-func initMetrics(ctx context.Context, res *resource.Resource, cfg *TelemetryConfig) (*sdkmetric.MeterProvider, func(context.Context) error, error) {
-	otlpExporter, err := otlpmetricgrpc.New(ctx,
-		otlpmetricgrpc.WithEndpoint(cfg.OTLPGRPCEndpoint),
-		otlpmetricgrpc.WithInsecure(), // Use WithTLSClientConfig for secure connections
-	)
-	if err != nil {
-		return nil, nil, fmt.Errorf("failed to create OTLP gRPC metric exporter: %w", err)
+// initMetrics initializes metrics with the OTLP exporter, the Prometheus
+// exporter or both; the handler serves the Prometheus exposition and is nil
+// without it.
+//
+// Histograms keep exemplars linking their buckets to traces: a measurement
+// recorded with a context holding a sampled span, such as the HTTP and query
+// durations recorded with the request context, is offered to the bucket's
+// reservoir with its trace and span IDs. The OTLP exporter sends exemplars to
+// Mimir through Alloy, and the Prometheus exporter serves them as trace_id
+// labels in the OpenMetrics format. Grafana's Mimir data source maps trace_id
+// to Tempo, so a latency spike links to a trace that caused it.
+//
+// Metrics are only exported, or served, while export is on. The OTLP exports
+// are followed by health, which is nil without the OTLP exporter
+func initMetrics(ctx context.Context, res *resource.Resource, cfg *TelemetryConfig, export *atomic.Bool, health *exportHealth) (*sdkmetric.MeterProvider, http.Handler, func(context.Context) error, error) {
+	opts := []sdkmetric.Option{
+		sdkmetric.WithResource(res),
+		sdkmetric.WithExemplarFilter(exemplarFilter(cfg.ExemplarFilter)),
 	}
 
-	meterProvider := sdkmetric.NewMeterProvider(
-		sdkmetric.WithReader(sdkmetric.NewPeriodicReader(otlpExporter, sdkmetric.WithInterval(15*time.Second))),
-		sdkmetric.WithResource(res),
-	)
+	if cfg.MetricsExporter != MetricsExporterPrometheus {
+		otlpExporter, err := newMetricExporter(ctx, cfg)
+		if err != nil {
+			return nil, nil, nil, fmt.Errorf("failed to create OTLP %s metric exporter: %w", cfg.protocolName(), err)
+		}
+		exporter := switchedMetricExporter{Exporter: healthMetricExporter{Exporter: otlpExporter, health: health}, on: export}
+		opts = append(opts, sdkmetric.WithReader(sdkmetric.NewPeriodicReader(exporter, cfg.readerOptions()...)))
+		log.Printf("OTLP %s metric exporter initialized for Grafana Mimir via Alloy", cfg.protocolName())
+	}
+
+	var handler http.Handler
+	if cfg.MetricsExporter == MetricsExporterPrometheus || cfg.MetricsExporter == MetricsExporterBoth {
+		registry := prometheus.NewRegistry()
+		promExporter, err := otelprom.New(otelprom.WithRegisterer(registry))
+		if err != nil {
+			return nil, nil, nil, fmt.Errorf("failed to create Prometheus metric exporter: %w", err)
+		}
+		opts = append(opts, sdkmetric.WithReader(promExporter))
+		// Exemplars are only part of the OpenMetrics exposition
+		handler = switchedHandler(promhttp.HandlerFor(registry, promhttp.HandlerOpts{EnableOpenMetrics: true}), export)
+		log.Println("Prometheus metric exporter initialized on /metrics")
+	}
+
+	meterProvider := sdkmetric.NewMeterProvider(opts...)
 
 	// Start runtime metrics collection if enabled
 	if cfg.EnableRuntimeMetrics {
-		err = runtime.Start(runtime.WithMinimumReadMemStatsInterval(15 * time.Second))
+		err := runtime.Start(runtime.WithMinimumReadMemStatsInterval(15 * time.Second))
 		if err != nil {
 			log.Printf("Warning: Failed to start runtime metrics collection: %v", err)
 		} else {
@@ -179,42 +313,127 @@ func initMetrics(ctx context.Context, res *resource.Resource, cfg *TelemetryConf
 		}
 	}
 
-	log.Println("OTLP gRPC metric exporter initialized for Grafana Mimir via Alloy")
-	return meterProvider, meterProvider.Shutdown, nil
+	return meterProvider, handler, meterProvider.Shutdown, nil
 }
 
-// initLogging initializes logging with OTLP gRPC exporter
-func initLogging(ctx context.Context, res *resource.Resource, cfg *TelemetryConfig) (*sdklog.LoggerProvider, func(context.Context) error, error) {
-	otlpExporter, err := otlploggrpc.New(ctx,
-		otlploggrpc.WithEndpoint(cfg.OTLPGRPCEndpoint),
-		otlploggrpc.WithInsecure(), // Use WithTLSClientConfig for secure connections
-	)
+// defaultMetricInterval is the interval of the OTLP metric exports when
+// MetricInterval is not set
+const defaultMetricInterval = 15 * time.Second
+
+// batchOptions returns the batch span processor options that are set
+func (cfg *TelemetryConfig) batchOptions() []sdktrace.BatchSpanProcessorOption {
+	var opts []sdktrace.BatchSpanProcessorOption
+	if cfg.BatchTimeout > 0 {
+		opts = append(opts, sdktrace.WithBatchTimeout(cfg.BatchTimeout))
+	}
+	if cfg.BatchExportTimeout > 0 {
+		opts = append(opts, sdktrace.WithExportTimeout(cfg.BatchExportTimeout))
+	}
+	if cfg.MaxQueueSize > 0 {
+		opts = append(opts, sdktrace.WithMaxQueueSize(cfg.MaxQueueSize))
+	}
+	if cfg.MaxExportBatchSize > 0 {
+		opts = append(opts, sdktrace.WithMaxExportBatchSize(cfg.MaxExportBatchSize))
+	}
+	return opts
+}
+
+// readerOptions returns the periodic reader options of the OTLP metric
+// exporter
+func (cfg *TelemetryConfig) readerOptions() []sdkmetric.PeriodicReaderOption {
+	interval := cfg.MetricInterval
+	if interval <= 0 {
+		interval = defaultMetricInterval
+	}
+	opts := []sdkmetric.PeriodicReaderOption{sdkmetric.WithInterval(interval)}
+	if cfg.MetricTimeout > 0 {
+		opts = append(opts, sdkmetric.WithTimeout(cfg.MetricTimeout))
+	}
+	return opts
+}
+
+// exemplarFilter returns the filter called name, sampling exemplars from
+// sampled traces unless told otherwise
+func exemplarFilter(name string) exemplar.Filter {
+	switch name {
+	case ExemplarFilterAlwaysOn:
+		return exemplar.AlwaysOnFilter
+	case ExemplarFilterAlwaysOff:
+		return exemplar.AlwaysOffFilter
+	default:
+		return exemplar.TraceBasedFilter
+	}
+}
+
+// initLogging initializes logging with an OTLP exporter whose exports are
+// followed by health; records are only exported while export is on
+func initLogging(ctx context.Context, res *resource.Resource, cfg *TelemetryConfig, export *atomic.Bool, health *exportHealth) (*sdklog.LoggerProvider, func(context.Context) error, error) {
+	otlpExporter, err := newLogExporter(ctx, cfg)
 	if err != nil {
-		return nil, nil, fmt.Errorf("failed to create OTLP gRPC log exporter: %w", err)
+		return nil, nil, fmt.Errorf("failed to create OTLP %s log exporter: %w", cfg.protocolName(), err)
 	}
 
 	// Create batch processor
-	processor := sdklog.NewBatchProcessor(otlpExporter)
+	processor := sdklog.NewBatchProcessor(healthLogExporter{Exporter: otlpExporter, health: health})
 
 	loggerProvider := sdklog.NewLoggerProvider(
-		sdklog.WithProcessor(processor),
+		sdklog.WithProcessor(switchedProcessor{Processor: processor, on: export}),
 		sdklog.WithResource(res),
 	)
 
-	log.Println("OTLP gRPC log exporter initialized for Grafana Loki via Alloy")
+	log.Printf("OTLP %s log exporter initialized for Grafana Loki via Alloy", cfg.protocolName())
 	return loggerProvider, loggerProvider.Shutdown, nil
 }
 
 // GetTelemetryConfig creates telemetry configuration from environment
 func GetTelemetryConfig() *TelemetryConfig {
+	protocol := getEnv("OTEL_EXPORTER_OTLP_PROTOCOL", ProtocolGRPC)
+	endpoint := defaultGRPCEndpoint
+	if protocol == ProtocolHTTPProtobuf {
+		endpoint = defaultHTTPEndpoint
+	}
 	return &TelemetryConfig{
 		ServiceName:          getEnv("OTEL_SERVICE_NAME", "otel-example-api"),
-		ServiceVersion:       getEnv("OTEL_SERVICE_VERSION", "1.0.0"),
+		ServiceVersion:       getEnv("OTEL_SERVICE_VERSION", buildinfo.Get().Version),
 		Environment:          getEnv("OTEL_ENVIRONMENT", getEnv("APP_ENV", "development")),
-		OTLPGRPCEndpoint:     getEnv("OTEL_EXPORTER_OTLP_ENDPOINT", "localhost:4317"),
+		MetricsExporter:      getEnv("OTEL_METRICS_EXPORTER", MetricsExporterOTLP),
+		Protocol:             protocol,
+		OTLPEndpoint:         getEnv("OTEL_EXPORTER_OTLP_ENDPOINT", endpoint),
+		TracesEndpoint:       getEnv("OTEL_EXPORTER_OTLP_TRACES_ENDPOINT", ""),
+		MetricsEndpoint:      getEnv("OTEL_EXPORTER_OTLP_METRICS_ENDPOINT", ""),
+		LogsEndpoint:         getEnv("OTEL_EXPORTER_OTLP_LOGS_ENDPOINT", ""),
+		Headers:              getEnvAsHeaders("OTEL_EXPORTER_OTLP_HEADERS"),
+		TracesHeaders:        getEnvAsHeaders("OTEL_EXPORTER_OTLP_TRACES_HEADERS"),
+		MetricsHeaders:       getEnvAsHeaders("OTEL_EXPORTER_OTLP_METRICS_HEADERS"),
+		LogsHeaders:          getEnvAsHeaders("OTEL_EXPORTER_OTLP_LOGS_HEADERS"),
 		EnableMetrics:        getEnv("OTEL_ENABLE_METRICS", defaultEnabledValue) == defaultEnabledValue,
 		EnableTracing:        getEnv("OTEL_ENABLE_TRACING", defaultEnabledValue) == defaultEnabledValue,
 		EnableLogging:        getEnv("OTEL_ENABLE_LOGGING", defaultEnabledValue) == defaultEnabledValue,
 		EnableRuntimeMetrics: getEnv("OTEL_ENABLE_RUNTIME_METRICS", defaultEnabledValue) == defaultEnabledValue,
+		Sampler:              getEnv("OTEL_TRACES_SAMPLER", SamplerParentBasedTraceIDRatio),
+		SampleRatio:          getEnvAsFloat("OTEL_TRACES_SAMPLER_ARG", 1),
+		UntracedPaths:        getUntracedPaths(),
+		ExemplarFilter:       getEnv("OTEL_METRICS_EXEMPLAR_FILTER", ExemplarFilterTraceBased),
+		FailFast:             getEnvAsBool("OTEL_EXPORTER_OTLP_FAIL_FAST", false),
+		// The OTEL_BSP_* and OTEL_METRIC_EXPORT_* durations are milliseconds,
+		// as in the OpenTelemetry specification
+		BatchTimeout:       getEnvAsMillis("OTEL_BSP_SCHEDULE_DELAY", 5*time.Second),
+		BatchExportTimeout: getEnvAsMillis("OTEL_BSP_EXPORT_TIMEOUT", 30*time.Second),
+		MaxQueueSize:       getEnvAsInt("OTEL_BSP_MAX_QUEUE_SIZE", 2048),
+		MaxExportBatchSize: getEnvAsInt("OTEL_BSP_MAX_EXPORT_BATCH_SIZE", 512),
+		MetricInterval:     getEnvAsMillis("OTEL_METRIC_EXPORT_INTERVAL", defaultMetricInterval),
+		MetricTimeout:      getEnvAsMillis("OTEL_METRIC_EXPORT_TIMEOUT", 30*time.Second),
+	}
+}
+
+// defaultUntracedPaths are the probe and scrape endpoints of the API
+const defaultUntracedPaths = "/health,/ready,/metrics"
+
+// getUntracedPaths reads OTEL_UNTRACED_PATHS; setting it empty traces every
+// path
+func getUntracedPaths() []string {
+	if _, ok := os.LookupEnv("OTEL_UNTRACED_PATHS"); !ok {
+		return strings.Split(defaultUntracedPaths, ",")
 	}
+	return getEnvAsList("OTEL_UNTRACED_PATHS")
 }
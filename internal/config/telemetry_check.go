@@ -0,0 +1,117 @@
+package config
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+
+	"go.opentelemetry.io/otel"
+	otellog "go.opentelemetry.io/otel/log"
+	"go.opentelemetry.io/otel/metric"
+)
+
+// Signals reported by CheckTelemetry
+const (
+	SignalTraces  = "traces"
+	SignalMetrics = "metrics"
+	SignalLogs    = "logs"
+)
+
+const checkScope = "telemetry-check"
+
+// SignalCheck is the outcome of exporting a test record of one signal
+type SignalCheck struct {
+	Signal  string
+	Enabled bool
+	Err     error
+}
+
+// CheckTelemetry emits one span, one metric data point and one log record and
+// flushes each provider, so export failures surface per signal instead of
+// being retried in the background. It replaces the global OpenTelemetry error
+// handler and is meant for commands that exit afterwards
+func CheckTelemetry(ctx context.Context, p *TelemetryProvider) []SignalCheck {
+	handler := &exportErrors{}
+	otel.SetErrorHandler(handler)
+
+	checks := []func(context.Context, *TelemetryProvider) SignalCheck{checkTraces, checkMetrics, checkLogs}
+	results := make([]SignalCheck, 0, len(checks))
+	for _, check := range checks {
+		result := check(ctx, p)
+		result.Err = errors.Join(result.Err, handler.take())
+		results = append(results, result)
+	}
+	return results
+}
+
+// exportErrors collects the errors batch processors report to the global
+// error handler instead of returning them from ForceFlush
+type exportErrors struct {
+	mu   sync.Mutex
+	errs []error
+}
+
+func (e *exportErrors) Handle(err error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.errs = append(e.errs, err)
+}
+
+func (e *exportErrors) take() error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	err := errors.Join(e.errs...)
+	e.errs = nil
+	return err
+}
+
+func checkTraces(ctx context.Context, p *TelemetryProvider) SignalCheck {
+	check := SignalCheck{Signal: SignalTraces, Enabled: p.TracerProvider != nil}
+	if !check.Enabled {
+		return check
+	}
+
+	// The test span must be exported whatever the sampling ratio
+	if p.Sampler != nil {
+		defer p.Sampler.sampleAll()()
+	}
+	_, span := p.TracerProvider.Tracer(checkScope).Start(ctx, "telemetry check")
+	span.End()
+	check.Err = p.TracerProvider.ForceFlush(ctx)
+	return check
+}
+
+func checkMetrics(ctx context.Context, p *TelemetryProvider) SignalCheck {
+	check := SignalCheck{Signal: SignalMetrics, Enabled: p.MeterProvider != nil}
+	if !check.Enabled {
+		return check
+	}
+
+	counter, err := p.MeterProvider.Meter(checkScope).Int64Counter(
+		"telemetry_check_total",
+		metric.WithDescription("Total number of telemetry connectivity checks"),
+	)
+	if err != nil {
+		check.Err = fmt.Errorf("failed to create telemetry check metric: %w", err)
+		return check
+	}
+	counter.Add(ctx, 1)
+	check.Err = p.MeterProvider.ForceFlush(ctx)
+	return check
+}
+
+func checkLogs(ctx context.Context, p *TelemetryProvider) SignalCheck {
+	check := SignalCheck{Signal: SignalLogs, Enabled: p.LoggerProvider != nil}
+	if !check.Enabled {
+		return check
+	}
+
+	var record otellog.Record
+	record.SetSeverity(otellog.SeverityInfo)
+	record.SetSeverityText("INFO")
+	record.SetBody(otellog.StringValue("telemetry check"))
+	p.LoggerProvider.Logger(checkScope).Emit(ctx, record)
+	check.Err = p.LoggerProvider.ForceFlush(ctx)
+	return check
+}
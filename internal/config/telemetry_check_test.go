@@ -0,0 +1,59 @@
+package config
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	sdklog "go.opentelemetry.io/otel/sdk/log"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+var errExport = errors.New("collector unavailable")
+
+type failingLogExporter struct{}
+
+func (failingLogExporter) Export(context.Context, []sdklog.Record) error { return errExport }
+func (failingLogExporter) Shutdown(context.Context) error                { return nil }
+func (failingLogExporter) ForceFlush(context.Context) error              { return nil }
+
+func TestCheckTelemetryReportsEachSignal(t *testing.T) {
+	spans := tracetest.NewInMemoryExporter()
+	sampler := NewDynamicSampler(SamplerTraceIDRatio, 0)
+	p := &TelemetryProvider{
+		TracerProvider: sdktrace.NewTracerProvider(sdktrace.WithBatcher(spans), sdktrace.WithSampler(sampler)),
+		MeterProvider:  sdkmetric.NewMeterProvider(sdkmetric.WithReader(sdkmetric.NewManualReader())),
+		LoggerProvider: sdklog.NewLoggerProvider(sdklog.WithProcessor(sdklog.NewBatchProcessor(failingLogExporter{}))),
+		Sampler:        sampler,
+	}
+
+	checks := CheckTelemetry(context.Background(), p)
+	if len(checks) != 3 {
+		t.Fatalf("expected 3 checks, got %d", len(checks))
+	}
+	for _, check := range checks[:2] {
+		if !check.Enabled || check.Err != nil {
+			t.Errorf("expected %s to succeed, got %+v", check.Signal, check)
+		}
+	}
+	if logs := checks[2]; logs.Signal != SignalLogs || !errors.Is(logs.Err, errExport) {
+		t.Errorf("expected logs to report the export error, got %+v", logs)
+	}
+
+	if got := len(spans.GetSpans()); got != 1 {
+		t.Errorf("expected the test span to be exported despite a 0 ratio, got %d spans", got)
+	}
+	if sampler.Ratio() != 0 {
+		t.Errorf("expected the sampling ratio to be restored, got %v", sampler.Ratio())
+	}
+}
+
+func TestCheckTelemetrySkipsDisabledSignals(t *testing.T) {
+	for _, check := range CheckTelemetry(context.Background(), &TelemetryProvider{}) {
+		if check.Enabled || check.Err != nil {
+			t.Errorf("expected %s to be disabled, got %+v", check.Signal, check)
+		}
+	}
+}
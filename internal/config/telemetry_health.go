@@ -0,0 +1,172 @@
+package config
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"sync"
+	"time"
+
+	"arquivolivre.com.br/otel/internal/logging"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+	sdklog "go.opentelemetry.io/otel/sdk/log"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/metric/metricdata"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+)
+
+// Bounds of the interval between two warnings about failing exports of a
+// signal; it doubles while the exports keep failing
+const (
+	minWarningInterval = 10 * time.Second
+	maxWarningInterval = 5 * time.Minute
+)
+
+// collectorProbeTimeout bounds the dial of each collector in fail-fast mode
+const collectorProbeTimeout = 5 * time.Second
+
+// exportHealth follows the exports of one signal. Failures are counted in
+// telemetry.exporter.errors and logged at most once per warning interval,
+// with the number of failures left out since the last warning, so an
+// unreachable collector does not flood the logs
+type exportHealth struct {
+	signal string
+	errors metric.Int64Counter
+	now    func() time.Time
+
+	mu          sync.Mutex
+	failures    int
+	suppressed  int
+	lastErr     error
+	interval    time.Duration
+	nextWarning time.Time
+}
+
+func newExportHealth(signal string) *exportHealth {
+	errs, _ := otel.Meter("otel-example-api").Int64Counter(
+		"telemetry.exporter.errors",
+		metric.WithDescription("Total number of failed telemetry exports"),
+	)
+	return &exportHealth{signal: signal, errors: errs, now: time.Now}
+}
+
+// record notes the outcome of an export
+func (h *exportHealth) record(ctx context.Context, err error) {
+	if err == nil {
+		h.recovered(ctx)
+		return
+	}
+	h.errors.Add(ctx, 1, metric.WithAttributes(attribute.String("signal", h.signal)))
+
+	h.mu.Lock()
+	h.failures++
+	h.lastErr = err
+	now := h.now()
+	if now.Before(h.nextWarning) {
+		h.suppressed++
+		h.mu.Unlock()
+		return
+	}
+	fields := map[string]interface{}{
+		"signal":               h.signal,
+		"error":                err.Error(),
+		"consecutive_failures": h.failures,
+		"suppressed":           h.suppressed,
+	}
+	h.interval = min(max(2*h.interval, minWarningInterval), maxWarningInterval)
+	h.nextWarning = now.Add(h.interval)
+	h.suppressed = 0
+	h.mu.Unlock()
+
+	logging.Module("telemetry").LogWarn(ctx, "Telemetry export failed", fields)
+}
+
+func (h *exportHealth) recovered(ctx context.Context) {
+	h.mu.Lock()
+	failures := h.failures
+	h.failures, h.suppressed, h.lastErr = 0, 0, nil
+	h.interval, h.nextWarning = 0, time.Time{}
+	h.mu.Unlock()
+
+	if failures > 0 {
+		logging.Module("telemetry").LogInfo(ctx, "Telemetry export recovered", map[string]interface{}{
+			"signal":   h.signal,
+			"failures": failures,
+		})
+	}
+}
+
+// err returns the last error while the exports are failing
+func (h *exportHealth) err() error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.failures == 0 {
+		return nil
+	}
+	return fmt.Errorf("last %d %s exports failed: %w", h.failures, h.signal, h.lastErr)
+}
+
+// ExportError returns why the OTLP exports of each failing signal failed,
+// or nil while every export succeeds
+func (p *TelemetryProvider) ExportError() error {
+	var errs []error
+	for _, signal := range []string{SignalTraces, SignalMetrics, SignalLogs} {
+		if h, ok := p.exports[signal]; ok {
+			errs = append(errs, h.err())
+		}
+	}
+	return errors.Join(errs...)
+}
+
+type healthSpanExporter struct {
+	sdktrace.SpanExporter
+	health *exportHealth
+}
+
+func (e healthSpanExporter) ExportSpans(ctx context.Context, spans []sdktrace.ReadOnlySpan) error {
+	err := e.SpanExporter.ExportSpans(ctx, spans)
+	e.health.record(ctx, err)
+	return err
+}
+
+type healthMetricExporter struct {
+	sdkmetric.Exporter
+	health *exportHealth
+}
+
+func (e healthMetricExporter) Export(ctx context.Context, rm *metricdata.ResourceMetrics) error {
+	err := e.Exporter.Export(ctx, rm)
+	e.health.record(ctx, err)
+	return err
+}
+
+type healthLogExporter struct {
+	sdklog.Exporter
+	health *exportHealth
+}
+
+func (e healthLogExporter) Export(ctx context.Context, records []sdklog.Record) error {
+	err := e.Exporter.Export(ctx, records)
+	e.health.record(ctx, err)
+	return err
+}
+
+// probeCollectors dials each collector and fails on the first that cannot
+// be reached
+func probeCollectors(ctx context.Context, addrs []string) error {
+	for _, addr := range addrs {
+		dialCtx, cancel := context.WithTimeout(ctx, collectorProbeTimeout)
+		var dialer net.Dialer
+		conn, err := dialer.DialContext(dialCtx, "tcp", addr)
+		cancel()
+		if err != nil {
+			return fmt.Errorf("collector %s is unreachable: %w", addr, err)
+		}
+		_ = conn.Close()
+	}
+	return nil
+}
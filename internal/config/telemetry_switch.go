@@ -0,0 +1,111 @@
+package config
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sync/atomic"
+
+	sdklog "go.opentelemetry.io/otel/sdk/log"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/metric/metricdata"
+)
+
+// Initialized reports whether signal was enabled at startup. Only those
+// signals can be switched at runtime
+func (p *TelemetryProvider) Initialized(signal string) bool {
+	switch signal {
+	case SignalTraces:
+		return p.TracerProvider != nil && p.Sampler != nil
+	case SignalMetrics:
+		return p.MeterProvider != nil && p.metricsExport != nil
+	case SignalLogs:
+		return p.LoggerProvider != nil && p.logsExport != nil
+	default:
+		return false
+	}
+}
+
+// Exporting reports whether signal is currently exported
+func (p *TelemetryProvider) Exporting(signal string) bool {
+	if !p.Initialized(signal) {
+		return false
+	}
+	switch signal {
+	case SignalTraces:
+		return p.Sampler.Enabled()
+	case SignalMetrics:
+		return p.metricsExport.Load()
+	default:
+		return p.logsExport.Load()
+	}
+}
+
+// SetExporting turns the export of signal on or off while the providers keep
+// running. Spans are dropped by the sampler, metrics by their exporters and
+// log records by their processor, so instrumented code is unaffected
+func (p *TelemetryProvider) SetExporting(signal string, enabled bool) error {
+	if !p.Initialized(signal) {
+		return fmt.Errorf("%s were not enabled at startup", signal)
+	}
+	switch signal {
+	case SignalTraces:
+		p.Sampler.SetEnabled(enabled)
+	case SignalMetrics:
+		p.metricsExport.Store(enabled)
+	default:
+		p.logsExport.Store(enabled)
+	}
+	return nil
+}
+
+// newExportSwitch returns a switch that starts on
+func newExportSwitch() *atomic.Bool {
+	on := &atomic.Bool{}
+	on.Store(true)
+	return on
+}
+
+// switchedMetricExporter drops the metrics collected while its switch is off.
+// The periodic reader keeps collecting, so cumulative sums stay correct once
+// the export is turned back on
+type switchedMetricExporter struct {
+	sdkmetric.Exporter
+	on *atomic.Bool
+}
+
+func (e switchedMetricExporter) Export(ctx context.Context, rm *metricdata.ResourceMetrics) error {
+	if !e.on.Load() {
+		return nil
+	}
+	return e.Exporter.Export(ctx, rm)
+}
+
+// switchedHandler serves the Prometheus exposition while its switch is on
+func switchedHandler(next http.Handler, on *atomic.Bool) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !on.Load() {
+			http.Error(w, "metrics export is disabled", http.StatusServiceUnavailable)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// switchedProcessor drops the log records emitted while its switch is off;
+// Enabled reports false then, so bridges can skip building records
+type switchedProcessor struct {
+	sdklog.Processor
+	on *atomic.Bool
+}
+
+func (p switchedProcessor) OnEmit(ctx context.Context, record *sdklog.Record) error {
+	if !p.on.Load() {
+		return nil
+	}
+	return p.Processor.OnEmit(ctx, record)
+}
+
+func (p switchedProcessor) Enabled(ctx context.Context, param sdklog.EnabledParameters) bool {
+	return p.on.Load() && p.Processor.Enabled(ctx, param)
+}
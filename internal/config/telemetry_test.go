@@ -2,18 +2,30 @@ package config
 
 import (
 	"context"
+	"errors"
+	"maps"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"slices"
+	"strings"
 	"testing"
+	"time"
+
+	"go.opentelemetry.io/otel/metric"
+	"go.opentelemetry.io/otel/trace"
 )
 
 func TestInitTelemetry_DisabledAll(t *testing.T) {
 	tp, err := InitTelemetry(&TelemetryConfig{
-		ServiceName:      "svc",
-		ServiceVersion:   "1",
-		Environment:      "test",
-		OTLPGRPCEndpoint: "localhost:4317",
-		EnableMetrics:    false,
-		EnableTracing:    false,
-		EnableLogging:    false,
+		ServiceName:    "svc",
+		ServiceVersion: "1",
+		Environment:    "test",
+		OTLPEndpoint:   "localhost:4317",
+		EnableMetrics:  false,
+		EnableTracing:  false,
+		EnableLogging:  false,
 	})
 	if err != nil {
 		t.Fatalf("err: %v", err)
@@ -38,20 +50,20 @@ func TestGetTelemetryConfig(t *testing.T) {
 	if cfg.Environment == "" {
 		t.Error("expected non-empty environment")
 	}
-	if cfg.OTLPGRPCEndpoint == "" {
+	if cfg.OTLPEndpoint == "" {
 		t.Error("expected non-empty OTLP endpoint")
 	}
 }
 
 func TestInitTelemetry_TracingOnly(t *testing.T) {
 	tp, err := InitTelemetry(&TelemetryConfig{
-		ServiceName:      "test-service",
-		ServiceVersion:   "1.0.0",
-		Environment:      "test",
-		OTLPGRPCEndpoint: "localhost:4317",
-		EnableMetrics:    false,
-		EnableTracing:    true,
-		EnableLogging:    false,
+		ServiceName:    "test-service",
+		ServiceVersion: "1.0.0",
+		Environment:    "test",
+		OTLPEndpoint:   "localhost:4317",
+		EnableMetrics:  false,
+		EnableTracing:  true,
+		EnableLogging:  false,
 	})
 	if err != nil {
 		t.Fatalf("expected no error, got: %v", err)
@@ -70,13 +82,13 @@ func TestInitTelemetry_TracingOnly(t *testing.T) {
 
 func TestInitTelemetry_MetricsOnly(t *testing.T) {
 	tp, err := InitTelemetry(&TelemetryConfig{
-		ServiceName:      "test-service",
-		ServiceVersion:   "1.0.0",
-		Environment:      "test",
-		OTLPGRPCEndpoint: "localhost:4317",
-		EnableMetrics:    true,
-		EnableTracing:    false,
-		EnableLogging:    false,
+		ServiceName:    "test-service",
+		ServiceVersion: "1.0.0",
+		Environment:    "test",
+		OTLPEndpoint:   "localhost:4317",
+		EnableMetrics:  true,
+		EnableTracing:  false,
+		EnableLogging:  false,
 	})
 	if err != nil {
 		t.Fatalf("expected no error, got: %v", err)
@@ -95,13 +107,13 @@ func TestInitTelemetry_MetricsOnly(t *testing.T) {
 
 func TestInitTelemetry_LoggingOnly(t *testing.T) {
 	tp, err := InitTelemetry(&TelemetryConfig{
-		ServiceName:      "test-service",
-		ServiceVersion:   "1.0.0",
-		Environment:      "test",
-		OTLPGRPCEndpoint: "localhost:4317",
-		EnableMetrics:    false,
-		EnableTracing:    false,
-		EnableLogging:    true,
+		ServiceName:    "test-service",
+		ServiceVersion: "1.0.0",
+		Environment:    "test",
+		OTLPEndpoint:   "localhost:4317",
+		EnableMetrics:  false,
+		EnableTracing:  false,
+		EnableLogging:  true,
 	})
 	if err != nil {
 		t.Fatalf("expected no error, got: %v", err)
@@ -123,7 +135,7 @@ func TestInitTelemetry_AllEnabled(t *testing.T) {
 		ServiceName:          "test-service",
 		ServiceVersion:       "1.0.0",
 		Environment:          "test",
-		OTLPGRPCEndpoint:     "localhost:4317",
+		OTLPEndpoint:         "localhost:4317",
 		EnableMetrics:        true,
 		EnableTracing:        true,
 		EnableLogging:        true,
@@ -149,13 +161,13 @@ func TestInitTelemetry_AllEnabled(t *testing.T) {
 
 func TestInitTelemetry_ShutdownError(t *testing.T) {
 	tp, err := InitTelemetry(&TelemetryConfig{
-		ServiceName:      "test-service",
-		ServiceVersion:   "1.0.0",
-		Environment:      "test",
-		OTLPGRPCEndpoint: "localhost:4317",
-		EnableMetrics:    true,
-		EnableTracing:    true,
-		EnableLogging:    true,
+		ServiceName:    "test-service",
+		ServiceVersion: "1.0.0",
+		Environment:    "test",
+		OTLPEndpoint:   "localhost:4317",
+		EnableMetrics:  true,
+		EnableTracing:  true,
+		EnableLogging:  true,
 	})
 	if err != nil {
 		t.Fatalf("expected no error, got: %v", err)
@@ -166,3 +178,385 @@ func TestInitTelemetry_ShutdownError(t *testing.T) {
 	}
 	// Skip actual shutdown call to avoid network timeouts in test environment
 }
+
+func TestGetTelemetryConfig_HTTPProtocol(t *testing.T) {
+	t.Setenv("OTEL_EXPORTER_OTLP_PROTOCOL", ProtocolHTTPProtobuf)
+	t.Setenv("OTEL_EXPORTER_OTLP_ENDPOINT", "")
+	t.Setenv("OTEL_EXPORTER_OTLP_LOGS_ENDPOINT", "http://loki:3100/otlp/v1/logs")
+
+	cfg := GetTelemetryConfig()
+	if cfg.OTLPEndpoint != defaultHTTPEndpoint {
+		t.Errorf("expected the HTTP default endpoint, got %q", cfg.OTLPEndpoint)
+	}
+	if cfg.LogsEndpoint != "http://loki:3100/otlp/v1/logs" {
+		t.Errorf("expected the logs endpoint override, got %q", cfg.LogsEndpoint)
+	}
+	if err := cfg.Validate(); err != nil {
+		t.Errorf("expected valid config, got: %v", err)
+	}
+}
+
+func TestSignalEndpoint(t *testing.T) {
+	tests := []struct {
+		name     string
+		cfg      TelemetryConfig
+		override string
+		want     string
+	}{
+		{"grpc host:port", TelemetryConfig{Protocol: ProtocolGRPC, OTLPEndpoint: "alloy:4320"}, "", "alloy:4320"},
+		{"http host:port", TelemetryConfig{Protocol: ProtocolHTTPProtobuf, OTLPEndpoint: "alloy:4321"}, "", "alloy:4321"},
+		{"http base URL gets the signal path", TelemetryConfig{Protocol: ProtocolHTTPProtobuf, OTLPEndpoint: "http://alloy:4321/"}, "", "http://alloy:4321/v1/traces"},
+		{"override is used as is", TelemetryConfig{Protocol: ProtocolHTTPProtobuf, OTLPEndpoint: "http://alloy:4321"}, "https://tempo/otlp", "https://tempo/otlp"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.cfg.signalEndpoint(tt.override, "/v1/traces"); got != tt.want {
+				t.Errorf("expected %q, got %q", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestParseHeaders(t *testing.T) {
+	headers, err := parseHeaders(" Authorization=Basic%20dXNlcjpwYXNz , X-Scope-OrgID=tenant-1,,")
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	want := map[string]string{"Authorization": "Basic dXNlcjpwYXNz", "X-Scope-OrgID": "tenant-1"}
+	if !maps.Equal(headers, want) {
+		t.Errorf("expected %v, got %v", want, headers)
+	}
+
+	for _, value := range []string{"Authorization", "=secret", "Authorization=%zz-secret"} {
+		_, err := parseHeaders(value)
+		if err == nil {
+			t.Errorf("expected an error for %q", value)
+		} else if strings.Contains(err.Error(), "secret") {
+			t.Errorf("expected the error to leave the value out, got %v", err)
+		}
+	}
+}
+
+func TestSignalHeaders(t *testing.T) {
+	t.Setenv("OTEL_EXPORTER_OTLP_HEADERS", "Authorization=Bearer%20shared")
+	t.Setenv("OTEL_EXPORTER_OTLP_TRACES_HEADERS", "X-Scope-OrgID=traces")
+	cfg := GetTelemetryConfig()
+
+	if got := cfg.signalHeaders(cfg.TracesHeaders); !maps.Equal(got, map[string]string{"X-Scope-OrgID": "traces"}) {
+		t.Errorf("expected the traces headers to replace the shared ones, got %v", got)
+	}
+	if got := cfg.signalHeaders(cfg.MetricsHeaders); !maps.Equal(got, map[string]string{"Authorization": "Bearer shared"}) {
+		t.Errorf("expected the shared headers, got %v", got)
+	}
+
+	t.Setenv("OTEL_EXPORTER_OTLP_LOGS_HEADERS", "no-value")
+	if err := GetTelemetryConfig().Validate(); err == nil || !strings.Contains(err.Error(), "OTEL_EXPORTER_OTLP_LOGS_HEADERS") {
+		t.Errorf("expected invalid logs headers to be reported, got %v", err)
+	}
+}
+
+func TestCollectorAddrs(t *testing.T) {
+	cfg := TelemetryConfig{
+		Protocol:        ProtocolHTTPProtobuf,
+		OTLPEndpoint:    "http://alloy:4318",
+		LogsEndpoint:    "https://loki.example.com/otlp/v1/logs",
+		MetricsExporter: MetricsExporterPrometheus,
+		EnableTracing:   true,
+		EnableMetrics:   true,
+		EnableLogging:   true,
+	}
+	got := cfg.collectorAddrs()
+	want := []string{"alloy:4318", "loki.example.com:443"}
+	if !slices.Equal(got, want) {
+		t.Errorf("expected %v, got %v", want, got)
+	}
+
+	cfg.MetricsExporter = MetricsExporterOTLP
+	if got := cfg.collectorAddrs(); !slices.Equal(got, want) {
+		t.Errorf("expected signals sharing a collector to be listed once, got %v", got)
+	}
+}
+
+func TestInitTelemetry_HTTPProtocol(t *testing.T) {
+	tp, err := InitTelemetry(&TelemetryConfig{
+		ServiceName:   "test-service",
+		Protocol:      ProtocolHTTPProtobuf,
+		OTLPEndpoint:  "localhost:4318",
+		EnableTracing: true,
+		EnableMetrics: true,
+		EnableLogging: true,
+	})
+	if err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	if tp.TracerProvider == nil || tp.MeterProvider == nil || tp.LoggerProvider == nil {
+		t.Fatalf("expected all providers, got %+v", tp)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	_ = tp.Shutdown(ctx)
+}
+
+func TestInitTelemetry_PrometheusExporter(t *testing.T) {
+	tp, err := InitTelemetry(&TelemetryConfig{
+		ServiceName:     "test-service",
+		ServiceVersion:  "1.0.0",
+		Environment:     "test",
+		OTLPEndpoint:    "localhost:4317",
+		EnableMetrics:   true,
+		MetricsExporter: MetricsExporterPrometheus,
+	})
+	if err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	defer func() { _ = tp.Shutdown(context.Background()) }()
+	if tp.PrometheusHandler == nil {
+		t.Fatal("expected a Prometheus handler")
+	}
+
+	counter, err := tp.MeterProvider.Meter("test").Int64Counter("requests")
+	if err != nil {
+		t.Fatalf("failed to create counter: %v", err)
+	}
+	counter.Add(context.Background(), 3)
+
+	rec := httptest.NewRecorder()
+	tp.PrometheusHandler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", rec.Code)
+	}
+	if body := rec.Body.String(); !strings.Contains(body, "# TYPE requests_total counter") {
+		t.Errorf("expected Prometheus text format, got:\n%s", body)
+	}
+}
+
+func TestInitTelemetry_PrometheusExemplars(t *testing.T) {
+	tp, err := InitTelemetry(&TelemetryConfig{
+		ServiceName:     "test-service",
+		OTLPEndpoint:    "localhost:4317",
+		EnableMetrics:   true,
+		MetricsExporter: MetricsExporterPrometheus,
+		ExemplarFilter:  ExemplarFilterTraceBased,
+	})
+	if err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	defer func() { _ = tp.Shutdown(context.Background()) }()
+
+	histogram, err := tp.MeterProvider.Meter("test").Float64Histogram("request_duration", metric.WithUnit("s"))
+	if err != nil {
+		t.Fatalf("failed to create histogram: %v", err)
+	}
+	traceID := trace.TraceID{0x4b, 0xf9, 0x2f, 0x35, 0x77, 0xb3, 0x4d, 0xa6, 0xa3, 0xce, 0x92, 0x9d, 0x0e, 0x0e, 0x47, 0x36}
+	ctx := trace.ContextWithSpanContext(context.Background(), trace.NewSpanContext(trace.SpanContextConfig{
+		TraceID:    traceID,
+		SpanID:     trace.SpanID{0, 0xf0, 0x67, 0xaa, 0x0b, 0xa9, 0x02, 0xb7},
+		TraceFlags: trace.FlagsSampled,
+	}))
+	histogram.Record(ctx, 0.2)
+
+	rec := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodGet, "/metrics", nil)
+	req.Header.Set("Accept", "application/openmetrics-text; version=1.0.0")
+	tp.PrometheusHandler.ServeHTTP(rec, req)
+	if body := rec.Body.String(); !strings.Contains(body, `trace_id="`+traceID.String()+`"`) {
+		t.Errorf("expected an exemplar of the sampled trace, got:\n%s", body)
+	}
+}
+
+func TestInitTelemetry_OTLPExporterHasNoPrometheusHandler(t *testing.T) {
+	tp, err := InitTelemetry(&TelemetryConfig{
+		ServiceName:     "test-service",
+		ServiceVersion:  "1.0.0",
+		Environment:     "test",
+		OTLPEndpoint:    "localhost:4317",
+		EnableMetrics:   true,
+		MetricsExporter: MetricsExporterOTLP,
+	})
+	if err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	defer func() { _ = tp.Shutdown(context.Background()) }()
+	if tp.PrometheusHandler != nil {
+		t.Error("expected no Prometheus handler with the OTLP exporter")
+	}
+}
+
+func TestGetTelemetryConfig_UntracedPaths(t *testing.T) {
+	// t.Setenv restores the variable after the test unsets it
+	t.Setenv("OTEL_UNTRACED_PATHS", "")
+	_ = os.Unsetenv("OTEL_UNTRACED_PATHS")
+	if got := GetTelemetryConfig().UntracedPaths; !slices.Equal(got, []string{"/health", "/ready", "/metrics"}) {
+		t.Errorf("expected the probe and scrape paths by default, got %v", got)
+	}
+
+	t.Setenv("OTEL_UNTRACED_PATHS", " /livez, /metrics ")
+	if got := GetTelemetryConfig().UntracedPaths; !slices.Equal(got, []string{"/livez", "/metrics"}) {
+		t.Errorf("unexpected paths %v", got)
+	}
+
+	t.Setenv("OTEL_UNTRACED_PATHS", "")
+	if got := GetTelemetryConfig().UntracedPaths; len(got) != 0 {
+		t.Errorf("expected an empty value to trace every path, got %v", got)
+	}
+}
+
+func TestSetExporting(t *testing.T) {
+	tp, err := InitTelemetry(&TelemetryConfig{
+		ServiceName:     "test-service",
+		OTLPEndpoint:    "localhost:4317",
+		EnableTracing:   true,
+		EnableMetrics:   true,
+		MetricsExporter: MetricsExporterPrometheus,
+		SampleRatio:     1,
+	})
+	if err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	defer func() { _ = tp.Shutdown(context.Background()) }()
+
+	for _, signal := range []string{SignalTraces, SignalMetrics} {
+		if !tp.Exporting(signal) {
+			t.Errorf("expected %s to be exported at startup", signal)
+		}
+	}
+	if tp.Initialized(SignalLogs) || tp.Exporting(SignalLogs) {
+		t.Error("expected logs to be disabled")
+	}
+	if err := tp.SetExporting(SignalLogs, true); err == nil {
+		t.Error("expected an error switching a signal disabled at startup")
+	}
+
+	if err := tp.SetExporting(SignalTraces, false); err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	_, span := tp.TracerProvider.Tracer("test").Start(context.Background(), "op")
+	if span.SpanContext().IsSampled() {
+		t.Error("expected spans to be dropped while tracing is off")
+	}
+	span.End()
+
+	if err := tp.SetExporting(SignalMetrics, false); err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	rec := httptest.NewRecorder()
+	tp.PrometheusHandler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	if rec.Code != http.StatusServiceUnavailable {
+		t.Errorf("expected 503 while metrics are off, got %d", rec.Code)
+	}
+
+	_ = tp.SetExporting(SignalTraces, true)
+	_ = tp.SetExporting(SignalMetrics, true)
+	_, span = tp.TracerProvider.Tracer("test").Start(context.Background(), "op")
+	if !span.SpanContext().IsSampled() {
+		t.Error("expected spans to be sampled once tracing is back on")
+	}
+	span.End()
+	rec = httptest.NewRecorder()
+	tp.PrometheusHandler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	if rec.Code != http.StatusOK {
+		t.Errorf("expected 200 once metrics are back on, got %d", rec.Code)
+	}
+}
+
+func TestExportHealthSuppressesWarnings(t *testing.T) {
+	h := newExportHealth(SignalTraces)
+	now := time.Now()
+	h.now = func() time.Time { return now }
+	ctx := context.Background()
+
+	failed := errors.New("connection refused")
+	h.record(ctx, failed)
+	if h.interval != minWarningInterval || h.suppressed != 0 {
+		t.Fatalf("expected a warning and a %s interval, got %s with %d suppressed", minWarningInterval, h.interval, h.suppressed)
+	}
+	h.record(ctx, failed)
+	h.record(ctx, failed)
+	if h.suppressed != 2 {
+		t.Errorf("expected 2 suppressed warnings, got %d", h.suppressed)
+	}
+
+	now = now.Add(minWarningInterval)
+	h.record(ctx, failed)
+	if h.interval != 2*minWarningInterval || h.suppressed != 0 {
+		t.Errorf("expected the interval to double after a warning, got %s with %d suppressed", h.interval, h.suppressed)
+	}
+	if err := h.err(); err == nil || !errors.Is(err, failed) || !strings.Contains(err.Error(), "last 4 traces exports failed") {
+		t.Errorf("expected the last error of 4 failures, got %v", err)
+	}
+
+	h.record(ctx, nil)
+	if err := h.err(); err != nil {
+		t.Errorf("expected no error after a successful export, got %v", err)
+	}
+	if h.interval != 0 || !h.nextWarning.IsZero() {
+		t.Error("expected the warning interval to be reset")
+	}
+}
+
+func TestExportError(t *testing.T) {
+	traces, logs := newExportHealth(SignalTraces), newExportHealth(SignalLogs)
+	p := &TelemetryProvider{exports: map[string]*exportHealth{SignalTraces: traces, SignalLogs: logs}}
+	if err := p.ExportError(); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	logs.record(context.Background(), errors.New("unavailable"))
+	if err := p.ExportError(); err == nil || !strings.Contains(err.Error(), "logs exports failed") {
+		t.Errorf("expected the logs error, got %v", err)
+	}
+}
+
+func TestInitTelemetry_FailFast(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("failed to listen: %v", err)
+	}
+	addr := listener.Addr().String()
+	_ = listener.Close()
+
+	_, err = InitTelemetry(&TelemetryConfig{
+		ServiceName:   "test-service",
+		OTLPEndpoint:  addr,
+		EnableTracing: true,
+		FailFast:      true,
+	})
+	if err == nil || !strings.Contains(err.Error(), "collector "+addr+" is unreachable") {
+		t.Errorf("expected the unreachable collector to fail startup, got %v", err)
+	}
+}
+
+func TestBatchAndReaderTuning(t *testing.T) {
+	t.Setenv("OTEL_BSP_SCHEDULE_DELAY", "1000")
+	t.Setenv("OTEL_BSP_MAX_QUEUE_SIZE", "4096")
+	t.Setenv("OTEL_METRIC_EXPORT_INTERVAL", "60000")
+	cfg := GetTelemetryConfig()
+
+	if cfg.BatchTimeout != time.Second || cfg.BatchExportTimeout != 30*time.Second {
+		t.Errorf("expected a 1s batch timeout and the 30s default export timeout, got %s and %s", cfg.BatchTimeout, cfg.BatchExportTimeout)
+	}
+	if cfg.MaxQueueSize != 4096 || cfg.MaxExportBatchSize != 512 {
+		t.Errorf("expected a queue of 4096 and the default batch of 512, got %d and %d", cfg.MaxQueueSize, cfg.MaxExportBatchSize)
+	}
+	if cfg.MetricInterval != time.Minute || cfg.MetricTimeout != 30*time.Second {
+		t.Errorf("expected a 1m interval and the 30s default timeout, got %s and %s", cfg.MetricInterval, cfg.MetricTimeout)
+	}
+	if err := cfg.Validate(); err != nil {
+		t.Errorf("expected a valid config, got %v", err)
+	}
+	if len(cfg.batchOptions()) != 4 || len(cfg.readerOptions()) != 2 {
+		t.Error("expected every tuning option to be applied")
+	}
+
+	// Unset values keep the SDK defaults and the 15s metric interval
+	empty := &TelemetryConfig{}
+	if len(empty.batchOptions()) != 0 || len(empty.readerOptions()) != 1 {
+		t.Error("expected only the default metric interval without tuning")
+	}
+
+	cfg.MaxExportBatchSize = 8192
+	cfg.MetricTimeout = -time.Second
+	err := cfg.Validate()
+	if err == nil || !strings.Contains(err.Error(), "OTEL_BSP_MAX_EXPORT_BATCH_SIZE") || !strings.Contains(err.Error(), "OTEL_METRIC_EXPORT_TIMEOUT") {
+		t.Errorf("expected an oversized batch and a negative timeout to be reported, got %v", err)
+	}
+}
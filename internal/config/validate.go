@@ -0,0 +1,558 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"arquivolivre.com.br/otel/internal/auth"
+
+	"github.com/robfig/cron/v3"
+)
+
+// maskedValue replaces secrets in printed settings
+const maskedValue = "********"
+
+// scheduleParser accepts the same expressions as the scheduler
+var scheduleParser = cron.NewParser(cron.Minute | cron.Hour | cron.Dom | cron.Month | cron.Dow | cron.Descriptor)
+
+// typedEnv lists the variables read with a typed getter, which silently fall
+// back to their default when the value does not parse
+var typedEnv = []struct {
+	key   string
+	parse func(string) error
+}{
+	{"DB_PORT", parseInt},
+	{"DB_REPLICA_PORT", parseInt},
+	{"DB_QUERY_TIMEOUT", parseDuration},
+	{"DB_SLOW_QUERY_MS", parseInt},
+	{"DB_CONNECT_MAX_ATTEMPTS", parseInt},
+	{"DB_CONNECT_MAX_ELAPSED", parseDuration},
+	{"SHUTDOWN_TIMEOUT", parseDuration},
+	{"REQUEST_READ_TIMEOUT", parseDuration},
+	{"REQUEST_WRITE_TIMEOUT", parseDuration},
+	{"REQUEST_READ_MAX_BODY_BYTES", parseInt},
+	{"REQUEST_WRITE_MAX_BODY_BYTES", parseInt},
+	{"METRICS_STREAM_INTERVAL", parseDuration},
+	{"METRICS_STREAM_MAX_CLIENTS", parseInt},
+	{"USER_CACHE_TTL", parseDuration},
+	{"IDEMPOTENCY_KEY_TTL", parseDuration},
+	{"HEALTH_CHECK_TIMEOUT", parseDuration},
+	{"HEALTH_DISK_MIN_FREE_MB", parseInt},
+	{"BULK_MAX_USERS", parseInt},
+	{"BULK_BATCH_SIZE", parseInt},
+	{"SEED_ON_STARTUP", parseInt},
+	{"CHAOS_ENABLED", parseBool},
+	{"CONFIG_HOT_RELOAD", parseBool},
+	{"RBAC_ENABLED", parseBool},
+	{"AUDIT_ENABLED", parseBool},
+	{"S3_USE_SSL", parseBool},
+	{"AVATAR_MAX_BYTES", parseInt},
+	{"JOB_WORKERS", parseInt},
+	{"JOB_QUEUE_SIZE", parseInt},
+	{"OPERATION_RETENTION", parseDuration},
+	{"SCHEDULER_RUN_TIMEOUT_SECONDS", parseInt},
+	{"OTEL_TRACES_SAMPLER_ARG", parseFloat},
+	{"OTEL_EXPORTER_OTLP_FAIL_FAST", parseBool},
+	{"OTEL_BSP_SCHEDULE_DELAY", parseInt},
+	{"OTEL_BSP_EXPORT_TIMEOUT", parseInt},
+	{"OTEL_BSP_MAX_QUEUE_SIZE", parseInt},
+	{"OTEL_BSP_MAX_EXPORT_BATCH_SIZE", parseInt},
+	{"OTEL_METRIC_EXPORT_INTERVAL", parseInt},
+	{"OTEL_METRIC_EXPORT_TIMEOUT", parseInt},
+}
+
+// Validate checks the configuration for missing and invalid values and
+// returns every problem found
+func (c *Config) Validate() error {
+	errs := validateTypedEnv()
+
+	switch c.Database.Driver {
+	case DriverMySQL:
+	case DriverMemory:
+		// These keep their rows in MySQL tables next to the users
+		if c.Outbox.Enabled || c.Webhooks.Enabled || c.Audit.Enabled {
+			errs = append(errs, errors.New("OUTBOX_ENABLED, WEBHOOKS_ENABLED and AUDIT_ENABLED require DB_DRIVER=mysql"))
+		}
+	default:
+		errs = append(errs, fmt.Errorf("DB_DRIVER: unsupported driver %q, expected %s or %s", c.Database.Driver, DriverMySQL, DriverMemory))
+	}
+	switch c.Database.Access {
+	case AccessSQL:
+	case AccessSQLX, AccessSQLC:
+		// These stores neither prepare their lookups nor write to the outbox
+		if c.Database.Driver != DriverMySQL || c.Database.PreparedStatements || c.Outbox.Enabled {
+			errs = append(errs, fmt.Errorf("DB_ACCESS=%s requires DB_DRIVER=mysql and is incompatible with DB_PREPARED_STATEMENTS and OUTBOX_ENABLED", c.Database.Access))
+		}
+	default:
+		errs = append(errs, fmt.Errorf("DB_ACCESS: unsupported data access %q, expected %s, %s or %s", c.Database.Access, AccessSQL, AccessSQLX, AccessSQLC))
+	}
+	if c.Database.SearchMode != SearchFullText && c.Database.SearchMode != SearchLike {
+		errs = append(errs, fmt.Errorf("DB_SEARCH_MODE: unsupported search mode %q, expected %s or %s", c.Database.SearchMode, SearchFullText, SearchLike))
+	}
+	if c.Database.Host == "" {
+		errs = append(errs, errors.New("DB_HOST is required"))
+	}
+	if c.Database.Name == "" {
+		errs = append(errs, errors.New("DB_NAME is required"))
+	}
+	errs = append(errs, validatePort("DB_PORT", strconv.Itoa(c.Database.Port)))
+	if c.Database.QueryTimeout < 0 {
+		errs = append(errs, errors.New("DB_QUERY_TIMEOUT must not be negative"))
+	}
+	if c.Database.SlowQueryThreshold < 0 {
+		errs = append(errs, errors.New("DB_SLOW_QUERY_MS must not be negative"))
+	}
+	if c.Database.ConnectMaxAttempts < 1 {
+		errs = append(errs, errors.New("DB_CONNECT_MAX_ATTEMPTS must be at least 1"))
+	}
+	if c.Database.ConnectMaxElapsed < 0 {
+		errs = append(errs, errors.New("DB_CONNECT_MAX_ELAPSED must not be negative"))
+	}
+	errs = append(errs, validatePort("SERVER_PORT", c.Server.Port))
+	if c.Server.GRPCPort != "" {
+		errs = append(errs, validatePort("GRPC_PORT", c.Server.GRPCPort))
+		if c.Server.GRPCPort == c.Server.Port {
+			errs = append(errs, errors.New("GRPC_PORT must differ from SERVER_PORT"))
+		}
+	}
+	if c.Server.ShutdownTimeout <= 0 {
+		errs = append(errs, errors.New("SHUTDOWN_TIMEOUT must be positive"))
+	}
+	if c.Server.ReadTimeout < 0 || c.Server.WriteTimeout < 0 {
+		errs = append(errs, errors.New("REQUEST_READ_TIMEOUT and REQUEST_WRITE_TIMEOUT must not be negative"))
+	}
+	if c.Server.ReadMaxBodyBytes < 0 || c.Server.WriteMaxBodyBytes < 0 {
+		errs = append(errs, errors.New("REQUEST_READ_MAX_BODY_BYTES and REQUEST_WRITE_MAX_BODY_BYTES must not be negative"))
+	}
+	if c.Server.MetricsStreamInterval != 0 && c.Server.MetricsStreamInterval < time.Second {
+		errs = append(errs, errors.New("METRICS_STREAM_INTERVAL must be 0 or at least 1s"))
+	}
+	if c.Server.MetricsStreamMaxClients < 1 {
+		errs = append(errs, errors.New("METRICS_STREAM_MAX_CLIENTS must be at least 1"))
+	}
+
+	switch c.App.Environment {
+	case "development", "test", "staging", "production":
+	default:
+		errs = append(errs, fmt.Errorf("APP_ENV: unsupported environment %q, expected development, test, staging or production", c.App.Environment))
+	}
+	switch c.App.LogLevel {
+	case "debug", "info", "warn", "error":
+	default:
+		errs = append(errs, fmt.Errorf("LOG_LEVEL: unsupported level %q", c.App.LogLevel))
+	}
+	errs = append(errs, validateURL("ENRICHER_URL", c.App.EnricherURL))
+	errs = append(errs, validateURL("EXTERNAL_SERVICE_URL", c.App.ExternalServiceURL))
+	if c.App.APISunset != "" {
+		if _, err := time.Parse(time.DateOnly, c.App.APISunset); err != nil {
+			errs = append(errs, fmt.Errorf("API_SUNSET: %q is not a YYYY-MM-DD date", c.App.APISunset))
+		}
+	}
+	if c.App.BulkMaxUsers < 1 {
+		errs = append(errs, errors.New("BULK_MAX_USERS must be at least 1"))
+	}
+	if c.App.BulkBatchSize < 1 {
+		errs = append(errs, errors.New("BULK_BATCH_SIZE must be at least 1"))
+	}
+	if c.App.SeedOnStartup < 0 {
+		errs = append(errs, errors.New("SEED_ON_STARTUP must not be negative"))
+	}
+	if c.App.HotReload && c.App.ConfigFile == "" {
+		errs = append(errs, errors.New("CONFIG_FILE is required when CONFIG_HOT_RELOAD is enabled"))
+	}
+
+	if c.Jobs.Workers < 1 {
+		errs = append(errs, errors.New("JOB_WORKERS must be at least 1"))
+	}
+	if c.Jobs.QueueSize < 0 {
+		errs = append(errs, errors.New("JOB_QUEUE_SIZE must not be negative"))
+	}
+	if c.Jobs.OperationRetention <= 0 {
+		errs = append(errs, errors.New("OPERATION_RETENTION must be positive"))
+	}
+
+	errs = append(errs,
+		validateSchedule("SCHEDULE_USER_COUNT_WARMUP", c.Scheduler.UserCountWarmup),
+		validateSchedule("SCHEDULE_SYNTHETIC_PROBE", c.Scheduler.SyntheticProbe),
+		validateSchedule("SCHEDULE_STALE_USER_CLEANUP", c.Scheduler.StaleUserCleanup),
+	)
+	if c.Scheduler.RunTimeout <= 0 {
+		errs = append(errs, errors.New("SCHEDULER_RUN_TIMEOUT_SECONDS must be positive"))
+	}
+	if c.Scheduler.SyntheticProbe != "off" {
+		errs = append(errs, validateURL("SYNTHETIC_PROBE_URL", c.Scheduler.SyntheticProbeURL))
+	}
+
+	for _, broker := range c.Kafka.Brokers {
+		errs = append(errs, validateHostPort("KAFKA_BROKERS", broker))
+	}
+	if len(c.Kafka.Brokers) > 0 && c.Kafka.UserEventsTopic == "" {
+		errs = append(errs, errors.New("KAFKA_USER_EVENTS_TOPIC is required when KAFKA_BROKERS is set"))
+	}
+	if c.Outbox.Enabled {
+		if c.Outbox.PollInterval <= 0 {
+			errs = append(errs, errors.New("OUTBOX_POLL_INTERVAL must be positive"))
+		}
+		if c.Outbox.BatchSize < 1 {
+			errs = append(errs, errors.New("OUTBOX_BATCH_SIZE must be at least 1"))
+		}
+		if c.Outbox.MaxAttempts < 1 {
+			errs = append(errs, errors.New("OUTBOX_MAX_ATTEMPTS must be at least 1"))
+		}
+	}
+	if c.Webhooks.Enabled {
+		if c.Webhooks.Timeout <= 0 {
+			errs = append(errs, errors.New("WEBHOOK_TIMEOUT must be positive"))
+		}
+		if c.Webhooks.MaxAttempts < 1 {
+			errs = append(errs, errors.New("WEBHOOK_MAX_ATTEMPTS must be at least 1"))
+		}
+	}
+	if c.SMTP.Addr != "" {
+		errs = append(errs, validateHostPort("SMTP_ADDR", c.SMTP.Addr))
+	}
+	if c.Storage.Endpoint != "" {
+		errs = append(errs, validateHostPort("S3_ENDPOINT", c.Storage.Endpoint))
+		if c.Storage.Bucket == "" {
+			errs = append(errs, errors.New("S3_BUCKET is required with S3_ENDPOINT"))
+		}
+		if c.Storage.AccessKey == "" || c.Storage.SecretKey == "" {
+			errs = append(errs, errors.New("S3_ACCESS_KEY and S3_SECRET_KEY are required with S3_ENDPOINT"))
+		}
+		if c.Storage.AvatarMaxBytes < 1 {
+			errs = append(errs, errors.New("AVATAR_MAX_BYTES must be at least 1"))
+		}
+	}
+
+	if c.Cache.RedisAddr != "" {
+		errs = append(errs, validateHostPort("REDIS_ADDR", c.Cache.RedisAddr))
+		if c.Cache.UserTTL <= 0 {
+			errs = append(errs, errors.New("USER_CACHE_TTL must be positive"))
+		}
+	}
+	if c.Cache.IdempotencyTTL <= 0 {
+		errs = append(errs, errors.New("IDEMPOTENCY_KEY_TTL must be positive"))
+	}
+	if c.Health.CheckTimeout <= 0 {
+		errs = append(errs, errors.New("HEALTH_CHECK_TIMEOUT must be positive"))
+	}
+	if c.Health.DiskMinFreeMB < 0 {
+		errs = append(errs, errors.New("HEALTH_DISK_MIN_FREE_MB must not be negative"))
+	}
+	if c.Auth.JWTSecret != "" && c.Auth.JWKSURL != "" {
+		errs = append(errs, errors.New("JWT_SECRET and JWT_JWKS_URL are mutually exclusive"))
+	}
+	errs = append(errs, validateURL("JWT_JWKS_URL", c.Auth.JWKSURL))
+	if _, err := auth.ParseAPIKeys(c.Auth.APIKeys); err != nil {
+		errs = append(errs, fmt.Errorf("API_KEYS: %w", err))
+	}
+	if c.Auth.RBAC && c.Auth.JWTSecret == "" && c.Auth.JWKSURL == "" && len(c.Auth.APIKeys) == 0 {
+		errs = append(errs, errors.New("RBAC_ENABLED requires JWT_SECRET, JWT_JWKS_URL or API_KEYS"))
+	}
+
+	return errors.Join(errs...)
+}
+
+// Validate checks the telemetry configuration for missing and invalid values
+func (t *TelemetryConfig) Validate() error {
+	var errs []error
+	if t.ServiceName == "" {
+		errs = append(errs, errors.New("OTEL_SERVICE_NAME is required"))
+	}
+	if err := validateProtocol(t.Protocol); err != nil {
+		errs = append(errs, err)
+	}
+	if err := validateSampler(t.Sampler); err != nil {
+		errs = append(errs, err)
+	}
+	switch t.MetricsExporter {
+	case MetricsExporterOTLP, MetricsExporterPrometheus, MetricsExporterBoth:
+	default:
+		errs = append(errs, fmt.Errorf("OTEL_METRICS_EXPORTER: unsupported exporter %q, expected %s, %s or %s",
+			t.MetricsExporter, MetricsExporterOTLP, MetricsExporterPrometheus, MetricsExporterBoth))
+	}
+	switch t.ExemplarFilter {
+	case ExemplarFilterTraceBased, ExemplarFilterAlwaysOn, ExemplarFilterAlwaysOff:
+	default:
+		errs = append(errs, fmt.Errorf("OTEL_METRICS_EXEMPLAR_FILTER: unsupported filter %q, expected %s, %s or %s",
+			t.ExemplarFilter, ExemplarFilterTraceBased, ExemplarFilterAlwaysOn, ExemplarFilterAlwaysOff))
+	}
+	if t.EnableTracing || t.EnableMetrics || t.EnableLogging {
+		errs = append(errs, validateEndpoint("OTEL_EXPORTER_OTLP_ENDPOINT", t.OTLPEndpoint))
+	}
+	for _, override := range []Setting{
+		{"OTEL_EXPORTER_OTLP_TRACES_ENDPOINT", t.TracesEndpoint},
+		{"OTEL_EXPORTER_OTLP_METRICS_ENDPOINT", t.MetricsEndpoint},
+		{"OTEL_EXPORTER_OTLP_LOGS_ENDPOINT", t.LogsEndpoint},
+	} {
+		if override.Value != "" {
+			errs = append(errs, validateEndpoint(override.Key, override.Value))
+		}
+	}
+	if t.SampleRatio < 0 || t.SampleRatio > 1 {
+		errs = append(errs, fmt.Errorf("OTEL_TRACES_SAMPLER_ARG: %v is not between 0 and 1", t.SampleRatio))
+	}
+	for _, setting := range []struct {
+		key   string
+		value int64
+	}{
+		{"OTEL_BSP_SCHEDULE_DELAY", t.BatchTimeout.Milliseconds()},
+		{"OTEL_BSP_EXPORT_TIMEOUT", t.BatchExportTimeout.Milliseconds()},
+		{"OTEL_BSP_MAX_QUEUE_SIZE", int64(t.MaxQueueSize)},
+		{"OTEL_BSP_MAX_EXPORT_BATCH_SIZE", int64(t.MaxExportBatchSize)},
+		{"OTEL_METRIC_EXPORT_INTERVAL", t.MetricInterval.Milliseconds()},
+		{"OTEL_METRIC_EXPORT_TIMEOUT", t.MetricTimeout.Milliseconds()},
+	} {
+		if setting.value < 0 {
+			errs = append(errs, fmt.Errorf("%s must not be negative", setting.key))
+		}
+	}
+	if t.MaxQueueSize > 0 && t.MaxExportBatchSize > t.MaxQueueSize {
+		errs = append(errs, fmt.Errorf("OTEL_BSP_MAX_EXPORT_BATCH_SIZE: %d exceeds OTEL_BSP_MAX_QUEUE_SIZE %d", t.MaxExportBatchSize, t.MaxQueueSize))
+	}
+	for _, key := range []string{
+		"OTEL_EXPORTER_OTLP_HEADERS",
+		"OTEL_EXPORTER_OTLP_TRACES_HEADERS",
+		"OTEL_EXPORTER_OTLP_METRICS_HEADERS",
+		"OTEL_EXPORTER_OTLP_LOGS_HEADERS",
+	} {
+		if _, err := parseHeaders(os.Getenv(key)); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", key, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// Setting is one effective configuration value keyed by its variable name
+type Setting struct {
+	Key   string
+	Value string
+}
+
+// Settings returns the effective configuration with secrets masked
+func Settings(c *Config, t *TelemetryConfig) []Setting {
+	return []Setting{
+		{"DB_DRIVER", c.Database.Driver},
+		{"DB_HOST", c.Database.Host},
+		{"DB_PORT", strconv.Itoa(c.Database.Port)},
+		{"DB_USER", c.Database.User},
+		{"DB_PASSWORD", mask(c.Database.Password)},
+		{"DB_NAME", c.Database.Name},
+		{"DB_QUERY_TIMEOUT", c.Database.QueryTimeout.String()},
+		{"DB_SLOW_QUERY_MS", strconv.FormatInt(c.Database.SlowQueryThreshold.Milliseconds(), 10)},
+		{"DB_CONNECT_MAX_ATTEMPTS", strconv.Itoa(c.Database.ConnectMaxAttempts)},
+		{"DB_CONNECT_MAX_ELAPSED", c.Database.ConnectMaxElapsed.String()},
+		{"DB_PREPARED_STATEMENTS", strconv.FormatBool(c.Database.PreparedStatements)},
+		{"DB_ACCESS", c.Database.Access},
+		{"DB_SEARCH_MODE", c.Database.SearchMode},
+		{"DB_REPLICA_HOST", c.Database.ReplicaHost},
+		{"DB_REPLICA_PORT", strconv.Itoa(c.Database.ReplicaPort)},
+		{"SERVER_HOST", c.Server.Host},
+		{"SERVER_PORT", c.Server.Port},
+		{"GRPC_PORT", c.Server.GRPCPort},
+		{"SHUTDOWN_TIMEOUT", c.Server.ShutdownTimeout.String()},
+		{"REQUEST_READ_TIMEOUT", c.Server.ReadTimeout.String()},
+		{"REQUEST_WRITE_TIMEOUT", c.Server.WriteTimeout.String()},
+		{"REQUEST_READ_MAX_BODY_BYTES", strconv.Itoa(c.Server.ReadMaxBodyBytes)},
+		{"REQUEST_WRITE_MAX_BODY_BYTES", strconv.Itoa(c.Server.WriteMaxBodyBytes)},
+		{"METRICS_STREAM_INTERVAL", c.Server.MetricsStreamInterval.String()},
+		{"METRICS_STREAM_MAX_CLIENTS", strconv.Itoa(c.Server.MetricsStreamMaxClients)},
+		{"CONFIG_YAML", os.Getenv("CONFIG_YAML")},
+		{"APP_ENV", c.App.Environment},
+		{"LOG_LEVEL", c.App.LogLevel},
+		{"DISALLOWED_EMAIL_DOMAINS", strings.Join(c.App.DisallowedEmailDomains, ",")},
+		{"FEATURE_FLAGS_FILE", c.App.FeatureFlagsFile},
+		{"ENRICHER_URL", c.App.EnricherURL},
+		{"EXTERNAL_SERVICE_URL", c.App.ExternalServiceURL},
+		{"ADMIN_TOKEN", mask(c.App.AdminToken)},
+		{"CHAOS_ENABLED", strconv.FormatBool(c.App.ChaosEnabled)},
+		{"API_SUNSET", c.App.APISunset},
+		{"BULK_MAX_USERS", strconv.Itoa(c.App.BulkMaxUsers)},
+		{"BULK_BATCH_SIZE", strconv.Itoa(c.App.BulkBatchSize)},
+		{"SEED_ON_STARTUP", strconv.Itoa(c.App.SeedOnStartup)},
+		{"CONFIG_FILE", c.App.ConfigFile},
+		{"CONFIG_HOT_RELOAD", strconv.FormatBool(c.App.HotReload)},
+		{"JOB_WORKERS", strconv.Itoa(c.Jobs.Workers)},
+		{"JOB_QUEUE_SIZE", strconv.Itoa(c.Jobs.QueueSize)},
+		{"OPERATION_RETENTION", c.Jobs.OperationRetention.String()},
+		{"SCHEDULE_USER_COUNT_WARMUP", c.Scheduler.UserCountWarmup},
+		{"SCHEDULER_RUN_TIMEOUT_SECONDS", strconv.Itoa(int(c.Scheduler.RunTimeout.Seconds()))},
+		{"SCHEDULE_SYNTHETIC_PROBE", c.Scheduler.SyntheticProbe},
+		{"SYNTHETIC_PROBE_URL", c.Scheduler.SyntheticProbeURL},
+		{"SYNTHETIC_PROBE_TOKEN", mask(c.Scheduler.SyntheticProbeToken)},
+		{"SYNTHETIC_PROBE_API_KEY", mask(c.Scheduler.SyntheticProbeAPIKey)},
+		{"SCHEDULE_STALE_USER_CLEANUP", c.Scheduler.StaleUserCleanup},
+		{"KAFKA_BROKERS", strings.Join(c.Kafka.Brokers, ",")},
+		{"KAFKA_USER_EVENTS_TOPIC", c.Kafka.UserEventsTopic},
+		{"KAFKA_CONSUMER_GROUP", c.Kafka.ConsumerGroup},
+		{"KAFKA_PUBLISH_EVENTS", strconv.FormatBool(c.Kafka.PublishEvents)},
+		{"OUTBOX_ENABLED", strconv.FormatBool(c.Outbox.Enabled)},
+		{"OUTBOX_POLL_INTERVAL", c.Outbox.PollInterval.String()},
+		{"OUTBOX_BATCH_SIZE", strconv.Itoa(c.Outbox.BatchSize)},
+		{"OUTBOX_MAX_ATTEMPTS", strconv.Itoa(c.Outbox.MaxAttempts)},
+		{"WEBHOOKS_ENABLED", strconv.FormatBool(c.Webhooks.Enabled)},
+		{"WEBHOOK_TIMEOUT", c.Webhooks.Timeout.String()},
+		{"WEBHOOK_MAX_ATTEMPTS", strconv.Itoa(c.Webhooks.MaxAttempts)},
+		{"AUDIT_ENABLED", strconv.FormatBool(c.Audit.Enabled)},
+		{"SMTP_ADDR", c.SMTP.Addr},
+		{"SMTP_FROM", c.SMTP.From},
+		{"SMTP_USERNAME", c.SMTP.Username},
+		{"SMTP_PASSWORD", mask(c.SMTP.Password)},
+		{"S3_ENDPOINT", c.Storage.Endpoint},
+		{"S3_BUCKET", c.Storage.Bucket},
+		{"S3_REGION", c.Storage.Region},
+		{"S3_ACCESS_KEY", c.Storage.AccessKey},
+		{"S3_SECRET_KEY", mask(c.Storage.SecretKey)},
+		{"S3_USE_SSL", strconv.FormatBool(c.Storage.UseSSL)},
+		{"AVATAR_MAX_BYTES", strconv.Itoa(c.Storage.AvatarMaxBytes)},
+		{"REDIS_ADDR", c.Cache.RedisAddr},
+		{"REDIS_PASSWORD", mask(c.Cache.RedisPassword)},
+		{"USER_CACHE_TTL", c.Cache.UserTTL.String()},
+		{"IDEMPOTENCY_KEY_TTL", c.Cache.IdempotencyTTL.String()},
+		{"HEALTH_CHECK_TIMEOUT", c.Health.CheckTimeout.String()},
+		{"HEALTH_DISK_PATH", c.Health.DiskPath},
+		{"HEALTH_DISK_MIN_FREE_MB", strconv.Itoa(c.Health.DiskMinFreeMB)},
+		{"JWT_SECRET", mask(c.Auth.JWTSecret)},
+		{"JWT_JWKS_URL", c.Auth.JWKSURL},
+		{"JWT_ISSUER", c.Auth.JWTIssuer},
+		{"JWT_AUDIENCE", c.Auth.JWTAudience},
+		{"API_KEYS", apiKeyClients(c.Auth.APIKeys)},
+		{"RBAC_ENABLED", strconv.FormatBool(c.Auth.RBAC)},
+		{"OTEL_SERVICE_NAME", t.ServiceName},
+		{"OTEL_SERVICE_VERSION", t.ServiceVersion},
+		{"OTEL_ENVIRONMENT", t.Environment},
+		{"OTEL_EXPORTER_OTLP_PROTOCOL", t.Protocol},
+		{"OTEL_EXPORTER_OTLP_ENDPOINT", t.OTLPEndpoint},
+		{"OTEL_EXPORTER_OTLP_TRACES_ENDPOINT", t.TracesEndpoint},
+		{"OTEL_EXPORTER_OTLP_METRICS_ENDPOINT", t.MetricsEndpoint},
+		{"OTEL_EXPORTER_OTLP_LOGS_ENDPOINT", t.LogsEndpoint},
+		{"OTEL_EXPORTER_OTLP_HEADERS", headerNames(t.Headers)},
+		{"OTEL_EXPORTER_OTLP_TRACES_HEADERS", headerNames(t.TracesHeaders)},
+		{"OTEL_EXPORTER_OTLP_METRICS_HEADERS", headerNames(t.MetricsHeaders)},
+		{"OTEL_EXPORTER_OTLP_LOGS_HEADERS", headerNames(t.LogsHeaders)},
+		{"OTEL_EXPORTER_OTLP_FAIL_FAST", strconv.FormatBool(t.FailFast)},
+		{"OTEL_BSP_SCHEDULE_DELAY", strconv.FormatInt(t.BatchTimeout.Milliseconds(), 10)},
+		{"OTEL_BSP_EXPORT_TIMEOUT", strconv.FormatInt(t.BatchExportTimeout.Milliseconds(), 10)},
+		{"OTEL_BSP_MAX_QUEUE_SIZE", strconv.Itoa(t.MaxQueueSize)},
+		{"OTEL_BSP_MAX_EXPORT_BATCH_SIZE", strconv.Itoa(t.MaxExportBatchSize)},
+		{"OTEL_METRIC_EXPORT_INTERVAL", strconv.FormatInt(t.MetricInterval.Milliseconds(), 10)},
+		{"OTEL_METRIC_EXPORT_TIMEOUT", strconv.FormatInt(t.MetricTimeout.Milliseconds(), 10)},
+		{"OTEL_ENABLE_TRACING", strconv.FormatBool(t.EnableTracing)},
+		{"OTEL_ENABLE_METRICS", strconv.FormatBool(t.EnableMetrics)},
+		{"OTEL_METRICS_EXPORTER", t.MetricsExporter},
+		{"OTEL_METRICS_EXEMPLAR_FILTER", t.ExemplarFilter},
+		{"OTEL_ENABLE_LOGGING", strconv.FormatBool(t.EnableLogging)},
+		{"OTEL_ENABLE_RUNTIME_METRICS", strconv.FormatBool(t.EnableRuntimeMetrics)},
+		{"OTEL_TRACES_SAMPLER", t.Sampler},
+		{"OTEL_TRACES_SAMPLER_ARG", strconv.FormatFloat(t.SampleRatio, 'g', -1, 64)},
+		{"OTEL_UNTRACED_PATHS", strings.Join(t.UntracedPaths, ",")},
+	}
+}
+
+// apiKeyClients lists the clients of keys, leaving the keys out
+func apiKeyClients(keys []string) string {
+	clients := make([]string, len(keys))
+	for i, key := range keys {
+		clientID, _, _ := strings.Cut(key, ":")
+		clients[i] = clientID + ":" + maskedValue
+	}
+	return strings.Join(clients, ",")
+}
+
+func mask(secret string) string {
+	if secret == "" {
+		return ""
+	}
+	return maskedValue
+}
+
+func validateTypedEnv() []error {
+	var errs []error
+	for _, env := range typedEnv {
+		if value := os.Getenv(env.key); value != "" {
+			if err := env.parse(value); err != nil {
+				errs = append(errs, fmt.Errorf("%s: %q %w", env.key, value, err))
+			}
+		}
+	}
+	return errs
+}
+
+func parseInt(value string) error {
+	if _, err := strconv.Atoi(value); err != nil {
+		return errors.New("is not an integer")
+	}
+	return nil
+}
+
+func parseBool(value string) error {
+	if _, err := strconv.ParseBool(value); err != nil {
+		return errors.New("is not a boolean")
+	}
+	return nil
+}
+
+func parseFloat(value string) error {
+	if _, err := strconv.ParseFloat(value, 64); err != nil {
+		return errors.New("is not a number")
+	}
+	return nil
+}
+
+func parseDuration(value string) error {
+	if _, err := time.ParseDuration(value); err != nil {
+		return errors.New("is not a duration")
+	}
+	return nil
+}
+
+func validatePort(key, value string) error {
+	port, err := strconv.Atoi(value)
+	if err != nil || port < 1 || port > 65535 {
+		return fmt.Errorf("%s: %q is not a port between 1 and 65535", key, value)
+	}
+	return nil
+}
+
+func validateHostPort(key, value string) error {
+	host, port, err := net.SplitHostPort(value)
+	if err != nil || host == "" {
+		return fmt.Errorf("%s: %q is not a host:port address", key, value)
+	}
+	return validatePort(key, port)
+}
+
+// validateEndpoint accepts a host:port address or an http(s) URL
+func validateEndpoint(key, value string) error {
+	if isURL(value) {
+		return validateURL(key, value)
+	}
+	return validateHostPort(key, value)
+}
+
+func validateURL(key, value string) error {
+	if value == "" {
+		return nil
+	}
+	u, err := url.Parse(value)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return fmt.Errorf("%s: %q is not an http(s) URL", key, value)
+	}
+	return nil
+}
+
+func validateSchedule(key, value string) error {
+	if value == "off" {
+		return nil
+	}
+	if _, err := scheduleParser.Parse(value); err != nil {
+		return fmt.Errorf("%s: invalid schedule %q: %w", key, value, err)
+	}
+	return nil
+}
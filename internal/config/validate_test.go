@@ -0,0 +1,179 @@
+package config
+
+import (
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestValidateAcceptsDefaults(t *testing.T) {
+	os.Clearenv()
+	defer os.Clearenv()
+
+	cfg, err := Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := cfg.Validate(); err != nil {
+		t.Fatalf("defaults should be valid: %v", err)
+	}
+	if err := GetTelemetryConfig().Validate(); err != nil {
+		t.Fatalf("telemetry defaults should be valid: %v", err)
+	}
+}
+
+func TestValidateReportsEveryProblem(t *testing.T) {
+	os.Clearenv()
+	defer os.Clearenv()
+	_ = os.Setenv("DB_PORT", "abc")
+	_ = os.Setenv("APP_ENV", "qa")
+	_ = os.Setenv("SERVER_PORT", "70000")
+	_ = os.Setenv("GRPC_PORT", "grpc")
+	_ = os.Setenv("ENRICHER_URL", "enricher:8081")
+	_ = os.Setenv("API_SUNSET", "next year")
+	_ = os.Setenv("BULK_BATCH_SIZE", "0")
+	_ = os.Setenv("SCHEDULE_USER_COUNT_WARMUP", "every minute")
+	_ = os.Setenv("OUTBOX_ENABLED", "true")
+	_ = os.Setenv("OUTBOX_BATCH_SIZE", "0")
+	_ = os.Setenv("METRICS_STREAM_INTERVAL", "100ms")
+	_ = os.Setenv("METRICS_STREAM_MAX_CLIENTS", "0")
+	_ = os.Setenv("OPERATION_RETENTION", "0s")
+	_ = os.Setenv("OTEL_EXPORTER_OTLP_ENDPOINT", "alloy")
+	_ = os.Setenv("OTEL_TRACES_SAMPLER_ARG", "1.5")
+	_ = os.Setenv("OTEL_METRICS_EXPORTER", "statsd")
+
+	cfg, err := Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = cfg.Validate()
+	if err == nil {
+		t.Fatal("expected validation errors")
+	}
+	for _, key := range []string{"DB_PORT", "APP_ENV", "SERVER_PORT", "GRPC_PORT", "ENRICHER_URL", "API_SUNSET", "BULK_BATCH_SIZE", "SCHEDULE_USER_COUNT_WARMUP", "OUTBOX_BATCH_SIZE", "METRICS_STREAM_INTERVAL", "METRICS_STREAM_MAX_CLIENTS", "OPERATION_RETENTION"} {
+		if !strings.Contains(err.Error(), key) {
+			t.Errorf("expected error for %s, got: %v", key, err)
+		}
+	}
+
+	err = GetTelemetryConfig().Validate()
+	if err == nil {
+		t.Fatal("expected telemetry validation errors")
+	}
+	for _, key := range []string{"OTEL_EXPORTER_OTLP_ENDPOINT", "OTEL_TRACES_SAMPLER_ARG", "OTEL_METRICS_EXPORTER"} {
+		if !strings.Contains(err.Error(), key) {
+			t.Errorf("expected error for %s, got: %v", key, err)
+		}
+	}
+}
+
+func TestValidateDBDriver(t *testing.T) {
+	os.Clearenv()
+	defer os.Clearenv()
+	_ = os.Setenv("DB_DRIVER", "memory")
+
+	cfg, err := Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := cfg.Validate(); err != nil {
+		t.Fatalf("the memory driver should be valid: %v", err)
+	}
+
+	cfg.Audit.Enabled = true
+	if err := cfg.Validate(); err == nil || !strings.Contains(err.Error(), "AUDIT_ENABLED") {
+		t.Errorf("expected the audit log to require MySQL, got: %v", err)
+	}
+
+	cfg.Database.Driver = "postgres"
+	if err := cfg.Validate(); err == nil || !strings.Contains(err.Error(), "DB_DRIVER") {
+		t.Errorf("expected an unsupported driver error, got: %v", err)
+	}
+}
+
+func TestValidateDBAccess(t *testing.T) {
+	os.Clearenv()
+	defer os.Clearenv()
+	_ = os.Setenv("DB_ACCESS", "sqlx")
+
+	cfg, err := Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := cfg.Validate(); err != nil {
+		t.Fatalf("sqlx on MySQL should be valid: %v", err)
+	}
+
+	cfg.Outbox.Enabled = true
+	if err := cfg.Validate(); err == nil || !strings.Contains(err.Error(), "DB_ACCESS=sqlx") {
+		t.Errorf("expected sqlx to be rejected with the outbox, got: %v", err)
+	}
+
+	cfg.Outbox.Enabled = false
+	cfg.Database.Access = AccessSQLC
+	cfg.Database.PreparedStatements = true
+	if err := cfg.Validate(); err == nil || !strings.Contains(err.Error(), "DB_ACCESS=sqlc") {
+		t.Errorf("expected sqlc to be rejected with prepared statements, got: %v", err)
+	}
+
+	cfg.Database.PreparedStatements = false
+	cfg.Database.SearchMode = "soundex"
+	if err := cfg.Validate(); err == nil || !strings.Contains(err.Error(), "DB_SEARCH_MODE") {
+		t.Errorf("expected an unsupported search mode error, got: %v", err)
+	}
+
+	cfg.Database.SearchMode = SearchLike
+	cfg.Database.Access = "gorm"
+	if err := cfg.Validate(); err == nil || !strings.Contains(err.Error(), "DB_ACCESS") {
+		t.Errorf("expected an unsupported data access error, got: %v", err)
+	}
+}
+
+func TestValidateStorage(t *testing.T) {
+	os.Clearenv()
+	defer os.Clearenv()
+	_ = os.Setenv("S3_ENDPOINT", "minio:9000")
+	_ = os.Setenv("S3_ACCESS_KEY", "admin")
+	_ = os.Setenv("S3_SECRET_KEY", "password123")
+
+	cfg, err := Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := cfg.Validate(); err != nil {
+		t.Fatalf("the storage should be valid: %v", err)
+	}
+
+	cfg.Storage.Endpoint = "http://minio:9000"
+	cfg.Storage.SecretKey = ""
+	cfg.Storage.AvatarMaxBytes = 0
+	err = cfg.Validate()
+	for _, key := range []string{"S3_ENDPOINT", "S3_SECRET_KEY", "AVATAR_MAX_BYTES"} {
+		if err == nil || !strings.Contains(err.Error(), key) {
+			t.Errorf("expected error for %s, got: %v", key, err)
+		}
+	}
+}
+
+func TestSettingsMasksSecrets(t *testing.T) {
+	cfg := &Config{}
+	cfg.Database.Password = "db-secret"
+	cfg.App.AdminToken = "admin-secret"
+	cfg.SMTP.Password = "smtp-secret"
+	cfg.Storage.SecretKey = "s3-secret"
+	cfg.Scheduler.SyntheticProbeToken = "probe-secret"
+	cfg.Scheduler.SyntheticProbeAPIKey = "probe-key-secret"
+
+	telemetryCfg := &TelemetryConfig{Headers: map[string]string{"Authorization": "Bearer secret"}}
+	for _, setting := range Settings(cfg, telemetryCfg) {
+		if strings.Contains(setting.Value, "secret") {
+			t.Errorf("%s is not masked: %s", setting.Key, setting.Value)
+		}
+		if setting.Key == "DB_PASSWORD" && setting.Value != maskedValue {
+			t.Errorf("expected DB_PASSWORD to be masked, got %q", setting.Value)
+		}
+		if setting.Key == "OTEL_EXPORTER_OTLP_HEADERS" && setting.Value != "Authorization="+maskedValue {
+			t.Errorf("expected the header names with masked values, got %q", setting.Value)
+		}
+	}
+}
@@ -3,6 +3,7 @@ package database
 import (
 	"context"
 	"database/sql"
+	"errors"
 	"fmt"
 	"log"
 	"time"
@@ -15,6 +16,7 @@ import (
 	"go.opentelemetry.io/otel/attribute"
 	"go.opentelemetry.io/otel/metric"
 	semconv "go.opentelemetry.io/otel/semconv/v1.21.0"
+	"go.opentelemetry.io/otel/trace"
 )
 
 type DatabaseConnector interface {
@@ -34,7 +36,9 @@ type DBMetrics struct {
 	QueryDuration       metric.Float64Histogram
 	QueryCount          metric.Int64Counter
 	QueryErrors         metric.Int64Counter
-	ConnectionCount     metric.Int64UpDownCounter
+	QueryTimeouts       metric.Int64Counter
+	QuerySlow           metric.Int64Counter
+	ConnectionCount     metric.Int64ObservableGauge
 	ConnectionErrors    metric.Int64Counter
 	HealthCheckDuration metric.Float64Histogram
 }
@@ -59,9 +63,17 @@ type DB struct {
 	queryDuration       metric.Float64Histogram
 	queryCount          metric.Int64Counter
 	queryErrors         metric.Int64Counter
-	connectionCount     metric.Int64UpDownCounter
+	queryTimeouts       metric.Int64Counter
+	querySlow           metric.Int64Counter
+	connectionCount     metric.Int64ObservableGauge
+	connectionStats     metric.Registration
 	connectionErrors    metric.Int64Counter
 	healthCheckDuration metric.Float64Histogram
+	queryTimeout        time.Duration
+	slowQueryThreshold  time.Duration
+	// role is RoleReplica for the pool of the read replica, see Reader
+	role    string
+	replica *DB
 }
 
 type OtelDatabaseConnector struct{}
@@ -109,9 +121,25 @@ func (f *DefaultMetricsFactory) CreateMetrics(meter metric.Meter) (*DBMetrics, e
 		return nil, fmt.Errorf("failed to create query errors metric: %w", err)
 	}
 
-	connectionCount, err := meter.Int64UpDownCounter(
+	queryTimeouts, err := meter.Int64Counter(
+		"db.query.timeouts",
+		metric.WithDescription("Total number of database queries cancelled by their deadline"),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create query timeouts metric: %w", err)
+	}
+
+	querySlow, err := meter.Int64Counter(
+		"db.query.slow",
+		metric.WithDescription("Total number of database queries over the slow query threshold"),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create slow query metric: %w", err)
+	}
+
+	connectionCount, err := meter.Int64ObservableGauge(
 		"db.connections.active",
-		metric.WithDescription("Number of active database connections"),
+		metric.WithDescription("Number of open database connections, by connection.type active (in use) or idle"),
 	)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create connection count metric: %w", err)
@@ -138,6 +166,8 @@ func (f *DefaultMetricsFactory) CreateMetrics(meter metric.Meter) (*DBMetrics, e
 		QueryDuration:       queryDuration,
 		QueryCount:          queryCount,
 		QueryErrors:         queryErrors,
+		QueryTimeouts:       queryTimeouts,
+		QuerySlow:           querySlow,
 		ConnectionCount:     connectionCount,
 		ConnectionErrors:    connectionErrors,
 		HealthCheckDuration: healthCheckDuration,
@@ -161,11 +191,41 @@ func NewConnectionWithDeps(
 	metricsFactory MetricsFactory,
 	connCfg ConnectionConfig,
 ) (*DB, error) {
-	db, err := connector.Open("mysql", cfg.Database.DSN,
+	dbInstance, err := openPool(cfg, cfg.Database.DSN, RolePrimary, connector, meterProvider, metricsFactory, connCfg)
+	if err != nil {
+		return nil, err
+	}
+
+	if cfg.Database.ReplicaDSN != "" {
+		replica, err := openPool(cfg, cfg.Database.ReplicaDSN, RoleReplica, connector, meterProvider, metricsFactory, connCfg)
+		if err != nil {
+			_ = dbInstance.Close()
+			return nil, fmt.Errorf("read replica: %w", err)
+		}
+		dbInstance.SetReplica(replica)
+		log.Printf("Routing reads to the replica at %s", cfg.Database.ReplicaHost)
+	}
+
+	log.Println("Successfully connected to database with comprehensive OpenTelemetry instrumentation")
+	return dbInstance, nil
+}
+
+// openPool opens the connection pool of the server at dsn, whose spans and
+// connection metrics are labelled with role
+func openPool(
+	cfg *config.Config,
+	dsn, role string,
+	connector DatabaseConnector,
+	meterProvider MeterProvider,
+	metricsFactory MetricsFactory,
+	connCfg ConnectionConfig,
+) (*DB, error) {
+	db, err := connector.Open("mysql", dsn,
 		otelsql.WithAttributes(
 			semconv.DBSystemMySQL,
 			semconv.DBName(cfg.Database.Name),
-			semconv.DBConnectionString(cfg.Database.DSN),
+			semconv.DBConnectionString(dsn),
+			attribute.String(RoleKey, role),
 		),
 		otelsql.WithSpanOptions(otelsql.SpanOptions{
 			OmitConnResetSession: true,
@@ -183,7 +243,8 @@ func NewConnectionWithDeps(
 	if err != nil {
 		return nil, fmt.Errorf("failed to configure connection pool: %w", err)
 	}
-	if err := db.Ping(); err != nil {
+	if err := pingWithRetry(db, cfg.Database, meterProvider); err != nil {
+		_ = db.Close()
 		return nil, fmt.Errorf("failed to ping database: %w", err)
 	}
 
@@ -191,6 +252,7 @@ func NewConnectionWithDeps(
 	err = connector.RegisterDBStatsMetrics(db, otelsql.WithAttributes(
 		semconv.DBSystemMySQL,
 		semconv.DBName(cfg.Database.Name),
+		attribute.String(RoleKey, role),
 	))
 	if err != nil {
 		log.Printf("Warning: Failed to register database stats metrics: %v", err)
@@ -201,8 +263,13 @@ func NewConnectionWithDeps(
 	if err != nil {
 		return nil, fmt.Errorf("failed to create database with metrics: %w", err)
 	}
-
-	log.Println("Successfully connected to database with comprehensive OpenTelemetry instrumentation")
+	dbInstance.role = role
+	if err := dbInstance.observeConnections(); err != nil {
+		_ = db.Close()
+		return nil, err
+	}
+	dbInstance.SetQueryTimeout(cfg.Database.QueryTimeout)
+	dbInstance.SetSlowQueryThreshold(cfg.Database.SlowQueryThreshold)
 	return dbInstance, nil
 }
 
@@ -231,14 +298,49 @@ func createDBWithMetrics(db *sql.DB, meterProvider MeterProvider, metricsFactory
 		queryDuration:       metrics.QueryDuration,
 		queryCount:          metrics.QueryCount,
 		queryErrors:         metrics.QueryErrors,
+		queryTimeouts:       metrics.QueryTimeouts,
+		querySlow:           metrics.QuerySlow,
 		connectionCount:     metrics.ConnectionCount,
 		connectionErrors:    metrics.ConnectionErrors,
 		healthCheckDuration: metrics.HealthCheckDuration,
 	}, nil
 }
 
-// Close closes the database connection
+// observeConnections reports the connections the pool holds in
+// db.connections.active each time metrics are collected
+func (db *DB) observeConnections() error {
+	if db.connectionCount == nil {
+		return nil
+	}
+	var err error
+	db.connectionStats, err = db.meter.RegisterCallback(func(_ context.Context, o metric.Observer) error {
+		stats := db.Stats()
+		for _, count := range []struct {
+			connType string
+			value    int
+		}{{"active", stats.InUse}, {"idle", stats.Idle}} {
+			o.ObserveInt64(db.connectionCount, int64(count.value), metric.WithAttributes(
+				semconv.DBSystemMySQL,
+				attribute.String(RoleKey, db.Role()),
+				attribute.String("connection.type", count.connType),
+			))
+		}
+		return nil
+	}, db.connectionCount)
+	if err != nil {
+		return fmt.Errorf("failed to register connection count callback: %w", err)
+	}
+	return nil
+}
+
+// Close closes the database connection and the one of the replica, if any
 func (db *DB) Close() error {
+	if db.connectionStats != nil {
+		_ = db.connectionStats.Unregister()
+	}
+	if db.replica != nil {
+		return errors.Join(db.DB.Close(), db.replica.Close())
+	}
 	return db.DB.Close()
 }
 
@@ -267,13 +369,47 @@ func (db *DB) Health() error {
 	return err
 }
 
-// RecordQueryMetrics records metrics for database queries
-func (db *DB) RecordQueryMetrics(ctx context.Context, operation, table string, duration time.Duration, err error) {
+// SetQueryTimeout sets the deadline of each repository operation, see
+// WithQueryTimeout; zero leaves operations bounded only by the request
+func (db *DB) SetQueryTimeout(timeout time.Duration) {
+	db.queryTimeout = timeout
+}
+
+// WithQueryTimeout returns the context of one repository operation, which
+// ends after the query timeout or with ctx, whichever comes first. Callers
+// must call the returned cancel function once the operation is done
+func (db *DB) WithQueryTimeout(ctx context.Context) (context.Context, context.CancelFunc) {
+	if db.queryTimeout <= 0 {
+		return context.WithCancel(ctx)
+	}
+	return context.WithTimeout(ctx, db.queryTimeout)
+}
+
+// TrackQuery starts timing a query of operation on table and returns the
+// function that records it, with the error it ended with, once it is done:
+//
+//	done := db.TrackQuery(ctx, "SELECT", "users", query)
+//	rows, err := db.QueryContext(ctx, query, args...)
+//	done(err)
+func (db *DB) TrackQuery(ctx context.Context, operation, table, statement string) func(err error) {
+	start := time.Now()
+	return func(err error) {
+		db.recordQueryMetrics(ctx, operation, table, statement, time.Since(start), err)
+	}
+}
+
+// recordQueryMetrics records metrics for database queries. A failed query
+// whose context ran out of time is counted in db.query.timeouts and marks
+// the active span with timeout=true. Queries over the slow query threshold
+// are reported with their statement, see recordSlowQuery
+func (db *DB) recordQueryMetrics(ctx context.Context, operation, table, statement string, duration time.Duration, err error) {
 	attrs := []attribute.KeyValue{
 		semconv.DBSystemMySQL,
 		attribute.String("db.operation", operation),
 		attribute.String("db.table", table),
+		attribute.String(RoleKey, db.Role()),
 	}
+	trace.SpanFromContext(ctx).SetAttributes(attribute.String(RoleKey, db.Role()))
 
 	// Record query duration
 	if db.queryDuration != nil {
@@ -286,26 +422,25 @@ func (db *DB) RecordQueryMetrics(ctx context.Context, operation, table string, d
 	}
 
 	// Record query errors
+	timedOut := err != nil && errors.Is(ctx.Err(), context.DeadlineExceeded)
 	if err != nil && db.queryErrors != nil {
-		errorAttrs := append(attrs, attribute.String("error.type", "query_failed"))
+		errorType := "query_failed"
+		if timedOut {
+			errorType = "timeout"
+		}
+		errorAttrs := append(attrs, attribute.String("error.type", errorType))
 		db.queryErrors.Add(ctx, 1, metric.WithAttributes(errorAttrs...))
 	}
-}
 
-// RecordConnectionMetrics records connection pool metrics
-func (db *DB) RecordConnectionMetrics(ctx context.Context) {
-	stats := db.Stats()
+	if timedOut {
+		trace.SpanFromContext(ctx).SetAttributes(attribute.Bool("timeout", true))
+		if db.queryTimeouts != nil {
+			db.queryTimeouts.Add(ctx, 1, metric.WithAttributes(attrs...))
+		}
+	}
 
-	// Record active connections
-	if db.connectionCount != nil {
-		db.connectionCount.Add(ctx, int64(stats.OpenConnections), metric.WithAttributes(
-			semconv.DBSystemMySQL,
-			attribute.String("connection.type", "active"),
-		))
-		db.connectionCount.Add(ctx, -int64(stats.Idle), metric.WithAttributes(
-			semconv.DBSystemMySQL,
-			attribute.String("connection.type", "idle"),
-		))
+	if db.slowQueryThreshold > 0 && duration >= db.slowQueryThreshold {
+		db.recordSlowQuery(ctx, attrs, statement, duration)
 	}
 }
 
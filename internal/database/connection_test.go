@@ -4,14 +4,20 @@ import (
 	"context"
 	"database/sql"
 	"fmt"
+	"slices"
 	"testing"
 	"time"
 
 	"arquivolivre.com.br/otel/internal/config"
 	sqlmock "github.com/DATA-DOG/go-sqlmock"
 	"github.com/XSAM/otelsql"
+	"go.opentelemetry.io/otel/attribute"
 	"go.opentelemetry.io/otel/metric"
 	"go.opentelemetry.io/otel/metric/noop"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/metric/metricdata"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
 )
 
 func TestDBHealth_Closed(t *testing.T) {
@@ -57,7 +63,7 @@ func TestRecordQueryMetrics_Success(t *testing.T) {
 	defer func() { _ = sqlDB.Close() }()
 
 	d := &DB{DB: sqlDB}
-	d.RecordQueryMetrics(context.Background(), "SELECT", "users", 100*1000000, nil)
+	d.recordQueryMetrics(context.Background(), "SELECT", "users", "SELECT 1", 100*1000000, nil)
 }
 
 func TestRecordQueryMetrics_WithMetrics(t *testing.T) {
@@ -69,8 +75,8 @@ func TestRecordQueryMetrics_WithMetrics(t *testing.T) {
 
 	d := &DB{DB: sqlDB}
 
-	d.RecordQueryMetrics(context.Background(), "SELECT", "users", 100*1000000, nil)
-	d.RecordQueryMetrics(context.Background(), "INSERT", "users", 50*1000000, fmt.Errorf("constraint error"))
+	d.recordQueryMetrics(context.Background(), "SELECT", "users", "SELECT 1", 100*1000000, nil)
+	d.recordQueryMetrics(context.Background(), "INSERT", "users", "SELECT 1", 50*1000000, fmt.Errorf("constraint error"))
 }
 
 func TestRecordQueryMetrics_Error(t *testing.T) {
@@ -81,29 +87,7 @@ func TestRecordQueryMetrics_Error(t *testing.T) {
 	defer func() { _ = sqlDB.Close() }()
 
 	d := &DB{DB: sqlDB}
-	d.RecordQueryMetrics(context.Background(), "SELECT", "users", 100*1000000, fmt.Errorf("query error"))
-}
-
-func TestRecordConnectionMetrics(t *testing.T) {
-	sqlDB, _, err := sqlmock.New()
-	if err != nil {
-		t.Fatalf("sqlmock new: %v", err)
-	}
-	defer func() { _ = sqlDB.Close() }()
-
-	d := &DB{DB: sqlDB}
-	d.RecordConnectionMetrics(context.Background())
-}
-
-func TestRecordConnectionMetrics_WithMetrics(t *testing.T) {
-	sqlDB, _, err := sqlmock.New()
-	if err != nil {
-		t.Fatalf("sqlmock new: %v", err)
-	}
-	defer func() { _ = sqlDB.Close() }()
-
-	d := &DB{DB: sqlDB}
-	d.RecordConnectionMetrics(context.Background())
+	d.recordQueryMetrics(context.Background(), "SELECT", "users", "SELECT 1", 100*1000000, fmt.Errorf("query error"))
 }
 
 func TestDBHealth_Success(t *testing.T) {
@@ -390,6 +374,57 @@ func TestDefaultConnectionConfig(t *testing.T) {
 	}
 }
 
+func TestRecordQueryMetrics_Timeout(t *testing.T) {
+	reader := sdkmetric.NewManualReader()
+	meter := sdkmetric.NewMeterProvider(sdkmetric.WithReader(reader)).Meter("test")
+	metrics, err := (&DefaultMetricsFactory{}).CreateMetrics(meter)
+	if err != nil {
+		t.Fatalf("create metrics: %v", err)
+	}
+	d := &DB{queryErrors: metrics.QueryErrors, queryTimeouts: metrics.QueryTimeouts}
+	d.SetQueryTimeout(time.Nanosecond)
+
+	recorder := tracetest.NewSpanRecorder()
+	ctx, span := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)).Tracer("test").Start(context.Background(), "query")
+	ctx, cancel := d.WithQueryTimeout(ctx)
+	defer cancel()
+	<-ctx.Done()
+
+	d.recordQueryMetrics(ctx, "SELECT", "users", "SELECT 1", time.Millisecond, ctx.Err())
+	// A failure before the deadline is not a timeout
+	d.recordQueryMetrics(context.Background(), "SELECT", "users", "SELECT 1", time.Millisecond, fmt.Errorf("query error"))
+	span.End()
+
+	var rm metricdata.ResourceMetrics
+	if err := reader.Collect(context.Background(), &rm); err != nil {
+		t.Fatalf("collect: %v", err)
+	}
+	var timeouts int64
+	for _, m := range rm.ScopeMetrics[0].Metrics {
+		if m.Name == "db.query.timeouts" {
+			for _, dp := range m.Data.(metricdata.Sum[int64]).DataPoints {
+				timeouts += dp.Value
+			}
+		}
+	}
+	if timeouts != 1 {
+		t.Errorf("expected 1 timeout, got %d", timeouts)
+	}
+
+	attrs := recorder.Ended()[0].Attributes()
+	if !slices.Contains(attrs, attribute.Bool("timeout", true)) {
+		t.Errorf("expected timeout=true on the span, got %v", attrs)
+	}
+}
+
+func TestWithQueryTimeout_Disabled(t *testing.T) {
+	ctx, cancel := (&DB{}).WithQueryTimeout(context.Background())
+	defer cancel()
+	if _, ok := ctx.Deadline(); ok {
+		t.Error("expected no deadline without a query timeout")
+	}
+}
+
 func TestDefaultMetricsFactory_CreateMetrics_Success(t *testing.T) {
 	factory := &DefaultMetricsFactory{}
 	meterProvider := &NoopMeterProvider{}
@@ -414,6 +449,12 @@ func TestDefaultMetricsFactory_CreateMetrics_Success(t *testing.T) {
 	if metrics.QueryErrors == nil {
 		t.Error("expected non-nil QueryErrors")
 	}
+	if metrics.QueryTimeouts == nil {
+		t.Error("expected non-nil QueryTimeouts")
+	}
+	if metrics.QuerySlow == nil {
+		t.Error("expected non-nil QuerySlow")
+	}
 	if metrics.ConnectionCount == nil {
 		t.Error("expected non-nil ConnectionCount")
 	}
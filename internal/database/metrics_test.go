@@ -2,27 +2,107 @@ package database
 
 import (
 	"context"
-	sqlmock "github.com/DATA-DOG/go-sqlmock"
 	"testing"
 	"time"
+
+	"arquivolivre.com.br/otel/pkg/oteltest"
+
+	sqlmock "github.com/DATA-DOG/go-sqlmock"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/sdk/metric/metricdata"
 )
 
 func TestRecordQueryMetrics_NoPanic(t *testing.T) {
 	d := &DB{}
-	d.RecordQueryMetrics(context.Background(), "SELECT", "users", 10*time.Millisecond, nil)
-	d.RecordQueryMetrics(context.Background(), "SELECT", "users", 10*time.Millisecond, assertErr{})
+	d.recordQueryMetrics(context.Background(), "SELECT", "users", "SELECT 1", 10*time.Millisecond, nil)
+	d.recordQueryMetrics(context.Background(), "SELECT", "users", "SELECT 1", 10*time.Millisecond, assertErr{})
 }
 
 type assertErr struct{}
 
 func (assertErr) Error() string { return "err" }
 
-func TestRecordConnectionMetrics_NoPanic(t *testing.T) {
+func TestConnectionCountObservesPoolStats(t *testing.T) {
+	rec := oteltest.Install(t)
 	sqlDB, _, err := sqlmock.New()
 	if err != nil {
 		t.Fatalf("sqlmock new: %v", err)
 	}
-	defer func() { _ = sqlDB.Close() }()
-	d := &DB{DB: sqlDB}
-	d.RecordConnectionMetrics(context.Background())
+	d, err := createDBWithMetrics(sqlDB, &OtelMeterProvider{}, &DefaultMetricsFactory{})
+	if err != nil {
+		t.Fatalf("createDBWithMetrics: %v", err)
+	}
+	d.role = RoleReplica
+	if err := d.observeConnections(); err != nil {
+		t.Fatalf("observeConnections: %v", err)
+	}
+	defer func() { _ = d.Close() }()
+
+	conn, err := sqlDB.Conn(context.Background())
+	if err != nil {
+		t.Fatalf("conn: %v", err)
+	}
+	defer func() { _ = conn.Close() }()
+
+	// The gauge reports the current pool sizes on every collection rather
+	// than adding them up
+	for range 2 {
+		m, ok := rec.Metric(t, "db.connections.active")
+		if !ok {
+			t.Fatal("db.connections.active not recorded")
+		}
+		gauge, ok := m.Data.(metricdata.Gauge[int64])
+		if !ok {
+			t.Fatalf("db.connections.active is a %T, not a gauge", m.Data)
+		}
+		got := map[string]int64{}
+		for _, dp := range gauge.DataPoints {
+			connType, _ := dp.Attributes.Value("connection.type")
+			role, _ := dp.Attributes.Value(RoleKey)
+			if role.AsString() != RoleReplica {
+				t.Errorf("unexpected %s %q", RoleKey, role.AsString())
+			}
+			got[connType.AsString()] = dp.Value
+		}
+		if got["active"] != 1 || got["idle"] != 0 {
+			t.Errorf("unexpected connection counts %v", got)
+		}
+	}
+}
+
+func TestRecordQueryMetrics_RecordsValues(t *testing.T) {
+	rec := oteltest.Install(t)
+	d, err := createDBWithMetrics(nil, &OtelMeterProvider{}, &DefaultMetricsFactory{})
+	if err != nil {
+		t.Fatalf("createDBWithMetrics: %v", err)
+	}
+
+	ctx := context.Background()
+	d.recordQueryMetrics(ctx, "SELECT", "users", "SELECT 1", 10*time.Millisecond, nil)
+	d.recordQueryMetrics(ctx, "SELECT", "users", "SELECT 1", 10*time.Millisecond, assertErr{})
+	d.recordQueryMetrics(ctx, "INSERT", "posts", "INSERT 1", 10*time.Millisecond, nil)
+
+	selects := []attribute.KeyValue{attribute.String("db.operation", "SELECT"), attribute.String("db.table", "users")}
+	rec.AssertCounterValue(t, "db.query.count", selects, 2)
+	rec.AssertCounterValue(t, "db.query.count", nil, 3)
+	rec.AssertCounterValue(t, "db.query.errors", []attribute.KeyValue{attribute.String("error.type", "query_failed")}, 1)
+	rec.AssertHistogramCount(t, "db.query.duration", selects, 2)
+	rec.AssertNoMetric(t, "db.query.timeouts")
+}
+
+func TestTrackQuery_RecordsOnceDone(t *testing.T) {
+	rec := oteltest.Install(t)
+	d, err := createDBWithMetrics(nil, &OtelMeterProvider{}, &DefaultMetricsFactory{})
+	if err != nil {
+		t.Fatalf("createDBWithMetrics: %v", err)
+	}
+
+	done := d.TrackQuery(context.Background(), "UPDATE", "users", "UPDATE users")
+	rec.AssertNoMetric(t, "db.query.count")
+	done(assertErr{})
+
+	attrs := []attribute.KeyValue{attribute.String("db.operation", "UPDATE"), attribute.String("db.table", "users")}
+	rec.AssertCounterValue(t, "db.query.count", attrs, 1)
+	rec.AssertCounterValue(t, "db.query.errors", attrs, 1)
+	rec.AssertHistogramCount(t, "db.query.duration", attrs, 1)
 }
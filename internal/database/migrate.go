@@ -0,0 +1,119 @@
+package database
+
+import (
+	"context"
+	"embed"
+	"fmt"
+	"io/fs"
+	"sort"
+	"strings"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+)
+
+//go:embed migrations/*.sql
+var migrationFiles embed.FS
+
+//go:embed seed.sql
+var seedSQL string
+
+const createMigrationsTable = `
+	CREATE TABLE IF NOT EXISTS schema_migrations (
+		version VARCHAR(255) PRIMARY KEY,
+		applied_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+	)
+`
+
+// Migrate applies the pending migrations in name order and returns the
+// names of those it applied
+func (db *DB) Migrate(ctx context.Context) ([]string, error) {
+	ctx, span := otel.Tracer("database").Start(ctx, "database.migrate")
+	defer span.End()
+
+	if _, err := db.ExecContext(ctx, createMigrationsTable); err != nil {
+		span.SetStatus(codes.Error, err.Error())
+		return nil, fmt.Errorf("failed to create schema_migrations table: %w", err)
+	}
+
+	names, err := fs.Glob(migrationFiles, "migrations/*.sql")
+	if err != nil {
+		return nil, fmt.Errorf("failed to list migrations: %w", err)
+	}
+	sort.Strings(names)
+
+	var applied []string
+	for _, name := range names {
+		version := strings.TrimSuffix(strings.TrimPrefix(name, "migrations/"), ".sql")
+
+		var exists int
+		err := db.QueryRowContext(ctx, "SELECT COUNT(*) FROM schema_migrations WHERE version = ?", version).Scan(&exists)
+		if err != nil {
+			span.SetStatus(codes.Error, err.Error())
+			return applied, fmt.Errorf("failed to check migration %s: %w", version, err)
+		}
+		if exists > 0 {
+			continue
+		}
+
+		contents, err := migrationFiles.ReadFile(name)
+		if err != nil {
+			return applied, fmt.Errorf("failed to read migration %s: %w", version, err)
+		}
+		if err := db.execScript(ctx, string(contents)); err != nil {
+			span.SetStatus(codes.Error, err.Error())
+			return applied, fmt.Errorf("failed to apply migration %s: %w", version, err)
+		}
+		if _, err := db.ExecContext(ctx, "INSERT INTO schema_migrations (version) VALUES (?)", version); err != nil {
+			span.SetStatus(codes.Error, err.Error())
+			return applied, fmt.Errorf("failed to record migration %s: %w", version, err)
+		}
+		applied = append(applied, version)
+	}
+
+	span.SetAttributes(attribute.Int("migrations.applied", len(applied)))
+	return applied, nil
+}
+
+// Seed inserts the demo users and posts; existing rows are kept
+func (db *DB) Seed(ctx context.Context) error {
+	ctx, span := otel.Tracer("database").Start(ctx, "database.seed")
+	defer span.End()
+
+	if err := db.execScript(ctx, seedSQL); err != nil {
+		span.SetStatus(codes.Error, err.Error())
+		return fmt.Errorf("failed to seed database: %w", err)
+	}
+	return nil
+}
+
+// execScript runs each statement of script; the driver does not accept
+// several statements in one call
+func (db *DB) execScript(ctx context.Context, script string) error {
+	for _, stmt := range splitStatements(script) {
+		if _, err := db.ExecContext(ctx, stmt); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// splitStatements splits script on statement-ending semicolons and drops
+// comment lines
+func splitStatements(script string) []string {
+	var lines []string
+	for _, line := range strings.Split(script, "\n") {
+		if !strings.HasPrefix(strings.TrimSpace(line), "--") {
+			lines = append(lines, line)
+		}
+	}
+
+	var statements []string
+	for _, stmt := range strings.Split(strings.Join(lines, "\n"), ";") {
+		if stmt = strings.TrimSpace(stmt); stmt != "" {
+			statements = append(statements, stmt)
+		}
+	}
+	return statements
+}
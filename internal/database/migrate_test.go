@@ -0,0 +1,65 @@
+package database
+
+import (
+	"context"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSplitStatements(t *testing.T) {
+	statements := splitStatements("-- comment\nCREATE TABLE a (id INT);\n\nINSERT INTO a VALUES (1);\n")
+	assert.Equal(t, []string{"CREATE TABLE a (id INT)", "INSERT INTO a VALUES (1)"}, statements)
+}
+
+func TestMigrateAppliesPendingMigrations(t *testing.T) {
+	sqlDB, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer func() { _ = sqlDB.Close() }()
+	d := &DB{DB: sqlDB}
+
+	mock.ExpectExec("CREATE TABLE IF NOT EXISTS schema_migrations").WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectQuery("SELECT COUNT").WithArgs("001_create_users").
+		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(1))
+	mock.ExpectQuery("SELECT COUNT").WithArgs("002_create_posts").
+		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(0))
+	mock.ExpectExec("CREATE TABLE IF NOT EXISTS posts").WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec("INSERT INTO schema_migrations").WithArgs("002_create_posts").
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectQuery("SELECT COUNT").WithArgs("003_create_outbox").
+		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(1))
+	mock.ExpectQuery("SELECT COUNT").WithArgs("004_create_webhooks").
+		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(1))
+	mock.ExpectQuery("SELECT COUNT").WithArgs("005_add_user_version").
+		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(1))
+	mock.ExpectQuery("SELECT COUNT").WithArgs("006_create_audit_log").
+		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(1))
+	mock.ExpectQuery("SELECT COUNT").WithArgs("007_create_roles").
+		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(1))
+	mock.ExpectQuery("SELECT COUNT").WithArgs("008_add_user_search_index").
+		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(1))
+	mock.ExpectQuery("SELECT COUNT").WithArgs("009_add_user_role_subject").
+		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(1))
+
+	applied, err := d.Migrate(context.Background())
+
+	require.NoError(t, err)
+	assert.Equal(t, []string{"002_create_posts"}, applied)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestSeedRunsEachStatement(t *testing.T) {
+	sqlDB, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer func() { _ = sqlDB.Close() }()
+	d := &DB{DB: sqlDB}
+
+	mock.ExpectExec("INSERT IGNORE INTO users").WillReturnResult(sqlmock.NewResult(0, 3))
+	mock.ExpectExec("INSERT INTO posts").WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec("INSERT INTO posts").WillReturnResult(sqlmock.NewResult(0, 1))
+
+	require.NoError(t, d.Seed(context.Background()))
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
@@ -0,0 +1,13 @@
+CREATE TABLE IF NOT EXISTS audit_log (
+    id BIGINT AUTO_INCREMENT PRIMARY KEY,
+    actor VARCHAR(255) NOT NULL,
+    client_id VARCHAR(255) NOT NULL DEFAULT '',
+    action VARCHAR(16) NOT NULL,
+    entity VARCHAR(32) NOT NULL,
+    entity_id INT NOT NULL,
+    changes JSON NOT NULL,
+    trace_id CHAR(32) NOT NULL DEFAULT '',
+    occurred_at TIMESTAMP(3) NOT NULL DEFAULT CURRENT_TIMESTAMP(3),
+    INDEX idx_audit_log_entity (entity, entity_id),
+    INDEX idx_audit_log_actor (actor)
+);
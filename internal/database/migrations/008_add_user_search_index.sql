@@ -0,0 +1 @@
+ALTER TABLE users ADD FULLTEXT INDEX ft_users_search (name, email, bio);
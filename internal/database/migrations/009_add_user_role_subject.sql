@@ -0,0 +1,5 @@
+-- Assigned roles are granted to the subject recorded when they were
+-- assigned, so editing the email of a user does not move its roles
+ALTER TABLE user_roles ADD COLUMN subject VARCHAR(255) NOT NULL DEFAULT '' AFTER role_id;
+UPDATE user_roles ur JOIN users u ON u.id = ur.user_id SET ur.subject = u.email;
+ALTER TABLE user_roles ADD INDEX idx_user_roles_subject (subject);
@@ -6,7 +6,8 @@ import (
 	"time"
 )
 
-// StartConnectionMonitoring starts periodic monitoring of database connection metrics
+// StartConnectionMonitoring periodically logs the connection pool stats; the
+// db.connections.active gauge reports them on every metrics collection
 func (db *DB) StartConnectionMonitoring(ctx context.Context, interval time.Duration) {
 	go func() {
 		ticker := time.NewTicker(interval)
@@ -18,28 +19,36 @@ func (db *DB) StartConnectionMonitoring(ctx context.Context, interval time.Durat
 				log.Println("Database connection monitoring stopped")
 				return
 			case <-ticker.C:
-				// Record connection pool metrics
-				db.RecordConnectionMetrics(ctx)
-
-				// Log connection stats for debugging
-				stats := db.GetConnectionStats()
-				log.Printf("DB Stats - Open: %d, InUse: %d, Idle: %d, WaitCount: %d, WaitDuration: %v",
-					stats.OpenConnections,
-					stats.InUse,
-					stats.Idle,
-					stats.WaitCount,
-					stats.WaitDuration,
-				)
+				for _, pool := range db.pools() {
+					stats := pool.GetConnectionStats()
+					log.Printf("DB Stats (%s) - Open: %d, InUse: %d, Idle: %d, WaitCount: %d, WaitDuration: %v",
+						pool.Role(),
+						stats.OpenConnections,
+						stats.InUse,
+						stats.Idle,
+						stats.WaitCount,
+						stats.WaitDuration,
+					)
+				}
 			}
 		}
 	}()
 }
 
-// GetDetailedStats returns detailed database statistics
+// pools returns db and the pool of its replica, if any
+func (db *DB) pools() []*DB {
+	if db.replica == nil {
+		return []*DB{db}
+	}
+	return []*DB{db, db.replica}
+}
+
+// GetDetailedStats returns detailed database statistics, with those of the
+// replica under "replica" when one is configured
 func (db *DB) GetDetailedStats() map[string]interface{} {
 	stats := db.GetConnectionStats()
 
-	return map[string]interface{}{
+	detailed := map[string]interface{}{
 		"open_connections":     stats.OpenConnections,
 		"in_use":               stats.InUse,
 		"idle":                 stats.Idle,
@@ -49,4 +58,8 @@ func (db *DB) GetDetailedStats() map[string]interface{} {
 		"max_idle_time_closed": stats.MaxIdleTimeClosed,
 		"max_lifetime_closed":  stats.MaxLifetimeClosed,
 	}
+	if db.replica != nil {
+		detailed["replica"] = db.replica.GetDetailedStats()
+	}
+	return detailed
 }
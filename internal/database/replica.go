@@ -0,0 +1,55 @@
+package database
+
+import "context"
+
+// Roles of a connection pool, recorded as RoleKey on the spans and metrics of
+// its queries
+const (
+	RolePrimary = "primary"
+	RoleReplica = "replica"
+)
+
+// RoleKey is the span and metric attribute holding the role of the pool a
+// query ran on
+const RoleKey = "db.role"
+
+type readPrimaryKey struct{}
+
+// ReadPrimary returns a context whose reads go to the primary even when a
+// replica is configured. Writes use it for the lookups they depend on, which
+// must see their own changes and not a lagging replica
+func ReadPrimary(ctx context.Context) context.Context {
+	return context.WithValue(ctx, readPrimaryKey{}, true)
+}
+
+// Reader returns the pool that reads under ctx should run on: the replica,
+// if one is configured and ctx was not marked with ReadPrimary, or db
+func (db *DB) Reader(ctx context.Context) *DB {
+	if db == nil || db.replica == nil {
+		return db
+	}
+	if primary, _ := ctx.Value(readPrimaryKey{}).(bool); primary {
+		return db
+	}
+	return db.replica
+}
+
+// SetReplica makes replica, labelled RoleReplica, serve the reads of db, see
+// Reader
+func (db *DB) SetReplica(replica *DB) {
+	replica.role = RoleReplica
+	db.replica = replica
+}
+
+// Replica returns the pool of the read replica, or nil without one
+func (db *DB) Replica() *DB {
+	return db.replica
+}
+
+// Role returns RolePrimary or RoleReplica
+func (db *DB) Role() string {
+	if db.role == "" {
+		return RolePrimary
+	}
+	return db.role
+}
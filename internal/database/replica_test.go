@@ -0,0 +1,81 @@
+package database
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"arquivolivre.com.br/otel/internal/config"
+	"arquivolivre.com.br/otel/pkg/oteltest"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+)
+
+func TestReaderRoutesToReplica(t *testing.T) {
+	primary := &DB{}
+	if got := primary.Reader(context.Background()); got != primary {
+		t.Fatal("expected reads to go to the primary without a replica")
+	}
+
+	replica := &DB{}
+	primary.SetReplica(replica)
+	if got := primary.Reader(context.Background()); got != replica {
+		t.Fatal("expected reads to go to the replica")
+	}
+	if got := primary.Reader(ReadPrimary(context.Background())); got != primary {
+		t.Fatal("expected ReadPrimary to keep reads on the primary")
+	}
+	if primary.Role() != RolePrimary || replica.Role() != RoleReplica {
+		t.Fatalf("unexpected roles %q and %q", primary.Role(), replica.Role())
+	}
+	if (*DB)(nil).Reader(context.Background()) != nil {
+		t.Fatal("expected a nil DB to read from nil")
+	}
+}
+
+func TestRecordQueryMetrics_RecordsRole(t *testing.T) {
+	rec := oteltest.Install(t)
+	primary, err := createDBWithMetrics(nil, &OtelMeterProvider{}, &DefaultMetricsFactory{})
+	if err != nil {
+		t.Fatalf("createDBWithMetrics: %v", err)
+	}
+	replica, err := createDBWithMetrics(nil, &OtelMeterProvider{}, &DefaultMetricsFactory{})
+	if err != nil {
+		t.Fatalf("createDBWithMetrics: %v", err)
+	}
+	primary.SetReplica(replica)
+
+	ctx, span := otel.Tracer("test").Start(context.Background(), "lookup")
+	replica.recordQueryMetrics(ctx, "SELECT", "users", "SELECT 1", time.Millisecond, nil)
+	span.End()
+	primary.recordQueryMetrics(context.Background(), "INSERT", "users", "INSERT 1", time.Millisecond, nil)
+
+	rec.AssertCounterValue(t, "db.query.count", []attribute.KeyValue{attribute.String(RoleKey, RoleReplica)}, 1)
+	rec.AssertCounterValue(t, "db.query.count", []attribute.KeyValue{attribute.String(RoleKey, RolePrimary)}, 1)
+	rec.AssertSpan(t, "lookup", attribute.String(RoleKey, RoleReplica))
+}
+
+func TestNewConnectionWithDeps_Replica(t *testing.T) {
+	cfg := &config.Config{
+		Database: config.DatabaseConfig{
+			DSN:         "test:dsn",
+			ReplicaDSN:  "test:replica",
+			ReplicaHost: "replica",
+			Name:        "testdb",
+		},
+	}
+
+	db, err := NewConnectionWithDeps(cfg, &mockDatabaseConnector{}, &NoopMeterProvider{}, &DefaultMetricsFactory{}, DefaultConnectionConfig())
+	if err != nil {
+		t.Fatalf("NewConnectionWithDeps: %v", err)
+	}
+	defer func() { _ = db.Close() }()
+
+	if db.Replica() == nil || db.Replica().Role() != RoleReplica {
+		t.Fatalf("expected a replica pool, got %+v", db.Replica())
+	}
+	if _, ok := db.GetDetailedStats()["replica"]; !ok {
+		t.Fatal("expected the replica stats in the detailed stats")
+	}
+}
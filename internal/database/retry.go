@@ -0,0 +1,63 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log"
+	"time"
+
+	"arquivolivre.com.br/otel/internal/config"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+	semconv "go.opentelemetry.io/otel/semconv/v1.21.0"
+)
+
+// Backoff between connection attempts; it doubles after each failure
+var (
+	initialConnectBackoff = 500 * time.Millisecond
+	maxConnectBackoff     = 10 * time.Second
+)
+
+// pingWithRetry pings db until it answers, waiting with exponential backoff
+// between attempts. It gives up after DB_CONNECT_MAX_ATTEMPTS attempts, or
+// when the next wait would exceed DB_CONNECT_MAX_ELAPSED, so the API can
+// start alongside MySQL in docker-compose. Each attempt is counted in
+// db.connection.attempts
+func pingWithRetry(db *sql.DB, cfg config.DatabaseConfig, meterProvider MeterProvider) error {
+	attempts, err := meterProvider.Meter("database").Int64Counter(
+		"db.connection.attempts",
+		metric.WithDescription("Database connection attempts made at startup"),
+	)
+	if err != nil {
+		log.Printf("Warning: Failed to create connection attempts metric: %v", err)
+	}
+
+	maxAttempts := max(cfg.ConnectMaxAttempts, 1)
+	backoff := initialConnectBackoff
+	start := time.Now()
+	for attempt := 1; ; attempt++ {
+		err := db.Ping()
+		if attempts != nil {
+			attempts.Add(context.Background(), 1, metric.WithAttributes(
+				semconv.DBSystemMySQL,
+				attribute.Bool("db.connection.success", err == nil),
+			))
+		}
+		if err == nil {
+			if attempt > 1 {
+				log.Printf("Connected to database after %d attempts in %s", attempt, time.Since(start).Round(time.Millisecond))
+			}
+			return nil
+		}
+
+		elapsed := time.Since(start)
+		if attempt >= maxAttempts || (cfg.ConnectMaxElapsed > 0 && elapsed+backoff > cfg.ConnectMaxElapsed) {
+			return fmt.Errorf("gave up after %d attempts in %s: %w", attempt, elapsed.Round(time.Millisecond), err)
+		}
+		log.Printf("Database not ready (attempt %d/%d): %v; retrying in %s", attempt, maxAttempts, err, backoff)
+		time.Sleep(backoff)
+		backoff = min(backoff*2, maxConnectBackoff)
+	}
+}
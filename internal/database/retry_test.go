@@ -0,0 +1,76 @@
+package database
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"arquivolivre.com.br/otel/internal/config"
+
+	sqlmock "github.com/DATA-DOG/go-sqlmock"
+)
+
+func withFastBackoff(t *testing.T) {
+	t.Helper()
+	initial, maxBackoff := initialConnectBackoff, maxConnectBackoff
+	initialConnectBackoff, maxConnectBackoff = time.Millisecond, 2*time.Millisecond
+	t.Cleanup(func() { initialConnectBackoff, maxConnectBackoff = initial, maxBackoff })
+}
+
+func TestPingWithRetry_RecoversAfterFailures(t *testing.T) {
+	withFastBackoff(t)
+	sqlDB, mock, err := sqlmock.New(sqlmock.MonitorPingsOption(true))
+	if err != nil {
+		t.Fatalf("sqlmock new: %v", err)
+	}
+	defer func() { _ = sqlDB.Close() }()
+
+	mock.ExpectPing().WillReturnError(errors.New("connection refused"))
+	mock.ExpectPing().WillReturnError(errors.New("connection refused"))
+	mock.ExpectPing()
+
+	if err := pingWithRetry(sqlDB, config.DatabaseConfig{ConnectMaxAttempts: 5}, &NoopMeterProvider{}); err != nil {
+		t.Fatalf("expected the third attempt to succeed, got %v", err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestPingWithRetry_GivesUpAfterMaxAttempts(t *testing.T) {
+	withFastBackoff(t)
+	sqlDB, mock, err := sqlmock.New(sqlmock.MonitorPingsOption(true))
+	if err != nil {
+		t.Fatalf("sqlmock new: %v", err)
+	}
+	defer func() { _ = sqlDB.Close() }()
+
+	for range 3 {
+		mock.ExpectPing().WillReturnError(errors.New("connection refused"))
+	}
+
+	err = pingWithRetry(sqlDB, config.DatabaseConfig{ConnectMaxAttempts: 3}, &NoopMeterProvider{})
+	if err == nil || !strings.Contains(err.Error(), "gave up after 3 attempts") {
+		t.Fatalf("expected to give up after 3 attempts, got %v", err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestPingWithRetry_StopsAtMaxElapsed(t *testing.T) {
+	withFastBackoff(t)
+	sqlDB, mock, err := sqlmock.New(sqlmock.MonitorPingsOption(true))
+	if err != nil {
+		t.Fatalf("sqlmock new: %v", err)
+	}
+	defer func() { _ = sqlDB.Close() }()
+
+	// The first wait already exceeds the budget, so only one attempt is made
+	mock.ExpectPing().WillReturnError(errors.New("connection refused"))
+	cfg := config.DatabaseConfig{ConnectMaxAttempts: 10, ConnectMaxElapsed: time.Microsecond}
+	if err := pingWithRetry(sqlDB, cfg, &NoopMeterProvider{}); err == nil || !strings.Contains(err.Error(), "gave up after 1 attempts") {
+		t.Fatalf("expected to give up after the first attempt, got %v", err)
+	}
+}
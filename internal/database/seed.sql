@@ -0,0 +1,16 @@
+-- Demo data; safe to run repeatedly
+
+INSERT IGNORE INTO users (name, email, bio) VALUES
+    ('John Doe', 'john@example.com', 'I am a software engineer'),
+    ('Jane Smith', 'jane@example.com', 'I am a salesperson'),
+    ('Bob Johnson', 'bob@example.com', 'I am a manager');
+
+INSERT INTO posts (user_id, title, body)
+SELECT id, 'Hello, OpenTelemetry', 'Tracing every request end to end.' FROM users
+WHERE email = 'john@example.com'
+    AND NOT EXISTS (SELECT 1 FROM posts WHERE title = 'Hello, OpenTelemetry');
+
+INSERT INTO posts (user_id, title, body)
+SELECT id, 'Sales update', 'Q3 numbers are looking good.' FROM users
+WHERE email = 'jane@example.com'
+    AND NOT EXISTS (SELECT 1 FROM posts WHERE title = 'Sales update');
@@ -0,0 +1,66 @@
+package database
+
+import (
+	"context"
+	"regexp"
+	"strings"
+	"time"
+
+	"arquivolivre.com.br/otel/internal/logging"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// maxStatementLength truncates statements in slow query logs and events
+const maxStatementLength = 1000
+
+var (
+	quotedLiteral  = regexp.MustCompile(`'(?:[^'\\]|\\.)*'|"(?:[^"\\]|\\.)*"`)
+	numericLiteral = regexp.MustCompile(`\b\d+(?:\.\d+)?\b`)
+)
+
+// SanitizeStatement replaces string and numeric literals with ? and collapses
+// whitespace, so a logged statement keeps its shape but none of its data
+func SanitizeStatement(statement string) string {
+	statement = quotedLiteral.ReplaceAllString(statement, "?")
+	statement = numericLiteral.ReplaceAllString(statement, "?")
+	statement = strings.Join(strings.Fields(statement), " ")
+	if len(statement) > maxStatementLength {
+		statement = statement[:maxStatementLength] + "..."
+	}
+	return statement
+}
+
+// SetSlowQueryThreshold sets the duration from which queries are reported as
+// slow; zero disables the report
+func (db *DB) SetSlowQueryThreshold(threshold time.Duration) {
+	db.slowQueryThreshold = threshold
+}
+
+// recordSlowQuery counts a query over the slow query threshold in
+// db.query.slow, adds a db.slow_query event to the active span and logs the
+// sanitized statement with the trace ID
+func (db *DB) recordSlowQuery(ctx context.Context, attrs []attribute.KeyValue, statement string, duration time.Duration) {
+	statement = SanitizeStatement(statement)
+	if db.querySlow != nil {
+		db.querySlow.Add(ctx, 1, metric.WithAttributes(attrs...))
+	}
+
+	trace.SpanFromContext(ctx).AddEvent("db.slow_query", trace.WithAttributes(
+		attribute.String("db.statement", statement),
+		attribute.Int64("db.query.duration_ms", duration.Milliseconds()),
+		attribute.Int64("db.slow_query.threshold_ms", db.slowQueryThreshold.Milliseconds()),
+	))
+
+	fields := map[string]interface{}{
+		"db.statement":               statement,
+		"db.query.duration_ms":       duration.Milliseconds(),
+		"db.slow_query.threshold_ms": db.slowQueryThreshold.Milliseconds(),
+	}
+	for _, kv := range attrs {
+		fields[string(kv.Key)] = kv.Value.Emit()
+	}
+	logging.Module("database").LogWarn(ctx, "Slow database query", fields)
+}
@@ -0,0 +1,85 @@
+package database
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/metric/metricdata"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+func TestSanitizeStatement(t *testing.T) {
+	cases := map[string]string{
+		"SELECT id FROM users\n\t\tWHERE id = ?":                  "SELECT id FROM users WHERE id = ?",
+		"SELECT * FROM users WHERE email = 'ann@example.com'":     "SELECT * FROM users WHERE email = ?",
+		`UPDATE users SET bio = "it's \"quoted\"" WHERE id = 42`:  "UPDATE users SET bio = ? WHERE id = ?",
+		"SELECT * FROM v2_users LIMIT 10 OFFSET 2.5":              "SELECT * FROM v2_users LIMIT ? OFFSET ?",
+		"SELECT name FROM users WHERE name = 'O\\'Brien' AND x=1": "SELECT name FROM users WHERE name = ? AND x=?",
+	}
+	for in, want := range cases {
+		if got := SanitizeStatement(in); got != want {
+			t.Errorf("SanitizeStatement(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
+
+func TestRecordQueryMetrics_SlowQuery(t *testing.T) {
+	reader := sdkmetric.NewManualReader()
+	meter := sdkmetric.NewMeterProvider(sdkmetric.WithReader(reader)).Meter("test")
+	metrics, err := (&DefaultMetricsFactory{}).CreateMetrics(meter)
+	if err != nil {
+		t.Fatalf("create metrics: %v", err)
+	}
+	d := &DB{querySlow: metrics.QuerySlow}
+	d.SetSlowQueryThreshold(100 * time.Millisecond)
+
+	recorder := tracetest.NewSpanRecorder()
+	ctx, span := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)).Tracer("test").Start(context.Background(), "query")
+	d.recordQueryMetrics(ctx, "SELECT", "users", "SELECT * FROM users WHERE email = 'ann@example.com'", 250*time.Millisecond, nil)
+	d.recordQueryMetrics(ctx, "SELECT", "users", "SELECT 1", 10*time.Millisecond, nil)
+	span.End()
+
+	var rm metricdata.ResourceMetrics
+	if err := reader.Collect(context.Background(), &rm); err != nil {
+		t.Fatalf("collect: %v", err)
+	}
+	var slow int64
+	for _, m := range rm.ScopeMetrics[0].Metrics {
+		if m.Name == "db.query.slow" {
+			for _, dp := range m.Data.(metricdata.Sum[int64]).DataPoints {
+				slow += dp.Value
+			}
+		}
+	}
+	if slow != 1 {
+		t.Errorf("expected 1 slow query, got %d", slow)
+	}
+
+	events := recorder.Ended()[0].Events()
+	if len(events) != 1 || events[0].Name != "db.slow_query" {
+		t.Fatalf("expected one db.slow_query event, got %v", events)
+	}
+	want := attribute.String("db.statement", "SELECT * FROM users WHERE email = ?")
+	found := false
+	for _, kv := range events[0].Attributes {
+		found = found || kv == want
+	}
+	if !found {
+		t.Errorf("expected %v in %v", want, events[0].Attributes)
+	}
+}
+
+func TestRecordQueryMetrics_SlowQueryDisabled(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	ctx, span := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)).Tracer("test").Start(context.Background(), "query")
+	(&DB{}).recordQueryMetrics(ctx, "SELECT", "users", "SELECT 1", time.Hour, nil)
+	span.End()
+
+	if events := recorder.Ended()[0].Events(); len(events) != 0 {
+		t.Errorf("expected no events without a threshold, got %v", events)
+	}
+}
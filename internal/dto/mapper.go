@@ -0,0 +1,45 @@
+// Package dto maps domain models to versioned response DTOs so the API can
+// evolve (renamed or removed fields) without touching repositories.
+package dto
+
+import "arquivolivre.com.br/otel/internal/models"
+
+// Mapper converts domain models into the response shape of one API version.
+// admin selects whether audit fields are included; a post embeds its author
+// unless author is nil.
+type Mapper interface {
+	Version() string
+	User(user *models.User, admin bool) any
+	Post(post *models.Post, author *models.User, admin bool) any
+}
+
+// Users maps a slice of users with m
+func Users(m Mapper, users []models.User, admin bool) []any {
+	out := make([]any, len(users))
+	for i := range users {
+		out[i] = m.User(&users[i], admin)
+	}
+	return out
+}
+
+// Posts maps a slice of posts with m, without their authors
+func Posts(m Mapper, posts []models.Post, admin bool) []any {
+	out := make([]any, len(posts))
+	for i := range posts {
+		out[i] = m.Post(&posts[i], nil, admin)
+	}
+	return out
+}
+
+// ForVersion returns the mapper for an API version such as "v1", or false
+// when the version is unknown
+func ForVersion(version string) (Mapper, bool) {
+	switch version {
+	case V1.Version():
+		return V1, true
+	case V2.Version():
+		return V2, true
+	default:
+		return nil, false
+	}
+}
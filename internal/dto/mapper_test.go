@@ -0,0 +1,58 @@
+package dto
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"arquivolivre.com.br/otel/internal/models"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMappers(t *testing.T) {
+	bio := "hi"
+	ts := models.NewTimestamp(time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC))
+	user := &models.User{ID: 1, Name: "Ann", Email: "ann@example.com", Bio: &bio, CreatedBy: "alice", UpdatedBy: "bob", CreatedAt: ts, UpdatedAt: ts, Version: 2}
+
+	cases := []struct {
+		mapper Mapper
+		admin  bool
+		want   string
+	}{
+		{V1, false, `{"id":1,"name":"Ann","email":"ann@example.com","bio":"hi","created_at":"2024-01-02T03:04:05.000Z","updated_at":"2024-01-02T03:04:05.000Z","version":2}`},
+		{V1, true, `{"id":1,"name":"Ann","email":"ann@example.com","bio":"hi","created_at":"2024-01-02T03:04:05.000Z","updated_at":"2024-01-02T03:04:05.000Z","version":2,"created_by":"alice","updated_by":"bob"}`},
+		{V2, false, `{"id":1,"display_name":"Ann","email":"ann@example.com","about":"hi","version":2,"meta":{"created_at":"2024-01-02T03:04:05.000Z","updated_at":"2024-01-02T03:04:05.000Z"}}`},
+		{V2, true, `{"id":1,"display_name":"Ann","email":"ann@example.com","about":"hi","version":2,"meta":{"created_at":"2024-01-02T03:04:05.000Z","updated_at":"2024-01-02T03:04:05.000Z","created_by":"alice","updated_by":"bob"}}`},
+	}
+	for _, tc := range cases {
+		got, err := json.Marshal(tc.mapper.User(user, tc.admin))
+		require.NoError(t, err)
+		assert.JSONEq(t, tc.want, string(got), "%s admin=%v", tc.mapper.Version(), tc.admin)
+	}
+
+	post := &models.Post{ID: 2, UserID: 1, Title: "T", Body: "B", CreatedAt: ts, UpdatedAt: ts}
+	got, err := json.Marshal(V2.Post(post, nil, false))
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"id":2,"author_id":1,"title":"T","body":"B","meta":{"created_at":"2024-01-02T03:04:05.000Z","updated_at":"2024-01-02T03:04:05.000Z"}}`, string(got))
+
+	got, err = json.Marshal(V2.Post(post, user, true))
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"id":2,"author_id":1,"author":{"id":1,"display_name":"Ann","email":"ann@example.com","about":"hi","version":2,"meta":{"created_at":"2024-01-02T03:04:05.000Z","updated_at":"2024-01-02T03:04:05.000Z"}},"title":"T","body":"B","meta":{"created_at":"2024-01-02T03:04:05.000Z","updated_at":"2024-01-02T03:04:05.000Z"}}`, string(got))
+
+	got, err = json.Marshal(V1.Post(post, user, false))
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"id":2,"user_id":1,"title":"T","body":"B","author":{"id":1,"name":"Ann","email":"ann@example.com","bio":"hi","created_at":"2024-01-02T03:04:05.000Z","updated_at":"2024-01-02T03:04:05.000Z","version":2},"created_at":"2024-01-02T03:04:05.000Z","updated_at":"2024-01-02T03:04:05.000Z"}`, string(got))
+}
+
+func TestForVersion(t *testing.T) {
+	m, ok := ForVersion("v2")
+	assert.True(t, ok)
+	assert.Equal(t, "v2", m.Version())
+
+	_, ok = ForVersion("v9")
+	assert.False(t, ok)
+
+	assert.Len(t, Users(V1, []models.User{{ID: 1}, {ID: 2}}, false), 2)
+}
@@ -0,0 +1,26 @@
+package dto
+
+import "arquivolivre.com.br/otel/internal/models"
+
+// V1 is the original response shape, identical to models.UserResponse and
+// models.PostResponse
+var V1 Mapper = v1Mapper{}
+
+type v1Mapper struct{}
+
+func (v1Mapper) Version() string { return "v1" }
+
+func (v1Mapper) User(user *models.User, admin bool) any {
+	if admin {
+		return user.ToAdminResponse()
+	}
+	return user.ToResponse()
+}
+
+func (v1Mapper) Post(post *models.Post, author *models.User, admin bool) any {
+	response := post.ToResponseWithAuthor(author)
+	if admin {
+		response.AuditInfo = post.ToAdminResponse().AuditInfo
+	}
+	return response
+}
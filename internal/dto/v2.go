@@ -0,0 +1,79 @@
+package dto
+
+import "arquivolivre.com.br/otel/internal/models"
+
+// V2 renames name to display_name and bio to about, and groups timestamps
+// and audit fields under meta
+var V2 Mapper = v2Mapper{}
+
+// UserV2 is the v2 user representation
+type UserV2 struct {
+	ID          int     `json:"id"`
+	DisplayName string  `json:"display_name"`
+	Email       string  `json:"email"`
+	About       *string `json:"about,omitempty"`
+	Version     int     `json:"version"`
+	Meta        MetaV2  `json:"meta"`
+}
+
+// PostV2 is the v2 post representation
+type PostV2 struct {
+	ID       int     `json:"id"`
+	AuthorID int     `json:"author_id"`
+	Author   *UserV2 `json:"author,omitempty"`
+	Title    string  `json:"title"`
+	Body     string  `json:"body"`
+	Meta     MetaV2  `json:"meta"`
+}
+
+// MetaV2 groups bookkeeping fields; audit fields are only set for admins
+type MetaV2 struct {
+	CreatedAt models.Timestamp `json:"created_at"`
+	UpdatedAt models.Timestamp `json:"updated_at"`
+	CreatedBy string           `json:"created_by,omitempty"`
+	UpdatedBy string           `json:"updated_by,omitempty"`
+}
+
+type v2Mapper struct{}
+
+func (v2Mapper) Version() string { return "v2" }
+
+func (v2Mapper) User(user *models.User, admin bool) any {
+	return newUserV2(user, admin)
+}
+
+func (v2Mapper) Post(post *models.Post, author *models.User, admin bool) any {
+	response := PostV2{
+		ID:       post.ID,
+		AuthorID: post.UserID,
+		Title:    post.Title,
+		Body:     post.Body,
+		Meta:     newMetaV2(post.CreatedAt, post.UpdatedAt, post.CreatedBy, post.UpdatedBy, admin),
+	}
+	if author != nil {
+		// Like the v1 author, the embedded user has no audit fields
+		user := newUserV2(author, false)
+		response.Author = &user
+	}
+	return response
+}
+
+func newUserV2(user *models.User, admin bool) UserV2 {
+	return UserV2{
+		ID:          user.ID,
+		DisplayName: user.Name,
+		Email:       user.Email,
+		About:       user.Bio,
+		Version:     user.Version,
+		Meta:        newMetaV2(user.CreatedAt, user.UpdatedAt, user.CreatedBy, user.UpdatedBy, admin),
+	}
+}
+
+func newMetaV2(createdAt, updatedAt models.Timestamp, createdBy, updatedBy string, admin bool) MetaV2 {
+	meta := MetaV2{CreatedAt: createdAt, UpdatedAt: updatedAt}
+	if admin {
+		meta.CreatedBy = createdBy
+		meta.UpdatedBy = updatedBy
+	}
+	return meta
+}
@@ -0,0 +1,59 @@
+package enrichment
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"time"
+
+	"arquivolivre.com.br/otel/pkg/utils"
+
+	"go.opentelemetry.io/contrib/instrumentation/net/http/otelhttp"
+)
+
+// Client calls the enrichment service; requests carry the caller's trace
+// context through otelhttp
+type Client struct {
+	baseURL    string
+	httpClient *http.Client
+}
+
+// NewClient creates a client for the service at baseURL
+func NewClient(baseURL string, timeout time.Duration) *Client {
+	return &Client{
+		baseURL: baseURL,
+		httpClient: &http.Client{
+			Timeout:   timeout,
+			Transport: otelhttp.NewTransport(http.DefaultTransport),
+		},
+	}
+}
+
+// Enrich fetches the profile for email
+func (c *Client) Enrich(ctx context.Context, email string) (*Profile, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.baseURL+"/enrich?email="+url.QueryEscape(email), nil)
+	if err != nil {
+		return nil, err
+	}
+	if requestID := utils.RequestIDFromContext(ctx); requestID != "" {
+		req.Header.Set(utils.RequestIDHeader, requestID)
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("enrichment request failed: %w", err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("enrichment service returned %d", resp.StatusCode)
+	}
+
+	var profile Profile
+	if err := json.NewDecoder(resp.Body).Decode(&profile); err != nil {
+		return nil, fmt.Errorf("failed to decode enrichment response: %w", err)
+	}
+	return &profile, nil
+}
@@ -0,0 +1,55 @@
+// Package enrichment implements the companion enrichment service and the
+// API's client for it. The service derives profile details from an email
+// address; its main purpose is to give the example a second service in its
+// traces.
+package enrichment
+
+import (
+	"crypto/md5"
+	"encoding/hex"
+	"net/http"
+	"slices"
+	"strings"
+
+	"arquivolivre.com.br/otel/internal/validation"
+	"arquivolivre.com.br/otel/pkg/utils"
+
+	"github.com/gin-gonic/gin"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Profile holds details derived from an email address
+type Profile struct {
+	Domain     string `json:"domain"`
+	AvatarURL  string `json:"avatar_url"`
+	Disposable bool   `json:"disposable"`
+}
+
+// Enrich derives a profile from email
+func Enrich(email string) Profile {
+	email = strings.ToLower(strings.TrimSpace(email))
+	_, domain, _ := strings.Cut(email, "@")
+	hash := md5.Sum([]byte(email))
+	return Profile{
+		Domain:     domain,
+		AvatarURL:  "https://www.gravatar.com/avatar/" + hex.EncodeToString(hash[:]) + "?d=identicon",
+		Disposable: slices.Contains(validation.DefaultDisallowedEmailDomains, domain),
+	}
+}
+
+// Handler serves GET /enrich?email=<address>
+func Handler(c *gin.Context) {
+	email := c.Query("email")
+	if !strings.Contains(email, "@") {
+		utils.SendBadRequest(c, "email query parameter must be an email address")
+		return
+	}
+
+	profile := Enrich(email)
+	trace.SpanFromContext(c.Request.Context()).SetAttributes(
+		attribute.String("enrichment.domain", profile.Domain),
+		attribute.Bool("enrichment.disposable", profile.Disposable),
+	)
+	utils.SendJSON(c, http.StatusOK, profile)
+}
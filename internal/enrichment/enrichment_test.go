@@ -0,0 +1,42 @@
+package enrichment
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEnrich(t *testing.T) {
+	p := Enrich(" Ann@Mailinator.com ")
+	assert.Equal(t, "mailinator.com", p.Domain)
+	assert.True(t, p.Disposable)
+	assert.Contains(t, p.AvatarURL, "https://www.gravatar.com/avatar/")
+
+	assert.False(t, Enrich("ann@example.com").Disposable)
+}
+
+func TestClientAgainstHandler(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.GET("/enrich", Handler)
+	srv := httptest.NewServer(r)
+	defer srv.Close()
+
+	client := NewClient(srv.URL, time.Second)
+	profile, err := client.Enrich(context.Background(), "ann+tag@example.com")
+	require.NoError(t, err)
+	assert.Equal(t, "example.com", profile.Domain)
+
+	_, err = client.Enrich(context.Background(), "not-an-email")
+	assert.ErrorContains(t, err, "400")
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/enrich", nil))
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
@@ -0,0 +1,172 @@
+package events
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"arquivolivre.com.br/otel/internal/logging"
+
+	"github.com/segmentio/kafka-go"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/metric"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Handler processes a decoded user event
+type Handler func(ctx context.Context, event UserEvent) error
+
+// messageReader is the subset of *kafka.Reader used by Consumer
+type messageReader interface {
+	FetchMessage(ctx context.Context) (kafka.Message, error)
+	CommitMessages(ctx context.Context, msgs ...kafka.Message) error
+	Close() error
+}
+
+// Consumer reads user events from Kafka and processes each one in a span
+// that continues the producer's trace
+type Consumer struct {
+	reader          messageReader
+	topic           string
+	group           string
+	handler         Handler
+	tracer          trace.Tracer
+	processDuration metric.Float64Histogram
+	processErrors   metric.Int64Counter
+	lag             metric.Int64Gauge
+}
+
+// NewKafkaConsumer creates a consumer in group reading topic from brokers
+func NewKafkaConsumer(brokers []string, topic, group string, handler Handler) (*Consumer, error) {
+	reader := kafka.NewReader(kafka.ReaderConfig{
+		Brokers: brokers,
+		Topic:   topic,
+		GroupID: group,
+	})
+	return newConsumer(reader, topic, group, handler)
+}
+
+func newConsumer(reader messageReader, topic, group string, handler Handler) (*Consumer, error) {
+	meter := otel.Meter(instrumentationName)
+
+	processDuration, err := meter.Float64Histogram(
+		"messaging_process_duration_seconds",
+		metric.WithDescription("Message processing duration in seconds"),
+		metric.WithUnit("s"),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create process duration metric: %w", err)
+	}
+
+	processErrors, err := meter.Int64Counter(
+		"messaging_process_errors_total",
+		metric.WithDescription("Total number of messages that failed processing"),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create process errors metric: %w", err)
+	}
+
+	lag, err := meter.Int64Gauge(
+		"messaging_consumer_lag",
+		metric.WithDescription("Messages between the last consumed offset and the partition high water mark"),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create consumer lag metric: %w", err)
+	}
+
+	return &Consumer{
+		reader:          reader,
+		topic:           topic,
+		group:           group,
+		handler:         handler,
+		tracer:          otel.Tracer(instrumentationName),
+		processDuration: processDuration,
+		processErrors:   processErrors,
+		lag:             lag,
+	}, nil
+}
+
+// Run consumes messages until ctx is cancelled. Messages are committed once
+// processed; a message that fails processing is logged and committed so a
+// poison message cannot block the partition.
+func (c *Consumer) Run(ctx context.Context) error {
+	for {
+		msg, err := c.reader.FetchMessage(ctx)
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil
+			}
+			return fmt.Errorf("failed to fetch message: %w", err)
+		}
+
+		c.process(ctx, msg)
+
+		if err := c.reader.CommitMessages(ctx, msg); err != nil && ctx.Err() == nil {
+			logging.Module("events").LogError(ctx, err, "Failed to commit message", map[string]interface{}{
+				"topic":     msg.Topic,
+				"partition": msg.Partition,
+				"offset":    msg.Offset,
+			})
+		}
+	}
+}
+
+// Close closes the underlying reader
+func (c *Consumer) Close() error {
+	return c.reader.Close()
+}
+
+func (c *Consumer) process(ctx context.Context, msg kafka.Message) {
+	attrs := []attribute.KeyValue{
+		attribute.String("messaging.system", "kafka"),
+		attribute.String("messaging.destination.name", c.topic),
+		attribute.String("messaging.consumer.group.name", c.group),
+		attribute.String("messaging.operation", "process"),
+	}
+	c.lag.Record(ctx, max(msg.HighWaterMark-msg.Offset-1, 0), metric.WithAttributes(
+		append(attrs, attribute.Int("messaging.kafka.destination.partition", msg.Partition))...,
+	))
+
+	parent := otel.GetTextMapPropagator().Extract(ctx, HeaderCarrier{Headers: &msg.Headers})
+	ctx, span := c.tracer.Start(parent, c.topic+" process",
+		trace.WithSpanKind(trace.SpanKindConsumer),
+		trace.WithAttributes(attrs...),
+		trace.WithAttributes(
+			attribute.Int("messaging.kafka.destination.partition", msg.Partition),
+			attribute.Int64("messaging.kafka.message.offset", msg.Offset),
+		),
+	)
+	defer span.End()
+
+	start := time.Now()
+	err := c.handle(ctx, msg)
+	c.processDuration.Record(ctx, time.Since(start).Seconds(), metric.WithAttributes(attrs...))
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+		c.processErrors.Add(ctx, 1, metric.WithAttributes(attrs...))
+		logging.Module("events").LogError(ctx, err, "Failed to process message", map[string]interface{}{
+			"topic":  msg.Topic,
+			"offset": msg.Offset,
+		})
+	}
+}
+
+func (c *Consumer) handle(ctx context.Context, msg kafka.Message) error {
+	var event UserEvent
+	if err := json.Unmarshal(msg.Value, &event); err != nil {
+		return fmt.Errorf("failed to decode event: %w", err)
+	}
+	if event.Type == "" {
+		return errors.New("event has no type")
+	}
+	trace.SpanFromContext(ctx).SetAttributes(
+		attribute.String("event.type", string(event.Type)),
+		attribute.Int("user.id", event.UserID),
+	)
+	return c.handler(ctx, event)
+}
@@ -0,0 +1,99 @@
+package events
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/segmentio/kafka-go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// fakeReader serves msgs then blocks until the context is cancelled
+type fakeReader struct {
+	msgs      []kafka.Message
+	committed []kafka.Message
+	cancel    context.CancelFunc
+}
+
+func (r *fakeReader) FetchMessage(ctx context.Context) (kafka.Message, error) {
+	if len(r.msgs) == 0 {
+		r.cancel()
+		<-ctx.Done()
+		return kafka.Message{}, ctx.Err()
+	}
+	msg := r.msgs[0]
+	r.msgs = r.msgs[1:]
+	return msg, nil
+}
+
+func (r *fakeReader) CommitMessages(_ context.Context, msgs ...kafka.Message) error {
+	r.committed = append(r.committed, msgs...)
+	return nil
+}
+
+func (r *fakeReader) Close() error { return nil }
+
+func TestConsumerContinuesProducerTrace(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	tp := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+	prevTP, prevProp := otel.GetTracerProvider(), otel.GetTextMapPropagator()
+	otel.SetTracerProvider(tp)
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+	defer func() {
+		otel.SetTracerProvider(prevTP)
+		otel.SetTextMapPropagator(prevProp)
+	}()
+
+	writer := &fakeWriter{}
+	publisher, err := newKafkaPublisher(writer, "user-events")
+	require.NoError(t, err)
+	require.NoError(t, publisher.PublishUserEvent(context.Background(), UserEvent{Type: UserCreated, UserID: 3}))
+	bad := kafka.Message{Topic: "user-events", Value: []byte("not json")}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	reader := &fakeReader{msgs: []kafka.Message{writer.msgs[0], bad}, cancel: cancel}
+
+	var handled []UserEvent
+	consumer, err := newConsumer(reader, "user-events", "test", func(_ context.Context, event UserEvent) error {
+		handled = append(handled, event)
+		return nil
+	})
+	require.NoError(t, err)
+	require.NoError(t, consumer.Run(ctx))
+
+	require.Len(t, handled, 1)
+	assert.Equal(t, 3, handled[0].UserID)
+	assert.Len(t, reader.committed, 2, "failed messages are committed too")
+
+	spans := recorder.Ended()
+	require.Len(t, spans, 3)
+	producer, processed, failed := spans[0], spans[1], spans[2]
+	assert.Equal(t, "user-events process", processed.Name())
+	assert.Equal(t, trace.SpanKindConsumer, processed.SpanKind())
+	assert.Equal(t, producer.SpanContext().TraceID(), processed.SpanContext().TraceID())
+	assert.Equal(t, producer.SpanContext().SpanID(), processed.Parent().SpanID())
+	assert.Contains(t, failed.Status().Description, "failed to decode event")
+}
+
+func TestConsumerFetchError(t *testing.T) {
+	consumer, err := newConsumer(errReader{}, "user-events", "test", nil)
+	require.NoError(t, err)
+	assert.ErrorContains(t, consumer.Run(context.Background()), "connection refused")
+}
+
+type errReader struct{}
+
+func (errReader) FetchMessage(context.Context) (kafka.Message, error) {
+	return kafka.Message{}, errors.New("connection refused")
+}
+
+func (errReader) CommitMessages(context.Context, ...kafka.Message) error { return nil }
+
+func (errReader) Close() error { return nil }
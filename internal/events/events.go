@@ -0,0 +1,37 @@
+// Package events publishes domain events for asynchronous consumers
+package events
+
+import (
+	"context"
+	"time"
+)
+
+// EventType identifies what happened to an entity
+type EventType string
+
+const (
+	UserCreated EventType = "user.created"
+	UserUpdated EventType = "user.updated"
+	UserDeleted EventType = "user.deleted"
+)
+
+// UserEvent describes a change to a user
+type UserEvent struct {
+	Type       EventType `json:"type"`
+	UserID     int       `json:"user_id"`
+	Actor      string    `json:"actor"`
+	OccurredAt time.Time `json:"occurred_at"`
+}
+
+// Publisher sends user events to a message broker
+type Publisher interface {
+	PublishUserEvent(ctx context.Context, event UserEvent) error
+	Close() error
+}
+
+// NoopPublisher discards events; it is used when no broker is configured
+type NoopPublisher struct{}
+
+func (NoopPublisher) PublishUserEvent(context.Context, UserEvent) error { return nil }
+
+func (NoopPublisher) Close() error { return nil }
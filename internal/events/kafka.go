@@ -0,0 +1,175 @@
+package events
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/segmentio/kafka-go"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/metric"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const instrumentationName = "arquivolivre.com.br/otel/internal/events"
+
+// messageWriter is the subset of *kafka.Writer used by KafkaPublisher
+type messageWriter interface {
+	WriteMessages(ctx context.Context, msgs ...kafka.Message) error
+	Close() error
+}
+
+// KafkaPublisher publishes user events to a Kafka topic with the W3C trace
+// context injected into the message headers
+type KafkaPublisher struct {
+	writer         messageWriter
+	topic          string
+	tracer         trace.Tracer
+	publishLatency metric.Float64Histogram
+	published      metric.Int64Counter
+	publishErrors  metric.Int64Counter
+}
+
+// NewKafkaPublisher creates a publisher writing to topic on brokers
+func NewKafkaPublisher(brokers []string, topic string) (*KafkaPublisher, error) {
+	writer := &kafka.Writer{
+		Addr:                   kafka.TCP(brokers...),
+		Topic:                  topic,
+		Balancer:               &kafka.Hash{},
+		BatchTimeout:           10 * time.Millisecond,
+		RequiredAcks:           kafka.RequireOne,
+		AllowAutoTopicCreation: true,
+	}
+	return newKafkaPublisher(writer, topic)
+}
+
+func newKafkaPublisher(writer messageWriter, topic string) (*KafkaPublisher, error) {
+	meter := otel.Meter(instrumentationName)
+
+	publishLatency, err := meter.Float64Histogram(
+		"messaging_publish_duration_seconds",
+		metric.WithDescription("Message publish duration in seconds"),
+		metric.WithUnit("s"),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create publish duration metric: %w", err)
+	}
+
+	published, err := meter.Int64Counter(
+		"messaging_published_messages_total",
+		metric.WithDescription("Total number of messages published"),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create published messages metric: %w", err)
+	}
+
+	publishErrors, err := meter.Int64Counter(
+		"messaging_publish_errors_total",
+		metric.WithDescription("Total number of failed message publishes"),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create publish errors metric: %w", err)
+	}
+
+	return &KafkaPublisher{
+		writer:         writer,
+		topic:          topic,
+		tracer:         otel.Tracer(instrumentationName),
+		publishLatency: publishLatency,
+		published:      published,
+		publishErrors:  publishErrors,
+	}, nil
+}
+
+// PublishUserEvent writes event keyed by user ID so events for one user stay
+// ordered within a partition
+func (p *KafkaPublisher) PublishUserEvent(ctx context.Context, event UserEvent) error {
+	attrs := []attribute.KeyValue{
+		attribute.String("messaging.system", "kafka"),
+		attribute.String("messaging.destination.name", p.topic),
+		attribute.String("messaging.operation", "publish"),
+		attribute.String("event.type", string(event.Type)),
+	}
+
+	ctx, span := p.tracer.Start(ctx, p.topic+" publish",
+		trace.WithSpanKind(trace.SpanKindProducer),
+		trace.WithAttributes(attrs...),
+	)
+	defer span.End()
+
+	value, err := json.Marshal(event)
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, "failed to encode event")
+		return fmt.Errorf("failed to encode event: %w", err)
+	}
+	span.SetAttributes(
+		attribute.String("messaging.kafka.message.key", strconv.Itoa(event.UserID)),
+		attribute.Int("messaging.message.body.size", len(value)),
+	)
+
+	msg := kafka.Message{
+		Key:   []byte(strconv.Itoa(event.UserID)),
+		Value: value,
+		Headers: []kafka.Header{
+			{Key: "event_type", Value: []byte(event.Type)},
+		},
+	}
+	otel.GetTextMapPropagator().Inject(ctx, HeaderCarrier{Headers: &msg.Headers})
+
+	start := time.Now()
+	err = p.writer.WriteMessages(ctx, msg)
+	p.publishLatency.Record(ctx, time.Since(start).Seconds(), metric.WithAttributes(attrs...))
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, "failed to publish event")
+		p.publishErrors.Add(ctx, 1, metric.WithAttributes(attrs...))
+		return fmt.Errorf("failed to publish event: %w", err)
+	}
+	p.published.Add(ctx, 1, metric.WithAttributes(attrs...))
+	return nil
+}
+
+// Close flushes pending messages and closes the writer
+func (p *KafkaPublisher) Close() error {
+	return p.writer.Close()
+}
+
+// HeaderCarrier adapts Kafka message headers to a propagation.TextMapCarrier
+type HeaderCarrier struct {
+	Headers *[]kafka.Header
+}
+
+var _ propagation.TextMapCarrier = HeaderCarrier{}
+
+func (c HeaderCarrier) Get(key string) string {
+	for _, h := range *c.Headers {
+		if h.Key == key {
+			return string(h.Value)
+		}
+	}
+	return ""
+}
+
+func (c HeaderCarrier) Set(key, value string) {
+	for i, h := range *c.Headers {
+		if h.Key == key {
+			(*c.Headers)[i].Value = []byte(value)
+			return
+		}
+	}
+	*c.Headers = append(*c.Headers, kafka.Header{Key: key, Value: []byte(value)})
+}
+
+func (c HeaderCarrier) Keys() []string {
+	keys := make([]string, 0, len(*c.Headers))
+	for _, h := range *c.Headers {
+		keys = append(keys, h.Key)
+	}
+	return keys
+}
@@ -0,0 +1,121 @@
+package events
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/segmentio/kafka-go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/propagation"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/metric/metricdata"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+)
+
+type fakeWriter struct {
+	msgs []kafka.Message
+	err  error
+}
+
+func (w *fakeWriter) WriteMessages(_ context.Context, msgs ...kafka.Message) error {
+	if w.err != nil {
+		return w.err
+	}
+	w.msgs = append(w.msgs, msgs...)
+	return nil
+}
+
+func (w *fakeWriter) Close() error { return nil }
+
+func TestPublishUserEventInjectsTraceContext(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	tp := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+	prevTP, prevProp := otel.GetTracerProvider(), otel.GetTextMapPropagator()
+	otel.SetTracerProvider(tp)
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+	defer func() {
+		otel.SetTracerProvider(prevTP)
+		otel.SetTextMapPropagator(prevProp)
+	}()
+
+	writer := &fakeWriter{}
+	p, err := newKafkaPublisher(writer, "user-events")
+	require.NoError(t, err)
+
+	ctx, parent := tp.Tracer("test").Start(context.Background(), "request")
+	event := UserEvent{Type: UserCreated, UserID: 7, Actor: "alice", OccurredAt: time.Now().UTC()}
+	require.NoError(t, p.PublishUserEvent(ctx, event))
+	parent.End()
+
+	require.Len(t, writer.msgs, 1)
+	msg := writer.msgs[0]
+	assert.Equal(t, "7", string(msg.Key))
+
+	var decoded UserEvent
+	require.NoError(t, json.Unmarshal(msg.Value, &decoded))
+	assert.Equal(t, UserCreated, decoded.Type)
+
+	carrier := HeaderCarrier{Headers: &msg.Headers}
+	assert.Equal(t, "user.created", carrier.Get("event_type"))
+	extracted := trace.SpanContextFromContext(otel.GetTextMapPropagator().Extract(context.Background(), carrier))
+	assert.Equal(t, parent.SpanContext().TraceID(), extracted.TraceID())
+
+	spans := recorder.Ended()
+	require.Len(t, spans, 2)
+	assert.Equal(t, "user-events publish", spans[0].Name())
+	assert.Equal(t, trace.SpanKindProducer, spans[0].SpanKind())
+	assert.Equal(t, spans[0].SpanContext().SpanID(), extracted.SpanID())
+}
+
+func TestPublishUserEventError(t *testing.T) {
+	p, err := newKafkaPublisher(&fakeWriter{err: errors.New("broker down")}, "user-events")
+	require.NoError(t, err)
+	err = p.PublishUserEvent(context.Background(), UserEvent{Type: UserDeleted, UserID: 1})
+	assert.ErrorContains(t, err, "broker down")
+}
+
+func TestHeaderCarrier(t *testing.T) {
+	var headers []kafka.Header
+	c := HeaderCarrier{Headers: &headers}
+	c.Set("a", "1")
+	c.Set("a", "2")
+	c.Set("b", "3")
+	assert.Equal(t, "2", c.Get("a"))
+	assert.Equal(t, "", c.Get("missing"))
+	assert.Equal(t, []string{"a", "b"}, c.Keys())
+}
+
+func TestPublishUserEventCountsMessages(t *testing.T) {
+	reader := sdkmetric.NewManualReader()
+	prevMP := otel.GetMeterProvider()
+	otel.SetMeterProvider(sdkmetric.NewMeterProvider(sdkmetric.WithReader(reader)))
+	defer otel.SetMeterProvider(prevMP)
+
+	writer := &fakeWriter{}
+	p, err := newKafkaPublisher(writer, "user-events")
+	require.NoError(t, err)
+	require.NoError(t, p.PublishUserEvent(context.Background(), UserEvent{Type: UserCreated, UserID: 1}))
+	require.NoError(t, p.PublishUserEvent(context.Background(), UserEvent{Type: UserUpdated, UserID: 1}))
+	writer.err = errors.New("broker down")
+	require.Error(t, p.PublishUserEvent(context.Background(), UserEvent{Type: UserDeleted, UserID: 1}))
+
+	var rm metricdata.ResourceMetrics
+	require.NoError(t, reader.Collect(context.Background(), &rm))
+	counts := map[string]int64{}
+	for _, m := range rm.ScopeMetrics[0].Metrics {
+		if sum, ok := m.Data.(metricdata.Sum[int64]); ok {
+			for _, dp := range sum.DataPoints {
+				counts[m.Name] += dp.Value
+			}
+		}
+	}
+	assert.Equal(t, int64(2), counts["messaging_published_messages_total"])
+	assert.Equal(t, int64(1), counts["messaging_publish_errors_total"])
+}
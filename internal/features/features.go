@@ -0,0 +1,76 @@
+// Package features evaluates feature flags through OpenFeature and records
+// each evaluation as a feature_flag.evaluation span event
+package features
+
+import (
+	"context"
+	"fmt"
+
+	"arquivolivre.com.br/otel/internal/auth"
+
+	"github.com/open-feature/go-sdk/openfeature"
+	"github.com/open-feature/go-sdk/openfeature/telemetry"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Flags used by the API
+const (
+	// MaskUserEmail masks email addresses in user responses for non-admins
+	MaskUserEmail = "mask-user-email"
+)
+
+const clientDomain = "otel-example-api"
+
+// Init registers provider and the tracing hook with OpenFeature
+func Init(provider openfeature.FeatureProvider) error {
+	if err := openfeature.SetProviderAndWait(provider); err != nil {
+		return fmt.Errorf("failed to set feature flag provider: %w", err)
+	}
+	openfeature.AddHooks(TracingHook{})
+	return nil
+}
+
+// Enabled evaluates a boolean flag for the principal in ctx. Evaluation
+// errors yield defaultValue.
+func Enabled(ctx context.Context, flag string, defaultValue bool) bool {
+	evalCtx := openfeature.NewEvaluationContext(auth.Actor(ctx), nil)
+	value, _ := openfeature.NewClient(clientDomain).BooleanValue(ctx, flag, defaultValue, evalCtx)
+	return value
+}
+
+// TracingHook adds a feature_flag.evaluation event to the active span
+type TracingHook struct {
+	openfeature.UnimplementedHook
+}
+
+func (TracingHook) Finally(ctx context.Context, hookContext openfeature.HookContext, details openfeature.InterfaceEvaluationDetails, _ openfeature.HookHints) {
+	span := trace.SpanFromContext(ctx)
+	if !span.IsRecording() {
+		return
+	}
+
+	event := telemetry.CreateEvaluationEvent(hookContext, details)
+	attrs := make([]attribute.KeyValue, 0, len(event.Attributes))
+	for key, value := range event.Attributes {
+		attrs = append(attrs, toAttribute(key, value))
+	}
+	span.AddEvent(event.Name, trace.WithAttributes(attrs...))
+}
+
+func toAttribute(key string, value any) attribute.KeyValue {
+	switch v := value.(type) {
+	case bool:
+		return attribute.Bool(key, v)
+	case string:
+		return attribute.String(key, v)
+	case int:
+		return attribute.Int(key, v)
+	case int64:
+		return attribute.Int64(key, v)
+	case float64:
+		return attribute.Float64(key, v)
+	default:
+		return attribute.String(key, fmt.Sprint(v))
+	}
+}
@@ -0,0 +1,57 @@
+package features
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/open-feature/go-sdk/openfeature"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/attribute"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+func TestProviderMergesFileAndEnv(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "flags.json")
+	require.NoError(t, os.WriteFile(file, []byte(`{"mask-user-email": false, "page-size": 20, "ratio": 0.5, "theme": "dark"}`), 0o600))
+
+	p, err := NewProvider(file, []string{"FEATURE_FLAG_MASK_USER_EMAIL=true", "PATH=/bin", "FEATURE_FLAG_NEW_THING=on"})
+	require.NoError(t, err)
+	assert.Equal(t, "env-file", p.Metadata().Name)
+
+	ctx := context.Background()
+	assert.True(t, p.BooleanEvaluation(ctx, "mask-user-email", false, nil).Value, "env overrides file")
+	assert.Equal(t, int64(20), p.IntEvaluation(ctx, "page-size", 0, nil).Value)
+	assert.Equal(t, 0.5, p.FloatEvaluation(ctx, "ratio", 0, nil).Value)
+	assert.Equal(t, "dark", p.StringEvaluation(ctx, "theme", "", nil).Value)
+	assert.Equal(t, "on", p.StringEvaluation(ctx, "new-thing", "", nil).Value)
+
+	_, err = NewProvider(filepath.Join(t.TempDir(), "missing.json"), nil)
+	assert.Error(t, err)
+}
+
+func TestEnabledAddsEvaluationSpanEvent(t *testing.T) {
+	p, err := NewProvider("", []string{"FEATURE_FLAG_MASK_USER_EMAIL=true"})
+	require.NoError(t, err)
+	require.NoError(t, Init(p))
+	defer openfeature.Shutdown()
+
+	recorder := tracetest.NewSpanRecorder()
+	tp := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+	ctx, span := tp.Tracer("test").Start(context.Background(), "request")
+
+	assert.True(t, Enabled(ctx, MaskUserEmail, false))
+	assert.True(t, Enabled(ctx, "unknown-flag", true), "missing flags fall back to the default")
+	span.End()
+
+	events := recorder.Ended()[0].Events()
+	require.Len(t, events, 2)
+	assert.Equal(t, "feature_flag.evaluation", events[0].Name)
+	assert.Contains(t, events[0].Attributes, attribute.String("feature_flag.key", MaskUserEmail))
+	assert.Contains(t, events[0].Attributes, attribute.String("feature_flag.provider.name", "env-file"))
+	assert.Contains(t, events[0].Attributes, attribute.String("feature_flag.context.id", "system"))
+	assert.Contains(t, events[1].Attributes, attribute.String("error.type", "flag_not_found"))
+}
@@ -0,0 +1,98 @@
+package features
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/open-feature/go-sdk/openfeature"
+	"github.com/open-feature/go-sdk/openfeature/memprovider"
+)
+
+// EnvPrefix marks environment variables that define flags, e.g.
+// FEATURE_FLAG_MASK_USER_EMAIL=true defines mask-user-email
+const EnvPrefix = "FEATURE_FLAG_"
+
+const defaultVariant = "configured"
+
+// Provider serves static flag values read from a JSON file and the
+// environment
+type Provider struct {
+	memprovider.InMemoryProvider
+}
+
+// NewProvider loads flags from file (a JSON object of flag key to value; an
+// empty path skips it) and then from environ, which overrides the file
+func NewProvider(file string, environ []string) (*Provider, error) {
+	values := map[string]any{}
+
+	if file != "" {
+		data, err := os.ReadFile(file)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read feature flags file: %w", err)
+		}
+		if values, err = decodeFlags(data); err != nil {
+			return nil, fmt.Errorf("failed to parse feature flags file: %w", err)
+		}
+	}
+
+	for _, kv := range environ {
+		name, raw, ok := strings.Cut(kv, "=")
+		if !ok || !strings.HasPrefix(name, EnvPrefix) {
+			continue
+		}
+		key := strings.ReplaceAll(strings.ToLower(strings.TrimPrefix(name, EnvPrefix)), "_", "-")
+		values[key] = parseValue(raw)
+	}
+
+	flags := make(map[string]memprovider.InMemoryFlag, len(values))
+	for key, value := range values {
+		flags[key] = memprovider.InMemoryFlag{
+			Key:            key,
+			State:          memprovider.Enabled,
+			DefaultVariant: defaultVariant,
+			Variants:       map[string]any{defaultVariant: value},
+		}
+	}
+	return &Provider{InMemoryProvider: memprovider.NewInMemoryProvider(flags)}, nil
+}
+
+func (p *Provider) Metadata() openfeature.Metadata {
+	return openfeature.Metadata{Name: "env-file"}
+}
+
+// decodeFlags keeps whole numbers as int64 so they resolve as integer flags
+func decodeFlags(data []byte) (map[string]any, error) {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	var raw map[string]any
+	if err := dec.Decode(&raw); err != nil {
+		return nil, err
+	}
+	for key, value := range raw {
+		if n, ok := value.(json.Number); ok {
+			if i, err := n.Int64(); err == nil {
+				raw[key] = i
+			} else if f, err := n.Float64(); err == nil {
+				raw[key] = f
+			}
+		}
+	}
+	return raw, nil
+}
+
+func parseValue(raw string) any {
+	if b, err := strconv.ParseBool(raw); err == nil {
+		return b
+	}
+	if i, err := strconv.ParseInt(raw, 10, 64); err == nil {
+		return i
+	}
+	if f, err := strconv.ParseFloat(raw, 64); err == nil {
+		return f
+	}
+	return raw
+}
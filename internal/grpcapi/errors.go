@@ -0,0 +1,101 @@
+package grpcapi
+
+import (
+	"context"
+	"strings"
+
+	"arquivolivre.com.br/otel/internal/auth"
+	"arquivolivre.com.br/otel/internal/logging"
+	"arquivolivre.com.br/otel/internal/middleware"
+	"arquivolivre.com.br/otel/internal/validation"
+	"arquivolivre.com.br/otel/pkg/apperrors"
+
+	"github.com/gin-gonic/gin/binding"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// statusError maps a repository error to a gRPC status with a client-safe
+// message. Internal errors are logged and reported with fallbackMessage
+func statusError(ctx context.Context, err error, fallbackMessage string) error {
+	code := apperrors.GRPCCode(err)
+	if code == codes.Internal {
+		logging.LogError(ctx, err, fallbackMessage, nil)
+		return status.Error(code, fallbackMessage)
+	}
+
+	message := apperrors.Message(err)
+	if message == "" {
+		message = fallbackMessage
+	}
+	return status.Error(code, message)
+}
+
+// validate checks req with the binding rules of the HTTP API. Field errors
+// are attached as a BadRequest detail
+func validate(req any) error {
+	err := binding.Validator.ValidateStruct(req)
+	if err == nil {
+		return nil
+	}
+
+	fields := validation.FieldErrors(err)
+	if fields == nil {
+		return status.Error(codes.InvalidArgument, "Invalid request data")
+	}
+	violations := make([]*errdetails.BadRequest_FieldViolation, len(fields))
+	for i, field := range fields {
+		violations[i] = &errdetails.BadRequest_FieldViolation{Field: field.Field, Description: field.Message}
+	}
+	st, detailErr := status.New(codes.InvalidArgument, "Validation failed").WithDetails(&errdetails.BadRequest{FieldViolations: violations})
+	if detailErr != nil {
+		return status.Error(codes.InvalidArgument, "Validation failed")
+	}
+	return st.Err()
+}
+
+// authInterceptor guards the methods in protected. With verifier it
+// requires a valid bearer token in the authorization metadata and
+// authenticates the call with it. With rbac the principal must also be
+// granted users:write, by its token or by the roles assigned to it in roles,
+// which may be nil; the decision is made as middleware.RequireRole makes it
+func authInterceptor(verifier *middleware.JWTVerifier, rbac bool, roles middleware.RoleSource, protected map[string]bool) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		if !protected[info.FullMethod] {
+			return handler(ctx, req)
+		}
+
+		if verifier != nil {
+			var raw string
+			if values := metadata.ValueFromIncomingContext(ctx, "authorization"); len(values) > 0 {
+				raw, _ = strings.CutPrefix(values[0], "Bearer ")
+			}
+			if raw == "" {
+				return nil, status.Error(codes.Unauthenticated, "Bearer token required")
+			}
+
+			claims, err := verifier.Verify(ctx, raw)
+			if err != nil {
+				return nil, status.Error(codes.Unauthenticated, "Invalid bearer token")
+			}
+			ctx = middleware.Authenticate(ctx, claims)
+		}
+
+		if rbac {
+			if roles != nil {
+				ctx = middleware.WithAssignedRoles(ctx, roles)
+			}
+			switch middleware.Authorize(ctx, auth.RoleUsersWrite, "gRPC", info.FullMethod) {
+			case "":
+			case middleware.ReasonUnauthenticated:
+				return nil, status.Error(codes.Unauthenticated, "Authentication required")
+			default:
+				return nil, status.Error(codes.PermissionDenied, "Role "+auth.RoleUsersWrite+" required")
+			}
+		}
+		return handler(ctx, req)
+	}
+}
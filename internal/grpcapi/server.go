@@ -0,0 +1,186 @@
+// Package grpcapi serves the user CRUD over gRPC. It shares the repository,
+// validation rules and error kinds with the HTTP handlers, and otelgrpc
+// continues the caller's trace like otelgin does for HTTP
+package grpcapi
+
+import (
+	"context"
+	"log"
+	"time"
+
+	usersv1 "arquivolivre.com.br/otel/api/users/v1"
+	"arquivolivre.com.br/otel/internal/auth"
+	"arquivolivre.com.br/otel/internal/events"
+	"arquivolivre.com.br/otel/internal/handlers"
+	"arquivolivre.com.br/otel/internal/logging"
+	"arquivolivre.com.br/otel/internal/middleware"
+	"arquivolivre.com.br/otel/internal/models"
+	"arquivolivre.com.br/otel/internal/repository"
+	"arquivolivre.com.br/otel/internal/validation"
+
+	"go.opentelemetry.io/contrib/instrumentation/google.golang.org/grpc/otelgrpc"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+	"google.golang.org/grpc"
+	"google.golang.org/protobuf/types/known/emptypb"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// UserService implements usersv1.UserServiceServer on a UserStore
+type UserService struct {
+	usersv1.UnimplementedUserServiceServer
+	users  repository.UserStore
+	events events.Publisher
+}
+
+// NewUserService creates the service; user change events go to publisher
+func NewUserService(users repository.UserStore, publisher events.Publisher) *UserService {
+	if err := validation.Register(); err != nil {
+		log.Printf("Warning: Failed to register custom validators: %v", err)
+	}
+	return &UserService{users: users, events: publisher}
+}
+
+// NewServer creates a gRPC server for svc traced and measured by otelgrpc.
+// As on the HTTP API, writes require a bearer token in the authorization
+// metadata when jwt is enabled, and with rbac the users:write role, granted
+// by the token or assigned in roles, which may be nil
+func NewServer(svc *UserService, jwt middleware.JWTConfig, rbac bool, roles middleware.RoleSource) *grpc.Server {
+	opts := []grpc.ServerOption{grpc.StatsHandler(otelgrpc.NewServerHandler())}
+	if jwt.Enabled() || rbac {
+		var verifier *middleware.JWTVerifier
+		if jwt.Enabled() {
+			verifier = middleware.NewJWTVerifier(jwt)
+		}
+		opts = append(opts, grpc.UnaryInterceptor(authInterceptor(verifier, rbac, roles, writeMethods)))
+	}
+	server := grpc.NewServer(opts...)
+	usersv1.RegisterUserServiceServer(server, svc)
+	return server
+}
+
+// writeMethods are the methods authInterceptor protects
+var writeMethods = map[string]bool{
+	usersv1.UserService_CreateUser_FullMethodName: true,
+	usersv1.UserService_UpdateUser_FullMethodName: true,
+	usersv1.UserService_DeleteUser_FullMethodName: true,
+}
+
+func (s *UserService) ListUsers(ctx context.Context, req *usersv1.ListUsersRequest) (*usersv1.ListUsersResponse, error) {
+	limits := handlers.CurrentPaginationLimits()
+	page, limit := int(req.GetPage()), int(req.GetLimit())
+	if page < 1 {
+		page = 1
+	}
+	if limit < 1 || limit > limits.Max {
+		limit = limits.Default
+	}
+	offset := (page - 1) * limit
+
+	trace.SpanFromContext(ctx).SetAttributes(
+		attribute.Int("pagination.page", page),
+		attribute.Int("pagination.limit", limit),
+		attribute.Int("pagination.offset", offset),
+	)
+
+	users, err := s.users.GetAll(ctx, models.UserFilter{}, limit, offset)
+	if err != nil {
+		return nil, statusError(ctx, err, "Failed to retrieve users")
+	}
+	total, err := s.users.Count(ctx, models.UserFilter{})
+	if err != nil {
+		return nil, statusError(ctx, err, "Failed to count users")
+	}
+
+	pagination := models.NewPagination(page, limit, total)
+	resp := &usersv1.ListUsersResponse{
+		Users:      make([]*usersv1.User, 0, len(users)),
+		Page:       int32(pagination.Page),
+		Limit:      int32(pagination.Limit),
+		Total:      int32(pagination.Total),
+		TotalPages: int32(pagination.TotalPages),
+	}
+	for i := range users {
+		resp.Users = append(resp.Users, toProto(&users[i]))
+	}
+	return resp, nil
+}
+
+func (s *UserService) GetUser(ctx context.Context, req *usersv1.GetUserRequest) (*usersv1.User, error) {
+	user, err := s.users.GetByID(ctx, int(req.GetId()))
+	if err != nil {
+		return nil, statusError(ctx, err, "Failed to retrieve user")
+	}
+	return toProto(user), nil
+}
+
+func (s *UserService) CreateUser(ctx context.Context, req *usersv1.CreateUserRequest) (*usersv1.User, error) {
+	create := models.CreateUserRequest{Name: req.GetName(), Email: req.GetEmail(), Bio: req.Bio}
+	create.Normalize()
+	if err := validate(&create); err != nil {
+		return nil, err
+	}
+
+	user, err := s.users.Create(ctx, create)
+	if err != nil {
+		return nil, statusError(ctx, err, "Failed to create user")
+	}
+	s.publish(ctx, events.UserCreated, user.ID)
+	return toProto(user), nil
+}
+
+func (s *UserService) UpdateUser(ctx context.Context, req *usersv1.UpdateUserRequest) (*usersv1.User, error) {
+	id := int(req.GetId())
+	update := models.UpdateUserRequest{Name: req.Name, Email: req.Email}
+	if req.Bio != nil {
+		update.Bio = models.NewNullable(req.GetBio())
+	}
+	update.Normalize()
+	if err := validate(&update); err != nil {
+		return nil, err
+	}
+
+	user, err := s.users.Update(ctx, id, update)
+	if err != nil {
+		return nil, statusError(ctx, err, "Failed to update user")
+	}
+	s.publish(ctx, events.UserUpdated, user.ID)
+	return toProto(user), nil
+}
+
+func (s *UserService) DeleteUser(ctx context.Context, req *usersv1.DeleteUserRequest) (*emptypb.Empty, error) {
+	id := int(req.GetId())
+	if err := s.users.Delete(ctx, id); err != nil {
+		return nil, statusError(ctx, err, "Failed to delete user")
+	}
+	s.publish(ctx, events.UserDeleted, id)
+	return &emptypb.Empty{}, nil
+}
+
+// publish emits a user change event; the change is already committed, so a
+// broker failure is logged rather than returned to the client
+func (s *UserService) publish(ctx context.Context, eventType events.EventType, userID int) {
+	err := s.events.PublishUserEvent(ctx, events.UserEvent{
+		Type:       eventType,
+		UserID:     userID,
+		Actor:      auth.Actor(ctx),
+		OccurredAt: time.Now().UTC(),
+	})
+	if err != nil {
+		logging.LogError(ctx, err, "Failed to publish user event", map[string]interface{}{
+			"event_type": string(eventType),
+			"user_id":    userID,
+		})
+	}
+}
+
+func toProto(user *models.User) *usersv1.User {
+	return &usersv1.User{
+		Id:        int64(user.ID),
+		Name:      user.Name,
+		Email:     user.Email,
+		Bio:       user.Bio,
+		CreatedAt: timestamppb.New(user.CreatedAt.Time),
+		UpdatedAt: timestamppb.New(user.UpdatedAt.Time),
+	}
+}
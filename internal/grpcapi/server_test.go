@@ -0,0 +1,322 @@
+package grpcapi
+
+import (
+	"context"
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	usersv1 "arquivolivre.com.br/otel/api/users/v1"
+	"arquivolivre.com.br/otel/internal/auth"
+	"arquivolivre.com.br/otel/internal/events"
+	"arquivolivre.com.br/otel/internal/middleware"
+	"arquivolivre.com.br/otel/internal/models"
+	"arquivolivre.com.br/otel/internal/repository"
+	"arquivolivre.com.br/otel/pkg/apperrors"
+	"arquivolivre.com.br/otel/pkg/oteltest"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/contrib/instrumentation/google.golang.org/grpc/otelgrpc"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+// memoryStore is a UserStore backed by a map
+type memoryStore struct {
+	users  map[int]models.User
+	nextID int
+}
+
+func newMemoryStore() *memoryStore {
+	return &memoryStore{users: map[int]models.User{}, nextID: 1}
+}
+
+func (m *memoryStore) GetAll(_ context.Context, _ models.UserFilter, limit, offset int) ([]models.User, error) {
+	users := []models.User{}
+	for id := 1; id < m.nextID && len(users) < limit; id++ {
+		if u, ok := m.users[id]; ok {
+			if offset > 0 {
+				offset--
+				continue
+			}
+			users = append(users, u)
+		}
+	}
+	return users, nil
+}
+
+func (m *memoryStore) Stream(ctx context.Context, filter models.UserFilter, fn func(*models.User) error) error {
+	users, _ := m.GetAll(ctx, filter, len(m.users), 0)
+	for i := range users {
+		if err := fn(&users[i]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (m *memoryStore) GetByID(_ context.Context, id int) (*models.User, error) {
+	u, ok := m.users[id]
+	if !ok {
+		return nil, apperrors.NotFound("User not found")
+	}
+	return &u, nil
+}
+
+func (m *memoryStore) GetByEmail(_ context.Context, email string) (*models.User, error) {
+	for _, u := range m.users {
+		if u.Email == email {
+			return &u, nil
+		}
+	}
+	return nil, apperrors.NotFound("User not found")
+}
+
+func (m *memoryStore) Count(context.Context, models.UserFilter) (int, error) {
+	return len(m.users), nil
+}
+
+// Create rejects a taken email like the unique index on users.email
+func (m *memoryStore) Create(ctx context.Context, req models.CreateUserRequest) (*models.User, error) {
+	if _, err := m.GetByEmail(ctx, req.Email); err == nil {
+		return nil, apperrors.Conflict("email already exists")
+	}
+	u := models.User{ID: m.nextID, Name: req.Name, Email: req.Email, Bio: req.Bio, CreatedAt: models.Now(), UpdatedAt: models.Now()}
+	m.users[u.ID] = u
+	m.nextID++
+	return &u, nil
+}
+
+func (m *memoryStore) CreateBatch(ctx context.Context, reqs []models.CreateUserRequest) ([]repository.BatchResult, error) {
+	results := make([]repository.BatchResult, len(reqs))
+	for i, req := range reqs {
+		results[i].User, results[i].Err = m.Create(ctx, req)
+	}
+	return results, nil
+}
+
+func (m *memoryStore) Update(ctx context.Context, id int, req models.UpdateUserRequest) (*models.User, error) {
+	u, ok := m.users[id]
+	if !ok {
+		return nil, apperrors.NotFound("User not found")
+	}
+	if req.Email != nil {
+		if existing, err := m.GetByEmail(ctx, *req.Email); err == nil && existing.ID != id {
+			return nil, apperrors.Conflict("email already exists")
+		}
+	}
+	if req.Name != nil {
+		u.Name = *req.Name
+	}
+	if req.Email != nil {
+		u.Email = *req.Email
+	}
+	if req.Bio.Set {
+		u.Bio = req.Bio.Ptr()
+	}
+	m.users[id] = u
+	return &u, nil
+}
+
+func (m *memoryStore) Delete(_ context.Context, id int) error {
+	if _, ok := m.users[id]; !ok {
+		return apperrors.NotFound("User not found")
+	}
+	delete(m.users, id)
+	return nil
+}
+
+// dial serves server on an in-memory listener and returns a client for it
+func dial(t *testing.T, server *grpc.Server, opts ...grpc.DialOption) usersv1.UserServiceClient {
+	t.Helper()
+	ln := bufconn.Listen(1 << 20)
+	go func() { _ = server.Serve(ln) }()
+	t.Cleanup(server.Stop)
+
+	opts = append(opts,
+		grpc.WithContextDialer(func(context.Context, string) (net.Conn, error) { return ln.Dial() }),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	)
+	conn, err := grpc.NewClient("passthrough:///bufnet", opts...)
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = conn.Close() })
+	return usersv1.NewUserServiceClient(conn)
+}
+
+func TestUserServiceCRUD(t *testing.T) {
+	ctx := context.Background()
+	client := dial(t, NewServer(NewUserService(newMemoryStore(), events.NoopPublisher{}), middleware.JWTConfig{}, false, nil))
+
+	created, err := client.CreateUser(ctx, &usersv1.CreateUserRequest{Name: " Alice ", Email: "Alice@Example.com"})
+	require.NoError(t, err)
+	assert.Equal(t, "Alice", created.GetName())
+	assert.Equal(t, "alice@example.com", created.GetEmail())
+	assert.NotNil(t, created.GetCreatedAt())
+
+	_, err = client.CreateUser(ctx, &usersv1.CreateUserRequest{Name: "Other", Email: "alice@example.com"})
+	assert.Equal(t, codes.AlreadyExists, status.Code(err))
+
+	bio := "Hello"
+	updated, err := client.UpdateUser(ctx, &usersv1.UpdateUserRequest{Id: created.GetId(), Bio: &bio})
+	require.NoError(t, err)
+	assert.Equal(t, "Alice", updated.GetName())
+	assert.Equal(t, "Hello", updated.GetBio())
+
+	empty := ""
+	updated, err = client.UpdateUser(ctx, &usersv1.UpdateUserRequest{Id: created.GetId(), Bio: &empty})
+	require.NoError(t, err)
+	assert.Nil(t, updated.Bio, "an empty bio clears it")
+
+	list, err := client.ListUsers(ctx, &usersv1.ListUsersRequest{Page: 1, Limit: 1000})
+	require.NoError(t, err)
+	assert.Len(t, list.GetUsers(), 1)
+	assert.Equal(t, int32(1), list.GetTotal())
+	assert.Equal(t, int32(10), list.GetLimit(), "out of range limits fall back to the default")
+
+	_, err = client.DeleteUser(ctx, &usersv1.DeleteUserRequest{Id: created.GetId()})
+	require.NoError(t, err)
+
+	_, err = client.GetUser(ctx, &usersv1.GetUserRequest{Id: created.GetId()})
+	st := status.Convert(err)
+	assert.Equal(t, codes.NotFound, st.Code())
+	assert.Equal(t, "User not found", st.Message())
+}
+
+func TestUserServiceReportsFieldViolations(t *testing.T) {
+	client := dial(t, NewServer(NewUserService(newMemoryStore(), events.NoopPublisher{}), middleware.JWTConfig{}, false, nil))
+
+	_, err := client.CreateUser(context.Background(), &usersv1.CreateUserRequest{Name: "Bob", Email: "not-an-email"})
+	st := status.Convert(err)
+	require.Equal(t, codes.InvalidArgument, st.Code())
+
+	var fields []string
+	for _, detail := range st.Details() {
+		if badRequest, ok := detail.(*errdetails.BadRequest); ok {
+			for _, violation := range badRequest.GetFieldViolations() {
+				fields = append(fields, violation.GetField())
+			}
+		}
+	}
+	assert.Equal(t, []string{"email"}, fields)
+
+	// Fields are validated once trimmed
+	_, err = client.CreateUser(context.Background(), &usersv1.CreateUserRequest{Name: "   ", Email: "bob@example.com"})
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+}
+
+func TestUserServiceRequiresTokenForWrites(t *testing.T) {
+	cfg := middleware.JWTConfig{Secret: "secret"}
+	client := dial(t, NewServer(NewUserService(newMemoryStore(), events.NoopPublisher{}), cfg, false, nil))
+	ctx := context.Background()
+
+	_, err := client.ListUsers(ctx, &usersv1.ListUsersRequest{})
+	require.NoError(t, err, "reads are not protected")
+
+	req := &usersv1.CreateUserRequest{Name: "Carol", Email: "carol@example.com"}
+	_, err = client.CreateUser(ctx, req)
+	assert.Equal(t, codes.Unauthenticated, status.Code(err))
+
+	bad := metadata.AppendToOutgoingContext(ctx, "authorization", "Bearer abc")
+	_, err = client.CreateUser(bad, req)
+	assert.Equal(t, codes.Unauthenticated, status.Code(err))
+
+	token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, middleware.Claims{RegisteredClaims: jwt.RegisteredClaims{
+		Subject:   "carol",
+		ExpiresAt: jwt.NewNumericDate(time.Now().Add(time.Hour)),
+	}}).SignedString([]byte("secret"))
+	require.NoError(t, err)
+	authed := metadata.AppendToOutgoingContext(ctx, "authorization", "Bearer "+token)
+	_, err = client.CreateUser(authed, req)
+	assert.NoError(t, err)
+}
+
+// roleSource assigns the same roles to every subject
+type roleSource []string
+
+func (r roleSource) RolesOf(context.Context, string) ([]string, error) { return r, nil }
+
+func TestUserServiceRequiresRoleForWrites(t *testing.T) {
+	rec := oteltest.Install(t)
+	cfg := middleware.JWTConfig{Secret: "secret"}
+	sign := func(roles ...string) context.Context {
+		token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, middleware.Claims{
+			RegisteredClaims: jwt.RegisteredClaims{
+				Subject:   "carol",
+				ExpiresAt: jwt.NewNumericDate(time.Now().Add(time.Hour)),
+			},
+			Roles: roles,
+		}).SignedString([]byte("secret"))
+		require.NoError(t, err)
+		return metadata.AppendToOutgoingContext(context.Background(), "authorization", "Bearer "+token)
+	}
+	req := &usersv1.CreateUserRequest{Name: "Carol", Email: "carol@example.com"}
+
+	client := dial(t, NewServer(NewUserService(newMemoryStore(), events.NoopPublisher{}), cfg, true, nil))
+	_, err := client.ListUsers(sign(), &usersv1.ListUsersRequest{})
+	require.NoError(t, err, "reads are not protected")
+
+	_, err = client.CreateUser(sign("posts:write"), req)
+	assert.Equal(t, codes.PermissionDenied, status.Code(err))
+	// The server span ends once the response is sent
+	name := strings.TrimPrefix(usersv1.UserService_CreateUser_FullMethodName, "/")
+	require.Eventually(t, func() bool {
+		_, ok := rec.Span(name)
+		return ok
+	}, time.Second, 5*time.Millisecond)
+	span := rec.AssertSpan(t, name)
+	require.NotEmpty(t, span.Events())
+	assert.Equal(t, "authorization.decision", span.Events()[0].Name)
+	assert.Contains(t, span.Events()[0].Attributes, attribute.String("authz.decision", "deny"))
+
+	_, err = client.CreateUser(sign(auth.RoleUsersWrite), req)
+	assert.NoError(t, err)
+
+	// Roles assigned to the subject are granted too
+	client = dial(t, NewServer(NewUserService(newMemoryStore(), events.NoopPublisher{}), cfg, true, roleSource{auth.RoleUsersWrite}))
+	_, err = client.CreateUser(sign(), req)
+	assert.NoError(t, err)
+}
+
+func TestUserServiceContinuesClientTrace(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	tp := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+	prevTP, prevProp := otel.GetTracerProvider(), otel.GetTextMapPropagator()
+	otel.SetTracerProvider(tp)
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+	t.Cleanup(func() {
+		otel.SetTracerProvider(prevTP)
+		otel.SetTextMapPropagator(prevProp)
+	})
+
+	client := dial(t, NewServer(NewUserService(newMemoryStore(), events.NoopPublisher{}), middleware.JWTConfig{}, false, nil),
+		grpc.WithStatsHandler(otelgrpc.NewClientHandler()))
+
+	ctx, parent := tp.Tracer("test").Start(context.Background(), "caller")
+	_, err := client.GetUser(ctx, &usersv1.GetUserRequest{Id: 42})
+	parent.End()
+	require.Equal(t, codes.NotFound, status.Code(err))
+
+	var server sdktrace.ReadOnlySpan
+	for _, span := range recorder.Ended() {
+		if span.SpanKind() == trace.SpanKindServer {
+			server = span
+		}
+	}
+	require.NotNil(t, server, "expected a server span")
+	assert.True(t, strings.HasSuffix(server.Name(), "UserService/GetUser"))
+	assert.Equal(t, parent.SpanContext().TraceID(), server.SpanContext().TraceID())
+}
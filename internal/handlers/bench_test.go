@@ -0,0 +1,67 @@
+package handlers
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+
+	"arquivolivre.com.br/otel/internal/logging"
+	"arquivolivre.com.br/otel/internal/models"
+)
+
+// benchmarkRouter serves a store holding n users
+func benchmarkRouter(n int) http.Handler {
+	store := newMockUserStore()
+	for i := 0; i < n; i++ {
+		store.users = append(store.users, models.User{
+			ID:      store.nextID,
+			Name:    fmt.Sprintf("User %d", i),
+			Email:   fmt.Sprintf("user%d@example.com", i),
+			Version: 1,
+		})
+		store.nextID++
+	}
+	return setupRouter(NewUserHandler(store))
+}
+
+// benchmarkRequest measures one request per iteration. Logs are still
+// formatted but discarded
+func benchmarkRequest(b *testing.B, handler http.Handler, method, target, body string) {
+	b.Helper()
+	logger := logging.GetLogger()
+	logger.SetOutput(io.Discard)
+	b.Cleanup(func() { logger.SetOutput(os.Stdout) })
+
+	b.ReportAllocs()
+	for b.Loop() {
+		req := httptest.NewRequest(method, target, strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, req)
+		if w.Code >= http.StatusBadRequest {
+			b.Fatalf("%s %s returned %d: %s", method, target, w.Code, w.Body.String())
+		}
+	}
+}
+
+func BenchmarkGetUsers(b *testing.B) {
+	benchmarkRequest(b, benchmarkRouter(100), http.MethodGet, "/api/users?page=1&limit=100", "")
+}
+
+func BenchmarkGetUser(b *testing.B) {
+	benchmarkRequest(b, benchmarkRouter(1), http.MethodGet, "/api/users/1", "")
+}
+
+func BenchmarkUpdateUser(b *testing.B) {
+	// If-Match: * applies every update whatever the current version
+	router := benchmarkRouter(1)
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		r.Header.Set("If-Match", "*")
+		router.ServeHTTP(w, r)
+	})
+	benchmarkRequest(b, handler, http.MethodPut, "/api/users/1", `{"name":"Updated User","bio":"Benchmarking the request decoder"}`)
+}
@@ -0,0 +1,85 @@
+package handlers
+
+import (
+	"fmt"
+	"hash/fnv"
+	"strings"
+
+	"arquivolivre.com.br/otel/internal/auth"
+	"arquivolivre.com.br/otel/internal/features"
+	"arquivolivre.com.br/otel/internal/models"
+	"arquivolivre.com.br/otel/pkg/utils"
+
+	"github.com/gin-gonic/gin"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// userETag returns a weak entity tag of users as the handler renders them
+// for c, plus any extra values the response depends on, such as the total of
+// a page. It changes whenever one of the users does, since every update
+// moves updated_at, and whenever the representation does: the API version,
+// the audit fields of admins and email masking. It is weak because equal
+// tags promise equivalent responses, not identical bytes; If-Match takes the
+// user version instead
+func (h *UserHandler) userETag(c *gin.Context, users []models.User, extra ...int) string {
+	ctx := c.Request.Context()
+	admin := auth.IsAdmin(ctx)
+	masked := !admin && features.Enabled(ctx, features.MaskUserEmail, false)
+
+	hash := fnv.New64a()
+	_, _ = fmt.Fprintf(hash, "%s|%t|%t", responseMapper(c, h.mapper).Version(), admin, masked)
+	for _, user := range users {
+		_, _ = fmt.Fprintf(hash, "|%d@%d", user.ID, user.UpdatedAt.UnixMilli())
+	}
+	for _, value := range extra {
+		_, _ = fmt.Fprintf(hash, "|%d", value)
+	}
+	return fmt.Sprintf(`W/"%016x"`, hash.Sum64())
+}
+
+// notModified sets etag and the caching headers on the response and answers
+// 304 Not Modified when If-None-Match holds etag, returning true. Outcomes
+// are counted by endpoint: hit (304), miss (the client's copy is stale) or
+// none (no If-None-Match)
+func (h *UserHandler) notModified(c *gin.Context, endpoint, etag string) bool {
+	c.Header("ETag", etag)
+	// Responses depend on the caller, so only private caches may keep them,
+	// and only after revalidating
+	c.Header("Cache-Control", "private, no-cache")
+	c.Writer.Header().Add("Vary", "Authorization, "+APIVersionHeader)
+
+	result := "none"
+	if ifNoneMatch := c.GetHeader("If-None-Match"); ifNoneMatch != "" {
+		result = "miss"
+		if etagMatches(ifNoneMatch, etag) {
+			result = "hit"
+		}
+	}
+	h.conditionals.Add(c.Request.Context(), 1, metric.WithAttributes(
+		attribute.String("endpoint", endpoint),
+		attribute.String("result", result),
+	))
+	trace.SpanFromContext(c.Request.Context()).SetAttributes(attribute.String("http.conditional", result))
+
+	if result != "hit" {
+		return false
+	}
+	utils.SendNotModified(c)
+	return true
+}
+
+// etagMatches reports whether the If-None-Match list header holds etag or
+// is *. Tags are compared weakly, ignoring the W/ prefix, as RFC 9110
+// requires for If-None-Match
+func etagMatches(header, etag string) bool {
+	etag = strings.TrimPrefix(etag, "W/")
+	for _, candidate := range strings.Split(header, ",") {
+		candidate = strings.TrimSpace(candidate)
+		if candidate == "*" || strings.TrimPrefix(candidate, "W/") == etag {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,85 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"arquivolivre.com.br/otel/internal/middleware"
+	"arquivolivre.com.br/otel/internal/models"
+	"arquivolivre.com.br/otel/pkg/utils"
+
+	"github.com/gin-gonic/gin"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// maxExternalBody limits how much of the downstream response is relayed
+const maxExternalBody = 1 << 20
+
+// ExternalHandler calls a downstream service to demonstrate traces that
+// span several services
+type ExternalHandler struct {
+	url        string
+	httpClient *http.Client
+}
+
+// NewExternalHandler creates a handler that calls url with httpClient, which
+// should propagate the trace context, e.g. one from internal/client
+func NewExternalHandler(url string, httpClient *http.Client) *ExternalHandler {
+	return &ExternalHandler{url: url, httpClient: httpClient}
+}
+
+// CallExternal handles GET /api/external by calling the downstream service
+// and relaying its status, latency and body
+func (h *ExternalHandler) CallExternal(c *gin.Context) {
+	if h == nil || h.url == "" {
+		_ = c.Error(middleware.NewAPIError(http.StatusServiceUnavailable, models.ErrCodeServiceUnavailable, "External service is not configured"))
+		return
+	}
+
+	ctx := c.Request.Context()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, h.url, nil)
+	if err != nil {
+		_ = c.Error(middleware.InternalError("Failed to build external request", err))
+		return
+	}
+	req.Header.Set("Accept", "application/json")
+
+	start := time.Now()
+	resp, err := h.httpClient.Do(req)
+	if err != nil {
+		_ = c.Error(middleware.NewAPIError(http.StatusServiceUnavailable, models.ErrCodeServiceUnavailable, "External service is unavailable").WithCause(err))
+		return
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxExternalBody))
+	duration := time.Since(start)
+	if err != nil {
+		_ = c.Error(middleware.NewAPIError(http.StatusServiceUnavailable, models.ErrCodeServiceUnavailable, "Failed to read external response").WithCause(err))
+		return
+	}
+	trace.SpanFromContext(ctx).SetAttributes(
+		attribute.Int("external.status_code", resp.StatusCode),
+		attribute.Int64("external.duration_ms", duration.Milliseconds()),
+	)
+	if resp.StatusCode >= http.StatusInternalServerError {
+		_ = c.Error(middleware.NewAPIError(http.StatusServiceUnavailable, models.ErrCodeServiceUnavailable,
+			fmt.Sprintf("External service returned %d", resp.StatusCode)))
+		return
+	}
+
+	var payload any = string(body)
+	if json.Valid(body) {
+		payload = json.RawMessage(body)
+	}
+	utils.SendSuccess(c, gin.H{
+		"url":         h.url,
+		"status":      resp.StatusCode,
+		"duration_ms": duration.Milliseconds(),
+		"body":        payload,
+	})
+}
@@ -0,0 +1,57 @@
+package handlers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"arquivolivre.com.br/otel/internal/middleware"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func callExternal(handler *ExternalHandler) *httptest.ResponseRecorder {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.Use(middleware.ErrorHandler())
+	r.GET("/api/external", handler.CallExternal)
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/external", nil))
+	return w
+}
+
+func TestCallExternal(t *testing.T) {
+	downstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/json":
+			w.Header().Set("Content-Type", "application/json")
+			_, _ = w.Write([]byte(`{"domain":"example.com"}`))
+		case "/text":
+			_, _ = w.Write([]byte("pong"))
+		default:
+			w.WriteHeader(http.StatusInternalServerError)
+		}
+	}))
+	defer downstream.Close()
+
+	w := callExternal(nil)
+	assert.Equal(t, http.StatusServiceUnavailable, w.Code, "no URL configured")
+
+	w = callExternal(NewExternalHandler(downstream.URL+"/json", downstream.Client()))
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), `"body":{"domain":"example.com"}`)
+	assert.Contains(t, w.Body.String(), `"status":200`)
+
+	w = callExternal(NewExternalHandler(downstream.URL+"/text", downstream.Client()))
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), `"body":"pong"`)
+
+	w = callExternal(NewExternalHandler(downstream.URL+"/fail", downstream.Client()))
+	assert.Equal(t, http.StatusServiceUnavailable, w.Code)
+	assert.Contains(t, w.Body.String(), "External service returned 500")
+
+	w = callExternal(NewExternalHandler("http://127.0.0.1:1", downstream.Client()))
+	assert.Equal(t, http.StatusServiceUnavailable, w.Code)
+	assert.Contains(t, w.Body.String(), "SERVICE_UNAVAILABLE")
+}
@@ -1,63 +1,63 @@
 package handlers
 
 import (
+	"context"
 	"net/http"
 
-	"arquivolivre.com.br/otel/internal/models"
+	"arquivolivre.com.br/otel/internal/health"
+	"arquivolivre.com.br/otel/pkg/utils"
 
 	"github.com/gin-gonic/gin"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
 )
 
-// DBHealth defines the minimal contract used by the health handler
+// DBHealth defines the minimal contract used by the database health check
 type DBHealth interface {
 	Health() error
 }
 
+// DatabaseCheck adapts db to a health.Checker
+func DatabaseCheck(db DBHealth) health.Checker {
+	return health.CheckerFunc(func(context.Context) error {
+		return db.Health()
+	})
+}
+
 // HealthHandler handles health check requests
 type HealthHandler struct {
-	db DBHealth
+	checks *health.Registry
 }
 
-// NewHealthHandler creates a new health handler
-func NewHealthHandler(db DBHealth) *HealthHandler {
-	return &HealthHandler{db: db}
+// NewHealthHandler creates a health handler reporting the checks of checks
+func NewHealthHandler(checks *health.Registry) *HealthHandler {
+	return &HealthHandler{checks: checks}
 }
 
 // HealthCheck handles GET /health
 func (h *HealthHandler) HealthCheck(c *gin.Context) {
-	// Check database health
-	if err := h.db.Health(); err != nil {
-		c.JSON(http.StatusServiceUnavailable, models.ErrorResponse{
-			Success: false,
-			Error:   "Database connection failed",
-		})
-		return
-	}
-
-	c.JSON(http.StatusOK, models.SuccessResponse{
-		Success: true,
-		Message: "Service is healthy",
-		Data: map[string]string{
-			"status":   "healthy",
-			"database": "connected",
-		},
-	})
+	h.report(c)
 }
 
 // ReadinessCheck handles GET /ready
 func (h *HealthHandler) ReadinessCheck(c *gin.Context) {
-	// Perform more comprehensive checks here
-	// For now, just check database
-	if err := h.db.Health(); err != nil {
-		c.JSON(http.StatusServiceUnavailable, models.ErrorResponse{
-			Success: false,
-			Error:   "Service not ready",
-		})
-		return
+	h.report(c)
+}
+
+// report runs the checks and responds with their results: 200 while the
+// service is up or degraded, 503 once a critical check fails
+func (h *HealthHandler) report(c *gin.Context) {
+	report := h.checks.Run(c.Request.Context())
+
+	span := trace.SpanFromContext(c.Request.Context())
+	span.SetAttributes(attribute.String("health.status", string(report.Status)))
+	for name, result := range report.Checks {
+		span.SetAttributes(attribute.String("health.check."+name, string(result.Status)))
 	}
 
-	c.JSON(http.StatusOK, models.SuccessResponse{
-		Success: true,
-		Message: "Service is ready",
-	})
+	status := http.StatusOK
+	if report.Status == health.StatusDown {
+		status = http.StatusServiceUnavailable
+	}
+	utils.SendJSON(c, status, report)
 }
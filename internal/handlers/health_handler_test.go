@@ -1,13 +1,19 @@
 package handlers
 
 import (
+	"context"
+	"encoding/json"
 	"errors"
 	"net/http"
 	"net/http/httptest"
 	"testing"
 
+	"arquivolivre.com.br/otel/internal/health"
+	"arquivolivre.com.br/otel/internal/middleware"
+
 	"github.com/gin-gonic/gin"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 type mockHealthDB struct{ healthy bool }
@@ -19,66 +25,64 @@ func (m *mockHealthDB) Health() error {
 	return errors.New("db down")
 }
 
-func TestHealthCheck(t *testing.T) {
+func newHealthRouter(t *testing.T, dbHealthy, cacheHealthy bool) *gin.Engine {
+	t.Helper()
 	gin.SetMode(gin.TestMode)
-	h := &HealthHandler{db: &mockDBWrapper{&mockHealthDB{healthy: true}}}
-	r := gin.New()
-	r.GET("/health", h.HealthCheck)
-
-	w := httptest.NewRecorder()
-	req := httptest.NewRequest(http.MethodGet, "/health", nil)
-	r.ServeHTTP(w, req)
-	assert.Equal(t, http.StatusOK, w.Code)
-}
+	checks := health.NewRegistry()
+	require.NoError(t, checks.Register("database", DatabaseCheck(&mockHealthDB{healthy: dbHealthy}), health.Critical()))
+	require.NoError(t, checks.Register("cache", health.CheckerFunc(func(context.Context) error {
+		if cacheHealthy {
+			return nil
+		}
+		return errors.New("connection refused")
+	})))
 
-func TestHealthCheck_Unhealthy(t *testing.T) {
-	gin.SetMode(gin.TestMode)
-	h := &HealthHandler{db: &mockDBWrapper{&mockHealthDB{healthy: false}}}
+	h := NewHealthHandler(checks)
 	r := gin.New()
+	r.Use(middleware.ErrorHandler())
 	r.GET("/health", h.HealthCheck)
+	r.GET("/ready", h.ReadinessCheck)
+	return r
+}
 
+func getHealthReport(t *testing.T, r *gin.Engine, path string) (int, health.Report) {
+	t.Helper()
 	w := httptest.NewRecorder()
-	req := httptest.NewRequest(http.MethodGet, "/health", nil)
-	r.ServeHTTP(w, req)
-	assert.Equal(t, http.StatusServiceUnavailable, w.Code)
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, path, nil))
+	var report health.Report
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &report))
+	return w.Code, report
 }
 
-// mockDBWrapper adapts mockHealthDB to the subset of methods used by handler
-type mockDBWrapper struct{ m *mockHealthDB }
+func TestHealthCheck(t *testing.T) {
+	code, report := getHealthReport(t, newHealthRouter(t, true, true), "/health")
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, health.StatusUp, report.Status)
+	assert.Equal(t, health.StatusUp, report.Checks["database"].Status)
+	assert.True(t, report.Checks["database"].Critical)
+}
 
-func (w *mockDBWrapper) Health() error { return w.m.Health() }
+func TestHealthCheck_Unhealthy(t *testing.T) {
+	code, report := getHealthReport(t, newHealthRouter(t, false, true), "/health")
+	assert.Equal(t, http.StatusServiceUnavailable, code)
+	assert.Equal(t, health.StatusDown, report.Status)
+	assert.Equal(t, "db down", report.Checks["database"].Error)
+}
 
-func TestNewHealthHandler(t *testing.T) {
-	db := &mockDBWrapper{&mockHealthDB{healthy: true}}
-	handler := NewHealthHandler(db)
-	if handler == nil {
-		t.Fatal("expected non-nil health handler")
-	}
-	if handler.db != db {
-		t.Error("expected handler to store provided db")
-	}
+func TestHealthCheck_Degraded(t *testing.T) {
+	code, report := getHealthReport(t, newHealthRouter(t, true, false), "/health")
+	assert.Equal(t, http.StatusOK, code, "non-critical failures keep the service up")
+	assert.Equal(t, health.StatusDegraded, report.Status)
+	assert.Equal(t, health.StatusDown, report.Checks["cache"].Status)
 }
 
 func TestReadinessCheck_Ready(t *testing.T) {
-	gin.SetMode(gin.TestMode)
-	h := &HealthHandler{db: &mockDBWrapper{&mockHealthDB{healthy: true}}}
-	r := gin.New()
-	r.GET("/ready", h.ReadinessCheck)
-
-	w := httptest.NewRecorder()
-	req := httptest.NewRequest(http.MethodGet, "/ready", nil)
-	r.ServeHTTP(w, req)
-	assert.Equal(t, http.StatusOK, w.Code)
+	code, report := getHealthReport(t, newHealthRouter(t, true, true), "/ready")
+	assert.Equal(t, http.StatusOK, code)
+	assert.Len(t, report.Checks, 2)
 }
 
 func TestReadinessCheck_NotReady(t *testing.T) {
-	gin.SetMode(gin.TestMode)
-	h := &HealthHandler{db: &mockDBWrapper{&mockHealthDB{healthy: false}}}
-	r := gin.New()
-	r.GET("/ready", h.ReadinessCheck)
-
-	w := httptest.NewRecorder()
-	req := httptest.NewRequest(http.MethodGet, "/ready", nil)
-	r.ServeHTTP(w, req)
-	assert.Equal(t, http.StatusServiceUnavailable, w.Code)
+	code, _ := getHealthReport(t, newHealthRouter(t, false, false), "/ready")
+	assert.Equal(t, http.StatusServiceUnavailable, code)
 }
@@ -0,0 +1,107 @@
+package handlers
+
+import (
+	"log/slog"
+
+	"arquivolivre.com.br/otel/internal/logging"
+	"arquivolivre.com.br/otel/internal/middleware"
+	"arquivolivre.com.br/otel/pkg/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+// LogLevels are the levels of a logger. Modules maps the modules whose
+// level is overridden to their level; in an update an empty module level
+// makes the module follow Level again
+type LogLevels struct {
+	Level   string            `json:"level,omitempty"`
+	Modules map[string]string `json:"modules,omitempty"`
+}
+
+// LogLevelHandler changes the levels of a logger at runtime
+type LogLevelHandler struct {
+	logger *logging.Logger
+}
+
+// NewLogLevelHandler creates a handler for logger
+func NewLogLevelHandler(logger *logging.Logger) *LogLevelHandler {
+	return &LogLevelHandler{logger: logger}
+}
+
+// Register mounts the log level endpoints on group
+func (h *LogLevelHandler) Register(group *gin.RouterGroup) {
+	group.GET("", h.GetLevels)
+	group.PUT("", h.SetLevels)
+}
+
+// GetLevels returns the logger level and the module overrides
+func (h *LogLevelHandler) GetLevels(c *gin.Context) {
+	utils.SendSuccess(c, h.levels())
+}
+
+// SetLevels changes the logger level and the module levels given. The
+// levels are validated before any is applied
+func (h *LogLevelHandler) SetLevels(c *gin.Context) {
+	var req LogLevels
+	if err := c.ShouldBindJSON(&req); err != nil {
+		_ = c.Error(middleware.BadRequestError("Invalid log levels: " + err.Error()))
+		return
+	}
+	if req.Level == "" && len(req.Modules) == 0 {
+		_ = c.Error(middleware.BadRequestError("Invalid log levels: level or modules is required"))
+		return
+	}
+
+	var level slog.Level
+	if req.Level != "" {
+		parsed, err := logging.ParseLevel(req.Level)
+		if err != nil {
+			_ = c.Error(middleware.BadRequestError("Invalid log levels: " + err.Error()))
+			return
+		}
+		level = parsed
+	}
+	modules := make(map[string]*slog.Level, len(req.Modules))
+	for module, name := range req.Modules {
+		if module == "" {
+			_ = c.Error(middleware.BadRequestError("Invalid log levels: module name is required"))
+			return
+		}
+		if name == "" {
+			modules[module] = nil
+			continue
+		}
+		parsed, err := logging.ParseLevel(name)
+		if err != nil {
+			_ = c.Error(middleware.BadRequestError("Invalid log levels of module " + module + ": " + err.Error()))
+			return
+		}
+		modules[module] = &parsed
+	}
+
+	if req.Level != "" {
+		h.logger.SetLevel(level)
+	}
+	for module, level := range modules {
+		if level == nil {
+			h.logger.ResetModuleLevel(module)
+		} else {
+			h.logger.SetModuleLevel(module, *level)
+		}
+	}
+
+	levels := h.levels()
+	h.logger.WithTraceContext(c.Request.Context()).WithFields(map[string]interface{}{
+		"level":   levels.Level,
+		"modules": levels.Modules,
+	}).Warn("Log levels changed")
+	utils.SendSuccess(c, levels)
+}
+
+func (h *LogLevelHandler) levels() LogLevels {
+	levels := LogLevels{Level: logging.LevelName(h.logger.Level()), Modules: map[string]string{}}
+	for module, level := range h.logger.ModuleLevels() {
+		levels.Modules[module] = logging.LevelName(level)
+	}
+	return levels
+}
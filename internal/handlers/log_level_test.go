@@ -0,0 +1,66 @@
+package handlers
+
+import (
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"arquivolivre.com.br/otel/internal/logging"
+	"arquivolivre.com.br/otel/internal/middleware"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLogLevelHandler(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	logger := logging.NewLogger()
+	logger.SetOutput(io.Discard)
+
+	r := gin.New()
+	r.Use(middleware.ErrorHandler())
+	NewLogLevelHandler(logger).Register(r.Group("/admin/loglevel"))
+
+	do := func(method, body string) (*httptest.ResponseRecorder, LogLevels) {
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest(method, "/admin/loglevel", strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		r.ServeHTTP(w, req)
+		var resp struct {
+			Data LogLevels `json:"data"`
+		}
+		_ = json.Unmarshal(w.Body.Bytes(), &resp)
+		return w, resp.Data
+	}
+
+	w, levels := do(http.MethodGet, "")
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "info", levels.Level)
+	assert.Empty(t, levels.Modules)
+
+	w, levels = do(http.MethodPut, `{"level":"warn","modules":{"http":"error","jobs":"debug"}}`)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	assert.Equal(t, LogLevels{Level: "warn", Modules: map[string]string{"http": "error", "jobs": "debug"}}, levels)
+	assert.Equal(t, slog.LevelWarn, logger.Level())
+	assert.True(t, logger.Module("jobs").Enabled(t.Context(), slog.LevelDebug))
+	assert.False(t, logger.Module("http").Enabled(t.Context(), slog.LevelWarn))
+	assert.False(t, logger.Module("audit").Enabled(t.Context(), slog.LevelInfo), "modules without an override follow the logger")
+
+	// An empty module level removes the override
+	w, levels = do(http.MethodPut, `{"modules":{"http":""}}`)
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, map[string]string{"jobs": "debug"}, levels.Modules)
+	assert.True(t, logger.Module("http").Enabled(t.Context(), slog.LevelWarn))
+
+	// Invalid levels are rejected without applying any change
+	for _, body := range []string{`{}`, `{"level":"verbose"}`, `{"level":"debug","modules":{"jobs":"loud"}}`, `not json`} {
+		w, _ = do(http.MethodPut, body)
+		assert.Equal(t, http.StatusBadRequest, w.Code, body)
+	}
+	assert.Equal(t, slog.LevelWarn, logger.Level())
+}
@@ -4,6 +4,7 @@ import (
 	"net/http"
 
 	"arquivolivre.com.br/otel/internal/database"
+	"arquivolivre.com.br/otel/pkg/utils"
 
 	"github.com/gin-gonic/gin"
 )
@@ -20,6 +21,17 @@ func NewMetricsHandler(db *database.DB) *MetricsHandler {
 
 // GetMetrics handles GET /metrics - returns database and application metrics
 func (h *MetricsHandler) GetMetrics(c *gin.Context) {
+	if h.db == nil {
+		// DB_DRIVER=memory has no database to report on
+		utils.SendJSON(c, http.StatusOK, gin.H{
+			"application": gin.H{
+				"status": "running",
+			},
+			"message": "Application metrics",
+		})
+		return
+	}
+
 	// Get database health status
 	healthErr := h.db.Health()
 
@@ -49,5 +61,5 @@ func (h *MetricsHandler) GetMetrics(c *gin.Context) {
 		statusCode = http.StatusServiceUnavailable
 	}
 
-	c.JSON(statusCode, response)
+	utils.SendJSON(c, statusCode, response)
 }
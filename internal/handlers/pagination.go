@@ -0,0 +1,53 @@
+package handlers
+
+import (
+	"fmt"
+	"strconv"
+	"sync/atomic"
+
+	"github.com/gin-gonic/gin"
+)
+
+// PaginationLimits bound the page size accepted by list endpoints
+type PaginationLimits struct {
+	Default int
+	Max     int
+}
+
+// DefaultPaginationLimits are used until SetPaginationLimits is called
+var DefaultPaginationLimits = PaginationLimits{Default: 10, Max: 100}
+
+var paginationLimits atomic.Pointer[PaginationLimits]
+
+// SetPaginationLimits changes the page size limits of every handler at runtime
+func SetPaginationLimits(limits PaginationLimits) error {
+	if limits.Default < 1 || limits.Max < limits.Default {
+		return fmt.Errorf("invalid pagination limits: default %d, max %d", limits.Default, limits.Max)
+	}
+	paginationLimits.Store(&limits)
+	return nil
+}
+
+// CurrentPaginationLimits returns the limits in effect
+func CurrentPaginationLimits() PaginationLimits {
+	if limits := paginationLimits.Load(); limits != nil {
+		return *limits
+	}
+	return DefaultPaginationLimits
+}
+
+// parsePagination reads the page and limit query parameters; out of range
+// values fall back to the first page and the default limit
+func parsePagination(c *gin.Context) (page, limit, offset int) {
+	limits := CurrentPaginationLimits()
+	page, _ = strconv.Atoi(c.DefaultQuery("page", "1"))
+	limit, _ = strconv.Atoi(c.DefaultQuery("limit", strconv.Itoa(limits.Default)))
+
+	if page < 1 {
+		page = 1
+	}
+	if limit < 1 || limit > limits.Max {
+		limit = limits.Default
+	}
+	return page, limit, (page - 1) * limit
+}
@@ -0,0 +1,19 @@
+package handlers
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSetPaginationLimits(t *testing.T) {
+	defer func() { require.NoError(t, SetPaginationLimits(DefaultPaginationLimits)) }()
+
+	assert.Error(t, SetPaginationLimits(PaginationLimits{Default: 0, Max: 10}))
+	assert.Error(t, SetPaginationLimits(PaginationLimits{Default: 20, Max: 10}))
+	assert.Equal(t, DefaultPaginationLimits, CurrentPaginationLimits())
+
+	require.NoError(t, SetPaginationLimits(PaginationLimits{Default: 5, Max: 20}))
+	assert.Equal(t, PaginationLimits{Default: 5, Max: 20}, CurrentPaginationLimits())
+}
@@ -0,0 +1,205 @@
+package handlers
+
+import (
+	"log"
+	"strconv"
+
+	"arquivolivre.com.br/otel/internal/auth"
+	"arquivolivre.com.br/otel/internal/dto"
+	"arquivolivre.com.br/otel/internal/features"
+	"arquivolivre.com.br/otel/internal/logging"
+	"arquivolivre.com.br/otel/internal/middleware"
+	"arquivolivre.com.br/otel/internal/models"
+	"arquivolivre.com.br/otel/internal/repository"
+	"arquivolivre.com.br/otel/internal/validation"
+	"arquivolivre.com.br/otel/pkg/utils"
+
+	"github.com/gin-gonic/gin"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// PostHandler serves the posts of users. Posts belong to a user, so it reads
+// users too, and those lookups appear as sibling spans in the request trace
+type PostHandler struct {
+	postRepo repository.PostStore
+	userRepo repository.UserStore
+	mapper   dto.Mapper
+}
+
+// NewPostHandler creates a handler that renders v1 responses
+func NewPostHandler(postRepo repository.PostStore, userRepo repository.UserStore) *PostHandler {
+	if err := validation.Register(); err != nil {
+		log.Printf("Warning: Failed to register custom validators: %v", err)
+	}
+	return &PostHandler{
+		postRepo: postRepo,
+		userRepo: userRepo,
+		mapper:   dto.V1,
+	}
+}
+
+// WithMapper returns a copy of the handler that renders responses with mapper
+func (h *PostHandler) WithMapper(mapper dto.Mapper) *PostHandler {
+	clone := *h
+	clone.mapper = mapper
+	return &clone
+}
+
+// GetPosts handles GET /api/posts
+func (h *PostHandler) GetPosts(c *gin.Context) {
+	h.listPosts(c, 0)
+}
+
+// GetUserPosts handles GET /api/users/:id/posts
+func (h *PostHandler) GetUserPosts(c *gin.Context) {
+	userID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		_ = c.Error(middleware.BadRequestError("Invalid user ID"))
+		return
+	}
+
+	// An unknown user is a 404 rather than an empty list
+	if _, err := h.userRepo.GetByID(c.Request.Context(), userID); err != nil {
+		_ = c.Error(middleware.FromError(err, "Failed to retrieve user"))
+		return
+	}
+	h.listPosts(c, userID)
+}
+
+// listPosts sends a page of the posts of userID, or of every user when it is 0
+func (h *PostHandler) listPosts(c *gin.Context, userID int) {
+	span := trace.SpanFromContext(c.Request.Context())
+	span.SetAttributes(
+		attribute.String("handler", "GetPosts"),
+		attribute.String("operation", "list_posts"),
+	)
+	if userID != 0 {
+		span.SetAttributes(attribute.Int("user.id", userID))
+	}
+
+	page, limit, offset := parsePagination(c)
+	span.SetAttributes(
+		attribute.Int("pagination.page", page),
+		attribute.Int("pagination.limit", limit),
+		attribute.Int("pagination.offset", offset),
+	)
+
+	posts, err := h.postRepo.GetAll(c.Request.Context(), userID, limit, offset)
+	if err != nil {
+		logging.LogError(c.Request.Context(), err, "Failed to retrieve posts from database", map[string]interface{}{
+			"user_id": userID,
+			"page":    page,
+			"limit":   limit,
+		})
+		middleware.RecordError(c, err, "Failed to retrieve posts from database")
+		_ = c.Error(middleware.InternalError("Failed to retrieve posts", err))
+		return
+	}
+
+	total, err := h.postRepo.Count(c.Request.Context(), userID)
+	if err != nil {
+		middleware.RecordError(c, err, "Failed to count posts in database")
+		_ = c.Error(middleware.InternalError("Failed to count posts", err))
+		return
+	}
+
+	span.SetAttributes(
+		attribute.Int("result.posts_count", len(posts)),
+		attribute.Int("result.total_count", total),
+	)
+	utils.SendPaginated(c, dto.Posts(responseMapper(c, h.mapper), posts, auth.IsAdmin(c.Request.Context())), page, limit, total)
+}
+
+// GetPost handles GET /api/posts/:id; the post embeds its author
+func (h *PostHandler) GetPost(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		_ = c.Error(middleware.BadRequestError("Invalid post ID"))
+		return
+	}
+
+	post, author, err := h.postRepo.GetWithAuthor(c.Request.Context(), id)
+	if err != nil {
+		_ = c.Error(middleware.FromError(err, "Failed to retrieve post"))
+		return
+	}
+
+	utils.SendSuccess(c, h.postResponse(c, post, author))
+}
+
+// CreatePost handles POST /api/posts
+func (h *PostHandler) CreatePost(c *gin.Context) {
+	var req models.CreatePostRequest
+	if apiErr := bindNormalizedJSON(c, &req); apiErr != nil {
+		_ = c.Error(apiErr)
+		return
+	}
+
+	author, err := h.userRepo.GetByID(c.Request.Context(), req.UserID)
+	if err != nil {
+		_ = c.Error(middleware.FromError(err, "Failed to retrieve user"))
+		return
+	}
+
+	post, err := h.postRepo.Create(c.Request.Context(), req)
+	if err != nil {
+		_ = c.Error(middleware.FromError(err, "Failed to create post"))
+		return
+	}
+
+	utils.SendCreated(c, h.postResponse(c, post, author), "Post created successfully")
+}
+
+// UpdatePost handles PUT /api/posts/:id
+func (h *PostHandler) UpdatePost(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		_ = c.Error(middleware.BadRequestError("Invalid post ID"))
+		return
+	}
+
+	var req models.UpdatePostRequest
+	if apiErr := bindNormalizedJSON(c, &req); apiErr != nil {
+		_ = c.Error(apiErr)
+		return
+	}
+
+	post, err := h.postRepo.Update(c.Request.Context(), id, req)
+	if err != nil {
+		_ = c.Error(middleware.FromError(err, "Failed to update post"))
+		return
+	}
+
+	utils.SendSuccess(c, h.postResponse(c, post, nil), "Post updated successfully")
+}
+
+// DeletePost handles DELETE /api/posts/:id
+func (h *PostHandler) DeletePost(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		_ = c.Error(middleware.BadRequestError("Invalid post ID"))
+		return
+	}
+
+	if err := h.postRepo.Delete(c.Request.Context(), id); err != nil {
+		_ = c.Error(middleware.FromError(err, "Failed to delete post"))
+		return
+	}
+
+	utils.SendNoContent(c)
+}
+
+// postResponse maps post with the handler's API version. Like user
+// responses, audit fields are for admins only and the author's email is
+// masked for other callers while the mask-user-email flag is on
+func (h *PostHandler) postResponse(c *gin.Context, post *models.Post, author *models.User) any {
+	ctx := c.Request.Context()
+	admin := auth.IsAdmin(ctx)
+	if author != nil && !admin && features.Enabled(ctx, features.MaskUserEmail, false) {
+		masked := *author
+		masked.Email = maskEmail(masked.Email)
+		author = &masked
+	}
+	return responseMapper(c, h.mapper).Post(post, author, admin)
+}
@@ -0,0 +1,221 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"arquivolivre.com.br/otel/internal/dto"
+	"arquivolivre.com.br/otel/internal/middleware"
+	"arquivolivre.com.br/otel/internal/models"
+	"arquivolivre.com.br/otel/pkg/apperrors"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// mockPostStore keeps posts in memory; authors come from users
+type mockPostStore struct {
+	posts  []models.Post
+	users  *mockUserStore
+	nextID int
+}
+
+func newMockPostStore(users *mockUserStore) *mockPostStore {
+	return &mockPostStore{users: users, nextID: 1}
+}
+
+func (m *mockPostStore) GetAll(_ context.Context, userID, limit, offset int) ([]models.Post, error) {
+	var posts []models.Post
+	for _, post := range m.posts {
+		if userID == 0 || post.UserID == userID {
+			posts = append(posts, post)
+		}
+	}
+	if offset > len(posts) {
+		offset = len(posts)
+	}
+	return posts[offset:min(offset+limit, len(posts))], nil
+}
+
+func (m *mockPostStore) Count(ctx context.Context, userID int) (int, error) {
+	posts, _ := m.GetAll(ctx, userID, len(m.posts), 0)
+	return len(posts), nil
+}
+
+func (m *mockPostStore) GetByID(_ context.Context, id int) (*models.Post, error) {
+	for i := range m.posts {
+		if m.posts[i].ID == id {
+			post := m.posts[i]
+			return &post, nil
+		}
+	}
+	return nil, apperrors.NotFound("post not found")
+}
+
+func (m *mockPostStore) GetWithAuthor(ctx context.Context, id int) (*models.Post, *models.User, error) {
+	post, err := m.GetByID(ctx, id)
+	if err != nil {
+		return nil, nil, err
+	}
+	author, err := m.users.GetByID(ctx, post.UserID)
+	if err != nil {
+		return nil, nil, err
+	}
+	return post, author, nil
+}
+
+func (m *mockPostStore) Create(_ context.Context, req models.CreatePostRequest) (*models.Post, error) {
+	post := models.Post{ID: m.nextID, UserID: req.UserID, Title: req.Title, Body: req.Body}
+	m.nextID++
+	m.posts = append(m.posts, post)
+	return &post, nil
+}
+
+func (m *mockPostStore) Update(ctx context.Context, id int, req models.UpdatePostRequest) (*models.Post, error) {
+	for i := range m.posts {
+		if m.posts[i].ID == id {
+			if req.Title != nil {
+				m.posts[i].Title = *req.Title
+			}
+			if req.Body != nil {
+				m.posts[i].Body = *req.Body
+			}
+			return m.GetByID(ctx, id)
+		}
+	}
+	return nil, apperrors.NotFound("post not found")
+}
+
+func (m *mockPostStore) Delete(_ context.Context, id int) error {
+	for i := range m.posts {
+		if m.posts[i].ID == id {
+			m.posts = append(m.posts[:i], m.posts[i+1:]...)
+			return nil
+		}
+	}
+	return apperrors.NotFound("post not found")
+}
+
+func setupPostRouter(handler *PostHandler) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.Use(middleware.ErrorHandler())
+	registerPostRoutes(r.Group("/api"), handler, nil, nil)
+	return r
+}
+
+func newPostFixtures() (*mockPostStore, *mockUserStore) {
+	users := newMockUserStore()
+	users.users = []models.User{{ID: 1, Name: "Ann", Email: "ann@example.com"}}
+	return newMockPostStore(users), users
+}
+
+func TestCreateAndGetPost(t *testing.T) {
+	posts, users := newPostFixtures()
+	r := setupPostRouter(NewPostHandler(posts, users))
+
+	body, _ := json.Marshal(models.CreatePostRequest{UserID: 1, Title: " Hello ", Body: "World"})
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/api/posts", bytes.NewReader(body)))
+	require.Equal(t, http.StatusCreated, w.Code, w.Body.String())
+
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/posts/1", nil))
+	require.Equal(t, http.StatusOK, w.Code)
+	var resp struct {
+		Data models.PostResponse `json:"data"`
+	}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	assert.Equal(t, "Hello", resp.Data.Title)
+	require.NotNil(t, resp.Data.Author)
+	assert.Equal(t, "Ann", resp.Data.Author.Name)
+}
+
+func TestCreatePostForUnknownUser(t *testing.T) {
+	posts, users := newPostFixtures()
+	r := setupPostRouter(NewPostHandler(posts, users))
+
+	body, _ := json.Marshal(models.CreatePostRequest{UserID: 9, Title: "T", Body: "B"})
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/api/posts", bytes.NewReader(body)))
+	assert.Equal(t, http.StatusNotFound, w.Code)
+	assert.Empty(t, posts.posts)
+}
+
+func TestPostWhitespaceOnlyFields(t *testing.T) {
+	posts, users := newPostFixtures()
+	posts.posts = []models.Post{{ID: 1, UserID: 1, Title: "Old", Body: "B"}}
+	r := setupPostRouter(NewPostHandler(posts, users))
+
+	// Text is validated after trimming, so blanks fail the required rules
+	for _, tc := range []struct {
+		method, path, body, field string
+	}{
+		{http.MethodPost, "/api/posts", `{"user_id":1,"title":"   ","body":"B"}`, "title"},
+		{http.MethodPost, "/api/posts", `{"user_id":1,"title":"T","body":" \n\t "}`, "body"},
+		{http.MethodPut, "/api/posts/1", `{"title":"   "}`, "title"},
+	} {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(tc.method, tc.path, bytes.NewReader([]byte(tc.body))))
+		require.Equal(t, http.StatusBadRequest, w.Code, tc.body)
+		var resp models.ErrorResponse
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+		if assert.Len(t, resp.Details, 1, tc.body) {
+			assert.Equal(t, tc.field, resp.Details[0].Field)
+		}
+	}
+	assert.Len(t, posts.posts, 1)
+	assert.Equal(t, "Old", posts.posts[0].Title)
+}
+
+func TestGetUserPosts(t *testing.T) {
+	posts, users := newPostFixtures()
+	users.users = append(users.users, models.User{ID: 2, Name: "Bob", Email: "bob@example.com"})
+	posts.posts = []models.Post{{ID: 1, UserID: 1, Title: "A"}, {ID: 2, UserID: 2, Title: "B"}, {ID: 3, UserID: 1, Title: "C"}}
+	r := setupPostRouter(NewPostHandler(posts, users).WithMapper(dto.V2))
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/users/1/posts", nil))
+	require.Equal(t, http.StatusOK, w.Code)
+	var page struct {
+		Data       []dto.PostV2      `json:"data"`
+		Pagination models.Pagination `json:"pagination"`
+	}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &page))
+	assert.Equal(t, 2, page.Pagination.Total)
+	for _, post := range page.Data {
+		assert.Equal(t, 1, post.AuthorID)
+	}
+
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/users/9/posts", nil))
+	assert.Equal(t, http.StatusNotFound, w.Code)
+}
+
+func TestUpdateAndDeletePost(t *testing.T) {
+	posts, users := newPostFixtures()
+	posts.posts = []models.Post{{ID: 1, UserID: 1, Title: "Old", Body: "B"}}
+	r := setupPostRouter(NewPostHandler(posts, users))
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodPut, "/api/posts/1", bytes.NewReader([]byte(`{"title":"New"}`))))
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "New", posts.posts[0].Title)
+
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodDelete, "/api/posts/1", nil))
+	assert.Equal(t, http.StatusNoContent, w.Code)
+
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodDelete, "/api/posts/1", nil))
+	assert.Equal(t, http.StatusNotFound, w.Code)
+
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/posts/abc", nil))
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
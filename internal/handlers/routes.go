@@ -1,58 +1,375 @@
 package handlers
 
 import (
+	"net/http"
+	"slices"
+	"time"
+
+	"arquivolivre.com.br/otel/internal/audit"
+	"arquivolivre.com.br/otel/internal/auth"
+	"arquivolivre.com.br/otel/internal/avatars"
+	"arquivolivre.com.br/otel/internal/buildinfo"
+	"arquivolivre.com.br/otel/internal/chaos"
+	"arquivolivre.com.br/otel/internal/config"
 	"arquivolivre.com.br/otel/internal/database"
+	"arquivolivre.com.br/otel/internal/dto"
+	"arquivolivre.com.br/otel/internal/events"
+	"arquivolivre.com.br/otel/internal/health"
+	"arquivolivre.com.br/otel/internal/jobs"
+	"arquivolivre.com.br/otel/internal/livemetrics"
 	"arquivolivre.com.br/otel/internal/logging"
 	"arquivolivre.com.br/otel/internal/middleware"
+	"arquivolivre.com.br/otel/internal/openapi"
+	"arquivolivre.com.br/otel/internal/operations"
+	"arquivolivre.com.br/otel/internal/reports"
 	"arquivolivre.com.br/otel/internal/repository"
+	"arquivolivre.com.br/otel/internal/roles"
+	"arquivolivre.com.br/otel/internal/webhooks"
+	"arquivolivre.com.br/otel/pkg/cache"
+	"arquivolivre.com.br/otel/pkg/utils"
 
 	"github.com/gin-gonic/gin"
 )
 
-func SetupRoutes(db *database.DB) *gin.Engine {
+// Services are optional collaborators of the handlers; nil fields disable the
+// corresponding feature
+type Services struct {
+	// Users replaces the MySQL user repository, e.g. with another backend
+	Users repository.UserStore
+	// Posts replaces the MySQL post repository
+	Posts    repository.PostStore
+	Jobs     jobs.Enqueuer
+	Events   events.Publisher
+	Notifier WelcomeNotifier
+	Enricher Enricher
+	// External serves GET /api/external; without it the endpoint returns 503
+	External *ExternalHandler
+	// Chaos injects faults into routes when set; its rules are managed under
+	// /admin/chaos
+	Chaos *chaos.Controller
+	// Avatars stores the uploads of POST /api/users/:id/avatar when set;
+	// AvatarMaxBytes bounds their size
+	Avatars        avatars.Storage
+	AvatarMaxBytes int64
+	// Webhooks are managed under /admin/webhooks when set
+	Webhooks webhooks.Store
+	// Audit serves the audit log to admins under /api/audit when set
+	Audit audit.Store
+	// Roles serves the roles and their assignment to users under /api when
+	// set; with RBAC the assigned roles are also granted to principals whose
+	// subject is the user's email
+	Roles roles.Store
+	// Search serves GET /api/users/search when set
+	Search repository.UserSearcher
+	// Operations runs the reports of POST /api/reports in the background
+	// and serves their status under /api/operations when set
+	Operations *operations.Manager
+	// Telemetry switches the export of the telemetry signals under
+	// /admin/telemetry when set
+	Telemetry *config.TelemetryProvider
+	// AdminToken is accepted as a bearer token on /admin routes
+	AdminToken string
+	// JWT protects the user write endpoints when enabled
+	JWT middleware.JWTConfig
+	// APIKeys authenticate clients of the user and post endpoints sending
+	// X-API-Key and enforce their daily quotas; API keys also satisfy JWT
+	APIKeys *auth.APIKeys
+	// RBAC requires the users:write role on user writes, posts:write on
+	// post writes and roles:write on role writes
+	RBAC bool
+	// RateLimits throttle the user endpoints
+	RateLimits RateLimits
+	// RequestLimits bound the duration and body size of the user and post
+	// endpoints
+	RequestLimits RequestLimits
+	// Bulk bounds POST /api/users/bulk; zero uses DefaultBulkLimits
+	Bulk BulkLimits
+	// Idempotency stores the responses of user creates sent with an
+	// Idempotency-Key for IdempotencyTTL; without it the header is ignored
+	Idempotency    cache.Cache
+	IdempotencyTTL time.Duration
+	// Prometheus serves /metrics in Prometheus text format when set, in
+	// place of the JSON metrics summary
+	Prometheus http.Handler
+	// Health holds the checks reported by /health and /ready; without it
+	// only the database is checked
+	Health *health.Registry
+	// UntracedPaths are served without spans or HTTP metrics, e.g. probes
+	// and scrapes
+	UntracedPaths []string
+	// APISunset is announced in the Sunset header of the deprecated
+	// unversioned routes; zero omits the header
+	APISunset time.Time
+	// MetricsStream serves GET /events/metrics when its interval is set
+	MetricsStream livemetrics.Options
+}
+
+// RateLimits are the limiters of the user read and write endpoints; a nil
+// limiter leaves its endpoints unlimited
+type RateLimits struct {
+	Read  *middleware.RateLimiter
+	Write *middleware.RateLimiter
+}
+
+// RequestLimits are the limits of the user and post read and write
+// endpoints
+type RequestLimits struct {
+	Read  RequestLimit
+	Write RequestLimit
+}
+
+// RequestLimit bounds the requests of a route group; zero fields leave them
+// unbounded
+type RequestLimit struct {
+	// Timeout cancels requests still running after it with 503
+	Timeout time.Duration
+	// MaxBodyBytes rejects larger bodies with 413
+	MaxBodyBytes int64
+}
+
+// middleware returns the Timeout and BodyLimit middleware enforcing l
+func (l RequestLimit) middleware() []gin.HandlerFunc {
+	var handlers []gin.HandlerFunc
+	if l.Timeout > 0 {
+		handlers = append(handlers, middleware.Timeout(l.Timeout))
+	}
+	if l.MaxBodyBytes > 0 {
+		handlers = append(handlers, middleware.BodyLimit(l.MaxBodyBytes))
+	}
+	return handlers
+}
+
+func SetupRoutes(db *database.DB, services Services) *gin.Engine {
 	router := gin.New()
 
-	telemetryMiddleware := middleware.NewTelemetryMiddleware("otel-example-api")
+	telemetryMiddleware := middleware.NewTelemetryMiddleware("otel-example-api").
+		ExcludePaths(services.UntracedPaths...)
 
-	logger := logging.NewLogger()
+	logger := logging.GetLogger()
 
+	router.Use(middleware.RequestID())
 	router.Use(logger.Middleware())
 	router.Use(middleware.Recovery())
 	router.Use(middleware.CORS())
 	router.Use(telemetryMiddleware.GinMiddleware())
 	router.Use(telemetryMiddleware.MetricsMiddleware())
+	// The stream counts requests by the status ErrorHandler writes, so its
+	// counter runs outside it
+	var requests *livemetrics.RequestCounter
+	if services.MetricsStream.Interval > 0 {
+		requests = livemetrics.NewRequestCounter()
+		router.Use(requests.Middleware())
+	}
 	router.Use(middleware.ErrorHandler())
+	router.Use(middleware.Tenant())
+	router.NoRoute(func(c *gin.Context) {
+		_ = c.Error(middleware.NotFoundError("Route not found"))
+	})
 
-	userRepo := repository.NewUserRepository(db)
+	var userRepo repository.UserStore = repository.NewUserRepository(db)
+	if services.Users != nil {
+		userRepo = services.Users
+	}
+	if services.Chaos != nil {
+		router.Use(services.Chaos.Middleware("/admin/"))
+		userRepo = services.Chaos.WrapUserStore(userRepo)
+	}
 
-	healthHandler := NewHealthHandler(db)
+	checks := services.Health
+	if checks == nil {
+		checks = health.NewRegistry()
+		_ = checks.Register("database", DatabaseCheck(db), health.Critical())
+	}
+	healthHandler := NewHealthHandler(checks)
 	userHandler := NewUserHandler(userRepo)
+	if services.Jobs != nil {
+		userHandler = userHandler.WithJobs(services.Jobs)
+	}
+	if services.Events != nil {
+		userHandler = userHandler.WithEvents(services.Events)
+	}
+	if services.Notifier != nil {
+		userHandler = userHandler.WithNotifier(services.Notifier)
+	}
+	if services.Enricher != nil {
+		userHandler = userHandler.WithEnricher(services.Enricher)
+	}
+	if services.Search != nil {
+		userHandler = userHandler.WithSearch(services.Search)
+	}
+	if services.Bulk != (BulkLimits{}) {
+		userHandler = userHandler.WithBulkLimits(services.Bulk)
+	}
+	var postRepo repository.PostStore = repository.NewPostRepository(db)
+	if services.Posts != nil {
+		postRepo = services.Posts
+	}
+	postHandler := NewPostHandler(postRepo, userRepo)
 	metricsHandler := NewMetricsHandler(db)
 
 	router.GET("/health", healthHandler.HealthCheck)
 	router.GET("/ready", healthHandler.ReadinessCheck)
+	router.GET("/version", version)
+
+	if services.Prometheus != nil {
+		router.GET("/metrics", gin.WrapH(services.Prometheus))
+	} else {
+		router.GET("/metrics", metricsHandler.GetMetrics)
+	}
+
+	if requests != nil {
+		// A nil *database.DB must not become a non-nil Stats
+		var stats livemetrics.Stats
+		if db != nil {
+			stats = db
+		}
+		livemetrics.NewStream(stats, requests, services.MetricsStream).Register(router.Group("/events"))
+	}
 
-	router.GET("/metrics", metricsHandler.GetMetrics)
+	router.GET("/openapi.json", openapi.Spec)
+	router.GET("/docs", openapi.Docs)
 
 	api := router.Group("/api")
 	{
 		api.GET("/", func(c *gin.Context) {
-			c.JSON(200, gin.H{
+			utils.SendJSON(c, http.StatusOK, gin.H{
 				"message": "OpenTelemetry Example API",
-				"version": "1.0.0",
+				"version": buildinfo.Get().Version,
 				"status":  "running",
 			})
 		})
 
-		users := api.Group("/users")
-		{
-			users.GET("", userHandler.GetUsers)
-			users.POST("", userHandler.CreateUser)
-			users.GET("/:id", userHandler.GetUser)
-			users.PUT("/:id", userHandler.UpdateUser)
-			users.DELETE("/:id", userHandler.DeleteUser)
+		api.GET("/version", version)
+
+		api.GET("/external", services.External.CallExternal)
+
+		// Requests run their request limits and then readAuth or writeAuth;
+		// exports and avatar uploads share them but have limits of their own
+		var readAuth, writeAuth []gin.HandlerFunc
+		if services.APIKeys != nil {
+			apiKeys := middleware.APIKeyAuth(services.APIKeys, middleware.NewQuotas())
+			readAuth = append(readAuth, apiKeys)
+			writeAuth = append(writeAuth, apiKeys)
+		}
+		if services.RateLimits.Read != nil {
+			readAuth = append(readAuth, middleware.RateLimit(services.RateLimits.Read))
+		}
+		reads := append(services.RequestLimits.Read.middleware(), readAuth...)
+		// An export streams for as long as there are users to send, so the
+		// read timeout would cut it short
+		exportLimit := RequestLimit{MaxBodyBytes: services.RequestLimits.Read.MaxBodyBytes}
+		exports := append(exportLimit.middleware(), readAuth...)
+		if services.RateLimits.Write != nil {
+			writeAuth = append(writeAuth, middleware.RateLimit(services.RateLimits.Write))
+		}
+		if services.JWT.Enabled() {
+			writeAuth = append(writeAuth, middleware.JWTAuth(services.JWT))
+		}
+		if services.RBAC && services.Roles != nil {
+			writeAuth = append(writeAuth, middleware.AssignedRoles(services.Roles))
+		}
+		writes := append(services.RequestLimits.Write.middleware(), writeAuth...)
+		userWrites, postWrites, roleWrites := writes, writes, writes
+		if services.RBAC {
+			userWrites = append(slices.Clip(writes), middleware.RequireRole(auth.RoleUsersWrite))
+			postWrites = append(slices.Clip(writes), middleware.RequireRole(auth.RolePostsWrite))
+			roleWrites = append(slices.Clip(writes), middleware.RequireRole(auth.RoleRolesWrite))
 		}
+		var creates []gin.HandlerFunc
+		if services.Idempotency != nil {
+			creates = append(creates, middleware.Idempotency(services.Idempotency, services.IdempotencyTTL))
+		}
+
+		if services.Audit != nil {
+			auditGroup := api.Group("/audit", append(slices.Clip(reads), middleware.AdminOnly(services.AdminToken))...)
+			audit.NewHandler(services.Audit).Register(auditGroup)
+		}
+		if services.Roles != nil {
+			roles.NewHandler(services.Roles).Register(api, reads, roleWrites)
+		}
+		if services.Operations != nil {
+			operations.NewHandler(services.Operations).Register(api, reads)
+			// Reports aggregate users, so they need the role of user writes
+			reports.NewHandler(services.Operations, userRepo).Register(api, userWrites)
+		}
+		if services.Avatars != nil {
+			// The body holds the avatar and its multipart framing
+			uploadLimit := RequestLimit{
+				Timeout:      services.RequestLimits.Write.Timeout,
+				MaxBodyBytes: services.AvatarMaxBytes + avatars.MultipartOverhead,
+			}
+			uploads := append(uploadLimit.middleware(), writeAuth...)
+			if services.RBAC {
+				uploads = append(uploads, middleware.RequireRole(auth.RoleUsersWrite))
+			}
+			avatars.NewHandler(services.Avatars, userRepo, services.AvatarMaxBytes).Register(api, uploads)
+		}
+
+		// Unversioned routes are deprecated aliases of v1; API-Version lets
+		// their clients opt into another response shape before moving
+		unversioned := api.Group("", middleware.Deprecated(services.APISunset, v1Successor), negotiateVersion())
+		registerUserRoutes(unversioned.Group("/users"), userHandler, reads, exports, userWrites, creates)
+		registerUserRoutes(api.Group("/v1/users"), userHandler.WithMapper(dto.V1), reads, exports, userWrites, creates)
+		registerUserRoutes(api.Group("/v2/users"), userHandler.WithMapper(dto.V2), reads, exports, userWrites, creates)
+		registerPostRoutes(unversioned, postHandler, reads, postWrites)
+		registerPostRoutes(api.Group("/v1"), postHandler.WithMapper(dto.V1), reads, postWrites)
+		registerPostRoutes(api.Group("/v2"), postHandler.WithMapper(dto.V2), reads, postWrites)
+	}
+
+	admin := router.Group("/admin", middleware.AdminOnly(services.AdminToken))
+	NewLogLevelHandler(logging.GetLogger()).Register(admin.Group("/loglevel"))
+	if services.Chaos != nil {
+		chaos.NewHandler(services.Chaos).Register(admin.Group("/chaos"))
+	}
+	if services.Webhooks != nil {
+		webhooks.NewHandler(services.Webhooks).Register(admin.Group("/webhooks"))
+	}
+	if services.Telemetry != nil {
+		NewTelemetryHandler(services.Telemetry).Register(admin.Group("/telemetry"))
 	}
 
 	return router
 }
+
+// version returns the build information of the running binary
+func version(c *gin.Context) {
+	utils.SendSuccess(c, buildinfo.Get())
+}
+
+// registerUserRoutes registers the user endpoints; the reads and writes
+// middleware run before the get and the create, update and delete handlers,
+// the exports middleware in place of reads before the export, and the
+// creates middleware after writes on the create handlers
+func registerUserRoutes(users *gin.RouterGroup, userHandler *UserHandler, reads, exports, writes, creates []gin.HandlerFunc) {
+	users.Group("", exports...).GET("/export", userHandler.ExportUsers)
+
+	readGroup := users.Group("", reads...)
+	readGroup.GET("", userHandler.GetUsers)
+	if userHandler.search != nil {
+		readGroup.GET("/search", userHandler.SearchUsers)
+	}
+	readGroup.GET("/:id", userHandler.GetUser)
+	readGroup.GET("/:id/profile", userHandler.GetUserProfile)
+
+	writeGroup := users.Group("", writes...)
+	createGroup := writeGroup.Group("", creates...)
+	createGroup.POST("", userHandler.CreateUser)
+	createGroup.POST("/bulk", userHandler.BulkCreateUsers)
+	writeGroup.PUT("/:id", userHandler.UpdateUser)
+	writeGroup.PATCH("/:id", userHandler.PatchUser)
+	writeGroup.DELETE("/:id", userHandler.DeleteUser)
+}
+
+// registerPostRoutes registers the post endpoints under root, including the
+// posts of a user; reads and writes are applied as in registerUserRoutes
+func registerPostRoutes(root *gin.RouterGroup, postHandler *PostHandler, reads, writes []gin.HandlerFunc) {
+	readGroup := root.Group("", reads...)
+	readGroup.GET("/posts", postHandler.GetPosts)
+	readGroup.GET("/posts/:id", postHandler.GetPost)
+	readGroup.GET("/users/:id/posts", postHandler.GetUserPosts)
+
+	writeGroup := root.Group("", writes...)
+	writeGroup.POST("/posts", postHandler.CreatePost)
+	writeGroup.PUT("/posts/:id", postHandler.UpdatePost)
+	writeGroup.DELETE("/posts/:id", postHandler.DeletePost)
+}
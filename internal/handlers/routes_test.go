@@ -1,12 +1,31 @@
 package handlers
 
 import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
 	"testing"
+	"time"
 
+	"arquivolivre.com.br/otel/internal/audit"
+	"arquivolivre.com.br/otel/internal/auth"
+	"arquivolivre.com.br/otel/internal/avatars"
 	"arquivolivre.com.br/otel/internal/database"
+	"arquivolivre.com.br/otel/internal/middleware"
+	"arquivolivre.com.br/otel/internal/models"
+	"arquivolivre.com.br/otel/internal/openapi"
+	"arquivolivre.com.br/otel/internal/operations"
+	"arquivolivre.com.br/otel/internal/repository"
+	"arquivolivre.com.br/otel/internal/roles"
+	"arquivolivre.com.br/otel/pkg/oteltest"
 
 	sqlmock "github.com/DATA-DOG/go-sqlmock"
 	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v5"
+	"go.opentelemetry.io/otel/attribute"
 )
 
 func TestSetupRoutes(t *testing.T) {
@@ -20,7 +39,7 @@ func TestSetupRoutes(t *testing.T) {
 
 	d := &database.DB{DB: sqlDB}
 
-	router := SetupRoutes(d)
+	router := SetupRoutes(d, Services{})
 	if router == nil {
 		t.Fatal("expected non-nil router")
 	}
@@ -33,15 +52,31 @@ func TestSetupRoutes(t *testing.T) {
 
 	// Check for specific expected routes
 	expectedRoutes := map[string]bool{
-		"GET /health":           false,
-		"GET /ready":            false,
-		"GET /metrics":          false,
-		"GET /api/":             false,
-		"GET /api/users":        false,
-		"POST /api/users":       false,
-		"GET /api/users/:id":    false,
-		"PUT /api/users/:id":    false,
-		"DELETE /api/users/:id": false,
+		"GET /health":                 false,
+		"GET /ready":                  false,
+		"GET /metrics":                false,
+		"GET /version":                false,
+		"GET /api/":                   false,
+		"GET /api/version":            false,
+		"GET /api/external":           false,
+		"GET /api/users":              false,
+		"POST /api/users":             false,
+		"POST /api/users/bulk":        false,
+		"GET /api/users/export":       false,
+		"GET /api/users/:id":          false,
+		"PUT /api/users/:id":          false,
+		"PATCH /api/users/:id":        false,
+		"DELETE /api/users/:id":       false,
+		"GET /api/users/:id/profile":  false,
+		"GET /api/v1/users":           false,
+		"GET /api/v1/users/:id":       false,
+		"GET /api/v2/users":           false,
+		"PUT /api/v2/users/:id":       false,
+		"GET /api/posts":              false,
+		"POST /api/posts":             false,
+		"GET /api/posts/:id":          false,
+		"DELETE /api/v1/posts/:id":    false,
+		"GET /api/v2/users/:id/posts": false,
 	}
 
 	for _, route := range routes {
@@ -57,3 +92,368 @@ func TestSetupRoutes(t *testing.T) {
 		}
 	}
 }
+
+func TestSetupRoutesWithInMemoryStores(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	rec := oteltest.Install(t)
+
+	users := repository.NewInMemoryUserStore()
+	if _, err := users.Create(t.Context(), models.CreateUserRequest{Name: "Ana", Email: "ana@example.com"}); err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+	// No database: /metrics and the stores must not touch it
+	router := SetupRoutes(nil, Services{Users: users, Posts: users.Posts()})
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/v1/users/1", nil))
+	if w.Code != http.StatusOK || !strings.Contains(w.Body.String(), "ana@example.com") {
+		t.Fatalf("GET /api/v1/users/1 = %d %s", w.Code, w.Body.String())
+	}
+	rec.AssertSpan(t, "InMemoryUserStore.GetByID", attribute.Int("user.id", 1), attribute.Bool("user.found", true))
+	rec.AssertChild(t, "InMemoryUserStore.GetByID", "GET /api/v1/users/:id")
+
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("GET /metrics = %d", w.Code)
+	}
+
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/v1/nothing", nil))
+	var resp models.ErrorResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil || w.Code != http.StatusNotFound {
+		t.Fatalf("GET /api/v1/nothing = %d %s", w.Code, w.Body.String())
+	}
+	if resp.Code != models.ErrCodeNotFound || len(resp.TraceID) != 32 {
+		t.Fatalf("expected a NOT_FOUND error with a trace ID, got %+v", resp)
+	}
+}
+
+func TestSetupRoutesServesPrometheusMetrics(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	sqlDB, _, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("sqlmock new: %v", err)
+	}
+	defer func() { _ = sqlDB.Close() }()
+
+	prometheus := http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4")
+		_, _ = w.Write([]byte("# TYPE up gauge\nup 1\n"))
+	})
+	router := SetupRoutes(&database.DB{DB: sqlDB}, Services{Prometheus: prometheus})
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", w.Code)
+	}
+	if body := w.Body.String(); body != "# TYPE up gauge\nup 1\n" {
+		t.Errorf("expected the Prometheus handler output, got %q", body)
+	}
+}
+
+func TestSetupRoutesProtectsUserWrites(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	sqlDB, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("sqlmock new: %v", err)
+	}
+	defer func() { _ = sqlDB.Close() }()
+	mock.MatchExpectationsInOrder(false)
+
+	router := SetupRoutes(&database.DB{DB: sqlDB}, Services{JWT: middleware.JWTConfig{Secret: "secret"}})
+
+	for _, req := range []*http.Request{
+		httptest.NewRequest(http.MethodPost, "/api/users", nil),
+		httptest.NewRequest(http.MethodPut, "/api/v1/users/1", nil),
+		httptest.NewRequest(http.MethodDelete, "/api/v2/users/1", nil),
+	} {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		if w.Code != http.StatusUnauthorized {
+			t.Errorf("%s %s: expected 401, got %d", req.Method, req.URL.Path, w.Code)
+		}
+	}
+
+	for _, path := range []string{"/health", "/metrics"} {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, path, nil))
+		if w.Code == http.StatusUnauthorized {
+			t.Errorf("GET %s: expected a public endpoint, got 401", path)
+		}
+	}
+}
+
+func TestSetupRoutesRequiresUserRoleForReports(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	users := repository.NewInMemoryUserStore()
+	router := SetupRoutes(nil, Services{
+		Users:      users,
+		Posts:      users.Posts(),
+		Operations: operations.NewManager(&recordingEnqueuer{}, time.Hour),
+		JWT:        middleware.JWTConfig{Secret: "secret"},
+		RBAC:       true,
+	})
+
+	for role, want := range map[string]int{auth.RolePostsWrite: http.StatusForbidden, auth.RoleUsersWrite: http.StatusAccepted} {
+		token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, middleware.Claims{
+			RegisteredClaims: jwt.RegisteredClaims{Subject: "ann", ExpiresAt: jwt.NewNumericDate(time.Now().Add(time.Hour))},
+			Roles:            []string{role},
+		}).SignedString([]byte("secret"))
+		if err != nil {
+			t.Fatalf("sign: %v", err)
+		}
+		req := httptest.NewRequest(http.MethodPost, "/api/reports", strings.NewReader(`{"type":"user_signups"}`))
+		req.Header.Set("Authorization", "Bearer "+token)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		if w.Code != want {
+			t.Errorf("POST /api/reports with %s = %d, want %d: %s", role, w.Code, want, w.Body.String())
+		}
+	}
+}
+
+func TestSetupRoutesRateLimitsUserWrites(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	sqlDB, _, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("sqlmock new: %v", err)
+	}
+	defer func() { _ = sqlDB.Close() }()
+
+	router := SetupRoutes(&database.DB{DB: sqlDB}, Services{RateLimits: RateLimits{
+		Write: middleware.NewRateLimiter("write", 0.1),
+	}})
+
+	codes := make([]int, 0, 2)
+	for i := 0; i < 2; i++ {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/api/v2/users", nil))
+		codes = append(codes, w.Code)
+	}
+	if codes[0] == http.StatusTooManyRequests || codes[1] != http.StatusTooManyRequests {
+		t.Errorf("expected only the second write to be throttled, got %v", codes)
+	}
+}
+
+// TestOpenAPIMatchesRoutes keeps the OpenAPI document in sync with the API
+// routes in both directions
+func TestOpenAPIMatchesRoutes(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	sqlDB, _, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("sqlmock new: %v", err)
+	}
+	defer func() { _ = sqlDB.Close() }()
+
+	db := &database.DB{DB: sqlDB}
+	// Optional routes that are documented must be enabled
+	avatarStorage, err := avatars.NewS3Storage(avatars.S3Config{Endpoint: "localhost:9000", Bucket: "avatars"})
+	if err != nil {
+		t.Fatalf("avatar storage: %v", err)
+	}
+	router := SetupRoutes(db, Services{
+		Audit:          audit.NewMySQLStore(db),
+		Roles:          roles.NewMySQLStore(db),
+		Search:         repository.NewUserSearch(db, repository.SearchFullText),
+		Operations:     operations.NewManager(&recordingEnqueuer{}, time.Hour),
+		Avatars:        avatarStorage,
+		AvatarMaxBytes: 1 << 20,
+	})
+	doc := openapi.Build()
+
+	registered := map[string]bool{}
+	for _, route := range router.Routes() {
+		path := openAPIPath(route.Path)
+		if !strings.HasPrefix(path, "/api/") && path != "/health" && path != "/ready" && path != "/version" {
+			continue
+		}
+		key := strings.ToLower(route.Method) + " " + path
+		registered[key] = true
+		if _, ok := doc.Paths[path][strings.ToLower(route.Method)]; !ok {
+			t.Errorf("route %s is not documented", key)
+		}
+	}
+
+	for path, ops := range doc.Paths {
+		for method := range ops {
+			if !registered[method+" "+path] {
+				t.Errorf("documented operation %s %s has no route", method, path)
+			}
+		}
+	}
+}
+
+// openAPIPath converts gin's :param segments to OpenAPI {param} templates
+func openAPIPath(path string) string {
+	segments := strings.Split(path, "/")
+	for i, segment := range segments {
+		if name, ok := strings.CutPrefix(segment, ":"); ok {
+			segments[i] = "{" + name + "}"
+		}
+	}
+	return strings.Join(segments, "/")
+}
+
+func TestSetupRoutesUsesInjectedUserStore(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	sqlDB, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("sqlmock new: %v", err)
+	}
+	defer func() { _ = sqlDB.Close() }()
+
+	store := newMockUserStore()
+	store.users = append(store.users, models.User{ID: 1, Name: "Ann", Email: "ann@example.com"})
+	router := SetupRoutes(&database.DB{DB: sqlDB}, Services{Users: store})
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/users/1", nil))
+	if w.Code != http.StatusOK || !strings.Contains(w.Body.String(), "ann@example.com") {
+		t.Fatalf("expected the user from the injected store, got %d: %s", w.Code, w.Body.String())
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("expected no database queries: %v", err)
+	}
+}
+
+func TestSetupRoutesDeprecatesUnversionedRoutes(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	store := newMockUserStore()
+	store.users = append(store.users, models.User{ID: 1, Name: "Ann", Email: "ann@example.com"})
+	sunset := time.Date(2027, 1, 31, 0, 0, 0, 0, time.UTC)
+	router := SetupRoutes(&database.DB{}, Services{Users: store, APISunset: sunset})
+
+	get := func(path, version string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		if version != "" {
+			req.Header.Set(APIVersionHeader, version)
+		}
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	w := get("/api/users/1", "")
+	if w.Code != http.StatusOK || !strings.Contains(w.Body.String(), `"name":"Ann"`) {
+		t.Fatalf("expected the v1 user, got %d: %s", w.Code, w.Body.String())
+	}
+	for header, want := range map[string]string{
+		"Deprecation": "true",
+		"Sunset":      "Sun, 31 Jan 2027 00:00:00 GMT",
+		"Link":        `</api/v1/users/1>; rel="successor-version"`,
+	} {
+		if got := w.Header().Get(header); got != want {
+			t.Errorf("%s = %q, want %q", header, got, want)
+		}
+	}
+
+	// API-Version opts the unversioned routes into another response shape
+	w = get("/api/users/1", "2")
+	if w.Code != http.StatusOK || !strings.Contains(w.Body.String(), `"display_name":"Ann"`) {
+		t.Fatalf("expected the v2 user, got %d: %s", w.Code, w.Body.String())
+	}
+	if got := w.Header().Get(APIVersionHeader); got != "2" {
+		t.Errorf("%s = %q, want 2", APIVersionHeader, got)
+	}
+	if w = get("/api/users/1", "v9"); w.Code != http.StatusBadRequest {
+		t.Errorf("expected 400 for an unknown version, got %d", w.Code)
+	}
+
+	// Versioned routes are not deprecated and ignore the header
+	w = get("/api/v1/users/1", "2")
+	if w.Code != http.StatusOK || !strings.Contains(w.Body.String(), `"name":"Ann"`) {
+		t.Fatalf("expected the v1 user, got %d: %s", w.Code, w.Body.String())
+	}
+	if got := w.Header().Get("Deprecation"); got != "" {
+		t.Errorf("versioned route has Deprecation %q", got)
+	}
+}
+
+// slowStore takes delay to list or stream the users of its store, or until
+// the request is cancelled
+type slowStore struct {
+	*repository.InMemoryUserStore
+	delay time.Duration
+}
+
+func (s slowStore) wait(ctx context.Context) error {
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-time.After(s.delay):
+		return nil
+	}
+}
+
+func (s slowStore) GetAll(ctx context.Context, filter models.UserFilter, limit, offset int) ([]models.User, error) {
+	if err := s.wait(ctx); err != nil {
+		return nil, err
+	}
+	return s.InMemoryUserStore.GetAll(ctx, filter, limit, offset)
+}
+
+func (s slowStore) Stream(ctx context.Context, filter models.UserFilter, fn func(*models.User) error) error {
+	if err := s.wait(ctx); err != nil {
+		return err
+	}
+	return s.InMemoryUserStore.Stream(ctx, filter, fn)
+}
+
+func TestSetupRoutesExportIgnoresReadTimeout(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	users := repository.NewInMemoryUserStore()
+	if _, err := users.Create(t.Context(), models.CreateUserRequest{Name: "Ana", Email: "ana@example.com"}); err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+	router := SetupRoutes(nil, Services{
+		Users:         slowStore{InMemoryUserStore: users, delay: 100 * time.Millisecond},
+		RequestLimits: RequestLimits{Read: RequestLimit{Timeout: 20 * time.Millisecond}},
+	})
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/v1/users", nil))
+	if w.Code != http.StatusServiceUnavailable {
+		t.Fatalf("expected the read timeout on the list, got %d: %s", w.Code, w.Body.String())
+	}
+
+	// The export outlives the read timeout
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/v1/users/export", nil))
+	if w.Code != http.StatusOK || !strings.Contains(w.Body.String(), "ana@example.com") {
+		t.Fatalf("expected the full export, got %d: %s", w.Code, w.Body.String())
+	}
+}
+
+func TestExportOutlastsServerDeadlines(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	users := repository.NewInMemoryUserStore()
+	if _, err := users.Create(t.Context(), models.CreateUserRequest{Name: "Ana", Email: "ana@example.com"}); err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+	router := SetupRoutes(nil, Services{Users: slowStore{InMemoryUserStore: users, delay: 150 * time.Millisecond}})
+	srv := httptest.NewUnstartedServer(router)
+	srv.Config.ReadTimeout = 50 * time.Millisecond
+	srv.Config.WriteTimeout = 50 * time.Millisecond
+	srv.Start()
+	defer srv.Close()
+
+	resp, err := http.Get(srv.URL + "/api/v1/users/export")
+	if err != nil {
+		t.Fatalf("GET /api/v1/users/export: %v", err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil || resp.StatusCode != http.StatusOK || !strings.Contains(string(body), "ana@example.com") {
+		t.Fatalf("expected the full export, got %d %q: %v", resp.StatusCode, body, err)
+	}
+}
@@ -0,0 +1,92 @@
+package handlers
+
+import (
+	"arquivolivre.com.br/otel/internal/config"
+	"arquivolivre.com.br/otel/internal/logging"
+	"arquivolivre.com.br/otel/internal/middleware"
+	"arquivolivre.com.br/otel/pkg/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+// TelemetrySettings are the runtime switches of the telemetry signals. In an
+// update, omitted fields are left unchanged
+type TelemetrySettings struct {
+	Traces      *bool    `json:"traces,omitempty"`
+	Metrics     *bool    `json:"metrics,omitempty"`
+	Logs        *bool    `json:"logs,omitempty"`
+	SampleRatio *float64 `json:"sample_ratio,omitempty"`
+}
+
+// TelemetryHandler switches the export of the telemetry signals at runtime
+type TelemetryHandler struct {
+	provider *config.TelemetryProvider
+}
+
+// NewTelemetryHandler creates a handler for provider
+func NewTelemetryHandler(provider *config.TelemetryProvider) *TelemetryHandler {
+	return &TelemetryHandler{provider: provider}
+}
+
+// Register mounts the telemetry endpoints on group
+func (h *TelemetryHandler) Register(group *gin.RouterGroup) {
+	group.GET("", h.GetSettings)
+	group.PUT("", h.UpdateSettings)
+}
+
+// GetSettings returns which signals are exported and the sampling ratio
+func (h *TelemetryHandler) GetSettings(c *gin.Context) {
+	utils.SendSuccess(c, h.settings())
+}
+
+// UpdateSettings turns the export of signals on or off and changes the
+// sampling ratio. The settings are validated before any is applied
+func (h *TelemetryHandler) UpdateSettings(c *gin.Context) {
+	var req TelemetrySettings
+	if err := c.ShouldBindJSON(&req); err != nil {
+		_ = c.Error(middleware.BadRequestError("Invalid telemetry settings: " + err.Error()))
+		return
+	}
+
+	switches := map[string]*bool{
+		config.SignalTraces:  req.Traces,
+		config.SignalMetrics: req.Metrics,
+		config.SignalLogs:    req.Logs,
+	}
+	for signal, enabled := range switches {
+		if enabled != nil && *enabled && !h.provider.Initialized(signal) {
+			_ = c.Error(middleware.BadRequestError("Invalid telemetry settings: " + signal + " were not enabled at startup"))
+			return
+		}
+	}
+	if req.SampleRatio != nil && (*req.SampleRatio < 0 || *req.SampleRatio > 1) {
+		_ = c.Error(middleware.BadRequestError("Invalid telemetry settings: sample_ratio must be between 0 and 1"))
+		return
+	}
+
+	for signal, enabled := range switches {
+		if enabled != nil && h.provider.Initialized(signal) {
+			_ = h.provider.SetExporting(signal, *enabled)
+		}
+	}
+	if req.SampleRatio != nil {
+		h.provider.Sampler.SetRatio(*req.SampleRatio)
+	}
+
+	settings := h.settings()
+	logging.LogWarn(c.Request.Context(), "Telemetry settings changed", map[string]interface{}{
+		"traces":       *settings.Traces,
+		"metrics":      *settings.Metrics,
+		"logs":         *settings.Logs,
+		"sample_ratio": *settings.SampleRatio,
+	})
+	utils.SendSuccess(c, settings)
+}
+
+func (h *TelemetryHandler) settings() TelemetrySettings {
+	traces := h.provider.Exporting(config.SignalTraces)
+	metrics := h.provider.Exporting(config.SignalMetrics)
+	logs := h.provider.Exporting(config.SignalLogs)
+	ratio := h.provider.Sampler.Ratio()
+	return TelemetrySettings{Traces: &traces, Metrics: &metrics, Logs: &logs, SampleRatio: &ratio}
+}
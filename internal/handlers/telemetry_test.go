@@ -0,0 +1,70 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"arquivolivre.com.br/otel/internal/config"
+	"arquivolivre.com.br/otel/internal/middleware"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTelemetryHandler(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	provider, err := config.InitTelemetry(&config.TelemetryConfig{
+		ServiceName:     "test-service",
+		OTLPEndpoint:    "localhost:4317",
+		EnableTracing:   true,
+		EnableMetrics:   true,
+		MetricsExporter: config.MetricsExporterPrometheus,
+		SampleRatio:     0.5,
+	})
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = provider.Shutdown(t.Context()) })
+
+	r := gin.New()
+	r.Use(middleware.ErrorHandler())
+	NewTelemetryHandler(provider).Register(r.Group("/admin/telemetry"))
+
+	do := func(method, body string) (*httptest.ResponseRecorder, map[string]interface{}) {
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest(method, "/admin/telemetry", strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		r.ServeHTTP(w, req)
+		var resp struct {
+			Data map[string]interface{} `json:"data"`
+		}
+		_ = json.Unmarshal(w.Body.Bytes(), &resp)
+		return w, resp.Data
+	}
+
+	w, settings := do(http.MethodGet, "")
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, map[string]interface{}{"traces": true, "metrics": true, "logs": false, "sample_ratio": 0.5}, settings)
+
+	w, settings = do(http.MethodPut, `{"metrics":false,"sample_ratio":0.1}`)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	assert.Equal(t, map[string]interface{}{"traces": true, "metrics": false, "logs": false, "sample_ratio": 0.1}, settings)
+	assert.False(t, provider.Exporting(config.SignalMetrics))
+	assert.Equal(t, 0.1, provider.Sampler.Ratio())
+
+	// Turning off a signal disabled at startup is accepted as a no-op
+	w, _ = do(http.MethodPut, `{"logs":false,"traces":false}`)
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.False(t, provider.Exporting(config.SignalTraces))
+
+	// Invalid settings are rejected without applying any change
+	for _, body := range []string{`{"traces":true,"logs":true}`, `{"metrics":true,"sample_ratio":1.5}`, `not json`} {
+		w, _ = do(http.MethodPut, body)
+		assert.Equal(t, http.StatusBadRequest, w.Code, body)
+	}
+	assert.False(t, provider.Exporting(config.SignalTraces))
+	assert.False(t, provider.Exporting(config.SignalMetrics))
+	assert.Equal(t, 0.1, provider.Sampler.Ratio())
+}
@@ -0,0 +1,154 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"arquivolivre.com.br/otel/internal/events"
+	"arquivolivre.com.br/otel/internal/logging"
+	"arquivolivre.com.br/otel/internal/middleware"
+	"arquivolivre.com.br/otel/internal/models"
+	"arquivolivre.com.br/otel/pkg/utils"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// BulkLimits bound POST /api/users/bulk
+type BulkLimits struct {
+	// MaxUsers is the most users a single request may create
+	MaxUsers int
+	// BatchSize is the number of users inserted per transaction
+	BatchSize int
+}
+
+// DefaultBulkLimits apply unless WithBulkLimits sets others
+var DefaultBulkLimits = BulkLimits{MaxUsers: 100, BatchSize: 25}
+
+// WithBulkLimits returns a copy of the handler that bounds bulk creates with
+// limits; limits that are not positive keep their default
+func (h *UserHandler) WithBulkLimits(limits BulkLimits) *UserHandler {
+	if limits.MaxUsers < 1 {
+		limits.MaxUsers = DefaultBulkLimits.MaxUsers
+	}
+	if limits.BatchSize < 1 {
+		limits.BatchSize = DefaultBulkLimits.BatchSize
+	}
+	clone := *h
+	clone.bulk = limits
+	return &clone
+}
+
+// bulkCreateUsersRequest is the body of POST /api/users/bulk; each user is
+// decoded and validated on its own so one bad user does not fail the rest
+type bulkCreateUsersRequest struct {
+	Users []json.RawMessage `json:"users" binding:"required"`
+}
+
+// BulkCreateUsers handles POST /api/users/bulk. Each user is validated like a
+// create request, and the valid ones are inserted BatchSize at a time, one
+// transaction per batch. The response reports every user in request order:
+// 201 Created when all of them were created, 207 Multi-Status otherwise
+func (h *UserHandler) BulkCreateUsers(c *gin.Context) {
+	span := trace.SpanFromContext(c.Request.Context())
+	span.SetAttributes(
+		attribute.String("handler", "BulkCreateUsers"),
+		attribute.String("operation", "bulk_create_users"),
+	)
+
+	var body bulkCreateUsersRequest
+	if err := c.ShouldBindJSON(&body); err != nil {
+		_ = c.Error(bindError(c, err))
+		return
+	}
+	if len(body.Users) == 0 || len(body.Users) > h.bulk.MaxUsers {
+		_ = c.Error(middleware.BadRequestError(fmt.Sprintf("users must hold between 1 and %d users", h.bulk.MaxUsers)))
+		return
+	}
+	span.SetAttributes(attribute.Int("bulk.size", len(body.Users)))
+
+	results := make([]models.BulkItemResult, len(body.Users))
+	var reqs []models.CreateUserRequest
+	var indexes []int
+	for i, raw := range body.Users {
+		results[i].Index = i
+		var req models.CreateUserRequest
+		err := json.Unmarshal(raw, &req)
+		if err == nil {
+			req.Normalize()
+			err = binding.Validator.ValidateStruct(&req)
+		}
+		if err != nil {
+			setItemError(&results[i], bindError(c, err))
+			continue
+		}
+		reqs = append(reqs, req)
+		indexes = append(indexes, i)
+	}
+
+	for start := 0; start < len(reqs); start += h.bulk.BatchSize {
+		end := min(start+h.bulk.BatchSize, len(reqs))
+		h.createBatch(c, reqs[start:end], indexes[start:end], results)
+	}
+
+	summary := models.BulkResult{Results: results}
+	for _, result := range results {
+		if result.Status == http.StatusCreated {
+			summary.Succeeded++
+		} else {
+			summary.Failed++
+		}
+	}
+	span.SetAttributes(
+		attribute.Int("bulk.succeeded", summary.Succeeded),
+		attribute.Int("bulk.failed", summary.Failed),
+	)
+
+	if summary.Failed == 0 {
+		utils.SendCreated(c, summary, "Users created successfully")
+		return
+	}
+	utils.SendMultiStatus(c, summary, fmt.Sprintf("%d of %d users created", summary.Succeeded, len(results)))
+}
+
+// createBatch creates reqs in one transaction and fills in the results at
+// indexes. A batch that fails as a whole fails each of its users
+func (h *UserHandler) createBatch(c *gin.Context, reqs []models.CreateUserRequest, indexes []int, results []models.BulkItemResult) {
+	ctx := c.Request.Context()
+	h.batchSizes.Record(ctx, int64(len(reqs)))
+
+	created, err := h.userRepo.CreateBatch(ctx, reqs)
+	if err != nil {
+		logging.LogError(ctx, err, "Failed to create batch of users", map[string]interface{}{
+			"batch_size": len(reqs),
+		})
+		apiErr := middleware.FromError(err, "Failed to create user")
+		for _, i := range indexes {
+			setItemError(&results[i], apiErr)
+		}
+		return
+	}
+
+	for j, i := range indexes {
+		if created[j].Err != nil {
+			setItemError(&results[i], middleware.FromError(created[j].Err, "Failed to create user"))
+			continue
+		}
+		user := created[j].User
+		results[i].Status = http.StatusCreated
+		results[i].Data = h.userResponse(c, user)
+		h.publish(c, events.UserCreated, user.ID)
+		h.enqueueWelcome(c, user)
+	}
+}
+
+// setItemError reports apiErr as the outcome of result
+func setItemError(result *models.BulkItemResult, apiErr *middleware.APIError) {
+	result.Status = apiErr.Status
+	result.Error = apiErr.Message
+	result.Code = apiErr.Code
+	result.Details = apiErr.Details
+}
@@ -0,0 +1,217 @@
+package handlers
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+
+	"arquivolivre.com.br/otel/internal/auth"
+	"arquivolivre.com.br/otel/internal/dto"
+	"arquivolivre.com.br/otel/internal/features"
+	"arquivolivre.com.br/otel/internal/logging"
+	"arquivolivre.com.br/otel/internal/middleware"
+	"arquivolivre.com.br/otel/internal/models"
+	"arquivolivre.com.br/otel/internal/repository"
+	"arquivolivre.com.br/otel/pkg/utils"
+
+	"github.com/gin-gonic/gin"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// exportProgressRows is how many rows an export writes between flushes and
+// progress span events
+const exportProgressRows = 1000
+
+// exportEncoder writes users in one export format
+type exportEncoder interface {
+	header() error
+	write(user *models.User) error
+	flush() error
+}
+
+// ExportUsers handles GET /api/users/export?format=csv|ndjson, streaming
+// every user matching the list filters in ID order. Users are written as
+// they are read from the database, so the export never holds them all in
+// memory. Once the first row is sent the status can no longer change; a
+// later failure ends the response early and is recorded on the span
+func (h *UserHandler) ExportUsers(c *gin.Context) {
+	ctx := c.Request.Context()
+	span := trace.SpanFromContext(ctx)
+	span.SetAttributes(
+		attribute.String("handler", "ExportUsers"),
+		attribute.String("operation", "export_users"),
+	)
+
+	format := c.DefaultQuery("format", "csv")
+	if format != "csv" && format != "ndjson" {
+		_ = c.Error(middleware.BadRequestError("format must be csv or ndjson"))
+		return
+	}
+	filter, apiErr := parseUserFilter(c)
+	if apiErr != nil {
+		_ = c.Error(apiErr)
+		return
+	}
+	span.SetAttributes(attribute.String("export.format", format))
+	span.SetAttributes(repository.FilterAttributes(filter)...)
+
+	// The export outlasts the read and write deadlines of the server
+	rc := http.NewResponseController(c.Writer)
+	_ = rc.SetReadDeadline(time.Time{})
+	_ = rc.SetWriteDeadline(time.Time{})
+
+	admin := auth.IsAdmin(ctx)
+	mask := !admin && features.Enabled(ctx, features.MaskUserEmail, false)
+	out := &countingWriter{w: c.Writer}
+	var enc exportEncoder
+	contentType := "text/csv; charset=utf-8"
+	if format == "csv" {
+		enc = &csvExport{w: csv.NewWriter(out), admin: admin}
+	} else {
+		contentType = "application/x-ndjson"
+		buf := bufio.NewWriter(out)
+		enc = &ndjsonExport{buf: buf, enc: json.NewEncoder(buf), mapper: responseMapper(c, h.mapper), admin: admin}
+	}
+
+	formatAttr := metric.WithAttributes(attribute.String("format", format))
+	rows, reported := 0, int64(0)
+	// flush sends what is buffered and counts the bytes it adds
+	flush := func() error {
+		err := enc.flush()
+		c.Writer.Flush()
+		h.exportBytes.Add(ctx, out.n-reported, formatAttr)
+		reported = out.n
+		return err
+	}
+
+	started := false
+	start := func() error {
+		if started {
+			return nil
+		}
+		started = true
+		c.Header("Content-Disposition", `attachment; filename="users.`+format+`"`)
+		utils.StartStream(c, contentType)
+		return enc.header()
+	}
+
+	err := h.userRepo.Stream(ctx, filter, func(user *models.User) error {
+		if err := start(); err != nil {
+			return err
+		}
+		if mask {
+			user.Email = maskEmail(user.Email)
+		}
+		if err := enc.write(user); err != nil {
+			return err
+		}
+		rows++
+		if rows%exportProgressRows == 0 {
+			span.AddEvent("export.progress", trace.WithAttributes(
+				attribute.Int("export.rows", rows),
+				attribute.Int64("export.bytes", out.n),
+			))
+			return flush()
+		}
+		return nil
+	})
+	if err == nil {
+		err = start()
+	}
+	if err != nil && !started {
+		_ = c.Error(middleware.InternalError("Failed to export users", err))
+		return
+	}
+	if flushErr := flush(); err == nil {
+		err = flushErr
+	}
+
+	span.SetAttributes(
+		attribute.Int("export.rows", rows),
+		attribute.Int64("export.bytes", out.n),
+	)
+	if err != nil {
+		logging.LogError(ctx, err, "User export ended early", map[string]interface{}{
+			"format": format,
+			"rows":   rows,
+		})
+		middleware.RecordError(c, err, "User export ended early")
+	}
+}
+
+// csvExport writes a header row and a row per user; admins also get the
+// audit columns
+type csvExport struct {
+	w     *csv.Writer
+	admin bool
+}
+
+func (e *csvExport) header() error {
+	columns := []string{"id", "name", "email", "bio", "created_at", "updated_at", "version"}
+	if e.admin {
+		columns = append(columns, "created_by", "updated_by")
+	}
+	return e.w.Write(columns)
+}
+
+func (e *csvExport) write(user *models.User) error {
+	bio := ""
+	if user.Bio != nil {
+		bio = *user.Bio
+	}
+	record := []string{
+		strconv.Itoa(user.ID),
+		user.Name,
+		user.Email,
+		bio,
+		user.CreatedAt.String(),
+		user.UpdatedAt.String(),
+		strconv.Itoa(user.Version),
+	}
+	if e.admin {
+		record = append(record, user.CreatedBy, user.UpdatedBy)
+	}
+	return e.w.Write(record)
+}
+
+func (e *csvExport) flush() error {
+	e.w.Flush()
+	return e.w.Error()
+}
+
+// ndjsonExport writes each user as a line of JSON in the shape of the
+// negotiated API version
+type ndjsonExport struct {
+	buf    *bufio.Writer
+	enc    *json.Encoder
+	mapper dto.Mapper
+	admin  bool
+}
+
+func (e *ndjsonExport) header() error { return nil }
+
+func (e *ndjsonExport) write(user *models.User) error {
+	return e.enc.Encode(e.mapper.User(user, e.admin))
+}
+
+func (e *ndjsonExport) flush() error {
+	return e.buf.Flush()
+}
+
+// countingWriter counts the bytes written through it
+type countingWriter struct {
+	w io.Writer
+	n int64
+}
+
+func (w *countingWriter) Write(p []byte) (int, error) {
+	n, err := w.w.Write(p)
+	w.n += int64(n)
+	return n, err
+}
@@ -1,31 +1,151 @@
 package handlers
 
 import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
 	"math"
 	"net/http"
 	"strconv"
 	"strings"
-
+	"time"
+	"unicode/utf8"
+
+	"arquivolivre.com.br/otel/internal/auth"
+	"arquivolivre.com.br/otel/internal/dto"
+	"arquivolivre.com.br/otel/internal/enrichment"
+	"arquivolivre.com.br/otel/internal/events"
+	"arquivolivre.com.br/otel/internal/features"
+	"arquivolivre.com.br/otel/internal/jobs"
 	"arquivolivre.com.br/otel/internal/logging"
 	"arquivolivre.com.br/otel/internal/middleware"
 	"arquivolivre.com.br/otel/internal/models"
 	"arquivolivre.com.br/otel/internal/repository"
+	"arquivolivre.com.br/otel/internal/validation"
+	"arquivolivre.com.br/otel/pkg/utils"
 
 	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+	"go.opentelemetry.io/otel"
 	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
 	"go.opentelemetry.io/otel/trace"
 )
 
 type UserHandler struct {
 	userRepo repository.UserStore
+	mapper   dto.Mapper
+	jobs     jobs.Enqueuer
+	events   events.Publisher
+	notifier WelcomeNotifier
+	enricher Enricher
+	bulk     BulkLimits
+	// search serves GET /users/search when set
+	search repository.UserSearcher
+	// conflicts counts updates rejected because the user changed since the
+	// version they were based on
+	conflicts metric.Int64Counter
+	// batchSizes records the users per batch of a bulk create
+	batchSizes metric.Int64Histogram
+	// exportBytes counts the bytes streamed by user exports
+	exportBytes metric.Int64Counter
+	// conditionals counts user reads by If-None-Match outcome
+	conditionals metric.Int64Counter
+}
+
+// Enricher looks up profile details derived from an email address
+type Enricher interface {
+	Enrich(ctx context.Context, email string) (*enrichment.Profile, error)
 }
 
+// WelcomeNotifier sends the welcome message to new users
+type WelcomeNotifier interface {
+	SendWelcome(ctx context.Context, name, email string) error
+}
+
+// NewUserHandler creates a handler that renders v1 responses
 func NewUserHandler(userRepo repository.UserStore) *UserHandler {
+	if err := validation.Register(); err != nil {
+		log.Printf("Warning: Failed to register custom validators: %v", err)
+	}
+	conflicts, _ := otel.Meter("otel-example-api").Int64Counter(
+		"user.update.conflicts",
+		metric.WithDescription("Total number of user updates rejected because the user was modified concurrently"),
+	)
+	batchSizes, _ := otel.Meter("otel-example-api").Int64Histogram(
+		"user.bulk.batch.size",
+		metric.WithDescription("Number of users inserted per batch of a bulk create"),
+		metric.WithUnit("{user}"),
+		metric.WithExplicitBucketBoundaries(1, 5, 10, 25, 50, 100),
+	)
+	exportBytes, _ := otel.Meter("otel-example-api").Int64Counter(
+		"user.export.bytes",
+		metric.WithDescription("Total number of bytes streamed by user exports"),
+		metric.WithUnit("By"),
+	)
+	conditionals, _ := otel.Meter("otel-example-api").Int64Counter(
+		"user.conditional.requests",
+		metric.WithDescription("Total number of user reads by If-None-Match outcome: hit, miss or none"),
+	)
 	return &UserHandler{
-		userRepo: userRepo,
+		userRepo:     userRepo,
+		mapper:       dto.V1,
+		events:       events.NoopPublisher{},
+		bulk:         DefaultBulkLimits,
+		conflicts:    conflicts,
+		batchSizes:   batchSizes,
+		exportBytes:  exportBytes,
+		conditionals: conditionals,
 	}
 }
 
+// WithMapper returns a copy of the handler that renders responses with mapper
+func (h *UserHandler) WithMapper(mapper dto.Mapper) *UserHandler {
+	clone := *h
+	clone.mapper = mapper
+	return &clone
+}
+
+// WithJobs returns a copy of the handler that schedules follow-up work on queue
+func (h *UserHandler) WithJobs(queue jobs.Enqueuer) *UserHandler {
+	clone := *h
+	clone.jobs = queue
+	return &clone
+}
+
+// WithNotifier returns a copy of the handler that sends welcome messages
+// through notifier; they are only sent when a job queue is configured
+func (h *UserHandler) WithNotifier(notifier WelcomeNotifier) *UserHandler {
+	clone := *h
+	clone.notifier = notifier
+	return &clone
+}
+
+// WithEnricher returns a copy of the handler that serves user profiles from
+// enricher
+func (h *UserHandler) WithEnricher(enricher Enricher) *UserHandler {
+	clone := *h
+	clone.enricher = enricher
+	return &clone
+}
+
+// WithSearch returns a copy of the handler that searches users through
+// searcher
+func (h *UserHandler) WithSearch(searcher repository.UserSearcher) *UserHandler {
+	clone := *h
+	clone.search = searcher
+	return &clone
+}
+
+// WithEvents returns a copy of the handler that publishes user change events
+func (h *UserHandler) WithEvents(publisher events.Publisher) *UserHandler {
+	clone := *h
+	clone.events = publisher
+	return &clone
+}
+
 func (h *UserHandler) GetUsers(c *gin.Context) {
 	span := trace.SpanFromContext(c.Request.Context())
 	span.SetAttributes(
@@ -35,23 +155,27 @@ func (h *UserHandler) GetUsers(c *gin.Context) {
 
 	logging.WithGinContext(c).Info("Getting users list")
 
-	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
-	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "10"))
+	page, limit, offset := parsePagination(c)
 
-	if page < 1 {
-		page = 1
-	}
-	if limit < 1 || limit > 100 {
-		limit = 10
+	filter, apiErr := parseUserFilter(c)
+	if apiErr != nil {
+		_ = c.Error(apiErr)
+		return
 	}
 
-	offset := (page - 1) * limit
-
 	span.SetAttributes(
 		attribute.Int("pagination.page", page),
 		attribute.Int("pagination.limit", limit),
 		attribute.Int("pagination.offset", offset),
 	)
+	span.SetAttributes(repository.FilterAttributes(filter)...)
+
+	logging.LogDebug(c.Request.Context(), "Parsed pagination parameters", map[string]interface{}{
+		"page":      page,
+		"limit":     limit,
+		"offset":    offset,
+		"raw_query": c.Request.URL.RawQuery,
+	})
 
 	middleware.AddSpanEvent(c, "pagination_parsed",
 		attribute.Int("page", page),
@@ -59,7 +183,7 @@ func (h *UserHandler) GetUsers(c *gin.Context) {
 		attribute.Int("offset", offset),
 	)
 
-	users, err := h.userRepo.GetAll(c.Request.Context(), limit, offset)
+	users, err := h.userRepo.GetAll(c.Request.Context(), filter, limit, offset)
 	if err != nil {
 		logging.LogError(c.Request.Context(), err, "Failed to retrieve users from database", map[string]interface{}{
 			"page":   page,
@@ -67,39 +191,28 @@ func (h *UserHandler) GetUsers(c *gin.Context) {
 			"offset": offset,
 		})
 		middleware.RecordError(c, err, "Failed to retrieve users from database")
-		c.JSON(http.StatusInternalServerError, models.ErrorResponse{
-			Success: false,
-			Error:   "Failed to retrieve users",
-		})
+		_ = c.Error(middleware.InternalError("Failed to retrieve users", err))
 		return
 	}
 
 	middleware.AddSpanEvent(c, "users_retrieved", attribute.Int("count", len(users)))
 
-	total, err := h.userRepo.Count(c.Request.Context())
+	total, err := h.userRepo.Count(c.Request.Context(), filter)
 	if err != nil {
 		logging.LogError(c.Request.Context(), err, "Failed to count users in database", nil)
 		middleware.RecordError(c, err, "Failed to count users in database")
-		c.JSON(http.StatusInternalServerError, models.ErrorResponse{
-			Success: false,
-			Error:   "Failed to count users",
-		})
+		_ = c.Error(middleware.InternalError("Failed to count users", err))
 		return
 	}
 
 	middleware.AddSpanEvent(c, "total_count_retrieved", attribute.Int("total", total))
 
-	userResponses := make([]models.UserResponse, len(users))
-	for i, user := range users {
-		userResponses[i] = user.ToResponse()
-	}
-
-	totalPages := int(math.Ceil(float64(total) / float64(limit)))
+	pagination := models.NewPagination(page, limit, total)
 
 	span.SetAttributes(
 		attribute.Int("result.users_count", len(users)),
 		attribute.Int("result.total_count", total),
-		attribute.Int("result.total_pages", totalPages),
+		attribute.Int("result.total_pages", pagination.TotalPages),
 	)
 
 	logging.WithGinContext(c).WithFields(map[string]interface{}{
@@ -109,172 +222,435 @@ func (h *UserHandler) GetUsers(c *gin.Context) {
 		"limit":       limit,
 	}).Info("Successfully retrieved users")
 
-	response := models.PaginatedResponse{
-		Success: true,
-		Data:    userResponses,
-		Pagination: models.Pagination{
-			Page:       page,
-			Limit:      limit,
-			Total:      total,
-			TotalPages: totalPages,
-		},
+	if h.notModified(c, "list_users", h.userETag(c, users, page, limit, total)) {
+		return
+	}
+	utils.SendPaginated(c, h.userResponses(c, users), page, limit, total)
+}
+
+// maxFilterQueryLength bounds the q parameter of the users list
+const maxFilterQueryLength = 100
+
+// parseUserFilter reads the q, email, created_after and created_before
+// query parameters. Timestamps are RFC 3339 or a YYYY-MM-DD date in UTC
+func parseUserFilter(c *gin.Context) (models.UserFilter, *middleware.APIError) {
+	filter := models.UserFilter{Query: c.Query("q"), Email: c.Query("email")}
+	filter.Normalize()
+	if utf8.RuneCountInString(filter.Query) > maxFilterQueryLength {
+		return filter, middleware.BadRequestError(fmt.Sprintf("q must be at most %d characters", maxFilterQueryLength))
 	}
 
-	c.JSON(http.StatusOK, response)
+	var err error
+	if filter.CreatedAfter, err = parseFilterTime(c.Query("created_after")); err != nil {
+		return filter, middleware.BadRequestError("created_after must be an RFC 3339 timestamp or a YYYY-MM-DD date")
+	}
+	if filter.CreatedBefore, err = parseFilterTime(c.Query("created_before")); err != nil {
+		return filter, middleware.BadRequestError("created_before must be an RFC 3339 timestamp or a YYYY-MM-DD date")
+	}
+	if !filter.CreatedAfter.IsZero() && !filter.CreatedBefore.IsZero() && !filter.CreatedAfter.Before(filter.CreatedBefore) {
+		return filter, middleware.BadRequestError("created_after must be earlier than created_before")
+	}
+	return filter, nil
+}
+
+// parseFilterTime returns the zero time for an empty value
+func parseFilterTime(value string) (time.Time, error) {
+	if value == "" {
+		return time.Time{}, nil
+	}
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t, nil
+	}
+	return time.Parse(time.DateOnly, value)
 }
 
 func (h *UserHandler) GetUser(c *gin.Context) {
 	id, err := strconv.Atoi(c.Param("id"))
 	if err != nil {
-		c.JSON(http.StatusBadRequest, models.ErrorResponse{
-			Success: false,
-			Error:   "Invalid user ID",
-		})
+		_ = c.Error(middleware.BadRequestError("Invalid user ID"))
 		return
 	}
 
 	user, err := h.userRepo.GetByID(c.Request.Context(), id)
 	if err != nil {
-		if strings.Contains(err.Error(), "not found") {
-			c.JSON(http.StatusNotFound, models.ErrorResponse{
-				Success: false,
-				Error:   "User not found",
-			})
-			return
-		}
-
-		c.JSON(http.StatusInternalServerError, models.ErrorResponse{
-			Success: false,
-			Error:   "Failed to retrieve user",
-		})
+		_ = c.Error(middleware.FromError(err, "Failed to retrieve user"))
 		return
 	}
 
-	c.JSON(http.StatusOK, models.SuccessResponse{
-		Success: true,
-		Data:    user.ToResponse(),
-	})
+	if h.notModified(c, "get_user", h.userETag(c, []models.User{*user})) {
+		return
+	}
+	utils.SendSuccess(c, h.userResponse(c, user))
 }
 
-func (h *UserHandler) CreateUser(c *gin.Context) {
-	var req models.CreateUserRequest
+// GetUserProfile handles GET /api/users/:id/profile by combining the user with
+// details from the enrichment service
+func (h *UserHandler) GetUserProfile(c *gin.Context) {
+	if h.enricher == nil {
+		_ = c.Error(middleware.NewAPIError(http.StatusServiceUnavailable, models.ErrCodeServiceUnavailable, "Enrichment service is not configured"))
+		return
+	}
 
-	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, models.ErrorResponse{
-			Success: false,
-			Error:   "Invalid request data: " + err.Error(),
-		})
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		_ = c.Error(middleware.BadRequestError("Invalid user ID"))
 		return
 	}
 
-	existingUser, _ := h.userRepo.GetByEmail(c.Request.Context(), req.Email)
-	if existingUser != nil {
-		c.JSON(http.StatusConflict, models.ErrorResponse{
-			Success: false,
-			Error:   "Email already exists",
-		})
+	user, err := h.userRepo.GetByID(c.Request.Context(), id)
+	if err != nil {
+		_ = c.Error(middleware.FromError(err, "Failed to retrieve user"))
 		return
 	}
 
+	profile, err := h.enricher.Enrich(c.Request.Context(), user.Email)
+	if err != nil {
+		_ = c.Error(middleware.NewAPIError(http.StatusServiceUnavailable, models.ErrCodeServiceUnavailable, "Enrichment service is unavailable").WithCause(err))
+		return
+	}
+
+	utils.SendSuccess(c, gin.H{
+		"user":    h.userResponse(c, user),
+		"profile": profile,
+	})
+}
+
+func (h *UserHandler) CreateUser(c *gin.Context) {
+	var req models.CreateUserRequest
+	if apiErr := bindNormalizedJSON(c, &req); apiErr != nil {
+		_ = c.Error(apiErr)
+		return
+	}
+
+	// The unique index on email rejects a taken email with a conflict
 	user, err := h.userRepo.Create(c.Request.Context(), req)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, models.ErrorResponse{
-			Success: false,
-			Error:   "Failed to create user",
-		})
+		_ = c.Error(middleware.FromError(err, "Failed to create user"))
 		return
 	}
 
-	c.JSON(http.StatusCreated, models.SuccessResponse{
-		Success: true,
-		Message: "User created successfully",
-		Data:    user.ToResponse(),
+	h.publish(c, events.UserCreated, user.ID)
+	h.enqueueWelcome(c, user)
+	utils.SendCreated(c, h.userResponse(c, user), "User created successfully")
+}
+
+// enqueueWelcome schedules the welcome notification for a new user; a full
+// queue is logged rather than failing the request
+func (h *UserHandler) enqueueWelcome(c *gin.Context, user *models.User) {
+	if h.jobs == nil || h.notifier == nil {
+		return
+	}
+	userID, name, email := user.ID, user.Name, user.Email
+	err := h.jobs.Enqueue(c.Request.Context(), "user.welcome", func(ctx context.Context) error {
+		return h.notifier.SendWelcome(ctx, name, email)
 	})
+	if err != nil {
+		logging.LogWarn(c.Request.Context(), "Failed to enqueue welcome job", map[string]interface{}{
+			"user_id": userID,
+			"error":   err.Error(),
+		})
+	}
 }
 
-// UpdateUser handles PUT /api/users/:id
+// UpdateUser handles PUT /api/users/:id. The update must name the version it
+// is based on, in the If-Match header or the version field
 func (h *UserHandler) UpdateUser(c *gin.Context) {
 	id, err := strconv.Atoi(c.Param("id"))
 	if err != nil {
-		c.JSON(http.StatusBadRequest, models.ErrorResponse{
-			Success: false,
-			Error:   "Invalid user ID",
-		})
+		_ = c.Error(middleware.BadRequestError("Invalid user ID"))
 		return
 	}
 
 	var req models.UpdateUserRequest
-	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, models.ErrorResponse{
-			Success: false,
-			Error:   "Invalid request data: " + err.Error(),
-		})
+	if apiErr := bindNormalizedJSON(c, &req); apiErr != nil {
+		_ = c.Error(apiErr)
 		return
 	}
 
-	// Check if email already exists (if email is being updated)
-	if req.Email != nil {
-		existingUser, _ := h.userRepo.GetByEmail(c.Request.Context(), *req.Email)
-		if existingUser != nil && existingUser.ID != id {
-			c.JSON(http.StatusConflict, models.ErrorResponse{
-				Success: false,
-				Error:   "Email already exists",
-			})
-			return
-		}
+	version, ifMatch, apiErr := updateVersion(c, req.Version)
+	if apiErr != nil {
+		_ = c.Error(apiErr)
+		return
 	}
+	req.Version = version
 
 	user, err := h.userRepo.Update(c.Request.Context(), id, req)
 	if err != nil {
-		if strings.Contains(err.Error(), "not found") {
-			c.JSON(http.StatusNotFound, models.ErrorResponse{
-				Success: false,
-				Error:   "User not found",
-			})
+		_ = c.Error(h.updateError(c, err, ifMatch))
+		return
+	}
+
+	h.publish(c, events.UserUpdated, user.ID)
+	utils.SendSuccess(c, h.userResponse(c, user), "User updated successfully")
+}
+
+// updateVersion returns the version of the user an update is based on, from
+// the If-Match header, which holds it as an entity tag such as "3", or from
+// bodyVersion. ifMatch reports whether the header named it. If-Match: *
+// matches any version and returns nil; an update naming no version at all is
+// rejected with 428 Precondition Required
+func updateVersion(c *gin.Context, bodyVersion *int) (version *int, ifMatch bool, apiErr *middleware.APIError) {
+	switch value := strings.TrimSpace(c.GetHeader("If-Match")); value {
+	case "":
+		if bodyVersion == nil {
+			return nil, false, middleware.NewAPIError(http.StatusPreconditionRequired, models.ErrCodePreconditionRequired,
+				"An If-Match header or a version is required")
+		}
+		return bodyVersion, false, nil
+	case "*":
+		return bodyVersion, false, nil
+	default:
+		n, err := strconv.Atoi(strings.Trim(value, `"`))
+		if err != nil || n < 1 {
+			return nil, false, middleware.BadRequestError(`If-Match must be a user version such as "3"`)
+		}
+		if bodyVersion != nil && *bodyVersion != n {
+			return nil, false, middleware.BadRequestError("If-Match and version name different versions")
+		}
+		return &n, true, nil
+	}
+}
+
+// updateError reports a failed update. Version mismatches are counted; they
+// are 412 Precondition Failed when If-Match named the version and 409
+// Conflict when the body did
+func (h *UserHandler) updateError(c *gin.Context, err error, ifMatch bool) *middleware.APIError {
+	if !errors.Is(err, repository.ErrVersionMismatch) {
+		return middleware.FromError(err, "Failed to update user")
+	}
+
+	precondition := "body"
+	if ifMatch {
+		precondition = "if_match"
+	}
+	h.conflicts.Add(c.Request.Context(), 1, metric.WithAttributes(attribute.String("precondition", precondition)))
+	middleware.AddSpanEvent(c, "user_update_conflict", attribute.String("precondition", precondition))
+
+	if ifMatch {
+		return middleware.NewAPIError(http.StatusPreconditionFailed, models.ErrCodePreconditionFailed,
+			"User was modified by another request").WithCause(err)
+	}
+	return middleware.FromError(err, "Failed to update user")
+}
+
+// patchableUserFields are the members a merge patch may change; the rest of
+// the representation is read-only, except version, which names the version the
+// patch is based on like in a PUT
+var patchableUserFields = map[string]bool{"name": true, "email": true, "bio": true}
+
+// PatchUser handles PATCH /api/users/:id with an RFC 7386 merge patch. The
+// patch is applied to the current user and the result is validated like a
+// create request, so clearing a required field is rejected. Like a PUT, the
+// patch must name the version it is based on
+func (h *UserHandler) PatchUser(c *gin.Context) {
+	span := trace.SpanFromContext(c.Request.Context())
+	span.SetAttributes(
+		attribute.String("handler", "PatchUser"),
+		attribute.String("operation", "patch_user"),
+	)
+
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		_ = c.Error(middleware.BadRequestError("Invalid user ID"))
+		return
+	}
+
+	// Plain JSON is accepted too, since most clients send it by default
+	if contentType := c.ContentType(); contentType != utils.MergePatchContentType && contentType != gin.MIMEJSON {
+		_ = c.Error(middleware.NewAPIError(http.StatusUnsupportedMediaType, models.ErrCodeInvalidRequest,
+			"Content-Type must be "+utils.MergePatchContentType))
+		return
+	}
+
+	var patch map[string]any
+	if err := json.NewDecoder(c.Request.Body).Decode(&patch); err != nil || patch == nil {
+		_ = c.Error(middleware.BadRequestError("Request body must be a JSON object"))
+		return
+	}
+	var bodyVersion *int
+	if value, ok := patch["version"]; ok {
+		n, ok := value.(float64)
+		if !ok || n < 1 || n != math.Trunc(n) {
+			_ = c.Error(middleware.BadRequestError("version must be a positive integer"))
 			return
 		}
+		v := int(n)
+		bodyVersion = &v
+		delete(patch, "version")
+	}
+	for name := range patch {
+		if !patchableUserFields[name] {
+			_ = c.Error(middleware.BadRequestError(fmt.Sprintf("Field %q cannot be patched", name)))
+			return
+		}
+	}
 
-		c.JSON(http.StatusInternalServerError, models.ErrorResponse{
-			Success: false,
-			Error:   "Failed to update user",
-		})
+	version, ifMatch, apiErr := updateVersion(c, bodyVersion)
+	if apiErr != nil {
+		_ = c.Error(apiErr)
 		return
 	}
 
-	c.JSON(http.StatusOK, models.SuccessResponse{
-		Success: true,
-		Message: "User updated successfully",
-		Data:    user.ToResponse(),
-	})
+	current, err := h.userRepo.GetByID(c.Request.Context(), id)
+	if err != nil {
+		_ = c.Error(middleware.FromError(err, "Failed to retrieve user"))
+		return
+	}
+	if version != nil && *version != current.Version {
+		_ = c.Error(h.updateError(c, repository.ErrVersionMismatch, ifMatch))
+		return
+	}
+
+	target := map[string]any{"name": current.Name, "email": current.Email}
+	if current.Bio != nil {
+		target["bio"] = *current.Bio
+	}
+	merged, err := json.Marshal(utils.MergePatch(target, patch))
+	if err != nil {
+		_ = c.Error(middleware.InternalError("Failed to apply patch", err))
+		return
+	}
+
+	var result models.CreateUserRequest
+	if err := json.Unmarshal(merged, &result); err != nil {
+		_ = c.Error(bindError(c, err))
+		return
+	}
+	result.Normalize()
+	if err := binding.Validator.ValidateStruct(&result); err != nil {
+		_ = c.Error(bindError(c, err))
+		return
+	}
+
+	req, changed := userChanges(current, result)
+	req.Version = version
+	span.SetAttributes(attribute.StringSlice("user.patch.changed_fields", changed))
+	if len(changed) == 0 {
+		utils.SendSuccess(c, h.userResponse(c, current), "User unchanged")
+		return
+	}
+
+	user, err := h.userRepo.Update(c.Request.Context(), id, req)
+	if err != nil {
+		_ = c.Error(h.updateError(c, err, ifMatch))
+		return
+	}
+
+	h.publish(c, events.UserUpdated, user.ID)
+	utils.SendSuccess(c, h.userResponse(c, user), "User updated successfully")
+}
+
+// userChanges builds the update that turns current into result, along with
+// the names of the fields it changes
+func userChanges(current *models.User, result models.CreateUserRequest) (models.UpdateUserRequest, []string) {
+	var req models.UpdateUserRequest
+	changed := []string{}
+	if result.Name != current.Name {
+		req.Name = &result.Name
+		changed = append(changed, "name")
+	}
+	if result.Email != current.Email {
+		req.Email = &result.Email
+		changed = append(changed, "email")
+	}
+	switch {
+	case result.Bio == nil && current.Bio != nil:
+		req.Bio = models.Null[string]()
+		changed = append(changed, "bio")
+	case result.Bio != nil && (current.Bio == nil || *result.Bio != *current.Bio):
+		req.Bio = models.NewNullable(*result.Bio)
+		changed = append(changed, "bio")
+	}
+	return req, changed
 }
 
 // DeleteUser handles DELETE /api/users/:id
 func (h *UserHandler) DeleteUser(c *gin.Context) {
 	id, err := strconv.Atoi(c.Param("id"))
 	if err != nil {
-		c.JSON(http.StatusBadRequest, models.ErrorResponse{
-			Success: false,
-			Error:   "Invalid user ID",
-		})
+		_ = c.Error(middleware.BadRequestError("Invalid user ID"))
 		return
 	}
 
 	err = h.userRepo.Delete(c.Request.Context(), id)
 	if err != nil {
-		if strings.Contains(err.Error(), "not found") {
-			c.JSON(http.StatusNotFound, models.ErrorResponse{
-				Success: false,
-				Error:   "User not found",
-			})
-			return
-		}
+		_ = c.Error(middleware.FromError(err, "Failed to delete user"))
+		return
+	}
+
+	h.publish(c, events.UserDeleted, id)
 
-		c.JSON(http.StatusInternalServerError, models.ErrorResponse{
-			Success: false,
-			Error:   "Failed to delete user",
+	utils.SendNoContent(c)
+}
+
+// publish emits a user change event; the change is already committed, so a
+// broker failure is logged rather than returned to the client
+func (h *UserHandler) publish(c *gin.Context, eventType events.EventType, userID int) {
+	ctx := c.Request.Context()
+	err := h.events.PublishUserEvent(ctx, events.UserEvent{
+		Type:       eventType,
+		UserID:     userID,
+		Actor:      auth.Actor(ctx),
+		OccurredAt: time.Now().UTC(),
+	})
+	if err != nil {
+		logging.LogError(ctx, err, "Failed to publish user event", map[string]interface{}{
+			"event_type": string(eventType),
+			"user_id":    userID,
 		})
-		return
 	}
+}
+
+// userResponse maps a single user, see userResponses
+func (h *UserHandler) userResponse(c *gin.Context, user *models.User) any {
+	return h.userResponses(c, []models.User{*user})[0]
+}
+
+// userResponses maps users with the handler's API version. Audit fields are
+// included only for admin principals, and other callers get masked emails
+// while the mask-user-email flag is on.
+func (h *UserHandler) userResponses(c *gin.Context, users []models.User) []any {
+	ctx := c.Request.Context()
+	admin := auth.IsAdmin(ctx)
+	if !admin && features.Enabled(ctx, features.MaskUserEmail, false) {
+		masked := make([]models.User, len(users))
+		for i, user := range users {
+			user.Email = maskEmail(user.Email)
+			masked[i] = user
+		}
+		users = masked
+	}
+	return dto.Users(responseMapper(c, h.mapper), users, admin)
+}
 
-	c.Status(http.StatusNoContent)
+// maskEmail keeps the first character of the local part and the domain. The
+// character is a whole rune, so a multi-byte one is not cut into invalid
+// UTF-8
+func maskEmail(email string) string {
+	local, domain, ok := strings.Cut(email, "@")
+	if !ok || local == "" {
+		return "***"
+	}
+	_, size := utf8.DecodeRuneInString(local)
+	return local[:size] + "***@" + domain
+}
+
+// bindError reports a request body that failed to bind, see
+// middleware.BindError
+func bindError(c *gin.Context, err error) *middleware.APIError {
+	return middleware.BindError(err, c.GetHeader("Accept-Language"))
+}
+
+// bindNormalizedJSON binds the JSON body into req and validates it again
+// once Normalize has trimmed it, so that whitespace-only text fails the
+// required and length rules instead of being stored empty
+func bindNormalizedJSON(c *gin.Context, req interface{ Normalize() }) *middleware.APIError {
+	if err := c.ShouldBindJSON(req); err != nil {
+		return bindError(c, err)
+	}
+	req.Normalize()
+	if err := binding.Validator.ValidateStruct(req); err != nil {
+		return bindError(c, err)
+	}
+	return nil
 }
@@ -53,13 +53,14 @@ func TestGetUser_StoreError(t *testing.T) {
 
 func TestUpdateUser_StoreError(t *testing.T) {
 	store := newMockUserStore()
-	store.users = []models.User{{ID: 1, Name: "Test", Email: "test@example.com"}}
+	store.users = []models.User{{ID: 1, Name: "Test", Email: "test@example.com", Version: 1}}
 	store.failOnCall["Update"] = true
 
 	handler := NewUserHandler(store)
 	r := setupRouter(handler)
 
-	upd := models.UpdateUserRequest{Name: func() *string { s := "New Name"; return &s }()}
+	version := 1
+	upd := models.UpdateUserRequest{Name: func() *string { s := "New Name"; return &s }(), Version: &version}
 	b, _ := json.Marshal(upd)
 	w := httptest.NewRecorder()
 	req := httptest.NewRequest(http.MethodPut, "/api/users/1", bytes.NewReader(b))
@@ -7,18 +7,37 @@ import (
 	"fmt"
 	"net/http"
 	"net/http/httptest"
+	"strings"
 	"testing"
+	"time"
 
+	"arquivolivre.com.br/otel/internal/auth"
+	"arquivolivre.com.br/otel/internal/dto"
+	"arquivolivre.com.br/otel/internal/enrichment"
+	"arquivolivre.com.br/otel/internal/events"
+	"arquivolivre.com.br/otel/internal/features"
+	"arquivolivre.com.br/otel/internal/jobs"
+	"arquivolivre.com.br/otel/internal/middleware"
 	"arquivolivre.com.br/otel/internal/models"
+	"arquivolivre.com.br/otel/internal/repository"
+	"arquivolivre.com.br/otel/pkg/apperrors"
 
 	"github.com/gin-gonic/gin"
+	"github.com/open-feature/go-sdk/openfeature"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/metric/metricdata"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
 )
 
 type mockUserStore struct {
 	users      []models.User
 	nextID     int
 	failOnCall map[string]bool
+	lastFilter models.UserFilter
 }
 
 func newMockUserStore() *mockUserStore {
@@ -29,7 +48,8 @@ func newMockUserStore() *mockUserStore {
 	}
 }
 
-func (m *mockUserStore) GetAll(_ context.Context, limit, offset int) ([]models.User, error) {
+func (m *mockUserStore) GetAll(_ context.Context, filter models.UserFilter, limit, offset int) ([]models.User, error) {
+	m.lastFilter = filter
 	if m.failOnCall["GetAll"] {
 		return nil, fmt.Errorf("mock error")
 	}
@@ -43,6 +63,20 @@ func (m *mockUserStore) GetAll(_ context.Context, limit, offset int) ([]models.U
 	return m.users[offset:end], nil
 }
 
+func (m *mockUserStore) Stream(_ context.Context, filter models.UserFilter, fn func(*models.User) error) error {
+	m.lastFilter = filter
+	if m.failOnCall["Stream"] {
+		return fmt.Errorf("mock error")
+	}
+	for i := range m.users {
+		u := m.users[i]
+		if err := fn(&u); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 func (m *mockUserStore) GetByID(_ context.Context, id int) (*models.User, error) {
 	if m.failOnCall["GetByID"] {
 		return nil, fmt.Errorf("mock error")
@@ -53,36 +87,68 @@ func (m *mockUserStore) GetByID(_ context.Context, id int) (*models.User, error)
 			return &u, nil
 		}
 	}
-	return nil, fmt.Errorf("user not found")
+	return nil, apperrors.NotFound("user not found")
 }
 
+// Create rejects a taken email like the unique index on users.email
 func (m *mockUserStore) Create(_ context.Context, req models.CreateUserRequest) (*models.User, error) {
-	u := models.User{ID: m.nextID, Name: req.Name, Email: req.Email, Bio: req.Bio}
+	if m.emailTaken(req.Email, 0) {
+		return nil, apperrors.Conflict("email already exists")
+	}
+	u := models.User{ID: m.nextID, Name: req.Name, Email: req.Email, Bio: req.Bio, Version: 1}
 	m.nextID++
 	m.users = append(m.users, u)
 	return &u, nil
 }
 
+func (m *mockUserStore) CreateBatch(ctx context.Context, reqs []models.CreateUserRequest) ([]repository.BatchResult, error) {
+	if m.failOnCall["CreateBatch"] {
+		return nil, fmt.Errorf("mock error")
+	}
+	results := make([]repository.BatchResult, len(reqs))
+	for i, req := range reqs {
+		results[i].User, results[i].Err = m.Create(ctx, req)
+	}
+	return results, nil
+}
+
+// emailTaken reports whether a user other than exceptID has email
+func (m *mockUserStore) emailTaken(email string, exceptID int) bool {
+	for _, u := range m.users {
+		if u.Email == email && u.ID != exceptID {
+			return true
+		}
+	}
+	return false
+}
+
 func (m *mockUserStore) Update(_ context.Context, id int, req models.UpdateUserRequest) (*models.User, error) {
 	if m.failOnCall["Update"] {
 		return nil, fmt.Errorf("mock error")
 	}
+	if req.Email != nil && m.emailTaken(*req.Email, id) {
+		return nil, apperrors.Conflict("email already exists")
+	}
 	for i := range m.users {
 		if m.users[i].ID == id {
+			if req.Version != nil && *req.Version != m.users[i].Version {
+				return nil, repository.ErrVersionMismatch
+			}
+			m.users[i].Version++
 			if req.Name != nil {
 				m.users[i].Name = *req.Name
 			}
 			if req.Email != nil {
 				m.users[i].Email = *req.Email
 			}
-			if req.Bio != nil {
-				m.users[i].Bio = *req.Bio
+			if req.Bio.Set {
+				m.users[i].Bio = req.Bio.Ptr()
 			}
 			u := m.users[i]
 			return &u, nil
 		}
 	}
-	return nil, fmt.Errorf("user not found")
+	return nil, apperrors.NotFound("user not found")
 }
 
 func (m *mockUserStore) Delete(_ context.Context, id int) error {
@@ -95,10 +161,10 @@ func (m *mockUserStore) Delete(_ context.Context, id int) error {
 			return nil
 		}
 	}
-	return fmt.Errorf("user not found")
+	return apperrors.NotFound("user not found")
 }
 
-func (m *mockUserStore) Count(_ context.Context) (int, error) {
+func (m *mockUserStore) Count(_ context.Context, _ models.UserFilter) (int, error) {
 	if m.failOnCall["Count"] {
 		return 0, fmt.Errorf("mock error")
 	}
@@ -111,18 +177,23 @@ func (m *mockUserStore) GetByEmail(_ context.Context, email string) (*models.Use
 			return &u, nil
 		}
 	}
-	return nil, fmt.Errorf("user not found")
+	return nil, apperrors.NotFound("user not found")
 }
 
 func setupRouter(handler *UserHandler) *gin.Engine {
 	gin.SetMode(gin.TestMode)
 	r := gin.New()
+	r.Use(middleware.ErrorHandler())
 	api := r.Group("/api")
 	users := api.Group("/users")
 	users.GET("", handler.GetUsers)
+	users.GET("/export", handler.ExportUsers)
 	users.POST("", handler.CreateUser)
+	users.POST("/bulk", handler.BulkCreateUsers)
 	users.GET(":id", handler.GetUser)
+	users.GET(":id/profile", handler.GetUserProfile)
 	users.PUT(":id", handler.UpdateUser)
+	users.PATCH(":id", handler.PatchUser)
 	users.DELETE(":id", handler.DeleteUser)
 	return r
 }
@@ -132,7 +203,8 @@ func TestCreateAndGetUser(t *testing.T) {
 	handler := NewUserHandler(store)
 	r := setupRouter(handler)
 
-	body := models.CreateUserRequest{Name: "Alice", Email: "alice@example.com", Bio: "bio"}
+	bio := "bio"
+	body := models.CreateUserRequest{Name: "Alice", Email: "alice@example.com", Bio: &bio}
 	b, _ := json.Marshal(body)
 	w := httptest.NewRecorder()
 	req := httptest.NewRequest(http.MethodPost, "/api/users", bytes.NewReader(b))
@@ -146,6 +218,93 @@ func TestCreateAndGetUser(t *testing.T) {
 	assert.Equal(t, http.StatusOK, w2.Code)
 }
 
+func TestGetUsersFilters(t *testing.T) {
+	tests := []struct {
+		name  string
+		query string
+		want  models.UserFilter
+		code  int
+	}{
+		{name: "none", query: "", code: http.StatusOK},
+		{
+			name:  "query and email",
+			query: "q=%20ann%20&email=Ann@Example.com",
+			want:  models.UserFilter{Query: "ann", Email: "ann@example.com"},
+			code:  http.StatusOK,
+		},
+		{
+			name:  "created range",
+			query: "created_after=2024-01-01&created_before=2024-02-01T12:00:00Z",
+			want: models.UserFilter{
+				CreatedAfter:  time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC),
+				CreatedBefore: time.Date(2024, 2, 1, 12, 0, 0, 0, time.UTC),
+			},
+			code: http.StatusOK,
+		},
+		{name: "invalid created_after", query: "created_after=yesterday", code: http.StatusBadRequest},
+		{name: "invalid created_before", query: "created_before=2024-13-01", code: http.StatusBadRequest},
+		{name: "empty range", query: "created_after=2024-02-01&created_before=2024-01-01", code: http.StatusBadRequest},
+		{name: "query too long", query: "q=" + strings.Repeat("a", maxFilterQueryLength+1), code: http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := newMockUserStore()
+			r := setupRouter(NewUserHandler(store))
+
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/users?"+tt.query, nil))
+			require.Equal(t, tt.code, w.Code, w.Body.String())
+			if tt.code == http.StatusOK {
+				assert.Equal(t, tt.want, store.lastFilter)
+			}
+		})
+	}
+}
+
+func TestGetUserAuditFieldsForAdmins(t *testing.T) {
+	store := newMockUserStore()
+	store.users = []models.User{{ID: 1, Name: "Ann", Email: "ann@example.com", CreatedBy: "alice", UpdatedBy: "bob"}}
+	handler := NewUserHandler(store)
+
+	for _, tc := range []struct {
+		roles     []string
+		wantAudit bool
+	}{
+		{roles: nil, wantAudit: false},
+		{roles: []string{auth.RoleAdmin}, wantAudit: true},
+	} {
+		r := gin.New()
+		r.Use(func(c *gin.Context) {
+			ctx := auth.WithPrincipal(c.Request.Context(), auth.Principal{Subject: "carol", Roles: tc.roles})
+			c.Request = c.Request.WithContext(ctx)
+		})
+		r.GET("/api/users/:id", handler.GetUser)
+
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/users/1", nil))
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, tc.wantAudit, strings.Contains(w.Body.String(), `"created_by":"alice"`), w.Body.String())
+	}
+}
+
+func TestGetUserV2Mapper(t *testing.T) {
+	store := newMockUserStore()
+	store.users = []models.User{{ID: 1, Name: "Ann", Email: "ann@example.com"}}
+	handler := NewUserHandler(store).WithMapper(dto.V2)
+	r := setupRouter(handler)
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/users/1", nil))
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), `"display_name":"Ann"`)
+	assert.NotContains(t, w.Body.String(), `"name":"Ann"`)
+
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/users", nil))
+	assert.Contains(t, w.Body.String(), `"display_name":"Ann"`)
+}
+
 func TestGetUserNotFound(t *testing.T) {
 	store := newMockUserStore()
 	handler := NewUserHandler(store)
@@ -165,7 +324,8 @@ func TestUpdateAndDeleteUser(t *testing.T) {
 	r := setupRouter(handler)
 
 	newName := "Bobby"
-	upd := models.UpdateUserRequest{Name: &newName}
+	version := 1
+	upd := models.UpdateUserRequest{Name: &newName, Version: &version}
 	b, _ := json.Marshal(upd)
 	w := httptest.NewRecorder()
 	req := httptest.NewRequest(http.MethodPut, "/api/users/1", bytes.NewReader(b))
@@ -191,6 +351,342 @@ func TestCreateUserInvalidPayload(t *testing.T) {
 	assert.Equal(t, http.StatusBadRequest, w.Code)
 }
 
+func TestCreateUserValidationDetails(t *testing.T) {
+	store := newMockUserStore()
+	handler := NewUserHandler(store)
+	r := setupRouter(handler)
+
+	b, _ := json.Marshal(models.CreateUserRequest{Name: "A", Email: "a@mailinator.com"})
+	w := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodPost, "/api/users", bytes.NewReader(b))
+	req.Header.Set("Content-Type", "application/json")
+	r.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	var resp models.ErrorResponse
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	assert.Equal(t, "Validation failed", resp.Error)
+	assert.Equal(t, models.ErrCodeValidationFailed, resp.Code)
+	assert.ElementsMatch(t, []string{"name", "email"}, []string{resp.Details[0].Field, resp.Details[1].Field})
+	assert.Empty(t, store.users)
+}
+
+func TestCreateUserValidationMessagesFollowAcceptLanguage(t *testing.T) {
+	handler := NewUserHandler(newMockUserStore())
+	r := setupRouter(handler)
+
+	w := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodPost, "/api/users", bytes.NewReader([]byte(`{"name":"Ann","email":"nope"}`)))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Accept-Language", "pt-BR")
+	r.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	var resp models.ErrorResponse
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	if assert.Len(t, resp.Details, 1) {
+		assert.Equal(t, "email deve ser um endereço válido", resp.Details[0].Message)
+	}
+}
+
+func TestCreateUserNormalizesInput(t *testing.T) {
+	store := newMockUserStore()
+	handler := NewUserHandler(store)
+	r := setupRouter(handler)
+
+	b, _ := json.Marshal(models.CreateUserRequest{Name: "  Jose\u0301  ", Email: "Jose@Example.COM"})
+	w := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodPost, "/api/users", bytes.NewReader(b))
+	req.Header.Set("Content-Type", "application/json")
+	r.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusCreated, w.Code)
+	assert.Equal(t, "Jos\u00e9", store.users[0].Name)
+	assert.Equal(t, "jose@example.com", store.users[0].Email)
+}
+
+func TestUserWhitespaceOnlyFields(t *testing.T) {
+	store := newMockUserStore()
+	_, _ = store.Create(context.Background(), models.CreateUserRequest{Name: "Bob", Email: "bob@example.com"})
+	r := setupRouter(NewUserHandler(store))
+
+	for _, tc := range []struct {
+		method, path, body, field string
+	}{
+		{http.MethodPost, "/api/users", `{"name":"   ","email":"ann@example.com"}`, "name"},
+		{http.MethodPost, "/api/users", `{"name":"Ann","email":"   "}`, "email"},
+		{http.MethodPut, "/api/users/1", `{"name":" \t ","version":1}`, "name"},
+		{http.MethodPut, "/api/users/1", `{"email":"   ","version":1}`, "email"},
+	} {
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest(tc.method, tc.path, bytes.NewReader([]byte(tc.body)))
+		req.Header.Set("Content-Type", "application/json")
+		r.ServeHTTP(w, req)
+		require.Equal(t, http.StatusBadRequest, w.Code, tc.body)
+		var resp models.ErrorResponse
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+		if assert.Len(t, resp.Details, 1, tc.body) {
+			assert.Equal(t, tc.field, resp.Details[0].Field)
+		}
+	}
+	require.Len(t, store.users, 1)
+	assert.Equal(t, "Bob", store.users[0].Name)
+	assert.Equal(t, "bob@example.com", store.users[0].Email)
+}
+
+type recordingEnqueuer struct {
+	names []string
+	err   error
+}
+
+func (e *recordingEnqueuer) Enqueue(ctx context.Context, name string, fn jobs.Func) error {
+	if e.err != nil {
+		return e.err
+	}
+	e.names = append(e.names, name)
+	return fn(ctx)
+}
+
+type recordingNotifier struct {
+	sent []string
+}
+
+func (n *recordingNotifier) SendWelcome(_ context.Context, _, email string) error {
+	n.sent = append(n.sent, email)
+	return nil
+}
+
+func TestCreateUserEnqueuesWelcomeJob(t *testing.T) {
+	queue := &recordingEnqueuer{}
+	notifier := &recordingNotifier{}
+	r := setupRouter(NewUserHandler(newMockUserStore()).WithJobs(queue).WithNotifier(notifier))
+
+	b, _ := json.Marshal(models.CreateUserRequest{Name: "Ann", Email: "ann@example.com"})
+	w := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodPost, "/api/users", bytes.NewReader(b))
+	req.Header.Set("Content-Type", "application/json")
+	r.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusCreated, w.Code)
+	assert.Equal(t, []string{"user.welcome"}, queue.names)
+	assert.Equal(t, []string{"ann@example.com"}, notifier.sent)
+
+	// A full queue must not fail the request
+	queue.err = jobs.ErrQueueFull
+	b, _ = json.Marshal(models.CreateUserRequest{Name: "Bea", Email: "bea@example.com"})
+	w = httptest.NewRecorder()
+	req = httptest.NewRequest(http.MethodPost, "/api/users", bytes.NewReader(b))
+	req.Header.Set("Content-Type", "application/json")
+	r.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusCreated, w.Code)
+}
+
+type recordingPublisher struct {
+	events []events.UserEvent
+}
+
+func (p *recordingPublisher) PublishUserEvent(_ context.Context, event events.UserEvent) error {
+	p.events = append(p.events, event)
+	return nil
+}
+
+func (p *recordingPublisher) Close() error { return nil }
+
+func TestUserMutationsPublishEvents(t *testing.T) {
+	publisher := &recordingPublisher{}
+	r := setupRouter(NewUserHandler(newMockUserStore()).WithEvents(publisher))
+
+	b, _ := json.Marshal(models.CreateUserRequest{Name: "Ann", Email: "ann@example.com"})
+	req := httptest.NewRequest(http.MethodPost, "/api/users", bytes.NewReader(b))
+	req.Header.Set("Content-Type", "application/json")
+	r.ServeHTTP(httptest.NewRecorder(), req)
+
+	req = httptest.NewRequest(http.MethodPut, "/api/users/1", strings.NewReader(`{"name":"Anna","version":1}`))
+	req.Header.Set("Content-Type", "application/json")
+	r.ServeHTTP(httptest.NewRecorder(), req)
+
+	r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodDelete, "/api/users/1", nil))
+
+	require.Len(t, publisher.events, 3)
+	assert.Equal(t, events.UserCreated, publisher.events[0].Type)
+	assert.Equal(t, events.UserUpdated, publisher.events[1].Type)
+	assert.Equal(t, events.UserDeleted, publisher.events[2].Type)
+	assert.Equal(t, 1, publisher.events[2].UserID)
+	assert.Equal(t, auth.SystemActor, publisher.events[0].Actor)
+}
+
+func TestGetUserMasksEmailWhenFlagEnabled(t *testing.T) {
+	provider, err := features.NewProvider("", []string{"FEATURE_FLAG_MASK_USER_EMAIL=true"})
+	require.NoError(t, err)
+	require.NoError(t, features.Init(provider))
+	defer openfeature.Shutdown()
+
+	store := newMockUserStore()
+	store.users = []models.User{{ID: 1, Name: "Ann", Email: "ann@example.com"}}
+	r := setupRouter(NewUserHandler(store))
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/users", nil))
+	assert.Contains(t, w.Body.String(), `"email":"a***@example.com"`)
+
+	admin := httptest.NewRequest(http.MethodGet, "/api/users/1", nil)
+	admin = admin.WithContext(auth.WithPrincipal(admin.Context(), auth.Principal{Subject: "root", Roles: []string{auth.RoleAdmin}}))
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, admin)
+	assert.Contains(t, w.Body.String(), `"email":"ann@example.com"`)
+}
+
+func TestMaskEmail(t *testing.T) {
+	assert.Equal(t, "j***@example.com", maskEmail("jane@example.com"))
+	assert.Equal(t, "é***@x.com", maskEmail("élodie@x.com"))
+	assert.Equal(t, "***", maskEmail("not-an-email"))
+}
+
+type stubEnricher struct {
+	err error
+}
+
+func (e stubEnricher) Enrich(_ context.Context, email string) (*enrichment.Profile, error) {
+	if e.err != nil {
+		return nil, e.err
+	}
+	profile := enrichment.Enrich(email)
+	return &profile, nil
+}
+
+func TestGetUserProfile(t *testing.T) {
+	store := newMockUserStore()
+	store.users = []models.User{{ID: 1, Name: "Ann", Email: "ann@example.com"}}
+
+	w := httptest.NewRecorder()
+	setupRouter(NewUserHandler(store)).ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/users/1/profile", nil))
+	assert.Equal(t, http.StatusServiceUnavailable, w.Code, "no enricher configured")
+
+	w = httptest.NewRecorder()
+	setupRouter(NewUserHandler(store).WithEnricher(stubEnricher{})).ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/users/1/profile", nil))
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), `"domain":"example.com"`)
+	assert.Contains(t, w.Body.String(), `"name":"Ann"`)
+
+	w = httptest.NewRecorder()
+	setupRouter(NewUserHandler(store).WithEnricher(stubEnricher{err: fmt.Errorf("timeout")})).ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/users/1/profile", nil))
+	assert.Equal(t, http.StatusServiceUnavailable, w.Code)
+	assert.Contains(t, w.Body.String(), "SERVICE_UNAVAILABLE")
+
+	w = httptest.NewRecorder()
+	setupRouter(NewUserHandler(store).WithEnricher(stubEnricher{})).ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/users/9/profile", nil))
+	assert.Equal(t, http.StatusNotFound, w.Code)
+}
+
+func TestUpdateUserValidationDetails(t *testing.T) {
+	store := newMockUserStore()
+	_, _ = store.Create(context.Background(), models.CreateUserRequest{Name: "Bob", Email: "bob@example.com"})
+	handler := NewUserHandler(store)
+	r := setupRouter(handler)
+
+	w := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodPut, "/api/users/1", bytes.NewReader([]byte(`{"bio":"`+strings.Repeat("x", 501)+`"}`)))
+	req.Header.Set("Content-Type", "application/json")
+	r.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	var resp models.ErrorResponse
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	if assert.Len(t, resp.Details, 1) {
+		assert.Equal(t, "bio", resp.Details[0].Field)
+		assert.Equal(t, "biotext", resp.Details[0].Rule)
+	}
+}
+
+func TestUpdateUserBioPatchSemantics(t *testing.T) {
+	bio := "original"
+	store := newMockUserStore()
+	store.users = []models.User{{ID: 1, Name: "Ann", Email: "ann@example.com", Bio: &bio, Version: 1}}
+	r := setupRouter(NewUserHandler(store))
+
+	put := func(body string) {
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodPut, "/api/users/1", bytes.NewReader([]byte(body)))
+		req.Header.Set("Content-Type", "application/json")
+		r.ServeHTTP(w, req)
+		assert.Equal(t, http.StatusOK, w.Code)
+	}
+
+	// An absent bio is left unchanged
+	put(`{"name":"Annie","version":1}`)
+	if assert.NotNil(t, store.users[0].Bio) {
+		assert.Equal(t, "original", *store.users[0].Bio)
+	}
+
+	// An explicit null clears it, and the response omits the field
+	put(`{"bio":null,"version":2}`)
+	assert.Nil(t, store.users[0].Bio)
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/users/1", nil))
+	assert.NotContains(t, w.Body.String(), `"bio"`)
+}
+
+func TestPatchUserMergePatch(t *testing.T) {
+	bio := "original"
+	store := newMockUserStore()
+	store.users = []models.User{
+		{ID: 1, Name: "Ann", Email: "ann@example.com", Bio: &bio, Version: 1},
+		{ID: 2, Name: "Bob", Email: "bob@example.com", Version: 1},
+	}
+	recorder := tracetest.NewSpanRecorder()
+	tracer := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)).Tracer("test")
+	r := gin.New()
+	r.Use(func(c *gin.Context) {
+		ctx, span := tracer.Start(c.Request.Context(), "request")
+		defer span.End()
+		c.Request = c.Request.WithContext(ctx)
+		c.Next()
+	})
+	r.Use(middleware.ErrorHandler())
+	r.PATCH("/api/users/:id", NewUserHandler(store).PatchUser)
+
+	patch := func(body, contentType string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodPatch, "/api/users/1", bytes.NewReader([]byte(body)))
+		req.Header.Set("Content-Type", contentType)
+		req.Header.Set("If-Match", fmt.Sprintf(`"%d"`, store.users[0].Version))
+		r.ServeHTTP(w, req)
+		return w
+	}
+
+	// Null removes the bio; absent members are left unchanged
+	w := patch(`{"name":" Annie ","bio":null}`, "application/merge-patch+json")
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	assert.Equal(t, "Annie", store.users[0].Name)
+	assert.Equal(t, "ann@example.com", store.users[0].Email)
+	assert.Nil(t, store.users[0].Bio)
+
+	spans := recorder.Ended()
+	require.NotEmpty(t, spans)
+	var changed []string
+	for _, kv := range spans[len(spans)-1].Attributes() {
+		if kv.Key == "user.patch.changed_fields" {
+			changed = kv.Value.AsStringSlice()
+		}
+	}
+	assert.ElementsMatch(t, []string{"name", "bio"}, changed)
+
+	// The result is validated as a whole, so a required member cannot be removed
+	w = patch(`{"name":null}`, "application/merge-patch+json")
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	var resp models.ErrorResponse
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	if assert.Len(t, resp.Details, 1) {
+		assert.Equal(t, "name", resp.Details[0].Field)
+		assert.Equal(t, "required", resp.Details[0].Rule)
+	}
+
+	assert.Equal(t, http.StatusBadRequest, patch(`{"id":7}`, "application/merge-patch+json").Code)
+	assert.Equal(t, http.StatusBadRequest, patch(`["name"]`, "application/merge-patch+json").Code)
+	assert.Equal(t, http.StatusConflict, patch(`{"email":"bob@example.com"}`, "application/json").Code)
+	assert.Equal(t, http.StatusUnsupportedMediaType, patch(`{"name":"Ann"}`, "text/plain").Code)
+	assert.Equal(t, "Annie", store.users[0].Name)
+}
+
 func TestCreateUserConflict(t *testing.T) {
 	store := newMockUserStore()
 	_, _ = store.Create(context.Background(), models.CreateUserRequest{Name: "X", Email: "x@example.com"})
@@ -198,7 +694,7 @@ func TestCreateUserConflict(t *testing.T) {
 	handler := NewUserHandler(store)
 	r := setupRouter(handler)
 
-	body := models.CreateUserRequest{Name: "Y", Email: "x@example.com"}
+	body := models.CreateUserRequest{Name: "Yves", Email: "x@example.com"}
 	b, _ := json.Marshal(body)
 	w := httptest.NewRecorder()
 	req := httptest.NewRequest(http.MethodPost, "/api/users", bytes.NewReader(b))
@@ -216,7 +712,8 @@ func TestUpdateUserEmailConflict(t *testing.T) {
 	r := setupRouter(handler)
 
 	newEmail := "a@example.com" // conflicts with id=1
-	upd := models.UpdateUserRequest{Email: &newEmail}
+	version := 1
+	upd := models.UpdateUserRequest{Email: &newEmail, Version: &version}
 	b, _ := json.Marshal(upd)
 	w := httptest.NewRecorder()
 	req := httptest.NewRequest(http.MethodPut, "/api/users/2", bytes.NewReader(b))
@@ -225,6 +722,132 @@ func TestUpdateUserEmailConflict(t *testing.T) {
 	assert.Equal(t, http.StatusConflict, w.Code)
 }
 
+func TestUpdateUserVersionPreconditions(t *testing.T) {
+	reader := sdkmetric.NewManualReader()
+	prevMP := otel.GetMeterProvider()
+	otel.SetMeterProvider(sdkmetric.NewMeterProvider(sdkmetric.WithReader(reader)))
+	defer otel.SetMeterProvider(prevMP)
+
+	store := newMockUserStore()
+	_, _ = store.Create(context.Background(), models.CreateUserRequest{Name: "Ann", Email: "ann@example.com"})
+	r := setupRouter(NewUserHandler(store))
+
+	do := func(method, body, ifMatch string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest(method, "/api/users/1", strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		if ifMatch != "" {
+			req.Header.Set("If-Match", ifMatch)
+		}
+		r.ServeHTTP(w, req)
+		return w
+	}
+
+	w := do(http.MethodPut, `{"name":"Anna"}`, "")
+	assert.Equal(t, http.StatusPreconditionRequired, w.Code)
+	assert.Contains(t, w.Body.String(), models.ErrCodePreconditionRequired)
+	assert.Equal(t, http.StatusPreconditionRequired, do(http.MethodPatch, `{"name":"Anna"}`, "").Code)
+	assert.Equal(t, http.StatusBadRequest, do(http.MethodPut, `{"name":"Anna"}`, `W/"1"`).Code)
+	assert.Equal(t, http.StatusBadRequest, do(http.MethodPut, `{"name":"Anna","version":2}`, `"1"`).Code)
+
+	w = do(http.MethodPut, `{"name":"Anna"}`, `"1"`)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	assert.Contains(t, w.Body.String(), `"version":2`)
+
+	// Both requests were based on version 1, which is no longer current
+	w = do(http.MethodPut, `{"name":"Annie"}`, `"1"`)
+	assert.Equal(t, http.StatusPreconditionFailed, w.Code)
+	assert.Contains(t, w.Body.String(), models.ErrCodePreconditionFailed)
+	assert.Equal(t, http.StatusConflict, do(http.MethodPut, `{"name":"Annie","version":1}`, "").Code)
+	assert.Equal(t, http.StatusPreconditionFailed, do(http.MethodPatch, `{"name":"Annie"}`, `"1"`).Code)
+	assert.Equal(t, http.StatusConflict, do(http.MethodPatch, `{"name":"Annie","version":1}`, "").Code)
+	assert.Equal(t, "Anna", store.users[0].Name)
+
+	assert.Equal(t, http.StatusOK, do(http.MethodPatch, `{"name":"Annie"}`, `*`).Code)
+	assert.Equal(t, "Annie", store.users[0].Name)
+
+	var rm metricdata.ResourceMetrics
+	require.NoError(t, reader.Collect(context.Background(), &rm))
+	conflicts := map[string]int64{}
+	for _, sm := range rm.ScopeMetrics {
+		for _, m := range sm.Metrics {
+			if m.Name != "user.update.conflicts" {
+				continue
+			}
+			for _, dp := range m.Data.(metricdata.Sum[int64]).DataPoints {
+				precondition, _ := dp.Attributes.Value("precondition")
+				conflicts[precondition.AsString()] = dp.Value
+			}
+		}
+	}
+	assert.Equal(t, map[string]int64{"if_match": 2, "body": 2}, conflicts)
+}
+
+func TestBulkCreateUsers(t *testing.T) {
+	reader := sdkmetric.NewManualReader()
+	prevMP := otel.GetMeterProvider()
+	otel.SetMeterProvider(sdkmetric.NewMeterProvider(sdkmetric.WithReader(reader)))
+	defer otel.SetMeterProvider(prevMP)
+
+	store := newMockUserStore()
+	_, _ = store.Create(context.Background(), models.CreateUserRequest{Name: "Ann", Email: "ann@example.com"})
+	publisher := &recordingPublisher{}
+	r := setupRouter(NewUserHandler(store).WithEvents(publisher).WithBulkLimits(BulkLimits{MaxUsers: 4, BatchSize: 2}))
+
+	do := func(body string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodPost, "/api/users/bulk", strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		r.ServeHTTP(w, req)
+		return w
+	}
+
+	w := do(`{"users":[
+		{"name":"Bob","email":"bob@example.com"},
+		{"name":"No Email"},
+		{"name":"Ann Again","email":"ANN@example.com"},
+		{"name":"Cat","email":"cat@example.com"}
+	]}`)
+	require.Equal(t, http.StatusMultiStatus, w.Code, w.Body.String())
+	var resp struct {
+		Data models.BulkResult `json:"data"`
+	}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	assert.Equal(t, 2, resp.Data.Succeeded)
+	assert.Equal(t, 2, resp.Data.Failed)
+	statuses := []int{}
+	for i, result := range resp.Data.Results {
+		assert.Equal(t, i, result.Index)
+		statuses = append(statuses, result.Status)
+	}
+	assert.Equal(t, []int{http.StatusCreated, http.StatusBadRequest, http.StatusConflict, http.StatusCreated}, statuses)
+	assert.Equal(t, models.ErrCodeValidationFailed, resp.Data.Results[1].Code)
+	assert.Equal(t, "email", resp.Data.Results[1].Details[0].Field)
+	assert.Equal(t, models.ErrCodeConflict, resp.Data.Results[2].Code)
+	assert.Len(t, store.users, 3)
+	assert.Len(t, publisher.events, 2)
+
+	w = do(`{"users":[{"name":"Dan","email":"dan@example.com"}]}`)
+	assert.Equal(t, http.StatusCreated, w.Code, w.Body.String())
+	assert.Equal(t, http.StatusBadRequest, do(`{"users":[]}`).Code)
+	assert.Equal(t, http.StatusBadRequest, do(`{"users":[{},{},{},{},{}]}`).Code)
+
+	var rm metricdata.ResourceMetrics
+	require.NoError(t, reader.Collect(context.Background(), &rm))
+	var batches metricdata.HistogramDataPoint[int64]
+	for _, sm := range rm.ScopeMetrics {
+		for _, m := range sm.Metrics {
+			if m.Name == "user.bulk.batch.size" {
+				batches = m.Data.(metricdata.Histogram[int64]).DataPoints[0]
+			}
+		}
+	}
+	// The three valid users of the first request in batches of 2 and 1,
+	// then the single user of the second
+	assert.Equal(t, uint64(3), batches.Count)
+	assert.Equal(t, int64(4), batches.Sum)
+}
+
 func TestGetUserInvalidID(t *testing.T) {
 	store := newMockUserStore()
 	handler := NewUserHandler(store)
@@ -273,3 +896,154 @@ func TestDeleteUserInvalidID(t *testing.T) {
 	r.ServeHTTP(w, req)
 	assert.Equal(t, http.StatusBadRequest, w.Code)
 }
+
+func TestExportUsers(t *testing.T) {
+	store := newMockUserStore()
+	bio := "Likes, commas"
+	_, _ = store.Create(context.Background(), models.CreateUserRequest{Name: "Ann", Email: "ann@example.com", Bio: &bio})
+	_, _ = store.Create(context.Background(), models.CreateUserRequest{Name: "Bob", Email: "bob@example.com"})
+	r := setupRouter(NewUserHandler(store))
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/users/export?q=example", nil))
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	assert.Equal(t, "text/csv; charset=utf-8", w.Header().Get("Content-Type"))
+	assert.Equal(t, `attachment; filename="users.csv"`, w.Header().Get("Content-Disposition"))
+	lines := strings.Split(strings.TrimSpace(w.Body.String()), "\n")
+	require.Len(t, lines, 3)
+	assert.Equal(t, "id,name,email,bio,created_at,updated_at,version", lines[0])
+	assert.True(t, strings.HasPrefix(lines[1], `1,Ann,ann@example.com,"Likes, commas",`), lines[1])
+	assert.Equal(t, "example", store.lastFilter.Query)
+
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/users/export?format=ndjson", nil))
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "application/x-ndjson", w.Header().Get("Content-Type"))
+	lines = strings.Split(strings.TrimSpace(w.Body.String()), "\n")
+	require.Len(t, lines, 2)
+	var user models.UserResponse
+	require.NoError(t, json.Unmarshal([]byte(lines[1]), &user))
+	assert.Equal(t, "bob@example.com", user.Email)
+
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/users/export?format=xml", nil))
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	store.failOnCall["Stream"] = true
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/users/export", nil))
+	assert.Equal(t, http.StatusInternalServerError, w.Code)
+}
+
+// stubSearcher finds its hits for any query
+type stubSearcher struct {
+	hits      []repository.UserSearchHit
+	lastQuery string
+	lastLimit int
+}
+
+func (s *stubSearcher) Search(_ context.Context, query string, limit int) ([]repository.UserSearchHit, error) {
+	s.lastQuery, s.lastLimit = query, limit
+	return s.hits, nil
+}
+
+func TestSearchUsers(t *testing.T) {
+	searcher := &stubSearcher{hits: []repository.UserSearchHit{
+		{User: models.User{ID: 2, Name: "Ann", Email: "ann@example.com"}, Relevance: 2.5},
+		{User: models.User{ID: 1, Name: "Annabel", Email: "annabel@example.com"}, Relevance: 0.5},
+	}}
+	r := setupRouter(NewUserHandler(newMockUserStore()))
+	r.GET("/api/v2/users/search", NewUserHandler(newMockUserStore()).WithMapper(dto.V2).WithSearch(searcher).SearchUsers)
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/v2/users/search?q=%20ann%20&limit=500", nil))
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	assert.Equal(t, "ann", searcher.lastQuery)
+	assert.Equal(t, CurrentPaginationLimits().Default, searcher.lastLimit)
+
+	var resp struct {
+		Data []struct {
+			User      map[string]any `json:"user"`
+			Relevance float64        `json:"relevance"`
+		} `json:"data"`
+	}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	require.Len(t, resp.Data, 2)
+	assert.Equal(t, "Ann", resp.Data[0].User["display_name"])
+	assert.Equal(t, 2.5, resp.Data[0].Relevance)
+
+	for _, query := range []string{"", "q=%20", "q=" + strings.Repeat("a", maxFilterQueryLength+1)} {
+		w = httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/v2/users/search?"+query, nil))
+		assert.Equal(t, http.StatusBadRequest, w.Code, query)
+	}
+}
+
+func TestUserETags(t *testing.T) {
+	reader := sdkmetric.NewManualReader()
+	prevMP := otel.GetMeterProvider()
+	otel.SetMeterProvider(sdkmetric.NewMeterProvider(sdkmetric.WithReader(reader)))
+	defer otel.SetMeterProvider(prevMP)
+
+	store := newMockUserStore()
+	_, _ = store.Create(context.Background(), models.CreateUserRequest{Name: "Ann", Email: "ann@example.com"})
+	store.users[0].UpdatedAt = models.NewTimestamp(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC))
+	r := setupRouter(NewUserHandler(store))
+
+	get := func(path, ifNoneMatch string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		if ifNoneMatch != "" {
+			req.Header.Set("If-None-Match", ifNoneMatch)
+		}
+		r.ServeHTTP(w, req)
+		return w
+	}
+
+	w := get("/api/users/1", "")
+	require.Equal(t, http.StatusOK, w.Code)
+	etag := w.Header().Get("ETag")
+	assert.True(t, strings.HasPrefix(etag, `W/"`), etag)
+	assert.Equal(t, "private, no-cache", w.Header().Get("Cache-Control"))
+
+	w = get("/api/users/1", `"other", `+etag)
+	assert.Equal(t, http.StatusNotModified, w.Code)
+	assert.Empty(t, w.Body.String())
+	assert.Equal(t, etag, w.Header().Get("ETag"))
+	assert.Equal(t, http.StatusNotModified, get("/api/users/1", strings.TrimPrefix(etag, "W/")).Code)
+
+	listETag := get("/api/users", "").Header().Get("ETag")
+	assert.NotEqual(t, etag, listETag)
+	assert.Equal(t, http.StatusNotModified, get("/api/users", listETag).Code)
+
+	// Any update moves updated_at and with it the tag
+	store.users[0].UpdatedAt = models.NewTimestamp(time.Date(2024, 1, 2, 0, 0, 0, 0, time.UTC))
+	w = get("/api/users/1", etag)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.NotEqual(t, etag, w.Header().Get("ETag"))
+	assert.Equal(t, http.StatusOK, get("/api/users", listETag).Code)
+
+	var rm metricdata.ResourceMetrics
+	require.NoError(t, reader.Collect(context.Background(), &rm))
+	outcomes := map[string]int64{}
+	for _, sm := range rm.ScopeMetrics {
+		for _, m := range sm.Metrics {
+			if m.Name != "user.conditional.requests" {
+				continue
+			}
+			for _, dp := range m.Data.(metricdata.Sum[int64]).DataPoints {
+				endpoint, _ := dp.Attributes.Value("endpoint")
+				result, _ := dp.Attributes.Value("result")
+				outcomes[endpoint.AsString()+" "+result.AsString()] = dp.Value
+			}
+		}
+	}
+	assert.Equal(t, map[string]int64{
+		"get_user none":   1,
+		"get_user hit":    2,
+		"get_user miss":   1,
+		"list_users none": 1,
+		"list_users hit":  1,
+		"list_users miss": 1,
+	}, outcomes)
+}
@@ -0,0 +1,72 @@
+package handlers
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"arquivolivre.com.br/otel/internal/logging"
+	"arquivolivre.com.br/otel/internal/middleware"
+	"arquivolivre.com.br/otel/internal/models"
+	"arquivolivre.com.br/otel/pkg/utils"
+
+	"github.com/gin-gonic/gin"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// searchHit is a user of the search results in the shape of the requested
+// API version, with its relevance
+type searchHit struct {
+	User      any     `json:"user"`
+	Relevance float64 `json:"relevance"`
+}
+
+// SearchUsers handles GET /api/users/search?q=, returning up to limit users
+// matching q, most relevant first
+func (h *UserHandler) SearchUsers(c *gin.Context) {
+	ctx := c.Request.Context()
+	span := trace.SpanFromContext(ctx)
+	span.SetAttributes(
+		attribute.String("handler", "SearchUsers"),
+		attribute.String("operation", "search_users"),
+	)
+
+	query := strings.TrimSpace(c.Query("q"))
+	if query == "" {
+		_ = c.Error(middleware.BadRequestError("q is required"))
+		return
+	}
+	if utf8.RuneCountInString(query) > maxFilterQueryLength {
+		_ = c.Error(middleware.BadRequestError(fmt.Sprintf("q must be at most %d characters", maxFilterQueryLength)))
+		return
+	}
+	limits := CurrentPaginationLimits()
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", strconv.Itoa(limits.Default)))
+	if limit < 1 || limit > limits.Max {
+		limit = limits.Default
+	}
+
+	hits, err := h.search.Search(ctx, query, limit)
+	if err != nil {
+		middleware.RecordError(c, err, "Failed to search users")
+		_ = c.Error(middleware.InternalError("Failed to search users", err))
+		return
+	}
+	span.SetAttributes(attribute.Int("result.users_count", len(hits)))
+	logging.WithGinContext(c).WithFields(map[string]interface{}{
+		"query":       query,
+		"users_count": len(hits),
+	}).Debug("Searched users")
+
+	users := make([]models.User, len(hits))
+	for i, hit := range hits {
+		users[i] = hit.User
+	}
+	results := make([]searchHit, len(hits))
+	for i, user := range h.userResponses(c, users) {
+		results[i] = searchHit{User: user, Relevance: hits[i].Relevance}
+	}
+	utils.SendSuccess(c, results)
+}
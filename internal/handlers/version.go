@@ -0,0 +1,61 @@
+package handlers
+
+import (
+	"net/http"
+	"strings"
+
+	"arquivolivre.com.br/otel/internal/dto"
+	"arquivolivre.com.br/otel/internal/middleware"
+	"arquivolivre.com.br/otel/internal/models"
+
+	"github.com/gin-gonic/gin"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// APIVersionHeader selects the response version of the unversioned /api
+// routes, e.g. "2" or "v2"; the chosen version is echoed in the response
+const APIVersionHeader = "API-Version"
+
+// mapperContextKey is the gin context key holding the negotiated mapper
+const mapperContextKey = "api.mapper"
+
+// negotiateVersion picks the mapper of the unversioned routes from the
+// API-Version header. Without the header the handlers keep their own mapper,
+// so existing clients still get v1; unknown versions are rejected with 400
+func negotiateVersion() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		requested := c.GetHeader(APIVersionHeader)
+		if requested == "" {
+			c.Next()
+			return
+		}
+
+		version := "v" + strings.TrimPrefix(strings.ToLower(requested), "v")
+		mapper, ok := dto.ForVersion(version)
+		if !ok {
+			_ = c.Error(middleware.NewAPIError(http.StatusBadRequest, models.ErrCodeInvalidRequest,
+				"Unsupported "+APIVersionHeader+" "+requested))
+			c.Abort()
+			return
+		}
+		c.Set(mapperContextKey, mapper)
+		c.Header(APIVersionHeader, strings.TrimPrefix(version, "v"))
+		trace.SpanFromContext(c.Request.Context()).SetAttributes(attribute.String("api.version", version))
+		c.Next()
+	}
+}
+
+// responseMapper returns the mapper negotiated for the request, falling back
+// to the handler's own
+func responseMapper(c *gin.Context, fallback dto.Mapper) dto.Mapper {
+	if mapper, ok := c.Get(mapperContextKey); ok {
+		return mapper.(dto.Mapper)
+	}
+	return fallback
+}
+
+// v1Successor maps an unversioned path to its /api/v1 equivalent
+func v1Successor(path string) string {
+	return "/api/v1" + strings.TrimPrefix(path, "/api")
+}
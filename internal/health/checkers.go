@@ -0,0 +1,35 @@
+package health
+
+import (
+	"context"
+	"fmt"
+	"net"
+)
+
+// Dial checks that a TCP connection to addr can be opened, e.g. to the OTLP
+// collector the exporters send to
+func Dial(addr string) Checker {
+	return CheckerFunc(func(ctx context.Context) error {
+		var dialer net.Dialer
+		conn, err := dialer.DialContext(ctx, "tcp", addr)
+		if err != nil {
+			return err
+		}
+		return conn.Close()
+	})
+}
+
+// DiskSpace checks that the filesystem holding path has at least minFree
+// bytes available
+func DiskSpace(path string, minFree uint64) Checker {
+	return CheckerFunc(func(context.Context) error {
+		free, err := freeBytes(path)
+		if err != nil {
+			return err
+		}
+		if free < minFree {
+			return fmt.Errorf("%d bytes free on %s, want at least %d", free, path, minFree)
+		}
+		return nil
+	})
+}
@@ -0,0 +1,179 @@
+// Package health runs the named checks behind /health and /ready. Components
+// register a Checker with a timeout; when a check fails the service is down
+// if the check is critical and degraded otherwise
+package health
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// DefaultTimeout bounds checks registered without WithTimeout
+const DefaultTimeout = 2 * time.Second
+
+// Status is the state of a check or of the whole service
+type Status string
+
+const (
+	StatusUp       Status = "up"
+	StatusDegraded Status = "degraded"
+	StatusDown     Status = "down"
+)
+
+// Checker reports whether a dependency is usable
+type Checker interface {
+	Check(ctx context.Context) error
+}
+
+// CheckerFunc adapts a function to Checker
+type CheckerFunc func(ctx context.Context) error
+
+func (f CheckerFunc) Check(ctx context.Context) error {
+	return f(ctx)
+}
+
+// Option configures a registered check
+type Option func(*check)
+
+// WithTimeout bounds how long the check may run before it counts as failed
+func WithTimeout(timeout time.Duration) Option {
+	return func(c *check) {
+		c.timeout = timeout
+	}
+}
+
+// Critical makes a failure of the check take the whole service down instead
+// of degrading it
+func Critical() Option {
+	return func(c *check) {
+		c.critical = true
+	}
+}
+
+type check struct {
+	name     string
+	checker  Checker
+	timeout  time.Duration
+	critical bool
+}
+
+// Result is the outcome of one check
+type Result struct {
+	Status    Status  `json:"status"`
+	Critical  bool    `json:"critical"`
+	LatencyMs float64 `json:"latency_ms"`
+	Error     string  `json:"error,omitempty"`
+}
+
+// Report is the outcome of all the checks of a Registry
+type Report struct {
+	Status Status            `json:"status"`
+	Checks map[string]Result `json:"checks"`
+}
+
+// Registry holds the checks of the service
+type Registry struct {
+	mu     sync.RWMutex
+	checks []check
+}
+
+// NewRegistry creates an empty registry
+func NewRegistry() *Registry {
+	return &Registry{}
+}
+
+// Register adds checker under name. Checks are non-critical and limited to
+// DefaultTimeout unless configured otherwise by opts
+func (r *Registry) Register(name string, checker Checker, opts ...Option) error {
+	c := check{name: name, checker: checker, timeout: DefaultTimeout}
+	for _, opt := range opts {
+		opt(&c)
+	}
+	if name == "" {
+		return errors.New("health check name is required")
+	}
+	if c.timeout <= 0 {
+		return fmt.Errorf("health check %q: timeout must be positive", name)
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, existing := range r.checks {
+		if existing.name == name {
+			return fmt.Errorf("health check %q is already registered", name)
+		}
+	}
+	r.checks = append(r.checks, c)
+	return nil
+}
+
+// Run executes all the checks concurrently and reports their results. The
+// service is down when a critical check fails and degraded when another
+// one does
+func (r *Registry) Run(ctx context.Context) Report {
+	r.mu.RLock()
+	checks := append([]check(nil), r.checks...)
+	r.mu.RUnlock()
+
+	results := make([]Result, len(checks))
+	var wg sync.WaitGroup
+	for i, c := range checks {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			results[i] = run(ctx, c)
+		}()
+	}
+	wg.Wait()
+
+	report := Report{Status: StatusUp, Checks: make(map[string]Result, len(checks))}
+	for i, c := range checks {
+		result := results[i]
+		report.Checks[c.name] = result
+		switch {
+		case result.Status == StatusUp:
+		case result.Critical:
+			report.Status = StatusDown
+		case report.Status == StatusUp:
+			report.Status = StatusDegraded
+		}
+	}
+	return report
+}
+
+// run executes c within its timeout. The checker runs in its own goroutine so
+// one that ignores ctx is still cut off; its late result is discarded
+func run(ctx context.Context, c check) Result {
+	ctx, cancel := context.WithTimeout(ctx, c.timeout)
+	defer cancel()
+
+	start := time.Now()
+	done := make(chan error, 1)
+	go func() {
+		done <- c.checker.Check(ctx)
+	}()
+
+	var err error
+	select {
+	case err = <-done:
+	case <-ctx.Done():
+		err = ctx.Err()
+		if errors.Is(err, context.DeadlineExceeded) {
+			err = fmt.Errorf("timed out after %s", c.timeout)
+		}
+	}
+
+	result := Result{
+		Status:    StatusUp,
+		Critical:  c.critical,
+		LatencyMs: float64(time.Since(start).Microseconds()) / 1000,
+	}
+	if err != nil {
+		result.Status = StatusDown
+		result.Error = err.Error()
+	}
+	return result
+}
@@ -0,0 +1,108 @@
+package health
+
+import (
+	"context"
+	"errors"
+	"net"
+	"testing"
+	"time"
+)
+
+func TestRunReportsEachCheck(t *testing.T) {
+	checks := NewRegistry()
+	mustRegister(t, checks, "database", CheckerFunc(func(context.Context) error { return nil }), Critical())
+	mustRegister(t, checks, "cache", CheckerFunc(func(context.Context) error { return errors.New("refused") }))
+
+	report := checks.Run(context.Background())
+	if report.Status != StatusDegraded {
+		t.Fatalf("expected degraded, got %s", report.Status)
+	}
+	if got := report.Checks["database"]; got.Status != StatusUp || !got.Critical {
+		t.Errorf("unexpected database result %+v", got)
+	}
+	if got := report.Checks["cache"]; got.Status != StatusDown || got.Error != "refused" {
+		t.Errorf("unexpected cache result %+v", got)
+	}
+}
+
+func TestRunIsDownWhenACriticalCheckFails(t *testing.T) {
+	checks := NewRegistry()
+	mustRegister(t, checks, "database", CheckerFunc(func(context.Context) error { return errors.New("down") }), Critical())
+	mustRegister(t, checks, "cache", CheckerFunc(func(context.Context) error { return errors.New("refused") }))
+
+	if report := checks.Run(context.Background()); report.Status != StatusDown {
+		t.Fatalf("expected down, got %s", report.Status)
+	}
+}
+
+func TestRunCutsOffSlowChecks(t *testing.T) {
+	checks := NewRegistry()
+	block := make(chan struct{})
+	defer close(block)
+	// The checker ignores ctx, so only the registry can stop waiting for it
+	mustRegister(t, checks, "slow", CheckerFunc(func(context.Context) error {
+		<-block
+		return nil
+	}), WithTimeout(20*time.Millisecond), Critical())
+
+	start := time.Now()
+	report := checks.Run(context.Background())
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Fatalf("run took %s", elapsed)
+	}
+	if got := report.Checks["slow"]; got.Status != StatusDown || got.Error != "timed out after 20ms" {
+		t.Fatalf("unexpected result %+v", got)
+	}
+}
+
+func TestRegisterRejectsInvalidChecks(t *testing.T) {
+	checks := NewRegistry()
+	ok := CheckerFunc(func(context.Context) error { return nil })
+	mustRegister(t, checks, "database", ok)
+
+	if err := checks.Register("database", ok); err == nil {
+		t.Error("expected duplicate names to be rejected")
+	}
+	if err := checks.Register("", ok); err == nil {
+		t.Error("expected an empty name to be rejected")
+	}
+	if err := checks.Register("disk", ok, WithTimeout(0)); err == nil {
+		t.Error("expected a zero timeout to be rejected")
+	}
+}
+
+func TestDial(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	addr := ln.Addr().String()
+
+	if err := Dial(addr).Check(context.Background()); err != nil {
+		t.Fatalf("expected dial to succeed: %v", err)
+	}
+	_ = ln.Close()
+	if err := Dial(addr).Check(context.Background()); err == nil {
+		t.Fatal("expected dial to a closed listener to fail")
+	}
+}
+
+func TestDiskSpace(t *testing.T) {
+	dir := t.TempDir()
+	if err := DiskSpace(dir, 0).Check(context.Background()); err != nil {
+		if errors.Is(err, errors.ErrUnsupported) {
+			t.Skip("disk space is not supported on this platform")
+		}
+		t.Fatalf("expected any free space to be enough: %v", err)
+	}
+	if err := DiskSpace(dir, ^uint64(0)).Check(context.Background()); err == nil {
+		t.Fatal("expected an impossible minimum to fail")
+	}
+}
+
+func mustRegister(t *testing.T, r *Registry, name string, checker Checker, opts ...Option) {
+	t.Helper()
+	if err := r.Register(name, checker, opts...); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,271 @@
+// Package jobs runs background work on an in-process worker pool. Each job
+// executes in its own root span linked to the trace that enqueued it, with
+// the baggage and request ID of the enqueuing context.
+package jobs
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"arquivolivre.com.br/otel/internal/logging"
+	"arquivolivre.com.br/otel/pkg/utils"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/baggage"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/metric"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const instrumentationName = "arquivolivre.com.br/otel/internal/jobs"
+
+var (
+	// ErrQueueFull is returned by Enqueue when the buffer has no free slots
+	ErrQueueFull = errors.New("job queue is full")
+	// ErrQueueClosed is returned by Enqueue after Shutdown has been called
+	ErrQueueClosed = errors.New("job queue is closed")
+)
+
+// Func is the work performed by a job
+type Func func(ctx context.Context) error
+
+// Enqueuer schedules background jobs
+type Enqueuer interface {
+	Enqueue(ctx context.Context, name string, fn Func) error
+}
+
+// Config controls the size of the worker pool
+type Config struct {
+	Workers    int
+	BufferSize int
+}
+
+type job struct {
+	name       string
+	fn         Func
+	link       trace.Link
+	baggage    baggage.Baggage
+	requestID  string
+	enqueuedAt time.Time
+}
+
+type queueMetrics struct {
+	depth    metric.Int64UpDownCounter
+	active   metric.Int64UpDownCounter
+	waitTime metric.Float64Histogram
+	duration metric.Float64Histogram
+	failures metric.Int64Counter
+	rejected metric.Int64Counter
+}
+
+// Queue is an in-process worker pool
+type Queue struct {
+	jobs    chan job
+	workers int
+	tracer  trace.Tracer
+	metrics queueMetrics
+
+	mu     sync.RWMutex
+	closed bool
+	wg     sync.WaitGroup
+}
+
+// NewQueue creates a queue; call Start to begin processing
+func NewQueue(cfg Config) (*Queue, error) {
+	if cfg.Workers < 1 {
+		cfg.Workers = 1
+	}
+	if cfg.BufferSize < 1 {
+		cfg.BufferSize = 100
+	}
+
+	m, err := newQueueMetrics(otel.Meter(instrumentationName))
+	if err != nil {
+		return nil, err
+	}
+
+	return &Queue{
+		jobs:    make(chan job, cfg.BufferSize),
+		workers: cfg.Workers,
+		tracer:  otel.Tracer(instrumentationName),
+		metrics: m,
+	}, nil
+}
+
+func newQueueMetrics(meter metric.Meter) (queueMetrics, error) {
+	depth, err := meter.Int64UpDownCounter(
+		"jobs_queue_depth",
+		metric.WithDescription("Number of jobs waiting to be processed"),
+	)
+	if err != nil {
+		return queueMetrics{}, fmt.Errorf("failed to create queue depth metric: %w", err)
+	}
+
+	active, err := meter.Int64UpDownCounter(
+		"jobs_active",
+		metric.WithDescription("Number of jobs being processed by a worker"),
+	)
+	if err != nil {
+		return queueMetrics{}, fmt.Errorf("failed to create active jobs metric: %w", err)
+	}
+
+	waitTime, err := meter.Float64Histogram(
+		"jobs_wait_duration_seconds",
+		metric.WithDescription("Time jobs spend in the queue before a worker picks them up"),
+		metric.WithUnit("s"),
+	)
+	if err != nil {
+		return queueMetrics{}, fmt.Errorf("failed to create wait duration metric: %w", err)
+	}
+
+	duration, err := meter.Float64Histogram(
+		"jobs_processing_duration_seconds",
+		metric.WithDescription("Job processing duration in seconds"),
+		metric.WithUnit("s"),
+	)
+	if err != nil {
+		return queueMetrics{}, fmt.Errorf("failed to create processing duration metric: %w", err)
+	}
+
+	failures, err := meter.Int64Counter(
+		"jobs_failures_total",
+		metric.WithDescription("Total number of failed jobs"),
+	)
+	if err != nil {
+		return queueMetrics{}, fmt.Errorf("failed to create failures metric: %w", err)
+	}
+
+	rejected, err := meter.Int64Counter(
+		"jobs_rejected_total",
+		metric.WithDescription("Total number of jobs Enqueue refused because the queue was full or closed"),
+	)
+	if err != nil {
+		return queueMetrics{}, fmt.Errorf("failed to create rejected jobs metric: %w", err)
+	}
+
+	return queueMetrics{
+		depth:    depth,
+		active:   active,
+		waitTime: waitTime,
+		duration: duration,
+		failures: failures,
+		rejected: rejected,
+	}, nil
+}
+
+// Start launches the workers; they exit once Shutdown drains the queue
+func (q *Queue) Start() {
+	for i := 0; i < q.workers; i++ {
+		q.wg.Add(1)
+		go func() {
+			defer q.wg.Done()
+			for j := range q.jobs {
+				q.run(j)
+			}
+		}()
+	}
+}
+
+// Enqueue schedules fn without blocking. The span in ctx, if any, is linked
+// from the job's root span, and fn receives the baggage and request ID of
+// ctx, so tenant attributes and log correlation carry over to the job.
+func (q *Queue) Enqueue(ctx context.Context, name string, fn Func) error {
+	nameAttr := attribute.String("job.name", name)
+	q.mu.RLock()
+	defer q.mu.RUnlock()
+	if q.closed {
+		q.metrics.rejected.Add(ctx, 1, metric.WithAttributes(nameAttr, attribute.String("reason", "closed")))
+		return ErrQueueClosed
+	}
+
+	j := job{
+		name:       name,
+		fn:         fn,
+		link:       trace.LinkFromContext(ctx, nameAttr),
+		baggage:    baggage.FromContext(ctx),
+		requestID:  utils.RequestIDFromContext(ctx),
+		enqueuedAt: time.Now(),
+	}
+
+	select {
+	case q.jobs <- j:
+		q.metrics.depth.Add(ctx, 1, metric.WithAttributes(nameAttr))
+		return nil
+	default:
+		q.metrics.rejected.Add(ctx, 1, metric.WithAttributes(nameAttr, attribute.String("reason", "full")))
+		return ErrQueueFull
+	}
+}
+
+// Shutdown stops accepting jobs and waits for queued jobs to finish or ctx
+// to expire
+func (q *Queue) Shutdown(ctx context.Context) error {
+	q.mu.Lock()
+	if !q.closed {
+		q.closed = true
+		close(q.jobs)
+	}
+	q.mu.Unlock()
+
+	done := make(chan struct{})
+	go func() {
+		q.wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (q *Queue) run(j job) {
+	nameAttr := attribute.String("job.name", j.name)
+	ctx := baggage.ContextWithBaggage(context.Background(), j.baggage)
+	if j.requestID != "" {
+		ctx = utils.ContextWithRequestID(ctx, j.requestID)
+	}
+	q.metrics.depth.Add(ctx, -1, metric.WithAttributes(nameAttr))
+	q.metrics.waitTime.Record(ctx, time.Since(j.enqueuedAt).Seconds(), metric.WithAttributes(nameAttr))
+	q.metrics.active.Add(ctx, 1, metric.WithAttributes(nameAttr))
+	defer q.metrics.active.Add(ctx, -1, metric.WithAttributes(nameAttr))
+
+	opts := []trace.SpanStartOption{
+		trace.WithNewRoot(),
+		trace.WithSpanKind(trace.SpanKindConsumer),
+		trace.WithAttributes(nameAttr),
+	}
+	if j.link.SpanContext.IsValid() {
+		opts = append(opts, trace.WithLinks(j.link))
+	}
+	ctx, span := q.tracer.Start(ctx, "job "+j.name, opts...)
+	defer span.End()
+
+	start := time.Now()
+	err := safeRun(ctx, j.fn)
+	status := "success"
+	if err != nil {
+		status = "error"
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+		q.metrics.failures.Add(ctx, 1, metric.WithAttributes(nameAttr))
+		logging.Module("jobs").LogError(ctx, err, "Background job failed", map[string]interface{}{"job": j.name})
+	}
+	q.metrics.duration.Record(ctx, time.Since(start).Seconds(), metric.WithAttributes(nameAttr, attribute.String("status", status)))
+}
+
+// safeRun turns a panicking job into an error so a worker is never lost
+func safeRun(ctx context.Context, fn Func) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("job panicked: %v", r)
+		}
+	}()
+	return fn(ctx)
+}
@@ -0,0 +1,122 @@
+package jobs
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"arquivolivre.com.br/otel/pkg/utils"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/baggage"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/metric/metricdata"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+func TestQueueRunsJobsWithLinkToEnqueuer(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	tp := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+	prev := otel.GetTracerProvider()
+	otel.SetTracerProvider(tp)
+	defer otel.SetTracerProvider(prev)
+
+	q, err := NewQueue(Config{Workers: 2, BufferSize: 4})
+	require.NoError(t, err)
+	q.Start()
+
+	ctx, parent := tp.Tracer("test").Start(context.Background(), "request")
+	done := make(chan struct{})
+	require.NoError(t, q.Enqueue(ctx, "ok", func(context.Context) error {
+		close(done)
+		return nil
+	}))
+	require.NoError(t, q.Enqueue(ctx, "fails", func(context.Context) error {
+		return errors.New("boom")
+	}))
+	require.NoError(t, q.Enqueue(ctx, "panics", func(context.Context) error {
+		panic("bad job")
+	}))
+	parent.End()
+
+	<-done
+	require.NoError(t, q.Shutdown(context.Background()))
+
+	jobSpans := map[string]sdktrace.ReadOnlySpan{}
+	for _, s := range recorder.Ended() {
+		if s.Name() != "request" {
+			jobSpans[s.Name()] = s
+		}
+	}
+	require.Len(t, jobSpans, 3)
+
+	ok := jobSpans["job ok"]
+	assert.NotEqual(t, parent.SpanContext().TraceID(), ok.SpanContext().TraceID(), "job must start a new trace")
+	require.Len(t, ok.Links(), 1)
+	assert.Equal(t, parent.SpanContext().SpanID(), ok.Links()[0].SpanContext.SpanID())
+
+	assert.Equal(t, "boom", jobSpans["job fails"].Status().Description)
+	assert.Contains(t, jobSpans["job panics"].Status().Description, "bad job")
+}
+
+func TestQueueFullAndClosed(t *testing.T) {
+	reader := sdkmetric.NewManualReader()
+	prevMP := otel.GetMeterProvider()
+	otel.SetMeterProvider(sdkmetric.NewMeterProvider(sdkmetric.WithReader(reader)))
+	defer otel.SetMeterProvider(prevMP)
+
+	q, err := NewQueue(Config{Workers: 1, BufferSize: 1})
+	require.NoError(t, err)
+
+	noop := func(context.Context) error { return nil }
+	require.NoError(t, q.Enqueue(context.Background(), "a", noop))
+	assert.ErrorIs(t, q.Enqueue(context.Background(), "b", noop), ErrQueueFull)
+
+	q.Start()
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	require.NoError(t, q.Shutdown(ctx))
+	assert.ErrorIs(t, q.Enqueue(context.Background(), "c", noop), ErrQueueClosed)
+	require.NoError(t, q.Shutdown(ctx), "shutdown is idempotent")
+
+	var rm metricdata.ResourceMetrics
+	require.NoError(t, reader.Collect(context.Background(), &rm))
+	rejected := map[string]int64{}
+	for _, m := range rm.ScopeMetrics[0].Metrics {
+		if m.Name != "jobs_rejected_total" {
+			continue
+		}
+		for _, dp := range m.Data.(metricdata.Sum[int64]).DataPoints {
+			reason, _ := dp.Attributes.Value("reason")
+			rejected[reason.AsString()] += dp.Value
+		}
+	}
+	assert.Equal(t, map[string]int64{"full": 1, "closed": 1}, rejected)
+}
+
+func TestQueuePassesBaggageAndRequestID(t *testing.T) {
+	q, err := NewQueue(Config{Workers: 1, BufferSize: 1})
+	require.NoError(t, err)
+	q.Start()
+
+	member, err := baggage.NewMember("tenant.id", "acme")
+	require.NoError(t, err)
+	bag, err := baggage.New(member)
+	require.NoError(t, err)
+	ctx := utils.ContextWithRequestID(baggage.ContextWithBaggage(context.Background(), bag), "req-1")
+
+	var tenant, requestID string
+	require.NoError(t, q.Enqueue(ctx, "ctx", func(ctx context.Context) error {
+		tenant = baggage.FromContext(ctx).Member("tenant.id").Value()
+		requestID = utils.RequestIDFromContext(ctx)
+		return nil
+	}))
+	require.NoError(t, q.Shutdown(context.Background()))
+
+	assert.Equal(t, "acme", tenant)
+	assert.Equal(t, "req-1", requestID)
+}
@@ -0,0 +1,90 @@
+// Package livemetrics streams periodic snapshots of the database connection
+// pool and the request rates over Server-Sent Events, for live dashboards
+// that do without Grafana.
+package livemetrics
+
+import (
+	"sync/atomic"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// statusClasses are the classes requests are counted in, from 2xx to 5xx
+var statusClasses = [...]string{"2xx", "3xx", "4xx", "5xx"}
+
+// RequestCounter counts the completed requests by status class
+type RequestCounter struct {
+	counts [len(statusClasses)]atomic.Int64
+}
+
+// NewRequestCounter creates a counter; its Middleware must run on the routes
+// to count
+func NewRequestCounter() *RequestCounter {
+	return &RequestCounter{}
+}
+
+// Middleware counts each request once its handlers have returned. It must
+// run before middleware.ErrorHandler, which writes the status of requests
+// that failed with c.Error only after the handlers return
+func (r *RequestCounter) Middleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Next()
+		if class := c.Writer.Status()/100 - 2; class >= 0 && class < len(statusClasses) {
+			r.counts[class].Add(1)
+		}
+	}
+}
+
+// totals returns the requests counted so far by status class
+func (r *RequestCounter) totals() [len(statusClasses)]int64 {
+	var totals [len(statusClasses)]int64
+	for i := range r.counts {
+		totals[i] = r.counts[i].Load()
+	}
+	return totals
+}
+
+// Snapshot is the data of one event of the stream
+type Snapshot struct {
+	Time time.Time `json:"time"`
+	// Database holds the statistics of the connection pool, and is omitted
+	// without a database
+	Database map[string]interface{} `json:"database,omitempty"`
+	Requests RequestRates           `json:"requests"`
+	// Clients is the number of streams open on this instance
+	Clients int64 `json:"clients"`
+}
+
+// RequestRates describe the requests completed in the window since the
+// previous snapshot of the stream; the window of the first one is empty
+type RequestRates struct {
+	WindowSeconds float64 `json:"window_seconds"`
+	Total         int64   `json:"total"`
+	PerSecond     float64 `json:"per_second"`
+	// PerSecondByStatusClass has every class from 2xx to 5xx
+	PerSecondByStatusClass map[string]float64 `json:"per_second_by_status_class"`
+}
+
+// rates computes the rates of the requests counted between previous and
+// current over window
+func rates(previous, current [len(statusClasses)]int64, window time.Duration) RequestRates {
+	rates := RequestRates{
+		WindowSeconds:          window.Seconds(),
+		PerSecondByStatusClass: make(map[string]float64, len(statusClasses)),
+	}
+	for i, class := range statusClasses {
+		count := current[i] - previous[i]
+		rates.Total += count
+		rates.PerSecondByStatusClass[class] = perSecond(count, window)
+	}
+	rates.PerSecond = perSecond(rates.Total, window)
+	return rates
+}
+
+func perSecond(count int64, window time.Duration) float64 {
+	if window <= 0 {
+		return 0
+	}
+	return float64(count) / window.Seconds()
+}
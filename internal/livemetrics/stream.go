@@ -0,0 +1,172 @@
+package livemetrics
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sync/atomic"
+	"time"
+
+	"arquivolivre.com.br/otel/internal/middleware"
+	"arquivolivre.com.br/otel/internal/models"
+	"arquivolivre.com.br/otel/pkg/utils"
+
+	"github.com/gin-gonic/gin"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Stats reports the statistics of the connection pool; *database.DB
+// implements it
+type Stats interface {
+	GetDetailedStats() map[string]interface{}
+}
+
+// Options configure a Stream
+type Options struct {
+	// Interval is the time between snapshots
+	Interval time.Duration
+	// MaxClients bounds the streams open at once; more get 503
+	MaxClients int
+	// Done ends every stream when closed, so that they do not hold up the
+	// shutdown of the server
+	Done <-chan struct{}
+}
+
+// Stream serves the snapshots as Server-Sent Events. Each client gets a
+// snapshot at once and then one every interval, until it disconnects or the
+// server shuts down
+type Stream struct {
+	stats    Stats
+	requests *RequestCounter
+	opts     Options
+	// open is the number of streams being served
+	open atomic.Int64
+
+	clients  metric.Int64UpDownCounter
+	events   metric.Int64Counter
+	rejected metric.Int64Counter
+}
+
+// NewStream creates a stream of the statistics of stats, which may be nil,
+// and of the requests counted by requests
+func NewStream(stats Stats, requests *RequestCounter, opts Options) *Stream {
+	meter := otel.Meter("otel-example-api")
+	clients, _ := meter.Int64UpDownCounter(
+		"metrics.stream.clients",
+		metric.WithDescription("Number of clients connected to the metrics event stream"),
+		metric.WithUnit("{client}"),
+	)
+	events, _ := meter.Int64Counter(
+		"metrics.stream.events",
+		metric.WithDescription("Total number of snapshots sent to metrics event stream clients"),
+		metric.WithUnit("{event}"),
+	)
+	rejected, _ := meter.Int64Counter(
+		"metrics.stream.rejected",
+		metric.WithDescription("Total number of metrics event stream clients turned away because too many were connected"),
+		metric.WithUnit("{client}"),
+	)
+	return &Stream{
+		stats:    stats,
+		requests: requests,
+		opts:     opts,
+		clients:  clients,
+		events:   events,
+		rejected: rejected,
+	}
+}
+
+// Register mounts the stream on group as GET /metrics
+func (s *Stream) Register(group *gin.RouterGroup) {
+	group.GET("/metrics", s.Serve)
+}
+
+// Serve streams snapshots to the client as "metrics" events until the
+// request context ends or Options.Done is closed. The request span records
+// the events sent in sse.events and why the stream ended in
+// sse.close_reason
+func (s *Stream) Serve(c *gin.Context) {
+	ctx := c.Request.Context()
+	span := trace.SpanFromContext(ctx)
+
+	if s.open.Add(1) > int64(s.opts.MaxClients) {
+		s.open.Add(-1)
+		s.rejected.Add(ctx, 1)
+		_ = c.Error(middleware.NewAPIError(http.StatusServiceUnavailable, models.ErrCodeServiceUnavailable,
+			"Too many clients are connected to the metrics stream"))
+		return
+	}
+	defer s.open.Add(-1)
+	s.clients.Add(ctx, 1)
+	defer s.clients.Add(ctx, -1)
+
+	// The stream outlasts the write timeout of the server
+	_ = http.NewResponseController(c.Writer).SetWriteDeadline(time.Time{})
+	utils.StartStream(c, "text/event-stream")
+	c.Header("Cache-Control", "no-cache")
+	c.Header("X-Accel-Buffering", "no")
+
+	sent, reason := 0, ""
+	defer func() {
+		span.SetAttributes(attribute.Int("sse.events", sent), attribute.String("sse.close_reason", reason))
+	}()
+
+	// Clients that lose the stream reconnect after an interval
+	if _, err := fmt.Fprintf(c.Writer, "retry: %d\n\n", s.opts.Interval.Milliseconds()); err != nil {
+		reason = "write_error"
+		return
+	}
+
+	ticker := time.NewTicker(s.opts.Interval)
+	defer ticker.Stop()
+	previous, previousAt := s.requests.totals(), time.Now()
+	for {
+		now := time.Now()
+		current := s.requests.totals()
+		var window time.Duration
+		if sent > 0 {
+			window = now.Sub(previousAt)
+		}
+		snapshot := Snapshot{
+			Time:     now.UTC(),
+			Requests: rates(previous, current, window),
+			Clients:  s.open.Load(),
+		}
+		if s.stats != nil {
+			snapshot.Database = s.stats.GetDetailedStats()
+		}
+		if err := writeEvent(c.Writer, sent+1, snapshot); err != nil {
+			reason = "write_error"
+			return
+		}
+		sent++
+		s.events.Add(ctx, 1)
+		previous, previousAt = current, now
+
+		select {
+		case <-ctx.Done():
+			reason = "client_disconnect"
+			return
+		case <-s.opts.Done:
+			reason = "shutdown"
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// writeEvent sends snapshot as the metrics event id and flushes it
+func writeEvent(w gin.ResponseWriter, id int, snapshot Snapshot) error {
+	data, err := json.Marshal(snapshot)
+	if err != nil {
+		return err
+	}
+	if _, err := fmt.Fprintf(w, "id: %d\nevent: metrics\ndata: %s\n\n", id, data); err != nil {
+		return err
+	}
+	w.Flush()
+	return nil
+}
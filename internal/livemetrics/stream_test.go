@@ -0,0 +1,179 @@
+package livemetrics
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"arquivolivre.com.br/otel/internal/middleware"
+	"arquivolivre.com.br/otel/pkg/oteltest"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/attribute"
+)
+
+type fakeStats struct{}
+
+func (fakeStats) GetDetailedStats() map[string]interface{} {
+	return map[string]interface{}{"open_connections": 3}
+}
+
+// server serves the stream of opts under /events/metrics, counting the
+// requests to /ok and /missing
+func server(t *testing.T, opts Options) *httptest.Server {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+	requests := NewRequestCounter()
+	r := gin.New()
+	r.Use(middleware.NewTelemetryMiddleware("test-service").GinMiddleware())
+	r.Use(requests.Middleware())
+	r.Use(middleware.ErrorHandler())
+	r.GET("/ok", func(c *gin.Context) { c.Status(http.StatusOK) })
+	r.GET("/fail", func(c *gin.Context) {
+		_ = c.Error(middleware.InternalError("Failed", errors.New("database down")))
+	})
+	NewStream(fakeStats{}, requests, opts).Register(r.Group("/events"))
+	srv := httptest.NewServer(r)
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+// connect opens the stream, which ends when cancel is called
+func connect(t *testing.T, url string) (*http.Response, *bufio.Reader, context.CancelFunc) {
+	t.Helper()
+	ctx, cancel := context.WithCancel(context.Background())
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url+"/events/metrics", nil)
+	require.NoError(t, err)
+	resp, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = resp.Body.Close() })
+	return resp, bufio.NewReader(resp.Body), cancel
+}
+
+// nextEvent reads the fields of the next event of the stream
+func nextEvent(t *testing.T, r *bufio.Reader) map[string]string {
+	t.Helper()
+	fields := map[string]string{}
+	for {
+		line, err := r.ReadString('\n')
+		require.NoError(t, err)
+		line = strings.TrimSuffix(line, "\n")
+		if line == "" {
+			return fields
+		}
+		name, value, _ := strings.Cut(line, ": ")
+		fields[name] = value
+	}
+}
+
+func snapshot(t *testing.T, event map[string]string) Snapshot {
+	t.Helper()
+	require.Equal(t, "metrics", event["event"])
+	var s Snapshot
+	require.NoError(t, json.Unmarshal([]byte(event["data"]), &s))
+	return s
+}
+
+// streamSpan waits for the span of a served stream to end and returns its
+// attributes; rejected requests have no close reason
+func streamSpan(t *testing.T, rec *oteltest.Recorder) attribute.Set {
+	t.Helper()
+	var attrs attribute.Set
+	require.Eventually(t, func() bool {
+		for _, span := range rec.Spans() {
+			set := attribute.NewSet(span.Attributes()...)
+			if set.HasValue("sse.close_reason") {
+				attrs = set
+				return true
+			}
+		}
+		return false
+	}, time.Second, 5*time.Millisecond)
+	return attrs
+}
+
+func TestStreamSendsSnapshots(t *testing.T) {
+	rec := oteltest.Install(t)
+	srv := server(t, Options{Interval: 50 * time.Millisecond, MaxClients: 1})
+
+	resp, events, cancel := connect(t, srv.URL)
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, "text/event-stream", resp.Header.Get("Content-Type"))
+	assert.Equal(t, map[string]string{"retry": "50"}, nextEvent(t, events))
+
+	first := nextEvent(t, events)
+	assert.Equal(t, "1", first["id"])
+	s := snapshot(t, first)
+	assert.Equal(t, map[string]interface{}{"open_connections": float64(3)}, s.Database)
+	assert.Equal(t, int64(1), s.Clients)
+	assert.Zero(t, s.Requests.WindowSeconds)
+
+	for range 2 {
+		ok, err := http.Get(srv.URL + "/ok")
+		require.NoError(t, err)
+		_ = ok.Body.Close()
+	}
+	missing, err := http.Get(srv.URL + "/missing")
+	require.NoError(t, err)
+	_ = missing.Body.Close()
+	// Errors are counted by the status ErrorHandler writes for them
+	failed, err := http.Get(srv.URL + "/fail")
+	require.NoError(t, err)
+	_ = failed.Body.Close()
+	assert.Equal(t, http.StatusInternalServerError, failed.StatusCode)
+
+	second := nextEvent(t, events)
+	assert.Equal(t, "2", second["id"])
+	s = snapshot(t, second)
+	assert.Equal(t, int64(4), s.Requests.Total)
+	assert.Positive(t, s.Requests.WindowSeconds)
+	assert.InDelta(t, 2/s.Requests.WindowSeconds, s.Requests.PerSecondByStatusClass["2xx"], 1e-9)
+	assert.InDelta(t, 1/s.Requests.WindowSeconds, s.Requests.PerSecondByStatusClass["4xx"], 1e-9)
+	assert.InDelta(t, 1/s.Requests.WindowSeconds, s.Requests.PerSecondByStatusClass["5xx"], 1e-9)
+	assert.Equal(t, 1.0, rec.CounterValue(t, "metrics.stream.clients"))
+
+	// A second client is one too many
+	rejected, err := http.Get(srv.URL + "/events/metrics")
+	require.NoError(t, err)
+	_ = rejected.Body.Close()
+	assert.Equal(t, http.StatusServiceUnavailable, rejected.StatusCode)
+	rec.AssertCounterValue(t, "metrics.stream.rejected", nil, 1)
+
+	cancel()
+	attrs := streamSpan(t, rec)
+	reason, _ := attrs.Value("sse.close_reason")
+	assert.Equal(t, "client_disconnect", reason.AsString())
+	sent, _ := attrs.Value("sse.events")
+	assert.GreaterOrEqual(t, sent.AsInt64(), int64(2))
+	assert.Eventually(t, func() bool {
+		return rec.CounterValue(t, "metrics.stream.clients") == 0
+	}, time.Second, 5*time.Millisecond)
+}
+
+func TestStreamEndsOnShutdown(t *testing.T) {
+	rec := oteltest.Install(t)
+	done := make(chan struct{})
+	srv := server(t, Options{Interval: time.Hour, MaxClients: 1, Done: done})
+
+	resp, events, cancel := connect(t, srv.URL)
+	defer cancel()
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	nextEvent(t, events)
+	assert.Equal(t, "1", nextEvent(t, events)["id"])
+
+	close(done)
+	_, err := events.ReadString('\n')
+	assert.Error(t, err, "the stream should end")
+	attrs := streamSpan(t, rec)
+	reason, _ := attrs.Value("sse.close_reason")
+	assert.Equal(t, "shutdown", reason.AsString())
+	rec.AssertCounterValue(t, "metrics.stream.events", nil, 1)
+}
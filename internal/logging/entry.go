@@ -0,0 +1,69 @@
+package logging
+
+import (
+	"context"
+	"log/slog"
+	"maps"
+	"slices"
+)
+
+// Entry collects the fields of a log call. It keeps the field-based helper
+// API (WithTraceContext, WithGinContext) on top of slog
+type Entry struct {
+	logger *Logger
+	ctx    context.Context
+	attrs  []slog.Attr
+}
+
+// WithField returns a copy of the entry with an additional field
+func (e *Entry) WithField(key string, value interface{}) *Entry {
+	return e.with(slog.Any(key, value))
+}
+
+// WithFields returns a copy of the entry with additional fields
+func (e *Entry) WithFields(fields map[string]interface{}) *Entry {
+	return e.with(fieldAttrs(fields)...)
+}
+
+// WithError returns a copy of the entry with the error field set
+func (e *Entry) WithError(err error) *Entry {
+	return e.with(slog.Any("error", err))
+}
+
+// Debug logs the entry at debug level
+func (e *Entry) Debug(message string) {
+	e.log(slog.LevelDebug, message)
+}
+
+// Info logs the entry at info level
+func (e *Entry) Info(message string) {
+	e.log(slog.LevelInfo, message)
+}
+
+// Warn logs the entry at warn level
+func (e *Entry) Warn(message string) {
+	e.log(slog.LevelWarn, message)
+}
+
+// Error logs the entry at error level
+func (e *Entry) Error(message string) {
+	e.log(slog.LevelError, message)
+}
+
+func (e *Entry) with(attrs ...slog.Attr) *Entry {
+	return &Entry{logger: e.logger, ctx: e.ctx, attrs: append(slices.Clip(e.attrs), attrs...)}
+}
+
+func (e *Entry) log(level slog.Level, message string) {
+	e.logger.LogAttrs(e.ctx, level, message, e.attrs...)
+}
+
+// fieldAttrs converts fields to attributes sorted by key, so log lines are
+// stable
+func fieldAttrs(fields map[string]interface{}) []slog.Attr {
+	attrs := make([]slog.Attr, 0, len(fields))
+	for _, key := range slices.Sorted(maps.Keys(fields)) {
+		attrs = append(attrs, slog.Any(key, fields[key]))
+	}
+	return attrs
+}
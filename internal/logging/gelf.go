@@ -0,0 +1,174 @@
+package logging
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"crypto/rand"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"os"
+	"regexp"
+)
+
+const (
+	gelfVersion = "1.1"
+	// gelfChunkSize keeps UDP datagrams below common network MTUs
+	gelfChunkSize = 1420
+	// gelfMaxChunks is the limit imposed by the GELF specification
+	gelfMaxChunks = 128
+)
+
+var (
+	gelfChunkMagic      = []byte{0x1e, 0x0f}
+	gelfInvalidFieldKey = regexp.MustCompile(`[^\w.\-]`)
+)
+
+// GELFHandler is a slog handler that ships logs to Graylog using GELF 1.1
+type GELFHandler struct {
+	cfg      RemoteOutputConfig
+	hostname string
+	writer   *asyncWriter
+	fields   remoteFields
+}
+
+// NewGELFHandler creates a new GELF handler. Messages are delivered in the
+// background, so an unreachable input does not fail construction.
+func NewGELFHandler(cfg RemoteOutputConfig) (*GELFHandler, error) {
+	if err := validateRemoteOutput(cfg); err != nil {
+		return nil, fmt.Errorf("invalid GELF output configuration: %w", err)
+	}
+
+	hostname, err := os.Hostname()
+	if err != nil || hostname == "" {
+		hostname = "unknown"
+	}
+
+	return &GELFHandler{
+		cfg:      cfg,
+		hostname: hostname,
+		writer:   newAsyncWriter("GELF", cfg),
+	}, nil
+}
+
+// Enabled reports true for every level; the logger applies its own level
+func (h *GELFHandler) Enabled(context.Context, slog.Level) bool {
+	return true
+}
+
+// Handle encodes the record as a GELF message and queues it for delivery
+func (h *GELFHandler) Handle(_ context.Context, r slog.Record) error {
+	payload, err := json.Marshal(h.buildMessage(r))
+	if err != nil {
+		return fmt.Errorf("failed to encode GELF message: %w", err)
+	}
+
+	if h.cfg.Network != networkUDP {
+		// GELF TCP frames are delimited by a null byte and must not be compressed
+		h.writer.enqueue(append(payload, 0))
+		return nil
+	}
+
+	chunks, err := gelfUDPChunks(payload)
+	if err != nil {
+		return err
+	}
+	h.writer.enqueue(chunks...)
+	return nil
+}
+
+// WithAttrs returns a handler adding attrs to every message
+func (h *GELFHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	c := *h
+	c.fields = h.fields.withAttrs(attrs)
+	return &c
+}
+
+// WithGroup returns a handler prefixing the fields that follow with name
+func (h *GELFHandler) WithGroup(name string) slog.Handler {
+	c := *h
+	c.fields = h.fields.withGroup(name)
+	return &c
+}
+
+// Dropped returns the number of messages that could not be delivered
+func (h *GELFHandler) Dropped() uint64 {
+	return h.writer.Dropped()
+}
+
+// Shutdown flushes queued messages and closes the connection
+func (h *GELFHandler) Shutdown(ctx context.Context) error {
+	return h.writer.Shutdown(ctx)
+}
+
+// buildMessage maps a slog record onto the GELF 1.1 payload
+func (h *GELFHandler) buildMessage(r slog.Record) map[string]interface{} {
+	msg := map[string]interface{}{
+		"version":       gelfVersion,
+		"host":          h.hostname,
+		"short_message": r.Message,
+		"timestamp":     float64(r.Time.UnixNano()) / 1e9,
+		"level":         syslogSeverity(r.Level),
+		"_app_name":     h.cfg.AppName,
+		"_logger":       "slog",
+	}
+
+	for key, value := range h.fields.of(r) {
+		field := "_" + gelfInvalidFieldKey.ReplaceAllString(key, "_")
+		if field == "_id" {
+			field = "_field_id" // _id is reserved by GELF
+		}
+		switch v := value.(type) {
+		case error:
+			msg[field] = v.Error()
+		case string, bool, int64, uint64, float64:
+			msg[field] = v
+		default:
+			msg[field] = toString(v)
+		}
+	}
+
+	return msg
+}
+
+// gelfUDPChunks compresses the payload and splits it into GELF chunks if needed
+func gelfUDPChunks(payload []byte) ([][]byte, error) {
+	var buf bytes.Buffer
+	zw := gzip.NewWriter(&buf)
+	if _, err := zw.Write(payload); err != nil {
+		return nil, err
+	}
+	if err := zw.Close(); err != nil {
+		return nil, err
+	}
+	data := buf.Bytes()
+
+	if len(data) <= gelfChunkSize {
+		return [][]byte{data}, nil
+	}
+
+	total := (len(data) + gelfChunkSize - 1) / gelfChunkSize
+	if total > gelfMaxChunks {
+		return nil, fmt.Errorf("GELF message too large: %d chunks", total)
+	}
+
+	messageID := make([]byte, 8)
+	if _, err := rand.Read(messageID); err != nil {
+		return nil, err
+	}
+
+	chunks := make([][]byte, 0, total)
+	for i := 0; i < total; i++ {
+		end := min((i+1)*gelfChunkSize, len(data))
+
+		chunk := make([]byte, 0, 12+end-i*gelfChunkSize)
+		chunk = append(chunk, gelfChunkMagic...)
+		chunk = append(chunk, messageID...)
+		chunk = append(chunk, byte(i), byte(total))
+		chunk = append(chunk, data[i*gelfChunkSize:end]...)
+		chunks = append(chunks, chunk)
+	}
+
+	return chunks, nil
+}
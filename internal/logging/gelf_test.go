@@ -0,0 +1,116 @@
+package logging
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"log/slog"
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGELFHandler_UDP(t *testing.T) {
+	server := listenUDP(t)
+
+	handler, err := NewGELFHandler(RemoteOutputConfig{
+		Network: "udp",
+		Address: server.LocalAddr().String(),
+		AppName: "otel-example-api",
+	})
+	require.NoError(t, err)
+	defer func() { _ = handler.Shutdown(context.Background()) }()
+
+	record := slog.NewRecord(time.Unix(1700000000, 500000000), slog.LevelError, "query failed", 0)
+	record.AddAttrs(
+		slog.String("trace_id", "abc"),
+		slog.Any("error", errors.New("boom")),
+		slog.Int("id", 7),
+		slog.String("bad key!", "v"),
+		slog.Group("db", slog.Float64("latency_ms", 1.5)),
+	)
+	require.NoError(t, handler.WithAttrs([]slog.Attr{slog.String("component", "repo")}).Handle(context.Background(), record))
+
+	zr, err := gzip.NewReader(bytes.NewReader(readDatagram(t, server)))
+	require.NoError(t, err)
+	raw, err := io.ReadAll(zr)
+	require.NoError(t, err)
+
+	var msg map[string]interface{}
+	require.NoError(t, json.Unmarshal(raw, &msg))
+
+	assert.Equal(t, "1.1", msg["version"])
+	assert.Equal(t, "query failed", msg["short_message"])
+	assert.Equal(t, float64(3), msg["level"])
+	assert.InDelta(t, 1700000000.5, msg["timestamp"], 0.001)
+	assert.Equal(t, "abc", msg["_trace_id"])
+	assert.Equal(t, "boom", msg["_error"])
+	assert.Equal(t, float64(7), msg["_field_id"])
+	assert.Equal(t, "v", msg["_bad_key_"])
+	assert.Equal(t, 1.5, msg["_db.latency_ms"])
+	assert.Equal(t, "repo", msg["_component"])
+}
+
+func TestGELFHandler_TCP(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer func() { _ = ln.Close() }()
+	received := acceptOnce(t, ln)
+
+	handler, err := NewGELFHandler(RemoteOutputConfig{Network: "tcp", Address: ln.Addr().String()})
+	require.NoError(t, err)
+	defer func() { _ = handler.Shutdown(context.Background()) }()
+
+	require.NoError(t, handler.Handle(context.Background(), slog.NewRecord(time.Now(), slog.LevelInfo, "over tcp", 0)))
+
+	select {
+	case raw := <-received:
+		require.NotEmpty(t, raw)
+		// Frames are null-terminated, uncompressed JSON
+		assert.Equal(t, byte(0), raw[len(raw)-1])
+		assert.Equal(t, byte('{'), raw[0])
+
+		var msg map[string]interface{}
+		require.NoError(t, json.Unmarshal(raw[:len(raw)-1], &msg))
+		assert.Equal(t, "over tcp", msg["short_message"])
+	case <-time.After(3 * time.Second):
+		t.Fatal("GELF message not received")
+	}
+}
+
+func TestGELFUDPChunks_SplitsLargeMessages(t *testing.T) {
+	// Random-looking payload that gzip cannot shrink below one chunk
+	var b strings.Builder
+	for i := 0; i < 4000; i++ {
+		b.WriteString(hex8(uint32(i) * 2654435761))
+	}
+
+	chunks, err := gelfUDPChunks([]byte(b.String()))
+	require.NoError(t, err)
+	require.True(t, len(chunks) > 1)
+
+	for i, chunk := range chunks {
+		assert.Equal(t, gelfChunkMagic, chunk[:2])
+		assert.Equal(t, chunks[0][2:10], chunk[2:10], "chunks share a message ID")
+		assert.Equal(t, byte(i), chunk[10])
+		assert.Equal(t, byte(len(chunks)), chunk[11])
+		assert.LessOrEqual(t, len(chunk), gelfChunkSize+12)
+	}
+}
+
+func hex8(n uint32) string {
+	const digits = "0123456789abcdef"
+	out := make([]byte, 8)
+	for i := 7; i >= 0; i-- {
+		out[i] = digits[n&0xf]
+		n >>= 4
+	}
+	return string(out)
+}
@@ -0,0 +1,221 @@
+package logging
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"slices"
+	"sync"
+
+	"arquivolivre.com.br/otel/internal/auth"
+	"arquivolivre.com.br/otel/internal/tenant"
+	"arquivolivre.com.br/otel/pkg/utils"
+
+	"go.opentelemetry.io/otel/trace"
+)
+
+// timestampLayout is the layout of the timestamp of JSON log lines
+const timestampLayout = "2006-01-02T15:04:05.000Z07:00"
+
+// contextHandler applies the level of a logger, or of its module when
+// overridden, and adds the trace context, the request ID, the tenant and the
+// API client of the record context
+type contextHandler struct {
+	next    slog.Handler
+	level   *slog.LevelVar
+	module  string
+	modules *moduleLevels
+
+	// sampledDebug emits debug records for sampled traces only, and
+	// regardless of the configured level (LOG_DEBUG_SAMPLED_ONLY)
+	sampledDebug bool
+}
+
+func (h *contextHandler) Enabled(ctx context.Context, level slog.Level) bool {
+	if h.sampledDebug && level >= slog.LevelDebug && level < slog.LevelInfo {
+		if !trace.SpanContextFromContext(ctx).IsSampled() {
+			return false
+		}
+	} else if level < h.threshold() {
+		return false
+	}
+	return h.next.Enabled(ctx, level)
+}
+
+// threshold returns the minimum level of the records of the handler
+func (h *contextHandler) threshold() slog.Level {
+	if h.module != "" {
+		if level, ok := h.modules.get(h.module); ok {
+			return level
+		}
+	}
+	return h.level.Level()
+}
+
+func (h *contextHandler) Handle(ctx context.Context, r slog.Record) error {
+	r.AddAttrs(contextAttrs(ctx)...)
+	return h.next.Handle(ctx, r)
+}
+
+func (h *contextHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	c := *h
+	c.next = h.next.WithAttrs(attrs)
+	return &c
+}
+
+func (h *contextHandler) WithGroup(name string) slog.Handler {
+	c := *h
+	c.next = h.next.WithGroup(name)
+	return &c
+}
+
+// contextAttrs returns the log fields carried by ctx
+func contextAttrs(ctx context.Context) []slog.Attr {
+	var attrs []slog.Attr
+	if requestID := utils.RequestIDFromContext(ctx); requestID != "" {
+		attrs = append(attrs, slog.String("request_id", requestID))
+	}
+	if tenantID := tenant.FromContext(ctx); tenantID != "" {
+		attrs = append(attrs, slog.String(tenant.Key, tenantID))
+	}
+	if clientID := auth.ClientID(ctx); clientID != "" {
+		attrs = append(attrs, slog.String(auth.ClientIDKey, clientID))
+	}
+	if sc := trace.SpanContextFromContext(ctx); sc.IsValid() {
+		attrs = append(attrs,
+			slog.String("trace_id", sc.TraceID().String()),
+			slog.String("span_id", sc.SpanID().String()),
+		)
+	}
+	return attrs
+}
+
+// moduleLevels holds the level overrides of the modules of a logger and the
+// module loggers created so far
+type moduleLevels struct {
+	mu      sync.RWMutex
+	levels  map[string]slog.Level
+	loggers map[string]*Logger
+}
+
+func (m *moduleLevels) get(module string) (slog.Level, bool) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	level, ok := m.levels[module]
+	return level, ok
+}
+
+// handlerSet holds the outputs of a logger. It is shared with the loggers
+// derived from it, so outputs added later (remote sinks, the OTel bridge)
+// reach every one of them
+type handlerSet struct {
+	mu       sync.RWMutex
+	handlers []slog.Handler
+}
+
+func (s *handlerSet) add(h slog.Handler) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.handlers = append(s.handlers, h)
+}
+
+func (s *handlerSet) list() []slog.Handler {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.handlers
+}
+
+// fanoutHandler sends each record to every output of a set. The attributes
+// and groups of derived loggers are applied to the outputs when handling
+type fanoutHandler struct {
+	set    *handlerSet
+	derive []func(slog.Handler) slog.Handler
+}
+
+func (h *fanoutHandler) Enabled(ctx context.Context, level slog.Level) bool {
+	for _, output := range h.set.list() {
+		if output.Enabled(ctx, level) {
+			return true
+		}
+	}
+	return false
+}
+
+func (h *fanoutHandler) Handle(ctx context.Context, r slog.Record) error {
+	var errs []error
+	for _, output := range h.set.list() {
+		if !output.Enabled(ctx, r.Level) {
+			continue
+		}
+		for _, derive := range h.derive {
+			output = derive(output)
+		}
+		if err := output.Handle(ctx, r.Clone()); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func (h *fanoutHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	if len(attrs) == 0 {
+		return h
+	}
+	return h.with(func(output slog.Handler) slog.Handler { return output.WithAttrs(attrs) })
+}
+
+func (h *fanoutHandler) WithGroup(name string) slog.Handler {
+	if name == "" {
+		return h
+	}
+	return h.with(func(output slog.Handler) slog.Handler { return output.WithGroup(name) })
+}
+
+func (h *fanoutHandler) with(derive func(slog.Handler) slog.Handler) *fanoutHandler {
+	return &fanoutHandler{set: h.set, derive: append(slices.Clip(h.derive), derive)}
+}
+
+// newJSONHandler creates the JSON output, keeping the timestamp, level and
+// message keys of the log pipeline
+func newJSONHandler(w io.Writer) slog.Handler {
+	return slog.NewJSONHandler(w, &slog.HandlerOptions{
+		// The logger applies its level before records reach the outputs
+		Level: slog.LevelDebug,
+		ReplaceAttr: func(groups []string, a slog.Attr) slog.Attr {
+			if len(groups) > 0 {
+				return a
+			}
+			switch a.Key {
+			case slog.TimeKey:
+				return slog.String("timestamp", a.Value.Time().Format(timestampLayout))
+			case slog.LevelKey:
+				if level, ok := a.Value.Any().(slog.Level); ok {
+					return slog.String(slog.LevelKey, LevelName(level))
+				}
+			case slog.MessageKey:
+				a.Key = "message"
+			}
+			return a
+		},
+	})
+}
+
+// switchWriter lets the output of a logger be replaced after its handlers
+// were created
+type switchWriter struct {
+	mu sync.Mutex
+	w  io.Writer
+}
+
+func (s *switchWriter) Write(p []byte) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.w.Write(p)
+}
+
+func (s *switchWriter) set(w io.Writer) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.w = w
+}
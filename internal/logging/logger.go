@@ -2,169 +2,332 @@ package logging
 
 import (
 	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"maps"
 	"os"
+	"strconv"
+	"sync/atomic"
+
+	"arquivolivre.com.br/otel/pkg/utils"
 
 	"github.com/gin-gonic/gin"
-	"github.com/sirupsen/logrus"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
 	sdklog "go.opentelemetry.io/otel/sdk/log"
-	"go.opentelemetry.io/otel/trace"
 )
 
-// Logger wraps logrus with OpenTelemetry integration
+// Logger is a structured slog logger with OpenTelemetry integration. Records
+// carry the trace context of the context they are logged with and are written
+// as JSON to stdout and to the outputs added to the logger
 type Logger struct {
-	*logrus.Logger
+	*slog.Logger
+
+	// root is the handler of the logger without a module
+	root     *contextHandler
+	level    *slog.LevelVar
+	modules  *moduleLevels
+	output   *switchWriter
+	handlers *handlerSet
+	changes  metric.Int64Counter
 }
 
 // NewLogger creates a new structured logger with OpenTelemetry integration
 func NewLogger() *Logger {
-	logger := logrus.New()
-
-	// Set JSON formatter for structured logging
-	logger.SetFormatter(&logrus.JSONFormatter{
-		TimestampFormat: "2006-01-02T15:04:05.000Z07:00",
-		FieldMap: logrus.FieldMap{
-			logrus.FieldKeyTime:  "timestamp",
-			logrus.FieldKeyLevel: "level",
-			logrus.FieldKeyMsg:   "message",
-		},
-	})
-
 	// Set log level from environment
-	level := os.Getenv("LOG_LEVEL")
+	level, err := ParseLevel(os.Getenv("LOG_LEVEL"))
+	if err != nil {
+		level = slog.LevelInfo
+	}
+
+	changes, _ := otel.Meter("otel-example-api").Int64Counter(
+		"log_level_changes_total",
+		metric.WithDescription("Total number of runtime log level changes, by module and level"),
+	)
+	l := &Logger{
+		level:    new(slog.LevelVar),
+		modules:  &moduleLevels{levels: map[string]slog.Level{}, loggers: map[string]*Logger{}},
+		output:   &switchWriter{w: os.Stdout},
+		handlers: &handlerSet{},
+		changes:  changes,
+	}
+	l.level.Set(level)
+	l.handlers.add(newJSONHandler(l.output))
+	l.root = &contextHandler{
+		next:         &fanoutHandler{set: l.handlers},
+		level:        l.level,
+		modules:      l.modules,
+		sampledDebug: os.Getenv("LOG_DEBUG_SAMPLED_ONLY") == "true",
+	}
+	l.Logger = slog.New(l.root)
+
+	return l
+}
+
+// ParseLevel parses one of the supported log levels: debug, info, warn or error
+func ParseLevel(level string) (slog.Level, error) {
 	switch level {
 	case "debug":
-		logger.SetLevel(logrus.DebugLevel)
+		return slog.LevelDebug, nil
 	case "info":
-		logger.SetLevel(logrus.InfoLevel)
+		return slog.LevelInfo, nil
 	case "warn":
-		logger.SetLevel(logrus.WarnLevel)
+		return slog.LevelWarn, nil
 	case "error":
-		logger.SetLevel(logrus.ErrorLevel)
+		return slog.LevelError, nil
 	default:
-		logger.SetLevel(logrus.InfoLevel)
+		return slog.LevelInfo, fmt.Errorf("unsupported log level %q", level)
 	}
+}
 
-	return &Logger{Logger: logger}
+// LevelName returns the name ParseLevel accepts for level
+func LevelName(level slog.Level) string {
+	switch {
+	case level < slog.LevelInfo:
+		return "debug"
+	case level < slog.LevelWarn:
+		return "info"
+	case level < slog.LevelError:
+		return "warn"
+	default:
+		return "error"
+	}
 }
 
-// WithTraceContext adds trace context to log entries
-func (l *Logger) WithTraceContext(ctx context.Context) *logrus.Entry {
-	entry := l.WithFields(logrus.Fields{})
+// Level returns the current level of the logger
+func (l *Logger) Level() slog.Level {
+	return l.level.Level()
+}
 
-	// Extract trace information from context
-	span := trace.SpanFromContext(ctx)
-	if span.SpanContext().IsValid() {
-		spanContext := span.SpanContext()
-		entry = entry.WithFields(logrus.Fields{
-			"trace_id": spanContext.TraceID().String(),
-			"span_id":  spanContext.SpanID().String(),
-		})
+// SetLevel changes the level of the logger and of the module loggers
+// without a level of their own
+func (l *Logger) SetLevel(level slog.Level) {
+	if previous := l.level.Level(); previous != level {
+		l.level.Set(level)
+		l.recordChange(attribute.String("level", LevelName(level)))
 	}
+}
 
-	return entry
+// Module returns the logger of a module. Its records carry the module
+// attribute, and its level can be set apart from the logger's with
+// SetModuleLevel
+func (l *Logger) Module(name string) *Logger {
+	l.modules.mu.Lock()
+	defer l.modules.mu.Unlock()
+	if module, ok := l.modules.loggers[name]; ok {
+		return module
+	}
+
+	h := *l.root
+	h.module = name
+	module := *l
+	module.Logger = slog.New(h.WithAttrs([]slog.Attr{slog.String("module", name)}))
+	l.modules.loggers[name] = &module
+	return &module
+}
+
+// SetModuleLevel overrides the level of the logger of module
+func (l *Logger) SetModuleLevel(module string, level slog.Level) {
+	l.modules.mu.Lock()
+	previous, ok := l.modules.levels[module]
+	l.modules.levels[module] = level
+	l.modules.mu.Unlock()
+
+	if !ok || previous != level {
+		l.recordChange(attribute.String("module", module), attribute.String("level", LevelName(level)))
+	}
+}
+
+// ResetModuleLevel makes the logger of module follow the logger level again
+func (l *Logger) ResetModuleLevel(module string) {
+	l.modules.mu.Lock()
+	_, ok := l.modules.levels[module]
+	delete(l.modules.levels, module)
+	l.modules.mu.Unlock()
+
+	if ok {
+		l.recordChange(attribute.String("module", module), attribute.String("level", "reset"))
+	}
+}
+
+// ModuleLevels returns the modules whose level is overridden
+func (l *Logger) ModuleLevels() map[string]slog.Level {
+	l.modules.mu.RLock()
+	defer l.modules.mu.RUnlock()
+	return maps.Clone(l.modules.levels)
+}
+
+func (l *Logger) recordChange(attrs ...attribute.KeyValue) {
+	l.changes.Add(context.Background(), 1, metric.WithAttributes(attrs...))
+}
+
+// SetOutput replaces the writer of the JSON output
+func (l *Logger) SetOutput(w io.Writer) {
+	l.output.set(w)
+}
+
+// AddHandler adds an output receiving every record the logger emits
+func (l *Logger) AddHandler(h slog.Handler) {
+	l.handlers.add(h)
+}
+
+// WithFields returns an entry with the given fields
+func (l *Logger) WithFields(fields map[string]interface{}) *Entry {
+	return l.WithTraceContext(context.Background()).WithFields(fields)
+}
+
+// WithTraceContext returns an entry logged with ctx, so it carries the trace
+// context, the request ID, the tenant and the API client of ctx
+func (l *Logger) WithTraceContext(ctx context.Context) *Entry {
+	return &Entry{logger: l, ctx: ctx}
 }
 
 // WithGinContext adds Gin context information to log entries
-func (l *Logger) WithGinContext(c *gin.Context) *logrus.Entry {
-	entry := l.WithTraceContext(c.Request.Context())
+func (l *Logger) WithGinContext(c *gin.Context) *Entry {
+	ctx := c.Request.Context()
+	entry := l.WithTraceContext(ctx)
 
 	// Add request information
-	entry = entry.WithFields(logrus.Fields{
+	entry = entry.WithFields(map[string]interface{}{
 		"method":     c.Request.Method,
 		"path":       c.Request.URL.Path,
 		"query":      c.Request.URL.RawQuery,
 		"user_agent": c.Request.UserAgent(),
 		"client_ip":  c.ClientIP(),
-		"request_id": c.GetString("request_id"),
 	})
+	if utils.RequestIDFromContext(ctx) == "" {
+		entry = entry.WithField("request_id", utils.RequestID(c))
+	}
 
 	return entry
 }
 
-// Middleware returns a Gin middleware for request logging
+// Middleware returns a Gin middleware for request logging. Requests are
+// logged by the "http" module. With LOG_REQUEST_SAMPLE_EVERY=N only one in N
+// successful requests is logged; client and server errors always are
 func (l *Logger) Middleware() gin.HandlerFunc {
+	requests := l.Module("http")
+	sampleEvery := requestSampleEvery()
+	var successes atomic.Uint64
+	dropped, _ := otel.Meter("otel-example-api").Int64Counter(
+		"http_request_logs_dropped_total",
+		metric.WithDescription("Total number of successful request logs dropped by sampling"),
+	)
+
 	return gin.LoggerWithFormatter(func(param gin.LogFormatterParams) string {
-		entry := l.WithFields(logrus.Fields{
-			"method":      param.Method,
-			"path":        param.Path,
-			"status_code": param.StatusCode,
-			"latency":     param.Latency.String(),
-			"client_ip":   param.ClientIP,
-			"user_agent":  param.Request.UserAgent(),
-		})
-
-		// Add trace context if available
-		if span := trace.SpanFromContext(param.Request.Context()); span.SpanContext().IsValid() {
-			spanContext := span.SpanContext()
-			entry = entry.WithFields(logrus.Fields{
-				"trace_id": spanContext.TraceID().String(),
-				"span_id":  spanContext.SpanID().String(),
-			})
+		ctx := param.Request.Context()
+		if param.StatusCode < 400 && sampleEvery > 1 && requests.Enabled(ctx, slog.LevelInfo) &&
+			successes.Add(1)%sampleEvery != 1 {
+			dropped.Add(ctx, 1)
+			return ""
 		}
 
-		// Log based on status code
+		attrs := []slog.Attr{
+			slog.String("method", param.Method),
+			slog.String("path", param.Path),
+			slog.Int("status_code", param.StatusCode),
+			slog.String("latency", param.Latency.String()),
+			slog.String("client_ip", param.ClientIP),
+			slog.String("user_agent", param.Request.UserAgent()),
+		}
+		if requestID, ok := param.Keys[utils.RequestIDContextKey].(string); ok && utils.RequestIDFromContext(ctx) == "" {
+			attrs = append(attrs, slog.String("request_id", requestID))
+		}
+
+		// Log based on status code; the trace context comes from ctx
 		if param.StatusCode >= 500 {
-			entry.Error("HTTP request completed with server error")
+			requests.LogAttrs(ctx, slog.LevelError, "HTTP request completed with server error", attrs...)
 		} else if param.StatusCode >= 400 {
-			entry.Warn("HTTP request completed with client error")
+			requests.LogAttrs(ctx, slog.LevelWarn, "HTTP request completed with client error", attrs...)
 		} else {
-			entry.Info("HTTP request completed successfully")
+			if sampleEvery > 1 {
+				// Lets log queries weight sampled lines back to request counts
+				attrs = append(attrs, slog.Uint64("sample_every", sampleEvery))
+			}
+			requests.LogAttrs(ctx, slog.LevelInfo, "HTTP request completed successfully", attrs...)
 		}
 
 		return "" // Return empty string since we're using structured logging
 	})
 }
 
-// LogError logs an error with trace context
-func (l *Logger) LogError(ctx context.Context, err error, message string, fields map[string]interface{}) {
-	entry := l.WithTraceContext(ctx).WithError(err)
-
-	if fields != nil {
-		entry = entry.WithFields(fields)
+// requestSampleEvery reads LOG_REQUEST_SAMPLE_EVERY; values below 2 log
+// every request
+func requestSampleEvery() uint64 {
+	every, err := strconv.ParseUint(os.Getenv("LOG_REQUEST_SAMPLE_EVERY"), 10, 64)
+	if err != nil || every < 1 {
+		return 1
 	}
+	return every
+}
 
-	entry.Error(message)
+// LogError logs an error with trace context
+func (l *Logger) LogError(ctx context.Context, err error, message string, fields map[string]interface{}) {
+	l.WithTraceContext(ctx).WithError(err).WithFields(fields).Error(message)
 }
 
 // LogInfo logs info with trace context
 func (l *Logger) LogInfo(ctx context.Context, message string, fields map[string]interface{}) {
-	entry := l.WithTraceContext(ctx)
-
-	if fields != nil {
-		entry = entry.WithFields(fields)
-	}
-
-	entry.Info(message)
+	l.WithTraceContext(ctx).WithFields(fields).Info(message)
 }
 
 // LogWarn logs warning with trace context
 func (l *Logger) LogWarn(ctx context.Context, message string, fields map[string]interface{}) {
-	entry := l.WithTraceContext(ctx)
-
-	if fields != nil {
-		entry = entry.WithFields(fields)
-	}
-
-	entry.Warn(message)
+	l.WithTraceContext(ctx).WithFields(fields).Warn(message)
 }
 
-// LogDebug logs debug with trace context
+// LogDebug logs debug with trace context. When sampled-only debug logging is
+// enabled, entries are emitted only for requests whose trace is sampled.
 func (l *Logger) LogDebug(ctx context.Context, message string, fields map[string]interface{}) {
-	entry := l.WithTraceContext(ctx)
-
-	if fields != nil {
-		entry = entry.WithFields(fields)
+	if !l.DebugEnabled(ctx) {
+		return
 	}
+	l.WithTraceContext(ctx).WithFields(fields).Debug(message)
+}
 
-	entry.Debug(message)
+// DebugEnabled reports whether a debug entry for ctx would be emitted, so
+// callers can skip building expensive debug payloads
+func (l *Logger) DebugEnabled(ctx context.Context) bool {
+	return l.Enabled(ctx, slog.LevelDebug)
 }
 
 // Global logger instance
 var globalLogger *Logger
 
-// InitGlobalLogger initializes the global logger
+// Remote outputs (syslog/GELF) attached to the global logger
+var globalRemoteOutputs []remoteOutput
+
+// InitGlobalLogger initializes the global logger and attaches the
+// syslog/GELF outputs configured via environment
 func InitGlobalLogger() {
+	if len(globalRemoteOutputs) > 0 {
+		ctx, cancel := context.WithTimeout(context.Background(), defaultDialTimeout)
+		_ = shutdownRemoteOutputs(ctx, globalRemoteOutputs)
+		cancel()
+	}
+
 	globalLogger = NewLogger()
+	globalRemoteOutputs = addRemoteOutputs(globalLogger)
+}
+
+// ShutdownRemoteOutputs flushes queued syslog/GELF messages and closes
+// their connections
+func ShutdownRemoteOutputs(ctx context.Context) error {
+	outputs := globalRemoteOutputs
+	globalRemoteOutputs = nil
+	return shutdownRemoteOutputs(ctx, outputs)
+}
+
+// SetLevel changes the level of the global logger at runtime
+func SetLevel(level string) error {
+	parsed, err := ParseLevel(level)
+	if err != nil {
+		return err
+	}
+	GetLogger().SetLevel(parsed)
+	return nil
 }
 
 // GetLogger returns the global logger instance
@@ -176,11 +339,15 @@ func GetLogger() *Logger {
 }
 
 // Helper functions for global logger access
-func WithTraceContext(ctx context.Context) *logrus.Entry {
+func Module(name string) *Logger {
+	return GetLogger().Module(name)
+}
+
+func WithTraceContext(ctx context.Context) *Entry {
 	return GetLogger().WithTraceContext(ctx)
 }
 
-func WithGinContext(c *gin.Context) *logrus.Entry {
+func WithGinContext(c *gin.Context) *Entry {
 	return GetLogger().WithGinContext(c)
 }
 
@@ -200,9 +367,14 @@ func LogDebug(ctx context.Context, message string, fields map[string]interface{}
 	GetLogger().LogDebug(ctx, message, fields)
 }
 
-// SetupOtelHook sets up the OpenTelemetry hook for the global logger
-func SetupOtelHook(loggerProvider *sdklog.LoggerProvider) {
+func DebugEnabled(ctx context.Context) bool {
+	return GetLogger().DebugEnabled(ctx)
+}
+
+// SetupOtelBridge forwards the records of the global logger to the
+// OpenTelemetry LoggerProvider
+func SetupOtelBridge(loggerProvider *sdklog.LoggerProvider) {
 	if globalLogger != nil {
-		AddOtelHook(globalLogger.Logger, loggerProvider)
+		globalLogger.AddOtelBridge(loggerProvider)
 	}
 }
@@ -1,31 +1,78 @@
 package logging
 
 import (
+	"bytes"
 	"context"
+	"encoding/json"
+	"log/slog"
 	"net/http"
 	"net/http/httptest"
 	"os"
+	"strings"
 	"testing"
 
+	"arquivolivre.com.br/otel/internal/tenant"
+	"arquivolivre.com.br/otel/pkg/utils"
+
 	"github.com/gin-gonic/gin"
 	"github.com/stretchr/testify/assert"
-	"go.opentelemetry.io/otel"
+	"github.com/stretchr/testify/require"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
 )
 
+// newBufferedLogger returns a logger writing its JSON output to a buffer
+func newBufferedLogger() (*Logger, *bytes.Buffer) {
+	var buf bytes.Buffer
+	l := NewLogger()
+	l.SetOutput(&buf)
+	return l, &buf
+}
+
+// lastLine decodes the last JSON line written to buf
+func lastLine(t *testing.T, buf *bytes.Buffer) map[string]interface{} {
+	t.Helper()
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	var line map[string]interface{}
+	require.NoError(t, json.Unmarshal([]byte(lines[len(lines)-1]), &line))
+	return line
+}
+
 func TestNewLoggerLevelFromEnv(t *testing.T) {
 	_ = os.Setenv("LOG_LEVEL", "debug")
 	defer func() { _ = os.Unsetenv("LOG_LEVEL") }()
 	l := NewLogger()
-	if l.Level.String() != "debug" {
-		t.Fatalf("expected debug, got %s", l.Level.String())
+	if l.Level() != slog.LevelDebug {
+		t.Fatalf("expected debug, got %s", LevelName(l.Level()))
 	}
 }
 
+func TestSetLevel(t *testing.T) {
+	InitGlobalLogger()
+	defer InitGlobalLogger()
+
+	assert.NoError(t, SetLevel("warn"))
+	assert.Equal(t, slog.LevelWarn, GetLogger().Level())
+	assert.Error(t, SetLevel("verbose"))
+	assert.Equal(t, slog.LevelWarn, GetLogger().Level())
+}
+
+func TestJSONOutputFormat(t *testing.T) {
+	l, buf := newBufferedLogger()
+	l.WithFields(map[string]interface{}{"count": 3}).Warn("slow query")
+
+	line := lastLine(t, buf)
+	assert.Equal(t, "warn", line["level"])
+	assert.Equal(t, "slow query", line["message"])
+	assert.Equal(t, float64(3), line["count"])
+	assert.Regexp(t, `^\d{4}-\d{2}-\d{2}T\d{2}:\d{2}:\d{2}\.\d{3}`, line["timestamp"])
+}
+
 func TestWithTraceAndGinContext(t *testing.T) {
 	gin.SetMode(gin.TestMode)
-	l := NewLogger()
+	l, buf := newBufferedLogger()
 	// Trace context with background should not add IDs but should not panic
-	_ = l.WithTraceContext(context.Background())
+	l.WithTraceContext(context.Background()).Info("no trace")
+	assert.NotContains(t, lastLine(t, buf), "trace_id")
 
 	// Build a minimal gin.Context
 	w := httptest.NewRecorder()
@@ -33,33 +80,84 @@ func TestWithTraceAndGinContext(t *testing.T) {
 	req := httptest.NewRequest("GET", "/x?y=1", nil)
 	c.Request = req
 	c.Set("request_id", "req-1")
-	_ = l.WithGinContext(c)
+	l.WithGinContext(c).Info("gin")
+
+	line := lastLine(t, buf)
+	assert.Equal(t, "req-1", line["request_id"])
+	assert.Equal(t, "/x", line["path"])
+	assert.Equal(t, "y=1", line["query"])
+}
+
+func TestWithTraceContextAddsRequestID(t *testing.T) {
+	l, buf := newBufferedLogger()
+	l.WithTraceContext(context.Background()).Info("none")
+	assert.NotContains(t, lastLine(t, buf), "request_id")
+
+	ctx := utils.ContextWithRequestID(context.Background(), "req-2")
+	l.WithTraceContext(ctx).Info("request")
+	assert.Equal(t, "req-2", lastLine(t, buf)["request_id"])
+
+	// slog calls made with the context carry it too
+	l.InfoContext(ctx, "slog")
+	assert.Equal(t, "req-2", lastLine(t, buf)["request_id"])
+}
+
+func TestWithTraceContextAddsTenant(t *testing.T) {
+	l, buf := newBufferedLogger()
+	l.WithTraceContext(context.Background()).Info("none")
+	assert.NotContains(t, lastLine(t, buf), tenant.Key)
+
+	ctx, err := tenant.WithID(context.Background(), "acme")
+	assert.NoError(t, err)
+	l.WithTraceContext(ctx).Info("tenant")
+	assert.Equal(t, "acme", lastLine(t, buf)[tenant.Key])
+}
+
+func TestMiddlewareLogsRequestID(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	l, buf := newBufferedLogger()
+
+	r := gin.New()
+	r.Use(func(c *gin.Context) { c.Set(utils.RequestIDContextKey, "req-3") })
+	r.Use(l.Middleware())
+	r.GET("/x", func(c *gin.Context) { c.Status(http.StatusOK) })
+	r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/x", nil))
+
+	assert.Contains(t, buf.String(), `"request_id":"req-3"`)
 }
 
 func TestNewLoggerDifferentLevels(t *testing.T) {
 	tests := []struct {
 		envValue string
-		expected string
+		expected slog.Level
 	}{
-		{"info", "info"},
-		{"warn", "warning"},
-		{"error", "error"},
-		{"invalid", "info"}, // defaults to info
-		{"", "info"},        // defaults to info
+		{"info", slog.LevelInfo},
+		{"warn", slog.LevelWarn},
+		{"error", slog.LevelError},
+		{"invalid", slog.LevelInfo}, // defaults to info
+		{"", slog.LevelInfo},        // defaults to info
 	}
 
 	for _, test := range tests {
 		_ = os.Setenv("LOG_LEVEL", test.envValue)
 		l := NewLogger()
-		if l.Level.String() != test.expected {
-			t.Errorf("for env %s expected %s, got %s", test.envValue, test.expected, l.Level.String())
+		if l.Level() != test.expected {
+			t.Errorf("for env %s expected %s, got %s", test.envValue, test.expected, l.Level())
 		}
 		_ = os.Unsetenv("LOG_LEVEL")
 	}
 }
 
+func TestLevelName(t *testing.T) {
+	for _, name := range []string{"debug", "info", "warn", "error"} {
+		level, err := ParseLevel(name)
+		require.NoError(t, err)
+		assert.Equal(t, name, LevelName(level))
+	}
+}
+
 func TestLoggerMethods(t *testing.T) {
-	l := NewLogger()
+	l, buf := newBufferedLogger()
 	ctx := context.Background()
 	fields := map[string]interface{}{"key": "value"}
 
@@ -68,6 +166,12 @@ func TestLoggerMethods(t *testing.T) {
 	l.LogInfo(ctx, "test info", fields)
 	l.LogWarn(ctx, "test warn", fields)
 	l.LogDebug(ctx, "test debug", nil)
+
+	out := buf.String()
+	assert.Contains(t, out, `"level":"error"`)
+	assert.Contains(t, out, "test info")
+	assert.Contains(t, out, "test warn")
+	assert.NotContains(t, out, "test debug", "debug is below the default level")
 }
 
 func TestGlobalLoggerFunctions(t *testing.T) {
@@ -102,12 +206,12 @@ func TestGlobalLoggerFunctions(t *testing.T) {
 	WithGinContext(c)
 }
 
-func TestSetupOtelHook(t *testing.T) {
+func TestSetupOtelBridge(t *testing.T) {
 	globalLogger = NewLogger()
-	SetupOtelHook(nil)
+	SetupOtelBridge(nil)
 
 	globalLogger = nil
-	SetupOtelHook(nil) // Should not panic when globalLogger is nil
+	SetupOtelBridge(nil) // Should not panic when globalLogger is nil
 }
 
 func TestLoggerMiddleware(t *testing.T) {
@@ -127,7 +231,7 @@ func TestLoggerMiddleware(t *testing.T) {
 
 func TestLoggerMiddleware_ErrorCodes(t *testing.T) {
 	gin.SetMode(gin.TestMode)
-	l := NewLogger()
+	l, buf := newBufferedLogger()
 	r := gin.New()
 	r.Use(l.Middleware())
 
@@ -146,29 +250,182 @@ func TestLoggerMiddleware_ErrorCodes(t *testing.T) {
 	req := httptest.NewRequest(http.MethodGet, "/badreq", nil)
 	r.ServeHTTP(w, req)
 	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Equal(t, "warn", lastLine(t, buf)["level"])
 
 	// Test 5xx
 	w = httptest.NewRecorder()
 	req = httptest.NewRequest(http.MethodGet, "/error", nil)
 	r.ServeHTTP(w, req)
 	assert.Equal(t, http.StatusInternalServerError, w.Code)
+	assert.Equal(t, "error", lastLine(t, buf)["level"])
 }
 
 func TestWithTraceContext_ValidSpan(t *testing.T) {
-	l := NewLogger()
+	l, buf := newBufferedLogger()
 
 	// Create a context with a valid span
-	tracer := otel.Tracer("test")
-	ctx, span := tracer.Start(context.Background(), "test-operation")
+	ctx, span := sdktrace.NewTracerProvider().Tracer("test").Start(context.Background(), "test-operation")
 	defer span.End()
 
-	entry := l.WithTraceContext(ctx)
+	l.WithTraceContext(ctx).Info("traced")
+
+	line := lastLine(t, buf)
+	assert.Equal(t, span.SpanContext().TraceID().String(), line["trace_id"])
+	assert.Equal(t, span.SpanContext().SpanID().String(), line["span_id"])
+}
+
+func TestDerivedLoggersShareOutputs(t *testing.T) {
+	l, buf := newBufferedLogger()
+	derived := l.With("component", "cache")
+
+	// Outputs added after deriving still receive the derived records
+	var extra bytes.Buffer
+	l.AddHandler(slog.NewJSONHandler(&extra, nil))
+	derived.Info("evicted")
+
+	assert.Equal(t, "cache", lastLine(t, buf)["component"])
+	assert.Contains(t, extra.String(), `"component":"cache"`)
+
+	// Level changes apply to derived loggers
+	l.SetLevel(slog.LevelError)
+	buf.Reset()
+	derived.Warn("suppressed")
+	assert.Empty(t, buf.String())
+}
+
+func TestLogDebug_SampledOnly(t *testing.T) {
+	_ = os.Setenv("LOG_LEVEL", "info")
+	_ = os.Setenv("LOG_DEBUG_SAMPLED_ONLY", "true")
+	defer func() {
+		_ = os.Unsetenv("LOG_LEVEL")
+		_ = os.Unsetenv("LOG_DEBUG_SAMPLED_ONLY")
+	}()
+
+	l, buf := newBufferedLogger()
+
+	sampled, sampledSpan := sdktrace.NewTracerProvider(sdktrace.WithSampler(sdktrace.AlwaysSample())).
+		Tracer("test").Start(context.Background(), "sampled")
+	defer sampledSpan.End()
+	unsampled, unsampledSpan := sdktrace.NewTracerProvider(sdktrace.WithSampler(sdktrace.NeverSample())).
+		Tracer("test").Start(context.Background(), "unsampled")
+	defer unsampledSpan.End()
+
+	assert.True(t, l.DebugEnabled(sampled))
+	assert.False(t, l.DebugEnabled(unsampled))
+	assert.False(t, l.DebugEnabled(context.Background()))
+
+	l.LogDebug(unsampled, "dropped debug", nil)
+	l.LogDebug(context.Background(), "dropped debug", nil)
+	assert.Empty(t, buf.String())
+
+	l.LogDebug(sampled, "kept debug", map[string]interface{}{"key": "value"})
+	out := buf.String()
+	assert.Contains(t, out, "kept debug")
+	assert.Contains(t, out, `"level":"debug"`)
+	assert.Contains(t, out, sampledSpan.SpanContext().TraceID().String())
+
+	// The base level is untouched, so debug calls without a sampled trace
+	// stay suppressed
+	buf.Reset()
+	l.Debug("plain debug")
+	assert.Empty(t, buf.String())
+}
+
+func TestLogDebug_SampledOnlyAtDebugLevel(t *testing.T) {
+	_ = os.Setenv("LOG_LEVEL", "debug")
+	_ = os.Setenv("LOG_DEBUG_SAMPLED_ONLY", "true")
+	defer func() {
+		_ = os.Unsetenv("LOG_LEVEL")
+		_ = os.Unsetenv("LOG_DEBUG_SAMPLED_ONLY")
+	}()
+
+	l, buf := newBufferedLogger()
+
+	sampled, sampledSpan := sdktrace.NewTracerProvider(sdktrace.WithSampler(sdktrace.AlwaysSample())).
+		Tracer("test").Start(context.Background(), "sampled")
+	defer sampledSpan.End()
+	unsampled, unsampledSpan := sdktrace.NewTracerProvider(sdktrace.WithSampler(sdktrace.NeverSample())).
+		Tracer("test").Start(context.Background(), "unsampled")
+	defer unsampledSpan.End()
+
+	// The flag still requires a sampled trace when LOG_LEVEL lets debug in
+	assert.True(t, l.DebugEnabled(sampled))
+	assert.False(t, l.DebugEnabled(unsampled))
+	l.LogDebug(unsampled, "dropped debug", nil)
+	l.Debug("plain debug")
+	assert.Empty(t, buf.String())
+
+	l.LogDebug(sampled, "kept debug", nil)
+	assert.Contains(t, buf.String(), "kept debug")
+
+	// Other levels follow LOG_LEVEL as usual
+	buf.Reset()
+	l.LogInfo(unsampled, "kept info", nil)
+	assert.Contains(t, buf.String(), "kept info")
+}
+
+func TestLogDebug_DefaultModeFollowsLevel(t *testing.T) {
+	_ = os.Setenv("LOG_LEVEL", "debug")
+	defer func() { _ = os.Unsetenv("LOG_LEVEL") }()
+
+	l, buf := newBufferedLogger()
+
+	assert.True(t, l.DebugEnabled(context.Background()))
+	l.LogDebug(context.Background(), "always logged", nil)
+	assert.Contains(t, buf.String(), "always logged")
+}
+
+func TestModuleLevels(t *testing.T) {
+	l, buf := newBufferedLogger()
+	jobs := l.Module("jobs")
+	assert.Same(t, jobs, l.Module("jobs"))
+
+	jobs.Info("job done")
+	assert.Equal(t, "jobs", lastLine(t, buf)["module"])
+
+	// Overrides apply to one module only
+	l.SetModuleLevel("jobs", slog.LevelError)
+	buf.Reset()
+	jobs.Info("suppressed")
+	l.Info("kept")
+	assert.NotContains(t, buf.String(), "suppressed")
+	assert.Contains(t, buf.String(), "kept")
+
+	l.SetModuleLevel("jobs", slog.LevelDebug)
+	l.SetLevel(slog.LevelError)
+	jobs.Debug("module debug")
+	assert.Contains(t, buf.String(), "module debug", "a module override wins over the logger level")
+	assert.Equal(t, map[string]slog.Level{"jobs": slog.LevelDebug}, l.ModuleLevels())
+
+	l.ResetModuleLevel("jobs")
+	buf.Reset()
+	jobs.Warn("follows the logger again")
+	assert.Empty(t, buf.String())
+	assert.Empty(t, l.ModuleLevels())
+}
+
+func TestMiddlewareSamplesSuccessfulRequests(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	setEnv(t, "LOG_REQUEST_SAMPLE_EVERY", "3")
+	l, buf := newBufferedLogger()
+
+	r := gin.New()
+	r.Use(l.Middleware())
+	r.GET("/ok", func(c *gin.Context) { c.Status(http.StatusOK) })
+	r.GET("/missing", func(c *gin.Context) { c.Status(http.StatusNotFound) })
+	r.GET("/broken", func(c *gin.Context) { c.Status(http.StatusInternalServerError) })
+
+	for range 6 {
+		r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/ok", nil))
+	}
+	assert.Equal(t, 2, strings.Count(buf.String(), "completed successfully"), "one in three successes is logged")
+	assert.Equal(t, float64(3), lastLine(t, buf)["sample_every"])
 
-	// Verify the entry has trace fields when span is valid
-	assert.NotNil(t, entry)
-	// The span context should be valid and add trace fields
-	if span.SpanContext().IsValid() {
-		assert.Contains(t, entry.Data, "trace_id")
-		assert.Contains(t, entry.Data, "span_id")
+	// Errors are never sampled
+	for range 2 {
+		r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/missing", nil))
+		r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/broken", nil))
 	}
+	assert.Equal(t, 2, strings.Count(buf.String(), "client error"))
+	assert.Equal(t, 2, strings.Count(buf.String(), "server error"))
 }
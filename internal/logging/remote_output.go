@@ -0,0 +1,378 @@
+package logging
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"log"
+	"log/slog"
+	"net"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	networkUDP = "udp"
+	networkTCP = "tcp"
+	networkTLS = "tls"
+
+	defaultDialTimeout  = 5 * time.Second
+	defaultBufferSize   = 1024
+	initialRetryBackoff = 500 * time.Millisecond
+	maxRetryBackoff     = 30 * time.Second
+)
+
+// RemoteOutputConfig holds the settings for a remote log destination
+type RemoteOutputConfig struct {
+	Network            string
+	Address            string
+	AppName            string
+	TLSCAFile          string
+	InsecureSkipVerify bool
+	BufferSize         int
+}
+
+// Enabled reports whether a destination address has been configured
+func (c RemoteOutputConfig) Enabled() bool {
+	return c.Address != ""
+}
+
+// GetSyslogConfig reads the syslog output configuration from environment
+func GetSyslogConfig() RemoteOutputConfig {
+	return remoteOutputConfigFromEnv("LOG_SYSLOG")
+}
+
+// GetGELFConfig reads the GELF output configuration from environment
+func GetGELFConfig() RemoteOutputConfig {
+	return remoteOutputConfigFromEnv("LOG_GELF")
+}
+
+func remoteOutputConfigFromEnv(prefix string) RemoteOutputConfig {
+	bufferSize, err := strconv.Atoi(os.Getenv(prefix + "_BUFFER_SIZE"))
+	if err != nil || bufferSize < 1 {
+		bufferSize = defaultBufferSize
+	}
+
+	return RemoteOutputConfig{
+		Network:            strings.ToLower(envOrDefault(prefix+"_NETWORK", networkUDP)),
+		Address:            os.Getenv(prefix + "_ADDRESS"),
+		AppName:            envOrDefault(prefix+"_APP_NAME", envOrDefault("OTEL_SERVICE_NAME", "otel-example-api")),
+		TLSCAFile:          os.Getenv(prefix + "_TLS_CA_FILE"),
+		InsecureSkipVerify: os.Getenv(prefix+"_TLS_INSECURE_SKIP_VERIFY") == "true",
+		BufferSize:         bufferSize,
+	}
+}
+
+// validateRemoteOutput checks the settings that can be verified without dialing
+func validateRemoteOutput(cfg RemoteOutputConfig) error {
+	switch cfg.Network {
+	case networkUDP, networkTCP:
+		return nil
+	case networkTLS:
+		_, err := buildTLSConfig(cfg)
+		return err
+	default:
+		return fmt.Errorf("unsupported network %q (expected udp, tcp or tls)", cfg.Network)
+	}
+}
+
+// dialRemote opens a connection to a remote log destination.
+// The "tls" network is TCP wrapped in TLS.
+func dialRemote(cfg RemoteOutputConfig) (net.Conn, error) {
+	switch cfg.Network {
+	case networkUDP, networkTCP:
+		return net.DialTimeout(cfg.Network, cfg.Address, defaultDialTimeout)
+	case networkTLS:
+		tlsCfg, err := buildTLSConfig(cfg)
+		if err != nil {
+			return nil, err
+		}
+		dialer := &net.Dialer{Timeout: defaultDialTimeout}
+		return tls.DialWithDialer(dialer, networkTCP, cfg.Address, tlsCfg)
+	default:
+		return nil, fmt.Errorf("unsupported network %q (expected udp, tcp or tls)", cfg.Network)
+	}
+}
+
+// buildTLSConfig creates the TLS configuration for a remote destination
+func buildTLSConfig(cfg RemoteOutputConfig) (*tls.Config, error) {
+	tlsCfg := &tls.Config{
+		MinVersion:         tls.VersionTLS12,
+		InsecureSkipVerify: cfg.InsecureSkipVerify, // #nosec G402 -- opt-in for self-signed lab setups
+	}
+
+	if cfg.TLSCAFile != "" {
+		pem, err := os.ReadFile(cfg.TLSCAFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read CA file: %w", err)
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("no certificates found in CA file %s", cfg.TLSCAFile)
+		}
+		tlsCfg.RootCAs = pool
+	}
+
+	return tlsCfg, nil
+}
+
+// asyncWriter delivers frames to a remote destination from a background
+// goroutine so that log calls never block on the network. Messages are
+// dropped when the buffer is full, and reconnects use exponential backoff.
+type asyncWriter struct {
+	cfg   RemoteOutputConfig
+	name  string
+	queue chan [][]byte
+	stop  chan struct{}
+	done  chan struct{}
+
+	dropped  atomic.Uint64
+	stopOnce sync.Once
+	conn     net.Conn
+}
+
+func newAsyncWriter(name string, cfg RemoteOutputConfig) *asyncWriter {
+	bufferSize := cfg.BufferSize
+	if bufferSize < 1 {
+		bufferSize = defaultBufferSize
+	}
+
+	w := &asyncWriter{
+		cfg:   cfg,
+		name:  name,
+		queue: make(chan [][]byte, bufferSize),
+		stop:  make(chan struct{}),
+		done:  make(chan struct{}),
+	}
+	go w.run()
+	return w
+}
+
+// enqueue queues a message made of one or more frames without blocking
+func (w *asyncWriter) enqueue(frames ...[]byte) {
+	select {
+	case <-w.stop:
+		w.dropped.Add(1)
+		return
+	default:
+	}
+
+	select {
+	case w.queue <- frames:
+	default:
+		w.dropped.Add(1)
+	}
+}
+
+// Dropped returns the number of messages discarded because the buffer was full
+// or the destination was unavailable during shutdown
+func (w *asyncWriter) Dropped() uint64 {
+	return w.dropped.Load()
+}
+
+// Shutdown flushes queued messages and closes the connection. It returns
+// ctx.Err() if the queue could not be drained in time.
+func (w *asyncWriter) Shutdown(ctx context.Context) error {
+	w.stopOnce.Do(func() { close(w.stop) })
+
+	select {
+	case <-w.done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (w *asyncWriter) run() {
+	defer close(w.done)
+
+	backoff := initialRetryBackoff
+	for {
+		select {
+		case frames := <-w.queue:
+			for !w.send(frames) {
+				select {
+				case <-w.stop:
+					w.dropped.Add(1)
+					w.drain()
+					return
+				case <-time.After(backoff):
+				}
+				backoff = min(backoff*2, maxRetryBackoff)
+			}
+			backoff = initialRetryBackoff
+		case <-w.stop:
+			w.drain()
+			return
+		}
+	}
+}
+
+// drain makes a single delivery attempt for every queued message
+func (w *asyncWriter) drain() {
+	defer w.closeConn()
+
+	for {
+		select {
+		case frames := <-w.queue:
+			if !w.send(frames) {
+				w.dropped.Add(uint64(len(w.queue)) + 1)
+				return
+			}
+		default:
+			return
+		}
+	}
+}
+
+// send writes all frames, dialing first if needed. It reports whether the
+// message was delivered.
+func (w *asyncWriter) send(frames [][]byte) bool {
+	if w.conn == nil {
+		conn, err := dialRemote(w.cfg)
+		if err != nil {
+			log.Printf("Warning: %s output unavailable: %v", w.name, err)
+			return false
+		}
+		w.conn = conn
+	}
+
+	for _, frame := range frames {
+		if _, err := w.conn.Write(frame); err != nil {
+			log.Printf("Warning: %s output write failed: %v", w.name, err)
+			w.closeConn()
+			return false
+		}
+	}
+	return true
+}
+
+func (w *asyncWriter) closeConn() {
+	if w.conn != nil {
+		_ = w.conn.Close()
+		w.conn = nil
+	}
+}
+
+// remoteOutput is implemented by handlers that ship logs to a remote destination
+type remoteOutput interface {
+	slog.Handler
+	Shutdown(ctx context.Context) error
+}
+
+// addRemoteOutputs adds the syslog and GELF outputs configured via environment.
+// Invalid settings are logged and otherwise ignored so that a missing log sink
+// never prevents the application from starting.
+func addRemoteOutputs(logger *Logger) []remoteOutput {
+	var outputs []remoteOutput
+
+	if cfg := GetSyslogConfig(); cfg.Enabled() {
+		handler, err := NewSyslogHandler(cfg)
+		if err != nil {
+			log.Printf("Warning: Failed to configure syslog output: %v", err)
+		} else {
+			logger.AddHandler(handler)
+			outputs = append(outputs, handler)
+		}
+	}
+
+	if cfg := GetGELFConfig(); cfg.Enabled() {
+		handler, err := NewGELFHandler(cfg)
+		if err != nil {
+			log.Printf("Warning: Failed to configure GELF output: %v", err)
+		} else {
+			logger.AddHandler(handler)
+			outputs = append(outputs, handler)
+		}
+	}
+
+	return outputs
+}
+
+// remoteFields holds the attributes added to a remote output with
+// WithAttrs, flattened with the names of the enclosing groups
+type remoteFields struct {
+	attrs  []slog.Attr
+	prefix string
+}
+
+func (f remoteFields) withAttrs(attrs []slog.Attr) remoteFields {
+	flattened := slices.Clip(f.attrs)
+	for _, attr := range attrs {
+		flattened = appendFlattened(flattened, f.prefix, attr)
+	}
+	f.attrs = flattened
+	return f
+}
+
+func (f remoteFields) withGroup(name string) remoteFields {
+	if name != "" {
+		f.prefix += name + "."
+	}
+	return f
+}
+
+// of returns the fields of a record: the attributes of the output followed
+// by those of the record
+func (f remoteFields) of(r slog.Record) map[string]interface{} {
+	fields := make(map[string]interface{}, len(f.attrs)+r.NumAttrs())
+	for _, attr := range f.attrs {
+		fields[attr.Key] = attr.Value.Any()
+	}
+	r.Attrs(func(attr slog.Attr) bool {
+		for _, a := range appendFlattened(nil, f.prefix, attr) {
+			fields[a.Key] = a.Value.Any()
+		}
+		return true
+	})
+	return fields
+}
+
+// appendFlattened appends attr to attrs, replacing groups by their members
+// named "group.member"
+func appendFlattened(attrs []slog.Attr, prefix string, attr slog.Attr) []slog.Attr {
+	attr.Value = attr.Value.Resolve()
+	if attr.Value.Kind() != slog.KindGroup {
+		if attr.Key == "" {
+			return attrs
+		}
+		return append(attrs, slog.Attr{Key: prefix + attr.Key, Value: attr.Value})
+	}
+	if attr.Key != "" {
+		prefix += attr.Key + "."
+	}
+	for _, member := range attr.Value.Group() {
+		attrs = appendFlattened(attrs, prefix, member)
+	}
+	return attrs
+}
+
+// toString converts any value to string
+func toString(value interface{}) string {
+	return fmt.Sprintf("%v", value)
+}
+
+// shutdownRemoteOutputs flushes and closes the given outputs
+func shutdownRemoteOutputs(ctx context.Context, outputs []remoteOutput) error {
+	var errs []error
+	for _, output := range outputs {
+		if err := output.Shutdown(ctx); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func envOrDefault(key, defaultValue string) string {
+	if value := os.Getenv(key); value != "" {
+		return value
+	}
+	return defaultValue
+}
@@ -0,0 +1,165 @@
+package logging
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"log/slog"
+	"math/big"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newTLSListener starts a TLS listener with a self-signed certificate for
+// 127.0.0.1 and returns it together with the path of the PEM-encoded CA
+func newTLSListener(t *testing.T) (net.Listener, string) {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "log-sink"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		KeyUsage:              x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+		IPAddresses:           []net.IP{net.IPv4(127, 0, 0, 1)},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	require.NoError(t, err)
+
+	caFile := filepath.Join(t.TempDir(), "ca.pem")
+	require.NoError(t, os.WriteFile(caFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0o600))
+
+	ln, err := tls.Listen("tcp", "127.0.0.1:0", &tls.Config{
+		MinVersion:   tls.VersionTLS12,
+		Certificates: []tls.Certificate{{Certificate: [][]byte{der}, PrivateKey: key}},
+	})
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = ln.Close() })
+
+	return ln, caFile
+}
+
+func TestSyslogHandler_TLSWithCAFile(t *testing.T) {
+	ln, caFile := newTLSListener(t)
+	received := acceptOnce(t, ln)
+
+	setEnv(t, "LOG_SYSLOG_ADDRESS", ln.Addr().String())
+	setEnv(t, "LOG_SYSLOG_NETWORK", "tls")
+	setEnv(t, "LOG_SYSLOG_TLS_CA_FILE", caFile)
+	setEnv(t, "LOG_SYSLOG_TLS_INSECURE_SKIP_VERIFY", "")
+
+	handler, err := NewSyslogHandler(GetSyslogConfig())
+	require.NoError(t, err)
+	defer func() { _ = handler.Shutdown(context.Background()) }()
+
+	require.NoError(t, handler.Handle(context.Background(), slog.NewRecord(time.Now(), slog.LevelInfo, "over tls", 0)))
+
+	select {
+	case raw := <-received:
+		assert.True(t, strings.HasSuffix(string(raw), " - over tls"), string(raw))
+	case <-time.After(3 * time.Second):
+		t.Fatal("syslog message not received over TLS")
+	}
+}
+
+func TestDialRemote_TLSRequiresTrustedCA(t *testing.T) {
+	ln, caFile := newTLSListener(t)
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			// Complete the handshake from the server side, then hang up
+			_ = conn.(*tls.Conn).Handshake()
+			_ = conn.Close()
+		}
+	}()
+
+	_, err := dialRemote(RemoteOutputConfig{Network: "tls", Address: ln.Addr().String()})
+	require.Error(t, err, "self-signed certificate must not verify against system roots")
+
+	conn, err := dialRemote(RemoteOutputConfig{Network: "tls", Address: ln.Addr().String(), TLSCAFile: caFile})
+	require.NoError(t, err)
+	_ = conn.Close()
+}
+
+func TestBuildTLSConfig_MissingCAFile(t *testing.T) {
+	_, err := buildTLSConfig(RemoteOutputConfig{TLSCAFile: "/does/not/exist.pem"})
+	assert.Error(t, err)
+
+	_, err = NewGELFHandler(RemoteOutputConfig{Network: "tls", Address: "x:1", TLSCAFile: "/does/not/exist.pem"})
+	assert.Error(t, err)
+}
+
+func TestRemoteOutput_UnreachableSinkDoesNotBlock(t *testing.T) {
+	// Reserve a port and close it so connections are refused
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	addr := ln.Addr().String()
+	_ = ln.Close()
+
+	handler, err := NewSyslogHandler(RemoteOutputConfig{Network: "tcp", Address: addr, BufferSize: 4})
+	require.NoError(t, err)
+
+	start := time.Now()
+	for i := 0; i < 50; i++ {
+		require.NoError(t, handler.Handle(context.Background(), slog.NewRecord(time.Now(), slog.LevelInfo, "lost", 0)))
+	}
+	assert.Less(t, time.Since(start), time.Second, "Handle must not wait for the network")
+	assert.Greater(t, handler.Dropped(), uint64(0), "overflowing the buffer drops messages")
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+	require.NoError(t, handler.Shutdown(ctx))
+}
+
+func TestAsyncWriter_ShutdownFlushesQueue(t *testing.T) {
+	server := listenUDP(t)
+
+	w := newAsyncWriter("test", RemoteOutputConfig{Network: "udp", Address: server.LocalAddr().String()})
+	w.enqueue([]byte("one"))
+	w.enqueue([]byte("two"))
+	require.NoError(t, w.Shutdown(context.Background()))
+
+	assert.Equal(t, "one", string(readDatagram(t, server)))
+	assert.Equal(t, "two", string(readDatagram(t, server)))
+
+	// Messages queued after shutdown are dropped instead of panicking
+	w.enqueue([]byte("late"))
+	assert.Equal(t, uint64(1), w.Dropped())
+}
+
+func TestInitGlobalLogger_AttachesRemoteOutputsOnce(t *testing.T) {
+	server := listenUDP(t)
+	setEnv(t, "LOG_SYSLOG_ADDRESS", server.LocalAddr().String())
+	setEnv(t, "LOG_SYSLOG_NETWORK", "udp")
+	setEnv(t, "LOG_GELF_ADDRESS", "")
+
+	InitGlobalLogger()
+	require.Len(t, globalRemoteOutputs, 1)
+
+	// Loggers created for other purposes do not open their own connections
+	assert.Len(t, NewLogger().handlers.list(), 1)
+
+	require.NoError(t, ShutdownRemoteOutputs(context.Background()))
+	assert.Empty(t, globalRemoteOutputs)
+}
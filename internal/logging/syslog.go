@@ -0,0 +1,169 @@
+package logging
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"maps"
+	"os"
+	"slices"
+	"strings"
+)
+
+const (
+	// syslogFacilityLocal0 is the facility used for all application logs
+	syslogFacilityLocal0 = 16
+	// syslogStructuredDataID uses the documentation enterprise number from RFC5424
+	syslogStructuredDataID = "fields@32473"
+	syslogNilValue         = "-"
+	// syslogTimestampLayout caps TIME-SECFRAC at the 6 digits RFC5424 allows
+	syslogTimestampLayout = "2006-01-02T15:04:05.000000Z07:00"
+)
+
+// SyslogHandler is a slog handler that ships logs to a syslog server using RFC5424
+type SyslogHandler struct {
+	cfg      RemoteOutputConfig
+	hostname string
+	pid      string
+	writer   *asyncWriter
+	fields   remoteFields
+}
+
+// NewSyslogHandler creates a new syslog handler. Messages are delivered in the
+// background, so an unreachable server does not fail construction.
+func NewSyslogHandler(cfg RemoteOutputConfig) (*SyslogHandler, error) {
+	if err := validateRemoteOutput(cfg); err != nil {
+		return nil, fmt.Errorf("invalid syslog output configuration: %w", err)
+	}
+
+	hostname, err := os.Hostname()
+	if err != nil || hostname == "" {
+		hostname = syslogNilValue
+	}
+
+	return &SyslogHandler{
+		cfg:      cfg,
+		hostname: hostname,
+		pid:      fmt.Sprintf("%d", os.Getpid()),
+		writer:   newAsyncWriter("syslog", cfg),
+	}, nil
+}
+
+// Enabled reports true for every level; the logger applies its own level
+func (h *SyslogHandler) Enabled(context.Context, slog.Level) bool {
+	return true
+}
+
+// Handle formats the record as an RFC5424 message and queues it for delivery
+func (h *SyslogHandler) Handle(_ context.Context, r slog.Record) error {
+	msg := h.format(r)
+	if h.cfg.Network != networkUDP {
+		// RFC5425/RFC6587 octet-counting framing for stream transports
+		msg = fmt.Sprintf("%d %s", len(msg), msg)
+	}
+	h.writer.enqueue([]byte(msg))
+	return nil
+}
+
+// WithAttrs returns a handler adding attrs to every message
+func (h *SyslogHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	c := *h
+	c.fields = h.fields.withAttrs(attrs)
+	return &c
+}
+
+// WithGroup returns a handler prefixing the fields that follow with name
+func (h *SyslogHandler) WithGroup(name string) slog.Handler {
+	c := *h
+	c.fields = h.fields.withGroup(name)
+	return &c
+}
+
+// Dropped returns the number of messages that could not be delivered
+func (h *SyslogHandler) Dropped() uint64 {
+	return h.writer.Dropped()
+}
+
+// Shutdown flushes queued messages and closes the connection
+func (h *SyslogHandler) Shutdown(ctx context.Context) error {
+	return h.writer.Shutdown(ctx)
+}
+
+// format renders a record as
+// <PRI>1 TIMESTAMP HOSTNAME APP-NAME PROCID MSGID [SD] MSG
+func (h *SyslogHandler) format(r slog.Record) string {
+	priority := syslogFacilityLocal0*8 + syslogSeverity(r.Level)
+
+	return fmt.Sprintf("<%d>1 %s %s %s %s %s %s %s",
+		priority,
+		r.Time.UTC().Format(syslogTimestampLayout),
+		h.hostname,
+		sanitizeSyslogHeader(h.cfg.AppName),
+		h.pid,
+		syslogNilValue,
+		formatStructuredData(h.fields.of(r)),
+		r.Message,
+	)
+}
+
+// syslogSeverity converts a slog level to syslog severity
+func syslogSeverity(level slog.Level) int {
+	switch {
+	case level >= slog.LevelError:
+		return 3
+	case level >= slog.LevelWarn:
+		return 4
+	case level >= slog.LevelInfo:
+		return 6
+	default:
+		return 7 // debug
+	}
+}
+
+// formatStructuredData renders the fields of a record as a single SD-ELEMENT
+func formatStructuredData(fields map[string]interface{}) string {
+	if len(fields) == 0 {
+		return syslogNilValue
+	}
+
+	var b strings.Builder
+	b.WriteString("[" + syslogStructuredDataID)
+	for _, key := range slices.Sorted(maps.Keys(fields)) {
+		name := sanitizeSDName(key)
+		if name == "" {
+			continue
+		}
+		fmt.Fprintf(&b, " %s=\"%s\"", name, escapeSDValue(toString(fields[key])))
+	}
+	b.WriteString("]")
+
+	return b.String()
+}
+
+// sanitizeSDName keeps only characters allowed in SD-NAME (max 32 chars)
+func sanitizeSDName(name string) string {
+	var b strings.Builder
+	for _, r := range name {
+		if r > 32 && r < 127 && r != '=' && r != ']' && r != '"' {
+			b.WriteRune(r)
+		}
+		if b.Len() == 32 {
+			break
+		}
+	}
+	return b.String()
+}
+
+// escapeSDValue escapes the characters RFC5424 requires inside PARAM-VALUE
+func escapeSDValue(value string) string {
+	replacer := strings.NewReplacer(`\`, `\\`, `"`, `\"`, `]`, `\]`)
+	return replacer.Replace(value)
+}
+
+// sanitizeSyslogHeader replaces whitespace so header fields stay tokenized
+func sanitizeSyslogHeader(value string) string {
+	if value == "" {
+		return syslogNilValue
+	}
+	return strings.Join(strings.Fields(value), "_")
+}
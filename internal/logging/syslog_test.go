@@ -0,0 +1,147 @@
+package logging
+
+import (
+	"context"
+	"log/slog"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func listenUDP(t *testing.T) *net.UDPConn {
+	t.Helper()
+	conn, err := net.ListenUDP("udp", &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1)})
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = conn.Close() })
+	return conn
+}
+
+func readDatagram(t *testing.T, conn *net.UDPConn) []byte {
+	t.Helper()
+	_ = conn.SetReadDeadline(time.Now().Add(2 * time.Second))
+	buf := make([]byte, 65535)
+	n, _, err := conn.ReadFromUDP(buf)
+	require.NoError(t, err)
+	return buf[:n]
+}
+
+// acceptOnce accepts a single connection and returns everything read from it
+func acceptOnce(t *testing.T, ln net.Listener) <-chan []byte {
+	t.Helper()
+	received := make(chan []byte, 1)
+	go func() {
+		conn, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		defer func() { _ = conn.Close() }()
+		_ = conn.SetReadDeadline(time.Now().Add(2 * time.Second))
+		buf := make([]byte, 4096)
+		n, _ := conn.Read(buf)
+		received <- buf[:n]
+	}()
+	return received
+}
+
+// setEnv sets an environment variable for the duration of the test and
+// restores the previous value afterwards
+func setEnv(t *testing.T, key, value string) {
+	t.Helper()
+	previous, existed := os.LookupEnv(key)
+	if value == "" {
+		_ = os.Unsetenv(key)
+	} else {
+		_ = os.Setenv(key, value)
+	}
+	t.Cleanup(func() {
+		if existed {
+			_ = os.Setenv(key, previous)
+		} else {
+			_ = os.Unsetenv(key)
+		}
+	})
+}
+
+func TestSyslogHandler_UDP(t *testing.T) {
+	server := listenUDP(t)
+
+	handler, err := NewSyslogHandler(RemoteOutputConfig{
+		Network: "udp",
+		Address: server.LocalAddr().String(),
+		AppName: "test app",
+	})
+	require.NoError(t, err)
+	defer func() { _ = handler.Shutdown(context.Background()) }()
+
+	record := slog.NewRecord(time.Date(2024, 1, 2, 3, 4, 5, 123456789, time.UTC), slog.LevelWarn, "disk almost full", 0)
+	record.AddAttrs(slog.String("trace_id", "abc"), slog.String("path", `/a"b]`))
+	require.NoError(t, handler.Handle(context.Background(), record))
+
+	msg := string(readDatagram(t, server))
+	// local0 (16) * 8 + warning (4) = 132; TIME-SECFRAC is capped at 6 digits
+	assert.True(t, strings.HasPrefix(msg, "<132>1 2024-01-02T03:04:05.123456Z "), msg)
+	assert.Contains(t, msg, " test_app ")
+	assert.Contains(t, msg, `[fields@32473 path="/a\"b\]" trace_id="abc"]`)
+	assert.True(t, strings.HasSuffix(msg, "disk almost full"))
+}
+
+func TestSyslogHandler_TCPFraming(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer func() { _ = ln.Close() }()
+	received := acceptOnce(t, ln)
+
+	handler, err := NewSyslogHandler(RemoteOutputConfig{Network: "tcp", Address: ln.Addr().String(), AppName: "app"})
+	require.NoError(t, err)
+	defer func() { _ = handler.Shutdown(context.Background()) }()
+
+	require.NoError(t, handler.Handle(context.Background(), slog.NewRecord(time.Now(), slog.LevelInfo, "hello", 0)))
+
+	select {
+	case raw := <-received:
+		parts := strings.SplitN(string(raw), " ", 2)
+		require.Len(t, parts, 2)
+		assert.Equal(t, parts[0], strconv.Itoa(len(parts[1])))
+		assert.Contains(t, parts[1], " - hello")
+	case <-time.After(3 * time.Second):
+		t.Fatal("syslog message not received")
+	}
+}
+
+func TestNewSyslogHandler_UnsupportedNetwork(t *testing.T) {
+	_, err := NewSyslogHandler(RemoteOutputConfig{Network: "carrier-pigeon", Address: "x:1"})
+	assert.Error(t, err)
+}
+
+func TestSyslogSeverity(t *testing.T) {
+	assert.Equal(t, 3, syslogSeverity(slog.LevelError))
+	assert.Equal(t, 4, syslogSeverity(slog.LevelWarn))
+	assert.Equal(t, 6, syslogSeverity(slog.LevelInfo))
+	assert.Equal(t, 7, syslogSeverity(slog.LevelDebug))
+}
+
+func TestRemoteOutputConfigFromEnv(t *testing.T) {
+	setEnv(t, "LOG_SYSLOG_ADDRESS", "syslog:6514")
+	setEnv(t, "LOG_SYSLOG_NETWORK", "TLS")
+	setEnv(t, "LOG_SYSLOG_TLS_INSECURE_SKIP_VERIFY", "true")
+	setEnv(t, "LOG_SYSLOG_BUFFER_SIZE", "16")
+	setEnv(t, "LOG_SYSLOG_APP_NAME", "")
+	setEnv(t, "OTEL_SERVICE_NAME", "")
+	setEnv(t, "LOG_GELF_ADDRESS", "")
+
+	cfg := GetSyslogConfig()
+	assert.True(t, cfg.Enabled())
+	assert.Equal(t, "tls", cfg.Network)
+	assert.True(t, cfg.InsecureSkipVerify)
+	assert.Equal(t, "otel-example-api", cfg.AppName)
+	assert.Equal(t, 16, cfg.BufferSize)
+
+	assert.False(t, GetGELFConfig().Enabled())
+	assert.Equal(t, defaultBufferSize, GetGELFConfig().BufferSize)
+}
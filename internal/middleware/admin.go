@@ -0,0 +1,34 @@
+package middleware
+
+import (
+	"crypto/subtle"
+	"net/http"
+	"strings"
+
+	"arquivolivre.com.br/otel/internal/auth"
+	"arquivolivre.com.br/otel/internal/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+// AdminOnly allows admin principals and callers presenting the shared admin
+// token as a bearer token; an empty token only admits admin principals
+func AdminOnly(token string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if auth.IsAdmin(c.Request.Context()) || validBearer(c.GetHeader("Authorization"), token) {
+			c.Next()
+			return
+		}
+
+		_ = c.Error(NewAPIError(http.StatusForbidden, models.ErrCodeForbidden, "Admin access required"))
+		c.Abort()
+	}
+}
+
+func validBearer(header, token string) bool {
+	presented, ok := strings.CutPrefix(header, "Bearer ")
+	if !ok || token == "" {
+		return false
+	}
+	return subtle.ConstantTimeCompare([]byte(presented), []byte(token)) == 1
+}
@@ -0,0 +1,53 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"arquivolivre.com.br/otel/internal/auth"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAdminOnly(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	tests := []struct {
+		name      string
+		token     string
+		header    string
+		principal *auth.Principal
+		want      int
+	}{
+		{name: "no credentials", token: "secret", want: http.StatusForbidden},
+		{name: "wrong token", token: "secret", header: "Bearer nope", want: http.StatusForbidden},
+		{name: "valid token", token: "secret", header: "Bearer secret", want: http.StatusOK},
+		{name: "empty token rejects bearer", header: "Bearer ", want: http.StatusForbidden},
+		{name: "admin principal", principal: &auth.Principal{Subject: "ann", Roles: []string{auth.RoleAdmin}}, want: http.StatusOK},
+		{name: "non-admin principal", principal: &auth.Principal{Subject: "bob"}, want: http.StatusForbidden},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := gin.New()
+			r.Use(ErrorHandler())
+			if tt.principal != nil {
+				r.Use(func(c *gin.Context) {
+					c.Request = c.Request.WithContext(auth.WithPrincipal(c.Request.Context(), *tt.principal))
+				})
+			}
+			r.GET("/admin", AdminOnly(tt.token), func(c *gin.Context) { c.Status(http.StatusOK) })
+
+			req := httptest.NewRequest(http.MethodGet, "/admin", nil)
+			if tt.header != "" {
+				req.Header.Set("Authorization", tt.header)
+			}
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, req)
+
+			assert.Equal(t, tt.want, w.Code)
+		})
+	}
+}
@@ -0,0 +1,120 @@
+package middleware
+
+import (
+	"math"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"arquivolivre.com.br/otel/internal/auth"
+	"arquivolivre.com.br/otel/internal/models"
+
+	"github.com/gin-gonic/gin"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+	semconv "go.opentelemetry.io/otel/semconv/v1.21.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// APIKeyHeader carries the API key of a client
+const APIKeyHeader = "X-API-Key"
+
+// Quotas count the requests of each API client per UTC day. Counts are kept
+// in memory, so each instance enforces the quota on its own
+type Quotas struct {
+	now func() time.Time
+
+	mu   sync.Mutex
+	day  time.Time
+	used map[string]int64
+}
+
+// NewQuotas creates an empty set of daily counts
+func NewQuotas() *Quotas {
+	return &Quotas{now: time.Now, used: map[string]int64{}}
+}
+
+// take counts a request of clientID against limit. It returns the requests
+// made today including this one, whether it is within the limit, and when
+// the counts reset
+func (q *Quotas) take(clientID string, limit int64) (int64, bool, time.Time) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	now := q.now().UTC()
+	day := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+	if !day.Equal(q.day) {
+		q.day = day
+		q.used = map[string]int64{}
+	}
+	reset := day.AddDate(0, 0, 1)
+	if q.used[clientID] >= limit {
+		return q.used[clientID], false, reset
+	}
+	q.used[clientID]++
+	return q.used[clientID], true, reset
+}
+
+// APIKeyAuth authenticates requests carrying an X-API-Key header. The key's
+// client becomes the request principal and its client.id is recorded on the
+// server span and made available to metrics and logs. Unknown keys are
+// rejected with 401. Keys with a daily quota get X-Quota-Limit and
+// X-Quota-Remaining headers, and requests over the quota are rejected with
+// 429 until midnight UTC. Requests are counted in api_key_requests_total by
+// client.id and result. Requests without the header pass through
+func APIKeyAuth(keys *auth.APIKeys, quotas *Quotas) gin.HandlerFunc {
+	requests, _ := otel.Meter("otel-example-api").Int64Counter(
+		"api_key_requests_total",
+		metric.WithDescription("Total number of HTTP requests made with an API key, by client and result"),
+	)
+
+	return func(c *gin.Context) {
+		secret := c.GetHeader(APIKeyHeader)
+		if secret == "" {
+			c.Next()
+			return
+		}
+
+		ctx := c.Request.Context()
+		key, ok := keys.Lookup(secret)
+		if !ok {
+			requests.Add(ctx, 1, metric.WithAttributes(
+				attribute.String(auth.ClientIDKey, "unknown"),
+				attribute.String("result", "invalid"),
+			))
+			_ = c.Error(NewAPIError(http.StatusUnauthorized, models.ErrCodeUnauthorized, "Invalid API key"))
+			c.Abort()
+			return
+		}
+
+		span := trace.SpanFromContext(ctx)
+		span.SetAttributes(attribute.String(auth.ClientIDKey, key.ClientID), semconv.EnduserID(key.ClientID))
+		clientAttr := attribute.String(auth.ClientIDKey, key.ClientID)
+
+		if key.DailyQuota > 0 {
+			used, ok, reset := quotas.take(key.ClientID, key.DailyQuota)
+			c.Header("X-Quota-Limit", strconv.FormatInt(key.DailyQuota, 10))
+			c.Header("X-Quota-Remaining", strconv.FormatInt(key.DailyQuota-used, 10))
+			if !ok {
+				requests.Add(ctx, 1, metric.WithAttributes(clientAttr, attribute.String("result", "quota_exceeded")))
+				retryAfter := reset.Sub(quotas.now())
+				span.AddEvent("api_key.quota_exceeded", trace.WithAttributes(
+					attribute.Int64("api_key.daily_quota", key.DailyQuota),
+					attribute.Float64("api_key.retry_after_seconds", retryAfter.Seconds()),
+				))
+				c.Header("Retry-After", strconv.Itoa(int(math.Ceil(retryAfter.Seconds()))))
+				_ = c.Error(NewAPIError(http.StatusTooManyRequests, models.ErrCodeQuotaExceeded, "Daily quota of the API key exceeded"))
+				c.Abort()
+				return
+			}
+		}
+		requests.Add(ctx, 1, metric.WithAttributes(clientAttr, attribute.String("result", "allowed")))
+
+		ctx = auth.WithClientID(ctx, key.ClientID)
+		ctx = auth.WithPrincipal(ctx, auth.Principal{Subject: key.ClientID, Roles: key.Roles})
+		c.Request = c.Request.WithContext(ctx)
+		c.Next()
+	}
+}
@@ -0,0 +1,73 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"arquivolivre.com.br/otel/internal/auth"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/attribute"
+)
+
+func TestAPIKeyAuth(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	recorder := withSpanRecorder(t)
+
+	keys, err := auth.ParseAPIKeys([]string{"ci-bot:s3cret:2", "mobile:unlimited"})
+	require.NoError(t, err)
+	now := time.Date(2024, 5, 1, 23, 59, 0, 0, time.UTC)
+	quotas := NewQuotas()
+	quotas.now = func() time.Time { return now }
+
+	r := gin.New()
+	r.Use(NewTelemetryMiddleware("test-service").GinMiddleware())
+	r.Use(ErrorHandler())
+	// JWT is satisfied by the API key
+	r.POST("/users", APIKeyAuth(keys, quotas), JWTAuth(JWTConfig{Secret: "jwt-secret"}), func(c *gin.Context) {
+		c.String(http.StatusCreated, auth.Actor(c.Request.Context())+"/"+auth.ClientID(c.Request.Context()))
+	})
+
+	post := func(key string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodPost, "/users", nil)
+		if key != "" {
+			req.Header.Set(APIKeyHeader, key)
+		}
+		r.ServeHTTP(w, req)
+		return w
+	}
+
+	w := post("s3cret")
+	require.Equal(t, http.StatusCreated, w.Code)
+	assert.Equal(t, "ci-bot/ci-bot", w.Body.String())
+	assert.Equal(t, "2", w.Header().Get("X-Quota-Limit"))
+	assert.Equal(t, "1", w.Header().Get("X-Quota-Remaining"))
+
+	assert.Equal(t, "0", post("s3cret").Header().Get("X-Quota-Remaining"))
+	w = post("s3cret")
+	assert.Equal(t, http.StatusTooManyRequests, w.Code)
+	assert.Contains(t, w.Body.String(), `"code":"QUOTA_EXCEEDED"`)
+	assert.Equal(t, "60", w.Header().Get("Retry-After"))
+
+	// Other keys have their own quota, and the count resets at midnight UTC
+	w = post("unlimited")
+	assert.Equal(t, http.StatusCreated, w.Code)
+	assert.Empty(t, w.Header().Get("X-Quota-Limit"))
+	now = now.Add(time.Minute)
+	assert.Equal(t, http.StatusCreated, post("s3cret").Code)
+
+	assert.Equal(t, http.StatusUnauthorized, post("wrong").Code)
+	// Without a key the request falls through to JWTAuth
+	assert.Equal(t, http.StatusUnauthorized, post("").Code)
+
+	spans := recorder.Ended()
+	require.Len(t, spans, 7)
+	assert.Contains(t, spans[0].Attributes(), attribute.String(auth.ClientIDKey, "ci-bot"))
+	require.NotEmpty(t, spans[2].Events())
+	assert.Equal(t, "api_key.quota_exceeded", spans[2].Events()[0].Name)
+}
@@ -0,0 +1,25 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func BenchmarkMetricsMiddleware(b *testing.B) {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	tm := NewTelemetryMiddleware("bench")
+	r.Use(tm.GinMiddleware(), tm.MetricsMiddleware())
+	r.GET("/users/:id", func(c *gin.Context) {
+		c.JSON(http.StatusOK, gin.H{"id": c.Param("id")})
+	})
+
+	b.ReportAllocs()
+	for b.Loop() {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/users/1", nil))
+	}
+}
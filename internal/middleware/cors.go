@@ -6,13 +6,25 @@ import (
 	"github.com/gin-gonic/gin"
 )
 
+// corsAllowHeaders are the request headers browsers may send, including
+// those the API reads itself: If-Match on updates, Idempotency-Key on
+// creates, and the API key, tenant, API version and request ID
+const corsAllowHeaders = "Content-Type, Content-Length, Accept-Encoding, X-CSRF-Token, Authorization, accept, origin, Cache-Control, X-Requested-With, " +
+	"If-Match, Idempotency-Key, X-API-Key, X-Tenant-ID, API-Version, X-Request-ID"
+
+// corsExposeHeaders are the response headers the API sets that browser
+// scripts may read
+const corsExposeHeaders = "ETag, X-Request-ID, Retry-After, X-Quota-Limit, X-Quota-Remaining, " +
+	"Deprecation, Sunset, Link, API-Version, Idempotent-Replayed, Location"
+
 // CORS middleware to handle Cross-Origin Resource Sharing
 func CORS() gin.HandlerFunc {
 	return func(c *gin.Context) {
 		c.Header("Access-Control-Allow-Origin", "*")
 		c.Header("Access-Control-Allow-Credentials", "true")
-		c.Header("Access-Control-Allow-Headers", "Content-Type, Content-Length, Accept-Encoding, X-CSRF-Token, Authorization, accept, origin, Cache-Control, X-Requested-With")
+		c.Header("Access-Control-Allow-Headers", corsAllowHeaders)
 		c.Header("Access-Control-Allow-Methods", "POST, OPTIONS, GET, PUT, DELETE, PATCH")
+		c.Header("Access-Control-Expose-Headers", corsExposeHeaders)
 
 		if c.Request.Method == "OPTIONS" {
 			c.AbortWithStatus(http.StatusNoContent)
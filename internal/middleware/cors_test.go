@@ -3,8 +3,11 @@ package middleware
 import (
 	"net/http"
 	"net/http/httptest"
+	"strings"
 	"testing"
 
+	"arquivolivre.com.br/otel/internal/tenant"
+
 	"github.com/gin-gonic/gin"
 	"github.com/stretchr/testify/assert"
 )
@@ -45,3 +48,22 @@ func TestCORS_OptionsRequest(t *testing.T) {
 	assert.Equal(t, http.StatusNoContent, w.Code)
 	assert.Equal(t, "*", w.Header().Get("Access-Control-Allow-Origin"))
 }
+
+func TestCORS_AllowsAPIHeaders(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.Use(CORS())
+	r.PUT("/test", func(c *gin.Context) { c.Status(http.StatusOK) })
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodOptions, "/test", nil))
+
+	allowed := strings.Split(w.Header().Get("Access-Control-Allow-Headers"), ", ")
+	for _, header := range []string{"If-Match", IdempotencyHeader, APIKeyHeader, tenant.Header, "API-Version", RequestIDHeader} {
+		assert.Contains(t, allowed, header)
+	}
+	exposed := strings.Split(w.Header().Get("Access-Control-Expose-Headers"), ", ")
+	for _, header := range []string{"ETag", RequestIDHeader, "Retry-After", "X-Quota-Limit", "X-Quota-Remaining", "Deprecation", "Sunset", IdempotentReplayedHeader} {
+		assert.Contains(t, exposed, header)
+	}
+}
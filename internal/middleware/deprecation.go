@@ -0,0 +1,32 @@
+package middleware
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Deprecated marks the responses of a deprecated route group with the
+// Deprecation header, a Sunset header (RFC 8594) unless sunset is zero and a
+// successor-version Link to the path returned by successor. The request span
+// gets http.route.deprecated so remaining callers can be found in traces
+func Deprecated(sunset time.Time, successor func(path string) string) gin.HandlerFunc {
+	var sunsetHeader string
+	if !sunset.IsZero() {
+		sunsetHeader = sunset.UTC().Format(http.TimeFormat)
+	}
+	return func(c *gin.Context) {
+		c.Header("Deprecation", "true")
+		if sunsetHeader != "" {
+			c.Header("Sunset", sunsetHeader)
+		}
+		if successor != nil {
+			c.Header("Link", "<"+successor(c.Request.URL.Path)+`>; rel="successor-version"`)
+		}
+		trace.SpanFromContext(c.Request.Context()).SetAttributes(attribute.Bool("http.route.deprecated", true))
+		c.Next()
+	}
+}
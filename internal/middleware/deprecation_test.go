@@ -0,0 +1,34 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDeprecated(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	serve := func(handler gin.HandlerFunc) http.Header {
+		r := gin.New()
+		r.GET("/api/users", handler, func(c *gin.Context) { c.Status(http.StatusOK) })
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/users", nil))
+		return w.Header()
+	}
+
+	sunset := time.Date(2027, 1, 31, 12, 0, 0, 0, time.FixedZone("BRT", -3*60*60))
+	header := serve(Deprecated(sunset, func(path string) string { return "/next" + path }))
+	assert.Equal(t, "true", header.Get("Deprecation"))
+	assert.Equal(t, "Sun, 31 Jan 2027 15:00:00 GMT", header.Get("Sunset"))
+	assert.Equal(t, `</next/api/users>; rel="successor-version"`, header.Get("Link"))
+
+	// Without a sunset date or successor only Deprecation is sent
+	header = serve(Deprecated(time.Time{}, nil))
+	assert.Equal(t, "true", header.Get("Deprecation"))
+	assert.Empty(t, header.Get("Sunset"))
+	assert.Empty(t, header.Get("Link"))
+}
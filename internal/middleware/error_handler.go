@@ -1,39 +1,192 @@
 package middleware
 
 import (
+	"encoding/json"
+	"errors"
+	"io"
 	"net/http"
+	"strings"
 
 	"arquivolivre.com.br/otel/internal/models"
+	"arquivolivre.com.br/otel/internal/validation"
+	"arquivolivre.com.br/otel/pkg/apperrors"
+	"arquivolivre.com.br/otel/pkg/utils"
 
 	"github.com/gin-gonic/gin"
 )
 
+// ErrorDocsBaseURL is where each error code is documented; the code is
+// appended as a lower-case anchor
+var ErrorDocsBaseURL = "https://github.com/devops-thiago/otel-example-go/blob/main/docs/errors.md"
+
+// APIError is an error that handlers attach with c.Error so the ErrorHandler
+// can render it consistently
+type APIError struct {
+	Status  int
+	Code    string
+	Message string
+	Details []models.FieldError
+	Err     error
+}
+
+func (e *APIError) Error() string {
+	if e.Err != nil {
+		return e.Message + ": " + e.Err.Error()
+	}
+	return e.Message
+}
+
+func (e *APIError) Unwrap() error {
+	return e.Err
+}
+
+// NewAPIError creates an APIError with the given status, code and message
+func NewAPIError(status int, code, message string) *APIError {
+	return &APIError{Status: status, Code: code, Message: message}
+}
+
+// WithCause records the underlying error; it is never exposed to clients
+func (e *APIError) WithCause(err error) *APIError {
+	e.Err = err
+	return e
+}
+
+// BadRequestError reports a malformed request
+func BadRequestError(message string) *APIError {
+	return NewAPIError(http.StatusBadRequest, models.ErrCodeInvalidRequest, message)
+}
+
+// ValidationError reports field-level validation failures
+func ValidationError(details []models.FieldError) *APIError {
+	e := NewAPIError(http.StatusBadRequest, models.ErrCodeValidationFailed, "Validation failed")
+	e.Details = details
+	return e
+}
+
+// BindError reports a request body that could not be bound. Validation
+// failures and values of the wrong JSON type are listed per field, with
+// messages in the language of acceptLanguage; other failures get a fixed
+// message rather than the decoder's error text
+func BindError(err error, acceptLanguage string) *APIError {
+	if details := validation.LocalizedFieldErrors(err, acceptLanguage); details != nil {
+		return ValidationError(details)
+	}
+
+	var syntaxErr *json.SyntaxError
+	switch {
+	case errors.Is(err, io.EOF):
+		return BadRequestError("Invalid request data: request body is empty").WithCause(err)
+	case errors.As(err, &syntaxErr), errors.Is(err, io.ErrUnexpectedEOF):
+		return BadRequestError("Invalid request data: request body is not valid JSON").WithCause(err)
+	default:
+		return BadRequestError("Invalid request data").WithCause(err)
+	}
+}
+
+// NotFoundError reports a missing resource
+func NotFoundError(message string) *APIError {
+	return NewAPIError(http.StatusNotFound, models.ErrCodeNotFound, message)
+}
+
+// ConflictError reports a conflict with existing state
+func ConflictError(message string) *APIError {
+	return NewAPIError(http.StatusConflict, models.ErrCodeConflict, message)
+}
+
+// InternalError reports an unexpected failure
+func InternalError(message string, cause error) *APIError {
+	return NewAPIError(http.StatusInternalServerError, models.ErrCodeInternal, message).WithCause(cause)
+}
+
+// FromError converts a domain error from pkg/apperrors into an APIError with
+// the mapped status. Errors without a domain kind become internal errors
+// reported with fallbackMessage.
+func FromError(err error, fallbackMessage string) *APIError {
+	var apiErr *APIError
+	if errors.As(err, &apiErr) {
+		return apiErr
+	}
+
+	status := apperrors.HTTPStatus(err)
+	if status == http.StatusInternalServerError {
+		return InternalError(fallbackMessage, err)
+	}
+
+	message := apperrors.Message(err)
+	if message == "" {
+		message = fallbackMessage
+	}
+	return NewAPIError(status, errorCodeForStatus(status), message).WithCause(err)
+}
+
+func errorCodeForStatus(status int) string {
+	switch status {
+	case http.StatusBadRequest:
+		return models.ErrCodeInvalidRequest
+	case http.StatusUnauthorized:
+		return models.ErrCodeUnauthorized
+	case http.StatusForbidden:
+		return models.ErrCodeForbidden
+	case http.StatusNotFound:
+		return models.ErrCodeNotFound
+	case http.StatusConflict:
+		return models.ErrCodeConflict
+	case http.StatusPreconditionFailed:
+		return models.ErrCodePreconditionFailed
+	case http.StatusPreconditionRequired:
+		return models.ErrCodePreconditionRequired
+	case http.StatusRequestEntityTooLarge:
+		return models.ErrCodePayloadTooLarge
+	case http.StatusTooManyRequests:
+		return models.ErrCodeRateLimited
+	case http.StatusServiceUnavailable:
+		return models.ErrCodeServiceUnavailable
+	default:
+		return models.ErrCodeInternal
+	}
+}
+
 // ErrorHandler middleware to handle errors consistently
 func ErrorHandler() gin.HandlerFunc {
 	return func(c *gin.Context) {
 		c.Next()
 
 		// Handle any errors that occurred during request processing
-		if len(c.Errors) > 0 {
-			err := c.Errors.Last()
-
-			switch err.Type {
-			case gin.ErrorTypeBind:
-				c.JSON(http.StatusBadRequest, models.ErrorResponse{
-					Success: false,
-					Error:   "Invalid request data: " + err.Error(),
-				})
-			case gin.ErrorTypePublic:
-				c.JSON(http.StatusBadRequest, models.ErrorResponse{
-					Success: false,
-					Error:   err.Error(),
-				})
-			default:
-				c.JSON(http.StatusInternalServerError, models.ErrorResponse{
-					Success: false,
-					Error:   "Internal server error",
-				})
-			}
+		if len(c.Errors) == 0 || c.Writer.Written() {
+			return
 		}
+
+		apiErr := toAPIError(c.Errors.Last(), c.GetHeader("Accept-Language"))
+		utils.SendErrorResponse(c, apiErr.Status, NewErrorResponse(c, apiErr))
+	}
+}
+
+// NewErrorResponse builds the response body for apiErr, adding the trace ID,
+// request ID and documentation link for the current request
+func NewErrorResponse(c *gin.Context, apiErr *APIError) models.ErrorResponse {
+	response := models.ErrorResponse{
+		Success:   false,
+		Error:     apiErr.Message,
+		Code:      apiErr.Code,
+		Details:   apiErr.Details,
+		TraceID:   utils.TraceID(c),
+		RequestID: utils.RequestID(c),
+	}
+
+	if apiErr.Code != "" && ErrorDocsBaseURL != "" {
+		response.DocumentationURL = ErrorDocsBaseURL + "#" + strings.ToLower(apiErr.Code)
+	}
+
+	return response
+}
+
+func toAPIError(err *gin.Error, acceptLanguage string) *APIError {
+	switch err.Type {
+	case gin.ErrorTypeBind:
+		return BindError(err.Err, acceptLanguage)
+	case gin.ErrorTypePublic:
+		return BadRequestError(err.Error())
+	default:
+		return FromError(err.Err, "Internal server error")
 	}
 }
@@ -1,12 +1,22 @@
 package middleware
 
 import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
 	"net/http"
 	"net/http/httptest"
 	"testing"
 
+	"arquivolivre.com.br/otel/internal/models"
+	"arquivolivre.com.br/otel/pkg/apperrors"
+
 	"github.com/gin-gonic/gin"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
 )
 
 func TestErrorHandler_NoErrors(t *testing.T) {
@@ -43,6 +53,32 @@ func TestErrorHandler_BindError(t *testing.T) {
 	assert.Contains(t, w.Body.String(), "Invalid request data")
 }
 
+func TestBindErrorDoesNotEchoDecoderErrors(t *testing.T) {
+	var req models.CreateUserRequest
+	tests := []struct {
+		name    string
+		err     error
+		code    string
+		message string
+	}{
+		{name: "empty body", err: io.EOF, code: models.ErrCodeInvalidRequest, message: "Invalid request data: request body is empty"},
+		{name: "malformed JSON", err: json.Unmarshal([]byte(`{"name": "A`), &req), code: models.ErrCodeInvalidRequest, message: "Invalid request data: request body is not valid JSON"},
+		{name: "wrong type", err: json.Unmarshal([]byte(`{"bio": true}`), &req), code: models.ErrCodeValidationFailed, message: "Validation failed"},
+		{name: "other", err: errors.New("unsupported content"), code: models.ErrCodeInvalidRequest, message: "Invalid request data"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			apiErr := BindError(tt.err, "")
+			assert.Equal(t, tt.code, apiErr.Code)
+			assert.Equal(t, tt.message, apiErr.Message)
+		})
+	}
+
+	apiErr := BindError(json.Unmarshal([]byte(`{"bio": true}`), &req), "")
+	require.Len(t, apiErr.Details, 1)
+	assert.Equal(t, models.FieldError{Field: "bio", Rule: "type", Message: "bio must be a string"}, apiErr.Details[0])
+}
+
 func TestErrorHandler_PublicError(t *testing.T) {
 	gin.SetMode(gin.TestMode)
 	r := gin.New()
@@ -79,3 +115,102 @@ func TestErrorHandler_GenericError(t *testing.T) {
 	assert.Equal(t, http.StatusInternalServerError, w.Code)
 	assert.Contains(t, w.Body.String(), "Internal server error")
 }
+
+func TestErrorHandler_APIError(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	tp := sdktrace.NewTracerProvider(sdktrace.WithSampler(sdktrace.AlwaysSample()))
+	defer func() { _ = tp.Shutdown(context.Background()) }()
+
+	var traceID string
+	r := gin.New()
+	r.Use(RequestID())
+	r.Use(ErrorHandler())
+	r.GET("/test", func(c *gin.Context) {
+		ctx, span := tp.Tracer("test").Start(c.Request.Context(), "handler")
+		defer span.End()
+		c.Request = c.Request.WithContext(ctx)
+		traceID = span.SpanContext().TraceID().String()
+
+		_ = c.Error(ValidationError([]models.FieldError{{Field: "email", Rule: "email", Message: "email must be a valid email address"}}))
+	})
+
+	w := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodGet, "/test", nil)
+	req.Header.Set(RequestIDHeader, "req-123")
+	r.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	var resp models.ErrorResponse
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	assert.False(t, resp.Success)
+	assert.Equal(t, "Validation failed", resp.Error)
+	assert.Equal(t, models.ErrCodeValidationFailed, resp.Code)
+	assert.Equal(t, traceID, resp.TraceID)
+	assert.Equal(t, "req-123", resp.RequestID)
+	assert.Equal(t, ErrorDocsBaseURL+"#validation_failed", resp.DocumentationURL)
+	require.Len(t, resp.Details, 1)
+	assert.Equal(t, "email", resp.Details[0].Field)
+}
+
+func TestErrorHandler_HidesInternalCause(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.Use(ErrorHandler())
+	r.GET("/test", func(c *gin.Context) {
+		_ = c.Error(InternalError("Failed to load", errors.New("dial tcp 10.0.0.1:3306: refused")))
+	})
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/test", nil))
+
+	assert.Equal(t, http.StatusInternalServerError, w.Code)
+	assert.Contains(t, w.Body.String(), `"code":"INTERNAL_ERROR"`)
+	assert.NotContains(t, w.Body.String(), "10.0.0.1")
+}
+
+func TestErrorHandler_ResponseAlreadyWritten(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.Use(ErrorHandler())
+	r.GET("/test", func(c *gin.Context) {
+		c.String(http.StatusTeapot, "short and stout")
+		_ = c.Error(assert.AnError)
+	})
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/test", nil))
+
+	assert.Equal(t, http.StatusTeapot, w.Code)
+	assert.Equal(t, "short and stout", w.Body.String())
+}
+
+func TestErrorHandler_DomainErrors(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.Use(ErrorHandler())
+	r.GET("/missing", func(c *gin.Context) { _ = c.Error(apperrors.NotFound("user not found")) })
+	r.GET("/taken", func(c *gin.Context) {
+		_ = c.Error(FromError(fmt.Errorf("create: %w", apperrors.Conflict("email already exists")), "Failed"))
+	})
+
+	cases := []struct {
+		path   string
+		status int
+		code   string
+		msg    string
+	}{
+		{"/missing", http.StatusNotFound, models.ErrCodeNotFound, "user not found"},
+		{"/taken", http.StatusConflict, models.ErrCodeConflict, "email already exists"},
+	}
+	for _, tc := range cases {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, tc.path, nil))
+		assert.Equal(t, tc.status, w.Code)
+
+		var resp models.ErrorResponse
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+		assert.Equal(t, tc.code, resp.Code)
+		assert.Equal(t, tc.msg, resp.Error)
+	}
+}
@@ -0,0 +1,201 @@
+package middleware
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"net/http"
+	"time"
+
+	"arquivolivre.com.br/otel/internal/auth"
+	"arquivolivre.com.br/otel/internal/logging"
+	"arquivolivre.com.br/otel/internal/models"
+	"arquivolivre.com.br/otel/internal/tenant"
+	"arquivolivre.com.br/otel/pkg/cache"
+
+	"github.com/gin-gonic/gin"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const (
+	// IdempotencyHeader carries the client's key for a retryable request
+	IdempotencyHeader = "Idempotency-Key"
+	// IdempotentReplayedHeader is set on responses replayed from the store
+	IdempotentReplayedHeader = "Idempotent-Replayed"
+
+	// maxIdempotencyKeyLen bounds the keys clients may send
+	maxIdempotencyKeyLen = 255
+	// idempotencyLockTTL bounds how long a key stays in progress should the
+	// instance handling it die before storing the response
+	idempotencyLockTTL = time.Minute
+)
+
+// idempotencyRecord is what the store keeps for a key: the fingerprint of
+// the request that first used it and, once that request completed, its
+// response
+type idempotencyRecord struct {
+	Fingerprint string `json:"fingerprint"`
+	Done        bool   `json:"done"`
+	Status      int    `json:"status,omitempty"`
+	ContentType string `json:"content_type,omitempty"`
+	Body        []byte `json:"body,omitempty"`
+}
+
+// Idempotency makes requests carrying an Idempotency-Key safe to retry. The
+// first request with a key runs and, when it succeeds, its response is kept
+// in store for ttl; retries with the same key and body get that response
+// back with Idempotent-Replayed: true instead of running again. Reusing a
+// key for a different request is rejected with 422, and a retry that
+// arrives while the first request is still running with 409. Failed
+// responses are not kept, so the client may retry them.
+//
+// Keys are scoped to the tenant and the authenticated caller. Outcomes are
+// counted in http_idempotent_requests_total and recorded on the server
+// span. When the store fails the request runs as if it carried no key
+func Idempotency(store cache.Cache, ttl time.Duration) gin.HandlerFunc {
+	requests, _ := otel.Meter("otel-example-api").Int64Counter(
+		"http_idempotent_requests_total",
+		metric.WithDescription("Total number of HTTP requests carrying an Idempotency-Key, by result"),
+	)
+
+	return func(c *gin.Context) {
+		key := c.GetHeader(IdempotencyHeader)
+		if key == "" {
+			c.Next()
+			return
+		}
+		if len(key) > maxIdempotencyKeyLen {
+			_ = c.Error(BadRequestError("Idempotency-Key must be at most 255 characters"))
+			c.Abort()
+			return
+		}
+
+		ctx := c.Request.Context()
+		span := trace.SpanFromContext(ctx)
+		record := func(result string) {
+			requests.Add(ctx, 1, metric.WithAttributes(
+				attribute.String("result", result),
+				attribute.String("method", c.Request.Method),
+				attribute.String("route", c.FullPath()),
+			))
+			span.SetAttributes(
+				attribute.String("idempotency.key", key),
+				attribute.String("idempotency.result", result),
+				attribute.Bool("idempotency.replayed", result == "replayed"),
+			)
+		}
+
+		body, err := io.ReadAll(c.Request.Body)
+		if err != nil {
+			_ = c.Error(BadRequestError("Failed to read request body"))
+			c.Abort()
+			return
+		}
+		c.Request.Body = io.NopCloser(bytes.NewReader(body))
+
+		storeKey := "idempotency:" + tenant.FromContext(ctx) + ":" + auth.Actor(ctx) + ":" + key
+		fingerprint := requestFingerprint(c.Request.Method, c.Request.URL.Path, body)
+
+		// The key is reserved in one atomic step, so of two requests arriving
+		// together only one runs
+		lock := idempotencyRecord{Fingerprint: fingerprint}
+		reserved, err := cache.SetNXJSON(ctx, store, storeKey, lock, idempotencyLockTTL)
+		var stored idempotencyRecord
+		if err == nil && !reserved {
+			var found bool
+			stored, found, err = cache.GetJSON[idempotencyRecord](ctx, store, storeKey)
+			if err == nil && !found {
+				// The request holding the key failed and released it since
+				stored = lock
+			}
+		}
+		if err != nil {
+			logging.LogWarn(ctx, "Idempotency store unavailable, handling request without it", map[string]interface{}{
+				"error": err.Error(),
+			})
+			record("unavailable")
+			c.Next()
+			return
+		}
+		if !reserved {
+			switch {
+			case stored.Fingerprint != fingerprint:
+				record("mismatch")
+				_ = c.Error(NewAPIError(http.StatusUnprocessableEntity, models.ErrCodeIdempotencyKeyReused,
+					"Idempotency-Key was already used for a different request"))
+				c.Abort()
+			case !stored.Done:
+				record("in_progress")
+				c.Header("Retry-After", "1")
+				_ = c.Error(NewAPIError(http.StatusConflict, models.ErrCodeConflict,
+					"A request with this Idempotency-Key is still in progress"))
+				c.Abort()
+			default:
+				record("replayed")
+				c.Header(IdempotentReplayedHeader, "true")
+				c.Data(stored.Status, stored.ContentType, stored.Body)
+				c.Abort()
+			}
+			return
+		}
+
+		record("new")
+		recorder := &responseRecorder{ResponseWriter: c.Writer}
+		c.Writer = recorder
+		c.Next()
+		c.Writer = recorder.ResponseWriter
+
+		// The request context may be done by now, but the outcome must be
+		// stored regardless. Errors are only written by ErrorHandler once
+		// this returns, so a pending error counts as a failure
+		storeCtx := context.WithoutCancel(ctx)
+		status := recorder.Status()
+		if len(c.Errors) > 0 || status < 200 || status >= 300 {
+			if err := store.Delete(storeCtx, storeKey); err != nil {
+				logging.LogWarn(ctx, "Failed to release Idempotency-Key", map[string]interface{}{
+					"error": err.Error(),
+				})
+			}
+			return
+		}
+		lock.Done = true
+		lock.Status = status
+		lock.ContentType = recorder.Header().Get("Content-Type")
+		lock.Body = recorder.body.Bytes()
+		if err := cache.SetJSON(storeCtx, store, storeKey, lock, ttl); err != nil {
+			logging.LogWarn(ctx, "Failed to store response for Idempotency-Key", map[string]interface{}{
+				"error": err.Error(),
+			})
+		}
+	}
+}
+
+// requestFingerprint identifies a request by its method, path and body, so a
+// key reused for another request is told apart from a retry
+func requestFingerprint(method, path string, body []byte) string {
+	hash := sha256.New()
+	hash.Write([]byte(method + " " + path + "\n"))
+	hash.Write(body)
+	return hex.EncodeToString(hash.Sum(nil))
+}
+
+// responseRecorder keeps a copy of the response body as it is written
+type responseRecorder struct {
+	gin.ResponseWriter
+	body bytes.Buffer
+}
+
+func (w *responseRecorder) Write(p []byte) (int, error) {
+	w.body.Write(p)
+	return w.ResponseWriter.Write(p)
+}
+
+func (w *responseRecorder) WriteString(s string) (int, error) {
+	w.body.WriteString(s)
+	return w.ResponseWriter.WriteString(s)
+}
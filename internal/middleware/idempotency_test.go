@@ -0,0 +1,171 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"arquivolivre.com.br/otel/pkg/apperrors"
+	"arquivolivre.com.br/otel/pkg/cache"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+func TestIdempotency(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	recorder := tracetest.NewSpanRecorder()
+	prevTP := otel.GetTracerProvider()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)))
+	defer otel.SetTracerProvider(prevTP)
+
+	created := 0
+	tm := NewTelemetryMiddleware("test-service")
+	r := gin.New()
+	r.Use(tm.GinMiddleware())
+	r.Use(ErrorHandler())
+	r.POST("/users", Idempotency(cache.NewMemory(cache.MemoryOptions{}), time.Hour), func(c *gin.Context) {
+		var body struct {
+			Email string `json:"email"`
+		}
+		_ = c.ShouldBindJSON(&body)
+		if body.Email == "taken@example.com" {
+			_ = c.Error(FromError(apperrors.Conflict("email already exists"), "Failed to create user"))
+			return
+		}
+		created++
+		c.JSON(http.StatusCreated, gin.H{"id": created, "email": body.Email})
+	})
+
+	post := func(key, body string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodPost, "/users", strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		if key != "" {
+			req.Header.Set(IdempotencyHeader, key)
+		}
+		r.ServeHTTP(w, req)
+		return w
+	}
+
+	first := post("k1", `{"email":"ann@example.com"}`)
+	require.Equal(t, http.StatusCreated, first.Code)
+	assert.Empty(t, first.Header().Get(IdempotentReplayedHeader))
+
+	retry := post("k1", `{"email":"ann@example.com"}`)
+	assert.Equal(t, http.StatusCreated, retry.Code)
+	assert.Equal(t, "true", retry.Header().Get(IdempotentReplayedHeader))
+	assert.Equal(t, first.Body.String(), retry.Body.String())
+	assert.Equal(t, "application/json; charset=utf-8", retry.Header().Get("Content-Type"))
+	assert.Equal(t, 1, created, "a replay must not run the handler")
+
+	reused := post("k1", `{"email":"bob@example.com"}`)
+	assert.Equal(t, http.StatusUnprocessableEntity, reused.Code)
+	assert.Contains(t, reused.Body.String(), `"code":"IDEMPOTENCY_KEY_REUSED"`)
+
+	// Failures are not kept, so the same key may be retried
+	assert.Equal(t, http.StatusConflict, post("k2", `{"email":"taken@example.com"}`).Code)
+	assert.Equal(t, http.StatusConflict, post("k2", `{"email":"taken@example.com"}`).Code)
+
+	// Without a key every request runs
+	assert.Equal(t, http.StatusCreated, post("", `{"email":"cy@example.com"}`).Code)
+	assert.Equal(t, http.StatusCreated, post("", `{"email":"cy@example.com"}`).Code)
+	assert.Equal(t, 3, created)
+
+	assert.Equal(t, http.StatusBadRequest, post(strings.Repeat("k", 256), `{}`).Code)
+
+	results := []string{}
+	for _, span := range recorder.Ended() {
+		for _, attr := range span.Attributes() {
+			if attr.Key == "idempotency.result" {
+				results = append(results, attr.Value.AsString())
+			}
+			if attr.Key == "idempotency.replayed" && attr.Value.AsBool() {
+				assert.Contains(t, span.Attributes(), attribute.String("idempotency.key", "k1"))
+			}
+		}
+	}
+	assert.Equal(t, []string{"new", "replayed", "mismatch", "new", "new"}, results)
+}
+
+func TestIdempotencyInProgress(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	store := cache.NewMemory(cache.MemoryOptions{})
+	r := gin.New()
+	r.Use(ErrorHandler())
+	release := make(chan struct{})
+	started := make(chan struct{})
+	r.POST("/users", Idempotency(store, time.Hour), func(c *gin.Context) {
+		close(started)
+		<-release
+		c.Status(http.StatusCreated)
+	})
+
+	post := func() *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodPost, "/users", strings.NewReader(`{}`))
+		req.Header.Set(IdempotencyHeader, "slow")
+		r.ServeHTTP(w, req)
+		return w
+	}
+
+	done := make(chan int)
+	go func() { done <- post().Code }()
+	<-started
+
+	w := post()
+	assert.Equal(t, http.StatusConflict, w.Code)
+	assert.Equal(t, "1", w.Header().Get("Retry-After"))
+
+	close(release)
+	assert.Equal(t, http.StatusCreated, <-done)
+	assert.Equal(t, http.StatusCreated, post().Code)
+}
+
+func TestIdempotencyConcurrentRequests(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	store := cache.NewMemory(cache.MemoryOptions{})
+	r := gin.New()
+	r.Use(ErrorHandler())
+	var runs atomic.Int32
+	release := make(chan struct{})
+	r.POST("/users", Idempotency(store, time.Hour), func(c *gin.Context) {
+		runs.Add(1)
+		<-release
+		c.Status(http.StatusCreated)
+	})
+
+	// Both requests are sent at once, so neither finds the key stored
+	// before the other reserves it
+	start := make(chan struct{})
+	codes := make(chan int, 2)
+	for range 2 {
+		go func() {
+			<-start
+			w := httptest.NewRecorder()
+			req := httptest.NewRequest(http.MethodPost, "/users", strings.NewReader(`{}`))
+			req.Header.Set(IdempotencyHeader, "race")
+			r.ServeHTTP(w, req)
+			codes <- w.Code
+		}()
+	}
+	close(start)
+
+	select {
+	case code := <-codes:
+		assert.Equal(t, http.StatusConflict, code)
+	case <-time.After(time.Second):
+		t.Fatal("both requests ran the handler")
+	}
+	close(release)
+	assert.Equal(t, http.StatusCreated, <-codes)
+	assert.Equal(t, int32(1), runs.Load())
+}
@@ -0,0 +1,167 @@
+package middleware
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"arquivolivre.com.br/otel/internal/auth"
+	"arquivolivre.com.br/otel/internal/models"
+	"arquivolivre.com.br/otel/internal/tenant"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v5"
+	semconv "go.opentelemetry.io/otel/semconv/v1.21.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// JWTClaimsKey is the gin context key holding the claims of a valid token
+const JWTClaimsKey = "jwt_claims"
+
+// JWTConfig selects how bearer tokens are verified: with the shared HMAC
+// Secret, or with the public keys published at JWKSURL
+type JWTConfig struct {
+	Secret   string
+	JWKSURL  string
+	Issuer   string
+	Audience string
+}
+
+// Enabled reports whether a verification key source is configured
+func (c JWTConfig) Enabled() bool {
+	return c.Secret != "" || c.JWKSURL != ""
+}
+
+// Claims are the token claims the API relies on
+type Claims struct {
+	jwt.RegisteredClaims
+	Roles []string `json:"roles,omitempty"`
+	// TenantID overrides the tenant sent in the X-Tenant-ID header
+	TenantID string `json:"tenant_id,omitempty"`
+}
+
+// JWTVerifier checks bearer tokens against a JWTConfig
+type JWTVerifier struct {
+	parser  *jwt.Parser
+	keyFunc func(context.Context, *jwt.Token) (any, error)
+}
+
+// NewJWTVerifier creates a verifier requiring an expiry and, when
+// configured, the issuer and audience
+func NewJWTVerifier(cfg JWTConfig) *JWTVerifier {
+	keyFunc, methods := jwtKeyFunc(cfg)
+	opts := []jwt.ParserOption{jwt.WithValidMethods(methods), jwt.WithExpirationRequired()}
+	if cfg.Issuer != "" {
+		opts = append(opts, jwt.WithIssuer(cfg.Issuer))
+	}
+	if cfg.Audience != "" {
+		opts = append(opts, jwt.WithAudience(cfg.Audience))
+	}
+	return &JWTVerifier{parser: jwt.NewParser(opts...), keyFunc: keyFunc}
+}
+
+// Verify returns the claims of raw if it is a valid token with a subject
+func (v *JWTVerifier) Verify(ctx context.Context, raw string) (*Claims, error) {
+	claims := &Claims{}
+	_, err := v.parser.ParseWithClaims(raw, claims, func(token *jwt.Token) (any, error) {
+		return v.keyFunc(ctx, token)
+	})
+	if err != nil {
+		return nil, err
+	}
+	if claims.Subject == "" {
+		return nil, errors.New("token has no subject")
+	}
+	if claims.TenantID != "" && !tenant.Valid(claims.TenantID) {
+		return nil, errors.New("token has an invalid tenant_id")
+	}
+	return claims, nil
+}
+
+// Authenticate makes the subject of claims the principal of ctx and records
+// it as enduser.id on the active span. A tenant_id claim replaces the tenant
+// in the baggage of ctx
+func Authenticate(ctx context.Context, claims *Claims) context.Context {
+	span := trace.SpanFromContext(ctx)
+	span.SetAttributes(semconv.EnduserID(claims.Subject))
+	if claims.TenantID != "" {
+		if tenantCtx, err := tenant.WithID(ctx, claims.TenantID); err == nil {
+			ctx = tenantCtx
+			span.SetAttributes(tenant.Attributes(ctx)...)
+		}
+	}
+	return auth.WithPrincipal(ctx, auth.Principal{
+		Subject: claims.Subject,
+		Roles:   claims.Roles,
+	})
+}
+
+// JWTAuth requires a valid bearer token. The claims are stored in the gin
+// context, the subject becomes the request principal and is recorded as
+// enduser.id on the server span. Requests already authenticated by
+// APIKeyAuth need no token
+func JWTAuth(cfg JWTConfig) gin.HandlerFunc {
+	verifier := NewJWTVerifier(cfg)
+
+	return func(c *gin.Context) {
+		if auth.ClientID(c.Request.Context()) != "" {
+			c.Next()
+			return
+		}
+
+		raw, ok := strings.CutPrefix(c.GetHeader("Authorization"), "Bearer ")
+		if !ok || raw == "" {
+			abortUnauthorized(c, "Bearer token required")
+			return
+		}
+
+		claims, err := verifier.Verify(c.Request.Context(), raw)
+		if err != nil {
+			abortUnauthorized(c, "Invalid bearer token")
+			return
+		}
+
+		c.Set(JWTClaimsKey, claims)
+		c.Request = c.Request.WithContext(Authenticate(c.Request.Context(), claims))
+		c.Next()
+	}
+}
+
+// GetJWTClaims returns the claims stored by JWTAuth, if any
+func GetJWTClaims(c *gin.Context) (*Claims, bool) {
+	value, ok := c.Get(JWTClaimsKey)
+	if !ok {
+		return nil, false
+	}
+	claims, ok := value.(*Claims)
+	return claims, ok
+}
+
+// jwtKeyFunc returns the verification key lookup and the signing methods it
+// accepts, so an HMAC secret can never verify an asymmetric token or the
+// other way around
+func jwtKeyFunc(cfg JWTConfig) (func(context.Context, *jwt.Token) (any, error), []string) {
+	if cfg.JWKSURL != "" {
+		jwks := auth.NewJWKS(cfg.JWKSURL, 5*time.Second)
+		return func(ctx context.Context, token *jwt.Token) (any, error) {
+			kid, _ := token.Header["kid"].(string)
+			return jwks.Key(ctx, kid)
+		}, []string{"RS256", "RS384", "RS512", "PS256", "PS384", "PS512", "ES256", "ES384", "ES512"}
+	}
+
+	secret := []byte(cfg.Secret)
+	return func(context.Context, *jwt.Token) (any, error) {
+		if len(secret) == 0 {
+			return nil, errors.New("no JWT secret configured")
+		}
+		return secret, nil
+	}, []string{"HS256", "HS384", "HS512"}
+}
+
+func abortUnauthorized(c *gin.Context, message string) {
+	c.Header("WWW-Authenticate", "Bearer")
+	_ = c.Error(NewAPIError(http.StatusUnauthorized, models.ErrCodeUnauthorized, message))
+	c.Abort()
+}
@@ -0,0 +1,177 @@
+package middleware
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"arquivolivre.com.br/otel/internal/auth"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+func signHMAC(t *testing.T, secret string, claims Claims) string {
+	t.Helper()
+	token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString([]byte(secret))
+	require.NoError(t, err)
+	return token
+}
+
+func validClaims(subject string) Claims {
+	return Claims{RegisteredClaims: jwt.RegisteredClaims{
+		Subject:   subject,
+		Issuer:    "issuer",
+		Audience:  jwt.ClaimStrings{"api"},
+		ExpiresAt: jwt.NewNumericDate(time.Now().Add(time.Hour)),
+	}}
+}
+
+func serveJWT(t *testing.T, cfg JWTConfig, header string) (*httptest.ResponseRecorder, *auth.Principal) {
+	t.Helper()
+	var principal *auth.Principal
+
+	r := gin.New()
+	r.Use(ErrorHandler())
+	r.POST("/users", JWTAuth(cfg), func(c *gin.Context) {
+		if p, ok := auth.FromContext(c.Request.Context()); ok {
+			principal = &p
+		}
+		claims, ok := GetJWTClaims(c)
+		assert.True(t, ok)
+		assert.Equal(t, principal.Subject, claims.Subject)
+		c.Status(http.StatusCreated)
+	})
+
+	req := httptest.NewRequest(http.MethodPost, "/users", nil)
+	if header != "" {
+		req.Header.Set("Authorization", header)
+	}
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	return w, principal
+}
+
+func TestJWTAuthHMAC(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	cfg := JWTConfig{Secret: "secret", Issuer: "issuer", Audience: "api"}
+
+	expired := validClaims("alice")
+	expired.ExpiresAt = jwt.NewNumericDate(time.Now().Add(-time.Minute))
+	otherAudience := validClaims("alice")
+	otherAudience.Audience = jwt.ClaimStrings{"other"}
+	noExpiry := validClaims("alice")
+	noExpiry.ExpiresAt = nil
+
+	tests := []struct {
+		name   string
+		header string
+		want   int
+	}{
+		{name: "no token", want: http.StatusUnauthorized},
+		{name: "not a bearer token", header: "Basic abc", want: http.StatusUnauthorized},
+		{name: "malformed token", header: "Bearer abc", want: http.StatusUnauthorized},
+		{name: "wrong secret", header: "Bearer " + signHMAC(t, "other", validClaims("alice")), want: http.StatusUnauthorized},
+		{name: "expired", header: "Bearer " + signHMAC(t, "secret", expired), want: http.StatusUnauthorized},
+		{name: "no expiry", header: "Bearer " + signHMAC(t, "secret", noExpiry), want: http.StatusUnauthorized},
+		{name: "wrong audience", header: "Bearer " + signHMAC(t, "secret", otherAudience), want: http.StatusUnauthorized},
+		{name: "no subject", header: "Bearer " + signHMAC(t, "secret", validClaims("")), want: http.StatusUnauthorized},
+		{name: "valid", header: "Bearer " + signHMAC(t, "secret", validClaims("alice")), want: http.StatusCreated},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w, _ := serveJWT(t, cfg, tt.header)
+			assert.Equal(t, tt.want, w.Code)
+			if tt.want == http.StatusUnauthorized {
+				assert.Equal(t, "Bearer", w.Header().Get("WWW-Authenticate"))
+			}
+		})
+	}
+}
+
+func TestJWTAuthSetsPrincipalAndSpanAttribute(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	recorder := tracetest.NewSpanRecorder()
+	tp := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+
+	claims := validClaims("alice")
+	claims.Roles = []string{auth.RoleAdmin}
+	token := signHMAC(t, "secret", claims)
+
+	r := gin.New()
+	r.Use(func(c *gin.Context) {
+		ctx, span := tp.Tracer("test").Start(c.Request.Context(), "request")
+		defer span.End()
+		c.Request = c.Request.WithContext(ctx)
+		c.Next()
+	})
+	r.POST("/users", JWTAuth(JWTConfig{Secret: "secret"}), func(c *gin.Context) {
+		assert.True(t, auth.IsAdmin(c.Request.Context()))
+		c.Status(http.StatusCreated)
+	})
+
+	req := httptest.NewRequest(http.MethodPost, "/users", nil)
+	req.Header.Set("Authorization", "Bearer "+token)
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	require.Equal(t, http.StatusCreated, w.Code)
+	spans := recorder.Ended()
+	require.Len(t, spans, 1)
+	var enduser string
+	for _, attr := range spans[0].Attributes() {
+		if attr.Key == "enduser.id" {
+			enduser = attr.Value.AsString()
+		}
+	}
+	assert.Equal(t, "alice", enduser)
+}
+
+func TestJWTAuthJWKS(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	jwks := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		_ = json.NewEncoder(w).Encode(map[string]any{"keys": []map[string]string{{
+			"kid": "key-1",
+			"kty": "RSA",
+			"use": "sig",
+			"n":   base64.RawURLEncoding.EncodeToString(key.N.Bytes()),
+			"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes()),
+		}}})
+	}))
+	defer jwks.Close()
+	cfg := JWTConfig{JWKSURL: jwks.URL}
+
+	sign := func(kid string, method jwt.SigningMethod, signingKey any) string {
+		token := jwt.NewWithClaims(method, validClaims("bob"))
+		token.Header["kid"] = kid
+		signed, err := token.SignedString(signingKey)
+		require.NoError(t, err)
+		return "Bearer " + signed
+	}
+
+	w, principal := serveJWT(t, cfg, sign("key-1", jwt.SigningMethodRS256, key))
+	assert.Equal(t, http.StatusCreated, w.Code)
+	require.NotNil(t, principal)
+	assert.Equal(t, "bob", principal.Subject)
+
+	w, _ = serveJWT(t, cfg, sign("key-2", jwt.SigningMethodRS256, key))
+	assert.Equal(t, http.StatusUnauthorized, w.Code, "unknown key ID")
+
+	// An HMAC token must not be accepted in JWKS mode
+	w, _ = serveJWT(t, cfg, sign("key-1", jwt.SigningMethodHS256, []byte("secret")))
+	assert.Equal(t, http.StatusUnauthorized, w.Code, "HMAC token")
+}
@@ -0,0 +1,118 @@
+package middleware
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"arquivolivre.com.br/otel/internal/models"
+
+	"github.com/gin-gonic/gin"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+var (
+	// ErrBodyTooLarge is the cause of the context of a request whose body
+	// went over its BodyLimit
+	ErrBodyTooLarge = errors.New("request body too large")
+	// ErrRequestTimeout is the cause of the context of a request that ran
+	// past its Timeout
+	ErrRequestTimeout = errors.New("request timed out")
+)
+
+// BodyLimit rejects requests whose body is larger than maxBytes with 413.
+// A declared Content-Length over the limit is rejected before the handler
+// runs; otherwise reading past the limit fails and cancels the request
+// context, so the handler stops early. The server span records
+// http.termination_reason=body_too_large. A limit of 0 or less admits any
+// body
+func BodyLimit(maxBytes int64) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if maxBytes <= 0 || c.Request.Body == nil || c.Request.Body == http.NoBody {
+			c.Next()
+			return
+		}
+		if c.Request.ContentLength > maxBytes {
+			terminate(c, "body_too_large", payloadTooLarge(maxBytes))
+			c.Abort()
+			return
+		}
+
+		ctx, cancel := context.WithCancelCause(c.Request.Context())
+		defer cancel(nil)
+		body := &limitedBody{body: http.MaxBytesReader(c.Writer, c.Request.Body, maxBytes), cancel: cancel}
+		c.Request = c.Request.WithContext(ctx)
+		c.Request.Body = body
+		c.Next()
+
+		if body.exceeded && !c.Writer.Written() {
+			terminate(c, "body_too_large", payloadTooLarge(maxBytes))
+		}
+	}
+}
+
+// limitedBody cancels the request when its body goes over the limit
+type limitedBody struct {
+	body     io.ReadCloser
+	cancel   context.CancelCauseFunc
+	exceeded bool
+}
+
+func (b *limitedBody) Read(p []byte) (int, error) {
+	n, err := b.body.Read(p)
+	var tooLarge *http.MaxBytesError
+	if errors.As(err, &tooLarge) && !b.exceeded {
+		b.exceeded = true
+		b.cancel(ErrBodyTooLarge)
+	}
+	return n, err
+}
+
+func (b *limitedBody) Close() error {
+	return b.body.Close()
+}
+
+func payloadTooLarge(maxBytes int64) *APIError {
+	return NewAPIError(http.StatusRequestEntityTooLarge, models.ErrCodePayloadTooLarge,
+		fmt.Sprintf("Request body must be at most %d bytes", maxBytes))
+}
+
+// Timeout gives each request d to complete. The request context is
+// cancelled at the deadline, so database queries and outbound calls made
+// with it are abandoned. A handler that has not written its response by then
+// gets 503 instead, and the server span records
+// http.termination_reason=timeout. A duration of 0 or less never times out.
+//
+// The handler keeps running on the request goroutine until it returns, so
+// Timeout bounds the work done for a request but relies on the handler
+// honouring its context
+func Timeout(d time.Duration) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if d <= 0 {
+			c.Next()
+			return
+		}
+
+		ctx, cancel := context.WithTimeoutCause(c.Request.Context(), d, ErrRequestTimeout)
+		defer cancel()
+		c.Request = c.Request.WithContext(ctx)
+		c.Next()
+
+		if errors.Is(context.Cause(ctx), ErrRequestTimeout) && !c.Writer.Written() {
+			terminate(c, "timeout", NewAPIError(http.StatusServiceUnavailable, models.ErrCodeRequestTimeout,
+				fmt.Sprintf("Request did not complete within %s", d)))
+		}
+	}
+}
+
+// terminate answers the request with apiErr in place of whatever error the
+// handler reported, and records why the request was cut short on the span
+func terminate(c *gin.Context, reason string, apiErr *APIError) {
+	trace.SpanFromContext(c.Request.Context()).SetAttributes(attribute.String("http.termination_reason", reason))
+	// ErrorHandler answers with the last error
+	_ = c.Error(apiErr)
+}
@@ -0,0 +1,104 @@
+package middleware
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+func withSpanRecorder(t *testing.T) *tracetest.SpanRecorder {
+	t.Helper()
+	recorder := tracetest.NewSpanRecorder()
+	prevTP := otel.GetTracerProvider()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)))
+	t.Cleanup(func() { otel.SetTracerProvider(prevTP) })
+	return recorder
+}
+
+func TestBodyLimit(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	recorder := withSpanRecorder(t)
+
+	var handlerErr error
+	r := gin.New()
+	r.Use(NewTelemetryMiddleware("test-service").GinMiddleware())
+	r.Use(ErrorHandler())
+	r.POST("/users", BodyLimit(8), func(c *gin.Context) {
+		if _, err := io.ReadAll(c.Request.Body); err != nil {
+			handlerErr = c.Request.Context().Err()
+			_ = c.Error(BadRequestError("Invalid request data"))
+			return
+		}
+		c.Status(http.StatusCreated)
+	})
+
+	post := func(body string, contentLength int64) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodPost, "/users", strings.NewReader(body))
+		req.ContentLength = contentLength
+		r.ServeHTTP(w, req)
+		return w
+	}
+
+	assert.Equal(t, http.StatusCreated, post("12345678", 8).Code)
+
+	// A declared length is rejected before the handler runs
+	w := post("123456789", 9)
+	assert.Equal(t, http.StatusRequestEntityTooLarge, w.Code)
+	assert.Contains(t, w.Body.String(), `"code":"PAYLOAD_TOO_LARGE"`)
+	assert.NoError(t, handlerErr)
+
+	// A chunked body fails once it is read past the limit
+	w = post("123456789", -1)
+	assert.Equal(t, http.StatusRequestEntityTooLarge, w.Code)
+	assert.ErrorIs(t, handlerErr, context.Canceled)
+
+	spans := recorder.Ended()
+	require.Len(t, spans, 3)
+	assert.Contains(t, spans[1].Attributes(), attribute.String("http.termination_reason", "body_too_large"))
+	assert.Contains(t, spans[2].Attributes(), attribute.String("http.termination_reason", "body_too_large"))
+}
+
+func TestTimeout(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	recorder := withSpanRecorder(t)
+
+	r := gin.New()
+	r.Use(NewTelemetryMiddleware("test-service").GinMiddleware())
+	r.Use(ErrorHandler())
+	r.GET("/slow", Timeout(10*time.Millisecond), func(c *gin.Context) {
+		<-c.Request.Context().Done()
+		_ = c.Error(InternalError("Failed to get user", c.Request.Context().Err()))
+	})
+	r.GET("/fast", Timeout(time.Minute), func(c *gin.Context) {
+		c.Status(http.StatusOK)
+	})
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/slow", nil))
+	assert.Equal(t, http.StatusServiceUnavailable, w.Code)
+	assert.Contains(t, w.Body.String(), `"code":"REQUEST_TIMEOUT"`)
+
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/fast", nil))
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	spans := recorder.Ended()
+	require.Len(t, spans, 2)
+	assert.Contains(t, spans[0].Attributes(), attribute.String("http.termination_reason", "timeout"))
+	for _, attr := range spans[1].Attributes() {
+		assert.NotEqual(t, attribute.Key("http.termination_reason"), attr.Key)
+	}
+}
@@ -0,0 +1,115 @@
+package middleware
+
+import (
+	"math"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"arquivolivre.com.br/otel/internal/models"
+
+	"github.com/gin-gonic/gin"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// RateLimiter is a token bucket shared by the requests of one route group.
+// The bucket holds up to one second of requests, so short bursts up to the
+// rate are admitted at once
+type RateLimiter struct {
+	group     string
+	throttled metric.Int64Counter
+	now       func() time.Time
+
+	mu     sync.Mutex
+	rate   float64
+	tokens float64
+	last   time.Time
+}
+
+// NewRateLimiter creates a limiter for group admitting rps requests per
+// second; a rate of 0 or less admits every request
+func NewRateLimiter(group string, rps float64) *RateLimiter {
+	throttled, _ := otel.Meter("otel-example-api").Int64Counter(
+		"http_requests_throttled_total",
+		metric.WithDescription("Total number of HTTP requests rejected by a rate limiter"),
+	)
+
+	l := &RateLimiter{group: group, throttled: throttled, now: time.Now}
+	l.SetRate(rps)
+	return l
+}
+
+// SetRate changes the rate at runtime. A new rate starts with a full bucket
+func (l *RateLimiter) SetRate(rps float64) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if rps == l.rate && !l.last.IsZero() {
+		return
+	}
+	l.rate = rps
+	l.tokens = l.capacity()
+	l.last = l.now()
+}
+
+// Rate returns the current rate in requests per second
+func (l *RateLimiter) Rate() float64 {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.rate
+}
+
+func (l *RateLimiter) capacity() float64 {
+	return math.Max(1, math.Ceil(l.rate))
+}
+
+// take consumes a token, or reports how long until one is available
+func (l *RateLimiter) take() (bool, time.Duration) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.rate <= 0 {
+		return true, 0
+	}
+
+	now := l.now()
+	l.tokens = math.Min(l.capacity(), l.tokens+now.Sub(l.last).Seconds()*l.rate)
+	l.last = now
+	if l.tokens >= 1 {
+		l.tokens--
+		return true, 0
+	}
+	return false, time.Duration((1 - l.tokens) / l.rate * float64(time.Second))
+}
+
+// RateLimit rejects requests beyond the limiter's rate with 429 and a
+// Retry-After header. Rejections are counted in
+// http_requests_throttled_total and added as an event to the server span
+func RateLimit(l *RateLimiter) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ok, retryAfter := l.take()
+		if ok {
+			c.Next()
+			return
+		}
+
+		attrs := []attribute.KeyValue{
+			attribute.String("rate_limit.group", l.group),
+			attribute.String("method", c.Request.Method),
+			attribute.String("route", c.FullPath()),
+		}
+		l.throttled.Add(c.Request.Context(), 1, metric.WithAttributes(attrs...))
+		trace.SpanFromContext(c.Request.Context()).AddEvent("rate_limit.exceeded", trace.WithAttributes(
+			attribute.String("rate_limit.group", l.group),
+			attribute.Float64("rate_limit.rps", l.Rate()),
+			attribute.Float64("rate_limit.retry_after_seconds", retryAfter.Seconds()),
+		))
+
+		// Retry-After takes whole seconds; round up so clients do not retry early
+		c.Header("Retry-After", strconv.Itoa(int(math.Ceil(retryAfter.Seconds()))))
+		_ = c.Error(NewAPIError(http.StatusTooManyRequests, models.ErrCodeRateLimited, "Rate limit exceeded, retry later"))
+		c.Abort()
+	}
+}
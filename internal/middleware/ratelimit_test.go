@@ -0,0 +1,82 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"go.opentelemetry.io/otel"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+func newTestLimiter(rps float64) (*RateLimiter, *time.Time) {
+	now := time.Unix(0, 0)
+	l := NewRateLimiter("test", 0)
+	l.now = func() time.Time { return now }
+	l.SetRate(rps)
+	return l, &now
+}
+
+func TestRateLimiterTokenBucket(t *testing.T) {
+	l, now := newTestLimiter(2)
+
+	// The bucket starts with one second of requests
+	for i := 0; i < 2; i++ {
+		ok, _ := l.take()
+		assert.True(t, ok, "request %d", i)
+	}
+	ok, retryAfter := l.take()
+	assert.False(t, ok)
+	assert.Equal(t, 500*time.Millisecond, retryAfter)
+
+	*now = now.Add(500 * time.Millisecond)
+	ok, _ = l.take()
+	assert.True(t, ok)
+
+	// Unchanged rates keep the bucket, a new rate refills it
+	l.SetRate(2)
+	ok, _ = l.take()
+	assert.False(t, ok)
+	l.SetRate(0)
+	for i := 0; i < 10; i++ {
+		ok, _ = l.take()
+		assert.True(t, ok, "unlimited request %d", i)
+	}
+}
+
+func TestRateLimit(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	recorder := tracetest.NewSpanRecorder()
+	prevTP := otel.GetTracerProvider()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)))
+	defer otel.SetTracerProvider(prevTP)
+
+	l, _ := newTestLimiter(0.5)
+	tm := NewTelemetryMiddleware("test-service")
+	r := gin.New()
+	r.Use(tm.GinMiddleware())
+	r.Use(ErrorHandler())
+	r.POST("/users", RateLimit(l), func(c *gin.Context) { c.Status(http.StatusCreated) })
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/users", nil))
+	assert.Equal(t, http.StatusCreated, w.Code)
+
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/users", nil))
+	assert.Equal(t, http.StatusTooManyRequests, w.Code)
+	assert.Equal(t, "2", w.Header().Get("Retry-After"))
+	assert.Contains(t, w.Body.String(), `"code":"RATE_LIMITED"`)
+
+	spans := recorder.Ended()
+	if assert.Len(t, spans, 2) {
+		events := spans[1].Events()
+		if assert.NotEmpty(t, events) {
+			assert.Equal(t, "rate_limit.exceeded", events[0].Name)
+		}
+	}
+}
@@ -0,0 +1,121 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+	"slices"
+
+	"arquivolivre.com.br/otel/internal/auth"
+	"arquivolivre.com.br/otel/internal/logging"
+	"arquivolivre.com.br/otel/internal/models"
+
+	"github.com/gin-gonic/gin"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// RequireRole admits principals granted role by the roles claim of their
+// token, the roles of their API key or the roles added by AssignedRoles, and
+// rejects the rest: anonymous callers with 401 and
+// authenticated ones with 403. Every decision adds an
+// authorization.decision event to the server span, and each denial is
+// written to the log as an audit entry. It must run after JWTAuth or
+// APIKeyAuth
+func RequireRole(role string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		switch Authorize(c.Request.Context(), role, c.Request.Method, c.FullPath()) {
+		case "":
+			c.Next()
+			return
+		case ReasonUnauthenticated:
+			_ = c.Error(NewAPIError(http.StatusUnauthorized, models.ErrCodeUnauthorized, "Authentication required"))
+		default:
+			_ = c.Error(NewAPIError(http.StatusForbidden, models.ErrCodeForbidden, "Role "+role+" required"))
+		}
+		c.Abort()
+	}
+}
+
+// Reasons Authorize gives for denials
+const (
+	ReasonUnauthenticated = "unauthenticated"
+	ReasonMissingRole     = "missing_role"
+)
+
+// Authorize decides whether the principal in ctx is granted role, as
+// RequireRole does for HTTP routes: it adds the authorization.decision event
+// to the span in ctx and logs denials with method and route. It returns the
+// reason of a denial, or "" when the role is granted
+func Authorize(ctx context.Context, role, method, route string) string {
+	principal, authenticated := auth.FromContext(ctx)
+	decision, reason := "allow", ""
+	switch {
+	case !authenticated:
+		decision, reason = "deny", ReasonUnauthenticated
+	case !principal.Grants(role):
+		decision, reason = "deny", ReasonMissingRole
+	}
+
+	attrs := []attribute.KeyValue{
+		attribute.String("authz.role", role),
+		attribute.String("authz.decision", decision),
+	}
+	if reason != "" {
+		attrs = append(attrs, attribute.String("authz.reason", reason))
+	}
+	trace.SpanFromContext(ctx).AddEvent("authorization.decision", trace.WithAttributes(attrs...))
+
+	if reason != "" {
+		logging.LogWarn(ctx, "Authorization denied", map[string]interface{}{
+			"audit":         true,
+			"actor":         auth.Actor(ctx),
+			"roles":         principal.Roles,
+			"required_role": role,
+			"reason":        reason,
+			"method":        method,
+			"route":         route,
+		})
+	}
+	return reason
+}
+
+// RoleSource looks up roles stored for a principal beyond those of its
+// credentials
+type RoleSource interface {
+	RolesOf(ctx context.Context, subject string) ([]string, error)
+}
+
+// AssignedRoles adds the roles source holds for the principal's subject to
+// the principal, so RequireRole also grants them. A failed lookup is logged
+// and leaves the principal with the roles of its credentials. It must run
+// after JWTAuth or APIKeyAuth and before RequireRole
+func AssignedRoles(source RoleSource) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Request = c.Request.WithContext(WithAssignedRoles(c.Request.Context(), source))
+		c.Next()
+	}
+}
+
+// WithAssignedRoles returns ctx with the roles source holds for the
+// principal of ctx added to it, as AssignedRoles does for HTTP routes
+func WithAssignedRoles(ctx context.Context, source RoleSource) context.Context {
+	principal, ok := auth.FromContext(ctx)
+	if !ok {
+		return ctx
+	}
+
+	assigned, err := source.RolesOf(ctx, principal.Subject)
+	if err != nil {
+		logging.LogWarn(ctx, "Failed to look up assigned roles", map[string]interface{}{
+			"actor": principal.Subject,
+			"error": err.Error(),
+		})
+		return ctx
+	}
+	trace.SpanFromContext(ctx).SetAttributes(attribute.Int("authz.assigned_roles", len(assigned)))
+	if len(assigned) == 0 {
+		return ctx
+	}
+	principal.Roles = append(slices.Clip(principal.Roles), assigned...)
+	return auth.WithPrincipal(ctx, principal)
+}
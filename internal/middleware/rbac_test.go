@@ -0,0 +1,101 @@
+package middleware
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"arquivolivre.com.br/otel/internal/auth"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/attribute"
+)
+
+func TestRequireRole(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	recorder := withSpanRecorder(t)
+
+	keys, err := auth.ParseAPIKeys([]string{"writer:k1::users:write", "reader:k2", "ops:k3::users:*"})
+	require.NoError(t, err)
+
+	r := gin.New()
+	r.Use(NewTelemetryMiddleware("test-service").GinMiddleware())
+	r.Use(ErrorHandler())
+	r.POST("/users", APIKeyAuth(keys, NewQuotas()), RequireRole(auth.RoleUsersWrite), func(c *gin.Context) {
+		c.Status(http.StatusCreated)
+	})
+
+	post := func(key string) int {
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodPost, "/users", nil)
+		if key != "" {
+			req.Header.Set(APIKeyHeader, key)
+		}
+		r.ServeHTTP(w, req)
+		return w.Code
+	}
+
+	assert.Equal(t, http.StatusCreated, post("k1"))
+	assert.Equal(t, http.StatusForbidden, post("k2"))
+	assert.Equal(t, http.StatusCreated, post("k3"))
+	assert.Equal(t, http.StatusUnauthorized, post(""))
+
+	spans := recorder.Ended()
+	require.Len(t, spans, 4)
+	decisions := []string{}
+	for _, span := range spans {
+		for _, event := range span.Events() {
+			if event.Name != "authorization.decision" {
+				continue
+			}
+			assert.Contains(t, event.Attributes, attribute.String("authz.role", auth.RoleUsersWrite))
+			for _, attr := range event.Attributes {
+				if attr.Key == "authz.decision" || attr.Key == "authz.reason" {
+					decisions = append(decisions, attr.Value.AsString())
+				}
+			}
+		}
+	}
+	assert.Equal(t, []string{"allow", "deny", "missing_role", "allow", "deny", "unauthenticated"}, decisions)
+}
+
+// roleSource returns the roles of each subject
+type roleSource map[string][]string
+
+func (s roleSource) RolesOf(_ context.Context, subject string) ([]string, error) {
+	if subject == "broken" {
+		return nil, errors.New("database down")
+	}
+	return s[subject], nil
+}
+
+func TestAssignedRolesGrantStoredRoles(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	keys, err := auth.ParseAPIKeys([]string{"ana@example.com:k1", "bob@example.com:k2", "broken:k3::users:write"})
+	require.NoError(t, err)
+	source := roleSource{"ana@example.com": {auth.RoleUsersWrite}}
+
+	r := gin.New()
+	r.Use(ErrorHandler())
+	r.POST("/users", APIKeyAuth(keys, NewQuotas()), AssignedRoles(source), RequireRole(auth.RoleUsersWrite), func(c *gin.Context) {
+		c.Status(http.StatusCreated)
+	})
+
+	post := func(key string) int {
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodPost, "/users", nil)
+		req.Header.Set(APIKeyHeader, key)
+		r.ServeHTTP(w, req)
+		return w.Code
+	}
+
+	assert.Equal(t, http.StatusCreated, post("k1"))
+	assert.Equal(t, http.StatusForbidden, post("k2"))
+	// A failed lookup keeps the roles of the credentials
+	assert.Equal(t, http.StatusCreated, post("k3"))
+}
@@ -0,0 +1,41 @@
+package middleware
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+
+	"arquivolivre.com.br/otel/pkg/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+// RequestIDHeader is the header used to propagate request IDs
+const RequestIDHeader = utils.RequestIDHeader
+
+// RequestID reuses the caller's X-Request-ID or generates a new one, stores it
+// in the gin and request contexts and echoes it in the response. Log entries
+// and the server span pick it up from there
+func RequestID() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		requestID := c.GetHeader(RequestIDHeader)
+		if requestID == "" || len(requestID) > 128 {
+			requestID = newRequestID()
+		}
+
+		c.Set(utils.RequestIDContextKey, requestID)
+		c.Request = c.Request.WithContext(utils.ContextWithRequestID(c.Request.Context(), requestID))
+		c.Header(RequestIDHeader, requestID)
+		c.Next()
+	}
+}
+
+// GetRequestID returns the request ID assigned by RequestID, if any
+func GetRequestID(c *gin.Context) string {
+	return utils.RequestID(c)
+}
+
+func newRequestID() string {
+	b := make([]byte, 16)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
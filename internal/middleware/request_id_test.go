@@ -0,0 +1,41 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"arquivolivre.com.br/otel/pkg/utils"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRequestID(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.Use(RequestID())
+	r.GET("/test", func(c *gin.Context) {
+		assert.Equal(t, GetRequestID(c), utils.RequestIDFromContext(c.Request.Context()))
+		c.String(http.StatusOK, GetRequestID(c))
+	})
+
+	// An incoming ID is propagated
+	w := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodGet, "/test", nil)
+	req.Header.Set(RequestIDHeader, "abc-123")
+	r.ServeHTTP(w, req)
+	assert.Equal(t, "abc-123", w.Body.String())
+	assert.Equal(t, "abc-123", w.Header().Get(RequestIDHeader))
+
+	// Missing or oversized IDs are replaced with a generated one
+	for _, incoming := range []string{"", strings.Repeat("x", 200)} {
+		w = httptest.NewRecorder()
+		req = httptest.NewRequest(http.MethodGet, "/test", nil)
+		req.Header.Set(RequestIDHeader, incoming)
+		r.ServeHTTP(w, req)
+		assert.Len(t, w.Body.String(), 32)
+		assert.Equal(t, w.Body.String(), w.Header().Get(RequestIDHeader))
+	}
+}
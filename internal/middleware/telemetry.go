@@ -1,14 +1,21 @@
 package middleware
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"strconv"
 	"time"
 
+	"arquivolivre.com.br/otel/internal/auth"
+	"arquivolivre.com.br/otel/internal/config"
+	"arquivolivre.com.br/otel/pkg/utils"
+
 	"github.com/gin-gonic/gin"
 	"go.opentelemetry.io/contrib/instrumentation/github.com/gin-gonic/gin/otelgin"
 	"go.opentelemetry.io/otel"
 	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/baggage"
 	"go.opentelemetry.io/otel/codes"
 	"go.opentelemetry.io/otel/metric"
 	"go.opentelemetry.io/otel/trace"
@@ -23,6 +30,8 @@ type TelemetryMiddleware struct {
 	requestSize     metric.Int64Histogram
 	responseSize    metric.Int64Histogram
 	activeRequests  metric.Int64UpDownCounter
+	cancelled       metric.Int64Counter
+	untraced        map[string]bool
 }
 
 // NewTelemetryMiddleware creates a new telemetry middleware
@@ -59,6 +68,11 @@ func NewTelemetryMiddleware(serviceName string) *TelemetryMiddleware {
 		metric.WithDescription("Number of active HTTP requests"),
 	)
 
+	cancelled, _ := meter.Int64Counter(
+		"http_requests_cancelled_total",
+		metric.WithDescription("Number of HTTP requests whose context ended before the handler returned"),
+	)
+
 	return &TelemetryMiddleware{
 		tracer:          tracer,
 		meter:           meter,
@@ -67,17 +81,47 @@ func NewTelemetryMiddleware(serviceName string) *TelemetryMiddleware {
 		requestSize:     requestSize,
 		responseSize:    responseSize,
 		activeRequests:  activeRequests,
+		cancelled:       cancelled,
+	}
+}
+
+// ExcludePaths leaves requests to paths out of tracing and the HTTP metrics.
+// Spans started while serving them, e.g. by database pings, are dropped by
+// config.DynamicSampler
+func (tm *TelemetryMiddleware) ExcludePaths(paths ...string) *TelemetryMiddleware {
+	if tm.untraced == nil {
+		tm.untraced = make(map[string]bool, len(paths))
+	}
+	for _, path := range paths {
+		tm.untraced[path] = true
 	}
+	return tm
+}
+
+func (tm *TelemetryMiddleware) excluded(c *gin.Context) bool {
+	return tm.untraced[c.Request.URL.Path]
 }
 
 // GinMiddleware returns Gin middleware for OpenTelemetry tracing
 func (tm *TelemetryMiddleware) GinMiddleware() gin.HandlerFunc {
-	return otelgin.Middleware("otel-example-api")
+	traced := otelgin.Middleware("otel-example-api")
+	return func(c *gin.Context) {
+		if !tm.excluded(c) {
+			traced(c)
+			return
+		}
+		c.Request = c.Request.WithContext(config.WithoutTracing(c.Request.Context()))
+		c.Next()
+	}
 }
 
 // MetricsMiddleware returns Gin middleware for custom metrics collection
 func (tm *TelemetryMiddleware) MetricsMiddleware() gin.HandlerFunc {
 	return func(c *gin.Context) {
+		if tm.excluded(c) {
+			c.Next()
+			return
+		}
 		start := time.Now()
 
 		// Common attributes for metrics
@@ -96,7 +140,9 @@ func (tm *TelemetryMiddleware) MetricsMiddleware() gin.HandlerFunc {
 				metric.WithAttributes(commonAttrs...))
 		}
 
-		// Process request
+		// Process request. The context of the request as it arrived ends
+		// early only when the client goes away
+		arrived := c.Request.Context()
 		c.Next()
 
 		// Calculate duration
@@ -113,15 +159,23 @@ func (tm *TelemetryMiddleware) MetricsMiddleware() gin.HandlerFunc {
 			responseSize = int64(c.Writer.Size())
 		}
 
-		// Final attributes including status
+		// Final attributes including status and, when known, the API client.
+		// The tenant comes from a client-chosen header, so it stays on spans
+		// and logs rather than creating unbounded metric series
 		finalAttrs := append(commonAttrs,
 			attribute.String("status_code", strconv.Itoa(c.Writer.Status())),
 			attribute.String("status_class", getStatusClass(c.Writer.Status())),
 		)
+		if clientID := auth.ClientID(c.Request.Context()); clientID != "" {
+			finalAttrs = append(finalAttrs, attribute.String(auth.ClientIDKey, clientID))
+		}
 
 		// Record metrics
 		tm.requestCounter.Add(c.Request.Context(), 1, metric.WithAttributes(finalAttrs...))
 		tm.requestDuration.Record(c.Request.Context(), duration, metric.WithAttributes(finalAttrs...))
+		if reason := cancellationReason(arrived, c.Request.Context()); reason != "" {
+			tm.cancelled.Add(c.Request.Context(), 1, metric.WithAttributes(append(commonAttrs, attribute.String("reason", reason))...))
+		}
 
 		if responseSize > 0 {
 			tm.responseSize.Record(c.Request.Context(), responseSize, metric.WithAttributes(finalAttrs...))
@@ -141,6 +195,15 @@ func (tm *TelemetryMiddleware) MetricsMiddleware() gin.HandlerFunc {
 				attribute.Float64("http.duration", duration),
 			)
 
+			if requestID := utils.RequestID(c); requestID != "" {
+				span.SetAttributes(attribute.String("http.request_id", requestID))
+			}
+
+			// Requests from the synthetic prober carry synthetic=true baggage
+			if baggage.FromContext(c.Request.Context()).Member("synthetic").Value() == "true" {
+				span.SetAttributes(attribute.Bool("synthetic", true))
+			}
+
 			// Add error information if present
 			if len(c.Errors) > 0 {
 				span.SetAttributes(
@@ -158,6 +221,21 @@ func (tm *TelemetryMiddleware) MetricsMiddleware() gin.HandlerFunc {
 	}
 }
 
+// cancellationReason tells why a request was cancelled: "timeout" when it ran
+// past the deadline set by Timeout, which is seen on current, the context it
+// ended with, or "client_disconnect" when arrived, the context it came with,
+// ended because the client went away. It is empty for requests that completed
+func cancellationReason(arrived, current context.Context) string {
+	switch {
+	case errors.Is(context.Cause(current), ErrRequestTimeout):
+		return "timeout"
+	case arrived.Err() != nil:
+		return "client_disconnect"
+	default:
+		return ""
+	}
+}
+
 // getStatusClass returns the HTTP status class (2xx, 3xx, 4xx, 5xx)
 func getStatusClass(statusCode int) string {
 	switch {
@@ -2,14 +2,23 @@ package middleware
 
 import (
 	"bytes"
+	"context"
 	"errors"
 	"net/http"
 	"net/http/httptest"
 	"testing"
+	"time"
+
+	"arquivolivre.com.br/otel/internal/config"
+	"arquivolivre.com.br/otel/pkg/oteltest"
 
 	"github.com/gin-gonic/gin"
 	"github.com/stretchr/testify/assert"
+	"go.opentelemetry.io/otel"
 	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
 )
 
 func TestMetricsMiddleware(t *testing.T) {
@@ -25,6 +34,63 @@ func TestMetricsMiddleware(t *testing.T) {
 	assert.Equal(t, 200, w.Code)
 }
 
+func TestMetricsMiddlewareRecordsValues(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	rec := oteltest.Install(t)
+	tm := NewTelemetryMiddleware("test-service")
+	r := gin.New()
+	r.Use(tm.MetricsMiddleware())
+	r.POST("/items/:id", func(c *gin.Context) { c.String(http.StatusCreated, "created") })
+	r.GET("/fail", func(c *gin.Context) { c.String(http.StatusInternalServerError, "error") })
+
+	for range 2 {
+		r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodPost, "/items/1", bytes.NewBufferString("data")))
+	}
+	r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/fail", nil))
+
+	created := []attribute.KeyValue{
+		attribute.String("method", http.MethodPost),
+		attribute.String("route", "/items/:id"),
+		attribute.String("status_code", "201"),
+	}
+	rec.AssertCounterValue(t, "http_requests_total", created, 2)
+	rec.AssertCounterValue(t, "http_requests_total", []attribute.KeyValue{attribute.String("status_class", "5xx")}, 1)
+	rec.AssertHistogramCount(t, "http_request_duration_seconds", nil, 3)
+	rec.AssertHistogramCount(t, "http_request_size_bytes", []attribute.KeyValue{attribute.String("route", "/items/:id")}, 2)
+	rec.AssertCounterValue(t, "http_active_requests", nil, 0)
+}
+
+func TestMetricsMiddlewareCountsCancelledRequests(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	rec := oteltest.Install(t)
+	tm := NewTelemetryMiddleware("test-service")
+	r := gin.New()
+	r.Use(tm.MetricsMiddleware())
+	wait := func(c *gin.Context) {
+		<-c.Request.Context().Done()
+		c.Status(http.StatusServiceUnavailable)
+	}
+	r.GET("/slow", Timeout(10*time.Millisecond), wait)
+	r.GET("/abandoned", Timeout(time.Minute), wait)
+	r.GET("/fast", Timeout(time.Minute), func(c *gin.Context) { c.Status(http.StatusOK) })
+
+	r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/slow", nil))
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/abandoned", nil).WithContext(ctx))
+	r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/fast", nil))
+
+	rec.AssertCounterValue(t, "http_requests_cancelled_total", []attribute.KeyValue{
+		attribute.String("route", "/slow"),
+		attribute.String("reason", "timeout"),
+	}, 1)
+	rec.AssertCounterValue(t, "http_requests_cancelled_total", []attribute.KeyValue{
+		attribute.String("route", "/abandoned"),
+		attribute.String("reason", "client_disconnect"),
+	}, 1)
+	rec.AssertCounterValue(t, "http_requests_cancelled_total", nil, 2)
+}
+
 func TestGetStatusClass(t *testing.T) {
 	cases := map[int]string{199: "1xx", 200: "2xx", 301: "3xx", 404: "4xx", 500: "5xx"}
 	for code, cls := range cases {
@@ -296,3 +362,86 @@ func TestRecordError_WithRecordingSpan(t *testing.T) {
 	r.ServeHTTP(w, req)
 	assert.Equal(t, http.StatusOK, w.Code)
 }
+
+func TestMetricsMiddlewareTagsSyntheticRequests(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	recorder := tracetest.NewSpanRecorder()
+	prevTP, prevProp := otel.GetTracerProvider(), otel.GetTextMapPropagator()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)))
+	otel.SetTextMapPropagator(propagation.Baggage{})
+	defer func() {
+		otel.SetTracerProvider(prevTP)
+		otel.SetTextMapPropagator(prevProp)
+	}()
+
+	tm := NewTelemetryMiddleware("test-service")
+	r := gin.New()
+	r.Use(tm.GinMiddleware())
+	r.Use(tm.MetricsMiddleware())
+	r.GET("/ok", func(c *gin.Context) { c.String(http.StatusOK, "ok") })
+
+	req := httptest.NewRequest(http.MethodGet, "/ok", nil)
+	req.Header.Set("Baggage", "synthetic=true")
+	r.ServeHTTP(httptest.NewRecorder(), req)
+
+	spans := recorder.Ended()
+	if assert.Len(t, spans, 1) {
+		assert.Contains(t, spans[0].Attributes(), attribute.Bool("synthetic", true))
+	}
+}
+
+func TestMetricsMiddlewareRecordsRequestID(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	recorder := tracetest.NewSpanRecorder()
+	prevTP := otel.GetTracerProvider()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)))
+	defer otel.SetTracerProvider(prevTP)
+
+	tm := NewTelemetryMiddleware("test-service")
+	r := gin.New()
+	r.Use(RequestID())
+	r.Use(tm.GinMiddleware())
+	r.Use(tm.MetricsMiddleware())
+	r.GET("/ok", func(c *gin.Context) { c.String(http.StatusOK, "ok") })
+
+	req := httptest.NewRequest(http.MethodGet, "/ok", nil)
+	req.Header.Set(RequestIDHeader, "req-1")
+	r.ServeHTTP(httptest.NewRecorder(), req)
+
+	spans := recorder.Ended()
+	if assert.Len(t, spans, 1) {
+		assert.Contains(t, spans[0].Attributes(), attribute.String("http.request_id", "req-1"))
+	}
+}
+
+func TestExcludedPathsAreNotTraced(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	recorder := tracetest.NewSpanRecorder()
+	prevTP := otel.GetTracerProvider()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(
+		sdktrace.WithSampler(config.NewDynamicSampler(config.SamplerAlwaysOn, 1)),
+		sdktrace.WithSpanProcessor(recorder),
+	))
+	defer otel.SetTracerProvider(prevTP)
+
+	tm := NewTelemetryMiddleware("test-service").ExcludePaths("/health")
+	r := gin.New()
+	r.Use(tm.GinMiddleware())
+	r.Use(tm.MetricsMiddleware())
+	handler := func(c *gin.Context) {
+		// Stands in for the database ping of a health check
+		_, span := otel.Tracer("test").Start(c.Request.Context(), "db.ping")
+		span.End()
+		c.String(http.StatusOK, "ok")
+	}
+	r.GET("/health", handler)
+	r.GET("/ok", handler)
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/health", nil))
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Empty(t, recorder.Ended())
+
+	r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/ok", nil))
+	assert.Len(t, recorder.Ended(), 2)
+}
@@ -0,0 +1,32 @@
+package middleware
+
+import (
+	"net/http"
+
+	"arquivolivre.com.br/otel/internal/models"
+	"arquivolivre.com.br/otel/internal/tenant"
+
+	"github.com/gin-gonic/gin"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Tenant stores the tenant of the X-Tenant-ID header in the request baggage
+// and records it as tenant.id on the server span. Without the header a
+// tenant already in the incoming baggage is kept. Invalid IDs are rejected
+// with 400; a tenant_id claim of a verified token later takes precedence
+func Tenant() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx := c.Request.Context()
+		if id := c.GetHeader(tenant.Header); id != "" {
+			var err error
+			if ctx, err = tenant.WithID(ctx, id); err != nil {
+				_ = c.Error(NewAPIError(http.StatusBadRequest, models.ErrCodeInvalidRequest, "Invalid "+tenant.Header+" header"))
+				c.Abort()
+				return
+			}
+			c.Request = c.Request.WithContext(ctx)
+		}
+		trace.SpanFromContext(ctx).SetAttributes(tenant.Attributes(ctx)...)
+		c.Next()
+	}
+}
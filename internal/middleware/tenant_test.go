@@ -0,0 +1,93 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"arquivolivre.com.br/otel/internal/tenant"
+	"arquivolivre.com.br/otel/pkg/oteltest"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/attribute"
+)
+
+func serveTenant(t *testing.T, jwtCfg JWTConfig, headers map[string]string) (int, string) {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+	var got string
+
+	r := gin.New()
+	r.Use(ErrorHandler(), Tenant())
+	handlers := []gin.HandlerFunc{}
+	if jwtCfg.Enabled() {
+		handlers = append(handlers, JWTAuth(jwtCfg))
+	}
+	r.POST("/users", append(handlers, func(c *gin.Context) {
+		got = tenant.FromContext(c.Request.Context())
+		c.Status(http.StatusCreated)
+	})...)
+
+	req := httptest.NewRequest(http.MethodPost, "/users", nil)
+	for key, value := range headers {
+		req.Header.Set(key, value)
+	}
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	return w.Code, got
+}
+
+func TestTenantFromHeader(t *testing.T) {
+	code, got := serveTenant(t, JWTConfig{}, map[string]string{tenant.Header: "acme"})
+	require.Equal(t, http.StatusCreated, code)
+	assert.Equal(t, "acme", got)
+
+	code, got = serveTenant(t, JWTConfig{}, nil)
+	require.Equal(t, http.StatusCreated, code)
+	assert.Empty(t, got)
+}
+
+func TestTenantRejectsInvalidHeader(t *testing.T) {
+	code, _ := serveTenant(t, JWTConfig{}, map[string]string{tenant.Header: "acme corp"})
+	assert.Equal(t, http.StatusBadRequest, code)
+}
+
+func TestTenantClaimOverridesHeader(t *testing.T) {
+	claims := validClaims("alice")
+	claims.TenantID = "globex"
+	token := signHMAC(t, "secret", claims)
+
+	code, got := serveTenant(t, JWTConfig{Secret: "secret"}, map[string]string{
+		tenant.Header:   "acme",
+		"Authorization": "Bearer " + token,
+	})
+	require.Equal(t, http.StatusCreated, code)
+	assert.Equal(t, "globex", got)
+
+	claims.TenantID = "not valid"
+	code, _ = serveTenant(t, JWTConfig{Secret: "secret"}, map[string]string{"Authorization": "Bearer " + signHMAC(t, "secret", claims)})
+	assert.Equal(t, http.StatusUnauthorized, code)
+}
+
+func TestTenantStaysOutOfMetrics(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	rec := oteltest.Install(t)
+	tm := NewTelemetryMiddleware("test-service")
+	r := gin.New()
+	r.Use(tm.GinMiddleware(), tm.MetricsMiddleware(), Tenant())
+	r.GET("/ok", func(c *gin.Context) { c.Status(http.StatusOK) })
+
+	req := httptest.NewRequest(http.MethodGet, "/ok", nil)
+	req.Header.Set(tenant.Header, "acme")
+	r.ServeHTTP(httptest.NewRecorder(), req)
+
+	// Any client can send a new tenant, so it must not become a series
+	rec.AssertCounterValue(t, "http_requests_total", nil, 1)
+	rec.AssertCounterValue(t, "http_requests_total", []attribute.KeyValue{attribute.String(tenant.Key, "acme")}, 0)
+	spans := rec.Spans()
+	if assert.Len(t, spans, 1) {
+		assert.Contains(t, spans[0].Attributes(), attribute.String(tenant.Key, "acme"))
+	}
+}
@@ -0,0 +1,60 @@
+package models
+
+import (
+	"bytes"
+	"encoding/json"
+)
+
+// Nullable is an optional request field that distinguishes "absent" (leave
+// unchanged) from an explicit JSON null (clear the value). Use it with the
+// omitzero JSON option.
+type Nullable[T any] struct {
+	Set   bool
+	Null  bool
+	Value T
+}
+
+// NewNullable returns a Nullable holding value
+func NewNullable[T any](value T) Nullable[T] {
+	return Nullable[T]{Set: true, Value: value}
+}
+
+// Null returns a Nullable that clears the field
+func Null[T any]() Nullable[T] {
+	return Nullable[T]{Set: true, Null: true}
+}
+
+// Ptr returns nil for null or absent values and a pointer to Value otherwise
+func (n Nullable[T]) Ptr() *T {
+	if !n.Set || n.Null {
+		return nil
+	}
+	value := n.Value
+	return &value
+}
+
+// IsZero reports whether the field was absent, so omitzero skips it
+func (n Nullable[T]) IsZero() bool {
+	return !n.Set
+}
+
+// MarshalJSON encodes null or the value
+func (n Nullable[T]) MarshalJSON() ([]byte, error) {
+	if !n.Set || n.Null {
+		return []byte("null"), nil
+	}
+	return json.Marshal(n.Value)
+}
+
+// UnmarshalJSON is only called when the field is present in the payload
+func (n *Nullable[T]) UnmarshalJSON(data []byte) error {
+	n.Set = true
+	if bytes.Equal(bytes.TrimSpace(data), []byte("null")) {
+		n.Null = true
+		var zero T
+		n.Value = zero
+		return nil
+	}
+	n.Null = false
+	return json.Unmarshal(data, &n.Value)
+}
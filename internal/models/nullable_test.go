@@ -0,0 +1,49 @@
+package models
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestNullable_UnmarshalDistinguishesAbsentAndNull(t *testing.T) {
+	cases := map[string]Nullable[string]{
+		`{}`:              {},
+		`{"bio":null}`:    {Set: true, Null: true},
+		`{"bio":"hello"}`: {Set: true, Value: "hello"},
+	}
+	for payload, want := range cases {
+		var req struct {
+			Bio Nullable[string] `json:"bio"`
+		}
+		if err := json.Unmarshal([]byte(payload), &req); err != nil {
+			t.Fatalf("%s: %v", payload, err)
+		}
+		if req.Bio != want {
+			t.Fatalf("%s: got %+v, want %+v", payload, req.Bio, want)
+		}
+	}
+}
+
+func TestNullable_MarshalOmitZero(t *testing.T) {
+	type body struct {
+		Bio Nullable[string] `json:"bio,omitzero"`
+	}
+	cases := map[string]body{
+		`{}`:           {},
+		`{"bio":null}`: {Bio: Null[string]()},
+		`{"bio":"hi"}`: {Bio: NewNullable("hi")},
+	}
+	for want, in := range cases {
+		got, err := json.Marshal(in)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(got) != want {
+			t.Fatalf("got %s, want %s", got, want)
+		}
+	}
+
+	if Null[string]().Ptr() != nil || (Nullable[string]{}).Ptr() != nil || *NewNullable("x").Ptr() != "x" {
+		t.Fatal("unexpected Ptr result")
+	}
+}
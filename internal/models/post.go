@@ -0,0 +1,92 @@
+package models
+
+// Post represents a post written by a user
+type Post struct {
+	ID        int       `json:"id" db:"id"`
+	UserID    int       `json:"user_id" db:"user_id"`
+	Title     string    `json:"title" db:"title"`
+	Body      string    `json:"body" db:"body"`
+	CreatedBy string    `json:"created_by" db:"created_by"`
+	UpdatedBy string    `json:"updated_by" db:"updated_by"`
+	CreatedAt Timestamp `json:"created_at" db:"created_at"`
+	UpdatedAt Timestamp `json:"updated_at" db:"updated_at"`
+}
+
+const (
+	// MaxPostTitleLength matches the posts.title column size
+	MaxPostTitleLength = 200
+	// MaxPostBodyLength is the maximum number of characters in a post body
+	MaxPostBodyLength = 10000
+)
+
+// CreatePostRequest represents the request payload for creating a post
+type CreatePostRequest struct {
+	UserID int    `json:"user_id" binding:"required,gt=0"`
+	Title  string `json:"title" binding:"required,min=1,max=200"`
+	Body   string `json:"body" binding:"required,max=10000"`
+}
+
+// Normalize applies Unicode NFC normalization and trims surrounding whitespace
+func (r *CreatePostRequest) Normalize() {
+	r.Title = normalizeText(r.Title)
+	r.Body = normalizeText(r.Body)
+}
+
+// UpdatePostRequest represents the request payload for updating a post
+type UpdatePostRequest struct {
+	Title *string `json:"title,omitempty" binding:"omitempty,min=1,max=200"`
+	Body  *string `json:"body,omitempty" binding:"omitempty,max=10000"`
+}
+
+// Normalize applies Unicode NFC normalization and trims surrounding whitespace
+func (r *UpdatePostRequest) Normalize() {
+	if r.Title != nil {
+		title := normalizeText(*r.Title)
+		r.Title = &title
+	}
+	if r.Body != nil {
+		body := normalizeText(*r.Body)
+		r.Body = &body
+	}
+}
+
+// PostResponse represents the response format for post data
+type PostResponse struct {
+	ID        int           `json:"id"`
+	UserID    int           `json:"user_id"`
+	Title     string        `json:"title"`
+	Body      string        `json:"body"`
+	Author    *UserResponse `json:"author,omitempty"`
+	CreatedAt Timestamp     `json:"created_at"`
+	UpdatedAt Timestamp     `json:"updated_at"`
+	*AuditInfo
+}
+
+// ToResponse converts a Post model to PostResponse
+func (p *Post) ToResponse() PostResponse {
+	return PostResponse{
+		ID:        p.ID,
+		UserID:    p.UserID,
+		Title:     p.Title,
+		Body:      p.Body,
+		CreatedAt: p.CreatedAt,
+		UpdatedAt: p.UpdatedAt,
+	}
+}
+
+// ToAdminResponse converts a Post model to PostResponse including audit fields
+func (p *Post) ToAdminResponse() PostResponse {
+	response := p.ToResponse()
+	response.AuditInfo = &AuditInfo{CreatedBy: p.CreatedBy, UpdatedBy: p.UpdatedBy}
+	return response
+}
+
+// ToResponseWithAuthor converts a Post model to PostResponse embedding its author
+func (p *Post) ToResponseWithAuthor(author *User) PostResponse {
+	response := p.ToResponse()
+	if author != nil {
+		authorResponse := author.ToResponse()
+		response.Author = &authorResponse
+	}
+	return response
+}
@@ -0,0 +1,36 @@
+package models
+
+import (
+	"testing"
+	"time"
+)
+
+func TestPostToResponse(t *testing.T) {
+	now := NewTimestamp(time.Now())
+	p := &Post{ID: 3, UserID: 7, Title: "T", Body: "B", CreatedAt: now, UpdatedAt: now}
+
+	r := p.ToResponse()
+	if r.ID != 3 || r.UserID != 7 || r.Title != "T" || r.Body != "B" || r.Author != nil {
+		t.Fatalf("unexpected response: %+v", r)
+	}
+
+	r = p.ToResponseWithAuthor(&User{ID: 7, Name: "N"})
+	if r.Author == nil || r.Author.ID != 7 || r.Author.Name != "N" {
+		t.Fatalf("unexpected author: %+v", r.Author)
+	}
+}
+
+func TestPostRequestNormalize(t *testing.T) {
+	create := CreatePostRequest{Title: "  Café ", Body: " body "}
+	create.Normalize()
+	if create.Title != "Café" || create.Body != "body" {
+		t.Fatalf("unexpected create request: %+v", create)
+	}
+
+	title := " Title "
+	update := UpdatePostRequest{Title: &title}
+	update.Normalize()
+	if *update.Title != "Title" || update.Body != nil {
+		t.Fatalf("unexpected update request: %+v", update)
+	}
+}
@@ -10,22 +10,95 @@ type APIResponse struct {
 
 // ErrorResponse represents an error response
 type ErrorResponse struct {
-	Success bool   `json:"success"`
-	Error   string `json:"error"`
+	Success          bool         `json:"success"`
+	Error            string       `json:"error"`
+	Code             string       `json:"code,omitempty"`
+	Details          []FieldError `json:"details,omitempty"`
+	TraceID          string       `json:"trace_id,omitempty"`
+	RequestID        string       `json:"request_id,omitempty"`
+	DocumentationURL string       `json:"documentation_url,omitempty"`
+}
+
+// Machine-readable error codes returned in ErrorResponse.Code
+const (
+	ErrCodeInvalidRequest       = "INVALID_REQUEST"
+	ErrCodeValidationFailed     = "VALIDATION_FAILED"
+	ErrCodeNotFound             = "NOT_FOUND"
+	ErrCodeConflict             = "CONFLICT"
+	ErrCodePreconditionFailed   = "PRECONDITION_FAILED"
+	ErrCodePreconditionRequired = "PRECONDITION_REQUIRED"
+	ErrCodeUnauthorized         = "UNAUTHORIZED"
+	ErrCodeForbidden            = "FORBIDDEN"
+	ErrCodePayloadTooLarge      = "PAYLOAD_TOO_LARGE"
+	ErrCodeIdempotencyKeyReused = "IDEMPOTENCY_KEY_REUSED"
+	ErrCodeRateLimited          = "RATE_LIMITED"
+	ErrCodeQuotaExceeded        = "QUOTA_EXCEEDED"
+	ErrCodeServiceUnavailable   = "SERVICE_UNAVAILABLE"
+	ErrCodeRequestTimeout       = "REQUEST_TIMEOUT"
+	ErrCodeInternal             = "INTERNAL_ERROR"
+)
+
+// FieldError describes a single field that failed validation
+type FieldError struct {
+	Field   string `json:"field"`
+	Rule    string `json:"rule"`
+	Message string `json:"message"`
+}
+
+// AuditInfo carries who created and last updated a resource; it is embedded
+// in responses only for admin consumers
+type AuditInfo struct {
+	CreatedBy string `json:"created_by"`
+	UpdatedBy string `json:"updated_by"`
 }
 
 // SuccessResponse represents a success response
 type SuccessResponse struct {
-	Success bool        `json:"success"`
-	Message string      `json:"message,omitempty"`
-	Data    interface{} `json:"data,omitempty"`
+	Success   bool        `json:"success"`
+	Message   string      `json:"message,omitempty"`
+	Data      interface{} `json:"data,omitempty"`
+	TraceID   string      `json:"trace_id,omitempty"`
+	RequestID string      `json:"request_id,omitempty"`
 }
 
-// PaginatedResponse represents a paginated response
-type PaginatedResponse struct {
-	Success    bool        `json:"success"`
-	Data       interface{} `json:"data"`
-	Pagination Pagination  `json:"pagination"`
+// BulkItemResult is the outcome of one item of a bulk request: its
+// resource, or the error that kept it from being processed
+type BulkItemResult struct {
+	Index   int          `json:"index"`
+	Status  int          `json:"status"`
+	Data    interface{}  `json:"data,omitempty"`
+	Error   string       `json:"error,omitempty"`
+	Code    string       `json:"code,omitempty"`
+	Details []FieldError `json:"details,omitempty"`
+}
+
+// BulkResult reports every item of a bulk request, in request order
+type BulkResult struct {
+	Succeeded int              `json:"succeeded"`
+	Failed    int              `json:"failed"`
+	Results   []BulkItemResult `json:"results"`
+}
+
+// PaginatedResponse represents a paginated response of T items
+type PaginatedResponse[T any] struct {
+	Success    bool       `json:"success"`
+	Data       []T        `json:"data"`
+	Pagination Pagination `json:"pagination"`
+	TraceID    string     `json:"trace_id,omitempty"`
+	RequestID  string     `json:"request_id,omitempty"`
+}
+
+// NewPaginatedResponse builds a successful paginated response; a nil slice is
+// rendered as an empty array
+func NewPaginatedResponse[T any](data []T, page, limit, total int) PaginatedResponse[T] {
+	if data == nil {
+		data = []T{}
+	}
+	return PaginatedResponse[T]{
+		Success:    true,
+		Data:       data,
+		Pagination: NewPagination(page, limit, total),
+	}
 }
 
 // Pagination represents pagination metadata
@@ -35,3 +108,17 @@ type Pagination struct {
 	Total      int `json:"total"`
 	TotalPages int `json:"total_pages"`
 }
+
+// NewPagination computes pagination metadata, including the total page count
+func NewPagination(page, limit, total int) Pagination {
+	totalPages := 0
+	if limit > 0 {
+		totalPages = (total + limit - 1) / limit
+	}
+	return Pagination{
+		Page:       page,
+		Limit:      limit,
+		Total:      total,
+		TotalPages: totalPages,
+	}
+}
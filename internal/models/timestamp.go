@@ -0,0 +1,101 @@
+package models
+
+import (
+	"database/sql/driver"
+	"fmt"
+	"time"
+)
+
+const (
+	// TimestampLayout is RFC3339 with millisecond precision; UTC values render with a Z suffix
+	TimestampLayout = "2006-01-02T15:04:05.000Z07:00"
+
+	// dbTimestampLayout is the wall-clock format sent to MySQL
+	dbTimestampLayout = "2006-01-02 15:04:05.000"
+)
+
+// Timestamp is a time.Time that is always stored and serialized in UTC with
+// millisecond precision, independent of the loc parameter in the DSN
+type Timestamp struct {
+	time.Time
+}
+
+// NewTimestamp converts t to UTC and truncates it to milliseconds
+func NewTimestamp(t time.Time) Timestamp {
+	return Timestamp{Time: t.UTC().Truncate(time.Millisecond)}
+}
+
+// Now returns the current time as a Timestamp
+func Now() Timestamp {
+	return NewTimestamp(time.Now())
+}
+
+// String formats the timestamp using TimestampLayout
+func (t Timestamp) String() string {
+	return t.UTC().Format(TimestampLayout)
+}
+
+// MarshalJSON encodes the timestamp as a UTC RFC3339 string with milliseconds
+func (t Timestamp) MarshalJSON() ([]byte, error) {
+	return []byte(`"` + t.String() + `"`), nil
+}
+
+// UnmarshalJSON accepts any RFC3339 timestamp and normalizes it to UTC
+func (t *Timestamp) UnmarshalJSON(data []byte) error {
+	if string(data) == "null" {
+		*t = Timestamp{}
+		return nil
+	}
+
+	if len(data) < 2 || data[0] != '"' || data[len(data)-1] != '"' {
+		return fmt.Errorf("invalid timestamp %s: expected RFC3339 string", data)
+	}
+
+	parsed, err := time.Parse(time.RFC3339Nano, string(data[1:len(data)-1]))
+	if err != nil {
+		return fmt.Errorf("invalid timestamp: %w", err)
+	}
+
+	*t = NewTimestamp(parsed)
+	return nil
+}
+
+// Scan implements sql.Scanner. The connection runs with a UTC session time
+// zone, so the wall clock returned by the driver is read as UTC whatever
+// location the driver attached to it.
+func (t *Timestamp) Scan(src interface{}) error {
+	switch v := src.(type) {
+	case nil:
+		*t = Timestamp{}
+		return nil
+	case time.Time:
+		*t = NewTimestamp(time.Date(v.Year(), v.Month(), v.Day(),
+			v.Hour(), v.Minute(), v.Second(), v.Nanosecond(), time.UTC))
+		return nil
+	case []byte:
+		return t.scanString(string(v))
+	case string:
+		return t.scanString(v)
+	default:
+		return fmt.Errorf("cannot scan %T into Timestamp", src)
+	}
+}
+
+func (t *Timestamp) scanString(value string) error {
+	for _, layout := range []string{"2006-01-02 15:04:05.999999", time.RFC3339Nano} {
+		if parsed, err := time.ParseInLocation(layout, value, time.UTC); err == nil {
+			*t = NewTimestamp(parsed)
+			return nil
+		}
+	}
+	return fmt.Errorf("cannot parse %q as Timestamp", value)
+}
+
+// Value implements driver.Valuer, sending the UTC wall clock as a string so
+// the driver does not shift it into its configured location
+func (t Timestamp) Value() (driver.Value, error) {
+	if t.IsZero() {
+		return nil, nil
+	}
+	return t.UTC().Format(dbTimestampLayout), nil
+}
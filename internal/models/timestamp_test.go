@@ -0,0 +1,69 @@
+package models
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTimestamp_MarshalJSON(t *testing.T) {
+	sp := time.FixedZone("BRT", -3*60*60)
+	ts := NewTimestamp(time.Date(2024, 5, 6, 7, 8, 9, 123456789, sp))
+
+	b, err := json.Marshal(ts)
+	require.NoError(t, err)
+	assert.Equal(t, `"2024-05-06T10:08:09.123Z"`, string(b))
+}
+
+func TestTimestamp_JSONRoundTrip(t *testing.T) {
+	var ts Timestamp
+	require.NoError(t, json.Unmarshal([]byte(`"2024-05-06T07:08:09.987654+02:00"`), &ts))
+	assert.Equal(t, time.UTC, ts.Location())
+	assert.Equal(t, "2024-05-06T05:08:09.987Z", ts.String())
+
+	b, err := json.Marshal(ts)
+	require.NoError(t, err)
+	var again Timestamp
+	require.NoError(t, json.Unmarshal(b, &again))
+	assert.True(t, ts.Equal(again.Time))
+
+	require.NoError(t, json.Unmarshal([]byte(`null`), &ts))
+	assert.True(t, ts.IsZero())
+	assert.Error(t, json.Unmarshal([]byte(`"yesterday"`), &ts))
+	assert.Error(t, json.Unmarshal([]byte(`12345`), &ts))
+}
+
+func TestTimestamp_ScanIgnoresDriverLocation(t *testing.T) {
+	// The driver attaches loc=Local to the UTC wall clock read from MySQL
+	local := time.FixedZone("Local", 5*60*60)
+	var ts Timestamp
+	require.NoError(t, ts.Scan(time.Date(2024, 1, 2, 3, 4, 5, 6_000_000, local)))
+	assert.Equal(t, "2024-01-02T03:04:05.006Z", ts.String())
+}
+
+func TestTimestamp_DatabaseRoundTrip(t *testing.T) {
+	original := NewTimestamp(time.Date(2024, 1, 2, 3, 4, 5, 678_000_000, time.FixedZone("X", 3600)))
+
+	v, err := original.Value()
+	require.NoError(t, err)
+	assert.Equal(t, "2024-01-02 02:04:05.678", v)
+
+	var scanned Timestamp
+	require.NoError(t, scanned.Scan([]byte(v.(string))))
+	assert.True(t, original.Equal(scanned.Time))
+
+	require.NoError(t, scanned.Scan("2024-01-02T02:04:05Z"))
+	assert.Equal(t, "2024-01-02T02:04:05.000Z", scanned.String())
+
+	require.NoError(t, scanned.Scan(nil))
+	assert.True(t, scanned.IsZero())
+	assert.Error(t, scanned.Scan(42))
+	assert.Error(t, scanned.Scan("not a time"))
+
+	zero, err := Timestamp{}.Value()
+	require.NoError(t, err)
+	assert.Nil(t, zero)
+}
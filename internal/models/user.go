@@ -1,7 +1,10 @@
 package models
 
 import (
+	"strings"
 	"time"
+
+	"golang.org/x/text/unicode/norm"
 )
 
 // User represents a user in the system
@@ -9,23 +12,99 @@ type User struct {
 	ID        int       `json:"id" db:"id"`
 	Name      string    `json:"name" db:"name" binding:"required"`
 	Email     string    `json:"email" db:"email" binding:"required,email"`
-	Bio       string    `json:"bio" db:"bio"`
-	CreatedAt time.Time `json:"created_at" db:"created_at"`
-	UpdatedAt time.Time `json:"updated_at" db:"updated_at"`
+	Bio       *string   `json:"bio,omitempty" db:"bio"`
+	CreatedBy string    `json:"created_by" db:"created_by"`
+	UpdatedBy string    `json:"updated_by" db:"updated_by"`
+	CreatedAt Timestamp `json:"created_at" db:"created_at"`
+	UpdatedAt Timestamp `json:"updated_at" db:"updated_at"`
+	// Version starts at 1 and is incremented by every update
+	Version int `json:"version" db:"version"`
 }
 
 // CreateUserRequest represents the request payload for creating a user
 type CreateUserRequest struct {
-	Name  string `json:"name" binding:"required"`
-	Email string `json:"email" binding:"required,email"`
-	Bio   string `json:"bio"`
+	Name  string  `json:"name" binding:"required,personname"`
+	Email string  `json:"email" binding:"required,email,allowed_email_domain"`
+	Bio   *string `json:"bio,omitempty" binding:"omitempty,biotext"`
+}
+
+// Normalize applies Unicode NFC normalization and trims surrounding whitespace
+func (r *CreateUserRequest) Normalize() {
+	r.Name = normalizeText(r.Name)
+	r.Email = normalizeEmail(r.Email)
+	r.Bio = normalizeOptionalText(r.Bio)
 }
 
-// UpdateUserRequest represents the request payload for updating a user
+// UpdateUserRequest represents the request payload for updating a user.
+// Absent fields are left unchanged; "bio": null (or "") clears the bio.
+// Version is the version of the user the change is based on; the update is
+// rejected when the user has changed since
 type UpdateUserRequest struct {
-	Name  *string `json:"name,omitempty"`
-	Email *string `json:"email,omitempty" binding:"omitempty,email"`
-	Bio   *string `json:"bio,omitempty"`
+	Name    *string          `json:"name,omitempty" binding:"omitempty,personname"`
+	Email   *string          `json:"email,omitempty" binding:"omitempty,email,allowed_email_domain"`
+	Bio     Nullable[string] `json:"bio,omitzero" binding:"omitempty,biotext"`
+	Version *int             `json:"version,omitempty" binding:"omitempty,min=1"`
+}
+
+// Normalize applies Unicode NFC normalization and trims surrounding whitespace
+func (r *UpdateUserRequest) Normalize() {
+	if r.Name != nil {
+		name := normalizeText(*r.Name)
+		r.Name = &name
+	}
+	if r.Email != nil {
+		email := normalizeEmail(*r.Email)
+		r.Email = &email
+	}
+	if r.Bio.Set {
+		if bio := normalizeOptionalText(r.Bio.Ptr()); bio != nil {
+			r.Bio = NewNullable(*bio)
+		} else {
+			r.Bio = Null[string]()
+		}
+	}
+}
+
+// UserFilter narrows a users list. Zero fields match every user
+type UserFilter struct {
+	// Query matches a substring of the name or the email
+	Query string
+	// Email matches the whole address, ignoring case
+	Email string
+	// CreatedAfter and CreatedBefore bound created_at, exclusive
+	CreatedAfter  time.Time
+	CreatedBefore time.Time
+}
+
+// IsZero reports whether the filter matches every user
+func (f UserFilter) IsZero() bool {
+	return f == UserFilter{}
+}
+
+// Normalize trims the query and normalizes the email like request payloads
+func (f *UserFilter) Normalize() {
+	f.Query = normalizeText(f.Query)
+	f.Email = normalizeEmail(f.Email)
+}
+
+func normalizeText(value string) string {
+	return strings.TrimSpace(norm.NFC.String(value))
+}
+
+// normalizeOptionalText normalizes value and maps blank text to nil
+func normalizeOptionalText(value *string) *string {
+	if value == nil {
+		return nil
+	}
+	normalized := normalizeText(*value)
+	if normalized == "" {
+		return nil
+	}
+	return &normalized
+}
+
+func normalizeEmail(value string) string {
+	return strings.ToLower(normalizeText(value))
 }
 
 // UserResponse represents the response format for user data
@@ -33,9 +112,11 @@ type UserResponse struct {
 	ID        int       `json:"id"`
 	Name      string    `json:"name"`
 	Email     string    `json:"email"`
-	Bio       string    `json:"bio"`
-	CreatedAt time.Time `json:"created_at"`
-	UpdatedAt time.Time `json:"updated_at"`
+	Bio       *string   `json:"bio,omitempty"`
+	CreatedAt Timestamp `json:"created_at"`
+	UpdatedAt Timestamp `json:"updated_at"`
+	Version   int       `json:"version"`
+	*AuditInfo
 }
 
 // ToResponse converts a User model to UserResponse
@@ -47,5 +128,13 @@ func (u *User) ToResponse() UserResponse {
 		Bio:       u.Bio,
 		CreatedAt: u.CreatedAt,
 		UpdatedAt: u.UpdatedAt,
+		Version:   u.Version,
 	}
 }
+
+// ToAdminResponse converts a User model to UserResponse including audit fields
+func (u *User) ToAdminResponse() UserResponse {
+	response := u.ToResponse()
+	response.AuditInfo = &AuditInfo{CreatedBy: u.CreatedBy, UpdatedBy: u.UpdatedBy}
+	return response
+}
@@ -1,15 +1,59 @@
 package models
 
 import (
+	"encoding/json"
+	"strings"
 	"testing"
 	"time"
 )
 
 func TestToResponse(t *testing.T) {
-	now := time.Now()
-	u := &User{ID: 7, Name: "N", Email: "e@x", Bio: "b", CreatedAt: now, UpdatedAt: now}
+	now := NewTimestamp(time.Now())
+	bio := "b"
+	u := &User{ID: 7, Name: "N", Email: "e@x", Bio: &bio, CreatedAt: now, UpdatedAt: now}
 	r := u.ToResponse()
-	if r.ID != 7 || r.Name != "N" || r.Email != "e@x" || r.Bio != "b" {
+	if r.ID != 7 || r.Name != "N" || r.Email != "e@x" || r.Bio == nil || *r.Bio != "b" {
 		t.Fatalf("unexpected response: %+v", r)
 	}
 }
+
+func TestToAdminResponse(t *testing.T) {
+	u := &User{ID: 7, Name: "N", CreatedBy: "alice", UpdatedBy: "bob"}
+
+	public, err := json.Marshal(u.ToResponse())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(string(public), "created_by") {
+		t.Fatalf("public response must not include audit fields: %s", public)
+	}
+
+	admin, err := json.Marshal(u.ToAdminResponse())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(admin), `"created_by":"alice"`) || !strings.Contains(string(admin), `"updated_by":"bob"`) {
+		t.Fatalf("admin response must include audit fields: %s", admin)
+	}
+}
+
+func TestUserRequestNormalizeBio(t *testing.T) {
+	blank := "   "
+	create := CreateUserRequest{Bio: &blank}
+	create.Normalize()
+	if create.Bio != nil {
+		t.Fatalf("blank bio should be dropped, got %q", *create.Bio)
+	}
+
+	update := UpdateUserRequest{Bio: NewNullable(" ")}
+	update.Normalize()
+	if !update.Bio.Set || !update.Bio.Null {
+		t.Fatalf("blank bio should clear the field, got %+v", update.Bio)
+	}
+
+	update = UpdateUserRequest{}
+	update.Normalize()
+	if update.Bio.Set {
+		t.Fatalf("absent bio should stay absent, got %+v", update.Bio)
+	}
+}
@@ -0,0 +1,142 @@
+// Package notifications renders and sends user notifications. Rendering and
+// delivery are traced and measured separately so slow templates and slow
+// relays can be told apart.
+package notifications
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"text/template"
+	"time"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/metric"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const instrumentationName = "arquivolivre.com.br/otel/internal/notifications"
+
+// Template names
+const (
+	TemplateWelcome = "welcome"
+)
+
+var templates = template.Must(template.New(TemplateWelcome).Parse(`Hi {{.Name}},
+
+Welcome to the OpenTelemetry example API. Your account has been created with {{.Email}}.
+`))
+
+var subjects = map[string]string{
+	TemplateWelcome: "Welcome!",
+}
+
+// Service renders templates and sends them with a Sender
+type Service struct {
+	sender         Sender
+	tracer         trace.Tracer
+	renderDuration metric.Float64Histogram
+	sendDuration   metric.Float64Histogram
+	deliveries     metric.Int64Counter
+}
+
+// NewService creates a notification service delivering through sender
+func NewService(sender Sender) (*Service, error) {
+	meter := otel.Meter(instrumentationName)
+
+	renderDuration, err := meter.Float64Histogram(
+		"notifications_template_render_duration_seconds",
+		metric.WithDescription("Notification template rendering duration in seconds"),
+		metric.WithUnit("s"),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create render duration metric: %w", err)
+	}
+
+	sendDuration, err := meter.Float64Histogram(
+		"notifications_send_duration_seconds",
+		metric.WithDescription("Notification delivery duration in seconds"),
+		metric.WithUnit("s"),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create send duration metric: %w", err)
+	}
+
+	deliveries, err := meter.Int64Counter(
+		"notifications_deliveries_total",
+		metric.WithDescription("Total number of notification deliveries by status"),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create deliveries metric: %w", err)
+	}
+
+	return &Service{
+		sender:         sender,
+		tracer:         otel.Tracer(instrumentationName),
+		renderDuration: renderDuration,
+		sendDuration:   sendDuration,
+		deliveries:     deliveries,
+	}, nil
+}
+
+// SendWelcome sends the welcome email to a newly created user
+func (s *Service) SendWelcome(ctx context.Context, name, email string) error {
+	return s.send(ctx, TemplateWelcome, email, map[string]string{"Name": name, "Email": email})
+}
+
+func (s *Service) send(ctx context.Context, templateName, to string, data any) error {
+	attrs := []attribute.KeyValue{
+		attribute.String("notification.template", templateName),
+		attribute.String("notification.sender", s.sender.Name()),
+	}
+	ctx, span := s.tracer.Start(ctx, "notifications.send "+templateName, trace.WithAttributes(attrs...))
+	defer span.End()
+
+	body, err := s.render(ctx, templateName, data)
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, "failed to render template")
+		s.deliveries.Add(ctx, 1, metric.WithAttributes(append(attrs, attribute.String("status", "render_error"))...))
+		return err
+	}
+
+	_, sendSpan := s.tracer.Start(ctx, s.sender.Name()+" send",
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(attrs...),
+	)
+	start := time.Now()
+	err = s.sender.Send(ctx, Message{To: to, Subject: subjects[templateName], Body: body})
+	s.sendDuration.Record(ctx, time.Since(start).Seconds(), metric.WithAttributes(attrs...))
+	status := "delivered"
+	if err != nil {
+		status = "failed"
+		sendSpan.RecordError(err)
+		sendSpan.SetStatus(codes.Error, err.Error())
+		span.SetStatus(codes.Error, "failed to deliver notification")
+	}
+	sendSpan.End()
+
+	s.deliveries.Add(ctx, 1, metric.WithAttributes(append(attrs, attribute.String("status", status))...))
+	if err != nil {
+		return fmt.Errorf("failed to send %s notification: %w", templateName, err)
+	}
+	return nil
+}
+
+func (s *Service) render(ctx context.Context, templateName string, data any) (string, error) {
+	start := time.Now()
+	var body strings.Builder
+	err := templates.ExecuteTemplate(&body, templateName, data)
+	duration := time.Since(start)
+
+	s.renderDuration.Record(ctx, duration.Seconds(), metric.WithAttributes(attribute.String("notification.template", templateName)))
+	trace.SpanFromContext(ctx).AddEvent("template.rendered", trace.WithAttributes(
+		attribute.Float64("duration_ms", float64(duration.Microseconds())/1000),
+	))
+	if err != nil {
+		return "", fmt.Errorf("failed to render %s template: %w", templateName, err)
+	}
+	return body.String(), nil
+}
@@ -0,0 +1,64 @@
+package notifications
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+type fakeSender struct {
+	sent []Message
+	err  error
+}
+
+func (s *fakeSender) Name() string { return "fake" }
+
+func (s *fakeSender) Send(_ context.Context, msg Message) error {
+	if s.err != nil {
+		return s.err
+	}
+	s.sent = append(s.sent, msg)
+	return nil
+}
+
+func TestSendWelcome(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	tp := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+	prev := otel.GetTracerProvider()
+	otel.SetTracerProvider(tp)
+	defer otel.SetTracerProvider(prev)
+
+	sender := &fakeSender{}
+	svc, err := NewService(sender)
+	require.NoError(t, err)
+
+	require.NoError(t, svc.SendWelcome(context.Background(), "Ann", "ann@example.com"))
+	require.Len(t, sender.sent, 1)
+	assert.Equal(t, "ann@example.com", sender.sent[0].To)
+	assert.Equal(t, "Welcome!", sender.sent[0].Subject)
+	assert.Contains(t, sender.sent[0].Body, "Hi Ann,")
+
+	spans := recorder.Ended()
+	require.Len(t, spans, 2)
+	assert.Equal(t, "fake send", spans[0].Name())
+	assert.Equal(t, "notifications.send welcome", spans[1].Name())
+	assert.Equal(t, spans[1].SpanContext().SpanID(), spans[0].Parent().SpanID())
+	require.Len(t, spans[1].Events(), 1)
+	assert.Equal(t, "template.rendered", spans[1].Events()[0].Name)
+
+	sender.err = errors.New("relay refused")
+	err = svc.SendWelcome(context.Background(), "Bea", "bea@example.com")
+	assert.ErrorContains(t, err, "relay refused")
+	assert.Equal(t, "relay refused", recorder.Ended()[2].Status().Description)
+}
+
+func TestLogSender(t *testing.T) {
+	assert.NoError(t, LogSender{}.Send(context.Background(), Message{To: "a@example.com"}))
+	assert.Equal(t, "smtp", NewSMTPSender("localhost:25", "noreply@example.com", "u", "p").Name())
+}
@@ -0,0 +1,63 @@
+package notifications
+
+import (
+	"context"
+	"fmt"
+	"net/smtp"
+	"strings"
+
+	"arquivolivre.com.br/otel/internal/logging"
+)
+
+// Message is a rendered email
+type Message struct {
+	To      string
+	Subject string
+	Body    string
+}
+
+// Sender delivers rendered messages
+type Sender interface {
+	Name() string
+	Send(ctx context.Context, msg Message) error
+}
+
+// SMTPSender delivers messages through an SMTP relay
+type SMTPSender struct {
+	addr string
+	from string
+	auth smtp.Auth
+}
+
+// NewSMTPSender creates a sender for the relay at addr (host:port). PLAIN
+// auth is used when username is set.
+func NewSMTPSender(addr, from, username, password string) *SMTPSender {
+	var auth smtp.Auth
+	if username != "" {
+		host, _, _ := strings.Cut(addr, ":")
+		auth = smtp.PlainAuth("", username, password, host)
+	}
+	return &SMTPSender{addr: addr, from: from, auth: auth}
+}
+
+func (s *SMTPSender) Name() string { return "smtp" }
+
+func (s *SMTPSender) Send(_ context.Context, msg Message) error {
+	body := fmt.Sprintf("From: %s\r\nTo: %s\r\nSubject: %s\r\nContent-Type: text/plain; charset=UTF-8\r\n\r\n%s",
+		s.from, msg.To, msg.Subject, msg.Body)
+	return smtp.SendMail(s.addr, s.auth, s.from, []string{msg.To}, []byte(body))
+}
+
+// LogSender logs messages instead of delivering them; it is used when no
+// SMTP relay is configured
+type LogSender struct{}
+
+func (LogSender) Name() string { return "log" }
+
+func (LogSender) Send(ctx context.Context, msg Message) error {
+	logging.LogInfo(ctx, "Email notification (not delivered, no SMTP relay configured)", map[string]interface{}{
+		"to":      msg.To,
+		"subject": msg.Subject,
+	})
+	return nil
+}
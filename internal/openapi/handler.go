@@ -0,0 +1,42 @@
+package openapi
+
+import (
+	"net/http"
+	"sync"
+
+	"github.com/gin-gonic/gin"
+)
+
+// swaggerUIVersion pins the Swagger UI assets loaded by the docs page
+const swaggerUIVersion = "5.17.14"
+
+var document = sync.OnceValue(Build)
+
+// Spec serves the OpenAPI document as JSON
+func Spec(c *gin.Context) {
+	c.JSON(http.StatusOK, document())
+}
+
+// Docs serves Swagger UI for the document at /openapi.json. The UI assets
+// are loaded from a CDN, so the page needs internet access
+func Docs(c *gin.Context) {
+	c.Header("Content-Type", "text/html; charset=utf-8")
+	c.String(http.StatusOK, docsPage)
+}
+
+const docsPage = `<!DOCTYPE html>
+<html lang="en">
+<head>
+  <meta charset="utf-8">
+  <title>OpenTelemetry Example API</title>
+  <link rel="stylesheet" href="https://unpkg.com/swagger-ui-dist@` + swaggerUIVersion + `/swagger-ui.css">
+</head>
+<body>
+  <div id="swagger-ui"></div>
+  <script src="https://unpkg.com/swagger-ui-dist@` + swaggerUIVersion + `/swagger-ui-bundle.js" crossorigin></script>
+  <script>
+    window.ui = SwaggerUIBundle({ url: "/openapi.json", dom_id: "#swagger-ui" });
+  </script>
+</body>
+</html>
+`
@@ -0,0 +1,127 @@
+package openapi
+
+import (
+	"reflect"
+	"strings"
+	"time"
+
+	"arquivolivre.com.br/otel/internal/models"
+)
+
+// Schema is the subset of the OpenAPI 3.0 schema object the API needs
+type Schema struct {
+	Ref         string             `json:"$ref,omitempty"`
+	Type        string             `json:"type,omitempty"`
+	Format      string             `json:"format,omitempty"`
+	Description string             `json:"description,omitempty"`
+	Nullable    bool               `json:"nullable,omitempty"`
+	Properties  map[string]*Schema `json:"properties,omitempty"`
+	Required    []string           `json:"required,omitempty"`
+	Items       *Schema            `json:"items,omitempty"`
+	Enum        []string           `json:"enum,omitempty"`
+	Default     any                `json:"default,omitempty"`
+	Minimum     *float64           `json:"minimum,omitempty"`
+}
+
+var (
+	timeType      = reflect.TypeOf(time.Time{})
+	timestampType = reflect.TypeOf(models.Timestamp{})
+	modelsPkg     = timestampType.PkgPath()
+)
+
+// registry derives schemas from Go types by their JSON tags. Named structs
+// are added to components and referenced, so each type is described once
+type registry map[string]*Schema
+
+// ref returns a reference to the component schema of t, registering it
+// under name on first use
+func (r registry) ref(name string, t reflect.Type) *Schema {
+	if _, ok := r[name]; !ok {
+		// Reserve the name first so self-referencing types terminate
+		r[name] = &Schema{}
+		*r[name] = *r.object(t)
+	}
+	return &Schema{Ref: "#/components/schemas/" + name}
+}
+
+// schema returns the schema of values of type t
+func (r registry) schema(t reflect.Type) *Schema {
+	switch {
+	case t == timeType || t == timestampType:
+		return &Schema{Type: "string", Format: "date-time"}
+	case t.PkgPath() == modelsPkg && strings.HasPrefix(t.Name(), "Nullable["):
+		// Nullable[T] is written as T or null
+		field, _ := t.FieldByName("Value")
+		s := r.schema(field.Type)
+		s.Nullable = true
+		return s
+	}
+
+	switch t.Kind() {
+	case reflect.Pointer:
+		return r.schema(t.Elem())
+	case reflect.String:
+		return &Schema{Type: "string"}
+	case reflect.Bool:
+		return &Schema{Type: "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return &Schema{Type: "integer"}
+	case reflect.Float32, reflect.Float64:
+		return &Schema{Type: "number"}
+	case reflect.Slice, reflect.Array:
+		return &Schema{Type: "array", Items: r.schema(t.Elem())}
+	case reflect.Map:
+		return &Schema{Type: "object"}
+	case reflect.Struct:
+		if t.Name() == "" || strings.Contains(t.Name(), "[") {
+			return r.object(t)
+		}
+		return r.ref(t.Name(), t)
+	}
+	// interface{} fields accept any value
+	return &Schema{}
+}
+
+// object describes the fields of struct t. Embedded structs without a JSON
+// name are flattened, as encoding/json does; fields of embedded pointers are
+// optional because the pointer may be nil
+func (r registry) object(t reflect.Type) *Schema {
+	s := &Schema{Type: "object", Properties: map[string]*Schema{}}
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		name, opts, _ := strings.Cut(field.Tag.Get("json"), ",")
+		if !field.IsExported() || name == "-" {
+			continue
+		}
+
+		if field.Anonymous && name == "" {
+			embedded := field.Type
+			optional := embedded.Kind() == reflect.Pointer
+			if optional {
+				embedded = embedded.Elem()
+			}
+			inner := r.object(embedded)
+			for key, prop := range inner.Properties {
+				s.Properties[key] = prop
+			}
+			if !optional {
+				s.Required = append(s.Required, inner.Required...)
+			}
+			continue
+		}
+
+		if name == "" {
+			name = field.Name
+		}
+		prop := r.schema(field.Type)
+		if strings.Contains(field.Tag.Get("binding"), "email") {
+			prop.Format = "email"
+		}
+		s.Properties[name] = prop
+		if !strings.Contains(opts, "omitempty") && !strings.Contains(opts, "omitzero") {
+			s.Required = append(s.Required, name)
+		}
+	}
+	return s
+}
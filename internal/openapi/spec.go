@@ -0,0 +1,693 @@
+// Package openapi describes the HTTP API as an OpenAPI 3 document. Schemas
+// are derived from the request, response and DTO types, and operations are
+// listed next to the routes they document
+package openapi
+
+import (
+	"net/http"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"arquivolivre.com.br/otel/internal/audit"
+	"arquivolivre.com.br/otel/internal/avatars"
+	"arquivolivre.com.br/otel/internal/buildinfo"
+	"arquivolivre.com.br/otel/internal/dto"
+	"arquivolivre.com.br/otel/internal/enrichment"
+	"arquivolivre.com.br/otel/internal/health"
+	"arquivolivre.com.br/otel/internal/models"
+	"arquivolivre.com.br/otel/internal/operations"
+	"arquivolivre.com.br/otel/internal/reports"
+	"arquivolivre.com.br/otel/internal/roles"
+	"arquivolivre.com.br/otel/pkg/utils"
+)
+
+// Document is an OpenAPI 3.0 document
+type Document struct {
+	OpenAPI    string                          `json:"openapi"`
+	Info       Info                            `json:"info"`
+	Paths      map[string]map[string]Operation `json:"paths"`
+	Components Components                      `json:"components"`
+}
+
+// Info describes the API
+type Info struct {
+	Title       string `json:"title"`
+	Description string `json:"description,omitempty"`
+	Version     string `json:"version"`
+}
+
+// Components holds the reusable schemas and security schemes
+type Components struct {
+	Schemas         map[string]*Schema        `json:"schemas"`
+	SecuritySchemes map[string]SecurityScheme `json:"securitySchemes,omitempty"`
+}
+
+// SecurityScheme describes how callers authenticate
+type SecurityScheme struct {
+	Type         string `json:"type"`
+	Scheme       string `json:"scheme,omitempty"`
+	BearerFormat string `json:"bearerFormat,omitempty"`
+	Description  string `json:"description,omitempty"`
+}
+
+// Operation is one method on a path
+type Operation struct {
+	OperationID string                `json:"operationId"`
+	Summary     string                `json:"summary"`
+	Tags        []string              `json:"tags,omitempty"`
+	Parameters  []Parameter           `json:"parameters,omitempty"`
+	RequestBody *RequestBody          `json:"requestBody,omitempty"`
+	Responses   map[string]Response   `json:"responses"`
+	Security    []map[string][]string `json:"security,omitempty"`
+	Deprecated  bool                  `json:"deprecated,omitempty"`
+}
+
+// Parameter is a path, query or header parameter
+type Parameter struct {
+	Name        string  `json:"name"`
+	In          string  `json:"in"`
+	Description string  `json:"description,omitempty"`
+	Required    bool    `json:"required,omitempty"`
+	Schema      *Schema `json:"schema"`
+}
+
+// RequestBody is a JSON request body
+type RequestBody struct {
+	Required bool                 `json:"required"`
+	Content  map[string]MediaType `json:"content"`
+}
+
+// Response is the response of one status code
+type Response struct {
+	Description string               `json:"description"`
+	Headers     map[string]Header    `json:"headers,omitempty"`
+	Content     map[string]MediaType `json:"content,omitempty"`
+}
+
+// Header is a response header
+type Header struct {
+	Description string  `json:"description,omitempty"`
+	Schema      *Schema `json:"schema"`
+}
+
+// MediaType holds the schema of a body
+type MediaType struct {
+	Schema *Schema `json:"schema"`
+}
+
+const jsonContentType = "application/json"
+
+// bearerAuth names the JWT security scheme of the user and post write
+// endpoints
+const bearerAuth = "bearerAuth"
+
+// userVersion describes one of the route groups serving the user endpoints;
+// suffix keeps operation IDs unique across the groups
+type userVersion struct {
+	prefix      string
+	tag         string
+	suffix      string
+	user        reflect.Type
+	unversioned bool
+}
+
+// The unversioned routes return the v1 shape unless API-Version asks
+// otherwise, and are deprecated
+var userVersions = []userVersion{
+	{prefix: "/api/users", tag: "users", user: reflect.TypeOf(models.UserResponse{}), unversioned: true},
+	{prefix: "/api/v1/users", tag: "users v1", suffix: "V1", user: reflect.TypeOf(models.UserResponse{})},
+	{prefix: "/api/v2/users", tag: "users v2", suffix: "V2", user: reflect.TypeOf(dto.UserV2{})},
+}
+
+// postVersion describes one of the route groups serving the post endpoints
+type postVersion struct {
+	prefix      string
+	tag         string
+	suffix      string
+	post        reflect.Type
+	unversioned bool
+}
+
+var postVersions = []postVersion{
+	{prefix: "/api", tag: "posts", post: reflect.TypeOf(models.PostResponse{}), unversioned: true},
+	{prefix: "/api/v1", tag: "posts v1", suffix: "V1", post: reflect.TypeOf(models.PostResponse{})},
+	{prefix: "/api/v2", tag: "posts v2", suffix: "V2", post: reflect.TypeOf(dto.PostV2{})},
+}
+
+// Build returns the OpenAPI document of the API
+func Build() *Document {
+	schemas := registry{}
+	errorResponses := func(statuses ...int) map[string]Response {
+		responses := map[string]Response{}
+		for _, status := range statuses {
+			response := Response{
+				Description: http.StatusText(status),
+				Content:     jsonContent(schemas.schema(reflect.TypeOf(models.ErrorResponse{}))),
+			}
+			if status == http.StatusTooManyRequests {
+				response.Headers = map[string]Header{
+					"Retry-After": {Description: "Seconds to wait before retrying", Schema: &Schema{Type: "integer"}},
+				}
+			}
+			responses[strconv.Itoa(status)] = response
+		}
+		return responses
+	}
+
+	doc := &Document{
+		OpenAPI: "3.0.3",
+		Info: Info{
+			Title:       "OpenTelemetry Example API",
+			Description: "User and post management API instrumented with OpenTelemetry. Errors share the ErrorResponse shape; see docs/errors.md for the codes.",
+			Version:     buildinfo.Get().Version,
+		},
+		Paths: map[string]map[string]Operation{},
+		Components: Components{
+			Schemas: schemas,
+			SecuritySchemes: map[string]SecurityScheme{
+				bearerAuth: {
+					Type:         "http",
+					Scheme:       "bearer",
+					BearerFormat: "JWT",
+					Description:  "Required on user and post writes when JWT_SECRET or JWT_JWKS_URL is set",
+				},
+			},
+		},
+	}
+
+	healthReport := schemas.ref("HealthReport", reflect.TypeOf(health.Report{}))
+	doc.add("/health", http.MethodGet, Operation{
+		OperationID: "healthCheck",
+		Summary:     "Report the status and latency of each dependency check",
+		Tags:        []string{"health"},
+		Responses: map[string]Response{
+			"200": {Description: "Up, or degraded by a non-critical check", Content: jsonContent(healthReport)},
+			"503": {Description: "Down: a critical check failed", Content: jsonContent(healthReport)},
+		},
+	})
+	doc.add("/ready", http.MethodGet, Operation{
+		OperationID: "readinessCheck",
+		Summary:     "Report whether the API is ready to receive traffic",
+		Tags:        []string{"health"},
+		Responses: map[string]Response{
+			"200": {Description: "Ready", Content: jsonContent(healthReport)},
+			"503": {Description: "Not ready: a critical check failed", Content: jsonContent(healthReport)},
+		},
+	})
+	buildInfo := jsonContent(success(schemas, schemas.schema(reflect.TypeOf(buildinfo.Info{}))))
+	doc.add("/version", http.MethodGet, Operation{
+		OperationID: "getBuildInfo",
+		Summary:     "Build information of the running binary",
+		Tags:        []string{"health"},
+		Responses: map[string]Response{
+			"200": {Description: "Build information", Content: buildInfo},
+		},
+	})
+	doc.add("/api/", http.MethodGet, Operation{
+		OperationID: "getAPIInfo",
+		Summary:     "Name, version and status of the API",
+		Tags:        []string{"health"},
+		Responses: map[string]Response{
+			"200": {Description: "API information", Content: jsonContent(&Schema{
+				Type: "object",
+				Properties: map[string]*Schema{
+					"message": {Type: "string"},
+					"version": {Type: "string"},
+					"status":  {Type: "string"},
+				},
+			})},
+		},
+	})
+	doc.add("/api/version", http.MethodGet, Operation{
+		OperationID: "getVersion",
+		Summary:     "Build information of the running binary",
+		Tags:        []string{"health"},
+		Responses: map[string]Response{
+			"200": {Description: "Build information", Content: buildInfo},
+		},
+	})
+	doc.add("/api/external", http.MethodGet, Operation{
+		OperationID: "callExternal",
+		Summary:     "Call the downstream service at EXTERNAL_SERVICE_URL",
+		Tags:        []string{"external"},
+		Responses: merge(map[string]Response{
+			"200": {Description: "Status, latency and body of the downstream response", Content: jsonContent(success(schemas, &Schema{
+				Type: "object",
+				Properties: map[string]*Schema{
+					"url":         {Type: "string"},
+					"status":      {Type: "integer"},
+					"duration_ms": {Type: "integer"},
+					"body":        {Description: "The downstream body, as JSON when it is valid JSON and as a string otherwise"},
+				},
+				Required: []string{"url", "status", "duration_ms", "body"},
+			}))},
+		}, errorResponses(http.StatusInternalServerError, http.StatusServiceUnavailable)),
+	})
+
+	idParam := Parameter{Name: "id", In: "path", Required: true, Description: "User ID", Schema: &Schema{Type: "integer"}}
+	ifMatchParam := Parameter{Name: "If-Match", In: "header", Description: `Version the update is based on, such as "3", or * for any version; required unless the body has a version`, Schema: &Schema{Type: "string"}}
+	ifNoneMatchParam := Parameter{Name: "If-None-Match", In: "header", Description: "ETag of a cached copy; answered with 304 while it is current", Schema: &Schema{Type: "string"}}
+	etagHeaders := map[string]Header{"ETag": {Description: "Weak entity tag of the response", Schema: &Schema{Type: "string"}}}
+	notModified := map[string]Response{"304": {Description: "The cached copy named by If-None-Match is current", Headers: etagHeaders}}
+	idempotencyKeyParam := Parameter{Name: "Idempotency-Key", In: "header", Description: "Client-chosen key, at most 255 characters, that makes the request safe to retry: a retry with the same key and body replays the first response with Idempotent-Replayed: true", Schema: &Schema{Type: "string"}}
+	writeSecurity := []map[string][]string{{bearerAuth: {}}, {}}
+	for _, v := range userVersions {
+		user := schemas.schema(v.user)
+		op := func(id string) string { return id + v.suffix }
+		add := doc.adder(v.unversioned)
+
+		add(v.prefix, http.MethodGet, Operation{
+			OperationID: op("listUsers"),
+			Summary:     "List users matching the filters, one page at a time",
+			Tags:        []string{v.tag},
+			Parameters: []Parameter{
+				{Name: "page", In: "query", Description: "Page number, starting at 1", Schema: &Schema{Type: "integer", Default: 1, Minimum: float(1)}},
+				{Name: "limit", In: "query", Description: "Page size; out of range values fall back to PAGINATION_DEFAULT_LIMIT", Schema: &Schema{Type: "integer", Minimum: float(1)}},
+				{Name: "q", In: "query", Description: "Substring of the name or email, at most 100 characters", Schema: &Schema{Type: "string"}},
+				{Name: "email", In: "query", Description: "Exact email address, case insensitive", Schema: &Schema{Type: "string", Format: "email"}},
+				{Name: "created_after", In: "query", Description: "Only users created after this RFC 3339 timestamp or YYYY-MM-DD date", Schema: &Schema{Type: "string", Format: "date-time"}},
+				{Name: "created_before", In: "query", Description: "Only users created before this RFC 3339 timestamp or YYYY-MM-DD date", Schema: &Schema{Type: "string", Format: "date-time"}},
+				ifNoneMatchParam,
+			},
+			Responses: merge(map[string]Response{
+				"200": {Description: "A page of users", Headers: etagHeaders, Content: jsonContent(paginated(schemas, user))},
+			}, notModified, errorResponses(http.StatusBadRequest, http.StatusTooManyRequests, http.StatusInternalServerError)),
+		})
+		add(v.prefix+"/export", http.MethodGet, Operation{
+			OperationID: op("exportUsers"),
+			Summary:     "Stream every user matching the filters as CSV or JSON lines, in ID order",
+			Tags:        []string{v.tag},
+			Parameters: []Parameter{
+				{Name: "format", In: "query", Description: "Export format", Schema: &Schema{Type: "string", Enum: []string{"csv", "ndjson"}, Default: "csv"}},
+				{Name: "q", In: "query", Description: "Substring of the name or email, at most 100 characters", Schema: &Schema{Type: "string"}},
+				{Name: "email", In: "query", Description: "Exact email address, case insensitive", Schema: &Schema{Type: "string", Format: "email"}},
+				{Name: "created_after", In: "query", Description: "Only users created after this RFC 3339 timestamp or YYYY-MM-DD date", Schema: &Schema{Type: "string", Format: "date-time"}},
+				{Name: "created_before", In: "query", Description: "Only users created before this RFC 3339 timestamp or YYYY-MM-DD date", Schema: &Schema{Type: "string", Format: "date-time"}},
+			},
+			Responses: merge(map[string]Response{
+				"200": {Description: "The users, one per line after the CSV header row", Content: map[string]MediaType{
+					"text/csv":             {Schema: &Schema{Type: "string"}},
+					"application/x-ndjson": {Schema: user},
+				}},
+			}, errorResponses(http.StatusBadRequest, http.StatusTooManyRequests, http.StatusInternalServerError)),
+		})
+		add(v.prefix+"/search", http.MethodGet, Operation{
+			OperationID: op("searchUsers"),
+			Summary:     "Search users by the words of their name, email and bio, most relevant first",
+			Tags:        []string{v.tag},
+			Parameters: []Parameter{
+				{Name: "q", In: "query", Required: true, Description: "Words to search for, at most 100 characters; each matches as a prefix unless DB_SEARCH_MODE=like", Schema: &Schema{Type: "string"}},
+				{Name: "limit", In: "query", Description: "Largest number of results; out of range values fall back to PAGINATION_DEFAULT_LIMIT", Schema: &Schema{Type: "integer", Minimum: float(1)}},
+			},
+			Responses: merge(map[string]Response{
+				"200": {Description: "The matching users with their relevance, higher first", Content: jsonContent(success(schemas, &Schema{
+					Type: "array",
+					Items: &Schema{
+						Type: "object",
+						Properties: map[string]*Schema{
+							"user":      user,
+							"relevance": {Type: "number"},
+						},
+						Required: []string{"user", "relevance"},
+					},
+				}))},
+			}, errorResponses(http.StatusBadRequest, http.StatusTooManyRequests, http.StatusInternalServerError)),
+		})
+		add(v.prefix, http.MethodPost, Operation{
+			OperationID: op("createUser"),
+			Summary:     "Create a user",
+			Tags:        []string{v.tag},
+			Parameters:  []Parameter{idempotencyKeyParam},
+			RequestBody: jsonBody(schemas.schema(reflect.TypeOf(models.CreateUserRequest{}))),
+			Responses: merge(map[string]Response{
+				"201": {Description: "The created user", Content: jsonContent(success(schemas, user))},
+			}, errorResponses(http.StatusBadRequest, http.StatusUnauthorized, http.StatusConflict, http.StatusUnprocessableEntity, http.StatusTooManyRequests, http.StatusInternalServerError)),
+			Security: writeSecurity,
+		})
+		bulkItem := schemas.object(reflect.TypeOf(models.BulkItemResult{}))
+		bulkItem.Properties["data"] = user
+		bulkResult := schemas.object(reflect.TypeOf(models.BulkResult{}))
+		bulkResult.Properties["results"] = &Schema{Type: "array", Items: bulkItem}
+		add(v.prefix+"/bulk", http.MethodPost, Operation{
+			OperationID: op("bulkCreateUsers"),
+			Summary:     "Create up to BULK_MAX_USERS users in batches, reporting the outcome of each",
+			Tags:        []string{v.tag},
+			Parameters:  []Parameter{idempotencyKeyParam},
+			RequestBody: jsonBody(&Schema{
+				Type: "object",
+				Properties: map[string]*Schema{
+					"users": {Type: "array", Items: schemas.schema(reflect.TypeOf(models.CreateUserRequest{}))},
+				},
+				Required: []string{"users"},
+			}),
+			Responses: merge(map[string]Response{
+				"201": {Description: "Every user was created", Content: jsonContent(success(schemas, bulkResult))},
+				"207": {Description: "Some users were not created; each result has its own status and error", Content: jsonContent(success(schemas, bulkResult))},
+			}, errorResponses(http.StatusBadRequest, http.StatusUnauthorized, http.StatusConflict, http.StatusUnprocessableEntity, http.StatusTooManyRequests, http.StatusInternalServerError)),
+			Security: writeSecurity,
+		})
+		add(v.prefix+"/{id}", http.MethodGet, Operation{
+			OperationID: op("getUser"),
+			Summary:     "Get a user",
+			Tags:        []string{v.tag},
+			Parameters:  []Parameter{idParam, ifNoneMatchParam},
+			Responses: merge(map[string]Response{
+				"200": {Description: "The user", Headers: etagHeaders, Content: jsonContent(success(schemas, user))},
+			}, notModified, errorResponses(http.StatusBadRequest, http.StatusNotFound, http.StatusTooManyRequests, http.StatusInternalServerError)),
+		})
+		add(v.prefix+"/{id}", http.MethodPut, Operation{
+			OperationID: op("updateUser"),
+			Summary:     "Update a user; absent fields are left unchanged and a null bio clears it",
+			Tags:        []string{v.tag},
+			Parameters:  []Parameter{idParam, ifMatchParam},
+			RequestBody: jsonBody(schemas.schema(reflect.TypeOf(models.UpdateUserRequest{}))),
+			Responses: merge(map[string]Response{
+				"200": {Description: "The updated user", Content: jsonContent(success(schemas, user))},
+			}, errorResponses(http.StatusBadRequest, http.StatusUnauthorized, http.StatusNotFound, http.StatusConflict, http.StatusPreconditionFailed, http.StatusPreconditionRequired, http.StatusTooManyRequests, http.StatusInternalServerError)),
+			Security: writeSecurity,
+		})
+		add(v.prefix+"/{id}", http.MethodPatch, Operation{
+			OperationID: op("patchUser"),
+			Summary:     "Apply a JSON merge patch (RFC 7386) to a user; null removes a member and the result must be a valid user",
+			Tags:        []string{v.tag},
+			Parameters:  []Parameter{idParam, ifMatchParam},
+			RequestBody: mergePatchBody(schemas.schema(reflect.TypeOf(models.UpdateUserRequest{}))),
+			Responses: merge(map[string]Response{
+				"200": {Description: "The patched user", Content: jsonContent(success(schemas, user))},
+			}, errorResponses(http.StatusBadRequest, http.StatusUnauthorized, http.StatusNotFound, http.StatusConflict, http.StatusPreconditionFailed, http.StatusPreconditionRequired, http.StatusUnsupportedMediaType, http.StatusTooManyRequests, http.StatusInternalServerError)),
+			Security: writeSecurity,
+		})
+		add(v.prefix+"/{id}", http.MethodDelete, Operation{
+			OperationID: op("deleteUser"),
+			Summary:     "Delete a user",
+			Tags:        []string{v.tag},
+			Parameters:  []Parameter{idParam},
+			Responses: merge(map[string]Response{
+				"204": {Description: "Deleted"},
+			}, errorResponses(http.StatusBadRequest, http.StatusUnauthorized, http.StatusNotFound, http.StatusTooManyRequests, http.StatusInternalServerError)),
+			Security: writeSecurity,
+		})
+		add(v.prefix+"/{id}/profile", http.MethodGet, Operation{
+			OperationID: op("getUserProfile"),
+			Summary:     "Get a user with the details derived by the enrichment service",
+			Tags:        []string{v.tag},
+			Parameters:  []Parameter{idParam},
+			Responses: merge(map[string]Response{
+				"200": {Description: "The user and its profile", Content: jsonContent(success(schemas, &Schema{
+					Type: "object",
+					Properties: map[string]*Schema{
+						"user":    user,
+						"profile": schemas.schema(reflect.TypeOf(enrichment.Profile{})),
+					},
+					Required: []string{"user", "profile"},
+				}))},
+			}, errorResponses(http.StatusBadRequest, http.StatusNotFound, http.StatusTooManyRequests, http.StatusServiceUnavailable)),
+		})
+	}
+
+	postIDParam := Parameter{Name: "id", In: "path", Required: true, Description: "Post ID", Schema: &Schema{Type: "integer"}}
+	pageParams := []Parameter{
+		{Name: "page", In: "query", Description: "Page number, starting at 1", Schema: &Schema{Type: "integer", Default: 1, Minimum: float(1)}},
+		{Name: "limit", In: "query", Description: "Page size; out of range values fall back to PAGINATION_DEFAULT_LIMIT", Schema: &Schema{Type: "integer", Minimum: float(1)}},
+	}
+	for _, v := range postVersions {
+		post := schemas.schema(v.post)
+		op := func(id string) string { return id + v.suffix }
+		add := doc.adder(v.unversioned)
+
+		add(v.prefix+"/posts", http.MethodGet, Operation{
+			OperationID: op("listPosts"),
+			Summary:     "List posts, newest first, one page at a time",
+			Tags:        []string{v.tag},
+			Parameters:  pageParams,
+			Responses: merge(map[string]Response{
+				"200": {Description: "A page of posts", Content: jsonContent(paginated(schemas, post))},
+			}, errorResponses(http.StatusTooManyRequests, http.StatusInternalServerError)),
+		})
+		add(v.prefix+"/posts", http.MethodPost, Operation{
+			OperationID: op("createPost"),
+			Summary:     "Create a post for an existing user",
+			Tags:        []string{v.tag},
+			RequestBody: jsonBody(schemas.schema(reflect.TypeOf(models.CreatePostRequest{}))),
+			Responses: merge(map[string]Response{
+				"201": {Description: "The created post with its author", Content: jsonContent(success(schemas, post))},
+			}, errorResponses(http.StatusBadRequest, http.StatusUnauthorized, http.StatusNotFound, http.StatusTooManyRequests, http.StatusInternalServerError)),
+			Security: writeSecurity,
+		})
+		add(v.prefix+"/posts/{id}", http.MethodGet, Operation{
+			OperationID: op("getPost"),
+			Summary:     "Get a post with its author",
+			Tags:        []string{v.tag},
+			Parameters:  []Parameter{postIDParam},
+			Responses: merge(map[string]Response{
+				"200": {Description: "The post", Content: jsonContent(success(schemas, post))},
+			}, errorResponses(http.StatusBadRequest, http.StatusNotFound, http.StatusTooManyRequests, http.StatusInternalServerError)),
+		})
+		add(v.prefix+"/posts/{id}", http.MethodPut, Operation{
+			OperationID: op("updatePost"),
+			Summary:     "Update a post; absent fields are left unchanged",
+			Tags:        []string{v.tag},
+			Parameters:  []Parameter{postIDParam},
+			RequestBody: jsonBody(schemas.schema(reflect.TypeOf(models.UpdatePostRequest{}))),
+			Responses: merge(map[string]Response{
+				"200": {Description: "The updated post", Content: jsonContent(success(schemas, post))},
+			}, errorResponses(http.StatusBadRequest, http.StatusUnauthorized, http.StatusNotFound, http.StatusTooManyRequests, http.StatusInternalServerError)),
+			Security: writeSecurity,
+		})
+		add(v.prefix+"/posts/{id}", http.MethodDelete, Operation{
+			OperationID: op("deletePost"),
+			Summary:     "Delete a post",
+			Tags:        []string{v.tag},
+			Parameters:  []Parameter{postIDParam},
+			Responses: merge(map[string]Response{
+				"204": {Description: "Deleted"},
+			}, errorResponses(http.StatusBadRequest, http.StatusUnauthorized, http.StatusNotFound, http.StatusTooManyRequests, http.StatusInternalServerError)),
+			Security: writeSecurity,
+		})
+		add(v.prefix+"/users/{id}/posts", http.MethodGet, Operation{
+			OperationID: op("listUserPosts"),
+			Summary:     "List the posts of a user, newest first",
+			Tags:        []string{v.tag},
+			Parameters:  append([]Parameter{idParam}, pageParams...),
+			Responses: merge(map[string]Response{
+				"200": {Description: "A page of the user's posts", Content: jsonContent(paginated(schemas, post))},
+			}, errorResponses(http.StatusBadRequest, http.StatusNotFound, http.StatusTooManyRequests, http.StatusInternalServerError)),
+		})
+	}
+
+	auditEntry := schemas.ref("AuditEntry", reflect.TypeOf(audit.Entry{}))
+	schemas["AuditEntry"].Properties["changes"] = &Schema{
+		Type:        "object",
+		Description: "The fields that changed by name, each with its before and after value; before is omitted for creates and after for deletes",
+	}
+	doc.add("/api/audit", http.MethodGet, Operation{
+		OperationID: "listAuditEntries",
+		Summary:     "List the changes made to users and posts, newest first; admins only",
+		Tags:        []string{"audit"},
+		Parameters: []Parameter{
+			{Name: "entity", In: "query", Description: "Only changes of this kind of entity", Schema: &Schema{Type: "string", Enum: []string{audit.EntityUser, audit.EntityPost}}},
+			{Name: "entity_id", In: "query", Description: "Only changes of the entity with this ID", Schema: &Schema{Type: "integer", Minimum: float(1)}},
+			{Name: "actor", In: "query", Description: "Only changes made by this principal", Schema: &Schema{Type: "string"}},
+			{Name: "page", In: "query", Description: "Page number, starting at 1", Schema: &Schema{Type: "integer", Default: 1, Minimum: float(1)}},
+			{Name: "limit", In: "query", Description: "Page size, at most 200", Schema: &Schema{Type: "integer", Default: 50, Minimum: float(1)}},
+		},
+		Responses: merge(map[string]Response{
+			"200": {Description: "A page of audit entries", Content: jsonContent(paginated(schemas, auditEntry))},
+		}, errorResponses(http.StatusBadRequest, http.StatusUnauthorized, http.StatusForbidden, http.StatusTooManyRequests, http.StatusInternalServerError)),
+		Security: []map[string][]string{{bearerAuth: {}}},
+	})
+
+	role := schemas.ref("Role", reflect.TypeOf(roles.Role{}))
+	assignment := schemas.ref("RoleAssignment", reflect.TypeOf(roles.Assignment{}))
+	member := schemas.ref("RoleMember", reflect.TypeOf(roles.Member{}))
+	roleParam := Parameter{Name: "role", In: "path", Required: true, Description: "Role name, e.g. users:write", Schema: &Schema{Type: "string"}}
+	doc.add("/api/roles", http.MethodGet, Operation{
+		OperationID: "listRoles",
+		Summary:     "List the roles by name",
+		Tags:        []string{"roles"},
+		Responses: merge(map[string]Response{
+			"200": {Description: "Every role", Content: jsonContent(success(schemas, &Schema{Type: "array", Items: role}))},
+		}, errorResponses(http.StatusTooManyRequests, http.StatusInternalServerError)),
+	})
+	doc.add("/api/roles", http.MethodPost, Operation{
+		OperationID: "createRole",
+		Summary:     "Define a role named resource:action",
+		Tags:        []string{"roles"},
+		RequestBody: jsonBody(schemas.schema(reflect.TypeOf(roles.CreateRequest{}))),
+		Responses: merge(map[string]Response{
+			"201": {Description: "The created role", Content: jsonContent(success(schemas, role))},
+		}, errorResponses(http.StatusBadRequest, http.StatusUnauthorized, http.StatusForbidden, http.StatusConflict, http.StatusTooManyRequests, http.StatusInternalServerError)),
+		Security: writeSecurity,
+	})
+	doc.add("/api/roles/{role}/users", http.MethodGet, Operation{
+		OperationID: "listRoleMembers",
+		Summary:     "List the users holding a role in ID order",
+		Tags:        []string{"roles"},
+		Parameters: []Parameter{
+			roleParam,
+			{Name: "page", In: "query", Description: "Page number, starting at 1", Schema: &Schema{Type: "integer", Default: 1, Minimum: float(1)}},
+			{Name: "limit", In: "query", Description: "Page size, at most 200", Schema: &Schema{Type: "integer", Default: 50, Minimum: float(1)}},
+		},
+		Responses: merge(map[string]Response{
+			"200": {Description: "A page of the role's users", Content: jsonContent(paginated(schemas, member))},
+		}, errorResponses(http.StatusNotFound, http.StatusTooManyRequests, http.StatusInternalServerError)),
+	})
+	doc.add("/api/users/{id}/roles", http.MethodGet, Operation{
+		OperationID: "listUserRoles",
+		Summary:     "List the roles assigned to a user",
+		Tags:        []string{"roles"},
+		Parameters:  []Parameter{idParam},
+		Responses: merge(map[string]Response{
+			"200": {Description: "The user's roles by name", Content: jsonContent(success(schemas, &Schema{Type: "array", Items: assignment}))},
+		}, errorResponses(http.StatusBadRequest, http.StatusNotFound, http.StatusTooManyRequests, http.StatusInternalServerError)),
+	})
+	doc.add("/api/users/{id}/roles/{role}", http.MethodPut, Operation{
+		OperationID: "assignUserRole",
+		Summary:     "Assign a role to a user; assigning it again changes nothing",
+		Tags:        []string{"roles"},
+		Parameters:  []Parameter{idParam, roleParam},
+		Responses: merge(map[string]Response{
+			"200": {Description: "The user's roles by name", Content: jsonContent(success(schemas, &Schema{Type: "array", Items: assignment}))},
+		}, errorResponses(http.StatusBadRequest, http.StatusUnauthorized, http.StatusForbidden, http.StatusNotFound, http.StatusTooManyRequests, http.StatusInternalServerError)),
+		Security: writeSecurity,
+	})
+	doc.add("/api/users/{id}/roles/{role}", http.MethodDelete, Operation{
+		OperationID: "removeUserRole",
+		Summary:     "Remove a role from a user",
+		Tags:        []string{"roles"},
+		Parameters:  []Parameter{idParam, roleParam},
+		Responses: merge(map[string]Response{
+			"204": {Description: "Removed"},
+		}, errorResponses(http.StatusBadRequest, http.StatusUnauthorized, http.StatusForbidden, http.StatusNotFound, http.StatusTooManyRequests, http.StatusInternalServerError)),
+		Security: writeSecurity,
+	})
+
+	doc.add("/api/users/{id}/avatar", http.MethodPost, Operation{
+		OperationID: "uploadUserAvatar",
+		Summary:     "Upload the avatar of a user, replacing the previous one; served when S3_ENDPOINT is set",
+		Tags:        []string{"users"},
+		Parameters:  []Parameter{idParam},
+		RequestBody: &RequestBody{Required: true, Content: map[string]MediaType{
+			"multipart/form-data": {Schema: &Schema{
+				Type: "object",
+				Properties: map[string]*Schema{
+					avatars.FormField: {Type: "string", Format: "binary", Description: "A PNG, JPEG, GIF or WebP image of at most AVATAR_MAX_BYTES"},
+				},
+				Required: []string{avatars.FormField},
+			}},
+		}},
+		Responses: merge(map[string]Response{
+			"200": {Description: "The stored avatar", Content: jsonContent(success(schemas, schemas.ref("Avatar", reflect.TypeOf(avatars.Object{}))))},
+		}, errorResponses(http.StatusBadRequest, http.StatusUnauthorized, http.StatusForbidden, http.StatusNotFound, http.StatusRequestEntityTooLarge, http.StatusUnsupportedMediaType, http.StatusTooManyRequests, http.StatusInternalServerError)),
+		Security: writeSecurity,
+	})
+
+	operation := schemas.ref("Operation", reflect.TypeOf(operations.Operation{}))
+	retryAfter := Header{Description: "Seconds to wait before polling the operation", Schema: &Schema{Type: "integer"}}
+	doc.add("/api/reports", http.MethodPost, Operation{
+		OperationID: "createReport",
+		Summary:     "Start generating a report; the SignupReport is the result of the returned operation",
+		Tags:        []string{"reports"},
+		RequestBody: jsonBody(schemas.schema(reflect.TypeOf(reports.Request{}))),
+		Responses: merge(map[string]Response{
+			"202": {
+				Description: "The pending operation generating the report",
+				Headers: map[string]Header{
+					"Location":    {Description: "Status of the operation", Schema: &Schema{Type: "string"}},
+					"Retry-After": retryAfter,
+				},
+				Content: jsonContent(success(schemas, operation)),
+			},
+		}, errorResponses(http.StatusBadRequest, http.StatusUnauthorized, http.StatusForbidden, http.StatusTooManyRequests, http.StatusInternalServerError, http.StatusServiceUnavailable)),
+		Security: writeSecurity,
+	})
+	schemas.ref("SignupReport", reflect.TypeOf(reports.SignupReport{}))
+	doc.add("/api/operations/{id}", http.MethodGet, Operation{
+		OperationID: "getOperation",
+		Summary:     "Poll an async operation until it has succeeded with a result or failed",
+		Tags:        []string{"operations"},
+		Parameters:  []Parameter{{Name: "id", In: "path", Required: true, Description: "Operation ID", Schema: &Schema{Type: "string"}}},
+		Responses: merge(map[string]Response{
+			"200": {
+				Description: "The operation; Retry-After is set until it finishes",
+				Headers:     map[string]Header{"Retry-After": retryAfter},
+				Content:     jsonContent(success(schemas, operation)),
+			},
+		}, errorResponses(http.StatusNotFound, http.StatusTooManyRequests, http.StatusInternalServerError)),
+	})
+
+	return doc
+}
+
+func (d *Document) add(path, method string, op Operation) {
+	if d.Paths[path] == nil {
+		d.Paths[path] = map[string]Operation{}
+	}
+	d.Paths[path][strings.ToLower(method)] = op
+}
+
+// apiVersionParam selects the response version of the unversioned routes
+var apiVersionParam = Parameter{
+	Name:        "API-Version",
+	In:          "header",
+	Description: "Response version, 1 or 2; defaults to 1",
+	Schema:      &Schema{Type: "string", Enum: []string{"1", "2"}},
+}
+
+// adder returns d.add for the operations of a route group. Operations of the
+// unversioned group are deprecated and take the API-Version header
+func (d *Document) adder(unversioned bool) func(path, method string, op Operation) {
+	return func(path, method string, op Operation) {
+		if unversioned {
+			op.Deprecated = true
+			op.Parameters = append(append([]Parameter{}, op.Parameters...), apiVersionParam)
+		}
+		d.add(path, method, op)
+	}
+}
+
+// success wraps data in the SuccessResponse envelope
+func success(schemas registry, data *Schema) *Schema {
+	envelope := schemas.object(reflect.TypeOf(models.SuccessResponse{}))
+	envelope.Properties["data"] = data
+	return envelope
+}
+
+// paginated wraps items in the PaginatedResponse envelope
+func paginated(schemas registry, item *Schema) *Schema {
+	envelope := schemas.object(reflect.TypeOf(models.PaginatedResponse[any]{}))
+	envelope.Properties["data"] = &Schema{Type: "array", Items: item}
+	return envelope
+}
+
+func jsonContent(schema *Schema) map[string]MediaType {
+	return map[string]MediaType{jsonContentType: {Schema: schema}}
+}
+
+func jsonBody(schema *Schema) *RequestBody {
+	return &RequestBody{Required: true, Content: jsonContent(schema)}
+}
+
+// mergePatchBody is a request body of merge patches; plain JSON is accepted
+// too
+func mergePatchBody(schema *Schema) *RequestBody {
+	return &RequestBody{Required: true, Content: map[string]MediaType{
+		utils.MergePatchContentType: {Schema: schema},
+		jsonContentType:             {Schema: schema},
+	}}
+}
+
+func merge(responses ...map[string]Response) map[string]Response {
+	out := map[string]Response{}
+	for _, r := range responses {
+		for status, response := range r {
+			out[status] = response
+		}
+	}
+	return out
+}
+
+func float(v float64) *float64 {
+	return &v
+}
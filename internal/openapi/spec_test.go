@@ -0,0 +1,108 @@
+package openapi
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBuildSchemas(t *testing.T) {
+	doc := Build()
+	schemas := doc.Components.Schemas
+
+	user := schemas["UserResponse"]
+	require.NotNil(t, user)
+	assert.ElementsMatch(t, []string{"id", "name", "email", "created_at", "updated_at", "version"}, user.Required)
+	// Audit fields come from the embedded *AuditInfo and are admin-only
+	assert.Contains(t, user.Properties, "created_by")
+	assert.Equal(t, "date-time", user.Properties["created_at"].Format)
+
+	v2 := schemas["UserV2"]
+	require.NotNil(t, v2)
+	assert.Equal(t, "#/components/schemas/MetaV2", v2.Properties["meta"].Ref)
+
+	create := schemas["CreateUserRequest"]
+	require.NotNil(t, create)
+	assert.ElementsMatch(t, []string{"name", "email"}, create.Required)
+	assert.Equal(t, "email", create.Properties["email"].Format)
+
+	update := schemas["UpdateUserRequest"]
+	require.NotNil(t, update)
+	assert.Empty(t, update.Required)
+	assert.True(t, update.Properties["bio"].Nullable)
+	assert.Equal(t, "string", update.Properties["bio"].Type)
+
+	errorResponse := schemas["ErrorResponse"]
+	require.NotNil(t, errorResponse)
+	assert.Equal(t, "#/components/schemas/FieldError", errorResponse.Properties["details"].Items.Ref)
+}
+
+func TestBuildOperations(t *testing.T) {
+	doc := Build()
+
+	create := doc.Paths["/api/v2/users"]["post"]
+	assert.Equal(t, "createUserV2", create.OperationID)
+	assert.NotEmpty(t, create.Security)
+	assert.Contains(t, create.Responses, "429")
+	assert.Contains(t, create.Responses["429"].Headers, "Retry-After")
+
+	list := doc.Paths["/api/users"]["get"]
+	assert.Equal(t, "listUsers", list.OperationID)
+	assert.Empty(t, list.Security)
+	page := list.Responses["200"].Content[jsonContentType].Schema
+	assert.Equal(t, "#/components/schemas/UserResponse", page.Properties["data"].Items.Ref)
+	// The unversioned routes are deprecated and negotiate with API-Version
+	assert.True(t, list.Deprecated)
+	assert.Contains(t, list.Parameters, apiVersionParam)
+	assert.False(t, doc.Paths["/api/v1/users"]["get"].Deprecated)
+	assert.True(t, doc.Paths["/api/posts/{id}"]["get"].Deprecated)
+
+	get := doc.Paths["/api/v1/users/{id}"]["get"]
+	assert.Contains(t, get.Responses, "304")
+	assert.Contains(t, get.Responses["200"].Headers, "ETag")
+
+	bulk := doc.Paths["/api/v2/users/bulk"]["post"]
+	assert.Equal(t, "bulkCreateUsersV2", bulk.OperationID)
+	require.Len(t, bulk.Parameters, 1)
+	assert.Equal(t, "Idempotency-Key", bulk.Parameters[0].Name)
+	assert.Contains(t, doc.Paths["/api/v1/users"]["post"].Responses, "422")
+	results := bulk.Responses["207"].Content[jsonContentType].Schema.Properties["data"].Properties["results"]
+	assert.Equal(t, "#/components/schemas/UserV2", results.Items.Properties["data"].Ref)
+
+	userPosts := doc.Paths["/api/v2/users/{id}/posts"]["get"]
+	assert.Equal(t, "listUserPostsV2", userPosts.OperationID)
+	assert.Equal(t, "#/components/schemas/PostV2", userPosts.Responses["200"].Content[jsonContentType].Schema.Properties["data"].Items.Ref)
+
+	ids := map[string]bool{}
+	for _, ops := range doc.Paths {
+		for _, op := range ops {
+			assert.False(t, ids[op.OperationID], "duplicate operation ID %s", op.OperationID)
+			ids[op.OperationID] = true
+		}
+	}
+}
+
+func TestSpecAndDocsHandlers(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.GET("/openapi.json", Spec)
+	r.GET("/docs", Docs)
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/openapi.json", nil))
+	require.Equal(t, http.StatusOK, w.Code)
+	var doc map[string]any
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &doc))
+	assert.Equal(t, "3.0.3", doc["openapi"])
+
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/docs", nil))
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Header().Get("Content-Type"), "text/html")
+	assert.Contains(t, w.Body.String(), `url: "/openapi.json"`)
+}
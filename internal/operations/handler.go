@@ -0,0 +1,60 @@
+package operations
+
+import (
+	"strconv"
+
+	"arquivolivre.com.br/otel/internal/middleware"
+	"arquivolivre.com.br/otel/pkg/utils"
+
+	"github.com/gin-gonic/gin"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// PollInterval is the Retry-After, in seconds, of unfinished operations
+const PollInterval = 1
+
+// Handler serves the status of operations over HTTP
+type Handler struct {
+	manager *Manager
+}
+
+// NewHandler creates a handler for the operations of manager
+func NewHandler(manager *Manager) *Handler {
+	return &Handler{manager: manager}
+}
+
+// Register mounts GET /operations/:id on root behind the reads middleware
+func (h *Handler) Register(root *gin.RouterGroup, reads []gin.HandlerFunc) {
+	root.Group("", reads...).GET("/operations/:id", h.GetOperation)
+}
+
+// GetOperation returns the status of an operation, with its result once it
+// has succeeded. Unfinished operations tell clients when to poll again in
+// Retry-After
+func (h *Handler) GetOperation(c *gin.Context) {
+	span := trace.SpanFromContext(c.Request.Context())
+	span.SetAttributes(attribute.String("operation.id", c.Param("id")))
+
+	op, err := h.manager.Get(c.Param("id"))
+	if err != nil {
+		_ = c.Error(middleware.FromError(err, "Failed to retrieve operation"))
+		return
+	}
+	span.SetAttributes(
+		attribute.String("operation.kind", op.Kind),
+		attribute.String("operation.status", string(op.Status)),
+	)
+	if !op.Status.Done() {
+		c.Header("Retry-After", strconv.Itoa(PollInterval))
+	}
+	utils.SendSuccess(c, op)
+}
+
+// Accepted answers a request that started op with 202 Accepted, pointing
+// the client at its status under base, e.g. /api
+func Accepted(c *gin.Context, base string, op Operation, message string) {
+	c.Header("Location", base+"/operations/"+op.ID)
+	c.Header("Retry-After", strconv.Itoa(PollInterval))
+	utils.SendAccepted(c, op, message)
+}
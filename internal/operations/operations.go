@@ -0,0 +1,224 @@
+// Package operations runs long requests in the background. The request is
+// answered with 202 Accepted and the ID of an operation, which clients poll
+// until it has succeeded or failed. The work runs as a job, in its own trace
+// linked to the request span.
+package operations
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"sync"
+	"time"
+
+	"arquivolivre.com.br/otel/internal/jobs"
+	"arquivolivre.com.br/otel/pkg/apperrors"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Status is the state of an operation
+type Status string
+
+const (
+	// StatusPending operations wait for a worker
+	StatusPending Status = "pending"
+	// StatusRunning operations are being worked on
+	StatusRunning Status = "running"
+	// StatusSucceeded operations have a result
+	StatusSucceeded Status = "succeeded"
+	// StatusFailed operations have an error
+	StatusFailed Status = "failed"
+)
+
+// Done reports whether the operation has finished
+func (s Status) Done() bool {
+	return s == StatusSucceeded || s == StatusFailed
+}
+
+// Operation is the status of background work and, once it has finished,
+// its result or error
+type Operation struct {
+	ID     string `json:"id"`
+	Kind   string `json:"kind"`
+	Status Status `json:"status"`
+	Result any    `json:"result,omitempty"`
+	// Error is the client-safe message of the failure
+	Error string `json:"error,omitempty"`
+	// TraceID is the trace of the work, linked to the trace of the request
+	// that started it
+	TraceID    string     `json:"trace_id,omitempty"`
+	CreatedAt  time.Time  `json:"created_at"`
+	StartedAt  *time.Time `json:"started_at,omitempty"`
+	FinishedAt *time.Time `json:"finished_at,omitempty"`
+}
+
+// Func is the work of an operation; its result is serialized to JSON
+type Func func(ctx context.Context) (any, error)
+
+// ErrNotFound is returned by Get for unknown and expired operations
+var ErrNotFound = apperrors.NotFound("operation not found")
+
+// Manager starts operations on a job queue and keeps their status for
+// retention once they finish. Operations are counted by kind and status in
+// operations.in_flight while pending or running, and their time in the
+// queue and until they finish go into operation.queue.duration and
+// operation.duration
+type Manager struct {
+	queue     jobs.Enqueuer
+	retention time.Duration
+
+	mu         sync.Mutex
+	operations map[string]*Operation
+
+	inFlight      metric.Int64UpDownCounter
+	queueDuration metric.Float64Histogram
+	duration      metric.Float64Histogram
+}
+
+// NewManager creates a manager running operations on queue
+func NewManager(queue jobs.Enqueuer, retention time.Duration) *Manager {
+	meter := otel.Meter("otel-example-api")
+	inFlight, _ := meter.Int64UpDownCounter(
+		"operations.in_flight",
+		metric.WithDescription("Number of async operations pending or running"),
+		metric.WithUnit("{operation}"),
+	)
+	queueDuration, _ := meter.Float64Histogram(
+		"operation.queue.duration",
+		metric.WithDescription("Time async operations wait for a worker after being accepted"),
+		metric.WithUnit("s"),
+	)
+	duration, _ := meter.Float64Histogram(
+		"operation.duration",
+		metric.WithDescription("Time from the acceptance of async operations until they finish"),
+		metric.WithUnit("s"),
+	)
+	return &Manager{
+		queue:         queue,
+		retention:     retention,
+		operations:    make(map[string]*Operation),
+		inFlight:      inFlight,
+		queueDuration: queueDuration,
+		duration:      duration,
+	}
+}
+
+// Start accepts an operation of kind doing fn and queues it. The span in
+// ctx gets the operation.id and is linked from the span of the work. A full
+// queue is reported as apperrors.ErrUnavailable
+func (m *Manager) Start(ctx context.Context, kind string, fn Func) (Operation, error) {
+	now := time.Now()
+	op := &Operation{ID: newID(), Kind: kind, Status: StatusPending, CreatedAt: now.UTC()}
+	trace.SpanFromContext(ctx).SetAttributes(
+		attribute.String("operation.id", op.ID),
+		attribute.String("operation.kind", kind),
+	)
+
+	m.mu.Lock()
+	m.prune(now)
+	m.operations[op.ID] = op
+	accepted := *op
+	m.mu.Unlock()
+
+	kindAttr := attribute.String("operation.kind", kind)
+	m.inFlight.Add(ctx, 1, metric.WithAttributes(kindAttr, attribute.String("operation.status", string(StatusPending))))
+	err := m.queue.Enqueue(ctx, kind, func(ctx context.Context) error {
+		return m.run(ctx, op.ID, now, fn)
+	})
+	if err != nil {
+		m.inFlight.Add(ctx, -1, metric.WithAttributes(kindAttr, attribute.String("operation.status", string(StatusPending))))
+		m.mu.Lock()
+		delete(m.operations, op.ID)
+		m.mu.Unlock()
+		return Operation{}, apperrors.Wrap(apperrors.ErrUnavailable, err, "Too many operations are in progress, try again later")
+	}
+	return accepted, nil
+}
+
+// Get returns the operation id
+func (m *Manager) Get(id string) (Operation, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	op, ok := m.operations[id]
+	if !ok || m.expired(op, time.Now()) {
+		return Operation{}, ErrNotFound
+	}
+	return *op, nil
+}
+
+// run does the work of the operation id accepted at acceptedAt in the span
+// of its job
+func (m *Manager) run(ctx context.Context, id string, acceptedAt time.Time, fn Func) error {
+	span := trace.SpanFromContext(ctx)
+	startedAt := time.Now()
+	kind := m.update(id, func(op *Operation) {
+		op.Status = StatusRunning
+		op.TraceID = span.SpanContext().TraceID().String()
+		started := startedAt.UTC()
+		op.StartedAt = &started
+	})
+	kindAttr := attribute.String("operation.kind", kind)
+	span.SetAttributes(attribute.String("operation.id", id), kindAttr)
+	m.queueDuration.Record(ctx, startedAt.Sub(acceptedAt).Seconds(), metric.WithAttributes(kindAttr))
+	m.inFlight.Add(ctx, -1, metric.WithAttributes(kindAttr, attribute.String("operation.status", string(StatusPending))))
+	m.inFlight.Add(ctx, 1, metric.WithAttributes(kindAttr, attribute.String("operation.status", string(StatusRunning))))
+
+	result, err := fn(ctx)
+
+	status := StatusSucceeded
+	if err != nil {
+		status = StatusFailed
+	}
+	finishedAt := time.Now()
+	span.SetAttributes(attribute.String("operation.status", string(status)))
+	m.inFlight.Add(ctx, -1, metric.WithAttributes(kindAttr, attribute.String("operation.status", string(StatusRunning))))
+	m.duration.Record(ctx, finishedAt.Sub(acceptedAt).Seconds(), metric.WithAttributes(kindAttr, attribute.String("operation.status", string(status))))
+	m.update(id, func(op *Operation) {
+		op.Status = status
+		finished := finishedAt.UTC()
+		op.FinishedAt = &finished
+		if err != nil {
+			op.Error = apperrors.Message(err)
+			if op.Error == "" {
+				op.Error = "The operation failed"
+			}
+			return
+		}
+		op.Result = result
+	})
+	// The job reports the error on its span and in the logs
+	return err
+}
+
+// update applies fn to the operation id and returns its kind
+func (m *Manager) update(id string, fn func(op *Operation)) string {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	op := m.operations[id]
+	fn(op)
+	return op.Kind
+}
+
+// prune forgets the operations that have expired at now; m.mu must be held
+func (m *Manager) prune(now time.Time) {
+	for id, op := range m.operations {
+		if m.expired(op, now) {
+			delete(m.operations, id)
+		}
+	}
+}
+
+// expired reports whether op finished more than the retention before now
+func (m *Manager) expired(op *Operation, now time.Time) bool {
+	return op.FinishedAt != nil && now.Sub(*op.FinishedAt) > m.retention
+}
+
+func newID() string {
+	b := make([]byte, 16)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
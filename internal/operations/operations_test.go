@@ -0,0 +1,203 @@
+package operations
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"arquivolivre.com.br/otel/internal/jobs"
+	"arquivolivre.com.br/otel/internal/middleware"
+	"arquivolivre.com.br/otel/pkg/apperrors"
+	"arquivolivre.com.br/otel/pkg/oteltest"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/attribute"
+)
+
+// fullQueue refuses every job
+type fullQueue struct{}
+
+func (fullQueue) Enqueue(context.Context, string, jobs.Func) error { return jobs.ErrQueueFull }
+
+func startQueue(t *testing.T) *jobs.Queue {
+	t.Helper()
+	queue, err := jobs.NewQueue(jobs.Config{Workers: 1, BufferSize: 10})
+	require.NoError(t, err)
+	queue.Start()
+	t.Cleanup(func() { _ = queue.Shutdown(context.Background()) })
+	return queue
+}
+
+// router starts an operation of kind "test" doing fn on POST /api/work and
+// serves the operations of manager
+func router(manager *Manager, fn Func) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.Use(middleware.NewTelemetryMiddleware("test-service").GinMiddleware())
+	r.Use(middleware.ErrorHandler())
+	api := r.Group("/api")
+	api.POST("/work", func(c *gin.Context) {
+		op, err := manager.Start(c.Request.Context(), "test", fn)
+		if err != nil {
+			_ = c.Error(middleware.FromError(err, "Failed to start work"))
+			return
+		}
+		Accepted(c, "/api", op, "Work accepted")
+	})
+	NewHandler(manager).Register(api, nil)
+	return r
+}
+
+func decode(t *testing.T, w *httptest.ResponseRecorder) Operation {
+	t.Helper()
+	var resp struct {
+		Data Operation `json:"data"`
+	}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	return resp.Data
+}
+
+// jobSpan waits for the span of the job of kind test to end
+func jobSpan(t *testing.T, rec *oteltest.Recorder) {
+	t.Helper()
+	require.Eventually(t, func() bool {
+		_, ok := rec.Span("job test")
+		return ok
+	}, time.Second, 5*time.Millisecond)
+}
+
+// poll gets the operation at location until it has finished
+func poll(t *testing.T, r http.Handler, location string) Operation {
+	t.Helper()
+	var op Operation
+	require.Eventually(t, func() bool {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, location, nil))
+		require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+		op = decode(t, w)
+		if op.Status.Done() {
+			assert.Empty(t, w.Header().Get("Retry-After"))
+		}
+		return op.Status.Done()
+	}, time.Second, 5*time.Millisecond)
+	return op
+}
+
+func TestOperationSucceeds(t *testing.T) {
+	rec := oteltest.Install(t)
+	release := make(chan struct{})
+	r := router(NewManager(startQueue(t), time.Hour), func(ctx context.Context) (any, error) {
+		<-release
+		return map[string]int{"answer": 42}, nil
+	})
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/api/work", nil))
+	require.Equal(t, http.StatusAccepted, w.Code, w.Body.String())
+	accepted := decode(t, w)
+	assert.Equal(t, StatusPending, accepted.Status)
+	assert.Equal(t, "test", accepted.Kind)
+	location := w.Header().Get("Location")
+	assert.Equal(t, "/api/operations/"+accepted.ID, location)
+	assert.Equal(t, "1", w.Header().Get("Retry-After"))
+	request := rec.AssertSpan(t, "POST /api/work", attribute.String("operation.id", accepted.ID))
+
+	// Until the work is done the client is told to poll again
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, location, nil))
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.False(t, decode(t, w).Status.Done())
+	assert.Equal(t, "1", w.Header().Get("Retry-After"))
+	close(release)
+
+	op := poll(t, r, location)
+	assert.Equal(t, StatusSucceeded, op.Status)
+	assert.Equal(t, map[string]any{"answer": float64(42)}, op.Result)
+	assert.Empty(t, op.Error)
+	require.NotNil(t, op.StartedAt)
+	require.NotNil(t, op.FinishedAt)
+
+	// The work runs in its own trace, linked to the request
+	jobSpan(t, rec)
+	work := rec.AssertSpan(t, "job test",
+		attribute.String("operation.id", accepted.ID),
+		attribute.String("operation.status", "succeeded"),
+	)
+	assert.Equal(t, work.SpanContext().TraceID().String(), op.TraceID)
+	assert.NotEqual(t, request.SpanContext().TraceID(), work.SpanContext().TraceID())
+	require.Len(t, work.Links(), 1)
+	assert.Equal(t, request.SpanContext().SpanID(), work.Links()[0].SpanContext.SpanID())
+
+	kind := attribute.String("operation.kind", "test")
+	rec.AssertHistogramCount(t, "operation.queue.duration", []attribute.KeyValue{kind}, 1)
+	rec.AssertHistogramCount(t, "operation.duration", []attribute.KeyValue{kind, attribute.String("operation.status", "succeeded")}, 1)
+	rec.AssertCounterValue(t, "operations.in_flight", []attribute.KeyValue{kind}, 0)
+}
+
+func TestOperationFails(t *testing.T) {
+	rec := oteltest.Install(t)
+	r := router(NewManager(startQueue(t), time.Hour), func(ctx context.Context) (any, error) {
+		return nil, errors.New("connection refused")
+	})
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/api/work", nil))
+	require.Equal(t, http.StatusAccepted, w.Code, w.Body.String())
+
+	op := poll(t, r, w.Header().Get("Location"))
+	assert.Equal(t, StatusFailed, op.Status)
+	// Internal errors are not shown to clients
+	assert.Equal(t, "The operation failed", op.Error)
+	assert.Nil(t, op.Result)
+	jobSpan(t, rec)
+	rec.AssertSpan(t, "job test", attribute.String("operation.status", "failed"))
+	rec.AssertHistogramCount(t, "operation.duration", []attribute.KeyValue{attribute.String("operation.status", "failed")}, 1)
+}
+
+func TestOperationRejectedWhenQueueIsFull(t *testing.T) {
+	oteltest.Install(t)
+	manager := NewManager(fullQueue{}, time.Hour)
+	r := router(manager, func(ctx context.Context) (any, error) { return nil, nil })
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/api/work", nil))
+	assert.Equal(t, http.StatusServiceUnavailable, w.Code, w.Body.String())
+	assert.Empty(t, manager.operations)
+
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/operations/unknown", nil))
+	assert.Equal(t, http.StatusNotFound, w.Code, w.Body.String())
+}
+
+func TestFinishedOperationsExpire(t *testing.T) {
+	oteltest.Install(t)
+	manager := NewManager(startQueue(t), time.Minute)
+	op, err := manager.Start(context.Background(), "test", func(ctx context.Context) (any, error) {
+		return "done", nil
+	})
+	require.NoError(t, err)
+	require.Eventually(t, func() bool {
+		got, err := manager.Get(op.ID)
+		return err == nil && got.Status.Done()
+	}, time.Second, 5*time.Millisecond)
+
+	manager.mu.Lock()
+	finished := time.Now().Add(-2 * time.Minute)
+	manager.operations[op.ID].FinishedAt = &finished
+	manager.mu.Unlock()
+	_, err = manager.Get(op.ID)
+	assert.ErrorIs(t, err, apperrors.ErrNotFound)
+
+	// The next operation forgets it
+	_, err = manager.Start(context.Background(), "test", func(ctx context.Context) (any, error) { return nil, nil })
+	require.NoError(t, err)
+	manager.mu.Lock()
+	assert.NotContains(t, manager.operations, op.ID)
+	manager.mu.Unlock()
+}
@@ -0,0 +1,45 @@
+// Package outbox implements the transactional outbox for user events. The
+// repository stores each event in the outbox table in the same transaction
+// as the change it describes, and a Relay publishes the stored events, so an
+// event is published if and only if its change was committed.
+package outbox
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+
+	"arquivolivre.com.br/otel/internal/events"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/propagation"
+)
+
+// InsertStatement stores one event; it is exported for query metrics
+const InsertStatement = "INSERT INTO outbox (event_type, payload, headers) VALUES (?, ?, ?)"
+
+// Execer runs statements, typically inside the transaction of the change
+type Execer interface {
+	ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error)
+}
+
+// Insert stores event with the trace context and baggage of ctx, which the
+// relay restores when it publishes the event
+func Insert(ctx context.Context, q Execer, event events.UserEvent) error {
+	payload, err := json.Marshal(event)
+	if err != nil {
+		return fmt.Errorf("failed to encode outbox event: %w", err)
+	}
+	carrier := propagation.MapCarrier{}
+	otel.GetTextMapPropagator().Inject(ctx, carrier)
+	headers, err := json.Marshal(carrier)
+	if err != nil {
+		return fmt.Errorf("failed to encode outbox headers: %w", err)
+	}
+
+	if _, err := q.ExecContext(ctx, InsertStatement, string(event.Type), payload, headers); err != nil {
+		return fmt.Errorf("failed to write outbox event: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,277 @@
+package outbox
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"arquivolivre.com.br/otel/internal/database"
+	"arquivolivre.com.br/otel/internal/events"
+	"arquivolivre.com.br/otel/internal/logging"
+	"arquivolivre.com.br/otel/internal/models"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/metric"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const instrumentationName = "arquivolivre.com.br/otel/internal/outbox"
+
+// maxBackoff caps the wait between polls after failed publishes
+const maxBackoff = time.Minute
+
+const (
+	selectPendingStatement = `
+		SELECT id, event_type, payload, headers, attempts, created_at
+		FROM outbox
+		WHERE sent_at IS NULL AND attempts < ?
+		ORDER BY id
+		LIMIT ?
+		FOR UPDATE SKIP LOCKED
+	`
+	markSentStatement     = "UPDATE outbox SET sent_at = NOW(3), attempts = attempts + 1, last_error = NULL WHERE id = ?"
+	markFailedStatement   = "UPDATE outbox SET attempts = attempts + 1, last_error = ? WHERE id = ?"
+	countPendingStatement = "SELECT COUNT(*) FROM outbox WHERE sent_at IS NULL AND attempts < ?"
+)
+
+// Config tunes the relay
+type Config struct {
+	// PollInterval is the wait between polls while publishes succeed
+	PollInterval time.Duration
+	// BatchSize bounds the events published per poll
+	BatchSize int
+	// MaxAttempts is the number of publishes after which an event is
+	// abandoned; it then stays in the table with its last error
+	MaxAttempts int
+}
+
+// Relay publishes the pending outbox events in insertion order and marks
+// them sent
+type Relay struct {
+	db        *database.DB
+	publisher events.Publisher
+	cfg       Config
+	tracer    trace.Tracer
+	metrics   relayMetrics
+}
+
+type relayMetrics struct {
+	lag     metric.Float64Histogram
+	relayed metric.Int64Counter
+	pending metric.Int64Gauge
+}
+
+// record is one row of the outbox table
+type record struct {
+	id        int64
+	eventType string
+	payload   []byte
+	headers   []byte
+	attempts  int
+	createdAt models.Timestamp
+}
+
+// NewRelay creates a relay publishing the events of db with publisher
+func NewRelay(db *database.DB, publisher events.Publisher, cfg Config) (*Relay, error) {
+	if cfg.PollInterval <= 0 {
+		cfg.PollInterval = time.Second
+	}
+	if cfg.BatchSize < 1 {
+		cfg.BatchSize = 100
+	}
+	if cfg.MaxAttempts < 1 {
+		cfg.MaxAttempts = 10
+	}
+
+	m, err := newRelayMetrics(otel.Meter(instrumentationName))
+	if err != nil {
+		return nil, err
+	}
+	return &Relay{
+		db:        db,
+		publisher: publisher,
+		cfg:       cfg,
+		tracer:    otel.Tracer(instrumentationName),
+		metrics:   m,
+	}, nil
+}
+
+func newRelayMetrics(meter metric.Meter) (relayMetrics, error) {
+	lag, err := meter.Float64Histogram(
+		"outbox_relay_lag_seconds",
+		metric.WithDescription("Time from writing an outbox event to publishing it"),
+		metric.WithUnit("s"),
+	)
+	if err != nil {
+		return relayMetrics{}, fmt.Errorf("failed to create relay lag metric: %w", err)
+	}
+
+	relayed, err := meter.Int64Counter(
+		"outbox_events_relayed_total",
+		metric.WithDescription("Total number of outbox publish attempts by result"),
+	)
+	if err != nil {
+		return relayMetrics{}, fmt.Errorf("failed to create relayed events metric: %w", err)
+	}
+
+	pending, err := meter.Int64Gauge(
+		"outbox_pending_events",
+		metric.WithDescription("Number of outbox events waiting to be published"),
+	)
+	if err != nil {
+		return relayMetrics{}, fmt.Errorf("failed to create pending events metric: %w", err)
+	}
+
+	return relayMetrics{lag: lag, relayed: relayed, pending: pending}, nil
+}
+
+// Run polls the outbox until ctx is done. After a failed publish the wait
+// between polls doubles, up to a minute, until a poll succeeds again
+func (r *Relay) Run(ctx context.Context) {
+	wait := r.cfg.PollInterval
+	for {
+		if _, err := r.relayBatch(ctx); err != nil {
+			if ctx.Err() != nil {
+				return
+			}
+			logging.Module("outbox").LogError(ctx, err, "Outbox relay failed", map[string]interface{}{"retry_in": wait.String()})
+			wait = min(wait*2, maxBackoff)
+		} else {
+			wait = r.cfg.PollInterval
+		}
+		r.recordPending(ctx)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(wait):
+		}
+	}
+}
+
+// relayBatch publishes up to BatchSize pending events and returns how many
+// were sent. It stops at the first failed publish so events stay in order
+func (r *Relay) relayBatch(ctx context.Context) (int, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, fmt.Errorf("failed to begin outbox transaction: %w", err)
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	done := r.db.TrackQuery(ctx, "SELECT", "outbox", selectPendingStatement)
+	rows, err := tx.QueryContext(ctx, selectPendingStatement, r.cfg.MaxAttempts, r.cfg.BatchSize)
+	done(err)
+	if err != nil {
+		return 0, fmt.Errorf("failed to query outbox: %w", err)
+	}
+	var batch []record
+	for rows.Next() {
+		var rec record
+		if err := rows.Scan(&rec.id, &rec.eventType, &rec.payload, &rec.headers, &rec.attempts, &rec.createdAt); err != nil {
+			_ = rows.Close()
+			return 0, fmt.Errorf("failed to scan outbox event: %w", err)
+		}
+		batch = append(batch, rec)
+	}
+	if err := rows.Err(); err != nil {
+		return 0, fmt.Errorf("error iterating over outbox: %w", err)
+	}
+
+	sent := 0
+	var publishErr error
+	for _, rec := range batch {
+		if publishErr = r.publish(ctx, rec); publishErr != nil {
+			done := r.db.TrackQuery(ctx, "UPDATE", "outbox", markFailedStatement)
+			_, err := tx.ExecContext(ctx, markFailedStatement, truncate(publishErr.Error(), 1024), rec.id)
+			done(err)
+			if err != nil {
+				return sent, fmt.Errorf("failed to record outbox failure: %w", err)
+			}
+			break
+		}
+		done := r.db.TrackQuery(ctx, "UPDATE", "outbox", markSentStatement)
+		_, err := tx.ExecContext(ctx, markSentStatement, rec.id)
+		done(err)
+		if err != nil {
+			return sent, fmt.Errorf("failed to mark outbox event sent: %w", err)
+		}
+		sent++
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, fmt.Errorf("failed to commit outbox transaction: %w", err)
+	}
+	return sent, publishErr
+}
+
+// publish sends rec in a span that continues the trace of the request that
+// wrote it
+func (r *Relay) publish(ctx context.Context, rec record) error {
+	var carrier propagation.MapCarrier
+	if err := json.Unmarshal(rec.headers, &carrier); err == nil {
+		ctx = otel.GetTextMapPropagator().Extract(ctx, carrier)
+	}
+
+	attempt := rec.attempts + 1
+	typeAttr := attribute.String("event.type", rec.eventType)
+	ctx, span := r.tracer.Start(ctx, "outbox relay",
+		trace.WithAttributes(
+			attribute.Int64("outbox.id", rec.id),
+			attribute.Int("outbox.attempt", attempt),
+			typeAttr,
+		),
+	)
+	defer span.End()
+
+	var event events.UserEvent
+	err := json.Unmarshal(rec.payload, &event)
+	if err == nil {
+		err = r.publisher.PublishUserEvent(ctx, event)
+	}
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, "failed to relay event")
+		result := "failed"
+		if attempt >= r.cfg.MaxAttempts {
+			result = "abandoned"
+			logging.Module("outbox").LogError(ctx, err, "Abandoning outbox event", map[string]interface{}{
+				"outbox_id":  rec.id,
+				"event_type": rec.eventType,
+				"attempts":   attempt,
+			})
+		}
+		r.metrics.relayed.Add(ctx, 1, metric.WithAttributes(typeAttr, attribute.String("result", result)))
+		return fmt.Errorf("failed to relay outbox event %d: %w", rec.id, err)
+	}
+
+	r.metrics.lag.Record(ctx, time.Since(rec.createdAt.Time).Seconds(), metric.WithAttributes(typeAttr))
+	r.metrics.relayed.Add(ctx, 1, metric.WithAttributes(typeAttr, attribute.String("result", "sent")))
+	return nil
+}
+
+// recordPending updates outbox_pending_events
+func (r *Relay) recordPending(ctx context.Context) {
+	var pending int64
+	done := r.db.TrackQuery(ctx, "SELECT", "outbox", countPendingStatement)
+	err := r.db.QueryRowContext(ctx, countPendingStatement, r.cfg.MaxAttempts).Scan(&pending)
+	done(err)
+	if err != nil {
+		if !errors.Is(err, context.Canceled) {
+			logging.Module("outbox").LogError(ctx, err, "Failed to count pending outbox events", nil)
+		}
+		return
+	}
+	r.metrics.pending.Record(ctx, pending)
+}
+
+func truncate(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+	return s[:n]
+}
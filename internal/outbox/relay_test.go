@@ -0,0 +1,136 @@
+package outbox
+
+import (
+	"context"
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	"arquivolivre.com.br/otel/internal/database"
+	"arquivolivre.com.br/otel/internal/events"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+)
+
+type fakePublisher struct {
+	published []events.UserEvent
+	contexts  []trace.SpanContext
+	failOn    int
+}
+
+func (p *fakePublisher) PublishUserEvent(ctx context.Context, event events.UserEvent) error {
+	if event.UserID == p.failOn {
+		return errors.New("broker down")
+	}
+	p.published = append(p.published, event)
+	p.contexts = append(p.contexts, trace.SpanContextFromContext(ctx))
+	return nil
+}
+
+func (p *fakePublisher) Close() error { return nil }
+
+func withTracing(t *testing.T) (*sdktrace.TracerProvider, *tracetest.SpanRecorder) {
+	t.Helper()
+	recorder := tracetest.NewSpanRecorder()
+	tp := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+	prevTP, prevProp := otel.GetTracerProvider(), otel.GetTextMapPropagator()
+	otel.SetTracerProvider(tp)
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+	t.Cleanup(func() {
+		otel.SetTracerProvider(prevTP)
+		otel.SetTextMapPropagator(prevProp)
+	})
+	return tp, recorder
+}
+
+func TestInsertStoresEventWithTraceContext(t *testing.T) {
+	tp, _ := withTracing(t)
+	sqlDB, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer func() { _ = sqlDB.Close() }()
+
+	var headers []byte
+	mock.ExpectExec("INSERT INTO outbox").
+		WithArgs("user.created", sqlmock.AnyArg(), captureArg{&headers}).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+
+	ctx, span := tp.Tracer("test").Start(context.Background(), "request")
+	require.NoError(t, Insert(ctx, sqlDB, events.UserEvent{Type: events.UserCreated, UserID: 7}))
+	span.End()
+
+	var carrier map[string]string
+	require.NoError(t, json.Unmarshal(headers, &carrier))
+	assert.Contains(t, carrier["traceparent"], span.SpanContext().TraceID().String())
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestRelayBatchPublishesInOrderAndStopsAtFailure(t *testing.T) {
+	tp, recorder := withTracing(t)
+	sqlDB, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer func() { _ = sqlDB.Close() }()
+
+	// The first event continues the trace of the request that wrote it
+	ctx, request := tp.Tracer("test").Start(context.Background(), "request")
+	carrier := propagation.MapCarrier{}
+	otel.GetTextMapPropagator().Inject(ctx, carrier)
+	request.End()
+	headers, _ := json.Marshal(carrier)
+
+	payload := func(userID int) []byte {
+		data, _ := json.Marshal(events.UserEvent{Type: events.UserUpdated, UserID: userID})
+		return data
+	}
+	created := time.Now().Add(-time.Second)
+	mock.ExpectBegin()
+	mock.ExpectQuery("SELECT id, event_type, payload, headers, attempts, created_at FROM outbox").
+		WithArgs(3, 10).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "event_type", "payload", "headers", "attempts", "created_at"}).
+			AddRow(1, "user.updated", payload(1), headers, 0, created).
+			AddRow(2, "user.updated", payload(2), []byte("{}"), 2, created).
+			AddRow(3, "user.updated", payload(3), []byte("{}"), 0, created))
+	mock.ExpectExec("UPDATE outbox SET sent_at").WithArgs(1).WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec("UPDATE outbox SET attempts").WithArgs("failed to relay outbox event 2: broker down", 2).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	publisher := &fakePublisher{failOn: 2}
+	relay, err := NewRelay(&database.DB{DB: sqlDB}, publisher, Config{BatchSize: 10, MaxAttempts: 3})
+	require.NoError(t, err)
+
+	sent, err := relay.relayBatch(context.Background())
+	assert.Equal(t, 1, sent)
+	assert.ErrorContains(t, err, "broker down")
+	assert.NoError(t, mock.ExpectationsWereMet())
+
+	require.Len(t, publisher.published, 1)
+	assert.Equal(t, 1, publisher.published[0].UserID)
+	assert.Equal(t, request.SpanContext().TraceID(), publisher.contexts[0].TraceID())
+
+	var relaySpans []sdktrace.ReadOnlySpan
+	for _, s := range recorder.Ended() {
+		if s.Name() == "outbox relay" {
+			relaySpans = append(relaySpans, s)
+		}
+	}
+	require.Len(t, relaySpans, 2)
+	assert.Equal(t, request.SpanContext().SpanID(), relaySpans[0].Parent().SpanID())
+	assert.Equal(t, "Error", relaySpans[1].Status().Code.String())
+}
+
+// captureArg matches any argument and keeps it
+type captureArg struct{ value *[]byte }
+
+func (a captureArg) Match(v driver.Value) bool {
+	*a.value, _ = v.([]byte)
+	return true
+}
@@ -0,0 +1,160 @@
+// Package prober periodically exercises the API's own endpoints so
+// regressions show up in telemetry even when there is no real traffic
+package prober
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"arquivolivre.com.br/otel/pkg/client"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/baggage"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/metric"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// SyntheticKey marks spans and baggage of synthetic requests
+const SyntheticKey = "synthetic"
+
+// Names of the checks performed on each run
+const (
+	CheckList   = "list"
+	CheckGet    = "get"
+	CheckCanary = "canary"
+)
+
+// Canary users get an email of CanaryEmailPrefix, a timestamp and
+// CanaryEmailDomain, so canaries a failed run could not delete can be found
+// and cleaned up
+const (
+	CanaryEmailPrefix = "synthetic-canary-"
+	CanaryEmailDomain = "@example.com"
+)
+
+// IsCanaryEmail reports whether email is the email of a canary user
+func IsCanaryEmail(email string) bool {
+	return strings.HasPrefix(email, CanaryEmailPrefix) && strings.HasSuffix(email, CanaryEmailDomain)
+}
+
+// Prober runs the synthetic checks against an API base URL
+type Prober struct {
+	client *client.Client
+	tracer trace.Tracer
+
+	duration metric.Float64Histogram
+	checks   metric.Int64Counter
+}
+
+// New creates a prober for the API at baseURL. opts are applied to its
+// client after the probe's own, e.g. to send the credentials the API
+// requires for the canary writes
+func New(baseURL string, opts ...client.Option) (*Prober, error) {
+	apiClient, err := client.New(baseURL, append([]client.Option{
+		client.WithRetries(0, 0),
+		client.WithTimeout(5 * time.Second),
+		client.WithUserAgent("otel-example-synthetic-probe"),
+	}, opts...)...)
+	if err != nil {
+		return nil, err
+	}
+
+	meter := otel.Meter("prober")
+	duration, err := meter.Float64Histogram(
+		"synthetic_probe_duration_seconds",
+		metric.WithDescription("Duration of synthetic probe checks in seconds"),
+		metric.WithUnit("s"),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create probe duration metric: %w", err)
+	}
+	checks, err := meter.Int64Counter(
+		"synthetic_probe_checks_total",
+		metric.WithDescription("Total number of synthetic probe checks by result"),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create probe checks metric: %w", err)
+	}
+
+	return &Prober{
+		client:   apiClient,
+		tracer:   otel.Tracer("prober"),
+		duration: duration,
+		checks:   checks,
+	}, nil
+}
+
+// Run performs every check once and returns the failures joined together.
+// Requests carry synthetic=true baggage so the API can tag its own spans
+func (p *Prober) Run(ctx context.Context) error {
+	member, _ := baggage.NewMember(SyntheticKey, "true")
+	bag, _ := baggage.New(member)
+	ctx = baggage.ContextWithBaggage(ctx, bag)
+
+	var errs []error
+	userID, err := p.check(ctx, CheckList, p.list)
+	errs = append(errs, err)
+	if userID > 0 {
+		_, err = p.check(ctx, CheckGet, func(ctx context.Context) (int, error) {
+			_, err := p.client.GetUser(ctx, userID)
+			return userID, err
+		})
+		errs = append(errs, err)
+	}
+	_, err = p.check(ctx, CheckCanary, p.canary)
+	errs = append(errs, err)
+	return errors.Join(errs...)
+}
+
+func (p *Prober) check(ctx context.Context, name string, fn func(context.Context) (int, error)) (int, error) {
+	ctx, span := p.tracer.Start(ctx, "synthetic "+name, trace.WithAttributes(
+		attribute.Bool(SyntheticKey, true),
+		attribute.String("synthetic.check", name),
+	))
+	defer span.End()
+
+	start := time.Now()
+	id, err := fn(ctx)
+	result := "success"
+	if err != nil {
+		result = "failure"
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+		err = fmt.Errorf("synthetic %s check failed: %w", name, err)
+	}
+
+	attrs := metric.WithAttributes(attribute.String("check", name), attribute.String("result", result))
+	p.duration.Record(ctx, time.Since(start).Seconds(), attrs)
+	p.checks.Add(ctx, 1, attrs)
+	return id, err
+}
+
+// list returns the ID of the first user, if any, for the get check
+func (p *Prober) list(ctx context.Context) (int, error) {
+	page, err := p.client.ListUsers(ctx, client.ListOptions{Page: 1, Limit: 1})
+	if err != nil || len(page.Users) == 0 {
+		return 0, err
+	}
+	return page.Users[0].ID, nil
+}
+
+// canary creates a throwaway user, reads it back and deletes it
+func (p *Prober) canary(ctx context.Context) (int, error) {
+	user, err := p.client.CreateUser(ctx, client.CreateUserRequest{
+		Name:  "Synthetic Canary",
+		Email: fmt.Sprintf("%s%d%s", CanaryEmailPrefix, time.Now().UnixNano(), CanaryEmailDomain),
+	})
+	if err != nil {
+		return 0, err
+	}
+	if _, err := p.client.GetUser(ctx, user.ID); err != nil {
+		// Still try to clean up the canary
+		return user.ID, errors.Join(err, p.client.DeleteUser(ctx, user.ID))
+	}
+	return user.ID, p.client.DeleteUser(ctx, user.ID)
+}
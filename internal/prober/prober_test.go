@@ -0,0 +1,133 @@
+package prober
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"arquivolivre.com.br/otel/pkg/client"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+// fakeAPI serves just enough of the user API for the probe checks
+type fakeAPI struct {
+	mu       sync.Mutex
+	requests []string
+	baggage  []string
+	auth     []string
+	failGet  bool
+}
+
+func (f *fakeAPI) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	f.requests = append(f.requests, r.Method+" "+r.URL.Path)
+	f.baggage = append(f.baggage, r.Header.Get("Baggage"))
+	f.auth = append(f.auth, r.Header.Get("Authorization"))
+	failGet := f.failGet
+	f.mu.Unlock()
+
+	w.Header().Set("Content-Type", "application/json")
+	user := map[string]any{"id": 7, "name": "Ann", "email": "ann@example.com"}
+	switch {
+	case r.Method == http.MethodGet && r.URL.Path == "/api/users":
+		_ = json.NewEncoder(w).Encode(map[string]any{"data": []any{user}, "pagination": map[string]any{"page": 1, "limit": 1, "total": 1}})
+	case r.Method == http.MethodGet && failGet:
+		w.WriteHeader(http.StatusInternalServerError)
+		_ = json.NewEncoder(w).Encode(map[string]any{"success": false, "error": "boom"})
+	case r.Method == http.MethodGet:
+		_ = json.NewEncoder(w).Encode(map[string]any{"data": user})
+	case r.Method == http.MethodPost:
+		w.WriteHeader(http.StatusCreated)
+		_ = json.NewEncoder(w).Encode(map[string]any{"data": user})
+	case r.Method == http.MethodDelete:
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+func setupTracing(t *testing.T) *tracetest.SpanRecorder {
+	t.Helper()
+	recorder := tracetest.NewSpanRecorder()
+	prevTP, prevProp := otel.GetTracerProvider(), otel.GetTextMapPropagator()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)))
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(propagation.TraceContext{}, propagation.Baggage{}))
+	t.Cleanup(func() {
+		otel.SetTracerProvider(prevTP)
+		otel.SetTextMapPropagator(prevProp)
+	})
+	return recorder
+}
+
+func TestRunExercisesEndpoints(t *testing.T) {
+	recorder := setupTracing(t)
+	api := &fakeAPI{}
+	server := httptest.NewServer(api)
+	defer server.Close()
+
+	p, err := New(server.URL)
+	require.NoError(t, err)
+	require.NoError(t, p.Run(context.Background()))
+
+	assert.Equal(t, []string{
+		"GET /api/users",
+		"GET /api/users/7",
+		"POST /api/users",
+		"GET /api/users/7",
+		"DELETE /api/users/7",
+	}, api.requests)
+	for _, header := range api.baggage {
+		assert.Contains(t, header, "synthetic=true")
+	}
+
+	var checks []string
+	for _, span := range recorder.Ended() {
+		if !strings.HasPrefix(span.Name(), "synthetic ") {
+			continue
+		}
+		checks = append(checks, span.Name())
+		assert.Contains(t, span.Attributes(), attribute.Bool(SyntheticKey, true))
+	}
+	assert.ElementsMatch(t, []string{"synthetic list", "synthetic get", "synthetic canary"}, checks)
+}
+
+func TestRunCleansUpCanaryOnFailure(t *testing.T) {
+	setupTracing(t)
+	api := &fakeAPI{failGet: true}
+	server := httptest.NewServer(api)
+	defer server.Close()
+
+	p, err := New(server.URL)
+	require.NoError(t, err)
+	err = p.Run(context.Background())
+
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "synthetic get check failed")
+	assert.Contains(t, err.Error(), "synthetic canary check failed")
+	assert.Equal(t, "DELETE /api/users/7", api.requests[len(api.requests)-1])
+}
+
+func TestRunSendsCredentials(t *testing.T) {
+	setupTracing(t)
+	api := &fakeAPI{}
+	server := httptest.NewServer(api)
+	defer server.Close()
+
+	p, err := New(server.URL, client.WithBearerToken("probe-token"))
+	require.NoError(t, err)
+	require.NoError(t, p.Run(context.Background()))
+
+	require.Len(t, api.auth, 5)
+	for _, header := range api.auth {
+		assert.Equal(t, "Bearer probe-token", header)
+	}
+}
@@ -0,0 +1,68 @@
+package reports
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"arquivolivre.com.br/otel/internal/middleware"
+	"arquivolivre.com.br/otel/internal/operations"
+
+	"github.com/gin-gonic/gin"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Handler accepts report requests over HTTP
+type Handler struct {
+	operations *operations.Manager
+	generator  *Generator
+}
+
+// NewHandler creates a handler generating the reports on users as
+// operations of manager
+func NewHandler(manager *operations.Manager, users Users) *Handler {
+	return &Handler{operations: manager, generator: NewGenerator(users)}
+}
+
+// Register mounts POST /reports on root behind the writes middleware
+func (h *Handler) Register(root *gin.RouterGroup, writes []gin.HandlerFunc) {
+	root.Group("", writes...).POST("/reports", h.CreateReport)
+}
+
+// CreateReport starts the generation of a report and answers 202 Accepted
+// with its operation, whose status is at the Location of the response
+func (h *Handler) CreateReport(c *gin.Context) {
+	var req Request
+	if err := c.ShouldBindJSON(&req); err != nil {
+		_ = c.Error(middleware.BindError(err, c.GetHeader("Accept-Language")))
+		return
+	}
+	to := time.Now()
+	if req.To != nil {
+		to = *req.To
+	}
+	from := to.Add(-DefaultPeriod)
+	if req.From != nil {
+		from = *req.From
+	}
+	if !from.Before(to) {
+		_ = c.Error(middleware.BadRequestError("from must be before to"))
+		return
+	}
+	if to.Sub(from) > MaxPeriod {
+		_ = c.Error(middleware.BadRequestError(fmt.Sprintf("The period must be at most %d days", MaxPeriod/(24*time.Hour))))
+		return
+	}
+	trace.SpanFromContext(c.Request.Context()).SetAttributes(attribute.String("report.type", req.Type))
+
+	op, err := h.operations.Start(c.Request.Context(), "report."+req.Type, func(ctx context.Context) (any, error) {
+		return h.generator.UserSignups(ctx, from, to)
+	})
+	if err != nil {
+		_ = c.Error(middleware.FromError(err, "Failed to start report"))
+		return
+	}
+	operations.Accepted(c, strings.TrimSuffix(c.FullPath(), "/reports"), op, "Report accepted")
+}
@@ -0,0 +1,105 @@
+// Package reports generates reports on the users as async operations:
+// POST /api/reports answers 202 Accepted at once, and the report is the
+// result of the operation polled under /api/operations.
+package reports
+
+import (
+	"context"
+	"time"
+
+	"arquivolivre.com.br/otel/internal/models"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// TypeUserSignups counts the users created on each day of a period
+const TypeUserSignups = "user_signups"
+
+const (
+	// DefaultPeriod is the period of reports requested without a start
+	DefaultPeriod = 30 * 24 * time.Hour
+	// MaxPeriod bounds the period of a report
+	MaxPeriod = 366 * 24 * time.Hour
+)
+
+const dateLayout = "2006-01-02"
+
+// Users streams the users a report is generated from
+type Users interface {
+	Stream(ctx context.Context, filter models.UserFilter, fn func(*models.User) error) error
+}
+
+// Request asks for a report of Type on the period from From to To. To
+// defaults to now and From to DefaultPeriod before To
+type Request struct {
+	Type string     `json:"type" binding:"required,oneof=user_signups"`
+	From *time.Time `json:"from"`
+	To   *time.Time `json:"to"`
+}
+
+// SignupReport is the number of users created in a period, in total and
+// on each of its days in UTC
+type SignupReport struct {
+	From       time.Time    `json:"from"`
+	To         time.Time    `json:"to"`
+	TotalUsers int          `json:"total_users"`
+	Days       []DaySignups `json:"days"`
+}
+
+// DaySignups is the number of users created on Date
+type DaySignups struct {
+	Date  string `json:"date"`
+	Users int    `json:"users"`
+}
+
+// Generator generates the reports, each in a Report.<type> span
+type Generator struct {
+	users  Users
+	tracer trace.Tracer
+}
+
+// NewGenerator creates a generator of reports on users
+func NewGenerator(users Users) *Generator {
+	return &Generator{users: users, tracer: otel.Tracer("reports")}
+}
+
+// UserSignups counts the users created after from and before to. Every day
+// of the period is listed, with 0 when no user was created on it
+func (g *Generator) UserSignups(ctx context.Context, from, to time.Time) (*SignupReport, error) {
+	ctx, span := g.tracer.Start(ctx, "Report.UserSignups", trace.WithAttributes(
+		attribute.String("report.type", TypeUserSignups),
+		attribute.String("report.from", from.UTC().Format(time.RFC3339)),
+		attribute.String("report.to", to.UTC().Format(time.RFC3339)),
+	))
+	defer span.End()
+
+	report := &SignupReport{From: from.UTC(), To: to.UTC(), Days: []DaySignups{}}
+	index := map[string]int{}
+	for day := report.From.Truncate(24 * time.Hour); day.Before(report.To); day = day.Add(24 * time.Hour) {
+		date := day.Format(dateLayout)
+		index[date] = len(report.Days)
+		report.Days = append(report.Days, DaySignups{Date: date})
+	}
+
+	err := g.users.Stream(ctx, models.UserFilter{CreatedAfter: from, CreatedBefore: to}, func(user *models.User) error {
+		if i, ok := index[user.CreatedAt.UTC().Format(dateLayout)]; ok {
+			report.Days[i].Users++
+		}
+		report.TotalUsers++
+		return nil
+	})
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+		return nil, err
+	}
+
+	span.SetAttributes(
+		attribute.Int("report.days", len(report.Days)),
+		attribute.Int("report.users", report.TotalUsers),
+	)
+	return report, nil
+}
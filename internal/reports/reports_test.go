@@ -0,0 +1,133 @@
+package reports
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"arquivolivre.com.br/otel/internal/jobs"
+	"arquivolivre.com.br/otel/internal/middleware"
+	"arquivolivre.com.br/otel/internal/models"
+	"arquivolivre.com.br/otel/internal/operations"
+	"arquivolivre.com.br/otel/pkg/oteltest"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/attribute"
+)
+
+// users streams users created at the given times, whatever the filter
+type users struct {
+	created []time.Time
+	filter  models.UserFilter
+}
+
+func (u *users) Stream(_ context.Context, filter models.UserFilter, fn func(*models.User) error) error {
+	u.filter = filter
+	for i, created := range u.created {
+		if err := fn(&models.User{ID: i + 1, CreatedAt: models.NewTimestamp(created)}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func TestUserSignups(t *testing.T) {
+	rec := oteltest.Install(t)
+	from := time.Date(2026, 10, 1, 12, 0, 0, 0, time.UTC)
+	to := time.Date(2026, 10, 4, 6, 0, 0, 0, time.UTC)
+	store := &users{created: []time.Time{
+		time.Date(2026, 10, 1, 13, 0, 0, 0, time.UTC),
+		time.Date(2026, 10, 3, 1, 0, 0, 0, time.UTC),
+		time.Date(2026, 10, 3, 23, 0, 0, 0, time.UTC),
+	}}
+
+	report, err := NewGenerator(store).UserSignups(context.Background(), from, to)
+	require.NoError(t, err)
+	assert.Equal(t, models.UserFilter{CreatedAfter: from, CreatedBefore: to}, store.filter)
+	assert.Equal(t, 3, report.TotalUsers)
+	// Days without users are listed too
+	assert.Equal(t, []DaySignups{
+		{Date: "2026-10-01", Users: 1},
+		{Date: "2026-10-02", Users: 0},
+		{Date: "2026-10-03", Users: 2},
+		{Date: "2026-10-04", Users: 0},
+	}, report.Days)
+	rec.AssertSpan(t, "Report.UserSignups",
+		attribute.String("report.type", TypeUserSignups),
+		attribute.Int("report.days", 4),
+		attribute.Int("report.users", 3),
+	)
+}
+
+func TestCreateReport(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	rec := oteltest.Install(t)
+	queue, err := jobs.NewQueue(jobs.Config{Workers: 1})
+	require.NoError(t, err)
+	queue.Start()
+	defer func() { _ = queue.Shutdown(context.Background()) }()
+	manager := operations.NewManager(queue, time.Hour)
+
+	r := gin.New()
+	r.Use(middleware.NewTelemetryMiddleware("test-service").GinMiddleware())
+	r.Use(middleware.ErrorHandler())
+	api := r.Group("/api")
+	store := &users{created: []time.Time{time.Now().Add(-time.Hour)}}
+	NewHandler(manager, store).Register(api, nil)
+	operations.NewHandler(manager).Register(api, nil)
+
+	post := func(body string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodPost, "/api/reports", strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		r.ServeHTTP(w, req)
+		return w
+	}
+
+	for _, body := range []string{
+		`{}`,
+		`{"type":"user_logins"}`,
+		`{"type":"user_signups","from":"2026-10-02T00:00:00Z","to":"2026-10-01T00:00:00Z"}`,
+		`{"type":"user_signups","from":"2024-01-01T00:00:00Z","to":"2026-01-01T00:00:00Z"}`,
+	} {
+		w := post(body)
+		assert.Equal(t, http.StatusBadRequest, w.Code, body)
+	}
+
+	w := post(`{"type":"user_signups"}`)
+	require.Equal(t, http.StatusAccepted, w.Code, w.Body.String())
+	location := w.Header().Get("Location")
+	assert.True(t, strings.HasPrefix(location, "/api/operations/"), location)
+	rec.AssertSpan(t, "POST /api/reports", attribute.String("report.type", TypeUserSignups))
+
+	var resp struct {
+		Data struct {
+			Status string        `json:"status"`
+			Result *SignupReport `json:"result"`
+		} `json:"data"`
+	}
+	require.Eventually(t, func() bool {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, location, nil))
+		require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+		return resp.Data.Status == string(operations.StatusSucceeded)
+	}, time.Second, 5*time.Millisecond)
+	require.NotNil(t, resp.Data.Result)
+	assert.Equal(t, 1, resp.Data.Result.TotalUsers)
+	assert.Len(t, resp.Data.Result.Days, 31)
+	assert.Equal(t, DefaultPeriod, resp.Data.Result.To.Sub(resp.Data.Result.From))
+
+	// The report is generated below the span of its job
+	require.Eventually(t, func() bool {
+		_, ok := rec.Span("job report.user_signups")
+		return ok
+	}, time.Second, 5*time.Millisecond)
+	rec.AssertChild(t, "Report.UserSignups", "job report.user_signups")
+}
@@ -0,0 +1,169 @@
+package repository
+
+import (
+	"context"
+	"strconv"
+	"strings"
+	"time"
+
+	"arquivolivre.com.br/otel/internal/logging"
+	"arquivolivre.com.br/otel/internal/models"
+	"arquivolivre.com.br/otel/pkg/cache"
+)
+
+// Cache keys of the user entries
+const (
+	userCountKey       = "users:count"
+	userIDKeyPrefix    = "users:id:"
+	userEmailKeyPrefix = "users:email:"
+)
+
+// CachedUserStore caches single-user lookups and the user count of another
+// UserStore. Writes invalidate the entries they affect, and the TTL bounds
+// how long changes made by other instances stay invisible. Cache failures
+// are logged and the request falls through to the wrapped store
+type CachedUserStore struct {
+	next  UserStore
+	cache cache.Cache
+	ttl   time.Duration
+}
+
+var _ UserStore = (*CachedUserStore)(nil)
+
+// NewCachedUserStore wraps next with c; entries expire after ttl
+func NewCachedUserStore(next UserStore, c cache.Cache, ttl time.Duration) *CachedUserStore {
+	return &CachedUserStore{next: next, cache: c, ttl: ttl}
+}
+
+func userIDKey(id int) string {
+	return userIDKeyPrefix + strconv.Itoa(id)
+}
+
+func userEmailKey(email string) string {
+	return userEmailKeyPrefix + strings.ToLower(email)
+}
+
+// GetAll is not cached: pages shift with every write
+func (s *CachedUserStore) GetAll(ctx context.Context, filter models.UserFilter, limit, offset int) ([]models.User, error) {
+	return s.next.GetAll(ctx, filter, limit, offset)
+}
+
+// Stream is not cached either
+func (s *CachedUserStore) Stream(ctx context.Context, filter models.UserFilter, fn func(*models.User) error) error {
+	return s.next.Stream(ctx, filter, fn)
+}
+
+func (s *CachedUserStore) GetByID(ctx context.Context, id int) (*models.User, error) {
+	return cached(ctx, s, userIDKey(id), func() (*models.User, error) {
+		return s.next.GetByID(ctx, id)
+	})
+}
+
+func (s *CachedUserStore) GetByEmail(ctx context.Context, email string) (*models.User, error) {
+	return cached(ctx, s, userEmailKey(email), func() (*models.User, error) {
+		return s.next.GetByEmail(ctx, email)
+	})
+}
+
+// Count caches the total only; filtered counts go to the wrapped store
+func (s *CachedUserStore) Count(ctx context.Context, filter models.UserFilter) (int, error) {
+	if !filter.IsZero() {
+		return s.next.Count(ctx, filter)
+	}
+	count, err := cached(ctx, s, userCountKey, func() (*int, error) {
+		count, err := s.next.Count(ctx, filter)
+		return &count, err
+	})
+	if err != nil {
+		return 0, err
+	}
+	return *count, nil
+}
+
+func (s *CachedUserStore) Create(ctx context.Context, req models.CreateUserRequest) (*models.User, error) {
+	user, err := s.next.Create(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	// A lookup of the email before the user existed may have been cached
+	s.invalidate(ctx, userCountKey, userEmailKey(user.Email))
+	return user, nil
+}
+
+func (s *CachedUserStore) CreateBatch(ctx context.Context, reqs []models.CreateUserRequest) ([]BatchResult, error) {
+	results, err := s.next.CreateBatch(ctx, reqs)
+	if err != nil {
+		return nil, err
+	}
+	keys := []string{userCountKey}
+	for _, result := range results {
+		if result.User != nil {
+			keys = append(keys, userEmailKey(result.User.Email))
+		}
+	}
+	s.invalidate(ctx, keys...)
+	return results, nil
+}
+
+func (s *CachedUserStore) Update(ctx context.Context, id int, req models.UpdateUserRequest) (*models.User, error) {
+	keys := []string{userIDKey(id)}
+	if previous, err := s.GetByID(ctx, id); err == nil {
+		keys = append(keys, userEmailKey(previous.Email))
+	}
+
+	user, err := s.next.Update(ctx, id, req)
+	if err != nil {
+		return nil, err
+	}
+	s.invalidate(ctx, append(keys, userEmailKey(user.Email))...)
+	return user, nil
+}
+
+func (s *CachedUserStore) Delete(ctx context.Context, id int) error {
+	keys := []string{userIDKey(id), userCountKey}
+	if previous, err := s.GetByID(ctx, id); err == nil {
+		keys = append(keys, userEmailKey(previous.Email))
+	}
+
+	if err := s.next.Delete(ctx, id); err != nil {
+		return err
+	}
+	s.invalidate(ctx, keys...)
+	return nil
+}
+
+func (s *CachedUserStore) invalidate(ctx context.Context, keys ...string) {
+	if err := s.cache.Delete(ctx, keys...); err != nil {
+		logging.LogWarn(ctx, "Failed to invalidate user cache entries", map[string]interface{}{
+			"keys":  keys,
+			"error": err.Error(),
+		})
+	}
+}
+
+// cached returns the value under key, or loads it and stores it for s.ttl.
+// Load errors, including not found, are returned without being cached
+func cached[T any](ctx context.Context, s *CachedUserStore, key string, load func() (*T, error)) (*T, error) {
+	value, found, err := cache.GetJSON[T](ctx, s.cache, key)
+	if err != nil {
+		logging.LogWarn(ctx, "Failed to read user cache", map[string]interface{}{
+			"key":   key,
+			"error": err.Error(),
+		})
+	}
+	if found {
+		return &value, nil
+	}
+
+	loaded, err := load()
+	if err != nil {
+		return nil, err
+	}
+	if err := cache.SetJSON(ctx, s.cache, key, *loaded, s.ttl); err != nil {
+		logging.LogWarn(ctx, "Failed to write user cache", map[string]interface{}{
+			"key":   key,
+			"error": err.Error(),
+		})
+	}
+	return loaded, nil
+}
@@ -0,0 +1,199 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"arquivolivre.com.br/otel/internal/models"
+	"arquivolivre.com.br/otel/pkg/apperrors"
+	"arquivolivre.com.br/otel/pkg/cache"
+)
+
+// fakeUserStore keeps users in a map and counts the lookups reaching it
+type fakeUserStore struct {
+	users   map[int]models.User
+	nextID  int
+	lookups int
+}
+
+func newFakeUserStore() *fakeUserStore {
+	return &fakeUserStore{users: map[int]models.User{}, nextID: 1}
+}
+
+func (f *fakeUserStore) GetAll(context.Context, models.UserFilter, int, int) ([]models.User, error) {
+	users := make([]models.User, 0, len(f.users))
+	for _, u := range f.users {
+		users = append(users, u)
+	}
+	return users, nil
+}
+
+func (f *fakeUserStore) Stream(ctx context.Context, filter models.UserFilter, fn func(*models.User) error) error {
+	users, _ := f.GetAll(ctx, filter, len(f.users), 0)
+	for i := range users {
+		if err := fn(&users[i]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (f *fakeUserStore) GetByID(_ context.Context, id int) (*models.User, error) {
+	f.lookups++
+	u, ok := f.users[id]
+	if !ok {
+		return nil, fmt.Errorf("user %d: %w", id, apperrors.ErrNotFound)
+	}
+	return &u, nil
+}
+
+func (f *fakeUserStore) GetByEmail(_ context.Context, email string) (*models.User, error) {
+	f.lookups++
+	for _, u := range f.users {
+		if u.Email == email {
+			return &u, nil
+		}
+	}
+	return nil, fmt.Errorf("user %s: %w", email, apperrors.ErrNotFound)
+}
+
+func (f *fakeUserStore) Count(context.Context, models.UserFilter) (int, error) {
+	f.lookups++
+	return len(f.users), nil
+}
+
+func (f *fakeUserStore) Create(_ context.Context, req models.CreateUserRequest) (*models.User, error) {
+	u := models.User{ID: f.nextID, Name: req.Name, Email: req.Email}
+	f.users[u.ID] = u
+	f.nextID++
+	return &u, nil
+}
+
+func (f *fakeUserStore) CreateBatch(ctx context.Context, reqs []models.CreateUserRequest) ([]BatchResult, error) {
+	results := make([]BatchResult, len(reqs))
+	for i, req := range reqs {
+		results[i].User, results[i].Err = f.Create(ctx, req)
+	}
+	return results, nil
+}
+
+func (f *fakeUserStore) Update(_ context.Context, id int, req models.UpdateUserRequest) (*models.User, error) {
+	u, ok := f.users[id]
+	if !ok {
+		return nil, fmt.Errorf("user %d: %w", id, apperrors.ErrNotFound)
+	}
+	if req.Name != nil {
+		u.Name = *req.Name
+	}
+	if req.Email != nil {
+		u.Email = *req.Email
+	}
+	f.users[id] = u
+	return &u, nil
+}
+
+func (f *fakeUserStore) Delete(_ context.Context, id int) error {
+	if _, ok := f.users[id]; !ok {
+		return fmt.Errorf("user %d: %w", id, apperrors.ErrNotFound)
+	}
+	delete(f.users, id)
+	return nil
+}
+
+func newTestCachedStore(t *testing.T) (*CachedUserStore, *fakeUserStore) {
+	t.Helper()
+	next := newFakeUserStore()
+	c := cache.NewMemory(cache.MemoryOptions{})
+	t.Cleanup(func() { _ = c.Close() })
+	return NewCachedUserStore(next, c, time.Minute), next
+}
+
+func TestCachedUserStore_ServesRepeatedLookupsFromCache(t *testing.T) {
+	ctx := context.Background()
+	store, next := newTestCachedStore(t)
+	created, _ := store.Create(ctx, models.CreateUserRequest{Name: "Alice", Email: "alice@example.com"})
+
+	for i := 0; i < 3; i++ {
+		u, err := store.GetByID(ctx, created.ID)
+		if err != nil || u.Email != "alice@example.com" {
+			t.Fatalf("GetByID = %v, %v", u, err)
+		}
+		if u, err := store.GetByEmail(ctx, "alice@example.com"); err != nil || u.ID != created.ID {
+			t.Fatalf("GetByEmail = %v, %v", u, err)
+		}
+		if count, err := store.Count(ctx, models.UserFilter{}); err != nil || count != 1 {
+			t.Fatalf("Count = %d, %v", count, err)
+		}
+	}
+	if next.lookups != 3 {
+		t.Fatalf("expected one load per key, got %d", next.lookups)
+	}
+}
+
+func TestCachedUserStore_DoesNotCacheNotFound(t *testing.T) {
+	ctx := context.Background()
+	store, _ := newTestCachedStore(t)
+
+	if _, err := store.GetByEmail(ctx, "bob@example.com"); err == nil {
+		t.Fatalf("expected not found")
+	}
+	if _, err := store.Create(ctx, models.CreateUserRequest{Name: "Bob", Email: "bob@example.com"}); err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+	u, err := store.GetByEmail(ctx, "bob@example.com")
+	if err != nil || u.Name != "Bob" {
+		t.Fatalf("GetByEmail = %v, %v", u, err)
+	}
+}
+
+func TestCachedUserStore_InvalidatesOnWrites(t *testing.T) {
+	ctx := context.Background()
+	store, _ := newTestCachedStore(t)
+	created, _ := store.Create(ctx, models.CreateUserRequest{Name: "Carol", Email: "carol@example.com"})
+
+	// Warm the cache
+	_, _ = store.GetByID(ctx, created.ID)
+	_, _ = store.GetByEmail(ctx, "carol@example.com")
+	_, _ = store.Count(ctx, models.UserFilter{})
+
+	name, email := "Caroline", "caroline@example.com"
+	if _, err := store.Update(ctx, created.ID, models.UpdateUserRequest{Name: &name, Email: &email}); err != nil {
+		t.Fatalf("Update: %v", err)
+	}
+	if u, _ := store.GetByID(ctx, created.ID); u == nil || u.Name != name {
+		t.Fatalf("expected updated user by ID, got %v", u)
+	}
+	if _, err := store.GetByEmail(ctx, "carol@example.com"); err == nil {
+		t.Fatalf("expected the old email to miss after update")
+	}
+
+	if err := store.Delete(ctx, created.ID); err != nil {
+		t.Fatalf("Delete: %v", err)
+	}
+	if _, err := store.GetByID(ctx, created.ID); err == nil {
+		t.Fatalf("expected deleted user to miss")
+	}
+	if _, err := store.GetByEmail(ctx, email); err == nil {
+		t.Fatalf("expected deleted user's email to miss")
+	}
+	if count, _ := store.Count(ctx, models.UserFilter{}); count != 0 {
+		t.Fatalf("expected count 0 after delete, got %d", count)
+	}
+}
+
+func TestCachedUserStore_DoesNotCacheFilteredCounts(t *testing.T) {
+	ctx := context.Background()
+	store, next := newTestCachedStore(t)
+
+	filter := models.UserFilter{Query: "ann"}
+	for i := 0; i < 2; i++ {
+		if _, err := store.Count(ctx, filter); err != nil {
+			t.Fatalf("Count: %v", err)
+		}
+	}
+	if next.lookups != 2 {
+		t.Fatalf("expected every filtered count to reach the store, got %d", next.lookups)
+	}
+}
@@ -0,0 +1,54 @@
+package repository
+
+import (
+	"context"
+	"errors"
+
+	"arquivolivre.com.br/otel/internal/database"
+	"arquivolivre.com.br/otel/pkg/apperrors"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// instrumentedQuery runs fn as the repository operation name, such as
+// "UserRepository.GetByID", in a span of its own and under the query timeout
+// of db. The span describes operation on table and, once fn returns, whether
+// the query succeeded, see startQuery. fn adds its own attributes to span
+func instrumentedQuery[T any](ctx context.Context, tracer trace.Tracer, db *database.DB, name, operation, table string,
+	fn func(ctx context.Context, span trace.Span) (T, error)) (T, error) {
+	ctx, span, end := startQuery(ctx, tracer, name, operation, table)
+	defer span.End()
+	ctx, cancel := db.WithQueryTimeout(ctx)
+	defer cancel()
+
+	result, err := fn(ctx, span)
+	end(err)
+	return result, err
+}
+
+// instrumentedExec is instrumentedQuery for operations that only return an
+// error
+func instrumentedExec(ctx context.Context, tracer trace.Tracer, db *database.DB, name, operation, table string,
+	fn func(ctx context.Context, span trace.Span) error) error {
+	_, err := instrumentedQuery(ctx, tracer, db, name, operation, table, func(ctx context.Context, span trace.Span) (struct{}, error) {
+		return struct{}{}, fn(ctx, span)
+	})
+	return err
+}
+
+// startQuery starts the span of the repository operation name, describing
+// operation on table, for operations the query timeout does not apply to.
+// The returned function records whether the query succeeded and must be
+// called before the span ends: a query that finds nothing or hits a conflict
+// succeeded, since the database answered it
+func startQuery(ctx context.Context, tracer trace.Tracer, name, operation, table string) (context.Context, trace.Span, func(err error)) {
+	ctx, span := tracer.Start(ctx, name, trace.WithAttributes(
+		attribute.String("db.operation", operation),
+		attribute.String("db.table", table),
+	))
+	return ctx, span, func(err error) {
+		success := err == nil || errors.Is(err, apperrors.ErrNotFound) || errors.Is(err, apperrors.ErrConflict)
+		span.SetAttributes(attribute.Bool("db.query.success", success))
+	}
+}
@@ -0,0 +1,58 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"regexp"
+	"testing"
+
+	"arquivolivre.com.br/otel/internal/models"
+	"arquivolivre.com.br/otel/pkg/oteltest"
+
+	sqlmock "github.com/DATA-DOG/go-sqlmock"
+	"github.com/go-sql-driver/mysql"
+	"go.opentelemetry.io/otel/attribute"
+)
+
+func TestRepositorySpans_RecordUniformAttributes(t *testing.T) {
+	db, mock, cleanup := newTestDB(t)
+	defer cleanup()
+	rec := oteltest.Install(t)
+	users := NewUserRepository(db)
+	posts := NewPostRepository(db)
+
+	mock.ExpectQuery(regexp.QuoteMeta(`WHERE id = ?`)).WithArgs(1).WillReturnRows(sqlmock.NewRows(userColumns))
+	mock.ExpectExec(regexp.QuoteMeta(`INSERT INTO users`)).
+		WillReturnError(&mysql.MySQLError{Number: mysqlErrDuplicateEntry, Message: "Duplicate entry"})
+	mock.ExpectQuery(regexp.QuoteMeta(`SELECT COUNT(*) FROM posts`)).WillReturnError(errors.New("connection reset"))
+
+	if _, err := users.GetByID(context.Background(), 1); err == nil {
+		t.Fatal("expected user 1 to be not found")
+	}
+	if _, err := users.Create(context.Background(), models.CreateUserRequest{Name: "A", Email: "a@example.com"}); err == nil {
+		t.Fatal("expected a conflict on a duplicate email")
+	}
+	if _, err := posts.Count(context.Background(), 0); err == nil {
+		t.Fatal("expected the count to fail")
+	}
+
+	// Finding nothing and hitting a conflict are answers, not failures
+	rec.AssertSpan(t, "UserRepository.GetByID",
+		attribute.String("db.operation", "SELECT"),
+		attribute.String("db.table", "users"),
+		attribute.Bool("db.query.success", true),
+	)
+	rec.AssertSpan(t, "UserRepository.Create",
+		attribute.String("db.operation", "INSERT"),
+		attribute.String("db.table", "users"),
+		attribute.Bool("db.query.success", true),
+	)
+	rec.AssertSpan(t, "PostRepository.Count",
+		attribute.String("db.operation", "SELECT"),
+		attribute.String("db.table", "posts"),
+		attribute.Bool("db.query.success", false),
+	)
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("expectations: %v", err)
+	}
+}
@@ -0,0 +1,250 @@
+package repository
+
+import (
+	"cmp"
+	"context"
+	"slices"
+	"time"
+
+	"arquivolivre.com.br/otel/internal/auth"
+	"arquivolivre.com.br/otel/internal/models"
+	"arquivolivre.com.br/otel/pkg/apperrors"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// InMemoryPostStore is the PostStore of the posts of an InMemoryUserStore,
+// see InMemoryUserStore.Posts
+type InMemoryPostStore struct {
+	tables  *memoryTables
+	tracer  trace.Tracer
+	metrics *memoryMetrics
+}
+
+var _ PostStore = (*InMemoryPostStore)(nil)
+
+// start starts the span of operation on the posts table
+func (s *InMemoryPostStore) start(ctx context.Context, name, operation string) (context.Context, trace.Span) {
+	ctx, span := s.tracer.Start(ctx, "InMemoryPostStore."+name)
+	span.SetAttributes(
+		dbSystemMemory,
+		attribute.String("db.operation", operation),
+		attribute.String("db.table", "posts"),
+	)
+	return ctx, span
+}
+
+// GetAll returns a page of posts, newest first
+func (s *InMemoryPostStore) GetAll(ctx context.Context, userID, limit, offset int) ([]models.Post, error) {
+	ctx, span := s.start(ctx, "GetAll", "SELECT")
+	defer span.End()
+	span.SetAttributes(
+		attribute.Int("pagination.limit", limit),
+		attribute.Int("pagination.offset", offset),
+	)
+	if userID != 0 {
+		span.SetAttributes(attribute.Int("user.id", userID))
+	}
+
+	start := time.Now()
+	s.tables.mu.RLock()
+	posts := s.tables.postsOf(userID)
+	s.tables.mu.RUnlock()
+	slices.SortFunc(posts, func(a, b models.Post) int {
+		return cmp.Or(b.CreatedAt.Compare(a.CreatedAt.Time), cmp.Compare(b.ID, a.ID))
+	})
+	posts = posts[min(max(offset, 0), len(posts)):]
+	posts = posts[:min(max(limit, 0), len(posts))]
+	s.metrics.record(ctx, "SELECT", "posts", start, nil)
+
+	span.SetAttributes(
+		attribute.Int("result.count", len(posts)),
+		attribute.Bool("db.query.success", true),
+	)
+	return posts, nil
+}
+
+// Count returns the number of posts of userID, or of every user when it is 0
+func (s *InMemoryPostStore) Count(ctx context.Context, userID int) (int, error) {
+	ctx, span := s.start(ctx, "Count", "SELECT")
+	defer span.End()
+
+	start := time.Now()
+	s.tables.mu.RLock()
+	count := len(s.tables.postsOf(userID))
+	s.tables.mu.RUnlock()
+	s.metrics.record(ctx, "SELECT", "posts", start, nil)
+
+	span.SetAttributes(attribute.Int("result.count", count))
+	return count, nil
+}
+
+func (s *InMemoryPostStore) GetByID(ctx context.Context, id int) (*models.Post, error) {
+	ctx, span := s.start(ctx, "GetByID", "SELECT")
+	defer span.End()
+	span.SetAttributes(attribute.Int("post.id", id))
+
+	start := time.Now()
+	s.tables.mu.RLock()
+	post, ok := s.tables.posts[id]
+	s.tables.mu.RUnlock()
+	s.metrics.record(ctx, "SELECT", "posts", start, nil)
+
+	span.SetAttributes(
+		attribute.Bool("post.found", ok),
+		attribute.Bool("db.query.success", true),
+	)
+	if !ok {
+		return nil, apperrors.NotFound("post not found")
+	}
+	return &post, nil
+}
+
+// GetWithAuthor returns a post along with the user who wrote it
+func (s *InMemoryPostStore) GetWithAuthor(ctx context.Context, id int) (*models.Post, *models.User, error) {
+	ctx, span := s.start(ctx, "GetWithAuthor", "SELECT")
+	defer span.End()
+	span.SetAttributes(
+		attribute.Int("post.id", id),
+		attribute.String("db.join", "users"),
+	)
+
+	start := time.Now()
+	s.tables.mu.RLock()
+	post, ok := s.tables.posts[id]
+	author, found := s.tables.users[post.UserID]
+	s.tables.mu.RUnlock()
+	s.metrics.record(ctx, "SELECT", "posts", start, nil)
+
+	if !ok || !found {
+		span.SetAttributes(
+			attribute.Bool("post.found", false),
+			attribute.Bool("db.query.success", true),
+		)
+		return nil, nil, apperrors.NotFound("post not found")
+	}
+	span.SetAttributes(
+		attribute.Bool("post.found", true),
+		attribute.Int("user.id", author.ID),
+		attribute.Bool("db.query.success", true),
+	)
+	return &post, cloneUser(author), nil
+}
+
+func (s *InMemoryPostStore) Create(ctx context.Context, req models.CreatePostRequest) (*models.Post, error) {
+	ctx, span := s.start(ctx, "Create", "INSERT")
+	defer span.End()
+
+	actor := auth.Actor(ctx)
+	span.SetAttributes(
+		attribute.Int("user.id", req.UserID),
+		attribute.Int("post.title_length", len(req.Title)),
+		attribute.String("enduser.id", actor),
+	)
+
+	start := time.Now()
+	s.tables.mu.Lock()
+	defer s.tables.mu.Unlock()
+
+	if _, ok := s.tables.users[req.UserID]; !ok {
+		err := apperrors.NotFound("user not found")
+		s.metrics.record(ctx, "INSERT", "posts", start, err)
+		span.SetAttributes(attribute.Bool("db.query.success", false))
+		return nil, err
+	}
+	s.tables.lastPostID++
+	now := models.Now()
+	post := models.Post{
+		ID:        s.tables.lastPostID,
+		UserID:    req.UserID,
+		Title:     req.Title,
+		Body:      req.Body,
+		CreatedBy: actor,
+		UpdatedBy: actor,
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+	s.tables.posts[post.ID] = post
+	s.metrics.record(ctx, "INSERT", "posts", start, nil)
+
+	span.SetAttributes(
+		attribute.Int("post.id", post.ID),
+		attribute.Bool("db.query.success", true),
+	)
+	return &post, nil
+}
+
+// Update updates the title and body of an existing post
+func (s *InMemoryPostStore) Update(ctx context.Context, id int, req models.UpdatePostRequest) (*models.Post, error) {
+	ctx, span := s.start(ctx, "Update", "UPDATE")
+	defer span.End()
+
+	actor := auth.Actor(ctx)
+	span.SetAttributes(
+		attribute.Int("post.id", id),
+		attribute.String("enduser.id", actor),
+	)
+
+	start := time.Now()
+	s.tables.mu.Lock()
+	defer s.tables.mu.Unlock()
+
+	post, ok := s.tables.posts[id]
+	if !ok {
+		s.metrics.record(ctx, "UPDATE", "posts", start, nil)
+		return nil, apperrors.NotFound("post not found")
+	}
+	if req.Title == nil && req.Body == nil {
+		s.metrics.record(ctx, "UPDATE", "posts", start, nil)
+		span.SetAttributes(attribute.Bool("post.no_changes", true))
+		return &post, nil
+	}
+	if req.Title != nil {
+		post.Title = *req.Title
+	}
+	if req.Body != nil {
+		post.Body = *req.Body
+	}
+	post.UpdatedBy = actor
+	post.UpdatedAt = models.Now()
+	s.tables.posts[id] = post
+	s.metrics.record(ctx, "UPDATE", "posts", start, nil)
+
+	return &post, nil
+}
+
+// Delete deletes a post by ID
+func (s *InMemoryPostStore) Delete(ctx context.Context, id int) error {
+	ctx, span := s.start(ctx, "Delete", "DELETE")
+	defer span.End()
+	span.SetAttributes(
+		attribute.Int("post.id", id),
+		attribute.String("enduser.id", auth.Actor(ctx)),
+	)
+
+	start := time.Now()
+	s.tables.mu.Lock()
+	_, ok := s.tables.posts[id]
+	delete(s.tables.posts, id)
+	s.tables.mu.Unlock()
+	s.metrics.record(ctx, "DELETE", "posts", start, nil)
+
+	if !ok {
+		return apperrors.NotFound("post not found")
+	}
+	span.SetAttributes(attribute.Bool("post.deleted", true))
+	return nil
+}
+
+// postsOf returns copies of the posts of userID, or of every user when it is
+// 0, in no particular order. The caller holds mu
+func (t *memoryTables) postsOf(userID int) []models.Post {
+	var posts []models.Post
+	for _, post := range t.posts {
+		if userID == 0 || post.UserID == userID {
+			posts = append(posts, post)
+		}
+	}
+	return posts
+}
@@ -0,0 +1,455 @@
+package repository
+
+import (
+	"cmp"
+	"context"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"arquivolivre.com.br/otel/internal/auth"
+	"arquivolivre.com.br/otel/internal/models"
+	"arquivolivre.com.br/otel/pkg/apperrors"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// dbSystemMemory is the db.system of the spans and query metrics of the
+// in-memory stores
+var dbSystemMemory = attribute.String("db.system", "memory")
+
+// memoryTables holds the rows of the in-memory stores. Users and posts share
+// it so deleting a user deletes its posts, like the posts.user_id foreign key
+type memoryTables struct {
+	mu         sync.RWMutex
+	users      map[int]models.User
+	posts      map[int]models.Post
+	lastUserID int
+	lastPostID int
+}
+
+// memoryMetrics records the db.query.* metrics of UserRepository for the
+// in-memory stores, with db.system=memory
+type memoryMetrics struct {
+	duration metric.Float64Histogram
+	count    metric.Int64Counter
+	errors   metric.Int64Counter
+}
+
+func newMemoryMetrics() *memoryMetrics {
+	meter := otel.Meter("database")
+	duration, _ := meter.Float64Histogram(
+		"db.query.duration",
+		metric.WithDescription("Database query duration in seconds"),
+		metric.WithUnit("s"),
+	)
+	count, _ := meter.Int64Counter(
+		"db.query.count",
+		metric.WithDescription("Total number of database queries"),
+	)
+	errs, _ := meter.Int64Counter(
+		"db.query.errors",
+		metric.WithDescription("Total number of database query errors"),
+	)
+	return &memoryMetrics{duration: duration, count: count, errors: errs}
+}
+
+// record records an operation on table that started at start
+func (m *memoryMetrics) record(ctx context.Context, operation, table string, start time.Time, err error) {
+	attrs := []attribute.KeyValue{
+		dbSystemMemory,
+		attribute.String("db.operation", operation),
+		attribute.String("db.table", table),
+	}
+
+	m.duration.Record(ctx, time.Since(start).Seconds(), metric.WithAttributes(attrs...))
+	m.count.Add(ctx, 1, metric.WithAttributes(attrs...))
+	if err != nil {
+		m.errors.Add(ctx, 1, metric.WithAttributes(append(attrs, attribute.String("error.type", "query_failed"))...))
+	}
+}
+
+// InMemoryUserStore is a UserStore that keeps the users in process memory,
+// for running the API without MySQL. Its spans and query metrics mirror
+// those of UserRepository, and like the users table it keeps emails unique.
+// Nothing survives a restart
+type InMemoryUserStore struct {
+	tables  *memoryTables
+	tracer  trace.Tracer
+	metrics *memoryMetrics
+}
+
+var _ UserStore = (*InMemoryUserStore)(nil)
+
+func NewInMemoryUserStore() *InMemoryUserStore {
+	return &InMemoryUserStore{
+		tables: &memoryTables{
+			users: map[int]models.User{},
+			posts: map[int]models.Post{},
+		},
+		tracer:  otel.Tracer("user-repository"),
+		metrics: newMemoryMetrics(),
+	}
+}
+
+// Posts returns the PostStore of the posts of these users
+func (s *InMemoryUserStore) Posts() *InMemoryPostStore {
+	return &InMemoryPostStore{
+		tables:  s.tables,
+		tracer:  otel.Tracer("post-repository"),
+		metrics: s.metrics,
+	}
+}
+
+// start starts the span of operation on the users table
+func (s *InMemoryUserStore) start(ctx context.Context, name, operation string) (context.Context, trace.Span) {
+	ctx, span := s.tracer.Start(ctx, "InMemoryUserStore."+name)
+	span.SetAttributes(
+		dbSystemMemory,
+		attribute.String("db.operation", operation),
+		attribute.String("db.table", "users"),
+	)
+	return ctx, span
+}
+
+// GetAll returns a page of the users matching filter, newest first
+func (s *InMemoryUserStore) GetAll(ctx context.Context, filter models.UserFilter, limit, offset int) ([]models.User, error) {
+	ctx, span := s.start(ctx, "GetAll", "SELECT")
+	defer span.End()
+	span.SetAttributes(
+		attribute.Int("pagination.limit", limit),
+		attribute.Int("pagination.offset", offset),
+	)
+	span.SetAttributes(FilterAttributes(filter)...)
+
+	start := time.Now()
+	s.tables.mu.RLock()
+	users := s.tables.matching(filter)
+	s.tables.mu.RUnlock()
+	slices.SortFunc(users, func(a, b models.User) int {
+		return cmp.Or(b.CreatedAt.Compare(a.CreatedAt.Time), cmp.Compare(b.ID, a.ID))
+	})
+	users = users[min(max(offset, 0), len(users)):]
+	users = users[:min(max(limit, 0), len(users))]
+	s.metrics.record(ctx, "SELECT", "users", start, nil)
+
+	span.SetAttributes(
+		attribute.Int("result.count", len(users)),
+		attribute.Bool("db.query.success", true),
+	)
+	return users, nil
+}
+
+// Stream calls fn with each user matching filter in ID order and stops at
+// the first error fn returns. It iterates over a snapshot, so fn may write
+// to the store
+func (s *InMemoryUserStore) Stream(ctx context.Context, filter models.UserFilter, fn func(*models.User) error) error {
+	ctx, span := s.start(ctx, "Stream", "SELECT")
+	defer span.End()
+	span.SetAttributes(FilterAttributes(filter)...)
+
+	start := time.Now()
+	s.tables.mu.RLock()
+	users := s.tables.matching(filter)
+	s.tables.mu.RUnlock()
+	slices.SortFunc(users, func(a, b models.User) int { return cmp.Compare(a.ID, b.ID) })
+	s.metrics.record(ctx, "SELECT", "users", start, nil)
+
+	for i := range users {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if err := fn(&users[i]); err != nil {
+			return err
+		}
+	}
+	span.SetAttributes(attribute.Int("result.count", len(users)))
+	return nil
+}
+
+func (s *InMemoryUserStore) GetByID(ctx context.Context, id int) (*models.User, error) {
+	ctx, span := s.start(ctx, "GetByID", "SELECT")
+	defer span.End()
+	span.SetAttributes(attribute.Int("user.id", id))
+
+	start := time.Now()
+	s.tables.mu.RLock()
+	user, ok := s.tables.users[id]
+	s.tables.mu.RUnlock()
+	s.metrics.record(ctx, "SELECT", "users", start, nil)
+
+	span.SetAttributes(
+		attribute.Bool("user.found", ok),
+		attribute.Bool("db.query.success", true),
+	)
+	if !ok {
+		return nil, apperrors.NotFound("user not found")
+	}
+	return cloneUser(user), nil
+}
+
+func (s *InMemoryUserStore) Create(ctx context.Context, req models.CreateUserRequest) (*models.User, error) {
+	ctx, span := s.start(ctx, "Create", "INSERT")
+	defer span.End()
+
+	actor := auth.Actor(ctx)
+	span.SetAttributes(
+		attribute.String("user.name", req.Name),
+		attribute.String("user.email", req.Email),
+		attribute.String("enduser.id", actor),
+	)
+
+	start := time.Now()
+	s.tables.mu.Lock()
+	user, err := s.tables.insertUser(req, actor)
+	s.tables.mu.Unlock()
+	s.metrics.record(ctx, "INSERT", "users", start, err)
+	if err != nil {
+		span.SetAttributes(attribute.Bool("db.query.success", false))
+		return nil, err
+	}
+
+	span.SetAttributes(
+		attribute.Int("user.id", user.ID),
+		attribute.Bool("db.query.success", true),
+	)
+	return cloneUser(user), nil
+}
+
+// CreateBatch creates the users of reqs and returns a result per request, in
+// order. A duplicate email only fails its own request
+func (s *InMemoryUserStore) CreateBatch(ctx context.Context, reqs []models.CreateUserRequest) ([]BatchResult, error) {
+	ctx, span := s.start(ctx, "CreateBatch", "INSERT")
+	defer span.End()
+
+	actor := auth.Actor(ctx)
+	span.SetAttributes(
+		attribute.Int("batch.size", len(reqs)),
+		attribute.String("enduser.id", actor),
+	)
+
+	results := make([]BatchResult, len(reqs))
+	created := 0
+	s.tables.mu.Lock()
+	for i, req := range reqs {
+		start := time.Now()
+		user, err := s.tables.insertUser(req, actor)
+		s.metrics.record(ctx, "INSERT", "users", start, err)
+		if err != nil {
+			results[i].Err = err
+			continue
+		}
+		results[i].User = cloneUser(user)
+		created++
+	}
+	s.tables.mu.Unlock()
+
+	span.SetAttributes(
+		attribute.Int("batch.created", created),
+		attribute.Int("batch.failed", len(reqs)-created),
+		attribute.Bool("db.query.success", true),
+	)
+	return results, nil
+}
+
+// Update updates an existing user and increments its version. When
+// req.Version is set, the update only applies to that version of the user and
+// ErrVersionMismatch is returned for any other
+func (s *InMemoryUserStore) Update(ctx context.Context, id int, req models.UpdateUserRequest) (*models.User, error) {
+	ctx, span := s.start(ctx, "Update", "UPDATE")
+	defer span.End()
+
+	actor := auth.Actor(ctx)
+	span.SetAttributes(
+		attribute.Int("user.id", id),
+		attribute.String("enduser.id", actor),
+	)
+
+	start := time.Now()
+	s.tables.mu.Lock()
+	defer s.tables.mu.Unlock()
+
+	user, ok := s.tables.users[id]
+	if !ok {
+		s.metrics.record(ctx, "UPDATE", "users", start, nil)
+		return nil, apperrors.NotFound("user not found")
+	}
+	if req.Version != nil {
+		span.SetAttributes(attribute.Int("user.version", *req.Version))
+		if user.Version != *req.Version {
+			s.metrics.record(ctx, "UPDATE", "users", start, nil)
+			span.SetAttributes(attribute.Bool("user.version_mismatch", true))
+			return nil, ErrVersionMismatch
+		}
+	}
+	if req.Name == nil && req.Email == nil && !req.Bio.Set {
+		s.metrics.record(ctx, "UPDATE", "users", start, nil)
+		span.SetAttributes(attribute.Bool("user.no_changes", true))
+		return cloneUser(user), nil
+	}
+
+	if req.Email != nil {
+		span.SetAttributes(attribute.String("user.email", *req.Email))
+		if other, taken := s.tables.userByEmail(*req.Email); taken && other.ID != id {
+			err := apperrors.Conflict("email already exists")
+			s.metrics.record(ctx, "UPDATE", "users", start, err)
+			return nil, err
+		}
+		user.Email = *req.Email
+	}
+	if req.Name != nil {
+		span.SetAttributes(attribute.String("user.name", *req.Name))
+		user.Name = *req.Name
+	}
+	if req.Bio.Set {
+		span.SetAttributes(attribute.Bool("user.bio_cleared", req.Bio.Null))
+		user.Bio = req.Bio.Ptr()
+	}
+	user.UpdatedBy = actor
+	user.UpdatedAt = models.Now()
+	user.Version++
+	s.tables.users[id] = *cloneUser(user)
+	s.metrics.record(ctx, "UPDATE", "users", start, nil)
+
+	return cloneUser(user), nil
+}
+
+// Delete deletes a user by ID along with its posts
+func (s *InMemoryUserStore) Delete(ctx context.Context, id int) error {
+	ctx, span := s.start(ctx, "Delete", "DELETE")
+	defer span.End()
+	span.SetAttributes(
+		attribute.Int("user.id", id),
+		attribute.String("enduser.id", auth.Actor(ctx)),
+	)
+
+	start := time.Now()
+	s.tables.mu.Lock()
+	defer s.tables.mu.Unlock()
+
+	if _, ok := s.tables.users[id]; !ok {
+		s.metrics.record(ctx, "DELETE", "users", start, nil)
+		return apperrors.NotFound("user not found")
+	}
+	posts := 0
+	for postID, post := range s.tables.posts {
+		if post.UserID == id {
+			delete(s.tables.posts, postID)
+			posts++
+		}
+	}
+	delete(s.tables.users, id)
+	s.metrics.record(ctx, "DELETE", "users", start, nil)
+
+	span.SetAttributes(
+		attribute.Int("user.posts_deleted", posts),
+		attribute.Bool("user.deleted", true),
+	)
+	return nil
+}
+
+// Count returns the number of users matching filter
+func (s *InMemoryUserStore) Count(ctx context.Context, filter models.UserFilter) (int, error) {
+	ctx, span := s.start(ctx, "Count", "SELECT")
+	defer span.End()
+	span.SetAttributes(FilterAttributes(filter)...)
+
+	start := time.Now()
+	s.tables.mu.RLock()
+	count := len(s.tables.matching(filter))
+	s.tables.mu.RUnlock()
+	s.metrics.record(ctx, "SELECT", "users", start, nil)
+
+	span.SetAttributes(attribute.Int("result.count", count))
+	return count, nil
+}
+
+// GetByEmail retrieves a user by email
+func (s *InMemoryUserStore) GetByEmail(ctx context.Context, email string) (*models.User, error) {
+	ctx, span := s.start(ctx, "GetByEmail", "SELECT")
+	defer span.End()
+	span.SetAttributes(attribute.String("user.email", email))
+
+	start := time.Now()
+	s.tables.mu.RLock()
+	user, ok := s.tables.userByEmail(email)
+	s.tables.mu.RUnlock()
+	s.metrics.record(ctx, "SELECT", "users", start, nil)
+
+	span.SetAttributes(attribute.Bool("user.found", ok))
+	if !ok {
+		return nil, apperrors.NotFound("user not found")
+	}
+	return cloneUser(user), nil
+}
+
+// matching returns copies of the users matching filter, in no particular
+// order. The caller holds mu
+func (t *memoryTables) matching(filter models.UserFilter) []models.User {
+	query := strings.ToLower(filter.Query)
+	var users []models.User
+	for _, user := range t.users {
+		switch {
+		case query != "" && !strings.Contains(strings.ToLower(user.Name), query) &&
+			!strings.Contains(strings.ToLower(user.Email), query):
+		case filter.Email != "" && !strings.EqualFold(user.Email, filter.Email):
+		case !filter.CreatedAfter.IsZero() && !user.CreatedAt.After(filter.CreatedAfter):
+		case !filter.CreatedBefore.IsZero() && !user.CreatedAt.Before(filter.CreatedBefore):
+		default:
+			users = append(users, *cloneUser(user))
+		}
+	}
+	return users
+}
+
+// userByEmail finds a user by email, ignoring case like the users.email
+// collation. The caller holds mu
+func (t *memoryTables) userByEmail(email string) (models.User, bool) {
+	for _, user := range t.users {
+		if strings.EqualFold(user.Email, email) {
+			return user, true
+		}
+	}
+	return models.User{}, false
+}
+
+// insertUser stores a new user, failing with apperrors.ErrConflict when its
+// email is taken. The caller holds mu for writing
+func (t *memoryTables) insertUser(req models.CreateUserRequest, actor string) (models.User, error) {
+	if _, taken := t.userByEmail(req.Email); taken {
+		return models.User{}, apperrors.Conflict("email already exists")
+	}
+	t.lastUserID++
+	now := models.Now()
+	user := models.User{
+		ID:        t.lastUserID,
+		Name:      req.Name,
+		Email:     req.Email,
+		Bio:       clonePtr(req.Bio),
+		CreatedBy: actor,
+		UpdatedBy: actor,
+		CreatedAt: now,
+		UpdatedAt: now,
+		Version:   1,
+	}
+	t.users[user.ID] = user
+	return user, nil
+}
+
+// cloneUser copies user so callers cannot change the stored bio
+func cloneUser(user models.User) *models.User {
+	user.Bio = clonePtr(user.Bio)
+	return &user
+}
+
+func clonePtr(value *string) *string {
+	if value == nil {
+		return nil
+	}
+	v := *value
+	return &v
+}
@@ -0,0 +1,174 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"arquivolivre.com.br/otel/internal/models"
+	"arquivolivre.com.br/otel/pkg/apperrors"
+	"arquivolivre.com.br/otel/pkg/oteltest"
+
+	"go.opentelemetry.io/otel/attribute"
+)
+
+func TestInMemoryUserStore_CRUD(t *testing.T) {
+	ctx := context.Background()
+	store := NewInMemoryUserStore()
+
+	bio := "hello"
+	alice, err := store.Create(ctx, models.CreateUserRequest{Name: "Alice", Email: "alice@example.com", Bio: &bio})
+	if err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+	if alice.ID != 1 || alice.Version != 1 || alice.CreatedAt.IsZero() {
+		t.Fatalf("unexpected created user %+v", alice)
+	}
+	*alice.Bio = "changed"
+	if u, err := store.GetByID(ctx, alice.ID); err != nil || *u.Bio != "hello" {
+		t.Fatalf("GetByID = %v, %v; the stored bio must not change", u, err)
+	}
+	if _, err := store.Create(ctx, models.CreateUserRequest{Name: "Other", Email: "ALICE@example.com"}); !errors.Is(err, apperrors.ErrConflict) {
+		t.Fatalf("expected a conflict on a duplicate email, got %v", err)
+	}
+
+	name := "Alice Smith"
+	version := 1
+	updated, err := store.Update(ctx, alice.ID, models.UpdateUserRequest{Name: &name, Bio: models.Null[string](), Version: &version})
+	if err != nil {
+		t.Fatalf("Update: %v", err)
+	}
+	if updated.Name != name || updated.Bio != nil || updated.Version != 2 {
+		t.Fatalf("unexpected updated user %+v", updated)
+	}
+	if _, err := store.Update(ctx, alice.ID, models.UpdateUserRequest{Name: &name, Version: &version}); !errors.Is(err, ErrVersionMismatch) {
+		t.Fatalf("expected a version mismatch, got %v", err)
+	}
+
+	bob, err := store.Create(ctx, models.CreateUserRequest{Name: "Bob", Email: "bob@example.com"})
+	if err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+	email := "alice@example.com"
+	if _, err := store.Update(ctx, bob.ID, models.UpdateUserRequest{Email: &email}); !errors.Is(err, apperrors.ErrConflict) {
+		t.Fatalf("expected a conflict on a taken email, got %v", err)
+	}
+	if u, err := store.GetByEmail(ctx, "bob@example.com"); err != nil || u.ID != bob.ID {
+		t.Fatalf("GetByEmail = %v, %v", u, err)
+	}
+
+	if err := store.Delete(ctx, bob.ID); err != nil {
+		t.Fatalf("Delete: %v", err)
+	}
+	if _, err := store.GetByID(ctx, bob.ID); !errors.Is(err, apperrors.ErrNotFound) {
+		t.Fatalf("expected deleted user to be not found, got %v", err)
+	}
+	if err := store.Delete(ctx, bob.ID); !errors.Is(err, apperrors.ErrNotFound) {
+		t.Fatalf("expected a second delete to be not found, got %v", err)
+	}
+}
+
+func TestInMemoryUserStore_FiltersAndPages(t *testing.T) {
+	ctx := context.Background()
+	store := NewInMemoryUserStore()
+	results, err := store.CreateBatch(ctx, []models.CreateUserRequest{
+		{Name: "Ana", Email: "ana@example.com"},
+		{Name: "Bruno", Email: "bruno@example.com"},
+		{Name: "Dup", Email: "ana@example.com"},
+		{Name: "Carla", Email: "carla@test.com"},
+	})
+	if err != nil {
+		t.Fatalf("CreateBatch: %v", err)
+	}
+	if results[0].User == nil || !errors.Is(results[2].Err, apperrors.ErrConflict) || results[3].User == nil {
+		t.Fatalf("unexpected batch results %+v", results)
+	}
+
+	users, err := store.GetAll(ctx, models.UserFilter{}, 2, 0)
+	if err != nil || len(users) != 2 || users[0].Name != "Carla" || users[1].Name != "Bruno" {
+		t.Fatalf("expected the newest users first, got %v, %v", users, err)
+	}
+	users, _ = store.GetAll(ctx, models.UserFilter{}, 2, 2)
+	if len(users) != 1 || users[0].Name != "Ana" {
+		t.Fatalf("expected the last page to hold Ana, got %v", users)
+	}
+	if users, _ := store.GetAll(ctx, models.UserFilter{}, 10, 5); len(users) != 0 {
+		t.Fatalf("expected no users past the end, got %v", users)
+	}
+
+	if count, _ := store.Count(ctx, models.UserFilter{Query: "EXAMPLE"}); count != 2 {
+		t.Fatalf("expected 2 users matching the query, got %d", count)
+	}
+	if count, _ := store.Count(ctx, models.UserFilter{Email: "carla@test.com"}); count != 1 {
+		t.Fatalf("expected 1 user with the email, got %d", count)
+	}
+
+	var ids []int
+	err = store.Stream(ctx, models.UserFilter{}, func(u *models.User) error {
+		ids = append(ids, u.ID)
+		return nil
+	})
+	if err != nil || len(ids) != 3 || ids[0] != 1 || ids[2] != 3 {
+		t.Fatalf("expected users streamed in ID order, got %v, %v", ids, err)
+	}
+}
+
+func TestInMemoryPostStore(t *testing.T) {
+	ctx := context.Background()
+	users := NewInMemoryUserStore()
+	posts := users.Posts()
+
+	if _, err := posts.Create(ctx, models.CreatePostRequest{UserID: 1, Title: "t", Body: "b"}); !errors.Is(err, apperrors.ErrNotFound) {
+		t.Fatalf("expected a missing author to be not found, got %v", err)
+	}
+	author, err := users.Create(ctx, models.CreateUserRequest{Name: "Ana", Email: "ana@example.com"})
+	if err != nil {
+		t.Fatalf("Create user: %v", err)
+	}
+	post, err := posts.Create(ctx, models.CreatePostRequest{UserID: author.ID, Title: "Hello", Body: "World"})
+	if err != nil {
+		t.Fatalf("Create post: %v", err)
+	}
+	title := "Hi"
+	if updated, err := posts.Update(ctx, post.ID, models.UpdatePostRequest{Title: &title}); err != nil || updated.Title != title || updated.Body != "World" {
+		t.Fatalf("Update = %v, %v", updated, err)
+	}
+	if p, u, err := posts.GetWithAuthor(ctx, post.ID); err != nil || p.ID != post.ID || u.ID != author.ID {
+		t.Fatalf("GetWithAuthor = %v, %v, %v", p, u, err)
+	}
+	if count, _ := posts.Count(ctx, author.ID); count != 1 {
+		t.Fatalf("expected 1 post of the author, got %d", count)
+	}
+
+	if err := users.Delete(ctx, author.ID); err != nil {
+		t.Fatalf("Delete user: %v", err)
+	}
+	if _, err := posts.GetByID(ctx, post.ID); !errors.Is(err, apperrors.ErrNotFound) {
+		t.Fatalf("expected the posts of a deleted user to be deleted, got %v", err)
+	}
+}
+
+func TestInMemoryUserStore_Spans(t *testing.T) {
+	rec := oteltest.Install(t)
+	store := NewInMemoryUserStore()
+	ctx := context.Background()
+
+	if _, err := store.Create(ctx, models.CreateUserRequest{Name: "Ana", Email: "ana@example.com"}); err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+	if _, err := store.GetByID(ctx, 2); !errors.Is(err, apperrors.ErrNotFound) {
+		t.Fatalf("expected not found, got %v", err)
+	}
+
+	rec.AssertSpan(t, "InMemoryUserStore.Create",
+		attribute.String("db.system", "memory"),
+		attribute.String("db.operation", "INSERT"),
+		attribute.String("db.table", "users"),
+		attribute.Int("user.id", 1),
+		attribute.Bool("db.query.success", true),
+	)
+	rec.AssertSpan(t, "InMemoryUserStore.GetByID",
+		attribute.Int("user.id", 2),
+		attribute.Bool("user.found", false),
+	)
+}
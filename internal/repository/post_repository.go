@@ -0,0 +1,323 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+
+	"arquivolivre.com.br/otel/internal/auth"
+	"arquivolivre.com.br/otel/internal/database"
+	"arquivolivre.com.br/otel/internal/models"
+	"arquivolivre.com.br/otel/pkg/apperrors"
+
+	"github.com/go-sql-driver/mysql"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+type PostRepository struct {
+	db     *database.DB
+	tracer trace.Tracer
+}
+
+var _ PostStore = (*PostRepository)(nil)
+
+func NewPostRepository(db *database.DB) *PostRepository {
+	return &PostRepository{
+		db:     db,
+		tracer: otel.Tracer("post-repository"),
+	}
+}
+
+// PostStore is the post persistence the handlers depend on; PostRepository
+// implements it on MySQL. A userID of 0 selects the posts of every user
+type PostStore interface {
+	GetAll(ctx context.Context, userID, limit, offset int) ([]models.Post, error)
+	Count(ctx context.Context, userID int) (int, error)
+	GetByID(ctx context.Context, id int) (*models.Post, error)
+	GetWithAuthor(ctx context.Context, id int) (*models.Post, *models.User, error)
+	Create(ctx context.Context, req models.CreatePostRequest) (*models.Post, error)
+	Update(ctx context.Context, id int, req models.UpdatePostRequest) (*models.Post, error)
+	Delete(ctx context.Context, id int) error
+}
+
+const postColumns = "id, user_id, title, body, created_by, updated_by, created_at, updated_at"
+
+// GetAll returns a page of posts, newest first
+func (r *PostRepository) GetAll(ctx context.Context, userID, limit, offset int) ([]models.Post, error) {
+	return instrumentedQuery(ctx, r.tracer, r.db, "PostRepository.GetAll", "SELECT", "posts", func(ctx context.Context, span trace.Span) ([]models.Post, error) {
+		db := r.db.Reader(ctx)
+		span.SetAttributes(
+			attribute.Int("pagination.limit", limit),
+			attribute.Int("pagination.offset", offset),
+		)
+
+		where, args := postUserClause(userID)
+		if userID != 0 {
+			span.SetAttributes(attribute.Int("user.id", userID))
+		}
+		query := `
+			SELECT ` + postColumns + `
+			FROM posts` + where + `
+			ORDER BY created_at DESC, id DESC
+			LIMIT ? OFFSET ?
+		`
+
+		done := db.TrackQuery(ctx, "SELECT", "posts", query)
+		rows, err := db.QueryContext(ctx, query, append(args, limit, offset)...)
+		done(err)
+		if err != nil {
+			return nil, fmt.Errorf("failed to query posts: %w", err)
+		}
+		defer func() { _ = rows.Close() }()
+
+		var posts []models.Post
+		for rows.Next() {
+			var post models.Post
+			if err := rows.Scan(postFields(&post)...); err != nil {
+				return nil, fmt.Errorf("failed to scan post: %w", err)
+			}
+			posts = append(posts, post)
+		}
+		if err := rows.Err(); err != nil {
+			return nil, fmt.Errorf("error iterating over posts: %w", err)
+		}
+
+		span.SetAttributes(attribute.Int("result.count", len(posts)))
+		return posts, nil
+	})
+}
+
+// Count returns the number of posts
+func (r *PostRepository) Count(ctx context.Context, userID int) (int, error) {
+	return instrumentedQuery(ctx, r.tracer, r.db, "PostRepository.Count", "SELECT", "posts", func(ctx context.Context, span trace.Span) (int, error) {
+		db := r.db.Reader(ctx)
+		where, args := postUserClause(userID)
+		query := "SELECT COUNT(*) FROM posts" + where
+
+		var count int
+		done := db.TrackQuery(ctx, "SELECT", "posts", query)
+		err := db.QueryRowContext(ctx, query, args...).Scan(&count)
+		done(err)
+		if err != nil {
+			return 0, fmt.Errorf("failed to count posts: %w", err)
+		}
+
+		span.SetAttributes(attribute.Int("result.count", count))
+		return count, nil
+	})
+}
+
+func (r *PostRepository) GetByID(ctx context.Context, id int) (*models.Post, error) {
+	return instrumentedQuery(ctx, r.tracer, r.db, "PostRepository.GetByID", "SELECT", "posts", func(ctx context.Context, span trace.Span) (*models.Post, error) {
+		db := r.db.Reader(ctx)
+		span.SetAttributes(attribute.Int("post.id", id))
+
+		query := `
+			SELECT ` + postColumns + `
+			FROM posts
+			WHERE id = ?
+		`
+
+		var post models.Post
+		done := db.TrackQuery(ctx, "SELECT", "posts", query)
+		err := db.QueryRowContext(ctx, query, id).Scan(postFields(&post)...)
+		done(err)
+		if err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				span.SetAttributes(attribute.Bool("post.found", false))
+				return nil, apperrors.NotFound("post not found")
+			}
+			return nil, fmt.Errorf("failed to get post: %w", err)
+		}
+
+		span.SetAttributes(attribute.Bool("post.found", true))
+		return &post, nil
+	})
+}
+
+// GetWithAuthor returns a post and the user who wrote it, read together by
+// joining posts with users
+func (r *PostRepository) GetWithAuthor(ctx context.Context, id int) (*models.Post, *models.User, error) {
+	var author models.User
+	post, err := instrumentedQuery(ctx, r.tracer, r.db, "PostRepository.GetWithAuthor", "SELECT", "posts", func(ctx context.Context, span trace.Span) (*models.Post, error) {
+		db := r.db.Reader(ctx)
+		span.SetAttributes(
+			attribute.Int("post.id", id),
+			attribute.String("db.join", "users"),
+		)
+
+		query := `
+			SELECT p.id, p.user_id, p.title, p.body, p.created_by, p.updated_by, p.created_at, p.updated_at,
+				u.id, u.name, u.email, u.bio, u.created_by, u.updated_by, u.created_at, u.updated_at, u.version
+			FROM posts p
+			JOIN users u ON u.id = p.user_id
+			WHERE p.id = ?
+		`
+
+		var post models.Post
+		done := db.TrackQuery(ctx, "SELECT", "posts", query)
+		err := db.QueryRowContext(ctx, query, id).Scan(append(postFields(&post), userFields(&author)...)...)
+		done(err)
+		if err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				span.SetAttributes(attribute.Bool("post.found", false))
+				return nil, apperrors.NotFound("post not found")
+			}
+			return nil, fmt.Errorf("failed to get post: %w", err)
+		}
+
+		span.SetAttributes(
+			attribute.Bool("post.found", true),
+			attribute.Int("user.id", author.ID),
+		)
+		return &post, nil
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	return post, &author, nil
+}
+
+func (r *PostRepository) Create(ctx context.Context, req models.CreatePostRequest) (*models.Post, error) {
+	// Lookups of the write must see its own changes
+	ctx = database.ReadPrimary(ctx)
+	return instrumentedQuery(ctx, r.tracer, r.db, "PostRepository.Create", "INSERT", "posts", func(ctx context.Context, span trace.Span) (*models.Post, error) {
+		actor := auth.Actor(ctx)
+		span.SetAttributes(
+			attribute.Int("user.id", req.UserID),
+			attribute.Int("post.title_length", len(req.Title)),
+			attribute.String("enduser.id", actor),
+		)
+
+		query := `
+			INSERT INTO posts (user_id, title, body, created_by, updated_by)
+			VALUES (?, ?, ?, ?, ?)
+		`
+
+		done := r.db.TrackQuery(ctx, "INSERT", "posts", query)
+		result, err := r.db.ExecContext(ctx, query, req.UserID, req.Title, req.Body, actor, actor)
+		done(err)
+		if err != nil {
+			return nil, mapPostWriteError(err, "failed to create post")
+		}
+
+		id, err := result.LastInsertId()
+		if err != nil {
+			return nil, fmt.Errorf("failed to get last insert id: %w", err)
+		}
+
+		span.SetAttributes(attribute.Int64("post.id", id))
+		return r.GetByID(ctx, int(id))
+	})
+}
+
+// Update updates the title and body of an existing post
+func (r *PostRepository) Update(ctx context.Context, id int, req models.UpdatePostRequest) (*models.Post, error) {
+	// Lookups of the write must see its own changes
+	ctx = database.ReadPrimary(ctx)
+	return instrumentedQuery(ctx, r.tracer, r.db, "PostRepository.Update", "UPDATE", "posts", func(ctx context.Context, span trace.Span) (*models.Post, error) {
+		actor := auth.Actor(ctx)
+		span.SetAttributes(
+			attribute.Int("post.id", id),
+			attribute.String("enduser.id", actor),
+		)
+
+		existingPost, err := r.GetByID(ctx, id)
+		if err != nil {
+			return nil, err
+		}
+
+		setParts := []string{}
+		args := []interface{}{}
+		if req.Title != nil {
+			setParts = append(setParts, "title = ?")
+			args = append(args, *req.Title)
+		}
+		if req.Body != nil {
+			setParts = append(setParts, "body = ?")
+			args = append(args, *req.Body)
+		}
+
+		if len(setParts) == 0 {
+			span.SetAttributes(attribute.Bool("post.no_changes", true))
+			return existingPost, nil
+		}
+
+		setParts = append(setParts, "updated_by = ?", "updated_at = NOW()")
+		args = append(args, actor, id)
+		query := "UPDATE posts SET " + strings.Join(setParts, ", ") + " WHERE id = ?"
+
+		done := r.db.TrackQuery(ctx, "UPDATE", "posts", query)
+		_, err = r.db.ExecContext(ctx, query, args...)
+		done(err)
+		if err != nil {
+			return nil, fmt.Errorf("failed to update post: %w", err)
+		}
+
+		return r.GetByID(ctx, id)
+	})
+}
+
+// Delete deletes a post by ID
+func (r *PostRepository) Delete(ctx context.Context, id int) error {
+	return instrumentedExec(ctx, r.tracer, r.db, "PostRepository.Delete", "DELETE", "posts", func(ctx context.Context, span trace.Span) error {
+		span.SetAttributes(
+			attribute.Int("post.id", id),
+			attribute.String("enduser.id", auth.Actor(ctx)),
+		)
+
+		query := "DELETE FROM posts WHERE id = ?"
+		done := r.db.TrackQuery(ctx, "DELETE", "posts", query)
+		result, err := r.db.ExecContext(ctx, query, id)
+		done(err)
+		if err != nil {
+			return fmt.Errorf("failed to delete post: %w", err)
+		}
+		if deleted, err := result.RowsAffected(); err == nil && deleted == 0 {
+			return apperrors.NotFound("post not found")
+		}
+
+		span.SetAttributes(attribute.Bool("post.deleted", true))
+		return nil
+	})
+}
+
+// postFields returns the scan destinations of postColumns
+func postFields(post *models.Post) []interface{} {
+	return []interface{}{
+		&post.ID,
+		&post.UserID,
+		&post.Title,
+		&post.Body,
+		&post.CreatedBy,
+		&post.UpdatedBy,
+		&post.CreatedAt,
+		&post.UpdatedAt,
+	}
+}
+
+// postUserClause restricts posts to the author userID unless it is 0
+func postUserClause(userID int) (string, []interface{}) {
+	if userID == 0 {
+		return "", nil
+	}
+	return "\n\t\tWHERE user_id = ?", []interface{}{userID}
+}
+
+// mysqlErrNoReferencedRow is the MySQL error number for inserts whose foreign
+// key has no parent row
+const mysqlErrNoReferencedRow = 1452
+
+// mapPostWriteError turns a missing author into apperrors.ErrNotFound and
+// wraps any other error with msg
+func mapPostWriteError(err error, msg string) error {
+	var mysqlErr *mysql.MySQLError
+	if errors.As(err, &mysqlErr) && mysqlErr.Number == mysqlErrNoReferencedRow {
+		return apperrors.Wrap(apperrors.ErrNotFound, err, "user not found")
+	}
+	return fmt.Errorf("%s: %w", msg, err)
+}
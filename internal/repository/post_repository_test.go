@@ -0,0 +1,121 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"regexp"
+	"testing"
+	"time"
+
+	"arquivolivre.com.br/otel/internal/models"
+	"arquivolivre.com.br/otel/pkg/apperrors"
+
+	sqlmock "github.com/DATA-DOG/go-sqlmock"
+	"github.com/go-sql-driver/mysql"
+)
+
+var postRowColumns = []string{"id", "user_id", "title", "body", "created_by", "updated_by", "created_at", "updated_at"}
+
+func TestPostGetAll_ByUser(t *testing.T) {
+	db, mock, cleanup := newTestDB(t)
+	defer cleanup()
+	repo := NewPostRepository(db)
+
+	now := time.Now()
+	mock.ExpectQuery(regexp.QuoteMeta(`FROM posts WHERE user_id = ? ORDER BY created_at DESC, id DESC LIMIT ? OFFSET ?`)).
+		WithArgs(7, 10, 0).
+		WillReturnRows(sqlmock.NewRows(postRowColumns).AddRow(1, 7, "T", "B", "system", "system", now, now))
+
+	posts, err := repo.GetAll(context.Background(), 7, 10, 0)
+	if err != nil {
+		t.Fatalf("get all err: %v", err)
+	}
+	if len(posts) != 1 || posts[0].UserID != 7 {
+		t.Fatalf("unexpected posts %+v", posts)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestPostGetWithAuthor(t *testing.T) {
+	db, mock, cleanup := newTestDB(t)
+	defer cleanup()
+	repo := NewPostRepository(db)
+
+	now := time.Now()
+	columns := append(append([]string{}, postRowColumns...), "id", "name", "email", "bio", "created_by", "updated_by", "created_at", "updated_at", "version")
+	mock.ExpectQuery(regexp.QuoteMeta(`FROM posts p JOIN users u ON u.id = p.user_id WHERE p.id = ?`)).
+		WithArgs(3).
+		WillReturnRows(sqlmock.NewRows(columns).AddRow(3, 7, "T", "B", "system", "system", now, now, 7, "Ann", "ann@example.com", nil, "system", "system", now, now, 1))
+
+	post, author, err := repo.GetWithAuthor(context.Background(), 3)
+	if err != nil {
+		t.Fatalf("get err: %v", err)
+	}
+	if post.ID != 3 || author.ID != 7 || author.Name != "Ann" {
+		t.Fatalf("unexpected post %+v by %+v", post, author)
+	}
+}
+
+func TestPostGetWithAuthor_NotFound(t *testing.T) {
+	db, mock, cleanup := newTestDB(t)
+	defer cleanup()
+	repo := NewPostRepository(db)
+
+	mock.ExpectQuery(regexp.QuoteMeta(`FROM posts p JOIN users u`)).WithArgs(9).WillReturnRows(sqlmock.NewRows(postRowColumns))
+
+	if _, _, err := repo.GetWithAuthor(context.Background(), 9); !errors.Is(err, apperrors.ErrNotFound) {
+		t.Fatalf("expected not found, got %v", err)
+	}
+}
+
+func TestPostCreate_UnknownUser(t *testing.T) {
+	db, mock, cleanup := newTestDB(t)
+	defer cleanup()
+	repo := NewPostRepository(db)
+
+	mock.ExpectExec(regexp.QuoteMeta(`INSERT INTO posts (user_id, title, body, created_by, updated_by)`)).
+		WithArgs(42, "T", "B", "system", "system").
+		WillReturnError(&mysql.MySQLError{Number: 1452, Message: "Cannot add or update a child row: a foreign key constraint fails"})
+
+	_, err := repo.Create(context.Background(), models.CreatePostRequest{UserID: 42, Title: "T", Body: "B"})
+	if !errors.Is(err, apperrors.ErrNotFound) || apperrors.Message(err) != "user not found" {
+		t.Fatalf("expected user not found, got %v", err)
+	}
+}
+
+func TestPostUpdate_SetsFields(t *testing.T) {
+	db, mock, cleanup := newTestDB(t)
+	defer cleanup()
+	repo := NewPostRepository(db)
+
+	now := time.Now()
+	mock.ExpectQuery(regexp.QuoteMeta(`FROM posts WHERE id = ?`)).WithArgs(3).
+		WillReturnRows(sqlmock.NewRows(postRowColumns).AddRow(3, 7, "Old", "B", "system", "system", now, now))
+	mock.ExpectExec(regexp.QuoteMeta(`UPDATE posts SET title = ?, updated_by = ?, updated_at = NOW() WHERE id = ?`)).
+		WithArgs("New", "system", 3).WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectQuery(regexp.QuoteMeta(`FROM posts WHERE id = ?`)).WithArgs(3).
+		WillReturnRows(sqlmock.NewRows(postRowColumns).AddRow(3, 7, "New", "B", "system", "system", now, now))
+
+	title := "New"
+	post, err := repo.Update(context.Background(), 3, models.UpdatePostRequest{Title: &title})
+	if err != nil {
+		t.Fatalf("update err: %v", err)
+	}
+	if post.Title != "New" {
+		t.Fatalf("unexpected post %+v", post)
+	}
+}
+
+func TestPostDelete_NotFound(t *testing.T) {
+	db, mock, cleanup := newTestDB(t)
+	defer cleanup()
+	repo := NewPostRepository(db)
+
+	mock.ExpectExec(regexp.QuoteMeta(`DELETE FROM posts WHERE id = ?`)).WithArgs(9).WillReturnResult(sqlmock.NewResult(0, 0))
+
+	if err := repo.Delete(context.Background(), 9); !errors.Is(err, apperrors.ErrNotFound) {
+		t.Fatalf("expected not found, got %v", err)
+	}
+}
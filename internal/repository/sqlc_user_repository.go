@@ -0,0 +1,410 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"arquivolivre.com.br/otel/internal/auth"
+	"arquivolivre.com.br/otel/internal/database"
+	"arquivolivre.com.br/otel/internal/models"
+	"arquivolivre.com.br/otel/internal/repository/sqlcdb"
+	"arquivolivre.com.br/otel/pkg/apperrors"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// SqlcUserRepository implements UserStore with the queries of package
+// sqlcdb, which sqlc generates from sqlcdb/queries.sql and checks against the
+// users migrations (run `make sqlc` after editing them). Like
+// SqlxUserRepository, it runs on the pools of database.DB and does not write
+// to the outbox or prepare statements
+type SqlcUserRepository struct {
+	db     *database.DB
+	tracer trace.Tracer
+}
+
+var _ UserStore = (*SqlcUserRepository)(nil)
+
+func NewSqlcUserRepository(db *database.DB) *SqlcUserRepository {
+	return &SqlcUserRepository{
+		db:     db,
+		tracer: otel.Tracer("sqlc-user-repository"),
+	}
+}
+
+// streamPageSize is the number of users Stream reads per query
+const streamPageSize = 500
+
+// GetAll returns a page of the users matching filter, newest first
+func (r *SqlcUserRepository) GetAll(ctx context.Context, filter models.UserFilter, limit, offset int) ([]models.User, error) {
+	return instrumentedQuery(ctx, r.tracer, r.db, "SqlcUserRepository.GetAll", "SELECT", "users", func(ctx context.Context, span trace.Span) ([]models.User, error) {
+		db := r.db.Reader(ctx)
+		span.SetAttributes(
+			attribute.Int("pagination.limit", limit),
+			attribute.Int("pagination.offset", offset),
+		)
+		span.SetAttributes(FilterAttributes(filter)...)
+
+		f := sqlcFilter(filter)
+		done := db.TrackQuery(ctx, "SELECT", "users", sqlcdb.ListUsers)
+		rows, err := sqlcdb.New(db).ListUsers(ctx, sqlcdb.ListUsersParams{
+			Pattern:       f.Pattern,
+			Email:         f.Email,
+			CreatedAfter:  f.CreatedAfter,
+			CreatedBefore: f.CreatedBefore,
+			Limit:         int32(limit),
+			Offset:        int32(offset),
+		})
+		done(err)
+		if err != nil {
+			return nil, fmt.Errorf("failed to query users: %w", err)
+		}
+
+		users := make([]models.User, len(rows))
+		for i, row := range rows {
+			users[i] = sqlcUser(row)
+		}
+		span.SetAttributes(attribute.Int("result.count", len(users)))
+		return users, nil
+	})
+}
+
+// Stream calls fn with each user matching filter in ID order. sqlc reads a
+// whole result into a slice, so the users are read in pages of
+// streamPageSize, each starting after the last ID of the previous one. Like
+// UserRepository.Stream, the query timeout does not apply
+func (r *SqlcUserRepository) Stream(ctx context.Context, filter models.UserFilter, fn func(*models.User) error) (err error) {
+	ctx, span, end := startQuery(ctx, r.tracer, "SqlcUserRepository.Stream", "SELECT", "users")
+	defer span.End()
+	defer func() { end(err) }()
+	db := r.db.Reader(ctx)
+	span.SetAttributes(FilterAttributes(filter)...)
+
+	f := sqlcFilter(filter)
+	params := sqlcdb.ListUsersAfterIDParams{
+		Pattern:       f.Pattern,
+		Email:         f.Email,
+		CreatedAfter:  f.CreatedAfter,
+		CreatedBefore: f.CreatedBefore,
+		Limit:         streamPageSize,
+	}
+	count := 0
+	for {
+		done := db.TrackQuery(ctx, "SELECT", "users", sqlcdb.ListUsersAfterID)
+		rows, err := sqlcdb.New(db).ListUsersAfterID(ctx, params)
+		done(err)
+		if err != nil {
+			return fmt.Errorf("failed to query users: %w", err)
+		}
+		for _, row := range rows {
+			user := sqlcUser(row)
+			if err := fn(&user); err != nil {
+				return err
+			}
+			count++
+		}
+		if len(rows) < streamPageSize {
+			break
+		}
+		params.AfterID = rows[len(rows)-1].ID
+	}
+	span.SetAttributes(attribute.Int("result.count", count))
+	return nil
+}
+
+func (r *SqlcUserRepository) GetByID(ctx context.Context, id int) (*models.User, error) {
+	return instrumentedQuery(ctx, r.tracer, r.db, "SqlcUserRepository.GetByID", "SELECT", "users", func(ctx context.Context, span trace.Span) (*models.User, error) {
+		db := r.db.Reader(ctx)
+		span.SetAttributes(attribute.Int("user.id", id))
+
+		done := db.TrackQuery(ctx, "SELECT", "users", sqlcdb.GetUser)
+		row, err := sqlcdb.New(db).GetUser(ctx, int32(id))
+		done(err)
+		return sqlcFound(span, row, err)
+	})
+}
+
+// GetByEmail retrieves a user by email
+func (r *SqlcUserRepository) GetByEmail(ctx context.Context, email string) (*models.User, error) {
+	return instrumentedQuery(ctx, r.tracer, r.db, "SqlcUserRepository.GetByEmail", "SELECT", "users", func(ctx context.Context, span trace.Span) (*models.User, error) {
+		db := r.db.Reader(ctx)
+		span.SetAttributes(attribute.String("user.email", email))
+
+		done := db.TrackQuery(ctx, "SELECT", "users", sqlcdb.GetUserByEmail)
+		row, err := sqlcdb.New(db).GetUserByEmail(ctx, email)
+		done(err)
+		return sqlcFound(span, row, err)
+	})
+}
+
+func (r *SqlcUserRepository) Create(ctx context.Context, req models.CreateUserRequest) (*models.User, error) {
+	// Lookups of the write must see its own changes
+	ctx = database.ReadPrimary(ctx)
+	return instrumentedQuery(ctx, r.tracer, r.db, "SqlcUserRepository.Create", "INSERT", "users", func(ctx context.Context, span trace.Span) (*models.User, error) {
+		actor := auth.Actor(ctx)
+		span.SetAttributes(
+			attribute.String("user.name", req.Name),
+			attribute.String("user.email", req.Email),
+			attribute.String("enduser.id", actor),
+		)
+
+		id, err := r.insert(ctx, sqlcdb.New(r.db), req, actor)
+		if err != nil {
+			return nil, err
+		}
+
+		span.SetAttributes(attribute.Int("user.id", id))
+		return r.GetByID(ctx, id)
+	})
+}
+
+// insert creates the user of req on behalf of actor with q and returns its
+// ID. A duplicate email is returned as apperrors.ErrConflict
+func (r *SqlcUserRepository) insert(ctx context.Context, q *sqlcdb.Queries, req models.CreateUserRequest, actor string) (int, error) {
+	done := r.db.TrackQuery(ctx, "INSERT", "users", sqlcdb.CreateUser)
+	id, err := q.CreateUser(ctx, sqlcdb.CreateUserParams{
+		Name:      req.Name,
+		Email:     req.Email,
+		Bio:       req.Bio,
+		CreatedBy: actor,
+		UpdatedBy: actor,
+	})
+	done(err)
+	if err != nil {
+		return 0, mapWriteError(err, "failed to create user")
+	}
+	return int(id), nil
+}
+
+// CreateBatch creates the users of reqs in one transaction and returns a
+// result per request, in order. Like UserRepository.CreateBatch, a duplicate
+// email only fails its own request
+func (r *SqlcUserRepository) CreateBatch(ctx context.Context, reqs []models.CreateUserRequest) ([]BatchResult, error) {
+	return instrumentedQuery(ctx, r.tracer, r.db, "SqlcUserRepository.CreateBatch", "INSERT", "users", func(ctx context.Context, span trace.Span) ([]BatchResult, error) {
+		actor := auth.Actor(ctx)
+		span.SetAttributes(
+			attribute.Int("batch.size", len(reqs)),
+			attribute.String("enduser.id", actor),
+		)
+
+		tx, err := r.db.BeginTx(ctx, nil)
+		if err != nil {
+			return nil, fmt.Errorf("failed to begin transaction: %w", err)
+		}
+		// A no-op once the transaction is committed
+		defer func() { _ = tx.Rollback() }()
+
+		q := sqlcdb.New(r.db).WithTx(tx)
+		results := make([]BatchResult, len(reqs))
+		ids := make([]int32, len(reqs))
+		var created []int32
+		for i, req := range reqs {
+			id, err := r.insert(ctx, q, req, actor)
+			if err != nil {
+				if !errors.Is(err, apperrors.ErrConflict) {
+					return nil, err
+				}
+				results[i].Err = err
+				continue
+			}
+			ids[i] = int32(id)
+			created = append(created, int32(id))
+		}
+
+		if err := tx.Commit(); err != nil {
+			return nil, fmt.Errorf("failed to commit transaction: %w", err)
+		}
+		span.SetAttributes(
+			attribute.Int("batch.created", len(created)),
+			attribute.Int("batch.failed", len(reqs)-len(created)),
+		)
+		if len(created) == 0 {
+			return results, nil
+		}
+
+		done := r.db.TrackQuery(ctx, "SELECT", "users", sqlcdb.ListUsersByIDs)
+		rows, err := sqlcdb.New(r.db).ListUsersByIDs(ctx, created)
+		done(err)
+		if err != nil {
+			return nil, fmt.Errorf("failed to query users: %w", err)
+		}
+
+		byID := make(map[int32]models.User, len(rows))
+		for _, row := range rows {
+			byID[row.ID] = sqlcUser(row)
+		}
+		for i, id := range ids {
+			if id == 0 {
+				continue
+			}
+			if user, ok := byID[id]; ok {
+				results[i].User = &user
+			} else {
+				results[i].Err = apperrors.NotFound("user not found")
+			}
+		}
+		return results, nil
+	})
+}
+
+// Update updates an existing user and increments its version. The generated
+// UPDATE writes every field, so req is applied to the user as read and the
+// write only succeeds if no other update came in between; like a mismatch of
+// req.Version, that returns ErrVersionMismatch
+func (r *SqlcUserRepository) Update(ctx context.Context, id int, req models.UpdateUserRequest) (*models.User, error) {
+	// Lookups of the write must see its own changes
+	ctx = database.ReadPrimary(ctx)
+	return instrumentedQuery(ctx, r.tracer, r.db, "SqlcUserRepository.Update", "UPDATE", "users", func(ctx context.Context, span trace.Span) (*models.User, error) {
+		actor := auth.Actor(ctx)
+		span.SetAttributes(
+			attribute.Int("user.id", id),
+			attribute.String("enduser.id", actor),
+		)
+
+		existingUser, err := r.GetByID(ctx, id)
+		if err != nil {
+			return nil, err
+		}
+		if req.Version != nil {
+			span.SetAttributes(attribute.Int("user.version", *req.Version))
+			if existingUser.Version != *req.Version {
+				span.SetAttributes(attribute.Bool("user.version_mismatch", true))
+				return nil, ErrVersionMismatch
+			}
+		}
+
+		params := sqlcdb.UpdateUserParams{
+			Name:      existingUser.Name,
+			Email:     existingUser.Email,
+			Bio:       existingUser.Bio,
+			UpdatedBy: actor,
+			ID:        int32(id),
+			Version:   int32(existingUser.Version),
+		}
+		if req.Name != nil {
+			params.Name = *req.Name
+			span.SetAttributes(attribute.String("user.name", *req.Name))
+		}
+		if req.Email != nil {
+			params.Email = *req.Email
+			span.SetAttributes(attribute.String("user.email", *req.Email))
+		}
+		if req.Bio.Set {
+			params.Bio = req.Bio.Ptr()
+			span.SetAttributes(attribute.Bool("user.bio_cleared", req.Bio.Null))
+		}
+		if req.Name == nil && req.Email == nil && !req.Bio.Set {
+			span.SetAttributes(attribute.Bool("user.no_changes", true))
+			return existingUser, nil
+		}
+
+		done := r.db.TrackQuery(ctx, "UPDATE", "users", sqlcdb.UpdateUser)
+		updated, err := sqlcdb.New(r.db).UpdateUser(ctx, params)
+		done(err)
+		if err != nil {
+			return nil, mapWriteError(err, "failed to update user")
+		}
+		if updated == 0 {
+			span.SetAttributes(attribute.Bool("user.version_mismatch", true))
+			return nil, ErrVersionMismatch
+		}
+
+		return r.GetByID(ctx, id)
+	})
+}
+
+// Delete deletes a user by ID; its posts are removed by the foreign key
+func (r *SqlcUserRepository) Delete(ctx context.Context, id int) error {
+	return instrumentedExec(ctx, r.tracer, r.db, "SqlcUserRepository.Delete", "DELETE", "users", func(ctx context.Context, span trace.Span) error {
+		span.SetAttributes(
+			attribute.Int("user.id", id),
+			attribute.String("enduser.id", auth.Actor(ctx)),
+		)
+
+		done := r.db.TrackQuery(ctx, "DELETE", "users", sqlcdb.DeleteUser)
+		deleted, err := sqlcdb.New(r.db).DeleteUser(ctx, int32(id))
+		done(err)
+		if err != nil {
+			return fmt.Errorf("failed to delete user: %w", err)
+		}
+		if deleted == 0 {
+			return apperrors.NotFound("user not found")
+		}
+
+		span.SetAttributes(attribute.Bool("user.deleted", true))
+		return nil
+	})
+}
+
+// Count returns the number of users matching filter
+func (r *SqlcUserRepository) Count(ctx context.Context, filter models.UserFilter) (int, error) {
+	return instrumentedQuery(ctx, r.tracer, r.db, "SqlcUserRepository.Count", "SELECT", "users", func(ctx context.Context, span trace.Span) (int, error) {
+		db := r.db.Reader(ctx)
+		span.SetAttributes(FilterAttributes(filter)...)
+
+		done := db.TrackQuery(ctx, "SELECT", "users", sqlcdb.CountUsers)
+		count, err := sqlcdb.New(db).CountUsers(ctx, sqlcFilter(filter))
+		done(err)
+		if err != nil {
+			return 0, fmt.Errorf("failed to count users: %w", err)
+		}
+
+		span.SetAttributes(attribute.Int("result.count", int(count)))
+		return int(count), nil
+	})
+}
+
+// sqlcFilter converts filter into the parameters of the filtered queries;
+// the unset fields stay NULL, which the queries read as no condition
+func sqlcFilter(filter models.UserFilter) sqlcdb.CountUsersParams {
+	var params sqlcdb.CountUsersParams
+	if filter.Query != "" {
+		params.Pattern = sql.NullString{String: "%" + likeEscaper.Replace(filter.Query) + "%", Valid: true}
+	}
+	if filter.Email != "" {
+		params.Email = sql.NullString{String: filter.Email, Valid: true}
+	}
+	// A zero models.Timestamp is sent as NULL
+	if !filter.CreatedAfter.IsZero() {
+		params.CreatedAfter = models.NewTimestamp(filter.CreatedAfter)
+	}
+	if !filter.CreatedBefore.IsZero() {
+		params.CreatedBefore = models.NewTimestamp(filter.CreatedBefore)
+	}
+	return params
+}
+
+// sqlcFound returns the user read by a lookup that returned row and err,
+// recording on span whether it was found
+func sqlcFound(span trace.Span, row sqlcdb.User, err error) (*models.User, error) {
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			span.SetAttributes(attribute.Bool("user.found", false))
+			return nil, apperrors.NotFound("user not found")
+		}
+		return nil, fmt.Errorf("failed to get user: %w", err)
+	}
+
+	span.SetAttributes(attribute.Bool("user.found", true))
+	user := sqlcUser(row)
+	return &user, nil
+}
+
+// sqlcUser converts a row of the generated queries into a models.User
+func sqlcUser(row sqlcdb.User) models.User {
+	return models.User{
+		ID:        int(row.ID),
+		Name:      row.Name,
+		Email:     row.Email,
+		Bio:       row.Bio,
+		CreatedBy: row.CreatedBy,
+		UpdatedBy: row.UpdatedBy,
+		CreatedAt: row.CreatedAt,
+		UpdatedAt: row.UpdatedAt,
+		Version:   int(row.Version),
+	}
+}
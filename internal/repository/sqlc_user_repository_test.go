@@ -0,0 +1,84 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"regexp"
+	"testing"
+	"time"
+
+	"arquivolivre.com.br/otel/internal/models"
+	"arquivolivre.com.br/otel/pkg/apperrors"
+	"arquivolivre.com.br/otel/pkg/oteltest"
+
+	sqlmock "github.com/DATA-DOG/go-sqlmock"
+	"go.opentelemetry.io/otel/attribute"
+)
+
+func TestSqlcUserRepository_GetAllFilters(t *testing.T) {
+	db, mock, cleanup := newTestDB(t)
+	defer cleanup()
+	rec := oteltest.Install(t)
+	repo := NewSqlcUserRepository(db)
+
+	now := time.Now()
+	// Unset filters are sent as NULL, which matches every row
+	mock.ExpectQuery(regexp.QuoteMeta(`-- name: ListUsers :many`)).
+		WithArgs(`%ana\_%`, `%ana\_%`, `%ana\_%`, nil, nil, nil, nil, nil, nil, 10, 0).
+		WillReturnRows(sqlmock.NewRows(userColumns).AddRow(1, "Ana", "ana_1@example.com", nil, "system", "system", now, now, 1))
+
+	users, err := repo.GetAll(context.Background(), models.UserFilter{Query: "ana_"}, 10, 0)
+	if err != nil {
+		t.Fatalf("GetAll: %v", err)
+	}
+	if len(users) != 1 || users[0].ID != 1 || users[0].Bio != nil {
+		t.Fatalf("unexpected users %+v", users)
+	}
+	rec.AssertSpan(t, "SqlcUserRepository.GetAll",
+		attribute.String("filter.q", "ana_"),
+		attribute.Int("result.count", 1),
+		attribute.Bool("db.query.success", true),
+	)
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("expectations: %v", err)
+	}
+}
+
+func TestSqlcUserRepository_GetByIDNotFound(t *testing.T) {
+	db, mock, cleanup := newTestDB(t)
+	defer cleanup()
+	repo := NewSqlcUserRepository(db)
+
+	mock.ExpectQuery(regexp.QuoteMeta(`-- name: GetUser :one`)).WithArgs(7).WillReturnRows(sqlmock.NewRows(userColumns))
+
+	if _, err := repo.GetByID(context.Background(), 7); !errors.Is(err, apperrors.ErrNotFound) {
+		t.Fatalf("expected not found, got %v", err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("expectations: %v", err)
+	}
+}
+
+func TestSqlcUserRepository_UpdateWritesTheReadVersion(t *testing.T) {
+	db, mock, cleanup := newTestDB(t)
+	defer cleanup()
+	repo := NewSqlcUserRepository(db)
+
+	now := time.Now()
+	bio := "hi"
+	mock.ExpectQuery(regexp.QuoteMeta(`-- name: GetUser :one`)).WithArgs(1).WillReturnRows(
+		sqlmock.NewRows(userColumns).AddRow(1, "Ana", "ana@example.com", bio, "system", "system", now, now, 3))
+	// The fields missing from the request keep the values read above
+	mock.ExpectExec(regexp.QuoteMeta(`-- name: UpdateUser :execrows`)).
+		WithArgs("Ana Maria", "ana@example.com", bio, "system", 1, 3).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+
+	name := "Ana Maria"
+	_, err := repo.Update(context.Background(), 1, models.UpdateUserRequest{Name: &name})
+	if !errors.Is(err, ErrVersionMismatch) {
+		t.Fatalf("expected a concurrent update to be a version mismatch, got %v", err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("expectations: %v", err)
+	}
+}
@@ -0,0 +1,56 @@
+-- name: GetUser :one
+SELECT id, name, email, bio, created_by, updated_by, created_at, updated_at, version
+FROM users
+WHERE id = ?;
+
+-- name: GetUserByEmail :one
+SELECT id, name, email, bio, created_by, updated_by, created_at, updated_at, version
+FROM users
+WHERE email = ?;
+
+-- name: ListUsers :many
+SELECT id, name, email, bio, created_by, updated_by, created_at, updated_at, version
+FROM users
+WHERE (name LIKE sqlc.narg(pattern) OR email LIKE sqlc.narg(pattern) OR sqlc.narg(pattern) IS NULL)
+  AND (email = sqlc.narg(email) OR sqlc.narg(email) IS NULL)
+  AND (created_at > sqlc.narg(created_after) OR sqlc.narg(created_after) IS NULL)
+  AND (created_at < sqlc.narg(created_before) OR sqlc.narg(created_before) IS NULL)
+ORDER BY created_at DESC
+LIMIT ? OFFSET ?;
+
+-- name: ListUsersAfterID :many
+SELECT id, name, email, bio, created_by, updated_by, created_at, updated_at, version
+FROM users
+WHERE id > sqlc.arg(after_id)
+  AND (name LIKE sqlc.narg(pattern) OR email LIKE sqlc.narg(pattern) OR sqlc.narg(pattern) IS NULL)
+  AND (email = sqlc.narg(email) OR sqlc.narg(email) IS NULL)
+  AND (created_at > sqlc.narg(created_after) OR sqlc.narg(created_after) IS NULL)
+  AND (created_at < sqlc.narg(created_before) OR sqlc.narg(created_before) IS NULL)
+ORDER BY id
+LIMIT ?;
+
+-- name: ListUsersByIDs :many
+SELECT id, name, email, bio, created_by, updated_by, created_at, updated_at, version
+FROM users
+WHERE id IN (sqlc.slice(ids));
+
+-- name: CountUsers :one
+SELECT COUNT(*)
+FROM users
+WHERE (name LIKE sqlc.narg(pattern) OR email LIKE sqlc.narg(pattern) OR sqlc.narg(pattern) IS NULL)
+  AND (email = sqlc.narg(email) OR sqlc.narg(email) IS NULL)
+  AND (created_at > sqlc.narg(created_after) OR sqlc.narg(created_after) IS NULL)
+  AND (created_at < sqlc.narg(created_before) OR sqlc.narg(created_before) IS NULL);
+
+-- name: CreateUser :execlastid
+INSERT INTO users (name, email, bio, created_by, updated_by)
+VALUES (?, ?, ?, ?, ?);
+
+-- name: UpdateUser :execrows
+UPDATE users
+SET name = ?, email = ?, bio = ?, updated_by = ?, updated_at = NOW(), version = version + 1
+WHERE id = ? AND version = ?;
+
+-- name: DeleteUser :execrows
+DELETE FROM users
+WHERE id = ?;
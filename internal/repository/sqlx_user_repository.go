@@ -0,0 +1,371 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"arquivolivre.com.br/otel/internal/auth"
+	"arquivolivre.com.br/otel/internal/database"
+	"arquivolivre.com.br/otel/internal/models"
+	"arquivolivre.com.br/otel/pkg/apperrors"
+
+	"github.com/jmoiron/sqlx"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// SqlxUserRepository implements UserStore with sqlx, which scans rows into
+// models.User by its db tags instead of listing each field. It runs on the
+// connection pools of database.DB, so otelsql traces its queries like those
+// of UserRepository; only the repository spans are its own. It does not
+// write to the outbox or prepare statements
+type SqlxUserRepository struct {
+	db      *database.DB
+	primary *sqlx.DB
+	// replica is nil when db has no read replica
+	replica *sqlx.DB
+	tracer  trace.Tracer
+}
+
+var _ UserStore = (*SqlxUserRepository)(nil)
+
+func NewSqlxUserRepository(db *database.DB) *SqlxUserRepository {
+	r := &SqlxUserRepository{
+		db:      db,
+		primary: sqlx.NewDb(db.DB, "mysql"),
+		tracer:  otel.Tracer("sqlx-user-repository"),
+	}
+	if replica := db.Replica(); replica != nil {
+		r.replica = sqlx.NewDb(replica.DB, "mysql")
+	}
+	return r
+}
+
+// sqlxUserColumns are the columns of models.User, named like its db tags
+const sqlxUserColumns = "id, name, email, bio, created_by, updated_by, created_at, updated_at, version"
+
+// reader returns the pool that serves the lookups of ctx, see
+// database.DB.Reader, and its sqlx handle
+func (r *SqlxUserRepository) reader(ctx context.Context) (*database.DB, *sqlx.DB) {
+	db := r.db.Reader(ctx)
+	if db != r.db && r.replica != nil {
+		return db, r.replica
+	}
+	return db, r.primary
+}
+
+// GetAll returns a page of the users matching filter, newest first
+func (r *SqlxUserRepository) GetAll(ctx context.Context, filter models.UserFilter, limit, offset int) ([]models.User, error) {
+	return instrumentedQuery(ctx, r.tracer, r.db, "SqlxUserRepository.GetAll", "SELECT", "users", func(ctx context.Context, span trace.Span) ([]models.User, error) {
+		db, x := r.reader(ctx)
+		span.SetAttributes(
+			attribute.Int("pagination.limit", limit),
+			attribute.Int("pagination.offset", offset),
+		)
+		span.SetAttributes(FilterAttributes(filter)...)
+
+		where, args := filterClause(filter)
+		query := "SELECT " + sqlxUserColumns + " FROM users" + where + " ORDER BY created_at DESC LIMIT ? OFFSET ?"
+
+		var users []models.User
+		done := db.TrackQuery(ctx, "SELECT", "users", query)
+		err := x.SelectContext(ctx, &users, query, append(args, limit, offset)...)
+		done(err)
+		if err != nil {
+			return nil, fmt.Errorf("failed to query users: %w", err)
+		}
+
+		span.SetAttributes(attribute.Int("result.count", len(users)))
+		return users, nil
+	})
+}
+
+// Stream calls fn with each user matching filter in ID order, scanning one
+// row at a time. Like UserRepository.Stream, the query timeout does not apply
+func (r *SqlxUserRepository) Stream(ctx context.Context, filter models.UserFilter, fn func(*models.User) error) (err error) {
+	ctx, span, end := startQuery(ctx, r.tracer, "SqlxUserRepository.Stream", "SELECT", "users")
+	defer span.End()
+	defer func() { end(err) }()
+	db, x := r.reader(ctx)
+	span.SetAttributes(FilterAttributes(filter)...)
+
+	where, args := filterClause(filter)
+	query := "SELECT " + sqlxUserColumns + " FROM users" + where + " ORDER BY id"
+
+	done := db.TrackQuery(ctx, "SELECT", "users", query)
+	rows, err := x.QueryxContext(ctx, query, args...)
+	done(err)
+	if err != nil {
+		return fmt.Errorf("failed to query users: %w", err)
+	}
+	defer func() { _ = rows.Close() }()
+
+	count := 0
+	for rows.Next() {
+		var user models.User
+		if err := rows.StructScan(&user); err != nil {
+			return fmt.Errorf("failed to scan user: %w", err)
+		}
+		if err := fn(&user); err != nil {
+			return err
+		}
+		count++
+	}
+	span.SetAttributes(attribute.Int("result.count", count))
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("error iterating over users: %w", err)
+	}
+	return nil
+}
+
+func (r *SqlxUserRepository) GetByID(ctx context.Context, id int) (*models.User, error) {
+	return instrumentedQuery(ctx, r.tracer, r.db, "SqlxUserRepository.GetByID", "SELECT", "users", func(ctx context.Context, span trace.Span) (*models.User, error) {
+		span.SetAttributes(attribute.Int("user.id", id))
+		return r.get(ctx, span, "SELECT "+sqlxUserColumns+" FROM users WHERE id = ?", id)
+	})
+}
+
+// GetByEmail retrieves a user by email
+func (r *SqlxUserRepository) GetByEmail(ctx context.Context, email string) (*models.User, error) {
+	return instrumentedQuery(ctx, r.tracer, r.db, "SqlxUserRepository.GetByEmail", "SELECT", "users", func(ctx context.Context, span trace.Span) (*models.User, error) {
+		span.SetAttributes(attribute.String("user.email", email))
+		return r.get(ctx, span, "SELECT "+sqlxUserColumns+" FROM users WHERE email = ?", email)
+	})
+}
+
+// get loads the single user selected by query, recording on span whether it
+// was found
+func (r *SqlxUserRepository) get(ctx context.Context, span trace.Span, query string, args ...interface{}) (*models.User, error) {
+	db, x := r.reader(ctx)
+
+	var user models.User
+	done := db.TrackQuery(ctx, "SELECT", "users", query)
+	err := x.GetContext(ctx, &user, query, args...)
+	done(err)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			span.SetAttributes(attribute.Bool("user.found", false))
+			return nil, apperrors.NotFound("user not found")
+		}
+		return nil, fmt.Errorf("failed to get user: %w", err)
+	}
+
+	span.SetAttributes(attribute.Bool("user.found", true))
+	return &user, nil
+}
+
+func (r *SqlxUserRepository) Create(ctx context.Context, req models.CreateUserRequest) (*models.User, error) {
+	// Lookups of the write must see its own changes
+	ctx = database.ReadPrimary(ctx)
+	return instrumentedQuery(ctx, r.tracer, r.db, "SqlxUserRepository.Create", "INSERT", "users", func(ctx context.Context, span trace.Span) (*models.User, error) {
+		actor := auth.Actor(ctx)
+		span.SetAttributes(
+			attribute.String("user.name", req.Name),
+			attribute.String("user.email", req.Email),
+			attribute.String("enduser.id", actor),
+		)
+
+		id, err := r.insert(ctx, r.primary, req, actor)
+		if err != nil {
+			return nil, err
+		}
+
+		span.SetAttributes(attribute.Int("user.id", id))
+		return r.GetByID(ctx, id)
+	})
+}
+
+// insert creates the user of req on behalf of actor through e and returns
+// its ID. A duplicate email is returned as apperrors.ErrConflict
+func (r *SqlxUserRepository) insert(ctx context.Context, e sqlx.ExecerContext, req models.CreateUserRequest, actor string) (int, error) {
+	user := models.User{Name: req.Name, Email: req.Email, Bio: req.Bio, CreatedBy: actor, UpdatedBy: actor}
+	query, args, err := sqlx.Named(`
+		INSERT INTO users (name, email, bio, created_by, updated_by)
+		VALUES (:name, :email, :bio, :created_by, :updated_by)
+	`, user)
+	if err != nil {
+		return 0, fmt.Errorf("failed to bind user: %w", err)
+	}
+
+	done := r.db.TrackQuery(ctx, "INSERT", "users", query)
+	result, err := e.ExecContext(ctx, query, args...)
+	done(err)
+	if err != nil {
+		return 0, mapWriteError(err, "failed to create user")
+	}
+
+	id, err := result.LastInsertId()
+	if err != nil {
+		return 0, fmt.Errorf("failed to get last insert id: %w", err)
+	}
+	return int(id), nil
+}
+
+// CreateBatch creates the users of reqs in one transaction and returns a
+// result per request, in order. Like UserRepository.CreateBatch, a duplicate
+// email only fails its own request
+func (r *SqlxUserRepository) CreateBatch(ctx context.Context, reqs []models.CreateUserRequest) ([]BatchResult, error) {
+	return instrumentedQuery(ctx, r.tracer, r.db, "SqlxUserRepository.CreateBatch", "INSERT", "users", func(ctx context.Context, span trace.Span) ([]BatchResult, error) {
+		actor := auth.Actor(ctx)
+		span.SetAttributes(
+			attribute.Int("batch.size", len(reqs)),
+			attribute.String("enduser.id", actor),
+		)
+
+		tx, err := r.primary.BeginTxx(ctx, nil)
+		if err != nil {
+			return nil, fmt.Errorf("failed to begin transaction: %w", err)
+		}
+		// A no-op once the transaction is committed
+		defer func() { _ = tx.Rollback() }()
+
+		results := make([]BatchResult, len(reqs))
+		ids := make([]int, len(reqs))
+		var created []int
+		for i, req := range reqs {
+			id, err := r.insert(ctx, tx, req, actor)
+			if err != nil {
+				if !errors.Is(err, apperrors.ErrConflict) {
+					return nil, err
+				}
+				results[i].Err = err
+				continue
+			}
+			ids[i] = id
+			created = append(created, id)
+		}
+
+		if err := tx.Commit(); err != nil {
+			return nil, fmt.Errorf("failed to commit transaction: %w", err)
+		}
+		span.SetAttributes(
+			attribute.Int("batch.created", len(created)),
+			attribute.Int("batch.failed", len(reqs)-len(created)),
+		)
+		if len(created) == 0 {
+			return results, nil
+		}
+
+		query, args, err := sqlx.In("SELECT "+sqlxUserColumns+" FROM users WHERE id IN (?)", created)
+		if err != nil {
+			return nil, fmt.Errorf("failed to bind user IDs: %w", err)
+		}
+		var users []models.User
+		done := r.db.TrackQuery(ctx, "SELECT", "users", query)
+		err = r.primary.SelectContext(ctx, &users, query, args...)
+		done(err)
+		if err != nil {
+			return nil, fmt.Errorf("failed to query users: %w", err)
+		}
+
+		byID := make(map[int]models.User, len(users))
+		for _, user := range users {
+			byID[user.ID] = user
+		}
+		for i, id := range ids {
+			if id == 0 {
+				continue
+			}
+			if user, ok := byID[id]; ok {
+				results[i].User = &user
+			} else {
+				results[i].Err = apperrors.NotFound("user not found")
+			}
+		}
+		return results, nil
+	})
+}
+
+// Update updates an existing user and increments its version, with the same
+// version checks as UserRepository.Update
+func (r *SqlxUserRepository) Update(ctx context.Context, id int, req models.UpdateUserRequest) (*models.User, error) {
+	// Lookups of the write must see its own changes
+	ctx = database.ReadPrimary(ctx)
+	return instrumentedQuery(ctx, r.tracer, r.db, "SqlxUserRepository.Update", "UPDATE", "users", func(ctx context.Context, span trace.Span) (*models.User, error) {
+		actor := auth.Actor(ctx)
+		span.SetAttributes(
+			attribute.Int("user.id", id),
+			attribute.String("enduser.id", actor),
+		)
+
+		existingUser, err := r.GetByID(ctx, id)
+		if err != nil {
+			return nil, err
+		}
+		if req.Version != nil {
+			span.SetAttributes(attribute.Int("user.version", *req.Version))
+			if existingUser.Version != *req.Version {
+				span.SetAttributes(attribute.Bool("user.version_mismatch", true))
+				return nil, ErrVersionMismatch
+			}
+		}
+
+		query, args := updateStatement(span, id, req, actor)
+		if query == "" {
+			span.SetAttributes(attribute.Bool("user.no_changes", true))
+			return existingUser, nil
+		}
+
+		done := r.db.TrackQuery(ctx, "UPDATE", "users", query)
+		result, err := r.primary.ExecContext(ctx, query, args...)
+		done(err)
+		if err != nil {
+			return nil, mapWriteError(err, "failed to update user")
+		}
+		if updated, err := result.RowsAffected(); err == nil && updated == 0 {
+			span.SetAttributes(attribute.Bool("user.version_mismatch", true))
+			return nil, ErrVersionMismatch
+		}
+
+		return r.GetByID(ctx, id)
+	})
+}
+
+// Delete deletes a user by ID; its posts are removed by the foreign key
+func (r *SqlxUserRepository) Delete(ctx context.Context, id int) error {
+	return instrumentedExec(ctx, r.tracer, r.db, "SqlxUserRepository.Delete", "DELETE", "users", func(ctx context.Context, span trace.Span) error {
+		span.SetAttributes(
+			attribute.Int("user.id", id),
+			attribute.String("enduser.id", auth.Actor(ctx)),
+		)
+
+		query := "DELETE FROM users WHERE id = ?"
+		done := r.db.TrackQuery(ctx, "DELETE", "users", query)
+		result, err := r.primary.ExecContext(ctx, query, id)
+		done(err)
+		if err != nil {
+			return fmt.Errorf("failed to delete user: %w", err)
+		}
+		if deleted, err := result.RowsAffected(); err == nil && deleted == 0 {
+			return apperrors.NotFound("user not found")
+		}
+
+		span.SetAttributes(attribute.Bool("user.deleted", true))
+		return nil
+	})
+}
+
+// Count returns the number of users matching filter
+func (r *SqlxUserRepository) Count(ctx context.Context, filter models.UserFilter) (int, error) {
+	return instrumentedQuery(ctx, r.tracer, r.db, "SqlxUserRepository.Count", "SELECT", "users", func(ctx context.Context, span trace.Span) (int, error) {
+		db, x := r.reader(ctx)
+		span.SetAttributes(FilterAttributes(filter)...)
+
+		where, args := filterClause(filter)
+		query := "SELECT COUNT(*) FROM users" + where
+
+		var count int
+		done := db.TrackQuery(ctx, "SELECT", "users", query)
+		err := x.GetContext(ctx, &count, query, args...)
+		done(err)
+		if err != nil {
+			return 0, fmt.Errorf("failed to count users: %w", err)
+		}
+
+		span.SetAttributes(attribute.Int("result.count", count))
+		return count, nil
+	})
+}
@@ -0,0 +1,100 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"regexp"
+	"testing"
+	"time"
+
+	"arquivolivre.com.br/otel/internal/models"
+	"arquivolivre.com.br/otel/pkg/apperrors"
+	"arquivolivre.com.br/otel/pkg/oteltest"
+
+	sqlmock "github.com/DATA-DOG/go-sqlmock"
+	"github.com/go-sql-driver/mysql"
+	"go.opentelemetry.io/otel/attribute"
+)
+
+func TestSqlxUserRepository_GetByID(t *testing.T) {
+	db, mock, cleanup := newTestDB(t)
+	defer cleanup()
+	rec := oteltest.Install(t)
+	repo := NewSqlxUserRepository(db)
+
+	now := time.Now()
+	mock.ExpectQuery(regexp.QuoteMeta(`FROM users WHERE id = ?`)).WithArgs(1).WillReturnRows(
+		sqlmock.NewRows(userColumns).AddRow(1, "Ana", "ana@example.com", "hi", "system", "system", now, now, 2))
+	mock.ExpectQuery(regexp.QuoteMeta(`FROM users WHERE id = ?`)).WithArgs(2).WillReturnRows(sqlmock.NewRows(userColumns))
+
+	user, err := repo.GetByID(context.Background(), 1)
+	if err != nil {
+		t.Fatalf("GetByID: %v", err)
+	}
+	if user.Name != "Ana" || user.Bio == nil || *user.Bio != "hi" || user.Version != 2 {
+		t.Fatalf("unexpected user %+v", user)
+	}
+	if _, err := repo.GetByID(context.Background(), 2); !errors.Is(err, apperrors.ErrNotFound) {
+		t.Fatalf("expected not found, got %v", err)
+	}
+
+	rec.AssertSpan(t, "SqlxUserRepository.GetByID",
+		attribute.Int("user.id", 2),
+		attribute.Bool("user.found", false),
+		attribute.Bool("db.query.success", true),
+	)
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("expectations: %v", err)
+	}
+}
+
+func TestSqlxUserRepository_CreateBatch(t *testing.T) {
+	db, mock, cleanup := newTestDB(t)
+	defer cleanup()
+	repo := NewSqlxUserRepository(db)
+
+	now := time.Now()
+	mock.ExpectBegin()
+	mock.ExpectExec(regexp.QuoteMeta(`INSERT INTO users`)).
+		WithArgs("Ana", "ana@example.com", nil, "system", "system").
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectExec(regexp.QuoteMeta(`INSERT INTO users`)).
+		WillReturnError(&mysql.MySQLError{Number: mysqlErrDuplicateEntry, Message: "Duplicate entry"})
+	mock.ExpectCommit()
+	mock.ExpectQuery(regexp.QuoteMeta(`FROM users WHERE id IN (?)`)).WithArgs(1).WillReturnRows(
+		sqlmock.NewRows(userColumns).AddRow(1, "Ana", "ana@example.com", nil, "system", "system", now, now, 1))
+
+	results, err := repo.CreateBatch(context.Background(), []models.CreateUserRequest{
+		{Name: "Ana", Email: "ana@example.com"},
+		{Name: "Dup", Email: "ana@example.com"},
+	})
+	if err != nil {
+		t.Fatalf("CreateBatch: %v", err)
+	}
+	if results[0].User == nil || results[0].User.ID != 1 || !errors.Is(results[1].Err, apperrors.ErrConflict) {
+		t.Fatalf("unexpected batch results %+v", results)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("expectations: %v", err)
+	}
+}
+
+func TestSqlxUserRepository_UpdateChecksVersion(t *testing.T) {
+	db, mock, cleanup := newTestDB(t)
+	defer cleanup()
+	repo := NewSqlxUserRepository(db)
+
+	now := time.Now()
+	mock.ExpectQuery(regexp.QuoteMeta(`FROM users WHERE id = ?`)).WithArgs(1).WillReturnRows(
+		sqlmock.NewRows(userColumns).AddRow(1, "Ana", "ana@example.com", nil, "system", "system", now, now, 3))
+
+	name := "Ana Maria"
+	version := 2
+	_, err := repo.Update(context.Background(), 1, models.UpdateUserRequest{Name: &name, Version: &version})
+	if !errors.Is(err, ErrVersionMismatch) {
+		t.Fatalf("expected a version mismatch, got %v", err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("expectations: %v", err)
+	}
+}
@@ -0,0 +1,92 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"sync"
+
+	"arquivolivre.com.br/otel/internal/database"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// statementCache holds the statements prepared by a repository, keyed by
+// their pool and query. A statement prepared on a pool is prepared again by
+// database/sql on each connection it runs on
+type statementCache struct {
+	mu    sync.Mutex
+	stmts map[statementKey]*sql.Stmt
+}
+
+type statementKey struct {
+	db    *database.DB
+	query string
+}
+
+func newStatementCache() *statementCache {
+	return &statementCache{stmts: make(map[statementKey]*sql.Stmt)}
+}
+
+// get returns the statement of query on db, preparing it on first use
+func (c *statementCache) get(ctx context.Context, db *database.DB, query string) (*sql.Stmt, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	key := statementKey{db, query}
+	if stmt, ok := c.stmts[key]; ok {
+		return stmt, nil
+	}
+	stmt, err := db.PrepareContext(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	c.stmts[key] = stmt
+	return stmt, nil
+}
+
+// close closes every prepared statement
+func (c *statementCache) close() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	var errs []error
+	for key, stmt := range c.stmts {
+		errs = append(errs, stmt.Close())
+		delete(c.stmts, key)
+	}
+	return errors.Join(errs...)
+}
+
+// WithPreparedStatements returns a copy of the repository that prepares the
+// queries of GetByID and GetByEmail on first use and reuses them, sparing the
+// server from parsing them again on each call. Close releases them
+func (r *UserRepository) WithPreparedStatements() *UserRepository {
+	clone := *r
+	clone.statements = newStatementCache()
+	return &clone
+}
+
+// Close closes the prepared statements of the repository, if any
+func (r *UserRepository) Close() error {
+	if r.statements == nil {
+		return nil
+	}
+	return r.statements.close()
+}
+
+// queryRow runs query on db, through its prepared statement when the
+// repository prepares statements, and records on span whether it did. A
+// statement that fails to prepare is recorded on span and query runs
+// unprepared
+func (r *UserRepository) queryRow(ctx context.Context, span trace.Span, db *database.DB, query string, args ...any) *sql.Row {
+	if r.statements != nil {
+		stmt, err := r.statements.get(ctx, db, query)
+		if err == nil {
+			span.SetAttributes(attribute.Bool("db.statement.prepared", true))
+			return stmt.QueryRowContext(ctx, args...)
+		}
+		span.RecordError(err)
+	}
+	span.SetAttributes(attribute.Bool("db.statement.prepared", false))
+	return db.QueryRowContext(ctx, query, args...)
+}
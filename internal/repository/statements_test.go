@@ -0,0 +1,70 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"regexp"
+	"testing"
+	"time"
+
+	"arquivolivre.com.br/otel/pkg/oteltest"
+
+	sqlmock "github.com/DATA-DOG/go-sqlmock"
+	"go.opentelemetry.io/otel/attribute"
+)
+
+func TestWithPreparedStatements_ReusesStatements(t *testing.T) {
+	db, mock, cleanup := newTestDB(t)
+	defer cleanup()
+	rec := oteltest.Install(t)
+	repo := NewUserRepository(db).WithPreparedStatements()
+
+	now := time.Now()
+	byID := mock.ExpectPrepare(regexp.QuoteMeta(`WHERE id = ?`))
+	byID.ExpectQuery().WithArgs(1).WillReturnRows(
+		sqlmock.NewRows(userColumns).AddRow(1, "A", "a@example.com", nil, "system", "system", now, now, 1))
+	byID.ExpectQuery().WithArgs(2).WillReturnRows(sqlmock.NewRows(userColumns))
+	byEmail := mock.ExpectPrepare(regexp.QuoteMeta(`WHERE email = ?`))
+	byEmail.ExpectQuery().WithArgs("a@example.com").WillReturnRows(
+		sqlmock.NewRows(userColumns).AddRow(1, "A", "a@example.com", nil, "system", "system", now, now, 1))
+	byID.WillBeClosed()
+	byEmail.WillBeClosed()
+
+	if u, err := repo.GetByID(context.Background(), 1); err != nil || u.ID != 1 {
+		t.Fatalf("GetByID = %v, %v", u, err)
+	}
+	rec.AssertSpan(t, "UserRepository.GetByID", attribute.Bool("db.statement.prepared", true))
+	if _, err := repo.GetByID(context.Background(), 2); err == nil {
+		t.Fatal("expected user 2 to be not found")
+	}
+	if u, err := repo.GetByEmail(context.Background(), "a@example.com"); err != nil || u.ID != 1 {
+		t.Fatalf("GetByEmail = %v, %v", u, err)
+	}
+	if err := repo.Close(); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("expectations: %v", err)
+	}
+}
+
+func TestWithPreparedStatements_FallsBackWhenPrepareFails(t *testing.T) {
+	db, mock, cleanup := newTestDB(t)
+	defer cleanup()
+	rec := oteltest.Install(t)
+	repo := NewUserRepository(db).WithPreparedStatements()
+
+	mock.ExpectPrepare(regexp.QuoteMeta(`WHERE id = ?`)).WillReturnError(errors.New("prepare failed"))
+	mock.ExpectQuery(regexp.QuoteMeta(`WHERE id = ?`)).WithArgs(1).WillReturnRows(sqlmock.NewRows(userColumns))
+
+	if _, err := repo.GetByID(context.Background(), 1); err == nil {
+		t.Fatal("expected user 1 to be not found")
+	}
+	span := rec.AssertSpan(t, "UserRepository.GetByID", attribute.Bool("db.statement.prepared", false))
+	if span != nil && len(span.Events()) == 0 {
+		t.Fatal("expected the failed prepare to be recorded on the span")
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("expectations: %v", err)
+	}
+}
@@ -3,12 +3,19 @@ package repository
 import (
 	"context"
 	"database/sql"
+	"errors"
 	"fmt"
+	"strings"
 	"time"
 
+	"arquivolivre.com.br/otel/internal/auth"
 	"arquivolivre.com.br/otel/internal/database"
+	"arquivolivre.com.br/otel/internal/events"
 	"arquivolivre.com.br/otel/internal/models"
+	"arquivolivre.com.br/otel/internal/outbox"
+	"arquivolivre.com.br/otel/pkg/apperrors"
 
+	"github.com/go-sql-driver/mysql"
 	"go.opentelemetry.io/otel"
 	"go.opentelemetry.io/otel/attribute"
 	"go.opentelemetry.io/otel/trace"
@@ -17,8 +24,14 @@ import (
 type UserRepository struct {
 	db     *database.DB
 	tracer trace.Tracer
+	// outbox records a user event with each write, see WithOutbox
+	outbox bool
+	// statements caches the prepared lookups, see WithPreparedStatements
+	statements *statementCache
 }
 
+var _ UserStore = (*UserRepository)(nil)
+
 func NewUserRepository(db *database.DB) *UserRepository {
 	return &UserRepository{
 		db:     db,
@@ -26,186 +39,370 @@ func NewUserRepository(db *database.DB) *UserRepository {
 	}
 }
 
+// WithOutbox returns a copy of the repository that stores a user event in the
+// outbox table in the same transaction as each create, update and delete,
+// for internal/outbox to publish
+func (r *UserRepository) WithOutbox() *UserRepository {
+	clone := *r
+	clone.outbox = true
+	return &clone
+}
+
+// UserStore is the user persistence the handlers depend on; UserRepository
+// implements it on MySQL
 type UserStore interface {
-	GetAll(ctx context.Context, limit, offset int) ([]models.User, error)
+	GetAll(ctx context.Context, filter models.UserFilter, limit, offset int) ([]models.User, error)
+	Stream(ctx context.Context, filter models.UserFilter, fn func(*models.User) error) error
 	GetByID(ctx context.Context, id int) (*models.User, error)
 	Create(ctx context.Context, req models.CreateUserRequest) (*models.User, error)
+	CreateBatch(ctx context.Context, reqs []models.CreateUserRequest) ([]BatchResult, error)
 	Update(ctx context.Context, id int, req models.UpdateUserRequest) (*models.User, error)
 	Delete(ctx context.Context, id int) error
-	Count(ctx context.Context) (int, error)
+	Count(ctx context.Context, filter models.UserFilter) (int, error)
 	GetByEmail(ctx context.Context, email string) (*models.User, error)
 }
 
-func (r *UserRepository) GetAll(ctx context.Context, limit, offset int) ([]models.User, error) {
-	ctx, span := r.tracer.Start(ctx, "UserRepository.GetAll")
-	defer span.End()
+// BatchResult is the outcome of one request of CreateBatch: the created
+// user, or the error that kept it from being created
+type BatchResult struct {
+	User *models.User
+	Err  error
+}
+
+// ErrVersionMismatch is returned by Update when the request's version is not
+// the user's current version. It matches apperrors.ErrConflict
+var ErrVersionMismatch = apperrors.Conflict("user was modified by another request")
+
+// GetAll returns a page of the users matching filter, newest first
+func (r *UserRepository) GetAll(ctx context.Context, filter models.UserFilter, limit, offset int) ([]models.User, error) {
+	return instrumentedQuery(ctx, r.tracer, r.db, "UserRepository.GetAll", "SELECT", "users", func(ctx context.Context, span trace.Span) ([]models.User, error) {
+		db := r.db.Reader(ctx)
+		span.SetAttributes(
+			attribute.Int("pagination.limit", limit),
+			attribute.Int("pagination.offset", offset),
+		)
+		span.SetAttributes(FilterAttributes(filter)...)
+
+		where, args := filterClause(filter)
+		query := `
+			SELECT id, name, email, bio, created_by, updated_by, created_at, updated_at, version
+			FROM users` + where + `
+			ORDER BY created_at DESC
+			LIMIT ? OFFSET ?
+		`
+
+		done := db.TrackQuery(ctx, "SELECT", "users", query)
+		rows, err := db.QueryContext(ctx, query, append(args, limit, offset)...)
+		done(err)
+		if err != nil {
+			return nil, fmt.Errorf("failed to query users: %w", err)
+		}
+		defer func() { _ = rows.Close() }()
+
+		var users []models.User
+		for rows.Next() {
+			var user models.User
+			if err := rows.Scan(userFields(&user)...); err != nil {
+				return nil, fmt.Errorf("failed to scan user: %w", err)
+			}
+			users = append(users, user)
+		}
+		if err := rows.Err(); err != nil {
+			return nil, fmt.Errorf("error iterating over users: %w", err)
+		}
 
-	span.SetAttributes(
-		attribute.Int("pagination.limit", limit),
-		attribute.Int("pagination.offset", offset),
-		attribute.String("db.operation", "SELECT"),
-		attribute.String("db.table", "users"),
-	)
+		span.SetAttributes(attribute.Int("result.count", len(users)))
+		return users, nil
+	})
+}
 
+// Stream calls fn with each user matching filter in ID order, scanning one
+// row at a time so the result is never held in memory. It stops at the first
+// error fn returns. The query timeout does not apply, since a large export
+// may legitimately outlast it; ctx bounds the stream instead
+func (r *UserRepository) Stream(ctx context.Context, filter models.UserFilter, fn func(*models.User) error) (err error) {
+	ctx, span, end := startQuery(ctx, r.tracer, "UserRepository.Stream", "SELECT", "users")
+	defer span.End()
+	defer func() { end(err) }()
+	db := r.db.Reader(ctx)
+	span.SetAttributes(FilterAttributes(filter)...)
+
+	where, args := filterClause(filter)
 	query := `
-		SELECT id, name, email, bio, created_at, updated_at
-		FROM users
-		ORDER BY created_at DESC
-		LIMIT ? OFFSET ?
+		SELECT id, name, email, bio, created_by, updated_by, created_at, updated_at, version
+		FROM users` + where + `
+		ORDER BY id
 	`
 
-	start := time.Now()
-	rows, err := r.db.QueryContext(ctx, query, limit, offset)
-	duration := time.Since(start)
-
-	r.db.RecordQueryMetrics(ctx, "SELECT", "users", duration, err)
-
+	done := db.TrackQuery(ctx, "SELECT", "users", query)
+	rows, err := db.QueryContext(ctx, query, args...)
+	done(err)
 	if err != nil {
-		span.SetAttributes(attribute.Bool("db.query.success", false))
-		return nil, fmt.Errorf("failed to query users: %w", err)
+		return fmt.Errorf("failed to query users: %w", err)
 	}
 	defer func() { _ = rows.Close() }()
 
-	var users []models.User
+	count := 0
 	for rows.Next() {
 		var user models.User
-		err := rows.Scan(
-			&user.ID,
-			&user.Name,
-			&user.Email,
-			&user.Bio,
-			&user.CreatedAt,
-			&user.UpdatedAt,
-		)
-		if err != nil {
-			span.SetAttributes(attribute.Bool("db.query.success", false))
-			return nil, fmt.Errorf("failed to scan user: %w", err)
+		if err := rows.Scan(userFields(&user)...); err != nil {
+			return fmt.Errorf("failed to scan user: %w", err)
+		}
+		if err := fn(&user); err != nil {
+			return err
 		}
-		users = append(users, user)
+		count++
 	}
-
-	if err = rows.Err(); err != nil {
-		span.SetAttributes(attribute.Bool("db.query.success", false))
-		return nil, fmt.Errorf("error iterating over users: %w", err)
+	span.SetAttributes(attribute.Int("result.count", count))
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("error iterating over users: %w", err)
 	}
-
-	span.SetAttributes(
-		attribute.Int("result.count", len(users)),
-		attribute.Bool("db.query.success", true),
-	)
-
-	return users, nil
+	return nil
 }
 
 func (r *UserRepository) GetByID(ctx context.Context, id int) (*models.User, error) {
-	ctx, span := r.tracer.Start(ctx, "UserRepository.GetByID")
-	defer span.End()
+	return instrumentedQuery(ctx, r.tracer, r.db, "UserRepository.GetByID", "SELECT", "users", func(ctx context.Context, span trace.Span) (*models.User, error) {
+		db := r.db.Reader(ctx)
+		span.SetAttributes(attribute.Int("user.id", id))
 
-	span.SetAttributes(
-		attribute.Int("user.id", id),
-		attribute.String("db.operation", "SELECT"),
-		attribute.String("db.table", "users"),
-	)
+		query := `
+			SELECT id, name, email, bio, created_by, updated_by, created_at, updated_at, version
+			FROM users
+			WHERE id = ?
+		`
 
-	query := `
-		SELECT id, name, email, bio, created_at, updated_at
-		FROM users
-		WHERE id = ?
-	`
-
-	start := time.Now()
-	row := r.db.QueryRowContext(ctx, query, id)
-	duration := time.Since(start)
+		var user models.User
+		done := db.TrackQuery(ctx, "SELECT", "users", query)
+		err := r.queryRow(ctx, span, db, query, id).Scan(userFields(&user)...)
+		done(err)
+		if err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				span.SetAttributes(attribute.Bool("user.found", false))
+				return nil, apperrors.NotFound("user not found")
+			}
+			return nil, fmt.Errorf("failed to get user: %w", err)
+		}
 
-	var user models.User
-	err := row.Scan(
-		&user.ID,
-		&user.Name,
-		&user.Email,
-		&user.Bio,
-		&user.CreatedAt,
-		&user.UpdatedAt,
-	)
+		span.SetAttributes(attribute.Bool("user.found", true))
+		return &user, nil
+	})
+}
 
-	r.db.RecordQueryMetrics(ctx, "SELECT", "users", duration, err)
+func (r *UserRepository) Create(ctx context.Context, req models.CreateUserRequest) (*models.User, error) {
+	// Lookups of the write must see its own changes
+	ctx = database.ReadPrimary(ctx)
+	return instrumentedQuery(ctx, r.tracer, r.db, "UserRepository.Create", "INSERT", "users", func(ctx context.Context, span trace.Span) (*models.User, error) {
+		actor := auth.Actor(ctx)
+		span.SetAttributes(
+			attribute.String("user.name", req.Name),
+			attribute.String("user.email", req.Email),
+			attribute.String("enduser.id", actor),
+		)
 
-	if err != nil {
-		if err == sql.ErrNoRows {
-			span.SetAttributes(
-				attribute.Bool("user.found", false),
-				attribute.Bool("db.query.success", true),
-			)
-			return nil, fmt.Errorf("user not found")
+		query := `
+			INSERT INTO users (name, email, bio, created_by, updated_by)
+			VALUES (?, ?, ?, ?, ?)
+		`
+
+		id, err := r.write(ctx, events.UserCreated, func(q outbox.Execer) (int, error) {
+			done := r.db.TrackQuery(ctx, "INSERT", "users", query)
+			result, err := q.ExecContext(ctx, query, req.Name, req.Email, req.Bio, actor, actor)
+			done(err)
+			if err != nil {
+				return 0, mapWriteError(err, "failed to create user")
+			}
+
+			id, err := result.LastInsertId()
+			if err != nil {
+				return 0, fmt.Errorf("failed to get last insert id: %w", err)
+			}
+			return int(id), nil
+		})
+		if err != nil {
+			return nil, err
 		}
-		span.SetAttributes(attribute.Bool("db.query.success", false))
-		return nil, fmt.Errorf("failed to get user: %w", err)
-	}
 
-	span.SetAttributes(
-		attribute.Bool("user.found", true),
-		attribute.Bool("db.query.success", true),
-	)
-	return &user, nil
+		span.SetAttributes(attribute.Int("user.id", id))
+		return r.GetByID(ctx, id)
+	})
 }
 
-func (r *UserRepository) Create(ctx context.Context, req models.CreateUserRequest) (*models.User, error) {
-	ctx, span := r.tracer.Start(ctx, "UserRepository.Create")
-	defer span.End()
+// CreateBatch creates the users of reqs in one transaction and returns a
+// result per request, in order. A duplicate email only fails its own
+// request; any other error rolls the transaction back and is returned
+func (r *UserRepository) CreateBatch(ctx context.Context, reqs []models.CreateUserRequest) ([]BatchResult, error) {
+	return instrumentedQuery(ctx, r.tracer, r.db, "UserRepository.CreateBatch", "INSERT", "users", func(ctx context.Context, span trace.Span) ([]BatchResult, error) {
+		actor := auth.Actor(ctx)
+		span.SetAttributes(
+			attribute.Int("batch.size", len(reqs)),
+			attribute.String("enduser.id", actor),
+		)
 
-	span.SetAttributes(
-		attribute.String("user.name", req.Name),
-		attribute.String("user.email", req.Email),
-		attribute.String("db.operation", "INSERT"),
-		attribute.String("db.table", "users"),
-	)
+		tx, err := r.db.BeginTx(ctx, nil)
+		if err != nil {
+			return nil, fmt.Errorf("failed to begin transaction: %w", err)
+		}
+		// A no-op once the transaction is committed
+		defer func() { _ = tx.Rollback() }()
+
+		query := `
+			INSERT INTO users (name, email, bio, created_by, updated_by)
+			VALUES (?, ?, ?, ?, ?)
+		`
+
+		results := make([]BatchResult, len(reqs))
+		ids := make([]int, len(reqs))
+		var created []int
+		for i, req := range reqs {
+			// A failed statement does not abort an InnoDB transaction, so a
+			// duplicate email leaves the rest of the batch to be committed
+			done := r.db.TrackQuery(ctx, "INSERT", "users", query)
+			result, err := tx.ExecContext(ctx, query, req.Name, req.Email, req.Bio, actor, actor)
+			done(err)
+			if err != nil {
+				err = mapWriteError(err, "failed to create user")
+				if !errors.Is(err, apperrors.ErrConflict) {
+					return nil, err
+				}
+				results[i].Err = err
+				continue
+			}
+
+			id, err := result.LastInsertId()
+			if err != nil {
+				return nil, fmt.Errorf("failed to get last insert id: %w", err)
+			}
+			ids[i] = int(id)
+			created = append(created, int(id))
+
+			if r.outbox {
+				if err := r.insertEvent(ctx, tx, events.UserCreated, int(id)); err != nil {
+					return nil, err
+				}
+			}
+		}
 
-	query := `
-		INSERT INTO users (name, email, bio)
-		VALUES (?, ?, ?)
-	`
+		if err := tx.Commit(); err != nil {
+			return nil, fmt.Errorf("failed to commit transaction: %w", err)
+		}
+		span.SetAttributes(
+			attribute.Int("batch.created", len(created)),
+			attribute.Int("batch.failed", len(reqs)-len(created)),
+		)
 
-	start := time.Now()
-	result, err := r.db.ExecContext(ctx, query, req.Name, req.Email, req.Bio)
-	duration := time.Since(start)
+		users, err := r.getByIDs(ctx, created)
+		if err != nil {
+			return nil, err
+		}
+		for i, id := range ids {
+			if id == 0 {
+				continue
+			}
+			if user, ok := users[id]; ok {
+				results[i].User = &user
+			} else {
+				results[i].Err = apperrors.NotFound("user not found")
+			}
+		}
+		return results, nil
+	})
+}
 
-	r.db.RecordQueryMetrics(ctx, "INSERT", "users", duration, err)
+// getByIDs loads the users with ids in a single query, keyed by ID
+func (r *UserRepository) getByIDs(ctx context.Context, ids []int) (map[int]models.User, error) {
+	users := make(map[int]models.User, len(ids))
+	if len(ids) == 0 {
+		return users, nil
+	}
 
-	if err != nil {
-		span.SetAttributes(attribute.Bool("db.query.success", false))
-		return nil, fmt.Errorf("failed to create user: %w", err)
+	args := make([]interface{}, len(ids))
+	for i, id := range ids {
+		args[i] = id
 	}
+	query := `
+		SELECT id, name, email, bio, created_by, updated_by, created_at, updated_at, version
+		FROM users
+		WHERE id IN (?` + strings.Repeat(", ?", len(ids)-1) + `)
+	`
 
-	id, err := result.LastInsertId()
+	done := r.db.TrackQuery(ctx, "SELECT", "users", query)
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	done(err)
 	if err != nil {
-		span.SetAttributes(attribute.Bool("db.query.success", false))
-		return nil, fmt.Errorf("failed to get last insert id: %w", err)
+		return nil, fmt.Errorf("failed to query users: %w", err)
 	}
+	defer func() { _ = rows.Close() }()
 
-	span.SetAttributes(
-		attribute.Int64("user.id", id),
-		attribute.Bool("db.query.success", true),
-	)
-	return r.GetByID(ctx, int(id))
+	for rows.Next() {
+		var user models.User
+		if err := rows.Scan(userFields(&user)...); err != nil {
+			return nil, fmt.Errorf("failed to scan user: %w", err)
+		}
+		users[user.ID] = user
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating over users: %w", err)
+	}
+	return users, nil
 }
 
-// Update updates an existing user
+// Update updates an existing user and increments its version. When
+// req.Version is set, the update only applies to that version of the user and
+// ErrVersionMismatch is returned for any other
 func (r *UserRepository) Update(ctx context.Context, id int, req models.UpdateUserRequest) (*models.User, error) {
-	ctx, span := r.tracer.Start(ctx, "UserRepository.Update")
-	defer span.End()
+	// Lookups of the write must see its own changes
+	ctx = database.ReadPrimary(ctx)
+	return instrumentedQuery(ctx, r.tracer, r.db, "UserRepository.Update", "UPDATE", "users", func(ctx context.Context, span trace.Span) (*models.User, error) {
+		actor := auth.Actor(ctx)
+		span.SetAttributes(
+			attribute.Int("user.id", id),
+			attribute.String("enduser.id", actor),
+		)
 
-	span.SetAttributes(
-		attribute.Int("user.id", id),
-		attribute.String("db.operation", "UPDATE"),
-		attribute.String("db.table", "users"),
-	)
+		// First check if user exists
+		existingUser, err := r.GetByID(ctx, id)
+		if err != nil {
+			return nil, err
+		}
+		if req.Version != nil {
+			span.SetAttributes(attribute.Int("user.version", *req.Version))
+			if existingUser.Version != *req.Version {
+				span.SetAttributes(attribute.Bool("user.version_mismatch", true))
+				return nil, ErrVersionMismatch
+			}
+		}
 
-	// First check if user exists
-	existingUser, err := r.GetByID(ctx, id)
-	if err != nil {
-		return nil, err
-	}
+		query, args := updateStatement(span, id, req, actor)
+		if query == "" {
+			span.SetAttributes(attribute.Bool("user.no_changes", true))
+			return existingUser, nil // No changes
+		}
+
+		_, err = r.write(ctx, events.UserUpdated, func(q outbox.Execer) (int, error) {
+			done := r.db.TrackQuery(ctx, "UPDATE", "users", query)
+			result, err := q.ExecContext(ctx, query, args...)
+			done(err)
+			if err != nil {
+				return 0, mapWriteError(err, "failed to update user")
+			}
+			if updated, err := result.RowsAffected(); err == nil && updated == 0 {
+				span.SetAttributes(attribute.Bool("user.version_mismatch", true))
+				return 0, ErrVersionMismatch
+			}
+			return id, nil
+		})
+		if err != nil {
+			return nil, err
+		}
+
+		return r.GetByID(ctx, id)
+	})
+}
 
-	// Build dynamic update query
+// updateStatement builds the UPDATE of the user id described by req on
+// behalf of actor, and records the changed fields on span. The query is
+// empty when req changes nothing
+func updateStatement(span trace.Span, id int, req models.UpdateUserRequest, actor string) (string, []interface{}) {
 	setParts := []string{}
 	args := []interface{}{}
 
@@ -219,135 +416,247 @@ func (r *UserRepository) Update(ctx context.Context, id int, req models.UpdateUs
 		args = append(args, *req.Email)
 		span.SetAttributes(attribute.String("user.email", *req.Email))
 	}
-	if req.Bio != nil {
+	if req.Bio.Set {
+		// A nil pointer writes NULL, clearing the bio
 		setParts = append(setParts, "bio = ?")
-		args = append(args, *req.Bio)
-		span.SetAttributes(attribute.String("user.bio", *req.Bio))
+		args = append(args, req.Bio.Ptr())
+		span.SetAttributes(attribute.Bool("user.bio_cleared", req.Bio.Null))
 	}
 
 	if len(setParts) == 0 {
-		span.SetAttributes(attribute.Bool("user.no_changes", true))
-		return existingUser, nil // No changes
+		return "", nil
 	}
 
-	setParts = append(setParts, "updated_at = NOW()")
-	args = append(args, id)
+	setParts = append(setParts, "updated_by = ?", "updated_at = NOW()", "version = version + 1")
+	args = append(args, actor, id)
 
-	// Rebuild query properly
-	query := "UPDATE users SET "
-	for i, part := range setParts {
-		if i > 0 {
-			query += ", "
-		}
-		query += part
-	}
-	query += " WHERE id = ?"
-
-	start := time.Now()
-	_, err = r.db.ExecContext(ctx, query, args...)
-	duration := time.Since(start)
-	r.db.RecordQueryMetrics(ctx, "UPDATE", "users", duration, err)
-	if err != nil {
-		return nil, fmt.Errorf("failed to update user: %w", err)
+	query := "UPDATE users SET " + strings.Join(setParts, ", ") + " WHERE id = ?"
+	if req.Version != nil {
+		// Guards against a concurrent update since the version check
+		query += " AND version = ?"
+		args = append(args, *req.Version)
 	}
-
-	return r.GetByID(ctx, id)
+	return query, args
 }
 
 // Delete deletes a user by ID
 func (r *UserRepository) Delete(ctx context.Context, id int) error {
-	ctx, span := r.tracer.Start(ctx, "UserRepository.Delete")
-	defer span.End()
-
-	span.SetAttributes(
-		attribute.Int("user.id", id),
-		attribute.String("db.operation", "DELETE"),
-		attribute.String("db.table", "users"),
-	)
+	// Lookups of the write must see its own changes
+	ctx = database.ReadPrimary(ctx)
+	return instrumentedExec(ctx, r.tracer, r.db, "UserRepository.Delete", "DELETE", "users", func(ctx context.Context, span trace.Span) error {
+		span.SetAttributes(
+			attribute.Int("user.id", id),
+			attribute.String("enduser.id", auth.Actor(ctx)),
+		)
 
-	// First check if user exists
-	_, err := r.GetByID(ctx, id)
-	if err != nil {
-		return err
-	}
+		// First check if user exists
+		_, err := r.GetByID(ctx, id)
+		if err != nil {
+			return err
+		}
 
-	query := "DELETE FROM users WHERE id = ?"
-	start := time.Now()
-	_, err = r.db.ExecContext(ctx, query, id)
-	duration := time.Since(start)
-	r.db.RecordQueryMetrics(ctx, "DELETE", "users", duration, err)
-	if err != nil {
-		return fmt.Errorf("failed to delete user: %w", err)
-	}
+		// The user's posts are removed by the ON DELETE CASCADE of posts.user_id;
+		// count them so the span shows what the delete takes with it
+		var posts int
+		countQuery := "SELECT COUNT(*) FROM posts WHERE user_id = ?"
+		done := r.db.TrackQuery(ctx, "SELECT", "posts", countQuery)
+		err = r.db.QueryRowContext(ctx, countQuery, id).Scan(&posts)
+		done(err)
+		if err != nil {
+			return fmt.Errorf("failed to count posts of user: %w", err)
+		}
+		span.SetAttributes(attribute.Int("user.posts_deleted", posts))
+
+		query := "DELETE FROM users WHERE id = ?"
+		_, err = r.write(ctx, events.UserDeleted, func(q outbox.Execer) (int, error) {
+			done := r.db.TrackQuery(ctx, "DELETE", "users", query)
+			_, err := q.ExecContext(ctx, query, id)
+			done(err)
+			if err != nil {
+				return 0, fmt.Errorf("failed to delete user: %w", err)
+			}
+			return id, nil
+		})
+		if err != nil {
+			return err
+		}
 
-	span.SetAttributes(attribute.Bool("user.deleted", true))
-	return nil
+		span.SetAttributes(attribute.Bool("user.deleted", true))
+		return nil
+	})
 }
 
-// Count returns the total number of users
-func (r *UserRepository) Count(ctx context.Context) (int, error) {
-	ctx, span := r.tracer.Start(ctx, "UserRepository.Count")
-	defer span.End()
-
-	span.SetAttributes(
-		attribute.String("db.operation", "SELECT"),
-		attribute.String("db.table", "users"),
-	)
+// Count returns the number of users matching filter
+func (r *UserRepository) Count(ctx context.Context, filter models.UserFilter) (int, error) {
+	return instrumentedQuery(ctx, r.tracer, r.db, "UserRepository.Count", "SELECT", "users", func(ctx context.Context, span trace.Span) (int, error) {
+		db := r.db.Reader(ctx)
+		span.SetAttributes(FilterAttributes(filter)...)
 
-	query := "SELECT COUNT(*) FROM users"
+		where, args := filterClause(filter)
+		query := "SELECT COUNT(*) FROM users" + where
 
-	var count int
-	start := time.Now()
-	err := r.db.QueryRowContext(ctx, query).Scan(&count)
-	duration := time.Since(start)
-	r.db.RecordQueryMetrics(ctx, "SELECT", "users", duration, err)
-	if err != nil {
-		return 0, fmt.Errorf("failed to count users: %w", err)
-	}
+		var count int
+		done := db.TrackQuery(ctx, "SELECT", "users", query)
+		err := db.QueryRowContext(ctx, query, args...).Scan(&count)
+		done(err)
+		if err != nil {
+			return 0, fmt.Errorf("failed to count users: %w", err)
+		}
 
-	span.SetAttributes(attribute.Int("result.count", count))
-	return count, nil
+		span.SetAttributes(attribute.Int("result.count", count))
+		return count, nil
+	})
 }
 
 // GetByEmail retrieves a user by email
 func (r *UserRepository) GetByEmail(ctx context.Context, email string) (*models.User, error) {
-	ctx, span := r.tracer.Start(ctx, "UserRepository.GetByEmail")
-	defer span.End()
+	return instrumentedQuery(ctx, r.tracer, r.db, "UserRepository.GetByEmail", "SELECT", "users", func(ctx context.Context, span trace.Span) (*models.User, error) {
+		db := r.db.Reader(ctx)
+		span.SetAttributes(attribute.String("user.email", email))
 
-	span.SetAttributes(
-		attribute.String("user.email", email),
-		attribute.String("db.operation", "SELECT"),
-		attribute.String("db.table", "users"),
-	)
+		query := `
+			SELECT id, name, email, bio, created_by, updated_by, created_at, updated_at, version
+			FROM users
+			WHERE email = ?
+		`
 
-	query := `
-		SELECT id, name, email, bio, created_at, updated_at
-		FROM users
-		WHERE email = ?
-	`
+		var user models.User
+		done := db.TrackQuery(ctx, "SELECT", "users", query)
+		err := r.queryRow(ctx, span, db, query, email).Scan(userFields(&user)...)
+		done(err)
+		if err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				span.SetAttributes(attribute.Bool("user.found", false))
+				return nil, apperrors.NotFound("user not found")
+			}
+			return nil, fmt.Errorf("failed to get user: %w", err)
+		}
+
+		span.SetAttributes(attribute.Bool("user.found", true))
+		return &user, nil
+	})
+}
 
-	var user models.User
-	start := time.Now()
-	err := r.db.QueryRowContext(ctx, query, email).Scan(
+// userFields returns the scan destinations of the user columns, in the order
+// the queries select them
+func userFields(user *models.User) []interface{} {
+	return []interface{}{
 		&user.ID,
 		&user.Name,
 		&user.Email,
 		&user.Bio,
+		&user.CreatedBy,
+		&user.UpdatedBy,
 		&user.CreatedAt,
 		&user.UpdatedAt,
-	)
-	duration := time.Since(start)
+		&user.Version,
+	}
+}
+
+// write runs fn, which returns the ID of the user it changed. With the outbox
+// enabled, fn runs in a transaction that also stores eventType for that
+// user, so the change and its event are committed together
+func (r *UserRepository) write(ctx context.Context, eventType events.EventType, fn func(q outbox.Execer) (int, error)) (int, error) {
+	if !r.outbox {
+		return fn(r.db)
+	}
 
-	// Record database query metrics
-	r.db.RecordQueryMetrics(ctx, "SELECT", "users", duration, err)
+	tx, err := r.db.BeginTx(ctx, nil)
 	if err != nil {
-		if err == sql.ErrNoRows {
-			span.SetAttributes(attribute.Bool("user.found", false))
-			return nil, fmt.Errorf("user not found")
-		}
-		return nil, fmt.Errorf("failed to get user: %w", err)
+		return 0, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	// A no-op once the transaction is committed
+	defer func() { _ = tx.Rollback() }()
+
+	id, err := fn(tx)
+	if err != nil {
+		return 0, err
+	}
+	if err := r.insertEvent(ctx, tx, eventType, id); err != nil {
+		return 0, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return id, nil
+}
+
+// insertEvent stores eventType for the user with id in the outbox through q
+func (r *UserRepository) insertEvent(ctx context.Context, q outbox.Execer, eventType events.EventType, id int) error {
+	event := events.UserEvent{
+		Type:       eventType,
+		UserID:     id,
+		Actor:      auth.Actor(ctx),
+		OccurredAt: time.Now().UTC(),
 	}
+	done := r.db.TrackQuery(ctx, "INSERT", "outbox", outbox.InsertStatement)
+	err := outbox.Insert(ctx, q, event)
+	done(err)
+	return err
+}
+
+// filterClause builds the WHERE clause of filter. Values are only ever passed
+// as arguments, never spliced into the SQL
+func filterClause(filter models.UserFilter) (string, []interface{}) {
+	conditions := []string{}
+	args := []interface{}{}
+
+	if filter.Query != "" {
+		pattern := "%" + likeEscaper.Replace(filter.Query) + "%"
+		conditions = append(conditions, "(name LIKE ? OR email LIKE ?)")
+		args = append(args, pattern, pattern)
+	}
+	if filter.Email != "" {
+		conditions = append(conditions, "email = ?")
+		args = append(args, filter.Email)
+	}
+	if !filter.CreatedAfter.IsZero() {
+		conditions = append(conditions, "created_at > ?")
+		args = append(args, models.NewTimestamp(filter.CreatedAfter))
+	}
+	if !filter.CreatedBefore.IsZero() {
+		conditions = append(conditions, "created_at < ?")
+		args = append(args, models.NewTimestamp(filter.CreatedBefore))
+	}
+
+	if len(conditions) == 0 {
+		return "", args
+	}
+	return "\n\t\tWHERE " + strings.Join(conditions, " AND "), args
+}
+
+// likeEscaper escapes the LIKE wildcards so a query matches them literally;
+// backslash is MySQL's default LIKE escape character
+var likeEscaper = strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`)
+
+// FilterAttributes describes the set fields of filter as span attributes
+func FilterAttributes(filter models.UserFilter) []attribute.KeyValue {
+	attrs := []attribute.KeyValue{}
+	if filter.Query != "" {
+		attrs = append(attrs, attribute.String("filter.q", filter.Query))
+	}
+	if filter.Email != "" {
+		attrs = append(attrs, attribute.String("filter.email", filter.Email))
+	}
+	if !filter.CreatedAfter.IsZero() {
+		attrs = append(attrs, attribute.String("filter.created_after", filter.CreatedAfter.UTC().Format(time.RFC3339)))
+	}
+	if !filter.CreatedBefore.IsZero() {
+		attrs = append(attrs, attribute.String("filter.created_before", filter.CreatedBefore.UTC().Format(time.RFC3339)))
+	}
+	return attrs
+}
 
-	span.SetAttributes(attribute.Bool("user.found", true))
-	return &user, nil
+// mysqlErrDuplicateEntry is the MySQL error number for unique key violations
+const mysqlErrDuplicateEntry = 1062
+
+// mapWriteError turns unique key violations into apperrors.ErrConflict and
+// wraps any other error with msg
+func mapWriteError(err error, msg string) error {
+	var mysqlErr *mysql.MySQLError
+	if errors.As(err, &mysqlErr) && mysqlErr.Number == mysqlErrDuplicateEntry {
+		return apperrors.Wrap(apperrors.ErrConflict, err, "email already exists")
+	}
+	return fmt.Errorf("%s: %w", msg, err)
 }
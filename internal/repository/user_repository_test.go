@@ -3,15 +3,20 @@ package repository
 import (
 	"context"
 	"database/sql"
+	"errors"
 	"fmt"
 	"regexp"
+	"strings"
 	"testing"
 	"time"
 
+	"arquivolivre.com.br/otel/internal/auth"
 	"arquivolivre.com.br/otel/internal/database"
 	"arquivolivre.com.br/otel/internal/models"
+	"arquivolivre.com.br/otel/pkg/apperrors"
 
 	sqlmock "github.com/DATA-DOG/go-sqlmock"
+	"github.com/go-sql-driver/mysql"
 )
 
 func newTestDB(t *testing.T) (*database.DB, sqlmock.Sqlmock, func()) {
@@ -30,9 +35,9 @@ func TestGetByID_NotFound(t *testing.T) {
 	defer cleanup()
 	repo := NewUserRepository(db)
 
-	mock.ExpectQuery(regexp.QuoteMeta(`SELECT id, name, email, bio, created_at, updated_at
+	mock.ExpectQuery(regexp.QuoteMeta(`SELECT id, name, email, bio, created_by, updated_by, created_at, updated_at, version
         FROM users
-        WHERE id = ?`)).WithArgs(99).WillReturnRows(sqlmock.NewRows([]string{"id", "name", "email", "bio", "created_at", "updated_at"}))
+        WHERE id = ?`)).WithArgs(99).WillReturnRows(sqlmock.NewRows([]string{"id", "name", "email", "bio", "created_by", "updated_by", "created_at", "updated_at", "version"}))
 
 	u, err := repo.GetByID(context.Background(), 99)
 	if err == nil || u != nil {
@@ -40,21 +45,41 @@ func TestGetByID_NotFound(t *testing.T) {
 	}
 }
 
+func TestGetByID_QueryTimeout(t *testing.T) {
+	db, mock, cleanup := newTestDB(t)
+	defer cleanup()
+	db.SetQueryTimeout(20 * time.Millisecond)
+	repo := NewUserRepository(db)
+
+	mock.ExpectQuery(regexp.QuoteMeta(`FROM users`)).WithArgs(1).
+		WillDelayFor(time.Second).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
+
+	start := time.Now()
+	if _, err := repo.GetByID(context.Background(), 1); err == nil {
+		t.Fatal("expected the slow query to fail")
+	}
+	if elapsed := time.Since(start); elapsed > 500*time.Millisecond {
+		t.Fatalf("query was not cancelled at its deadline, took %s", elapsed)
+	}
+}
+
 func TestCreate_Success(t *testing.T) {
 	db, mock, cleanup := newTestDB(t)
 	defer cleanup()
 	repo := NewUserRepository(db)
 
-	mock.ExpectExec(regexp.QuoteMeta(`INSERT INTO users (name, email, bio)
-        VALUES (?, ?, ?)`)).WithArgs("Alice", "alice@example.com", "bio").WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectExec(regexp.QuoteMeta(`INSERT INTO users (name, email, bio, created_by, updated_by)
+        VALUES (?, ?, ?, ?, ?)`)).WithArgs("Alice", "alice@example.com", "bio", "system", "system").WillReturnResult(sqlmock.NewResult(1, 1))
 
 	now := time.Now()
-	rows := sqlmock.NewRows([]string{"id", "name", "email", "bio", "created_at", "updated_at"}).AddRow(1, "Alice", "alice@example.com", "bio", now, now)
-	mock.ExpectQuery(regexp.QuoteMeta(`SELECT id, name, email, bio, created_at, updated_at
+	rows := sqlmock.NewRows([]string{"id", "name", "email", "bio", "created_by", "updated_by", "created_at", "updated_at", "version"}).AddRow(1, "Alice", "alice@example.com", "bio", "system", "system", now, now, 1)
+	mock.ExpectQuery(regexp.QuoteMeta(`SELECT id, name, email, bio, created_by, updated_by, created_at, updated_at, version
         FROM users
         WHERE id = ?`)).WithArgs(1).WillReturnRows(rows)
 
-	u, err := repo.Create(context.Background(), models.CreateUserRequest{Name: "Alice", Email: "alice@example.com", Bio: "bio"})
+	bio := "bio"
+	u, err := repo.Create(context.Background(), models.CreateUserRequest{Name: "Alice", Email: "alice@example.com", Bio: &bio})
 	if err != nil {
 		t.Fatalf("create err: %v", err)
 	}
@@ -69,27 +94,77 @@ func TestGetAll_Pagination(t *testing.T) {
 	repo := NewUserRepository(db)
 
 	now := time.Now()
-	rows := sqlmock.NewRows([]string{"id", "name", "email", "bio", "created_at", "updated_at"}).
-		AddRow(1, "A", "a@x", "", now, now).
-		AddRow(2, "B", "b@x", "", now, now)
-	mock.ExpectQuery(regexp.QuoteMeta(`SELECT id, name, email, bio, created_at, updated_at
+	rows := sqlmock.NewRows([]string{"id", "name", "email", "bio", "created_by", "updated_by", "created_at", "updated_at", "version"}).
+		AddRow(1, "A", "a@x", "", "system", "system", now, now, 1).
+		AddRow(2, "B", "b@x", "", "system", "system", now, now, 1)
+	mock.ExpectQuery(regexp.QuoteMeta(`SELECT id, name, email, bio, created_by, updated_by, created_at, updated_at, version
         FROM users
         ORDER BY created_at DESC
         LIMIT ? OFFSET ?`)).WithArgs(2, 0).WillReturnRows(rows)
 
-	users, err := repo.GetAll(context.Background(), 2, 0)
+	users, err := repo.GetAll(context.Background(), models.UserFilter{}, 2, 0)
 	if err != nil || len(users) != 2 {
 		t.Fatalf("unexpected: %v %d", err, len(users))
 	}
 }
 
+func TestGetAll_FiltersArePassedAsArguments(t *testing.T) {
+	db, mock, cleanup := newTestDB(t)
+	defer cleanup()
+	repo := NewUserRepository(db)
+
+	after := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	before := time.Date(2024, 2, 1, 0, 0, 0, 0, time.UTC)
+	filter := models.UserFilter{
+		Query:         "x' OR '1'='1",
+		Email:         "a@x'; DROP TABLE users; --",
+		CreatedAfter:  after,
+		CreatedBefore: before,
+	}
+
+	mock.ExpectQuery(regexp.QuoteMeta(`SELECT id, name, email, bio, created_by, updated_by, created_at, updated_at, version
+        FROM users
+        WHERE (name LIKE ? OR email LIKE ?) AND email = ? AND created_at > ? AND created_at < ?
+        ORDER BY created_at DESC
+        LIMIT ? OFFSET ?`)).
+		WithArgs("%x' OR '1'='1%", "%x' OR '1'='1%", "a@x'; DROP TABLE users; --",
+			models.NewTimestamp(after), models.NewTimestamp(before), 10, 20).
+		WillReturnRows(sqlmock.NewRows(userColumns))
+	mock.ExpectQuery(regexp.QuoteMeta(`SELECT COUNT(*) FROM users
+        WHERE (name LIKE ? OR email LIKE ?) AND email = ? AND created_at > ? AND created_at < ?`)).
+		WithArgs("%x' OR '1'='1%", "%x' OR '1'='1%", "a@x'; DROP TABLE users; --",
+			models.NewTimestamp(after), models.NewTimestamp(before)).
+		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(0))
+
+	if _, err := repo.GetAll(context.Background(), filter, 10, 20); err != nil {
+		t.Fatalf("GetAll: %v", err)
+	}
+	if _, err := repo.Count(context.Background(), filter); err != nil {
+		t.Fatalf("Count: %v", err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestFilterClause_EscapesLikeWildcards(t *testing.T) {
+	where, args := filterClause(models.UserFilter{Query: `50%_off\`})
+	if strings.Contains(where, "50") {
+		t.Fatalf("query text leaked into SQL: %q", where)
+	}
+	want := `%50\%\_off\\%`
+	if len(args) != 2 || args[0] != want || args[1] != want {
+		t.Fatalf("unexpected args %q, want %q", args, want)
+	}
+}
+
 func TestCount_Success(t *testing.T) {
 	db, mock, cleanup := newTestDB(t)
 	defer cleanup()
 	repo := NewUserRepository(db)
 
 	mock.ExpectQuery(regexp.QuoteMeta(`SELECT COUNT(*) FROM users`)).WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(5))
-	c, err := repo.Count(context.Background())
+	c, err := repo.Count(context.Background(), models.UserFilter{})
 	if err != nil || c != 5 {
 		t.Fatalf("unexpected: %v %d", err, c)
 	}
@@ -101,10 +176,11 @@ func TestDelete_Success(t *testing.T) {
 	repo := NewUserRepository(db)
 
 	now := time.Now()
-	sel := sqlmock.NewRows([]string{"id", "name", "email", "bio", "created_at", "updated_at"}).AddRow(3, "C", "c@x", "", now, now)
-	mock.ExpectQuery(regexp.QuoteMeta(`SELECT id, name, email, bio, created_at, updated_at
+	sel := sqlmock.NewRows([]string{"id", "name", "email", "bio", "created_by", "updated_by", "created_at", "updated_at", "version"}).AddRow(3, "C", "c@x", "", "system", "system", now, now, 1)
+	mock.ExpectQuery(regexp.QuoteMeta(`SELECT id, name, email, bio, created_by, updated_by, created_at, updated_at, version
         FROM users
         WHERE id = ?`)).WithArgs(3).WillReturnRows(sel)
+	mock.ExpectQuery(regexp.QuoteMeta(`SELECT COUNT(*) FROM posts WHERE user_id = ?`)).WithArgs(3).WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(2))
 	mock.ExpectExec(regexp.QuoteMeta(`DELETE FROM users WHERE id = ?`)).WithArgs(3).WillReturnResult(sqlmock.NewResult(0, 1))
 
 	if err := repo.Delete(context.Background(), 3); err != nil {
@@ -118,22 +194,23 @@ func TestUpdate_SetsFields(t *testing.T) {
 	repo := NewUserRepository(db)
 
 	now := time.Now()
-	sel := sqlmock.NewRows([]string{"id", "name", "email", "bio", "created_at", "updated_at"}).AddRow(5, "Old", "old@x", "bio", now, now)
-	mock.ExpectQuery(regexp.QuoteMeta(`SELECT id, name, email, bio, created_at, updated_at
+	sel := sqlmock.NewRows([]string{"id", "name", "email", "bio", "created_by", "updated_by", "created_at", "updated_at", "version"}).AddRow(5, "Old", "old@x", "bio", "system", "system", now, now, 1)
+	mock.ExpectQuery(regexp.QuoteMeta(`SELECT id, name, email, bio, created_by, updated_by, created_at, updated_at, version
         FROM users
 		WHERE id = ?`)).WithArgs(5).WillReturnRows(sel)
 
-	mock.ExpectExec(regexp.QuoteMeta(`UPDATE users SET name = ?, email = ?, updated_at = NOW() WHERE id = ?`)).
-		WithArgs("New", "new@x", 5).WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(regexp.QuoteMeta(`UPDATE users SET name = ?, email = ?, updated_by = ?, updated_at = NOW(), version = version + 1 WHERE id = ?`)).
+		WithArgs("New", "new@x", "alice", 5).WillReturnResult(sqlmock.NewResult(0, 1))
 
-	sel2 := sqlmock.NewRows([]string{"id", "name", "email", "bio", "created_at", "updated_at"}).AddRow(5, "New", "new@x", "bio", now, now)
-	mock.ExpectQuery(regexp.QuoteMeta(`SELECT id, name, email, bio, created_at, updated_at
+	sel2 := sqlmock.NewRows([]string{"id", "name", "email", "bio", "created_by", "updated_by", "created_at", "updated_at", "version"}).AddRow(5, "New", "new@x", "bio", "system", "system", now, now, 1)
+	mock.ExpectQuery(regexp.QuoteMeta(`SELECT id, name, email, bio, created_by, updated_by, created_at, updated_at, version
         FROM users
         WHERE id = ?`)).WithArgs(5).WillReturnRows(sel2)
 
 	newName := "New"
 	newEmail := "new@x"
-	u, err := repo.Update(context.Background(), 5, models.UpdateUserRequest{Name: &newName, Email: &newEmail})
+	ctx := auth.WithPrincipal(context.Background(), auth.Principal{Subject: "alice"})
+	u, err := repo.Update(ctx, 5, models.UpdateUserRequest{Name: &newName, Email: &newEmail})
 	if err != nil {
 		t.Fatalf("update err: %v", err)
 	}
@@ -148,10 +225,10 @@ func TestGetByEmail_Found(t *testing.T) {
 	repo := NewUserRepository(db)
 
 	now := time.Now()
-	rows := sqlmock.NewRows([]string{"id", "name", "email", "bio", "created_at", "updated_at"}).
-		AddRow(1, "John Doe", "john@example.com", "Bio", now, now)
+	rows := sqlmock.NewRows([]string{"id", "name", "email", "bio", "created_by", "updated_by", "created_at", "updated_at", "version"}).
+		AddRow(1, "John Doe", "john@example.com", "Bio", "system", "system", now, now, 1)
 
-	mock.ExpectQuery(regexp.QuoteMeta(`SELECT id, name, email, bio, created_at, updated_at
+	mock.ExpectQuery(regexp.QuoteMeta(`SELECT id, name, email, bio, created_by, updated_by, created_at, updated_at, version
         FROM users
         WHERE email = ?`)).
 		WithArgs("john@example.com").
@@ -174,7 +251,7 @@ func TestGetByEmail_NotFound(t *testing.T) {
 	defer cleanup()
 	repo := NewUserRepository(db)
 
-	mock.ExpectQuery(regexp.QuoteMeta(`SELECT id, name, email, bio, created_at, updated_at
+	mock.ExpectQuery(regexp.QuoteMeta(`SELECT id, name, email, bio, created_by, updated_by, created_at, updated_at, version
         FROM users
         WHERE email = ?`)).
 		WithArgs("notfound@example.com").
@@ -194,13 +271,13 @@ func TestGetAll_DatabaseError(t *testing.T) {
 	defer cleanup()
 	repo := NewUserRepository(db)
 
-	mock.ExpectQuery(regexp.QuoteMeta(`SELECT id, name, email, bio, created_at, updated_at
+	mock.ExpectQuery(regexp.QuoteMeta(`SELECT id, name, email, bio, created_by, updated_by, created_at, updated_at, version
         FROM users
         LIMIT ? OFFSET ?`)).
 		WithArgs(10, 0).
 		WillReturnError(fmt.Errorf("database error"))
 
-	users, err := repo.GetAll(context.Background(), 10, 0)
+	users, err := repo.GetAll(context.Background(), models.UserFilter{}, 10, 0)
 	if err == nil {
 		t.Fatal("expected error, got nil")
 	}
@@ -214,7 +291,7 @@ func TestGetByID_DatabaseError(t *testing.T) {
 	defer cleanup()
 	repo := NewUserRepository(db)
 
-	mock.ExpectQuery(regexp.QuoteMeta(`SELECT id, name, email, bio, created_at, updated_at
+	mock.ExpectQuery(regexp.QuoteMeta(`SELECT id, name, email, bio, created_by, updated_by, created_at, updated_at, version
         FROM users
         WHERE id = ?`)).
 		WithArgs(1).
@@ -241,7 +318,6 @@ func TestCreate_DatabaseError(t *testing.T) {
 	user, err := repo.Create(context.Background(), models.CreateUserRequest{
 		Name:  "John",
 		Email: "john@example.com",
-		Bio:   "Bio",
 	})
 	if err == nil {
 		t.Fatal("expected error, got nil")
@@ -274,7 +350,7 @@ func TestCount_DatabaseError(t *testing.T) {
 	mock.ExpectQuery(regexp.QuoteMeta(`SELECT COUNT(*) FROM users`)).
 		WillReturnError(fmt.Errorf("database error"))
 
-	count, err := repo.Count(context.Background())
+	count, err := repo.Count(context.Background(), models.UserFilter{})
 	if err == nil {
 		t.Fatal("expected error, got nil")
 	}
@@ -282,3 +358,267 @@ func TestCount_DatabaseError(t *testing.T) {
 		t.Errorf("expected 0 count, got: %d", count)
 	}
 }
+
+func TestCreate_DuplicateEmailIsConflict(t *testing.T) {
+	db, mock, cleanup := newTestDB(t)
+	defer cleanup()
+	repo := NewUserRepository(db)
+
+	mock.ExpectExec(regexp.QuoteMeta(`INSERT INTO users`)).
+		WillReturnError(&mysql.MySQLError{Number: 1062, Message: "Duplicate entry 'a@x' for key 'email'"})
+
+	_, err := repo.Create(context.Background(), models.CreateUserRequest{Name: "A", Email: "a@x"})
+	if !errors.Is(err, apperrors.ErrConflict) {
+		t.Fatalf("expected conflict, got %v", err)
+	}
+}
+
+func TestGetByID_NotFoundIsDomainError(t *testing.T) {
+	db, mock, cleanup := newTestDB(t)
+	defer cleanup()
+	repo := NewUserRepository(db)
+
+	mock.ExpectQuery(regexp.QuoteMeta(`FROM users`)).WithArgs(7).WillReturnError(sql.ErrNoRows)
+
+	_, err := repo.GetByID(context.Background(), 7)
+	if !errors.Is(err, apperrors.ErrNotFound) {
+		t.Fatalf("expected not found, got %v", err)
+	}
+}
+
+func TestUpdate_ClearsBio(t *testing.T) {
+	db, mock, cleanup := newTestDB(t)
+	defer cleanup()
+	repo := NewUserRepository(db)
+
+	now := time.Now()
+	columns := []string{"id", "name", "email", "bio", "created_by", "updated_by", "created_at", "updated_at", "version"}
+	mock.ExpectQuery(regexp.QuoteMeta(`FROM users`)).WithArgs(5).
+		WillReturnRows(sqlmock.NewRows(columns).AddRow(5, "Ann", "ann@x", "bio", "system", "system", now, now, 1))
+	mock.ExpectExec(regexp.QuoteMeta(`UPDATE users SET bio = ?, updated_by = ?, updated_at = NOW(), version = version + 1 WHERE id = ?`)).
+		WithArgs(nil, "system", 5).WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectQuery(regexp.QuoteMeta(`FROM users`)).WithArgs(5).
+		WillReturnRows(sqlmock.NewRows(columns).AddRow(5, "Ann", "ann@x", nil, "system", "system", now, now, 1))
+
+	u, err := repo.Update(context.Background(), 5, models.UpdateUserRequest{Bio: models.Null[string]()})
+	if err != nil {
+		t.Fatalf("update err: %v", err)
+	}
+	if u.Bio != nil {
+		t.Fatalf("expected bio to be cleared, got %q", *u.Bio)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestUpdate_RejectsStaleVersion(t *testing.T) {
+	db, mock, cleanup := newTestDB(t)
+	defer cleanup()
+	repo := NewUserRepository(db)
+
+	now := time.Now()
+	columns := []string{"id", "name", "email", "bio", "created_by", "updated_by", "created_at", "updated_at", "version"}
+	name := "Anna"
+
+	// The user is already past the requested version
+	mock.ExpectQuery(regexp.QuoteMeta(`FROM users`)).WithArgs(5).
+		WillReturnRows(sqlmock.NewRows(columns).AddRow(5, "Ann", "ann@x", nil, "system", "system", now, now, 3))
+	stale := 2
+	_, err := repo.Update(context.Background(), 5, models.UpdateUserRequest{Name: &name, Version: &stale})
+	if !errors.Is(err, ErrVersionMismatch) || !errors.Is(err, apperrors.ErrConflict) {
+		t.Fatalf("expected version mismatch, got %v", err)
+	}
+
+	// Another update commits between the read and the write
+	mock.ExpectQuery(regexp.QuoteMeta(`FROM users`)).WithArgs(5).
+		WillReturnRows(sqlmock.NewRows(columns).AddRow(5, "Ann", "ann@x", nil, "system", "system", now, now, 3))
+	mock.ExpectExec(regexp.QuoteMeta(`UPDATE users SET name = ?, updated_by = ?, updated_at = NOW(), version = version + 1 WHERE id = ? AND version = ?`)).
+		WithArgs("Anna", "system", 5, 3).WillReturnResult(sqlmock.NewResult(0, 0))
+	current := 3
+	_, err = repo.Update(context.Background(), 5, models.UpdateUserRequest{Name: &name, Version: &current})
+	if !errors.Is(err, ErrVersionMismatch) {
+		t.Fatalf("expected version mismatch, got %v", err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestCreate_WithOutboxWritesEventInTransaction(t *testing.T) {
+	db, mock, cleanup := newTestDB(t)
+	defer cleanup()
+	repo := NewUserRepository(db).WithOutbox()
+
+	mock.ExpectBegin()
+	mock.ExpectExec(regexp.QuoteMeta(`INSERT INTO users`)).WillReturnResult(sqlmock.NewResult(5, 1))
+	mock.ExpectExec(regexp.QuoteMeta(`INSERT INTO outbox`)).WithArgs("user.created", sqlmock.AnyArg(), sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectCommit()
+	now := time.Now()
+	mock.ExpectQuery(regexp.QuoteMeta(`FROM users`)).WithArgs(5).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "name", "email", "bio", "created_by", "updated_by", "created_at", "updated_at", "version"}).
+			AddRow(5, "Alice", "alice@example.com", nil, "system", "system", now, now, 1))
+
+	u, err := repo.Create(context.Background(), models.CreateUserRequest{Name: "Alice", Email: "alice@example.com"})
+	if err != nil {
+		t.Fatalf("create err: %v", err)
+	}
+	if u.ID != 5 {
+		t.Fatalf("unexpected user: %+v", u)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestDelete_WithOutboxRollsBackWhenEventFails(t *testing.T) {
+	db, mock, cleanup := newTestDB(t)
+	defer cleanup()
+	repo := NewUserRepository(db).WithOutbox()
+
+	now := time.Now()
+	mock.ExpectQuery(regexp.QuoteMeta(`FROM users`)).WithArgs(3).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "name", "email", "bio", "created_by", "updated_by", "created_at", "updated_at", "version"}).
+			AddRow(3, "Bob", "bob@example.com", nil, "system", "system", now, now, 1))
+	mock.ExpectQuery(regexp.QuoteMeta(`SELECT COUNT(*) FROM posts`)).WithArgs(3).
+		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(0))
+	mock.ExpectBegin()
+	mock.ExpectExec(regexp.QuoteMeta(`DELETE FROM users`)).WithArgs(3).WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(regexp.QuoteMeta(`INSERT INTO outbox`)).WillReturnError(errors.New("disk full"))
+	mock.ExpectRollback()
+
+	if err := repo.Delete(context.Background(), 3); err == nil {
+		t.Fatal("expected the outbox failure to fail the delete")
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestCreateBatch_DuplicateFailsOnlyItsUser(t *testing.T) {
+	db, mock, cleanup := newTestDB(t)
+	defer cleanup()
+	repo := NewUserRepository(db)
+
+	mock.ExpectBegin()
+	mock.ExpectExec(regexp.QuoteMeta(`INSERT INTO users`)).WithArgs("A", "a@x", nil, "system", "system").
+		WillReturnResult(sqlmock.NewResult(7, 1))
+	mock.ExpectExec(regexp.QuoteMeta(`INSERT INTO users`)).WithArgs("B", "a@x", nil, "system", "system").
+		WillReturnError(&mysql.MySQLError{Number: 1062, Message: "Duplicate entry 'a@x' for key 'email'"})
+	mock.ExpectExec(regexp.QuoteMeta(`INSERT INTO users`)).WithArgs("C", "c@x", nil, "system", "system").
+		WillReturnResult(sqlmock.NewResult(8, 1))
+	mock.ExpectCommit()
+	now := time.Now()
+	mock.ExpectQuery(regexp.QuoteMeta(`WHERE id IN (?, ?)`)).WithArgs(7, 8).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "name", "email", "bio", "created_by", "updated_by", "created_at", "updated_at", "version"}).
+			AddRow(7, "A", "a@x", nil, "system", "system", now, now, 1).
+			AddRow(8, "C", "c@x", nil, "system", "system", now, now, 1))
+
+	results, err := repo.CreateBatch(context.Background(), []models.CreateUserRequest{
+		{Name: "A", Email: "a@x"},
+		{Name: "B", Email: "a@x"},
+		{Name: "C", Email: "c@x"},
+	})
+	if err != nil {
+		t.Fatalf("create batch err: %v", err)
+	}
+	if len(results) != 3 || results[0].User.ID != 7 || results[2].User.ID != 8 {
+		t.Fatalf("unexpected results: %+v", results)
+	}
+	if results[1].User != nil || !errors.Is(results[1].Err, apperrors.ErrConflict) {
+		t.Fatalf("expected the duplicate to be a conflict, got %+v", results[1])
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestCreateBatch_RollsBackOnDatabaseError(t *testing.T) {
+	db, mock, cleanup := newTestDB(t)
+	defer cleanup()
+	repo := NewUserRepository(db).WithOutbox()
+
+	mock.ExpectBegin()
+	mock.ExpectExec(regexp.QuoteMeta(`INSERT INTO users`)).WillReturnResult(sqlmock.NewResult(7, 1))
+	mock.ExpectExec(regexp.QuoteMeta(`INSERT INTO outbox`)).WithArgs("user.created", sqlmock.AnyArg(), sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectExec(regexp.QuoteMeta(`INSERT INTO users`)).WillReturnError(errors.New("connection reset"))
+	mock.ExpectRollback()
+
+	_, err := repo.CreateBatch(context.Background(), []models.CreateUserRequest{
+		{Name: "A", Email: "a@x"},
+		{Name: "B", Email: "b@x"},
+	})
+	if err == nil {
+		t.Fatal("expected the batch to fail")
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestStream_CallsFnPerRowAndStopsOnError(t *testing.T) {
+	db, mock, cleanup := newTestDB(t)
+	defer cleanup()
+	repo := NewUserRepository(db)
+
+	now := time.Now()
+	rows := sqlmock.NewRows([]string{"id", "name", "email", "bio", "created_by", "updated_by", "created_at", "updated_at", "version"}).
+		AddRow(1, "A", "a@x", nil, "system", "system", now, now, 1).
+		AddRow(2, "B", "b@x", nil, "system", "system", now, now, 1).
+		AddRow(3, "C", "c@x", nil, "system", "system", now, now, 1)
+	mock.ExpectQuery(regexp.QuoteMeta(`WHERE email = ?`) + `\s+ORDER BY id`).WithArgs("a@x").WillReturnRows(rows)
+
+	stop := errors.New("client went away")
+	var ids []int
+	err := repo.Stream(context.Background(), models.UserFilter{Email: "a@x"}, func(u *models.User) error {
+		ids = append(ids, u.ID)
+		if u.ID == 2 {
+			return stop
+		}
+		return nil
+	})
+	if !errors.Is(err, stop) {
+		t.Fatalf("expected the callback error, got %v", err)
+	}
+	if len(ids) != 2 {
+		t.Fatalf("expected the stream to stop after 2 users, got %v", ids)
+	}
+}
+
+func TestReplicaServesReadsButNotTheLookupsOfWrites(t *testing.T) {
+	db, primary, cleanup := newTestDB(t)
+	defer cleanup()
+	replicaDB, replica, cleanupReplica := newTestDB(t)
+	defer cleanupReplica()
+	db.SetReplica(replicaDB)
+	repo := NewUserRepository(db)
+
+	now := time.Now()
+	user := func(name string) *sqlmock.Rows {
+		return sqlmock.NewRows(userColumns).AddRow(5, name, "a@x", nil, "system", "system", now, now, 1)
+	}
+	replica.ExpectQuery(regexp.QuoteMeta(`WHERE id = ?`)).WithArgs(5).WillReturnRows(user("Old"))
+	replica.ExpectQuery(regexp.QuoteMeta(`SELECT COUNT(*) FROM users`)).WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(1))
+	primary.ExpectQuery(regexp.QuoteMeta(`WHERE id = ?`)).WithArgs(5).WillReturnRows(user("Old"))
+	primary.ExpectExec(regexp.QuoteMeta(`UPDATE users`)).WillReturnResult(sqlmock.NewResult(0, 1))
+	primary.ExpectQuery(regexp.QuoteMeta(`WHERE id = ?`)).WithArgs(5).WillReturnRows(user("New"))
+
+	if _, err := repo.GetByID(context.Background(), 5); err != nil {
+		t.Fatalf("GetByID: %v", err)
+	}
+	if _, err := repo.Count(context.Background(), models.UserFilter{}); err != nil {
+		t.Fatalf("Count: %v", err)
+	}
+	name := "New"
+	if u, err := repo.Update(context.Background(), 5, models.UpdateUserRequest{Name: &name}); err != nil || u.Name != "New" {
+		t.Fatalf("Update = %v, %v", u, err)
+	}
+
+	for role, mock := range map[string]sqlmock.Sqlmock{"primary": primary, "replica": replica} {
+		if err := mock.ExpectationsWereMet(); err != nil {
+			t.Errorf("%s expectations: %v", role, err)
+		}
+	}
+}
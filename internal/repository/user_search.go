@@ -0,0 +1,172 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"arquivolivre.com.br/otel/internal/database"
+	"arquivolivre.com.br/otel/internal/models"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// SearchMode selects how UserSearch matches users
+type SearchMode string
+
+const (
+	// SearchFullText matches words against the ft_users_search FULLTEXT
+	// index, ranked by MySQL's relevance
+	SearchFullText SearchMode = "fulltext"
+	// SearchLike matches substrings with LIKE, for databases without the
+	// index; relevance only ranks name over email over bio matches
+	SearchLike SearchMode = "like"
+)
+
+// UserSearchHit is a user found by a search and its relevance to the query;
+// higher is more relevant
+type UserSearchHit struct {
+	User      models.User
+	Relevance float64
+}
+
+// UserSearcher finds users by free text, most relevant first
+type UserSearcher interface {
+	Search(ctx context.Context, query string, limit int) ([]UserSearchHit, error)
+}
+
+// booleanOperators are the characters with a meaning in a FULLTEXT boolean
+// search; they are dropped from the words of the query
+var booleanOperators = strings.NewReplacer(
+	"+", " ", "-", " ", "<", " ", ">", " ", "(", " ", ")", " ",
+	"~", " ", "*", " ", `"`, " ", "@", " ",
+)
+
+// UserSearch searches the users table in one of the SearchMode modes. Each
+// search is a UserSearch.Search span and goes into the user.search.duration
+// histogram, besides the db.query.* metrics of its query
+type UserSearch struct {
+	db       *database.DB
+	tracer   trace.Tracer
+	mode     SearchMode
+	duration metric.Float64Histogram
+}
+
+var _ UserSearcher = (*UserSearch)(nil)
+
+// NewUserSearch creates a search on db in mode
+func NewUserSearch(db *database.DB, mode SearchMode) *UserSearch {
+	duration, _ := otel.Meter("otel-example-api").Float64Histogram(
+		"user.search.duration",
+		metric.WithDescription("Duration of user searches, including the time to read the results"),
+		metric.WithUnit("s"),
+		metric.WithExplicitBucketBoundaries(0.001, 0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5),
+	)
+	return &UserSearch{
+		db:       db,
+		tracer:   otel.Tracer("user-repository"),
+		mode:     mode,
+		duration: duration,
+	}
+}
+
+// Search returns up to limit users matching query, most relevant first and
+// then by ID
+func (s *UserSearch) Search(ctx context.Context, query string, limit int) ([]UserSearchHit, error) {
+	start := time.Now()
+	hits, err := instrumentedQuery(ctx, s.tracer, s.db, "UserSearch.Search", "SELECT", "users", func(ctx context.Context, span trace.Span) ([]UserSearchHit, error) {
+		span.SetAttributes(
+			attribute.String("search.mode", string(s.mode)),
+			attribute.String("search.query", query),
+			attribute.Int("pagination.limit", limit),
+		)
+		if s.mode == SearchLike {
+			return s.like(ctx, span, query, limit)
+		}
+		return s.fullText(ctx, span, query, limit)
+	})
+
+	outcome := "hits"
+	switch {
+	case err != nil:
+		outcome = "error"
+	case len(hits) == 0:
+		outcome = "empty"
+	}
+	s.duration.Record(ctx, time.Since(start).Seconds(), metric.WithAttributes(
+		attribute.String("search.mode", string(s.mode)),
+		attribute.String("search.outcome", outcome),
+	))
+	return hits, err
+}
+
+// fullText matches every word of query as a prefix, so "ann dev" finds
+// "Annabel" and "developer"; users matching more words rank higher
+func (s *UserSearch) fullText(ctx context.Context, span trace.Span, query string, limit int) ([]UserSearchHit, error) {
+	words := strings.Fields(booleanOperators.Replace(query))
+	span.SetAttributes(attribute.Int("search.terms", len(words)))
+	if len(words) == 0 {
+		span.SetAttributes(attribute.Int("result.count", 0))
+		return []UserSearchHit{}, nil
+	}
+	terms := strings.Join(words, "* ") + "*"
+
+	sqlQuery := `
+		SELECT id, name, email, bio, created_by, updated_by, created_at, updated_at, version,
+			MATCH (name, email, bio) AGAINST (? IN BOOLEAN MODE) AS relevance
+		FROM users
+		WHERE MATCH (name, email, bio) AGAINST (? IN BOOLEAN MODE)
+		ORDER BY relevance DESC, id
+		LIMIT ?
+	`
+	return s.query(ctx, span, sqlQuery, terms, terms, limit)
+}
+
+// like matches query as a substring of the name, email or bio
+func (s *UserSearch) like(ctx context.Context, span trace.Span, query string, limit int) ([]UserSearchHit, error) {
+	span.SetAttributes(attribute.Int("search.terms", 1))
+	pattern := "%" + likeEscaper.Replace(query) + "%"
+
+	sqlQuery := `
+		SELECT id, name, email, bio, created_by, updated_by, created_at, updated_at, version,
+			CASE WHEN name LIKE ? THEN 3 WHEN email LIKE ? THEN 2 ELSE 1 END AS relevance
+		FROM users
+		WHERE name LIKE ? OR email LIKE ? OR bio LIKE ?
+		ORDER BY relevance DESC, id
+		LIMIT ?
+	`
+	return s.query(ctx, span, sqlQuery, pattern, pattern, pattern, pattern, pattern, limit)
+}
+
+func (s *UserSearch) query(ctx context.Context, span trace.Span, sqlQuery string, args ...interface{}) ([]UserSearchHit, error) {
+	db := s.db.Reader(ctx)
+	done := db.TrackQuery(ctx, "SELECT", "users", sqlQuery)
+	rows, err := db.QueryContext(ctx, sqlQuery, args...)
+	done(err)
+	if err != nil {
+		return nil, fmt.Errorf("failed to search users: %w", err)
+	}
+	defer func() { _ = rows.Close() }()
+
+	hits := []UserSearchHit{}
+	for rows.Next() {
+		var hit UserSearchHit
+		if err := rows.Scan(append(userFields(&hit.User), &hit.Relevance)...); err != nil {
+			return nil, fmt.Errorf("failed to scan user: %w", err)
+		}
+		hits = append(hits, hit)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating over users: %w", err)
+	}
+
+	span.SetAttributes(attribute.Int("result.count", len(hits)))
+	if len(hits) > 0 {
+		span.SetAttributes(attribute.Float64("search.relevance.max", hits[0].Relevance))
+	}
+	return hits, nil
+}
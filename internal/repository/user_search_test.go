@@ -0,0 +1,90 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"regexp"
+	"testing"
+	"time"
+
+	"arquivolivre.com.br/otel/pkg/oteltest"
+
+	sqlmock "github.com/DATA-DOG/go-sqlmock"
+	"go.opentelemetry.io/otel/attribute"
+)
+
+var searchColumns = append(append([]string{}, userColumns...), "relevance")
+
+func TestUserSearch_FullTextMatchesWordPrefixes(t *testing.T) {
+	db, mock, cleanup := newTestDB(t)
+	defer cleanup()
+	rec := oteltest.Install(t)
+	search := NewUserSearch(db, SearchFullText)
+
+	now := time.Now()
+	mock.ExpectQuery(regexp.QuoteMeta(`AGAINST (? IN BOOLEAN MODE)`)).WithArgs("ann* dev*", "ann* dev*", 10).
+		WillReturnRows(sqlmock.NewRows(searchColumns).
+			AddRow(3, "Ann", "ann@example.com", "developer", "system", "system", now, now, 1, 2.5).
+			AddRow(1, "Annabel", "annabel@example.com", nil, "system", "system", now, now, 1, 0.7))
+
+	// Boolean operators are dropped, so they cannot change the search
+	hits, err := search.Search(context.Background(), `+ann -"dev"*`, 10)
+	if err != nil {
+		t.Fatalf("search: %v", err)
+	}
+	if len(hits) != 2 || hits[0].User.ID != 3 || hits[0].Relevance != 2.5 {
+		t.Fatalf("expected user 3 first, got %+v", hits)
+	}
+	rec.AssertSpan(t, "UserSearch.Search",
+		attribute.String("search.mode", "fulltext"),
+		attribute.Int("search.terms", 2),
+		attribute.Int("result.count", 2),
+		attribute.Float64("search.relevance.max", 2.5),
+	)
+
+	// A query without words finds nothing without asking MySQL
+	hits, err = search.Search(context.Background(), "+-*", 10)
+	if err != nil || len(hits) != 0 {
+		t.Fatalf("expected no hits, got %v, %v", hits, err)
+	}
+	rec.AssertSpan(t, "UserSearch.Search", attribute.Int("search.terms", 0), attribute.Int("result.count", 0))
+
+	rec.AssertHistogramCount(t, "user.search.duration", []attribute.KeyValue{
+		attribute.String("search.mode", "fulltext"),
+		attribute.String("search.outcome", "hits"),
+	}, 1)
+	rec.AssertHistogramCount(t, "user.search.duration", []attribute.KeyValue{
+		attribute.String("search.outcome", "empty"),
+	}, 1)
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("expectations: %v", err)
+	}
+}
+
+func TestUserSearch_LikeEscapesThePattern(t *testing.T) {
+	db, mock, cleanup := newTestDB(t)
+	defer cleanup()
+	rec := oteltest.Install(t)
+	search := NewUserSearch(db, SearchLike)
+
+	pattern := `%50\%%`
+	mock.ExpectQuery(regexp.QuoteMeta(`WHERE name LIKE ? OR email LIKE ? OR bio LIKE ?`)).
+		WithArgs(pattern, pattern, pattern, pattern, pattern, 5).
+		WillReturnError(errors.New("connection reset"))
+
+	if _, err := search.Search(context.Background(), "50%", 5); err == nil {
+		t.Fatal("expected the search to fail")
+	}
+
+	rec.AssertSpan(t, "UserSearch.Search",
+		attribute.String("search.mode", "like"),
+		attribute.Bool("db.query.success", false),
+	)
+	rec.AssertHistogramCount(t, "user.search.duration", []attribute.KeyValue{
+		attribute.String("search.mode", "like"),
+		attribute.String("search.outcome", "error"),
+	}, 1)
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("expectations: %v", err)
+	}
+}
@@ -0,0 +1,144 @@
+package roles
+
+import (
+	"strconv"
+
+	"arquivolivre.com.br/otel/internal/middleware"
+	"arquivolivre.com.br/otel/pkg/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Page sizes of the member listing
+const (
+	defaultPageSize = 50
+	maxPageSize     = 200
+)
+
+// Handler manages roles and their assignments over HTTP
+type Handler struct {
+	store Store
+}
+
+// NewHandler creates a handler for store
+func NewHandler(store Store) *Handler {
+	return &Handler{store: store}
+}
+
+// Register mounts the role endpoints on root; the reads and writes
+// middleware run before the read and write handlers
+func (h *Handler) Register(root *gin.RouterGroup, reads, writes []gin.HandlerFunc) {
+	readGroup := root.Group("", reads...)
+	readGroup.GET("/roles", h.ListRoles)
+	readGroup.GET("/roles/:role/users", h.ListMembers)
+	readGroup.GET("/users/:id/roles", h.ListUserRoles)
+
+	writeGroup := root.Group("", writes...)
+	writeGroup.POST("/roles", h.CreateRole)
+	writeGroup.PUT("/users/:id/roles/:role", h.AssignRole)
+	writeGroup.DELETE("/users/:id/roles/:role", h.RemoveRole)
+}
+
+// ListRoles returns every role
+func (h *Handler) ListRoles(c *gin.Context) {
+	list, err := h.store.List(c.Request.Context())
+	if err != nil {
+		_ = c.Error(middleware.InternalError("Failed to retrieve roles", err))
+		return
+	}
+	utils.SendSuccess(c, list)
+}
+
+// CreateRole defines a role
+func (h *Handler) CreateRole(c *gin.Context) {
+	var req CreateRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		_ = c.Error(middleware.BindError(err, c.GetHeader("Accept-Language")))
+		return
+	}
+	if !ValidName(req.Name) {
+		_ = c.Error(middleware.BadRequestError("name must be a role such as resource:action"))
+		return
+	}
+
+	role, err := h.store.Create(c.Request.Context(), req)
+	if err != nil {
+		_ = c.Error(middleware.FromError(err, "Failed to create role"))
+		return
+	}
+	utils.SendCreated(c, role, "Role created successfully")
+}
+
+// ListMembers returns a page of the users holding a role
+func (h *Handler) ListMembers(c *gin.Context) {
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", strconv.Itoa(defaultPageSize)))
+	if page < 1 {
+		page = 1
+	}
+	if limit < 1 || limit > maxPageSize {
+		limit = defaultPageSize
+	}
+
+	members, total, err := h.store.Members(c.Request.Context(), c.Param("role"), limit, (page-1)*limit)
+	if err != nil {
+		_ = c.Error(middleware.FromError(err, "Failed to retrieve role members"))
+		return
+	}
+	utils.SendPaginated(c, members, page, limit, total)
+}
+
+// ListUserRoles returns the roles assigned to a user
+func (h *Handler) ListUserRoles(c *gin.Context) {
+	id, ok := userID(c)
+	if !ok {
+		return
+	}
+	assignments, err := h.store.UserRoles(c.Request.Context(), id)
+	if err != nil {
+		_ = c.Error(middleware.FromError(err, "Failed to retrieve user roles"))
+		return
+	}
+	utils.SendSuccess(c, assignments)
+}
+
+// AssignRole assigns a role to a user and returns the user's roles
+func (h *Handler) AssignRole(c *gin.Context) {
+	id, ok := userID(c)
+	if !ok {
+		return
+	}
+	ctx := c.Request.Context()
+	if err := h.store.Assign(ctx, id, c.Param("role")); err != nil {
+		_ = c.Error(middleware.FromError(err, "Failed to assign role"))
+		return
+	}
+	assignments, err := h.store.UserRoles(ctx, id)
+	if err != nil {
+		_ = c.Error(middleware.FromError(err, "Failed to retrieve user roles"))
+		return
+	}
+	utils.SendSuccess(c, assignments, "Role assigned successfully")
+}
+
+// RemoveRole removes a role from a user
+func (h *Handler) RemoveRole(c *gin.Context) {
+	id, ok := userID(c)
+	if !ok {
+		return
+	}
+	if err := h.store.Remove(c.Request.Context(), id, c.Param("role")); err != nil {
+		_ = c.Error(middleware.FromError(err, "Failed to remove role"))
+		return
+	}
+	utils.SendNoContent(c)
+}
+
+func userID(c *gin.Context) (int, bool) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		_ = c.Error(middleware.BadRequestError("Invalid user ID"))
+		return 0, false
+	}
+	return id, true
+}
@@ -0,0 +1,69 @@
+// Package roles keeps named roles and their assignment to users. Roles
+// follow the resource:action format of the RBAC roles, and the roles
+// assigned to a user are granted to principals whose subject is the user's
+// email, in addition to those of their credentials.
+package roles
+
+import (
+	"context"
+	"regexp"
+
+	"arquivolivre.com.br/otel/internal/models"
+)
+
+// namePattern matches resource:action role names and single word roles
+// such as admin; the action may be the * wildcard
+var namePattern = regexp.MustCompile(`^[a-z][a-z0-9_-]*(:([a-z][a-z0-9_-]*|\*))?$`)
+
+// ValidName reports whether name is a role name RBAC understands
+func ValidName(name string) bool {
+	return len(name) <= 100 && namePattern.MatchString(name)
+}
+
+// Role is a named role that can be assigned to users
+type Role struct {
+	ID          int              `json:"id"`
+	Name        string           `json:"name"`
+	Description string           `json:"description"`
+	CreatedAt   models.Timestamp `json:"created_at"`
+}
+
+// Assignment is a role assigned to a user
+type Assignment struct {
+	Role
+	AssignedBy string           `json:"assigned_by"`
+	AssignedAt models.Timestamp `json:"assigned_at"`
+}
+
+// Member is a user holding a role; emails are left out so the listing does
+// not need the masking of the user endpoints
+type Member struct {
+	UserID     int              `json:"user_id"`
+	Name       string           `json:"name"`
+	AssignedBy string           `json:"assigned_by"`
+	AssignedAt models.Timestamp `json:"assigned_at"`
+}
+
+// CreateRequest defines a role
+type CreateRequest struct {
+	Name        string `json:"name" binding:"required,max=100"`
+	Description string `json:"description" binding:"max=255"`
+}
+
+// Store persists roles and their assignments
+type Store interface {
+	List(ctx context.Context) ([]Role, error)
+	Create(ctx context.Context, req CreateRequest) (*Role, error)
+	// UserRoles returns the roles assigned to the user with userID
+	UserRoles(ctx context.Context, userID int) ([]Assignment, error)
+	// Assign assigns the role named role to the user; assigning it again
+	// keeps the first assignment
+	Assign(ctx context.Context, userID int, role string) error
+	Remove(ctx context.Context, userID int, role string) error
+	// Members returns a page of the users holding role in user ID order,
+	// and how many hold it
+	Members(ctx context.Context, role string, limit, offset int) ([]Member, int, error)
+	// RolesOf returns the names of the roles assigned to subject, the email
+	// their user had when they were assigned
+	RolesOf(ctx context.Context, subject string) ([]string, error)
+}
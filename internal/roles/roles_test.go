@@ -0,0 +1,229 @@
+package roles
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"arquivolivre.com.br/otel/internal/auth"
+	"arquivolivre.com.br/otel/internal/database"
+	"arquivolivre.com.br/otel/internal/middleware"
+	"arquivolivre.com.br/otel/pkg/apperrors"
+	"arquivolivre.com.br/otel/pkg/oteltest"
+
+	sqlmock "github.com/DATA-DOG/go-sqlmock"
+	"github.com/gin-gonic/gin"
+	"github.com/go-sql-driver/mysql"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/attribute"
+)
+
+var assignmentColumns = []string{"id", "name", "description", "created_at", "assigned_by", "assigned_at"}
+
+func newTestStore(t *testing.T) (*MySQLStore, sqlmock.Sqlmock) {
+	t.Helper()
+	sqlDB, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = sqlDB.Close() })
+	return NewMySQLStore(&database.DB{DB: sqlDB}), mock
+}
+
+func TestValidName(t *testing.T) {
+	for _, name := range []string{"admin", "users:write", "posts:*", "billing-team:read_only"} {
+		assert.True(t, ValidName(name), name)
+	}
+	for _, name := range []string{"", "Users:write", "users:", ":write", "users:write:all", "users write"} {
+		assert.False(t, ValidName(name), name)
+	}
+}
+
+func TestUserRolesJoinsAssignments(t *testing.T) {
+	rec := oteltest.Install(t)
+	store, mock := newTestStore(t)
+
+	now := time.Now()
+	mock.ExpectQuery(regexp.QuoteMeta("LEFT JOIN user_roles ur ON ur.user_id = u.id")).WithArgs(1).WillReturnRows(
+		sqlmock.NewRows(assignmentColumns).
+			AddRow(1, "posts:write", "", now, "alice", now).
+			AddRow(2, "users:write", "Manage users", now, "system", now))
+	// A user without roles joins to a single row of NULLs
+	mock.ExpectQuery(regexp.QuoteMeta("LEFT JOIN user_roles ur ON ur.user_id = u.id")).WithArgs(2).WillReturnRows(
+		sqlmock.NewRows(assignmentColumns).AddRow(nil, nil, nil, nil, nil, nil))
+	mock.ExpectQuery(regexp.QuoteMeta("LEFT JOIN user_roles ur ON ur.user_id = u.id")).WithArgs(3).WillReturnRows(
+		sqlmock.NewRows(assignmentColumns))
+
+	assignments, err := store.UserRoles(context.Background(), 1)
+	require.NoError(t, err)
+	require.Len(t, assignments, 2)
+	assert.Equal(t, "users:write", assignments[1].Name)
+	assert.Equal(t, "alice", assignments[0].AssignedBy)
+
+	assignments, err = store.UserRoles(context.Background(), 2)
+	require.NoError(t, err)
+	assert.Empty(t, assignments)
+
+	_, err = store.UserRoles(context.Background(), 3)
+	assert.True(t, errors.Is(err, apperrors.ErrNotFound), "got %v", err)
+
+	rec.AssertSpan(t, "RoleStore.UserRoles",
+		attribute.String("db.table", "user_roles"),
+		attribute.StringSlice("db.join.tables", []string{"users", "user_roles", "roles"}),
+		attribute.Int("db.join.rows", 0),
+		attribute.Int("result.count", 0),
+	)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestAssignResolvesRoleAndUser(t *testing.T) {
+	rec := oteltest.Install(t)
+	store, mock := newTestStore(t)
+	ctx := auth.WithPrincipal(context.Background(), auth.Principal{Subject: "alice"})
+
+	mock.ExpectQuery(regexp.QuoteMeta("SELECT id FROM roles WHERE name = ?")).WithArgs("users:write").
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(4))
+	mock.ExpectQuery(regexp.QuoteMeta("SELECT email FROM users WHERE id = ?")).WithArgs(1).
+		WillReturnRows(sqlmock.NewRows([]string{"email"}).AddRow("bob@example.com"))
+	mock.ExpectExec(regexp.QuoteMeta("INSERT INTO user_roles")).WithArgs(1, 4, "bob@example.com", "alice").
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectQuery(regexp.QuoteMeta("SELECT id FROM roles WHERE name = ?")).WithArgs("users:write").
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(4))
+	mock.ExpectQuery(regexp.QuoteMeta("SELECT email FROM users WHERE id = ?")).WithArgs(99).
+		WillReturnRows(sqlmock.NewRows([]string{"email"}))
+	// The user may be deleted between the lookup and the insert
+	mock.ExpectQuery(regexp.QuoteMeta("SELECT id FROM roles WHERE name = ?")).WithArgs("users:write").
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(4))
+	mock.ExpectQuery(regexp.QuoteMeta("SELECT email FROM users WHERE id = ?")).WithArgs(2).
+		WillReturnRows(sqlmock.NewRows([]string{"email"}).AddRow("ana@example.com"))
+	mock.ExpectExec(regexp.QuoteMeta("INSERT INTO user_roles")).WithArgs(2, 4, "ana@example.com", "alice").
+		WillReturnError(&mysql.MySQLError{Number: mysqlErrNoReferencedRow, Message: "foreign key constraint fails"})
+	mock.ExpectQuery(regexp.QuoteMeta("SELECT id FROM roles WHERE name = ?")).WithArgs("billing:read").
+		WillReturnRows(sqlmock.NewRows([]string{"id"}))
+
+	require.NoError(t, store.Assign(ctx, 1, "users:write"))
+	rec.AssertSpan(t, "RoleStore.Assign", attribute.Bool("role.already_assigned", true))
+
+	err := store.Assign(ctx, 99, "users:write")
+	assert.True(t, errors.Is(err, apperrors.ErrNotFound), "got %v", err)
+	assert.Equal(t, "user not found", apperrors.Message(err))
+
+	err = store.Assign(ctx, 2, "users:write")
+	assert.True(t, errors.Is(err, apperrors.ErrNotFound), "got %v", err)
+
+	err = store.Assign(ctx, 1, "billing:read")
+	assert.True(t, errors.Is(err, apperrors.ErrNotFound), "got %v", err)
+	assert.Equal(t, "role billing:read not found", apperrors.Message(err))
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestRolesOfIgnoresLaterEmailChanges(t *testing.T) {
+	rec := oteltest.Install(t)
+	store, mock := newTestStore(t)
+	ctx := auth.WithPrincipal(context.Background(), auth.Principal{Subject: "alice"})
+
+	// bob@example.com is made admin, then someone allowed to write users
+	// changes bob's email to their own. The roles stay with the subject
+	// recorded on assignment rather than following the users row
+	rolesOf := `FROM user_roles ur\s+JOIN roles r ON r\.id = ur\.role_id\s+WHERE ur\.subject = \?`
+	mock.ExpectQuery(regexp.QuoteMeta("SELECT id FROM roles WHERE name = ?")).WithArgs("admin").
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
+	mock.ExpectQuery(regexp.QuoteMeta("SELECT email FROM users WHERE id = ?")).WithArgs(5).
+		WillReturnRows(sqlmock.NewRows([]string{"email"}).AddRow("bob@example.com"))
+	mock.ExpectExec(regexp.QuoteMeta("INSERT INTO user_roles")).WithArgs(5, 1, "bob@example.com", "alice").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectQuery(rolesOf).WithArgs("mallory@example.com").
+		WillReturnRows(sqlmock.NewRows([]string{"name"}))
+	mock.ExpectQuery(rolesOf).WithArgs("bob@example.com").
+		WillReturnRows(sqlmock.NewRows([]string{"name"}).AddRow("admin"))
+
+	require.NoError(t, store.Assign(ctx, 5, "admin"))
+
+	names, err := store.RolesOf(context.Background(), "mallory@example.com")
+	require.NoError(t, err)
+	assert.Empty(t, names)
+
+	names, err = store.RolesOf(context.Background(), "bob@example.com")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"admin"}, names)
+	rec.AssertSpan(t, "RoleStore.RolesOf",
+		attribute.StringSlice("db.join.tables", []string{"user_roles", "roles"}),
+		attribute.Int("db.join.rows", 1),
+	)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestMembersPagesTheJoin(t *testing.T) {
+	rec := oteltest.Install(t)
+	store, mock := newTestStore(t)
+
+	now := time.Now()
+	mock.ExpectQuery(regexp.QuoteMeta("SELECT id FROM roles WHERE name = ?")).WithArgs("posts:write").
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(2))
+	mock.ExpectQuery(regexp.QuoteMeta("SELECT COUNT(*) FROM user_roles WHERE role_id = ?")).WithArgs(2).
+		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(3))
+	mock.ExpectQuery(regexp.QuoteMeta("JOIN users u ON u.id = ur.user_id")).WithArgs(2, 2, 0).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "name", "assigned_by", "assigned_at"}).
+			AddRow(1, "Ana", "system", now).
+			AddRow(5, "Bob", "alice", now))
+
+	members, total, err := store.Members(context.Background(), "posts:write", 2, 0)
+	require.NoError(t, err)
+	assert.Equal(t, 3, total)
+	require.Len(t, members, 2)
+	assert.Equal(t, 5, members[1].UserID)
+
+	rec.AssertSpan(t, "RoleStore.Members",
+		attribute.StringSlice("db.join.tables", []string{"user_roles", "users"}),
+		attribute.Int("db.join.rows", 2),
+		attribute.Int("role.members", 3),
+	)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestHandlerManagesAssignments(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	store, mock := newTestStore(t)
+
+	r := gin.New()
+	r.Use(middleware.ErrorHandler())
+	NewHandler(store).Register(r.Group("/api"), nil, nil)
+
+	now := time.Now()
+	mock.ExpectQuery(regexp.QuoteMeta("SELECT id FROM roles WHERE name = ?")).WithArgs("users:write").
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(4))
+	mock.ExpectQuery(regexp.QuoteMeta("SELECT email FROM users WHERE id = ?")).WithArgs(1).
+		WillReturnRows(sqlmock.NewRows([]string{"email"}).AddRow("bob@example.com"))
+	mock.ExpectExec(regexp.QuoteMeta("INSERT INTO user_roles")).WithArgs(1, 4, "bob@example.com", auth.SystemActor).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectQuery(regexp.QuoteMeta("LEFT JOIN user_roles ur ON ur.user_id = u.id")).WithArgs(1).WillReturnRows(
+		sqlmock.NewRows(assignmentColumns).AddRow(4, "users:write", "", now, auth.SystemActor, now))
+	mock.ExpectExec(regexp.QuoteMeta("DELETE ur FROM user_roles ur")).WithArgs(1, "posts:write").
+		WillReturnResult(sqlmock.NewResult(0, 0))
+
+	serve := func(method, path, body string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		r.ServeHTTP(w, req)
+		return w
+	}
+
+	w := serve(http.MethodPut, "/api/users/1/roles/users:write", "")
+	assert.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	assert.Contains(t, w.Body.String(), `"name":"users:write"`)
+
+	w = serve(http.MethodDelete, "/api/users/1/roles/posts:write", "")
+	assert.Equal(t, http.StatusNotFound, w.Code, w.Body.String())
+
+	w = serve(http.MethodPost, "/api/roles", `{"name": "Users Write"}`)
+	assert.Equal(t, http.StatusBadRequest, w.Code, w.Body.String())
+
+	w = serve(http.MethodGet, "/api/users/abc/roles", "")
+	assert.Equal(t, http.StatusBadRequest, w.Code, w.Body.String())
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
@@ -0,0 +1,367 @@
+package roles
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"arquivolivre.com.br/otel/internal/auth"
+	"arquivolivre.com.br/otel/internal/database"
+	"arquivolivre.com.br/otel/pkg/apperrors"
+
+	"github.com/go-sql-driver/mysql"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const roleColumns = "id, name, description, created_at"
+
+// MySQL error numbers of key violations
+const (
+	mysqlErrDuplicateEntry  = 1062
+	mysqlErrNoReferencedRow = 1452
+)
+
+// MySQLStore keeps roles in the roles table and their assignments in
+// user_roles
+type MySQLStore struct {
+	db     *database.DB
+	tracer trace.Tracer
+}
+
+var _ Store = (*MySQLStore)(nil)
+
+// NewMySQLStore creates a store on db
+func NewMySQLStore(db *database.DB) *MySQLStore {
+	return &MySQLStore{
+		db:     db,
+		tracer: otel.Tracer("role-store"),
+	}
+}
+
+// List returns every role by name
+func (s *MySQLStore) List(ctx context.Context) ([]Role, error) {
+	ctx, span := s.start(ctx, "RoleStore.List", "SELECT", "roles")
+	defer span.End()
+	ctx, cancel := s.db.WithQueryTimeout(ctx)
+	defer cancel()
+
+	query := "SELECT " + roleColumns + " FROM roles ORDER BY name"
+	done := s.db.TrackQuery(ctx, "SELECT", "roles", query)
+	rows, err := s.db.QueryContext(ctx, query)
+	done(err)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query roles: %w", err)
+	}
+	defer func() { _ = rows.Close() }()
+
+	list := []Role{}
+	for rows.Next() {
+		var role Role
+		if err := rows.Scan(&role.ID, &role.Name, &role.Description, &role.CreatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan role: %w", err)
+		}
+		list = append(list, role)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating over roles: %w", err)
+	}
+
+	span.SetAttributes(attribute.Int("result.count", len(list)))
+	return list, nil
+}
+
+// Create defines a role; its name must be unused
+func (s *MySQLStore) Create(ctx context.Context, req CreateRequest) (*Role, error) {
+	ctx, span := s.start(ctx, "RoleStore.Create", "INSERT", "roles")
+	defer span.End()
+	ctx, cancel := s.db.WithQueryTimeout(ctx)
+	defer cancel()
+	span.SetAttributes(attribute.String("role.name", req.Name))
+
+	query := "INSERT INTO roles (name, description) VALUES (?, ?)"
+	done := s.db.TrackQuery(ctx, "INSERT", "roles", query)
+	result, err := s.db.ExecContext(ctx, query, req.Name, req.Description)
+	done(err)
+	if isMySQLError(err, mysqlErrDuplicateEntry) {
+		return nil, apperrors.Wrap(apperrors.ErrConflict, err, "role %s already exists", req.Name)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to create role: %w", err)
+	}
+	id, err := result.LastInsertId()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get last insert id: %w", err)
+	}
+	span.SetAttributes(attribute.Int64("role.id", id))
+
+	query = "SELECT " + roleColumns + " FROM roles WHERE id = ?"
+	done = s.db.TrackQuery(ctx, "SELECT", "roles", query)
+	var role Role
+	err = s.db.QueryRowContext(ctx, query, id).Scan(&role.ID, &role.Name, &role.Description, &role.CreatedAt)
+	done(err)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read created role: %w", err)
+	}
+	return &role, nil
+}
+
+// UserRoles left joins the user to its roles, so a user without roles still
+// returns a row and an unknown user none
+func (s *MySQLStore) UserRoles(ctx context.Context, userID int) ([]Assignment, error) {
+	ctx, span := s.start(ctx, "RoleStore.UserRoles", "SELECT", "user_roles")
+	defer span.End()
+	ctx, cancel := s.db.WithQueryTimeout(ctx)
+	defer cancel()
+	span.SetAttributes(attribute.Int("user.id", userID))
+
+	query := `SELECT r.id, r.name, r.description, r.created_at, ur.assigned_by, ur.assigned_at
+		FROM users u
+		LEFT JOIN user_roles ur ON ur.user_id = u.id
+		LEFT JOIN roles r ON r.id = ur.role_id
+		WHERE u.id = ?
+		ORDER BY r.name`
+	done := s.db.TrackQuery(ctx, "SELECT", "user_roles", query)
+	rows, err := s.db.QueryContext(ctx, query, userID)
+	done(err)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query user roles: %w", err)
+	}
+	defer func() { _ = rows.Close() }()
+
+	joined := 0
+	assignments := []Assignment{}
+	for rows.Next() {
+		joined++
+		var roleID sql.NullInt64
+		var name, description, assignedBy sql.NullString
+		var assignment Assignment
+		if err := rows.Scan(&roleID, &name, &description, &assignment.CreatedAt, &assignedBy, &assignment.AssignedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan user role: %w", err)
+		}
+		if !roleID.Valid {
+			continue
+		}
+		assignment.ID = int(roleID.Int64)
+		assignment.Name = name.String
+		assignment.Description = description.String
+		assignment.AssignedBy = assignedBy.String
+		assignments = append(assignments, assignment)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating over user roles: %w", err)
+	}
+
+	span.SetAttributes(joinAttributes(joined, "users", "user_roles", "roles")...)
+	span.SetAttributes(attribute.Int("result.count", len(assignments)))
+	if joined == 0 {
+		return nil, apperrors.NotFound("user not found")
+	}
+	return assignments, nil
+}
+
+// Assign records the acting principal as the assigner of a new assignment,
+// and the current email of the user as the subject it is granted to
+func (s *MySQLStore) Assign(ctx context.Context, userID int, role string) error {
+	ctx, span := s.start(ctx, "RoleStore.Assign", "INSERT", "user_roles")
+	defer span.End()
+	ctx, cancel := s.db.WithQueryTimeout(ctx)
+	defer cancel()
+	span.SetAttributes(attribute.Int("user.id", userID), attribute.String("role.name", role))
+
+	roleID, err := s.roleID(ctx, role)
+	if err != nil {
+		return err
+	}
+
+	var subject string
+	query := "SELECT email FROM users WHERE id = ?"
+	done := s.db.TrackQuery(ctx, "SELECT", "users", query)
+	err = s.db.QueryRowContext(ctx, query, userID).Scan(&subject)
+	done(err)
+	if errors.Is(err, sql.ErrNoRows) {
+		return apperrors.NotFound("user not found")
+	}
+	if err != nil {
+		return fmt.Errorf("failed to look up user: %w", err)
+	}
+
+	query = "INSERT INTO user_roles (user_id, role_id, subject, assigned_by) VALUES (?, ?, ?, ?) ON DUPLICATE KEY UPDATE user_id = user_id"
+	done = s.db.TrackQuery(ctx, "INSERT", "user_roles", query)
+	result, err := s.db.ExecContext(ctx, query, userID, roleID, subject, auth.Actor(ctx))
+	done(err)
+	if isMySQLError(err, mysqlErrNoReferencedRow) {
+		return apperrors.Wrap(apperrors.ErrNotFound, err, "user not found")
+	}
+	if err != nil {
+		return fmt.Errorf("failed to assign role: %w", err)
+	}
+	// An existing assignment is left unchanged and affects no rows
+	if inserted, err := result.RowsAffected(); err == nil {
+		span.SetAttributes(attribute.Bool("role.already_assigned", inserted == 0))
+	}
+	return nil
+}
+
+// Remove deletes the assignment through a join on the role name
+func (s *MySQLStore) Remove(ctx context.Context, userID int, role string) error {
+	ctx, span := s.start(ctx, "RoleStore.Remove", "DELETE", "user_roles")
+	defer span.End()
+	ctx, cancel := s.db.WithQueryTimeout(ctx)
+	defer cancel()
+	span.SetAttributes(attribute.Int("user.id", userID), attribute.String("role.name", role))
+
+	query := `DELETE ur FROM user_roles ur
+		JOIN roles r ON r.id = ur.role_id
+		WHERE ur.user_id = ? AND r.name = ?`
+	done := s.db.TrackQuery(ctx, "DELETE", "user_roles", query)
+	result, err := s.db.ExecContext(ctx, query, userID, role)
+	done(err)
+	if err != nil {
+		return fmt.Errorf("failed to remove role: %w", err)
+	}
+	deleted, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get affected rows: %w", err)
+	}
+	span.SetAttributes(joinAttributes(int(deleted), "user_roles", "roles")...)
+	if deleted == 0 {
+		return apperrors.NotFound("role %s is not assigned to user %d", role, userID)
+	}
+	return nil
+}
+
+// Members joins the assignments of role to their users
+func (s *MySQLStore) Members(ctx context.Context, role string, limit, offset int) ([]Member, int, error) {
+	ctx, span := s.start(ctx, "RoleStore.Members", "SELECT", "user_roles")
+	defer span.End()
+	ctx, cancel := s.db.WithQueryTimeout(ctx)
+	defer cancel()
+	span.SetAttributes(
+		attribute.String("role.name", role),
+		attribute.Int("query.limit", limit),
+		attribute.Int("query.offset", offset),
+	)
+
+	roleID, err := s.roleID(ctx, role)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	var total int
+	countQuery := "SELECT COUNT(*) FROM user_roles WHERE role_id = ?"
+	done := s.db.TrackQuery(ctx, "SELECT", "user_roles", countQuery)
+	err = s.db.QueryRowContext(ctx, countQuery, roleID).Scan(&total)
+	done(err)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to count role members: %w", err)
+	}
+
+	query := `SELECT u.id, u.name, ur.assigned_by, ur.assigned_at
+		FROM user_roles ur
+		JOIN users u ON u.id = ur.user_id
+		WHERE ur.role_id = ?
+		ORDER BY u.id
+		LIMIT ? OFFSET ?`
+	done = s.db.TrackQuery(ctx, "SELECT", "user_roles", query)
+	rows, err := s.db.QueryContext(ctx, query, roleID, limit, offset)
+	done(err)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to query role members: %w", err)
+	}
+	defer func() { _ = rows.Close() }()
+
+	members := []Member{}
+	for rows.Next() {
+		var member Member
+		if err := rows.Scan(&member.UserID, &member.Name, &member.AssignedBy, &member.AssignedAt); err != nil {
+			return nil, 0, fmt.Errorf("failed to scan role member: %w", err)
+		}
+		members = append(members, member)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, 0, fmt.Errorf("error iterating over role members: %w", err)
+	}
+
+	span.SetAttributes(joinAttributes(len(members), "user_roles", "users")...)
+	span.SetAttributes(attribute.Int("result.count", len(members)), attribute.Int("role.members", total))
+	return members, total, nil
+}
+
+// RolesOf returns the names of the roles assigned to subject. Assignments
+// keep the email the user had when they were made, so a user whose email is
+// changed later, by anyone allowed to write users, does not carry its roles
+// to the new email
+func (s *MySQLStore) RolesOf(ctx context.Context, subject string) ([]string, error) {
+	ctx, span := s.start(ctx, "RoleStore.RolesOf", "SELECT", "user_roles")
+	defer span.End()
+	ctx, cancel := s.db.WithQueryTimeout(ctx)
+	defer cancel()
+
+	query := `SELECT r.name
+		FROM user_roles ur
+		JOIN roles r ON r.id = ur.role_id
+		WHERE ur.subject = ?
+		ORDER BY r.name`
+	done := s.db.TrackQuery(ctx, "SELECT", "user_roles", query)
+	rows, err := s.db.QueryContext(ctx, query, subject)
+	done(err)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query assigned roles: %w", err)
+	}
+	defer func() { _ = rows.Close() }()
+
+	names := []string{}
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			return nil, fmt.Errorf("failed to scan role name: %w", err)
+		}
+		names = append(names, name)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating over assigned roles: %w", err)
+	}
+
+	span.SetAttributes(joinAttributes(len(names), "user_roles", "roles")...)
+	return names, nil
+}
+
+// roleID returns the ID of the role named name
+func (s *MySQLStore) roleID(ctx context.Context, name string) (int, error) {
+	query := "SELECT id FROM roles WHERE name = ?"
+	done := s.db.TrackQuery(ctx, "SELECT", "roles", query)
+	var id int
+	err := s.db.QueryRowContext(ctx, query, name).Scan(&id)
+	done(err)
+	if errors.Is(err, sql.ErrNoRows) {
+		return 0, apperrors.NotFound("role %s not found", name)
+	}
+	if err != nil {
+		return 0, fmt.Errorf("failed to query role: %w", err)
+	}
+	return id, nil
+}
+
+func (s *MySQLStore) start(ctx context.Context, name, operation, table string) (context.Context, trace.Span) {
+	return s.tracer.Start(ctx, name, trace.WithAttributes(
+		attribute.String("db.operation", operation),
+		attribute.String("db.table", table),
+	))
+}
+
+// joinAttributes describe a join of tables that produced rows rows, so the
+// fan-out of an assignment lookup shows on its span
+func joinAttributes(rows int, tables ...string) []attribute.KeyValue {
+	return []attribute.KeyValue{
+		attribute.StringSlice("db.join.tables", tables),
+		attribute.Int("db.join.rows", rows),
+	}
+}
+
+func isMySQLError(err error, number uint16) bool {
+	var mysqlErr *mysql.MySQLError
+	return errors.As(err, &mysqlErr) && mysqlErr.Number == number
+}
@@ -0,0 +1,182 @@
+// Package scheduler runs periodic tasks on cron schedules. Every run gets
+// its own root span and duration/outcome metrics, and a run is skipped while
+// the previous run of the same task is still in progress.
+package scheduler
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"arquivolivre.com.br/otel/internal/logging"
+
+	"github.com/robfig/cron/v3"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/metric"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const instrumentationName = "arquivolivre.com.br/otel/internal/scheduler"
+
+// Disabled is the schedule value that turns a task off
+const Disabled = "off"
+
+// Task is a unit of periodic work
+type Task struct {
+	Name     string
+	Schedule string
+	Run      func(ctx context.Context) error
+}
+
+type schedulerMetrics struct {
+	duration metric.Float64Histogram
+	runs     metric.Int64Counter
+	skipped  metric.Int64Counter
+}
+
+// Scheduler runs registered tasks on their cron schedules
+type Scheduler struct {
+	cron    *cron.Cron
+	tracer  trace.Tracer
+	metrics schedulerMetrics
+	timeout time.Duration
+
+	mu      sync.Mutex
+	running map[string]*atomic.Bool
+}
+
+// New creates a scheduler; each run is cancelled after timeout when it is
+// positive
+func New(timeout time.Duration) (*Scheduler, error) {
+	m, err := newSchedulerMetrics(otel.Meter(instrumentationName))
+	if err != nil {
+		return nil, err
+	}
+
+	return &Scheduler{
+		cron:    cron.New(cron.WithParser(cron.NewParser(cron.Minute | cron.Hour | cron.Dom | cron.Month | cron.Dow | cron.Descriptor))),
+		tracer:  otel.Tracer(instrumentationName),
+		metrics: m,
+		timeout: timeout,
+		running: make(map[string]*atomic.Bool),
+	}, nil
+}
+
+func newSchedulerMetrics(meter metric.Meter) (schedulerMetrics, error) {
+	duration, err := meter.Float64Histogram(
+		"scheduler_run_duration_seconds",
+		metric.WithDescription("Scheduled task run duration in seconds"),
+		metric.WithUnit("s"),
+	)
+	if err != nil {
+		return schedulerMetrics{}, fmt.Errorf("failed to create run duration metric: %w", err)
+	}
+
+	runs, err := meter.Int64Counter(
+		"scheduler_runs_total",
+		metric.WithDescription("Total number of scheduled task runs by status"),
+	)
+	if err != nil {
+		return schedulerMetrics{}, fmt.Errorf("failed to create runs metric: %w", err)
+	}
+
+	skipped, err := meter.Int64Counter(
+		"scheduler_skipped_runs_total",
+		metric.WithDescription("Total number of runs skipped because the previous run was still in progress"),
+	)
+	if err != nil {
+		return schedulerMetrics{}, fmt.Errorf("failed to create skipped runs metric: %w", err)
+	}
+
+	return schedulerMetrics{duration: duration, runs: runs, skipped: skipped}, nil
+}
+
+// Register adds a task. Tasks whose schedule is empty or Disabled are
+// ignored; an invalid cron expression is an error.
+func (s *Scheduler) Register(task Task) error {
+	if task.Schedule == "" || task.Schedule == Disabled {
+		return nil
+	}
+
+	s.mu.Lock()
+	if _, exists := s.running[task.Name]; exists {
+		s.mu.Unlock()
+		return fmt.Errorf("task %q already registered", task.Name)
+	}
+	busy := &atomic.Bool{}
+	s.running[task.Name] = busy
+	s.mu.Unlock()
+
+	if _, err := s.cron.AddFunc(task.Schedule, func() { s.run(task, busy) }); err != nil {
+		return fmt.Errorf("invalid schedule %q for task %q: %w", task.Schedule, task.Name, err)
+	}
+	return nil
+}
+
+// Start begins running tasks in the background
+func (s *Scheduler) Start() {
+	s.cron.Start()
+}
+
+// Stop prevents new runs and waits for in-flight runs or ctx to expire
+func (s *Scheduler) Stop(ctx context.Context) error {
+	select {
+	case <-s.cron.Stop().Done():
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (s *Scheduler) run(task Task, busy *atomic.Bool) {
+	nameAttr := attribute.String("task.name", task.Name)
+	if !busy.CompareAndSwap(false, true) {
+		s.metrics.skipped.Add(context.Background(), 1, metric.WithAttributes(nameAttr))
+		logging.Module("scheduler").LogWarn(context.Background(), "Skipping scheduled task, previous run still in progress", map[string]interface{}{
+			"task": task.Name,
+		})
+		return
+	}
+	defer busy.Store(false)
+
+	ctx := context.Background()
+	if s.timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, s.timeout)
+		defer cancel()
+	}
+
+	ctx, span := s.tracer.Start(ctx, "scheduler "+task.Name,
+		trace.WithNewRoot(),
+		trace.WithAttributes(nameAttr, attribute.String("task.schedule", task.Schedule)),
+	)
+	defer span.End()
+
+	start := time.Now()
+	err := safeRun(ctx, task.Run)
+	status := "success"
+	if err != nil {
+		status = "error"
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+		logging.Module("scheduler").LogError(ctx, err, "Scheduled task failed", map[string]interface{}{"task": task.Name})
+	}
+
+	statusAttr := attribute.String("status", status)
+	s.metrics.duration.Record(ctx, time.Since(start).Seconds(), metric.WithAttributes(nameAttr, statusAttr))
+	s.metrics.runs.Add(ctx, 1, metric.WithAttributes(nameAttr, statusAttr))
+}
+
+// safeRun turns a panicking task into an error so the scheduler keeps going
+func safeRun(ctx context.Context, fn func(ctx context.Context) error) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("task panicked: %v", r)
+		}
+	}()
+	return fn(ctx)
+}
@@ -0,0 +1,70 @@
+package scheduler
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+func TestRegisterValidatesSchedules(t *testing.T) {
+	s, err := New(0)
+	require.NoError(t, err)
+
+	noop := func(context.Context) error { return nil }
+	assert.NoError(t, s.Register(Task{Name: "off", Schedule: Disabled, Run: noop}))
+	assert.NoError(t, s.Register(Task{Name: "empty", Run: noop}))
+	assert.NoError(t, s.Register(Task{Name: "every", Schedule: "@every 1m", Run: noop}))
+	assert.NoError(t, s.Register(Task{Name: "cron", Schedule: "*/5 * * * *", Run: noop}))
+	assert.Error(t, s.Register(Task{Name: "cron", Schedule: "*/5 * * * *", Run: noop}), "duplicate name")
+	assert.Error(t, s.Register(Task{Name: "bad", Schedule: "not a cron", Run: noop}))
+	assert.Len(t, s.cron.Entries(), 2)
+}
+
+func TestRunRecordsSpanAndSkipsOverlap(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	tp := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+	prev := otel.GetTracerProvider()
+	otel.SetTracerProvider(tp)
+	defer otel.SetTracerProvider(prev)
+
+	s, err := New(0)
+	require.NoError(t, err)
+
+	var calls atomic.Int32
+	task := Task{Name: "cleanup", Schedule: "@every 1h", Run: func(context.Context) error {
+		calls.Add(1)
+		return errors.New("boom")
+	}}
+
+	busy := &atomic.Bool{}
+	busy.Store(true)
+	s.run(task, busy)
+	assert.Equal(t, int32(0), calls.Load(), "overlapping run must be skipped")
+
+	busy.Store(false)
+	s.run(task, busy)
+	assert.Equal(t, int32(1), calls.Load())
+	assert.False(t, busy.Load())
+
+	spans := recorder.Ended()
+	require.Len(t, spans, 1)
+	assert.Equal(t, "scheduler cleanup", spans[0].Name())
+	assert.Equal(t, "boom", spans[0].Status().Description)
+
+	s.run(Task{Name: "panics", Run: func(context.Context) error { panic("bad") }}, &atomic.Bool{})
+	assert.Contains(t, recorder.Ended()[1].Status().Description, "bad")
+}
+
+func TestStartStop(t *testing.T) {
+	s, err := New(0)
+	require.NoError(t, err)
+	s.Start()
+	assert.NoError(t, s.Stop(context.Background()))
+}
@@ -0,0 +1,105 @@
+// Package seed fills the database with fake users and posts for demos. The
+// data depends only on the seed, so two runs with the same seed generate the
+// same users and a repeated run inserts nothing new
+package seed
+
+import (
+	"fmt"
+	"math/rand/v2"
+	"strings"
+)
+
+var (
+	firstNames = []string{
+		"Ada", "Alan", "Beatriz", "Carlos", "Chen", "Diego", "Elena", "Fatima",
+		"Grace", "Hiro", "Ines", "Jamal", "Julia", "Kofi", "Lucas", "Maya",
+		"Noah", "Olga", "Priya", "Rafael", "Sofia", "Tomas", "Yara", "Zoe",
+	}
+	lastNames = []string{
+		"Almeida", "Brown", "Costa", "Dubois", "Fernandes", "Garcia", "Hansen",
+		"Ito", "Kowalski", "Lopez", "Mensah", "Nakamura", "Okafor", "Patel",
+		"Rossi", "Silva", "Smith", "Tanaka", "Novak", "Wang",
+	}
+	roles = []string{
+		"software engineer", "site reliability engineer", "product manager",
+		"designer", "data analyst", "support specialist", "salesperson",
+	}
+	interests = []string{
+		"distributed tracing", "coffee", "open source", "hiking", "databases",
+		"observability", "chess", "cycling", "Go", "dashboards",
+	}
+	topics = []string{
+		"Tracing", "Metrics", "Logs", "Alerting", "Dashboards", "Sampling",
+		"Exemplars", "Collectors", "Retries", "Timeouts",
+	}
+	sentences = []string{
+		"Every request now carries its trace ID end to end.",
+		"The P99 latency dropped after we fixed the slow query.",
+		"We added a histogram for the queue wait time.",
+		"Sampling keeps the storage bill under control.",
+		"The collector batches spans before exporting them.",
+		"An exemplar linked the spike straight to its trace.",
+		"Structured logs made the incident review much shorter.",
+		"The dashboard shows the error rate per route.",
+	}
+)
+
+// User is a generated user
+type User struct {
+	Name  string
+	Email string
+	Bio   string
+}
+
+// Post is a generated post
+type Post struct {
+	Title string
+	Body  string
+}
+
+// Generator produces fake users and posts from a seed
+type Generator struct {
+	seed uint64
+	rng  *rand.Rand
+}
+
+// NewGenerator creates a generator; generators with the same seed produce
+// the same sequence
+func NewGenerator(seed uint64) *Generator {
+	return &Generator{seed: seed, rng: rand.New(rand.NewPCG(seed, seed))}
+}
+
+// User returns the i-th user. Its email holds the seed and i, so it is
+// unique within and across seeds
+func (g *Generator) User(i int) User {
+	first := pick(g.rng, firstNames)
+	last := pick(g.rng, lastNames)
+	return User{
+		Name:  first + " " + last,
+		Email: fmt.Sprintf("%s.%s.%d.%d@example.com", strings.ToLower(first), strings.ToLower(last), g.seed, i),
+		Bio:   fmt.Sprintf("I am a %s who likes %s", pick(g.rng, roles), pick(g.rng, interests)),
+	}
+}
+
+// Posts returns between 0 and limit posts
+func (g *Generator) Posts(limit int) []Post {
+	if limit < 1 {
+		return nil
+	}
+	posts := make([]Post, g.rng.IntN(limit+1))
+	for i := range posts {
+		body := make([]string, 1+g.rng.IntN(3))
+		for j := range body {
+			body[j] = pick(g.rng, sentences)
+		}
+		posts[i] = Post{
+			Title: fmt.Sprintf("%s notes #%d", pick(g.rng, topics), 1+g.rng.IntN(100)),
+			Body:  strings.Join(body, " "),
+		}
+	}
+	return posts
+}
+
+func pick(rng *rand.Rand, values []string) string {
+	return values[rng.IntN(len(values))]
+}
@@ -0,0 +1,205 @@
+package seed
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+
+	"arquivolivre.com.br/otel/internal/database"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+)
+
+// Actor is recorded as created_by and updated_by of the seeded rows
+const Actor = "seed"
+
+// DefaultBatchSize is the number of users inserted per transaction when
+// Options.BatchSize is not set
+const DefaultBatchSize = 100
+
+// Options configure a seeding run
+type Options struct {
+	// Users is the number of users to generate
+	Users int
+	// MaxPostsPerUser bounds the posts generated for each user
+	MaxPostsPerUser int
+	// Seed selects the generated data
+	Seed uint64
+	// BatchSize is the number of users inserted per transaction
+	BatchSize int
+	// Progress, when set, is called after each batch with the number of
+	// users generated so far
+	Progress func(done, total int)
+}
+
+// Result counts the rows inserted by a run; users that already exist are
+// skipped along with their posts
+type Result struct {
+	Users int
+	Posts int
+}
+
+// Run inserts the users and posts generated from opts.Seed, one transaction
+// per batch. The batches committed before an error are kept
+func Run(ctx context.Context, db *database.DB, opts Options) (Result, error) {
+	if opts.Users < 0 || opts.MaxPostsPerUser < 0 || opts.BatchSize < 0 {
+		return Result{}, fmt.Errorf("invalid seed options: counts must not be negative")
+	}
+	if opts.BatchSize == 0 {
+		opts.BatchSize = DefaultBatchSize
+	}
+
+	ctx, span := otel.Tracer("seed").Start(ctx, "seed.Run")
+	defer span.End()
+	span.SetAttributes(
+		attribute.Int("seed.users", opts.Users),
+		attribute.Int("seed.max_posts_per_user", opts.MaxPostsPerUser),
+		attribute.Int64("seed.seed", int64(opts.Seed)),
+		attribute.Int("batch.size", opts.BatchSize),
+	)
+
+	gen := NewGenerator(opts.Seed)
+	var result Result
+	for start := 0; start < opts.Users; start += opts.BatchSize {
+		end := min(start+opts.BatchSize, opts.Users)
+		batch := make([]generated, 0, end-start)
+		for i := start; i < end; i++ {
+			batch = append(batch, generated{user: gen.User(i), posts: gen.Posts(opts.MaxPostsPerUser)})
+		}
+
+		users, posts, err := insertBatch(ctx, db, batch)
+		result.Users += users
+		result.Posts += posts
+		if err != nil {
+			span.SetStatus(codes.Error, err.Error())
+			return result, err
+		}
+		if opts.Progress != nil {
+			opts.Progress(end, opts.Users)
+		}
+	}
+
+	span.SetAttributes(
+		attribute.Int("seed.users.created", result.Users),
+		attribute.Int("seed.posts.created", result.Posts),
+	)
+	return result, nil
+}
+
+type generated struct {
+	user  User
+	posts []Post
+}
+
+// insertBatch inserts the users of batch that do not exist yet and their
+// posts in one transaction
+func insertBatch(ctx context.Context, db *database.DB, batch []generated) (users, posts int, err error) {
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, 0, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	// A no-op once the transaction is committed
+	defer func() { _ = tx.Rollback() }()
+
+	emails := make([]string, len(batch))
+	for i, g := range batch {
+		emails[i] = g.user.Email
+	}
+	existing, err := userIDs(ctx, db, tx, emails)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	var fresh []generated
+	for _, g := range batch {
+		if _, ok := existing[g.user.Email]; !ok {
+			fresh = append(fresh, g)
+		}
+	}
+	if len(fresh) == 0 {
+		return 0, 0, tx.Commit()
+	}
+
+	args := make([]interface{}, 0, len(fresh)*5)
+	emails = emails[:0]
+	for _, g := range fresh {
+		args = append(args, g.user.Name, g.user.Email, g.user.Bio, Actor, Actor)
+		emails = append(emails, g.user.Email)
+	}
+	query := `INSERT INTO users (name, email, bio, created_by, updated_by) VALUES ` + rows(len(fresh), 5)
+	if err := exec(ctx, db, tx, "users", query, args); err != nil {
+		return 0, 0, fmt.Errorf("failed to insert users: %w", err)
+	}
+
+	ids, err := userIDs(ctx, db, tx, emails)
+	if err != nil {
+		return 0, 0, err
+	}
+	args = args[:0]
+	for _, g := range fresh {
+		for _, post := range g.posts {
+			args = append(args, ids[g.user.Email], post.Title, post.Body, Actor, Actor)
+			posts++
+		}
+	}
+	if posts > 0 {
+		query := `INSERT INTO posts (user_id, title, body, created_by, updated_by) VALUES ` + rows(posts, 5)
+		if err := exec(ctx, db, tx, "posts", query, args); err != nil {
+			return 0, 0, fmt.Errorf("failed to insert posts: %w", err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, 0, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return len(fresh), posts, nil
+}
+
+// userIDs returns the IDs of the users with emails, keyed by email
+func userIDs(ctx context.Context, db *database.DB, tx *sql.Tx, emails []string) (map[string]int, error) {
+	args := make([]interface{}, len(emails))
+	for i, email := range emails {
+		args[i] = email
+	}
+	query := `SELECT id, email FROM users WHERE email IN (?` + strings.Repeat(", ?", len(emails)-1) + `)`
+
+	done := db.TrackQuery(ctx, "SELECT", "users", query)
+	result, err := tx.QueryContext(ctx, query, args...)
+	done(err)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query users: %w", err)
+	}
+	defer func() { _ = result.Close() }()
+
+	ids := make(map[string]int, len(emails))
+	for result.Next() {
+		var (
+			id    int
+			email string
+		)
+		if err := result.Scan(&id, &email); err != nil {
+			return nil, fmt.Errorf("failed to scan user: %w", err)
+		}
+		ids[email] = id
+	}
+	if err := result.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating over users: %w", err)
+	}
+	return ids, nil
+}
+
+func exec(ctx context.Context, db *database.DB, tx *sql.Tx, table, query string, args []interface{}) error {
+	done := db.TrackQuery(ctx, "INSERT", table, query)
+	_, err := tx.ExecContext(ctx, query, args...)
+	done(err)
+	return err
+}
+
+// rows returns the placeholders of n rows of columns values each
+func rows(n, columns int) string {
+	row := "(?" + strings.Repeat(", ?", columns-1) + ")"
+	return row + strings.Repeat(", "+row, n-1)
+}
@@ -0,0 +1,72 @@
+package seed
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"testing"
+
+	"arquivolivre.com.br/otel/internal/database"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGeneratorIsDeterministic(t *testing.T) {
+	a, b := NewGenerator(7), NewGenerator(7)
+	for i := range 20 {
+		user := a.User(i)
+		assert.Equal(t, user, b.User(i))
+		assert.Equal(t, a.Posts(3), b.Posts(3))
+		assert.True(t, strings.HasSuffix(user.Email, fmt.Sprintf(".7.%d@example.com", i)), user.Email)
+		assert.NotEmpty(t, user.Name)
+		assert.NotEmpty(t, user.Bio)
+	}
+
+	assert.NotEqual(t, NewGenerator(7).User(0).Email, NewGenerator(8).User(0).Email)
+	assert.Empty(t, NewGenerator(7).Posts(0))
+}
+
+func TestRunSkipsExistingUsers(t *testing.T) {
+	sqlDB, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer func() { _ = sqlDB.Close() }()
+	db := &database.DB{DB: sqlDB}
+
+	gen := NewGenerator(1)
+	first, second := gen.User(0), gen.User(1)
+
+	mock.ExpectBegin()
+	mock.ExpectQuery(regexp.QuoteMeta(`SELECT id, email FROM users WHERE email IN (?, ?)`)).
+		WithArgs(first.Email, second.Email).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "email"}).AddRow(1, first.Email))
+	mock.ExpectExec(regexp.QuoteMeta(`INSERT INTO users (name, email, bio, created_by, updated_by) VALUES (?, ?, ?, ?, ?)`)).
+		WithArgs(second.Name, second.Email, second.Bio, Actor, Actor).
+		WillReturnResult(sqlmock.NewResult(2, 1))
+	mock.ExpectQuery(regexp.QuoteMeta(`SELECT id, email FROM users WHERE email IN (?)`)).
+		WithArgs(second.Email).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "email"}).AddRow(2, second.Email))
+	mock.ExpectCommit()
+
+	var progress []int
+	result, err := Run(t.Context(), db, Options{
+		Users:    2,
+		Seed:     1,
+		Progress: func(done, total int) { progress = append(progress, done, total) },
+	})
+	require.NoError(t, err)
+	assert.Equal(t, Result{Users: 1}, result)
+	assert.Equal(t, []int{2, 2}, progress)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestRunRejectsNegativeCounts(t *testing.T) {
+	_, err := Run(t.Context(), &database.DB{}, Options{Users: -1})
+	assert.Error(t, err)
+}
+
+func TestRows(t *testing.T) {
+	assert.Equal(t, "(?, ?)", rows(1, 2))
+	assert.Equal(t, "(?, ?), (?, ?), (?, ?)", rows(3, 2))
+}
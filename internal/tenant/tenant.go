@@ -0,0 +1,80 @@
+// Package tenant carries the tenant of a request in OpenTelemetry baggage, so
+// it crosses service boundaries with the trace context and every span and
+// log record of the request can be sliced by tenant.id. Tenants are chosen
+// by clients, so they are kept out of metric attributes
+package tenant
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/baggage"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+)
+
+// Key names the tenant in baggage and in span and log attributes
+const Key = "tenant.id"
+
+// Header carries the tenant ID of HTTP requests
+const Header = "X-Tenant-ID"
+
+// validID bounds tenant IDs to short, safe label values
+var validID = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9._-]{0,63}$`)
+
+// Valid reports whether id can be used as a tenant ID
+func Valid(id string) bool {
+	return validID.MatchString(id)
+}
+
+// WithID returns a copy of ctx whose baggage carries id as the tenant,
+// replacing any tenant already there
+func WithID(ctx context.Context, id string) (context.Context, error) {
+	if !Valid(id) {
+		return ctx, fmt.Errorf("invalid tenant ID %q", id)
+	}
+	member, err := baggage.NewMember(Key, id)
+	if err != nil {
+		return ctx, err
+	}
+	bag, err := baggage.FromContext(ctx).SetMember(member)
+	if err != nil {
+		return ctx, err
+	}
+	return baggage.ContextWithBaggage(ctx, bag), nil
+}
+
+// FromContext returns the tenant ID in the baggage of ctx, if any. Baggage
+// received from callers is not validated on the way in, so invalid IDs are
+// ignored here
+func FromContext(ctx context.Context) string {
+	id := baggage.FromContext(ctx).Member(Key).Value()
+	if !Valid(id) {
+		return ""
+	}
+	return id
+}
+
+// Attributes returns the tenant.id attribute of ctx, or nothing without a
+// tenant
+func Attributes(ctx context.Context) []attribute.KeyValue {
+	if id := FromContext(ctx); id != "" {
+		return []attribute.KeyValue{attribute.String(Key, id)}
+	}
+	return nil
+}
+
+// SpanProcessor adds tenant.id to every span started in a context carrying a
+// tenant, so spans of libraries and repositories are annotated too
+type SpanProcessor struct{}
+
+var _ sdktrace.SpanProcessor = SpanProcessor{}
+
+func (SpanProcessor) OnStart(ctx context.Context, span sdktrace.ReadWriteSpan) {
+	span.SetAttributes(Attributes(ctx)...)
+}
+
+func (SpanProcessor) OnEnd(sdktrace.ReadOnlySpan)      {}
+func (SpanProcessor) Shutdown(context.Context) error   { return nil }
+func (SpanProcessor) ForceFlush(context.Context) error { return nil }
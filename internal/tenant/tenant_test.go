@@ -0,0 +1,56 @@
+package tenant
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/baggage"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+func TestWithID(t *testing.T) {
+	assert.Empty(t, FromContext(context.Background()))
+	assert.Nil(t, Attributes(context.Background()))
+
+	ctx, err := WithID(context.Background(), "acme")
+	require.NoError(t, err)
+	assert.Equal(t, "acme", FromContext(ctx))
+
+	ctx, err = WithID(ctx, "globex")
+	require.NoError(t, err)
+	assert.Equal(t, "globex", FromContext(ctx), "a new tenant replaces the previous one")
+
+	for _, id := range []string{"", "-acme", "acme corp", "a/b", string(make([]byte, 65))} {
+		_, err := WithID(context.Background(), id)
+		assert.Error(t, err, "expected %q to be rejected", id)
+	}
+}
+
+func TestFromContextIgnoresInvalidBaggage(t *testing.T) {
+	member, err := baggage.NewMember(Key, "not%20valid")
+	require.NoError(t, err)
+	bag, err := baggage.New(member)
+	require.NoError(t, err)
+
+	assert.Empty(t, FromContext(baggage.ContextWithBaggage(context.Background(), bag)))
+}
+
+func TestSpanProcessorAnnotatesSpans(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	tp := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(SpanProcessor{}), sdktrace.WithSpanProcessor(recorder))
+
+	ctx, err := WithID(context.Background(), "acme")
+	require.NoError(t, err)
+	_, span := tp.Tracer("test").Start(ctx, "with tenant")
+	span.End()
+	_, span = tp.Tracer("test").Start(context.Background(), "without tenant")
+	span.End()
+
+	spans := recorder.Ended()
+	require.Len(t, spans, 2)
+	assert.Contains(t, spans[0].Attributes(), Attributes(ctx)[0])
+	assert.Empty(t, spans[1].Attributes())
+}
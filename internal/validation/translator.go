@@ -0,0 +1,194 @@
+package validation
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+
+	"arquivolivre.com.br/otel/internal/models"
+
+	"github.com/go-playground/validator/v10"
+	"golang.org/x/text/language"
+)
+
+// fallbackRule is the catalog key used for tags without a dedicated message
+const fallbackRule = "*"
+
+// typeRule reports a JSON value of the wrong type, e.g. a number for a string
+const typeRule = "type"
+
+// jsonTypeName names the JSON type expected for values of Go type t
+func jsonTypeName(t reflect.Type) string {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	switch t.Kind() {
+	case reflect.String:
+		return "string"
+	case reflect.Bool:
+		return "boolean"
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Float32, reflect.Float64:
+		return "number"
+	case reflect.Slice, reflect.Array:
+		return "array"
+	default:
+		return "object"
+	}
+}
+
+// Catalog maps validator tags to message templates. Templates may use
+// {field} and {param}, e.g. "{field} must be at most {param} characters".
+type Catalog map[string]string
+
+// Translator renders validation failures as readable messages in the
+// locale that best matches the client's preferences
+type Translator struct {
+	tags     []language.Tag
+	catalogs []Catalog
+	matcher  language.Matcher
+}
+
+// NewTranslator creates a translator. The first locale is the default used
+// when nothing else matches.
+func NewTranslator(defaultLocale language.Tag, defaultCatalog Catalog) *Translator {
+	t := &Translator{}
+	t.AddLocale(defaultLocale, defaultCatalog)
+	return t
+}
+
+// AddLocale registers or replaces the catalog for a locale. Tags missing
+// from catalog fall back to the default locale.
+func (t *Translator) AddLocale(locale language.Tag, catalog Catalog) {
+	for i, tag := range t.tags {
+		if tag == locale {
+			t.catalogs[i] = catalog
+			return
+		}
+	}
+	t.tags = append(t.tags, locale)
+	t.catalogs = append(t.catalogs, catalog)
+	t.matcher = language.NewMatcher(t.tags)
+}
+
+// Translate renders fe in the best match for acceptLanguage
+func (t *Translator) Translate(fe validator.FieldError, acceptLanguage string) string {
+	return t.render(acceptLanguage, fe.Tag(), fe.Field(), fe.Param())
+}
+
+func (t *Translator) render(acceptLanguage, rule, field, param string) string {
+	template := t.template(t.catalogFor(acceptLanguage), rule)
+	return strings.NewReplacer(
+		"{field}", field,
+		"{param}", param,
+		"{rule}", rule,
+	).Replace(template)
+}
+
+// FieldErrors converts a binding error into field-level details, or returns
+// nil when err is neither a validation error nor a JSON value of the wrong
+// type
+func (t *Translator) FieldErrors(err error, acceptLanguage string) []models.FieldError {
+	var typeErr *json.UnmarshalTypeError
+	if errors.As(err, &typeErr) && typeErr.Field != "" {
+		return []models.FieldError{{
+			Field:   typeErr.Field,
+			Rule:    typeRule,
+			Message: t.render(acceptLanguage, typeRule, typeErr.Field, jsonTypeName(typeErr.Type)),
+		}}
+	}
+
+	var validationErrs validator.ValidationErrors
+	if !errors.As(err, &validationErrs) {
+		return nil
+	}
+
+	details := make([]models.FieldError, 0, len(validationErrs))
+	for _, fe := range validationErrs {
+		details = append(details, models.FieldError{
+			Field:   fe.Field(),
+			Rule:    fe.Tag(),
+			Message: t.Translate(fe, acceptLanguage),
+		})
+	}
+	return details
+}
+
+func (t *Translator) catalogFor(acceptLanguage string) Catalog {
+	if acceptLanguage == "" {
+		return t.catalogs[0]
+	}
+	prefs, _, err := language.ParseAcceptLanguage(acceptLanguage)
+	if err != nil || len(prefs) == 0 {
+		return t.catalogs[0]
+	}
+	_, index, _ := t.matcher.Match(prefs...)
+	return t.catalogs[index]
+}
+
+func (t *Translator) template(catalog Catalog, tag string) string {
+	for _, c := range []Catalog{catalog, t.catalogs[0]} {
+		if template, ok := c[tag]; ok {
+			return template
+		}
+	}
+	for _, c := range []Catalog{catalog, t.catalogs[0]} {
+		if template, ok := c[fallbackRule]; ok {
+			return template
+		}
+	}
+	return "{field} failed the {rule} rule"
+}
+
+// EnglishCatalog holds the default English messages
+var EnglishCatalog = Catalog{
+	"required":             "{field} is required",
+	"email":                "{field} must be a valid address",
+	"personname":           fmt.Sprintf("{field} must be between %d and %d characters and contain no control characters", MinNameLength, MaxNameLength),
+	"biotext":              fmt.Sprintf("{field} must be at most %d characters", MaxBioLength),
+	"allowed_email_domain": "{field} uses an email domain that is not allowed",
+	"min":                  "{field} must be at least {param} characters",
+	"max":                  "{field} must be at most {param} characters",
+	"gt":                   "{field} must be greater than {param}",
+	typeRule:               "{field} must be a {param}",
+	fallbackRule:           "{field} failed the {rule} rule",
+}
+
+// PortugueseCatalog holds Brazilian Portuguese messages
+var PortugueseCatalog = Catalog{
+	"required":             "{field} é obrigatório",
+	"email":                "{field} deve ser um endereço válido",
+	"personname":           fmt.Sprintf("{field} deve ter entre %d e %d caracteres e não conter caracteres de controle", MinNameLength, MaxNameLength),
+	"biotext":              fmt.Sprintf("{field} deve ter no máximo %d caracteres", MaxBioLength),
+	"allowed_email_domain": "{field} usa um domínio de e-mail não permitido",
+	"min":                  "{field} deve ter pelo menos {param} caracteres",
+	"max":                  "{field} deve ter no máximo {param} caracteres",
+	"gt":                   "{field} deve ser maior que {param}",
+	typeRule:               "{field} deve ser do tipo {param}",
+	fallbackRule:           "{field} não atende à regra {rule}",
+}
+
+// SpanishCatalog holds Spanish messages
+var SpanishCatalog = Catalog{
+	"required":             "{field} es obligatorio",
+	"email":                "{field} debe ser una dirección válida",
+	"personname":           fmt.Sprintf("{field} debe tener entre %d y %d caracteres y no contener caracteres de control", MinNameLength, MaxNameLength),
+	"biotext":              fmt.Sprintf("{field} debe tener como máximo %d caracteres", MaxBioLength),
+	"allowed_email_domain": "{field} usa un dominio de correo no permitido",
+	"min":                  "{field} debe tener al menos {param} caracteres",
+	"max":                  "{field} debe tener como máximo {param} caracteres",
+	"gt":                   "{field} debe ser mayor que {param}",
+	typeRule:               "{field} debe ser de tipo {param}",
+	fallbackRule:           "{field} no cumple la regla {rule}",
+}
+
+// DefaultTranslator serves English, Brazilian Portuguese and Spanish
+var DefaultTranslator = func() *Translator {
+	t := NewTranslator(language.English, EnglishCatalog)
+	t.AddLocale(language.BrazilianPortuguese, PortugueseCatalog)
+	t.AddLocale(language.Spanish, SpanishCatalog)
+	return t
+}()
@@ -0,0 +1,66 @@
+package validation
+
+import (
+	"encoding/json"
+	"testing"
+
+	"arquivolivre.com.br/otel/internal/models"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/text/language"
+)
+
+func TestTranslator_Locales(t *testing.T) {
+	v := newValidator(t)
+	v.SetTagName("binding")
+	err := v.Struct(models.CreateUserRequest{Name: "Ann", Email: "not-an-email"})
+	require.Error(t, err)
+
+	cases := map[string]string{
+		"":                          "email must be a valid address",
+		"pt-BR,pt;q=0.9,en;q=0.8":   "email deve ser um endereço válido",
+		"pt":                        "email deve ser um endereço válido",
+		"es-MX":                     "email debe ser una dirección válida",
+		"fr-FR, de;q=0.5":           "email must be a valid address",
+		"this is not a language!!!": "email must be a valid address",
+	}
+	for acceptLanguage, want := range cases {
+		details := LocalizedFieldErrors(err, acceptLanguage)
+		require.Len(t, details, 1, acceptLanguage)
+		assert.Equal(t, want, details[0].Message, acceptLanguage)
+	}
+}
+
+func TestTranslator_Fallbacks(t *testing.T) {
+	v := newValidator(t)
+	tr := NewTranslator(language.English, Catalog{"required": "{field} is required"})
+	tr.AddLocale(language.German, Catalog{"required": "{field} ist erforderlich"})
+
+	// Tag missing from both catalogs uses the generic message
+	err := v.Struct(struct {
+		Code string `json:"code" validate:"len=5"`
+	}{Code: "abc"})
+	assert.Equal(t, "code failed the len rule", tr.FieldErrors(err, "de")[0].Message)
+
+	// Tag missing from the German catalog falls back to English
+	tr.AddLocale(language.German, Catalog{})
+	err = v.Struct(struct {
+		Name string `json:"name" validate:"required"`
+	}{})
+	assert.Equal(t, "name is required", tr.FieldErrors(err, "de")[0].Message)
+}
+
+func TestTranslator_JSONTypeErrors(t *testing.T) {
+	var req models.CreateUserRequest
+	err := json.Unmarshal([]byte(`{"name": 42, "email": "ann@example.com"}`), &req)
+	require.Error(t, err)
+
+	details := LocalizedFieldErrors(err, "")
+	require.Len(t, details, 1)
+	assert.Equal(t, models.FieldError{Field: "name", Rule: "type", Message: "name must be a string"}, details[0])
+	assert.Equal(t, "name deve ser do tipo string", LocalizedFieldErrors(err, "pt-BR")[0].Message)
+
+	// Syntax errors have no field to report
+	assert.Nil(t, FieldErrors(json.Unmarshal([]byte(`{"name":`), &req)))
+}
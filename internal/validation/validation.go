@@ -0,0 +1,154 @@
+package validation
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"sync"
+	"unicode"
+	"unicode/utf8"
+
+	"arquivolivre.com.br/otel/internal/models"
+
+	"github.com/gin-gonic/gin/binding"
+	"github.com/go-playground/validator/v10"
+	"golang.org/x/text/unicode/norm"
+)
+
+const (
+	// MinNameLength is the minimum number of characters in a user name
+	MinNameLength = 2
+	// MaxNameLength matches the users.name column size
+	MaxNameLength = 100
+	// MaxBioLength is the maximum number of characters in a user bio
+	MaxBioLength = 500
+)
+
+// DefaultDisallowedEmailDomains lists disposable mailbox providers rejected by default
+var DefaultDisallowedEmailDomains = []string{
+	"mailinator.com",
+	"guerrillamail.com",
+	"10minutemail.com",
+	"trashmail.com",
+}
+
+var (
+	registerOnce sync.Once
+	registerErr  error
+
+	domainsMu         sync.RWMutex
+	disallowedDomains = toDomainSet(DefaultDisallowedEmailDomains)
+)
+
+// Register installs the custom validators on Gin's binding engine.
+// It is safe to call multiple times.
+func Register() error {
+	registerOnce.Do(func() {
+		v, ok := binding.Validator.Engine().(*validator.Validate)
+		if !ok {
+			registerErr = fmt.Errorf("unexpected validator engine %T", binding.Validator.Engine())
+			return
+		}
+		registerErr = RegisterOn(v)
+	})
+	return registerErr
+}
+
+// RegisterOn installs the custom validators on v and reports field names
+// using their JSON tags
+func RegisterOn(v *validator.Validate) error {
+	v.RegisterTagNameFunc(func(field reflect.StructField) string {
+		name := strings.SplitN(field.Tag.Get("json"), ",", 2)[0]
+		if name == "-" || name == "" {
+			return field.Name
+		}
+		return name
+	})
+
+	// Validate the value inside Nullable fields; absent and null values are
+	// skipped by omitempty
+	v.RegisterCustomTypeFunc(func(field reflect.Value) interface{} {
+		if value := field.Interface().(models.Nullable[string]).Ptr(); value != nil {
+			return *value
+		}
+		return nil
+	}, models.Nullable[string]{})
+
+	validators := map[string]validator.Func{
+		"personname":           validatePersonName,
+		"biotext":              validateBio,
+		"allowed_email_domain": validateEmailDomain,
+	}
+	for tag, fn := range validators {
+		if err := v.RegisterValidation(tag, fn); err != nil {
+			return fmt.Errorf("failed to register %s validator: %w", tag, err)
+		}
+	}
+	return nil
+}
+
+// SetDisallowedEmailDomains replaces the list of rejected email domains
+func SetDisallowedEmailDomains(domains []string) {
+	domainsMu.Lock()
+	defer domainsMu.Unlock()
+	disallowedDomains = toDomainSet(domains)
+}
+
+// FieldErrors converts a binding error into field-level details with English
+// messages. It returns nil when err is not a validation error (e.g.
+// malformed JSON).
+func FieldErrors(err error) []models.FieldError {
+	return DefaultTranslator.FieldErrors(err, "")
+}
+
+// LocalizedFieldErrors is like FieldErrors but picks the message language
+// from an Accept-Language header value
+func LocalizedFieldErrors(err error, acceptLanguage string) []models.FieldError {
+	return DefaultTranslator.FieldErrors(err, acceptLanguage)
+}
+
+// validatePersonName checks the NFC-normalized, trimmed length and rejects
+// control characters
+func validatePersonName(fl validator.FieldLevel) bool {
+	name := strings.TrimSpace(norm.NFC.String(fl.Field().String()))
+	length := utf8.RuneCountInString(name)
+	if length < MinNameLength || length > MaxNameLength {
+		return false
+	}
+	for _, r := range name {
+		if unicode.IsControl(r) {
+			return false
+		}
+	}
+	return true
+}
+
+// validateBio enforces the maximum bio length in characters, not bytes
+func validateBio(fl validator.FieldLevel) bool {
+	return utf8.RuneCountInString(norm.NFC.String(fl.Field().String())) <= MaxBioLength
+}
+
+// validateEmailDomain rejects addresses whose domain is disallowed
+func validateEmailDomain(fl validator.FieldLevel) bool {
+	email := fl.Field().String()
+	at := strings.LastIndex(email, "@")
+	if at < 0 {
+		return true // format is checked by the email rule
+	}
+	domain := strings.ToLower(strings.TrimSpace(email[at+1:]))
+
+	domainsMu.RLock()
+	defer domainsMu.RUnlock()
+	_, blocked := disallowedDomains[domain]
+	return !blocked
+}
+
+func toDomainSet(domains []string) map[string]struct{} {
+	set := make(map[string]struct{}, len(domains))
+	for _, domain := range domains {
+		if domain = strings.ToLower(strings.TrimSpace(domain)); domain != "" {
+			set[domain] = struct{}{}
+		}
+	}
+	return set
+}
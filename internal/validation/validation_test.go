@@ -0,0 +1,102 @@
+package validation
+
+import (
+	"strings"
+	"testing"
+
+	"arquivolivre.com.br/otel/internal/models"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newValidator(t *testing.T) *validator.Validate {
+	t.Helper()
+	v := validator.New()
+	require.NoError(t, RegisterOn(v))
+	return v
+}
+
+func TestPersonName(t *testing.T) {
+	v := newValidator(t)
+
+	assert.NoError(t, v.Var("Al", "personname"))
+	assert.NoError(t, v.Var("  José  ", "personname"))
+	assert.Error(t, v.Var("A", "personname"))
+	assert.Error(t, v.Var("   A   ", "personname"), "length is measured after trimming")
+	assert.Error(t, v.Var(strings.Repeat("a", MaxNameLength+1), "personname"))
+	assert.Error(t, v.Var("Bad\x00Name", "personname"))
+
+	// "e" + combining acute accent normalizes to a single rune under NFC
+	assert.NoError(t, v.Var("é"+strings.Repeat("a", MaxNameLength-1), "personname"))
+}
+
+func TestBioText(t *testing.T) {
+	v := newValidator(t)
+
+	assert.NoError(t, v.Var("", "biotext"))
+	assert.NoError(t, v.Var(strings.Repeat("é", MaxBioLength), "biotext"), "length is counted in characters")
+	assert.Error(t, v.Var(strings.Repeat("a", MaxBioLength+1), "biotext"))
+}
+
+func TestAllowedEmailDomain(t *testing.T) {
+	v := newValidator(t)
+	t.Cleanup(func() { SetDisallowedEmailDomains(DefaultDisallowedEmailDomains) })
+
+	assert.NoError(t, v.Var("user@example.com", "allowed_email_domain"))
+	assert.Error(t, v.Var("user@Mailinator.COM", "allowed_email_domain"))
+
+	SetDisallowedEmailDomains([]string{" example.com "})
+	assert.Error(t, v.Var("user@example.com", "allowed_email_domain"))
+	assert.NoError(t, v.Var("user@mailinator.com", "allowed_email_domain"))
+}
+
+func TestFieldErrors(t *testing.T) {
+	v := newValidator(t)
+	v.SetTagName("binding") // the request models use Gin's tag name
+
+	bio := strings.Repeat("b", MaxBioLength+1)
+	req := models.CreateUserRequest{Name: "A", Email: "a@mailinator.com", Bio: &bio}
+	details := FieldErrors(v.Struct(req))
+	require.Len(t, details, 3)
+
+	byField := map[string]models.FieldError{}
+	for _, d := range details {
+		byField[d.Field] = d
+	}
+	assert.Equal(t, "personname", byField["name"].Rule)
+	assert.Equal(t, "allowed_email_domain", byField["email"].Rule)
+	assert.Equal(t, "biotext", byField["bio"].Rule)
+	assert.Contains(t, byField["bio"].Message, "at most 500 characters")
+
+	assert.Nil(t, FieldErrors(assert.AnError))
+}
+
+func TestFieldErrors_PostRequest(t *testing.T) {
+	v := newValidator(t)
+	v.SetTagName("binding")
+
+	details := FieldErrors(v.Struct(models.CreatePostRequest{Title: strings.Repeat("t", models.MaxPostTitleLength+1), Body: "b"}))
+	require.Len(t, details, 2)
+
+	byField := map[string]models.FieldError{}
+	for _, d := range details {
+		byField[d.Field] = d
+	}
+	assert.Equal(t, "user_id is required", byField["user_id"].Message)
+	assert.Equal(t, "title must be at most 200 characters", byField["title"].Message)
+}
+
+func TestNullableBioValidation(t *testing.T) {
+	v := newValidator(t)
+	v.SetTagName("binding")
+
+	assert.NoError(t, v.Struct(models.UpdateUserRequest{}))
+	assert.NoError(t, v.Struct(models.UpdateUserRequest{Bio: models.Null[string]()}))
+	assert.NoError(t, v.Struct(models.UpdateUserRequest{Bio: models.NewNullable("short")}))
+
+	details := FieldErrors(v.Struct(models.UpdateUserRequest{Bio: models.NewNullable(strings.Repeat("b", MaxBioLength+1))}))
+	require.Len(t, details, 1)
+	assert.Equal(t, "bio", details[0].Field)
+}
@@ -0,0 +1,243 @@
+package webhooks
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+
+	"arquivolivre.com.br/otel/internal/client"
+	"arquivolivre.com.br/otel/internal/events"
+	"arquivolivre.com.br/otel/internal/jobs"
+	"arquivolivre.com.br/otel/internal/logging"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/metric"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const instrumentationName = "arquivolivre.com.br/otel/internal/webhooks"
+
+// maxBackoff caps the wait between delivery attempts
+const maxBackoff = 30 * time.Second
+
+// Config tunes deliveries
+type Config struct {
+	// Timeout bounds each attempt
+	Timeout time.Duration
+	// MaxAttempts is the number of attempts after which a delivery fails
+	MaxAttempts int
+	// Backoff is the wait before the first retry; it doubles after each
+	// retry, up to 30s
+	Backoff time.Duration
+}
+
+// Dispatcher is an events.Publisher that publishes with next and then
+// delivers the event to every active webhook subscribed to it, one job per
+// webhook
+type Dispatcher struct {
+	next    events.Publisher
+	store   Store
+	jobs    jobs.Enqueuer
+	client  *http.Client
+	cfg     Config
+	tracer  trace.Tracer
+	metrics dispatcherMetrics
+}
+
+var _ events.Publisher = (*Dispatcher)(nil)
+
+type dispatcherMetrics struct {
+	deliveries metric.Int64Counter
+	duration   metric.Float64Histogram
+}
+
+// NewDispatcher creates a dispatcher delivering the events published with
+// next to the webhooks of store on enqueuer
+func NewDispatcher(next events.Publisher, store Store, enqueuer jobs.Enqueuer, cfg Config) (*Dispatcher, error) {
+	if cfg.Timeout <= 0 {
+		cfg.Timeout = 5 * time.Second
+	}
+	if cfg.MaxAttempts < 1 {
+		cfg.MaxAttempts = 5
+	}
+	if cfg.Backoff <= 0 {
+		cfg.Backoff = time.Second
+	}
+
+	m, err := newDispatcherMetrics(otel.Meter(instrumentationName))
+	if err != nil {
+		return nil, err
+	}
+	return &Dispatcher{
+		next:  next,
+		store: store,
+		jobs:  enqueuer,
+		// POSTs are never retried by the client; deliver retries them
+		client:  client.New(client.WithTimeout(cfg.Timeout), client.WithRetries(0, 0)),
+		cfg:     cfg,
+		tracer:  otel.Tracer(instrumentationName),
+		metrics: m,
+	}, nil
+}
+
+func newDispatcherMetrics(meter metric.Meter) (dispatcherMetrics, error) {
+	deliveries, err := meter.Int64Counter(
+		"webhook_deliveries_total",
+		metric.WithDescription("Total number of webhook deliveries by webhook and result"),
+	)
+	if err != nil {
+		return dispatcherMetrics{}, fmt.Errorf("failed to create webhook deliveries metric: %w", err)
+	}
+
+	duration, err := meter.Float64Histogram(
+		"webhook_delivery_attempt_duration_seconds",
+		metric.WithDescription("Webhook delivery attempt duration in seconds"),
+		metric.WithUnit("s"),
+	)
+	if err != nil {
+		return dispatcherMetrics{}, fmt.Errorf("failed to create webhook attempt duration metric: %w", err)
+	}
+
+	return dispatcherMetrics{deliveries: deliveries, duration: duration}, nil
+}
+
+// PublishUserEvent publishes event with next and schedules its webhook
+// deliveries. Webhooks are only notified once next succeeds, so a publish
+// the outbox relay retries does not notify them twice; failures to schedule
+// deliveries are logged rather than returned for the same reason
+func (d *Dispatcher) PublishUserEvent(ctx context.Context, event events.UserEvent) error {
+	if err := d.next.PublishUserEvent(ctx, event); err != nil {
+		return err
+	}
+
+	hooks, err := d.store.List(ctx)
+	if err != nil {
+		logging.Module("webhooks").LogError(ctx, err, "Failed to list webhooks", map[string]interface{}{"event_type": string(event.Type)})
+		return nil
+	}
+	for _, hook := range hooks {
+		if !hook.Active || !hook.Subscribes(event.Type) {
+			continue
+		}
+		err := d.jobs.Enqueue(ctx, "webhook.deliver", func(ctx context.Context) error {
+			return d.deliver(ctx, hook, event)
+		})
+		if err != nil {
+			d.metrics.deliveries.Add(ctx, 1, metric.WithAttributes(
+				attribute.Int("webhook.id", hook.ID),
+				attribute.String("result", "dropped"),
+			))
+			logging.Module("webhooks").LogError(ctx, err, "Failed to schedule webhook delivery", map[string]interface{}{
+				"webhook_id": hook.ID,
+				"event_type": string(event.Type),
+			})
+		}
+	}
+	return nil
+}
+
+// Close closes next
+func (d *Dispatcher) Close() error {
+	return d.next.Close()
+}
+
+// deliver POSTs event to hook until it is accepted, a permanent failure is
+// returned, or MaxAttempts is reached. Every attempt is a client span under
+// the "webhook deliver" span
+func (d *Dispatcher) deliver(ctx context.Context, hook Webhook, event events.UserEvent) error {
+	hookAttr := attribute.Int("webhook.id", hook.ID)
+	ctx, span := d.tracer.Start(ctx, "webhook deliver", trace.WithAttributes(
+		hookAttr,
+		attribute.String("event.type", string(event.Type)),
+		attribute.String("url.full", hook.URL),
+	))
+	defer span.End()
+
+	body, err := json.Marshal(event)
+	if err != nil {
+		return fmt.Errorf("failed to encode webhook payload: %w", err)
+	}
+
+	deliveryID := randomHex(16)
+	backoff := d.cfg.Backoff
+	attempt := 1
+	for ; ; attempt++ {
+		start := time.Now()
+		status, err := d.post(ctx, hook, event.Type, deliveryID, body)
+		result := "success"
+		if err != nil {
+			result = "error"
+		}
+		d.metrics.duration.Record(ctx, time.Since(start).Seconds(), metric.WithAttributes(hookAttr, attribute.String("status", result)))
+		if err == nil {
+			break
+		}
+
+		if attempt >= d.cfg.MaxAttempts || !retryable(status) {
+			span.SetAttributes(attribute.Int("webhook.attempts", attempt))
+			span.RecordError(err)
+			span.SetStatus(codes.Error, "webhook delivery failed")
+			d.metrics.deliveries.Add(ctx, 1, metric.WithAttributes(hookAttr, attribute.String("result", "failed")))
+			return fmt.Errorf("failed to deliver %s to webhook %d after %d attempts: %w", event.Type, hook.ID, attempt, err)
+		}
+
+		span.AddEvent("retry", trace.WithAttributes(
+			attribute.Int("attempt", attempt),
+			attribute.String("error", err.Error()),
+		))
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(backoff):
+		}
+		backoff = min(backoff*2, maxBackoff)
+	}
+
+	span.SetAttributes(attribute.Int("webhook.attempts", attempt))
+	d.metrics.deliveries.Add(ctx, 1, metric.WithAttributes(hookAttr, attribute.String("result", "delivered")))
+	return nil
+}
+
+// post makes one delivery attempt and returns the response status, or 0
+// when no response was received
+func (d *Dispatcher) post(ctx context.Context, hook Webhook, eventType events.EventType, deliveryID string, body []byte) (int, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, hook.URL, bytes.NewReader(body))
+	if err != nil {
+		return 0, fmt.Errorf("failed to create webhook request: %w", err)
+	}
+	timestamp := time.Now().Unix()
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(EventHeader, string(eventType))
+	req.Header.Set(DeliveryHeader, deliveryID)
+	req.Header.Set(TimestampHeader, strconv.FormatInt(timestamp, 10))
+	req.Header.Set(SignatureHeader, Sign(hook.Secret, timestamp, body))
+
+	resp, err := d.client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer func() { _ = resp.Body.Close() }()
+	_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return resp.StatusCode, fmt.Errorf("webhook responded with status %d", resp.StatusCode)
+	}
+	return resp.StatusCode, nil
+}
+
+// retryable reports whether an attempt that got status may succeed later:
+// network errors, timeouts, throttling and server errors are retried, other
+// client errors are not
+func retryable(status int) bool {
+	return status == 0 ||
+		status == http.StatusRequestTimeout ||
+		status == http.StatusTooManyRequests ||
+		status >= http.StatusInternalServerError
+}
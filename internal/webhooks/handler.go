@@ -0,0 +1,118 @@
+package webhooks
+
+import (
+	"strconv"
+
+	"arquivolivre.com.br/otel/internal/middleware"
+	"arquivolivre.com.br/otel/pkg/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Handler manages webhooks over HTTP for administrators
+type Handler struct {
+	store Store
+}
+
+// NewHandler creates a handler for store
+func NewHandler(store Store) *Handler {
+	return &Handler{store: store}
+}
+
+// Register mounts the webhook endpoints on group
+func (h *Handler) Register(group *gin.RouterGroup) {
+	group.GET("", h.ListWebhooks)
+	group.POST("", h.CreateWebhook)
+	group.GET("/:id", h.GetWebhook)
+	group.PUT("/:id", h.UpdateWebhook)
+	group.DELETE("/:id", h.DeleteWebhook)
+}
+
+// createdWebhook is the response to a create; it is the only response that
+// includes the secret
+type createdWebhook struct {
+	*Webhook
+	Secret string `json:"secret"`
+}
+
+// ListWebhooks returns every webhook
+func (h *Handler) ListWebhooks(c *gin.Context) {
+	hooks, err := h.store.List(c.Request.Context())
+	if err != nil {
+		_ = c.Error(middleware.InternalError("Failed to retrieve webhooks", err))
+		return
+	}
+	utils.SendSuccess(c, hooks)
+}
+
+// CreateWebhook registers a webhook and returns it with its secret
+func (h *Handler) CreateWebhook(c *gin.Context) {
+	var req CreateRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		_ = c.Error(middleware.BindError(err, c.GetHeader("Accept-Language")))
+		return
+	}
+
+	hook, err := h.store.Create(c.Request.Context(), req)
+	if err != nil {
+		_ = c.Error(middleware.FromError(err, "Failed to create webhook"))
+		return
+	}
+	utils.SendCreated(c, createdWebhook{Webhook: hook, Secret: hook.Secret}, "Webhook created successfully")
+}
+
+// GetWebhook returns one webhook
+func (h *Handler) GetWebhook(c *gin.Context) {
+	id, ok := webhookID(c)
+	if !ok {
+		return
+	}
+	hook, err := h.store.Get(c.Request.Context(), id)
+	if err != nil {
+		_ = c.Error(middleware.FromError(err, "Failed to retrieve webhook"))
+		return
+	}
+	utils.SendSuccess(c, hook)
+}
+
+// UpdateWebhook changes the fields given in the body
+func (h *Handler) UpdateWebhook(c *gin.Context) {
+	id, ok := webhookID(c)
+	if !ok {
+		return
+	}
+	var req UpdateRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		_ = c.Error(middleware.BindError(err, c.GetHeader("Accept-Language")))
+		return
+	}
+
+	hook, err := h.store.Update(c.Request.Context(), id, req)
+	if err != nil {
+		_ = c.Error(middleware.FromError(err, "Failed to update webhook"))
+		return
+	}
+	utils.SendSuccess(c, hook, "Webhook updated successfully")
+}
+
+// DeleteWebhook removes a webhook
+func (h *Handler) DeleteWebhook(c *gin.Context) {
+	id, ok := webhookID(c)
+	if !ok {
+		return
+	}
+	if err := h.store.Delete(c.Request.Context(), id); err != nil {
+		_ = c.Error(middleware.FromError(err, "Failed to delete webhook"))
+		return
+	}
+	utils.SendNoContent(c)
+}
+
+func webhookID(c *gin.Context) (int, bool) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		_ = c.Error(middleware.BadRequestError("Invalid webhook ID"))
+		return 0, false
+	}
+	return id, true
+}
@@ -0,0 +1,225 @@
+package webhooks
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+
+	"arquivolivre.com.br/otel/internal/database"
+	"arquivolivre.com.br/otel/internal/events"
+	"arquivolivre.com.br/otel/pkg/apperrors"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const webhookColumns = "id, url, secret, events, active, created_at, updated_at"
+
+// MySQLStore keeps webhooks in the webhooks table
+type MySQLStore struct {
+	db     *database.DB
+	tracer trace.Tracer
+}
+
+var _ Store = (*MySQLStore)(nil)
+
+// NewMySQLStore creates a store on db
+func NewMySQLStore(db *database.DB) *MySQLStore {
+	return &MySQLStore{
+		db:     db,
+		tracer: otel.Tracer("webhook-store"),
+	}
+}
+
+// List returns every webhook in ID order
+func (s *MySQLStore) List(ctx context.Context) ([]Webhook, error) {
+	ctx, span := s.start(ctx, "WebhookStore.List", "SELECT")
+	defer span.End()
+	ctx, cancel := s.db.WithQueryTimeout(ctx)
+	defer cancel()
+
+	query := "SELECT " + webhookColumns + " FROM webhooks ORDER BY id"
+	done := s.db.TrackQuery(ctx, "SELECT", "webhooks", query)
+	rows, err := s.db.QueryContext(ctx, query)
+	done(err)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query webhooks: %w", err)
+	}
+	defer func() { _ = rows.Close() }()
+
+	hooks := []Webhook{}
+	for rows.Next() {
+		hook, err := scanWebhook(rows)
+		if err != nil {
+			return nil, err
+		}
+		hooks = append(hooks, *hook)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating over webhooks: %w", err)
+	}
+
+	span.SetAttributes(attribute.Int("result.count", len(hooks)))
+	return hooks, nil
+}
+
+// Get returns the webhook with id
+func (s *MySQLStore) Get(ctx context.Context, id int) (*Webhook, error) {
+	ctx, span := s.start(ctx, "WebhookStore.Get", "SELECT")
+	defer span.End()
+	ctx, cancel := s.db.WithQueryTimeout(ctx)
+	defer cancel()
+	span.SetAttributes(attribute.Int("webhook.id", id))
+
+	query := "SELECT " + webhookColumns + " FROM webhooks WHERE id = ?"
+	done := s.db.TrackQuery(ctx, "SELECT", "webhooks", query)
+	hook, err := scanWebhook(s.db.QueryRowContext(ctx, query, id))
+	done(err)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, apperrors.NotFound("webhook not found")
+	}
+	if err != nil {
+		return nil, err
+	}
+	return hook, nil
+}
+
+// Create registers a webhook, generating its secret when req has none
+func (s *MySQLStore) Create(ctx context.Context, req CreateRequest) (*Webhook, error) {
+	ctx, span := s.start(ctx, "WebhookStore.Create", "INSERT")
+	defer span.End()
+	ctx, cancel := s.db.WithQueryTimeout(ctx)
+	defer cancel()
+
+	secret := req.Secret
+	if secret == "" {
+		secret = randomHex(32)
+	}
+	active := req.Active == nil || *req.Active
+
+	query := "INSERT INTO webhooks (url, secret, events, active) VALUES (?, ?, ?, ?)"
+	done := s.db.TrackQuery(ctx, "INSERT", "webhooks", query)
+	result, err := s.db.ExecContext(ctx, query, req.URL, secret, joinEvents(req.Events), active)
+	done(err)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create webhook: %w", err)
+	}
+	id, err := result.LastInsertId()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get last insert id: %w", err)
+	}
+
+	span.SetAttributes(attribute.Int64("webhook.id", id))
+	return s.Get(ctx, int(id))
+}
+
+// Update changes the fields set in req
+func (s *MySQLStore) Update(ctx context.Context, id int, req UpdateRequest) (*Webhook, error) {
+	ctx, span := s.start(ctx, "WebhookStore.Update", "UPDATE")
+	defer span.End()
+	ctx, cancel := s.db.WithQueryTimeout(ctx)
+	defer cancel()
+	span.SetAttributes(attribute.Int("webhook.id", id))
+
+	existing, err := s.Get(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	setParts := []string{}
+	args := []interface{}{}
+	if req.URL != nil {
+		setParts = append(setParts, "url = ?")
+		args = append(args, *req.URL)
+	}
+	if req.Secret != nil {
+		setParts = append(setParts, "secret = ?")
+		args = append(args, *req.Secret)
+	}
+	if req.Events != nil {
+		setParts = append(setParts, "events = ?")
+		args = append(args, joinEvents(req.Events))
+	}
+	if req.Active != nil {
+		setParts = append(setParts, "active = ?")
+		args = append(args, *req.Active)
+	}
+	if len(setParts) == 0 {
+		return existing, nil
+	}
+
+	query := "UPDATE webhooks SET " + strings.Join(setParts, ", ") + " WHERE id = ?"
+	done := s.db.TrackQuery(ctx, "UPDATE", "webhooks", query)
+	_, err = s.db.ExecContext(ctx, query, append(args, id)...)
+	done(err)
+	if err != nil {
+		return nil, fmt.Errorf("failed to update webhook: %w", err)
+	}
+	return s.Get(ctx, id)
+}
+
+// Delete removes the webhook with id
+func (s *MySQLStore) Delete(ctx context.Context, id int) error {
+	ctx, span := s.start(ctx, "WebhookStore.Delete", "DELETE")
+	defer span.End()
+	ctx, cancel := s.db.WithQueryTimeout(ctx)
+	defer cancel()
+	span.SetAttributes(attribute.Int("webhook.id", id))
+
+	query := "DELETE FROM webhooks WHERE id = ?"
+	done := s.db.TrackQuery(ctx, "DELETE", "webhooks", query)
+	result, err := s.db.ExecContext(ctx, query, id)
+	done(err)
+	if err != nil {
+		return fmt.Errorf("failed to delete webhook: %w", err)
+	}
+	if deleted, err := result.RowsAffected(); err == nil && deleted == 0 {
+		return apperrors.NotFound("webhook not found")
+	}
+	return nil
+}
+
+func (s *MySQLStore) start(ctx context.Context, name, operation string) (context.Context, trace.Span) {
+	return s.tracer.Start(ctx, name, trace.WithAttributes(
+		attribute.String("db.operation", operation),
+		attribute.String("db.table", "webhooks"),
+	))
+}
+
+type scanner interface {
+	Scan(dest ...interface{}) error
+}
+
+// scanWebhook reads a row of webhookColumns
+func scanWebhook(row scanner) (*Webhook, error) {
+	var hook Webhook
+	var eventList string
+	if err := row.Scan(&hook.ID, &hook.URL, &hook.Secret, &eventList, &hook.Active, &hook.CreatedAt, &hook.UpdatedAt); err != nil {
+		return nil, fmt.Errorf("failed to scan webhook: %w", err)
+	}
+	hook.Events = splitEvents(eventList)
+	return &hook, nil
+}
+
+// joinEvents stores an event list as a comma-separated column
+func joinEvents(list []events.EventType) string {
+	parts := make([]string, len(list))
+	for i, eventType := range list {
+		parts[i] = string(eventType)
+	}
+	return strings.Join(parts, ",")
+}
+
+func splitEvents(column string) []events.EventType {
+	list := []events.EventType{}
+	if column == "" {
+		return list
+	}
+	for _, part := range strings.Split(column, ",") {
+		list = append(list, events.EventType(part))
+	}
+	return list
+}
@@ -0,0 +1,93 @@
+// Package webhooks delivers user events to registered HTTP endpoints. Each
+// delivery is a signed JSON POST made by a background job, retried on
+// transient failures and measured per endpoint.
+package webhooks
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"slices"
+	"strconv"
+
+	"arquivolivre.com.br/otel/internal/events"
+	"arquivolivre.com.br/otel/internal/models"
+)
+
+// Headers of a delivery
+const (
+	// EventHeader carries the event type, e.g. user.created
+	EventHeader = "X-Webhook-Event"
+	// DeliveryHeader identifies a delivery; retries of it share the ID so
+	// receivers can ignore duplicates
+	DeliveryHeader = "X-Webhook-Delivery"
+	// TimestampHeader is the Unix time the attempt was signed at
+	TimestampHeader = "X-Webhook-Timestamp"
+	// SignatureHeader carries the signature computed by Sign
+	SignatureHeader = "X-Webhook-Signature"
+)
+
+// Webhook is an endpoint receiving user events. The secret signs deliveries
+// and is only shown when the webhook is created
+type Webhook struct {
+	ID        int                `json:"id"`
+	URL       string             `json:"url"`
+	Events    []events.EventType `json:"events"`
+	Active    bool               `json:"active"`
+	Secret    string             `json:"-"`
+	CreatedAt models.Timestamp   `json:"created_at"`
+	UpdatedAt models.Timestamp   `json:"updated_at"`
+}
+
+// Subscribes reports whether the webhook receives events of eventType; an
+// empty event list subscribes to every event
+func (w *Webhook) Subscribes(eventType events.EventType) bool {
+	return len(w.Events) == 0 || slices.Contains(w.Events, eventType)
+}
+
+// CreateRequest registers a webhook. A random secret is generated when none
+// is given
+type CreateRequest struct {
+	URL    string             `json:"url" binding:"required,url,max=2048"`
+	Secret string             `json:"secret" binding:"omitempty,min=16,max=255"`
+	Events []events.EventType `json:"events" binding:"omitempty,dive,oneof=user.created user.updated user.deleted"`
+	Active *bool              `json:"active"`
+}
+
+// UpdateRequest changes the fields that are set; an empty events list
+// subscribes to every event
+type UpdateRequest struct {
+	URL    *string            `json:"url,omitempty" binding:"omitempty,url,max=2048"`
+	Secret *string            `json:"secret,omitempty" binding:"omitempty,min=16,max=255"`
+	Events []events.EventType `json:"events,omitempty" binding:"omitempty,dive,oneof=user.created user.updated user.deleted"`
+	Active *bool              `json:"active,omitempty"`
+}
+
+// Store persists webhooks
+type Store interface {
+	List(ctx context.Context) ([]Webhook, error)
+	Get(ctx context.Context, id int) (*Webhook, error)
+	Create(ctx context.Context, req CreateRequest) (*Webhook, error)
+	Update(ctx context.Context, id int, req UpdateRequest) (*Webhook, error)
+	Delete(ctx context.Context, id int) error
+}
+
+// Sign returns the SignatureHeader value of body signed at timestamp: the
+// hex HMAC-SHA256 of "<timestamp>.<body>" keyed with secret, prefixed with
+// "sha256=". Receivers recompute it to authenticate a delivery
+func Sign(secret string, timestamp int64, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(strconv.FormatInt(timestamp, 10)))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// randomHex returns n random bytes encoded as hex
+func randomHex(n int) string {
+	b := make([]byte, n)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
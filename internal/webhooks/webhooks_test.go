@@ -0,0 +1,219 @@
+package webhooks
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"arquivolivre.com.br/otel/internal/events"
+	"arquivolivre.com.br/otel/internal/jobs"
+	"arquivolivre.com.br/otel/internal/middleware"
+	"arquivolivre.com.br/otel/pkg/apperrors"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+// memStore keeps webhooks in memory
+type memStore struct {
+	hooks  []Webhook
+	nextID int
+}
+
+func (s *memStore) List(context.Context) ([]Webhook, error) { return s.hooks, nil }
+
+func (s *memStore) Get(_ context.Context, id int) (*Webhook, error) {
+	for i := range s.hooks {
+		if s.hooks[i].ID == id {
+			hook := s.hooks[i]
+			return &hook, nil
+		}
+	}
+	return nil, apperrors.NotFound("webhook not found")
+}
+
+func (s *memStore) Create(ctx context.Context, req CreateRequest) (*Webhook, error) {
+	s.nextID++
+	s.hooks = append(s.hooks, Webhook{ID: s.nextID, URL: req.URL, Secret: "generated-secret", Events: req.Events, Active: true})
+	return s.Get(ctx, s.nextID)
+}
+
+func (s *memStore) Update(ctx context.Context, id int, req UpdateRequest) (*Webhook, error) {
+	for i := range s.hooks {
+		if s.hooks[i].ID == id && req.Active != nil {
+			s.hooks[i].Active = *req.Active
+		}
+	}
+	return s.Get(ctx, id)
+}
+
+func (s *memStore) Delete(context.Context, int) error { return nil }
+
+// fakeJobs keeps enqueued jobs for the test to run
+type fakeJobs struct {
+	fns []jobs.Func
+}
+
+func (j *fakeJobs) Enqueue(_ context.Context, _ string, fn jobs.Func) error {
+	j.fns = append(j.fns, fn)
+	return nil
+}
+
+type failingPublisher struct{}
+
+func (failingPublisher) PublishUserEvent(context.Context, events.UserEvent) error {
+	return errors.New("broker down")
+}
+
+func (failingPublisher) Close() error { return nil }
+
+func withTracing(t *testing.T) *tracetest.SpanRecorder {
+	t.Helper()
+	recorder := tracetest.NewSpanRecorder()
+	prevTP, prevProp := otel.GetTracerProvider(), otel.GetTextMapPropagator()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)))
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+	t.Cleanup(func() {
+		otel.SetTracerProvider(prevTP)
+		otel.SetTextMapPropagator(prevProp)
+	})
+	return recorder
+}
+
+func TestDispatcherDeliversSignedEventsWithRetries(t *testing.T) {
+	recorder := withTracing(t)
+
+	var mu sync.Mutex
+	var requests []*http.Request
+	var bodies [][]byte
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		mu.Lock()
+		requests = append(requests, r)
+		bodies = append(bodies, body)
+		first := len(requests) == 1
+		mu.Unlock()
+		if first {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+	}))
+	defer receiver.Close()
+
+	store := &memStore{hooks: []Webhook{
+		{ID: 1, URL: receiver.URL, Secret: "s3cret", Active: true},
+		{ID: 2, URL: receiver.URL, Secret: "s3cret", Active: true, Events: []events.EventType{events.UserDeleted}},
+		{ID: 3, URL: receiver.URL, Secret: "s3cret", Active: false},
+	}}
+	queue := &fakeJobs{}
+	dispatcher, err := NewDispatcher(events.NoopPublisher{}, store, queue, Config{Backoff: time.Millisecond})
+	require.NoError(t, err)
+
+	event := events.UserEvent{Type: events.UserCreated, UserID: 7, Actor: "ann"}
+	require.NoError(t, dispatcher.PublishUserEvent(context.Background(), event))
+	require.Len(t, queue.fns, 1, "only the active webhook subscribed to user.created")
+	require.NoError(t, queue.fns[0](context.Background()))
+
+	require.Len(t, requests, 2)
+	for i, r := range requests {
+		assert.Equal(t, "user.created", r.Header.Get(EventHeader))
+		timestamp, err := strconv.ParseInt(r.Header.Get(TimestampHeader), 10, 64)
+		require.NoError(t, err)
+		assert.Equal(t, Sign("s3cret", timestamp, bodies[i]), r.Header.Get(SignatureHeader))
+		assert.NotEmpty(t, r.Header.Get("Traceparent"))
+	}
+	assert.Equal(t, requests[0].Header.Get(DeliveryHeader), requests[1].Header.Get(DeliveryHeader))
+
+	var delivered events.UserEvent
+	require.NoError(t, json.Unmarshal(bodies[1], &delivered))
+	assert.Equal(t, event, delivered)
+
+	spans := recorder.Ended()
+	deliverSpan := spans[len(spans)-1]
+	assert.Equal(t, "webhook deliver", deliverSpan.Name())
+	require.Len(t, deliverSpan.Events(), 1)
+	assert.Equal(t, "retry", deliverSpan.Events()[0].Name)
+	clientSpans := 0
+	for _, s := range spans[:len(spans)-1] {
+		if s.Parent().SpanID() == deliverSpan.SpanContext().SpanID() {
+			clientSpans++
+		}
+	}
+	assert.Equal(t, 2, clientSpans, "one client span per attempt")
+}
+
+func TestDispatcherDoesNotRetryClientErrors(t *testing.T) {
+	withTracing(t)
+	attempts := 0
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		attempts++
+		w.WriteHeader(http.StatusGone)
+	}))
+	defer receiver.Close()
+
+	store := &memStore{hooks: []Webhook{{ID: 1, URL: receiver.URL, Secret: "s3cret", Active: true}}}
+	queue := &fakeJobs{}
+	dispatcher, err := NewDispatcher(events.NoopPublisher{}, store, queue, Config{Backoff: time.Millisecond})
+	require.NoError(t, err)
+
+	require.NoError(t, dispatcher.PublishUserEvent(context.Background(), events.UserEvent{Type: events.UserDeleted, UserID: 1}))
+	require.Len(t, queue.fns, 1)
+	err = queue.fns[0](context.Background())
+	assert.ErrorContains(t, err, "after 1 attempts: webhook responded with status 410")
+	assert.Equal(t, 1, attempts)
+}
+
+func TestDispatcherSkipsWebhooksWhenPublishFails(t *testing.T) {
+	store := &memStore{hooks: []Webhook{{ID: 1, URL: "http://127.0.0.1:1", Active: true}}}
+	queue := &fakeJobs{}
+	dispatcher, err := NewDispatcher(failingPublisher{}, store, queue, Config{})
+	require.NoError(t, err)
+
+	err = dispatcher.PublishUserEvent(context.Background(), events.UserEvent{Type: events.UserCreated, UserID: 1})
+	assert.ErrorContains(t, err, "broker down")
+	assert.Empty(t, queue.fns)
+}
+
+func TestHandler(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.Use(middleware.ErrorHandler())
+	NewHandler(&memStore{}).Register(r.Group("/admin/webhooks"))
+
+	do := func(method, path, body string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		r.ServeHTTP(w, req)
+		return w
+	}
+
+	w := do(http.MethodPost, "/admin/webhooks", `{"url":"https://example.com/hook","events":["user.created"]}`)
+	require.Equal(t, http.StatusCreated, w.Code)
+	assert.Contains(t, w.Body.String(), `"secret":"generated-secret"`)
+	assert.Contains(t, w.Body.String(), `"events":["user.created"]`)
+
+	w = do(http.MethodGet, "/admin/webhooks/1", "")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.NotContains(t, w.Body.String(), "secret")
+
+	w = do(http.MethodPut, "/admin/webhooks/1", `{"active":false}`)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), `"active":false`)
+
+	assert.Equal(t, http.StatusNotFound, do(http.MethodGet, "/admin/webhooks/9", "").Code)
+	assert.Equal(t, http.StatusBadRequest, do(http.MethodPost, "/admin/webhooks", `{"url":"not a url"}`).Code)
+	assert.Equal(t, http.StatusBadRequest, do(http.MethodPost, "/admin/webhooks", `{"url":"https://example.com","events":["post.created"]}`).Code)
+}
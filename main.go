@@ -1,20 +1,9 @@
+// Command otel-example is the API CLI, the same binary as cmd/api, so that
+// `go run .` works from a checkout
 package main
 
-import (
-	"fmt"
-	"log"
-	"os"
-	"os/exec"
-)
+import "arquivolivre.com.br/otel/internal/cli"
 
 func main() {
-	fmt.Println("Starting OpenTelemetry Example API...")
-
-	cmd := exec.Command("go", "run", "./cmd/api/main.go")
-	cmd.Stdout = os.Stdout
-	cmd.Stderr = os.Stderr
-
-	if err := cmd.Run(); err != nil {
-		log.Fatalf("Failed to start API server: %v", err)
-	}
+	cli.Execute()
 }
@@ -0,0 +1,123 @@
+// Package apperrors defines domain errors shared by repositories, handlers and
+// transports, and maps them to HTTP and gRPC status codes.
+package apperrors
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+
+	"google.golang.org/grpc/codes"
+)
+
+// Sentinel errors identifying the kind of failure. Match them with errors.Is.
+var (
+	ErrNotFound     = errors.New("not found")
+	ErrConflict     = errors.New("conflict")
+	ErrInvalidInput = errors.New("invalid input")
+	ErrUnauthorized = errors.New("unauthorized")
+	ErrForbidden    = errors.New("forbidden")
+	ErrUnavailable  = errors.New("unavailable")
+)
+
+// Error is a domain error with a client-safe message. It matches its kind
+// and, when set, the underlying cause.
+type Error struct {
+	Kind    error
+	Message string
+	Err     error
+}
+
+func (e *Error) Error() string {
+	if e.Err != nil {
+		return e.Message + ": " + e.Err.Error()
+	}
+	return e.Message
+}
+
+// Unwrap exposes both the kind and the cause to errors.Is and errors.As
+func (e *Error) Unwrap() []error {
+	if e.Err != nil {
+		return []error{e.Kind, e.Err}
+	}
+	return []error{e.Kind}
+}
+
+// New creates an Error of the given kind
+func New(kind error, format string, args ...interface{}) error {
+	return &Error{Kind: kind, Message: fmt.Sprintf(format, args...)}
+}
+
+// Wrap creates an Error of the given kind that keeps cause for errors.Is/As
+func Wrap(kind error, cause error, format string, args ...interface{}) error {
+	return &Error{Kind: kind, Message: fmt.Sprintf(format, args...), Err: cause}
+}
+
+// NotFound creates an ErrNotFound error
+func NotFound(format string, args ...interface{}) error {
+	return New(ErrNotFound, format, args...)
+}
+
+// Conflict creates an ErrConflict error
+func Conflict(format string, args ...interface{}) error {
+	return New(ErrConflict, format, args...)
+}
+
+// InvalidInput creates an ErrInvalidInput error
+func InvalidInput(format string, args ...interface{}) error {
+	return New(ErrInvalidInput, format, args...)
+}
+
+// Message returns the client-safe message of a domain error, or "" for
+// errors that are not domain errors
+func Message(err error) string {
+	var appErr *Error
+	if errors.As(err, &appErr) {
+		return appErr.Message
+	}
+	return ""
+}
+
+// HTTPStatus maps err to an HTTP status code; unknown errors map to 500
+func HTTPStatus(err error) int {
+	switch {
+	case err == nil:
+		return http.StatusOK
+	case errors.Is(err, ErrNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, ErrConflict):
+		return http.StatusConflict
+	case errors.Is(err, ErrInvalidInput):
+		return http.StatusBadRequest
+	case errors.Is(err, ErrUnauthorized):
+		return http.StatusUnauthorized
+	case errors.Is(err, ErrForbidden):
+		return http.StatusForbidden
+	case errors.Is(err, ErrUnavailable):
+		return http.StatusServiceUnavailable
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
+// GRPCCode maps err to a gRPC status code; unknown errors map to Internal
+func GRPCCode(err error) codes.Code {
+	switch {
+	case err == nil:
+		return codes.OK
+	case errors.Is(err, ErrNotFound):
+		return codes.NotFound
+	case errors.Is(err, ErrConflict):
+		return codes.AlreadyExists
+	case errors.Is(err, ErrInvalidInput):
+		return codes.InvalidArgument
+	case errors.Is(err, ErrUnauthorized):
+		return codes.Unauthenticated
+	case errors.Is(err, ErrForbidden):
+		return codes.PermissionDenied
+	case errors.Is(err, ErrUnavailable):
+		return codes.Unavailable
+	default:
+		return codes.Internal
+	}
+}
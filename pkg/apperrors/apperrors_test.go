@@ -0,0 +1,46 @@
+package apperrors
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc/codes"
+)
+
+func TestStatusMapping(t *testing.T) {
+	cases := []struct {
+		err  error
+		http int
+		grpc codes.Code
+	}{
+		{nil, http.StatusOK, codes.OK},
+		{NotFound("user %d not found", 1), http.StatusNotFound, codes.NotFound},
+		{Conflict("email already exists"), http.StatusConflict, codes.AlreadyExists},
+		{InvalidInput("bad"), http.StatusBadRequest, codes.InvalidArgument},
+		{New(ErrUnauthorized, "no token"), http.StatusUnauthorized, codes.Unauthenticated},
+		{New(ErrForbidden, "no access"), http.StatusForbidden, codes.PermissionDenied},
+		{New(ErrUnavailable, "db down"), http.StatusServiceUnavailable, codes.Unavailable},
+		{errors.New("boom"), http.StatusInternalServerError, codes.Internal},
+		// Wrapping with %w keeps the mapping
+		{fmt.Errorf("lookup: %w", NotFound("user not found")), http.StatusNotFound, codes.NotFound},
+	}
+
+	for _, tc := range cases {
+		assert.Equal(t, tc.http, HTTPStatus(tc.err), "%v", tc.err)
+		assert.Equal(t, tc.grpc, GRPCCode(tc.err), "%v", tc.err)
+	}
+}
+
+func TestWrapKeepsCause(t *testing.T) {
+	cause := errors.New("duplicate entry")
+	err := Wrap(ErrConflict, cause, "email already exists")
+
+	assert.True(t, errors.Is(err, ErrConflict))
+	assert.True(t, errors.Is(err, cause))
+	assert.Equal(t, "email already exists: duplicate entry", err.Error())
+	assert.Equal(t, "email already exists", Message(err))
+	assert.Equal(t, "", Message(cause))
+}
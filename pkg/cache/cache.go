@@ -0,0 +1,63 @@
+// Package cache provides a small key/value cache interface with in-memory and
+// Redis backends, and an instrumented wrapper that records hits, misses,
+// latency and size as OpenTelemetry metrics and span attributes.
+package cache
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"time"
+)
+
+// ErrClosed is returned by operations on a closed cache
+var ErrClosed = errors.New("cache is closed")
+
+// Cache stores byte values under string keys. A ttl of zero means the
+// backend's default expiration.
+type Cache interface {
+	Get(ctx context.Context, key string) (value []byte, found bool, err error)
+	Set(ctx context.Context, key string, value []byte, ttl time.Duration) error
+	// SetNX stores value under key only if the key holds no value, as one
+	// atomic step, and reports whether it did
+	SetNX(ctx context.Context, key string, value []byte, ttl time.Duration) (stored bool, err error)
+	Delete(ctx context.Context, keys ...string) error
+	Close() error
+}
+
+// Sizer is implemented by backends that can report their number of entries
+type Sizer interface {
+	Len(ctx context.Context) (int64, error)
+}
+
+// GetJSON reads key and decodes it into a T
+func GetJSON[T any](ctx context.Context, c Cache, key string) (T, bool, error) {
+	var value T
+	data, found, err := c.Get(ctx, key)
+	if err != nil || !found {
+		return value, false, err
+	}
+	if err := json.Unmarshal(data, &value); err != nil {
+		return value, false, err
+	}
+	return value, true, nil
+}
+
+// SetJSON encodes value as JSON and stores it under key
+func SetJSON[T any](ctx context.Context, c Cache, key string, value T, ttl time.Duration) error {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return err
+	}
+	return c.Set(ctx, key, data, ttl)
+}
+
+// SetNXJSON encodes value as JSON and stores it under key unless the key
+// already holds a value
+func SetNXJSON[T any](ctx context.Context, c Cache, key string, value T, ttl time.Duration) (bool, error) {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return false, err
+	}
+	return c.SetNX(ctx, key, data, ttl)
+}
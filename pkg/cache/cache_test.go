@@ -0,0 +1,178 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/metric/metricdata"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+// testBackends runs fn against every backend
+func testBackends(t *testing.T, fn func(t *testing.T, c Cache)) {
+	t.Run("memory", func(t *testing.T) {
+		fn(t, NewMemory(MemoryOptions{}))
+	})
+	t.Run("redis", func(t *testing.T) {
+		mr := miniredis.RunT(t)
+		fn(t, NewRedis(redis.NewClient(&redis.Options{Addr: mr.Addr()}), "test:", 0))
+	})
+}
+
+func TestCacheContract(t *testing.T) {
+	testBackends(t, func(t *testing.T, c Cache) {
+		ctx := context.Background()
+
+		_, found, err := c.Get(ctx, "missing")
+		require.NoError(t, err)
+		assert.False(t, found)
+
+		require.NoError(t, c.Set(ctx, "a", []byte("1"), 0))
+		value, found, err := c.Get(ctx, "a")
+		require.NoError(t, err)
+		assert.True(t, found)
+		assert.Equal(t, []byte("1"), value)
+
+		require.NoError(t, c.Delete(ctx, "a", "missing"))
+		_, found, _ = c.Get(ctx, "a")
+		assert.False(t, found)
+
+		require.NoError(t, SetJSON(ctx, c, "user", map[string]int{"id": 7}, time.Minute))
+		decoded, found, err := GetJSON[map[string]int](ctx, c, "user")
+		require.NoError(t, err)
+		assert.True(t, found)
+		assert.Equal(t, 7, decoded["id"])
+
+		stored, err := c.SetNX(ctx, "lock", []byte("first"), time.Minute)
+		require.NoError(t, err)
+		assert.True(t, stored)
+		stored, err = c.SetNX(ctx, "lock", []byte("second"), time.Minute)
+		require.NoError(t, err)
+		assert.False(t, stored, "an existing value is kept")
+		value, _, _ = c.Get(ctx, "lock")
+		assert.Equal(t, []byte("first"), value)
+
+		require.NoError(t, c.Close())
+	})
+}
+
+func TestMemoryExpirationAndEviction(t *testing.T) {
+	ctx := context.Background()
+	now := time.Now()
+	m := NewMemory(MemoryOptions{MaxEntries: 2, DefaultTTL: time.Minute})
+	m.now = func() time.Time { return now }
+
+	require.NoError(t, m.Set(ctx, "a", []byte("a"), 0))
+	require.NoError(t, m.Set(ctx, "b", []byte("b"), time.Hour))
+	_, _, _ = m.Get(ctx, "a") // a is now most recently used
+	require.NoError(t, m.Set(ctx, "c", []byte("c"), 0))
+
+	_, found, _ := m.Get(ctx, "b")
+	assert.False(t, found, "least recently used entry is evicted")
+	n, _ := m.Len(ctx)
+	assert.Equal(t, int64(2), n)
+
+	now = now.Add(2 * time.Minute)
+	_, found, _ = m.Get(ctx, "a")
+	assert.False(t, found, "entry expires after the default ttl")
+	stored, err := m.SetNX(ctx, "c", []byte("c2"), 0)
+	require.NoError(t, err)
+	assert.True(t, stored, "an expired entry does not block SetNX")
+
+	require.NoError(t, m.Close())
+	_, _, err = m.Get(ctx, "c")
+	assert.ErrorIs(t, err, ErrClosed)
+}
+
+func TestRedisExpiration(t *testing.T) {
+	ctx := context.Background()
+	mr := miniredis.RunT(t)
+	r := NewRedis(redis.NewClient(&redis.Options{Addr: mr.Addr()}), "test:", time.Minute)
+
+	require.NoError(t, r.Set(ctx, "a", []byte("a"), 0))
+	assert.True(t, mr.Exists("test:a"))
+	mr.FastForward(2 * time.Minute)
+	_, found, err := r.Get(ctx, "a")
+	require.NoError(t, err)
+	assert.False(t, found)
+
+	mr.Close()
+	_, _, err = r.Get(ctx, "a")
+	assert.Error(t, err)
+}
+
+type failingCache struct{ Cache }
+
+func (failingCache) Get(context.Context, string) ([]byte, bool, error) {
+	return nil, false, errors.New("backend down")
+}
+
+func TestInstrumentedRecordsTelemetry(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	tp := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+	reader := sdkmetric.NewManualReader()
+	mp := sdkmetric.NewMeterProvider(sdkmetric.WithReader(reader))
+	prevTP, prevMP := otel.GetTracerProvider(), otel.GetMeterProvider()
+	otel.SetTracerProvider(tp)
+	otel.SetMeterProvider(mp)
+	defer func() {
+		otel.SetTracerProvider(prevTP)
+		otel.SetMeterProvider(prevMP)
+	}()
+
+	ctx := context.Background()
+	c, err := Instrument("users", NewMemory(MemoryOptions{}))
+	require.NoError(t, err)
+
+	require.NoError(t, c.Set(ctx, "a", []byte("1"), 0))
+	_, _, _ = c.Get(ctx, "a")
+	_, _, _ = c.Get(ctx, "b")
+	require.NoError(t, c.Delete(ctx, "a"))
+
+	spans := recorder.Ended()
+	require.Len(t, spans, 4)
+	assert.Equal(t, "cache.get", spans[1].Name())
+	assert.Contains(t, spans[1].Attributes(), hitAttr(true))
+	assert.Contains(t, spans[2].Attributes(), hitAttr(false))
+
+	var rm metricdata.ResourceMetrics
+	require.NoError(t, reader.Collect(ctx, &rm))
+	got := map[string]int64{}
+	for _, sm := range rm.ScopeMetrics {
+		for _, m := range sm.Metrics {
+			switch data := m.Data.(type) {
+			case metricdata.Sum[int64]:
+				got[m.Name] = data.DataPoints[0].Value
+			case metricdata.Gauge[int64]:
+				got[m.Name] = data.DataPoints[0].Value
+			}
+		}
+	}
+	assert.Equal(t, int64(1), got["cache_hits_total"])
+	assert.Equal(t, int64(1), got["cache_misses_total"])
+	assert.Equal(t, int64(0), got["cache_size"])
+
+	failing, err := Instrument("failing", failingCache{NewMemory(MemoryOptions{})})
+	require.NoError(t, err)
+	_, _, err = failing.Get(ctx, "a")
+	assert.Error(t, err)
+	_, err = failing.Len(ctx)
+	assert.Error(t, err, "wrapped cache without Sizer")
+	assert.Equal(t, "backend down", recorder.Ended()[4].Status().Description)
+
+	require.NoError(t, c.Close())
+}
+
+func hitAttr(hit bool) attribute.KeyValue {
+	return attribute.Bool("cache.hit", hit)
+}
@@ -0,0 +1,165 @@
+package cache
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/metric"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const instrumentationName = "arquivolivre.com.br/otel/pkg/cache"
+
+// Instrumented wraps a Cache with spans and metrics
+type Instrumented struct {
+	next         Cache
+	name         attribute.KeyValue
+	tracer       trace.Tracer
+	hits         metric.Int64Counter
+	misses       metric.Int64Counter
+	duration     metric.Float64Histogram
+	registration metric.Registration
+}
+
+// Instrument wraps next; name identifies the cache in telemetry (for example
+// "users" or "responses"). When next implements Sizer its size is exported
+// as the cache_size gauge.
+func Instrument(name string, next Cache) (*Instrumented, error) {
+	meter := otel.Meter(instrumentationName)
+	c := &Instrumented{
+		next:   next,
+		name:   attribute.String("cache.name", name),
+		tracer: otel.Tracer(instrumentationName),
+	}
+
+	var err error
+	if c.hits, err = meter.Int64Counter("cache_hits_total",
+		metric.WithDescription("Total number of cache hits")); err != nil {
+		return nil, fmt.Errorf("failed to create cache hits metric: %w", err)
+	}
+	if c.misses, err = meter.Int64Counter("cache_misses_total",
+		metric.WithDescription("Total number of cache misses")); err != nil {
+		return nil, fmt.Errorf("failed to create cache misses metric: %w", err)
+	}
+	if c.duration, err = meter.Float64Histogram("cache_operation_duration_seconds",
+		metric.WithDescription("Cache operation duration in seconds"),
+		metric.WithUnit("s")); err != nil {
+		return nil, fmt.Errorf("failed to create cache duration metric: %w", err)
+	}
+
+	if sizer, ok := next.(Sizer); ok {
+		size, err := meter.Int64ObservableGauge("cache_size",
+			metric.WithDescription("Number of entries in the cache"))
+		if err != nil {
+			return nil, fmt.Errorf("failed to create cache size metric: %w", err)
+		}
+		c.registration, err = meter.RegisterCallback(func(ctx context.Context, o metric.Observer) error {
+			n, err := sizer.Len(ctx)
+			if err != nil {
+				return err
+			}
+			o.ObserveInt64(size, n, metric.WithAttributes(c.name))
+			return nil
+		}, size)
+		if err != nil {
+			return nil, fmt.Errorf("failed to register cache size callback: %w", err)
+		}
+	}
+
+	return c, nil
+}
+
+func (c *Instrumented) Get(ctx context.Context, key string) ([]byte, bool, error) {
+	ctx, span := c.start(ctx, "get")
+	defer span.End()
+
+	start := time.Now()
+	value, found, err := c.next.Get(ctx, key)
+	c.finish(ctx, span, "get", start, err)
+	if err == nil {
+		span.SetAttributes(attribute.Bool("cache.hit", found))
+		if found {
+			c.hits.Add(ctx, 1, metric.WithAttributes(c.name))
+		} else {
+			c.misses.Add(ctx, 1, metric.WithAttributes(c.name))
+		}
+	}
+	return value, found, err
+}
+
+func (c *Instrumented) Set(ctx context.Context, key string, value []byte, ttl time.Duration) error {
+	ctx, span := c.start(ctx, "set")
+	defer span.End()
+	span.SetAttributes(attribute.Int("cache.value_size", len(value)))
+
+	start := time.Now()
+	err := c.next.Set(ctx, key, value, ttl)
+	c.finish(ctx, span, "set", start, err)
+	return err
+}
+
+func (c *Instrumented) SetNX(ctx context.Context, key string, value []byte, ttl time.Duration) (bool, error) {
+	ctx, span := c.start(ctx, "setnx")
+	defer span.End()
+	span.SetAttributes(attribute.Int("cache.value_size", len(value)))
+
+	start := time.Now()
+	stored, err := c.next.SetNX(ctx, key, value, ttl)
+	c.finish(ctx, span, "setnx", start, err)
+	if err == nil {
+		span.SetAttributes(attribute.Bool("cache.stored", stored))
+	}
+	return stored, err
+}
+
+func (c *Instrumented) Delete(ctx context.Context, keys ...string) error {
+	ctx, span := c.start(ctx, "delete")
+	defer span.End()
+	span.SetAttributes(attribute.Int("cache.keys", len(keys)))
+
+	start := time.Now()
+	err := c.next.Delete(ctx, keys...)
+	c.finish(ctx, span, "delete", start, err)
+	return err
+}
+
+// Len delegates to the wrapped cache when it implements Sizer
+func (c *Instrumented) Len(ctx context.Context) (int64, error) {
+	if sizer, ok := c.next.(Sizer); ok {
+		return sizer.Len(ctx)
+	}
+	return 0, fmt.Errorf("cache does not report its size")
+}
+
+// Close unregisters the size callback and closes the wrapped cache
+func (c *Instrumented) Close() error {
+	if c.registration != nil {
+		_ = c.registration.Unregister()
+	}
+	return c.next.Close()
+}
+
+func (c *Instrumented) start(ctx context.Context, op string) (context.Context, trace.Span) {
+	return c.tracer.Start(ctx, "cache."+op,
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(c.name, attribute.String("cache.operation", op)),
+	)
+}
+
+func (c *Instrumented) finish(ctx context.Context, span trace.Span, op string, start time.Time, err error) {
+	status := "success"
+	if err != nil {
+		status = "error"
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	c.duration.Record(ctx, time.Since(start).Seconds(), metric.WithAttributes(
+		c.name,
+		attribute.String("cache.operation", op),
+		attribute.String("status", status),
+	))
+}
@@ -0,0 +1,154 @@
+package cache
+
+import (
+	"container/list"
+	"context"
+	"sync"
+	"time"
+)
+
+// MemoryOptions configures a Memory cache
+type MemoryOptions struct {
+	// MaxEntries bounds the cache; the least recently used entry is evicted
+	// when it is exceeded. Zero means unbounded.
+	MaxEntries int
+	// DefaultTTL applies when Set is called with a zero ttl. Zero means
+	// entries never expire.
+	DefaultTTL time.Duration
+}
+
+type memoryEntry struct {
+	key       string
+	value     []byte
+	expiresAt time.Time
+}
+
+// Memory is an in-process LRU cache with per-entry expiration
+type Memory struct {
+	opts MemoryOptions
+	now  func() time.Time
+
+	mu      sync.Mutex
+	entries map[string]*list.Element
+	lru     *list.List
+	closed  bool
+}
+
+// NewMemory creates an in-memory cache
+func NewMemory(opts MemoryOptions) *Memory {
+	return &Memory{
+		opts:    opts,
+		now:     time.Now,
+		entries: make(map[string]*list.Element),
+		lru:     list.New(),
+	}
+}
+
+func (m *Memory) Get(_ context.Context, key string) ([]byte, bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.closed {
+		return nil, false, ErrClosed
+	}
+
+	elem, ok := m.entries[key]
+	if !ok {
+		return nil, false, nil
+	}
+	entry := elem.Value.(*memoryEntry)
+	if !entry.expiresAt.IsZero() && !m.now().Before(entry.expiresAt) {
+		m.remove(elem)
+		return nil, false, nil
+	}
+	m.lru.MoveToFront(elem)
+	return entry.value, true, nil
+}
+
+func (m *Memory) Set(_ context.Context, key string, value []byte, ttl time.Duration) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.closed {
+		return ErrClosed
+	}
+	m.set(key, value, ttl)
+	return nil
+}
+
+// SetNX checks for an unexpired entry and stores value under the same lock
+func (m *Memory) SetNX(_ context.Context, key string, value []byte, ttl time.Duration) (bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.closed {
+		return false, ErrClosed
+	}
+
+	if elem, ok := m.entries[key]; ok {
+		entry := elem.Value.(*memoryEntry)
+		if entry.expiresAt.IsZero() || m.now().Before(entry.expiresAt) {
+			return false, nil
+		}
+	}
+	m.set(key, value, ttl)
+	return true, nil
+}
+
+// set stores value under key; m.mu must be held
+func (m *Memory) set(key string, value []byte, ttl time.Duration) {
+	if ttl == 0 {
+		ttl = m.opts.DefaultTTL
+	}
+	var expiresAt time.Time
+	if ttl > 0 {
+		expiresAt = m.now().Add(ttl)
+	}
+
+	if elem, ok := m.entries[key]; ok {
+		entry := elem.Value.(*memoryEntry)
+		entry.value = value
+		entry.expiresAt = expiresAt
+		m.lru.MoveToFront(elem)
+		return
+	}
+
+	m.entries[key] = m.lru.PushFront(&memoryEntry{key: key, value: value, expiresAt: expiresAt})
+	if m.opts.MaxEntries > 0 && m.lru.Len() > m.opts.MaxEntries {
+		m.remove(m.lru.Back())
+	}
+}
+
+func (m *Memory) Delete(_ context.Context, keys ...string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.closed {
+		return ErrClosed
+	}
+
+	for _, key := range keys {
+		if elem, ok := m.entries[key]; ok {
+			m.remove(elem)
+		}
+	}
+	return nil
+}
+
+// Len reports the number of entries, including expired entries that have
+// not been read since they expired
+func (m *Memory) Len(context.Context) (int64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return int64(m.lru.Len()), nil
+}
+
+func (m *Memory) Close() error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.closed = true
+	m.entries = nil
+	m.lru.Init()
+	return nil
+}
+
+func (m *Memory) remove(elem *list.Element) {
+	m.lru.Remove(elem)
+	delete(m.entries, elem.Value.(*memoryEntry).key)
+}
@@ -0,0 +1,69 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// Redis stores entries in Redis under a key prefix
+type Redis struct {
+	client     redis.UniversalClient
+	prefix     string
+	defaultTTL time.Duration
+}
+
+// NewRedis creates a cache on client. Keys are stored as prefix+key and a zero
+// ttl in Set falls back to defaultTTL (zero means no expiration).
+func NewRedis(client redis.UniversalClient, prefix string, defaultTTL time.Duration) *Redis {
+	return &Redis{client: client, prefix: prefix, defaultTTL: defaultTTL}
+}
+
+func (r *Redis) Get(ctx context.Context, key string) ([]byte, bool, error) {
+	value, err := r.client.Get(ctx, r.prefix+key).Bytes()
+	if errors.Is(err, redis.Nil) {
+		return nil, false, nil
+	}
+	if err != nil {
+		return nil, false, err
+	}
+	return value, true, nil
+}
+
+func (r *Redis) Set(ctx context.Context, key string, value []byte, ttl time.Duration) error {
+	if ttl == 0 {
+		ttl = r.defaultTTL
+	}
+	return r.client.Set(ctx, r.prefix+key, value, ttl).Err()
+}
+
+// SetNX is SET with NX and PX, so the check and the write are one command
+func (r *Redis) SetNX(ctx context.Context, key string, value []byte, ttl time.Duration) (bool, error) {
+	if ttl == 0 {
+		ttl = r.defaultTTL
+	}
+	return r.client.SetNX(ctx, r.prefix+key, value, ttl).Result()
+}
+
+func (r *Redis) Delete(ctx context.Context, keys ...string) error {
+	if len(keys) == 0 {
+		return nil
+	}
+	prefixed := make([]string, len(keys))
+	for i, key := range keys {
+		prefixed[i] = r.prefix + key
+	}
+	return r.client.Del(ctx, prefixed...).Err()
+}
+
+// Len reports the size of the Redis database, which includes keys outside
+// the prefix when the database is shared
+func (r *Redis) Len(ctx context.Context) (int64, error) {
+	return r.client.DBSize(ctx).Result()
+}
+
+func (r *Redis) Close() error {
+	return r.client.Close()
+}
@@ -0,0 +1,201 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"arquivolivre.com.br/otel/pkg/apperrors"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+)
+
+func newTestClient(t *testing.T, handler http.HandlerFunc) *Client {
+	t.Helper()
+	server := httptest.NewServer(handler)
+	t.Cleanup(server.Close)
+
+	c, err := New(server.URL, WithRetries(2, time.Millisecond))
+	require.NoError(t, err)
+	return c
+}
+
+func writeJSON(w http.ResponseWriter, status int, body interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(body)
+}
+
+func TestNew_InvalidBaseURL(t *testing.T) {
+	_, err := New("localhost:8080")
+	assert.Error(t, err)
+}
+
+func TestGetUser(t *testing.T) {
+	c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/api/users/7", r.URL.Path)
+		writeJSON(w, http.StatusOK, map[string]interface{}{
+			"success": true,
+			"data":    map[string]interface{}{"id": 7, "name": "Ann", "created_at": "2024-01-02T03:04:05.000Z"},
+		})
+	})
+
+	user, err := c.GetUser(context.Background(), 7)
+	require.NoError(t, err)
+	assert.Equal(t, 7, user.ID)
+	assert.Equal(t, "Ann", user.Name)
+	assert.Equal(t, time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC), user.CreatedAt)
+}
+
+func TestSendsCredentials(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "Bearer secret-token", r.Header.Get("Authorization"))
+		assert.Equal(t, "k1", r.Header.Get("X-API-Key"))
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	t.Cleanup(server.Close)
+
+	c, err := New(server.URL, WithBearerToken("secret-token"), WithAPIKey("k1"))
+	require.NoError(t, err)
+	require.NoError(t, c.DeleteUser(context.Background(), 7))
+}
+
+func TestAPIError(t *testing.T) {
+	c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, http.StatusNotFound, map[string]interface{}{
+			"success": false, "error": "user not found", "code": "NOT_FOUND", "trace_id": "abc",
+		})
+	})
+
+	_, err := c.GetUser(context.Background(), 1)
+	require.Error(t, err)
+	assert.True(t, errors.Is(err, apperrors.ErrNotFound))
+
+	var apiErr *APIError
+	require.True(t, errors.As(err, &apiErr))
+	assert.Equal(t, "NOT_FOUND", apiErr.Code)
+	assert.Equal(t, "abc", apiErr.TraceID)
+	assert.Contains(t, err.Error(), "trace_id=abc")
+}
+
+func TestRetriesIdempotentRequests(t *testing.T) {
+	var calls atomic.Int32
+	c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		if calls.Add(1) < 3 {
+			writeJSON(w, http.StatusServiceUnavailable, map[string]interface{}{"error": "busy"})
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	})
+
+	require.NoError(t, c.DeleteUser(context.Background(), 1))
+	assert.Equal(t, int32(3), calls.Load())
+}
+
+func TestDoesNotRetryCreate(t *testing.T) {
+	var calls atomic.Int32
+	c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		writeJSON(w, http.StatusServiceUnavailable, map[string]interface{}{"error": "busy"})
+	})
+
+	_, err := c.CreateUser(context.Background(), CreateUserRequest{Name: "Ann", Email: "ann@example.com"})
+	assert.True(t, errors.Is(err, apperrors.ErrUnavailable))
+	assert.Equal(t, int32(1), calls.Load())
+}
+
+func TestDoesNotRetryClientErrors(t *testing.T) {
+	var calls atomic.Int32
+	c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		writeJSON(w, http.StatusBadRequest, map[string]interface{}{
+			"error": "Validation failed", "details": []map[string]string{{"field": "email", "rule": "email"}},
+		})
+	})
+
+	email := "nope"
+	_, err := c.UpdateUser(context.Background(), 1, UpdateUserRequest{Email: &email})
+	var apiErr *APIError
+	require.True(t, errors.As(err, &apiErr))
+	assert.Equal(t, "email", apiErr.Details[0].Field)
+	assert.Equal(t, int32(1), calls.Load())
+}
+
+func TestAllUsersIteratesPages(t *testing.T) {
+	const total = 5
+	c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		page, _ := strconv.Atoi(r.URL.Query().Get("page"))
+		limit, _ := strconv.Atoi(r.URL.Query().Get("limit"))
+
+		var users []map[string]interface{}
+		for id := (page-1)*limit + 1; id <= min(page*limit, total); id++ {
+			users = append(users, map[string]interface{}{"id": id})
+		}
+		writeJSON(w, http.StatusOK, map[string]interface{}{
+			"success":    true,
+			"data":       users,
+			"pagination": map[string]int{"page": page, "limit": limit, "total": total, "total_pages": (total + limit - 1) / limit},
+		})
+	})
+
+	var ids []int
+	for user, err := range c.AllUsers(context.Background(), 2) {
+		require.NoError(t, err)
+		ids = append(ids, user.ID)
+	}
+	assert.Equal(t, []int{1, 2, 3, 4, 5}, ids)
+
+	// Breaking out of the loop stops fetching
+	ids = nil
+	for user := range c.AllUsers(context.Background(), 2) {
+		ids = append(ids, user.ID)
+		break
+	}
+	assert.Equal(t, []int{1}, ids)
+}
+
+func TestPropagatesTraceContext(t *testing.T) {
+	tp := sdktrace.NewTracerProvider(sdktrace.WithSampler(sdktrace.AlwaysSample()))
+	defer func() { _ = tp.Shutdown(context.Background()) }()
+	prevTP, prevProp := otel.GetTracerProvider(), otel.GetTextMapPropagator()
+	otel.SetTracerProvider(tp)
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+	defer func() {
+		otel.SetTracerProvider(prevTP)
+		otel.SetTextMapPropagator(prevProp)
+	}()
+
+	var traceparent string
+	c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		traceparent = r.Header.Get("traceparent")
+		writeJSON(w, http.StatusOK, map[string]interface{}{"success": true})
+	})
+
+	ctx, span := tp.Tracer("test").Start(context.Background(), "caller")
+	require.NoError(t, c.Health(ctx))
+	span.End()
+
+	require.NotEmpty(t, traceparent)
+	assert.Contains(t, traceparent, span.SpanContext().TraceID().String())
+}
+
+func TestUpdateUserRequest_ClearBio(t *testing.T) {
+	name := "Ann"
+	body, err := json.Marshal(UpdateUserRequest{Name: &name})
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"name":"Ann"}`, string(body))
+
+	body, err = json.Marshal(UpdateUserRequest{ClearBio: true})
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"bio":null}`, string(body))
+}
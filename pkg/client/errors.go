@@ -0,0 +1,72 @@
+package client
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+
+	"arquivolivre.com.br/otel/pkg/apperrors"
+)
+
+// FieldError describes a single field that failed validation
+type FieldError struct {
+	Field   string `json:"field"`
+	Rule    string `json:"rule"`
+	Message string `json:"message"`
+}
+
+// APIError is returned for non-2xx responses. It matches the apperrors
+// sentinel for its status, e.g. errors.Is(err, apperrors.ErrNotFound).
+type APIError struct {
+	StatusCode       int          `json:"-"`
+	Message          string       `json:"error"`
+	Code             string       `json:"code"`
+	Details          []FieldError `json:"details"`
+	TraceID          string       `json:"trace_id"`
+	RequestID        string       `json:"request_id"`
+	DocumentationURL string       `json:"documentation_url"`
+}
+
+func (e *APIError) Error() string {
+	msg := fmt.Sprintf("api error %d", e.StatusCode)
+	if e.Code != "" {
+		msg += " " + e.Code
+	}
+	if e.Message != "" {
+		msg += ": " + e.Message
+	}
+	if e.TraceID != "" {
+		msg += " (trace_id=" + e.TraceID + ")"
+	}
+	return msg
+}
+
+// Unwrap maps the status code to an apperrors sentinel
+func (e *APIError) Unwrap() error {
+	switch e.StatusCode {
+	case http.StatusBadRequest, http.StatusUnprocessableEntity:
+		return apperrors.ErrInvalidInput
+	case http.StatusUnauthorized:
+		return apperrors.ErrUnauthorized
+	case http.StatusForbidden:
+		return apperrors.ErrForbidden
+	case http.StatusNotFound:
+		return apperrors.ErrNotFound
+	case http.StatusConflict:
+		return apperrors.ErrConflict
+	case http.StatusServiceUnavailable:
+		return apperrors.ErrUnavailable
+	default:
+		return nil
+	}
+}
+
+func decodeAPIError(resp *http.Response) error {
+	apiErr := &APIError{StatusCode: resp.StatusCode}
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err := json.Unmarshal(body, apiErr); err != nil || apiErr.Message == "" {
+		apiErr.Message = http.StatusText(resp.StatusCode)
+	}
+	return apiErr
+}
@@ -0,0 +1,177 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"iter"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+)
+
+// User is a user returned by the API
+type User struct {
+	ID        int       `json:"id"`
+	Name      string    `json:"name"`
+	Email     string    `json:"email"`
+	Bio       *string   `json:"bio,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+	Version   int       `json:"version"`
+}
+
+// CreateUserRequest is the payload for CreateUser
+type CreateUserRequest struct {
+	Name  string  `json:"name"`
+	Email string  `json:"email"`
+	Bio   *string `json:"bio,omitempty"`
+}
+
+// UpdateUserRequest is the payload for UpdateUser; nil fields are left
+// unchanged and ClearBio removes the bio. Version is the User.Version the
+// update is based on; the API rejects it with a 409 when the user has changed
+// since
+type UpdateUserRequest struct {
+	Name     *string `json:"name,omitempty"`
+	Email    *string `json:"email,omitempty"`
+	Bio      *string `json:"bio,omitempty"`
+	Version  int     `json:"version,omitempty"`
+	ClearBio bool    `json:"-"`
+}
+
+// MarshalJSON sends "bio": null when ClearBio is set
+func (r UpdateUserRequest) MarshalJSON() ([]byte, error) {
+	type plain UpdateUserRequest
+	if !r.ClearBio {
+		return json.Marshal(plain(r))
+	}
+	return json.Marshal(struct {
+		plain
+		Bio *string `json:"bio"`
+	}{plain: plain(r)})
+}
+
+// ListOptions selects a page of results
+type ListOptions struct {
+	Page  int
+	Limit int
+}
+
+// Pagination is the pagination metadata of a page
+type Pagination struct {
+	Page       int `json:"page"`
+	Limit      int `json:"limit"`
+	Total      int `json:"total"`
+	TotalPages int `json:"total_pages"`
+}
+
+// UserPage is one page of users
+type UserPage struct {
+	Users      []User     `json:"data"`
+	Pagination Pagination `json:"pagination"`
+}
+
+type dataEnvelope[T any] struct {
+	Data T `json:"data"`
+}
+
+// ListUsers returns one page of users
+func (c *Client) ListUsers(ctx context.Context, opts ListOptions) (page *UserPage, err error) {
+	ctx, span := c.startSpan(ctx, "ListUsers",
+		attribute.Int("pagination.page", opts.Page),
+		attribute.Int("pagination.limit", opts.Limit),
+	)
+	defer func() { endSpan(span, err) }()
+
+	query := url.Values{}
+	if opts.Page > 0 {
+		query.Set("page", strconv.Itoa(opts.Page))
+	}
+	if opts.Limit > 0 {
+		query.Set("limit", strconv.Itoa(opts.Limit))
+	}
+
+	page = &UserPage{}
+	if err = c.do(ctx, http.MethodGet, "/api/users", query, nil, page); err != nil {
+		return nil, err
+	}
+	return page, nil
+}
+
+// AllUsers iterates over every user, fetching pages of pageSize lazily. It
+// stops after yielding the first error.
+func (c *Client) AllUsers(ctx context.Context, pageSize int) iter.Seq2[User, error] {
+	return func(yield func(User, error) bool) {
+		for pageNum := 1; ; pageNum++ {
+			page, err := c.ListUsers(ctx, ListOptions{Page: pageNum, Limit: pageSize})
+			if err != nil {
+				yield(User{}, err)
+				return
+			}
+			for _, user := range page.Users {
+				if !yield(user, nil) {
+					return
+				}
+			}
+			if len(page.Users) == 0 || pageNum >= page.Pagination.TotalPages {
+				return
+			}
+		}
+	}
+}
+
+// GetUser returns the user with the given ID
+func (c *Client) GetUser(ctx context.Context, id int) (user *User, err error) {
+	ctx, span := c.startSpan(ctx, "GetUser", attribute.Int("user.id", id))
+	defer func() { endSpan(span, err) }()
+
+	var resp dataEnvelope[User]
+	if err = c.do(ctx, http.MethodGet, "/api/users/"+strconv.Itoa(id), nil, nil, &resp); err != nil {
+		return nil, err
+	}
+	return &resp.Data, nil
+}
+
+// CreateUser creates a user. Creation is not idempotent and is never retried.
+func (c *Client) CreateUser(ctx context.Context, req CreateUserRequest) (user *User, err error) {
+	ctx, span := c.startSpan(ctx, "CreateUser")
+	defer func() { endSpan(span, err) }()
+
+	var resp dataEnvelope[User]
+	if err = c.do(ctx, http.MethodPost, "/api/users", nil, req, &resp); err != nil {
+		return nil, err
+	}
+	span.SetAttributes(attribute.Int("user.id", resp.Data.ID))
+	return &resp.Data, nil
+}
+
+// UpdateUser updates the fields set in req
+func (c *Client) UpdateUser(ctx context.Context, id int, req UpdateUserRequest) (user *User, err error) {
+	ctx, span := c.startSpan(ctx, "UpdateUser", attribute.Int("user.id", id))
+	defer func() { endSpan(span, err) }()
+
+	var resp dataEnvelope[User]
+	if err = c.do(ctx, http.MethodPut, "/api/users/"+strconv.Itoa(id), nil, req, &resp); err != nil {
+		return nil, err
+	}
+	return &resp.Data, nil
+}
+
+// DeleteUser deletes the user with the given ID
+func (c *Client) DeleteUser(ctx context.Context, id int) (err error) {
+	ctx, span := c.startSpan(ctx, "DeleteUser", attribute.Int("user.id", id))
+	defer func() { endSpan(span, err) }()
+
+	return c.do(ctx, http.MethodDelete, "/api/users/"+strconv.Itoa(id), nil, nil, nil)
+}
+
+// Health calls GET /health and returns an error unless the service is healthy
+func (c *Client) Health(ctx context.Context) (err error) {
+	ctx, span := c.startSpan(ctx, "Health")
+	defer func() { endSpan(span, err) }()
+
+	return c.do(ctx, http.MethodGet, "/health", nil, nil, nil)
+}
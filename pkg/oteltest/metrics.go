@@ -0,0 +1,132 @@
+package oteltest
+
+import (
+	"testing"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/sdk/metric/metricdata"
+)
+
+// Metric returns the metric named name among those recorded so far
+func (r *Recorder) Metric(t testing.TB, name string) (metricdata.Metrics, bool) {
+	t.Helper()
+	for _, scope := range r.Collect(t).ScopeMetrics {
+		for _, m := range scope.Metrics {
+			if m.Name == name {
+				return m, true
+			}
+		}
+	}
+	return metricdata.Metrics{}, false
+}
+
+// CounterValue returns the total of the counter or up-down counter name over
+// the data points that have each of attrs, so no attrs totals every data
+// point. It fails t when name is not a counter
+func (r *Recorder) CounterValue(t testing.TB, name string, attrs ...attribute.KeyValue) float64 {
+	t.Helper()
+	m, ok := r.Metric(t, name)
+	if !ok {
+		return 0
+	}
+	var total float64
+	switch data := m.Data.(type) {
+	case metricdata.Sum[int64]:
+		for _, dp := range data.DataPoints {
+			if hasAttributes(dp.Attributes, attrs) {
+				total += float64(dp.Value)
+			}
+		}
+	case metricdata.Sum[float64]:
+		for _, dp := range data.DataPoints {
+			if hasAttributes(dp.Attributes, attrs) {
+				total += dp.Value
+			}
+		}
+	default:
+		t.Errorf("metric %q is a %T, not a counter", name, m.Data)
+	}
+	return total
+}
+
+// HistogramCount returns the number of measurements of the histogram name
+// over the data points that have each of attrs. It fails t when name is not
+// a histogram
+func (r *Recorder) HistogramCount(t testing.TB, name string, attrs ...attribute.KeyValue) uint64 {
+	t.Helper()
+	m, ok := r.Metric(t, name)
+	if !ok {
+		return 0
+	}
+	var count uint64
+	switch data := m.Data.(type) {
+	case metricdata.Histogram[int64]:
+		for _, dp := range data.DataPoints {
+			if hasAttributes(dp.Attributes, attrs) {
+				count += dp.Count
+			}
+		}
+	case metricdata.Histogram[float64]:
+		for _, dp := range data.DataPoints {
+			if hasAttributes(dp.Attributes, attrs) {
+				count += dp.Count
+			}
+		}
+	default:
+		t.Errorf("metric %q is a %T, not a histogram", name, m.Data)
+	}
+	return count
+}
+
+// AssertCounterValue fails t unless the counter name totals want over the
+// data points that have each of attrs, see CounterValue
+func (r *Recorder) AssertCounterValue(t testing.TB, name string, attrs []attribute.KeyValue, want float64) {
+	t.Helper()
+	if _, ok := r.Metric(t, name); !ok {
+		t.Errorf("no metric named %q was recorded", name)
+		return
+	}
+	if got := r.CounterValue(t, name, attrs...); got != want {
+		t.Errorf("counter %q%s = %v, want %v", name, formatAttributes(attrs), got, want)
+	}
+}
+
+// AssertHistogramCount fails t unless the histogram name has want
+// measurements over the data points that have each of attrs
+func (r *Recorder) AssertHistogramCount(t testing.TB, name string, attrs []attribute.KeyValue, want uint64) {
+	t.Helper()
+	if _, ok := r.Metric(t, name); !ok {
+		t.Errorf("no metric named %q was recorded", name)
+		return
+	}
+	if got := r.HistogramCount(t, name, attrs...); got != want {
+		t.Errorf("histogram %q%s has %d measurements, want %d", name, formatAttributes(attrs), got, want)
+	}
+}
+
+// AssertNoMetric fails t if the metric name was recorded
+func (r *Recorder) AssertNoMetric(t testing.TB, name string) {
+	t.Helper()
+	if _, ok := r.Metric(t, name); ok {
+		t.Errorf("unexpected metric %q", name)
+	}
+}
+
+// hasAttributes reports whether set has each of attrs with the same value
+func hasAttributes(set attribute.Set, attrs []attribute.KeyValue) bool {
+	for _, want := range attrs {
+		if got, ok := set.Value(want.Key); !ok || got != want.Value {
+			return false
+		}
+	}
+	return true
+}
+
+// formatAttributes formats attrs for failure messages
+func formatAttributes(attrs []attribute.KeyValue) string {
+	if len(attrs) == 0 {
+		return ""
+	}
+	set := attribute.NewSet(attrs...)
+	return "{" + set.Encoded(attribute.DefaultEncoder()) + "}"
+}
@@ -0,0 +1,46 @@
+package oteltest
+
+import (
+	"context"
+	"testing"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+)
+
+func TestMetricAssertions(t *testing.T) {
+	r := Install(t)
+	ctx := context.Background()
+	meter := otel.Meter("test")
+
+	requests, _ := meter.Int64Counter("requests")
+	requests.Add(ctx, 2, metric.WithAttributes(attribute.String("method", "GET"), attribute.String("route", "/users")))
+	requests.Add(ctx, 1, metric.WithAttributes(attribute.String("method", "POST"), attribute.String("route", "/users")))
+	active, _ := meter.Int64UpDownCounter("active")
+	active.Add(ctx, 1)
+	active.Add(ctx, -1)
+	duration, _ := meter.Float64Histogram("duration")
+	duration.Record(ctx, 0.5, metric.WithAttributes(attribute.String("method", "GET")))
+	duration.Record(ctx, 1.5, metric.WithAttributes(attribute.String("method", "GET")))
+
+	r.AssertCounterValue(t, "requests", nil, 3)
+	r.AssertCounterValue(t, "requests", []attribute.KeyValue{attribute.String("method", "GET")}, 2)
+	r.AssertCounterValue(t, "requests", []attribute.KeyValue{attribute.String("method", "DELETE")}, 0)
+	r.AssertCounterValue(t, "active", nil, 0)
+	r.AssertHistogramCount(t, "duration", []attribute.KeyValue{attribute.String("method", "GET")}, 2)
+	r.AssertNoMetric(t, "missing")
+
+	f := &fakeT{TB: t}
+	r.AssertCounterValue(f, "requests", []attribute.KeyValue{attribute.String("method", "GET")}, 5)
+	r.AssertCounterValue(f, "missing", nil, 1)
+	r.AssertCounterValue(f, "duration", nil, 1)
+	r.AssertHistogramCount(f, "requests", nil, 1)
+	r.AssertNoMetric(f, "requests")
+	if len(f.errors) != 7 {
+		t.Fatalf("expected 7 failures, got %d: %q", len(f.errors), f.errors)
+	}
+	if want := `counter "requests"{method=GET} = 2, want 5`; f.errors[0] != want {
+		t.Errorf("got %q, want %q", f.errors[0], want)
+	}
+}
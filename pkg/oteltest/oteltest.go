@@ -0,0 +1,165 @@
+// Package oteltest installs in-memory OpenTelemetry providers for tests and
+// asserts on what they record, so tests can check the instrumentation of the
+// code under test and not only its results.
+package oteltest
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+	"testing"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/metric/metricdata"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+// Recorder records the spans and metrics of a test
+type Recorder struct {
+	TracerProvider *sdktrace.TracerProvider
+	MeterProvider  *sdkmetric.MeterProvider
+
+	spans  *tracetest.SpanRecorder
+	reader *sdkmetric.ManualReader
+}
+
+// New returns a Recorder without installing its providers, for code that
+// takes them as parameters
+func New() *Recorder {
+	spans := tracetest.NewSpanRecorder()
+	reader := sdkmetric.NewManualReader()
+	return &Recorder{
+		TracerProvider: sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(spans)),
+		MeterProvider:  sdkmetric.NewMeterProvider(sdkmetric.WithReader(reader)),
+		spans:          spans,
+		reader:         reader,
+	}
+}
+
+// Install makes a new Recorder's providers the global tracer and meter
+// providers until the end of t. Instruments created before Install keep
+// recording to the previous meter provider, and tests using Install must
+// not run in parallel
+func Install(t testing.TB) *Recorder {
+	t.Helper()
+	r := New()
+	prevTP, prevMP := otel.GetTracerProvider(), otel.GetMeterProvider()
+	otel.SetTracerProvider(r.TracerProvider)
+	otel.SetMeterProvider(r.MeterProvider)
+	t.Cleanup(func() {
+		otel.SetTracerProvider(prevTP)
+		otel.SetMeterProvider(prevMP)
+		_ = r.TracerProvider.Shutdown(context.Background())
+		_ = r.MeterProvider.Shutdown(context.Background())
+	})
+	return r
+}
+
+// Spans returns the ended spans in the order they ended
+func (r *Recorder) Spans() []sdktrace.ReadOnlySpan {
+	return r.spans.Ended()
+}
+
+// Span returns the last ended span named name
+func (r *Recorder) Span(name string) (sdktrace.ReadOnlySpan, bool) {
+	spans := r.Spans()
+	for i := len(spans) - 1; i >= 0; i-- {
+		if spans[i].Name() == name {
+			return spans[i], true
+		}
+	}
+	return nil, false
+}
+
+// Collect returns the metrics recorded so far
+func (r *Recorder) Collect(t testing.TB) metricdata.ResourceMetrics {
+	t.Helper()
+	var rm metricdata.ResourceMetrics
+	if err := r.reader.Collect(context.Background(), &rm); err != nil {
+		t.Fatalf("failed to collect metrics: %v", err)
+	}
+	return rm
+}
+
+// AssertSpan fails t unless a span named name has ended, and checks that the
+// last one has each of attrs. It returns that span, or nil
+func (r *Recorder) AssertSpan(t testing.TB, name string, attrs ...attribute.KeyValue) sdktrace.ReadOnlySpan {
+	t.Helper()
+	span, ok := r.Span(name)
+	if !ok {
+		t.Errorf("no span named %q ended; got %s", name, r.spanNames())
+		return nil
+	}
+	AssertAttributes(t, span, attrs...)
+	return span
+}
+
+// AssertNoSpan fails t if a span named name has ended
+func (r *Recorder) AssertNoSpan(t testing.TB, name string) {
+	t.Helper()
+	if _, ok := r.Span(name); ok {
+		t.Errorf("unexpected span %q", name)
+	}
+}
+
+// AssertChild fails t unless the last spans named child and parent have
+// ended and the first is a direct child of the second
+func (r *Recorder) AssertChild(t testing.TB, child, parent string) {
+	t.Helper()
+	c, ok := r.Span(child)
+	if !ok {
+		t.Errorf("no span named %q ended; got %s", child, r.spanNames())
+		return
+	}
+	p, ok := r.Span(parent)
+	if !ok {
+		t.Errorf("no span named %q ended; got %s", parent, r.spanNames())
+		return
+	}
+	AssertChildOf(t, c, p)
+}
+
+// AssertAttributes fails t unless span has each of attrs with the same value
+func AssertAttributes(t testing.TB, span sdktrace.ReadOnlySpan, attrs ...attribute.KeyValue) {
+	t.Helper()
+	set := attribute.NewSet(span.Attributes()...)
+	for _, want := range attrs {
+		got, ok := set.Value(want.Key)
+		switch {
+		case !ok:
+			t.Errorf("span %q has no attribute %s", span.Name(), want.Key)
+		case got != want.Value:
+			t.Errorf("span %q has %s=%s, want %s", span.Name(), want.Key, got.Emit(), want.Value.Emit())
+		}
+	}
+}
+
+// AssertChildOf fails t unless child is a direct child of parent in the same
+// trace
+func AssertChildOf(t testing.TB, child, parent sdktrace.ReadOnlySpan) {
+	t.Helper()
+	if child.SpanContext().TraceID() != parent.SpanContext().TraceID() {
+		t.Errorf("span %q is not in the trace of %q", child.Name(), parent.Name())
+		return
+	}
+	if child.Parent().SpanID() != parent.SpanContext().SpanID() {
+		t.Errorf("span %q is not a child of %q", child.Name(), parent.Name())
+	}
+}
+
+// spanNames lists the names of the ended spans for failure messages
+func (r *Recorder) spanNames() string {
+	var names []string
+	for _, span := range r.Spans() {
+		names = append(names, span.Name())
+	}
+	if len(names) == 0 {
+		return "none"
+	}
+	slices.Sort(names)
+	return fmt.Sprintf("[%s]", strings.Join(slices.Compact(names), ", "))
+}
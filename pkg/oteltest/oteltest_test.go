@@ -0,0 +1,66 @@
+package oteltest
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+)
+
+// fakeT records the failures of the assertions under test
+type fakeT struct {
+	testing.TB
+	errors []string
+}
+
+func (f *fakeT) Helper() {}
+
+func (f *fakeT) Errorf(format string, args ...any) {
+	f.errors = append(f.errors, fmt.Sprintf(format, args...))
+}
+
+func TestRecorderAssertions(t *testing.T) {
+	r := Install(t)
+
+	ctx, parent := otel.Tracer("test").Start(context.Background(), "parent")
+	_, child := otel.Tracer("test").Start(ctx, "child")
+	child.SetAttributes(attribute.String("db.table", "users"), attribute.Int("result.count", 2))
+	child.End()
+	parent.End()
+	_, other := otel.Tracer("test").Start(context.Background(), "other")
+	other.End()
+
+	r.AssertSpan(t, "child", attribute.String("db.table", "users"), attribute.Int("result.count", 2))
+	r.AssertChild(t, "child", "parent")
+	r.AssertNoSpan(t, "missing")
+
+	f := &fakeT{TB: t}
+	r.AssertSpan(f, "missing")
+	r.AssertSpan(f, "child", attribute.String("db.table", "posts"), attribute.Bool("found", true))
+	r.AssertChild(f, "other", "parent")
+	r.AssertChild(f, "parent", "child")
+	r.AssertNoSpan(f, "child")
+	if len(f.errors) != 6 {
+		t.Fatalf("expected 6 failures, got %d: %q", len(f.errors), f.errors)
+	}
+	if want := `no span named "missing" ended; got [child, other, parent]`; f.errors[0] != want {
+		t.Errorf("got %q, want %q", f.errors[0], want)
+	}
+}
+
+func TestRecorderCollectsMetrics(t *testing.T) {
+	r := Install(t)
+
+	counter, err := otel.Meter("test").Int64Counter("jobs")
+	if err != nil {
+		t.Fatal(err)
+	}
+	counter.Add(context.Background(), 3)
+
+	rm := r.Collect(t)
+	if len(rm.ScopeMetrics) != 1 || rm.ScopeMetrics[0].Metrics[0].Name != "jobs" {
+		t.Fatalf("expected the jobs metric, got %+v", rm.ScopeMetrics)
+	}
+}
@@ -0,0 +1,27 @@
+package utils
+
+// MergePatchContentType is the media type of RFC 7386 JSON merge patches
+const MergePatchContentType = "application/merge-patch+json"
+
+// MergePatch applies an RFC 7386 JSON merge patch to target. Both are
+// decoded JSON values: objects are merged member by member, a null member
+// removes it from the target and any other patch value replaces the target.
+// Nested target objects are modified in place.
+func MergePatch(target, patch any) any {
+	patchObject, ok := patch.(map[string]any)
+	if !ok {
+		return patch
+	}
+	targetObject, ok := target.(map[string]any)
+	if !ok {
+		targetObject = map[string]any{}
+	}
+	for name, value := range patchObject {
+		if value == nil {
+			delete(targetObject, name)
+			continue
+		}
+		targetObject[name] = MergePatch(targetObject[name], value)
+	}
+	return targetObject
+}
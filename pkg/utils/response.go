@@ -1,44 +1,157 @@
 package utils
 
 import (
+	"context"
 	"net/http"
 
 	"arquivolivre.com.br/otel/internal/models"
 
 	"github.com/gin-gonic/gin"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
 )
 
+const (
+	// RequestIDContextKey is the gin context key holding the request ID
+	RequestIDContextKey = "request_id"
+	// RequestIDHeader carries the request ID in requests and responses
+	RequestIDHeader = "X-Request-ID"
+	// TraceIDHeader exposes the trace ID of the request to clients
+	TraceIDHeader = "X-Trace-ID"
+)
+
+// TraceID returns the trace ID of the active span, if any
+func TraceID(c *gin.Context) string {
+	if spanCtx := trace.SpanContextFromContext(c.Request.Context()); spanCtx.HasTraceID() {
+		return spanCtx.TraceID().String()
+	}
+	return ""
+}
+
+// RequestID returns the request ID assigned by the RequestID middleware, if any
+func RequestID(c *gin.Context) string {
+	return c.GetString(RequestIDContextKey)
+}
+
+type requestIDKey struct{}
+
+// ContextWithRequestID returns a copy of ctx carrying requestID, so code that
+// only sees the request context can log and forward it
+func ContextWithRequestID(ctx context.Context, requestID string) context.Context {
+	return context.WithValue(ctx, requestIDKey{}, requestID)
+}
+
+// RequestIDFromContext returns the request ID stored in ctx, if any
+func RequestIDFromContext(ctx context.Context) string {
+	requestID, _ := ctx.Value(requestIDKey{}).(string)
+	return requestID
+}
+
+// SendJSON writes body with the standard headers and records the response
+// outcome on the active span. All Send* helpers go through it.
+func SendJSON(c *gin.Context, statusCode int, body interface{}) {
+	setStandardHeaders(c)
+	recordOutcome(c, statusCode, "")
+	c.JSON(statusCode, body)
+}
+
+// SendNotModified writes an empty 304 response; the handler sets the ETag
+// the client's copy still matches
+func SendNotModified(c *gin.Context) {
+	setStandardHeaders(c)
+	recordOutcome(c, http.StatusNotModified, "")
+	c.Status(http.StatusNotModified)
+}
+
+// StartStream writes the standard headers of a 200 response whose body the
+// caller then streams as contentType, and records the outcome on the span
+func StartStream(c *gin.Context, contentType string) {
+	setStandardHeaders(c)
+	recordOutcome(c, http.StatusOK, "")
+	c.Header("Content-Type", contentType)
+	c.Status(http.StatusOK)
+}
+
+// SendNoContent writes an empty 204 response
+func SendNoContent(c *gin.Context) {
+	setStandardHeaders(c)
+	recordOutcome(c, http.StatusNoContent, "")
+	c.Status(http.StatusNoContent)
+}
+
 func SendSuccess(c *gin.Context, data interface{}, message ...string) {
+	sendSuccess(c, http.StatusOK, data, message)
+}
+
+func SendCreated(c *gin.Context, data interface{}, message ...string) {
+	sendSuccess(c, http.StatusCreated, data, message)
+}
+
+// SendAccepted writes a 202 response for a request whose work goes on in
+// the background, described by data
+func SendAccepted(c *gin.Context, data interface{}, message ...string) {
+	sendSuccess(c, http.StatusAccepted, data, message)
+}
+
+// SendMultiStatus writes a 207 response for a request whose items had
+// different outcomes, each reported in data
+func SendMultiStatus(c *gin.Context, data interface{}, message ...string) {
+	sendSuccess(c, http.StatusMultiStatus, data, message)
+}
+
+func sendSuccess(c *gin.Context, statusCode int, data interface{}, message []string) {
 	response := models.SuccessResponse{
-		Success: true,
-		Data:    data,
+		Success:   true,
+		Data:      data,
+		TraceID:   TraceID(c),
+		RequestID: RequestID(c),
 	}
 
 	if len(message) > 0 {
 		response.Message = message[0]
 	}
 
-	c.JSON(http.StatusOK, response)
+	SendJSON(c, statusCode, response)
 }
 
-func SendCreated(c *gin.Context, data interface{}, message ...string) {
-	response := models.SuccessResponse{
-		Success: true,
-		Data:    data,
+// SendPaginated writes a typed paginated response with computed page metadata
+func SendPaginated[T any](c *gin.Context, data []T, page, limit, total int) {
+	response := models.NewPaginatedResponse(data, page, limit, total)
+	response.TraceID = TraceID(c)
+	response.RequestID = RequestID(c)
+	SendJSON(c, http.StatusOK, response)
+}
+
+// MapSlice converts every item with fn, e.g. entities to response DTOs
+func MapSlice[S, T any](items []S, fn func(S) T) []T {
+	out := make([]T, len(items))
+	for i, item := range items {
+		out[i] = fn(item)
 	}
+	return out
+}
 
-	if len(message) > 0 {
-		response.Message = message[0]
+// SendErrorResponse writes response, filling in the trace and request IDs
+// and, when it has none, the error code of statusCode
+func SendErrorResponse(c *gin.Context, statusCode int, response models.ErrorResponse) {
+	response.Success = false
+	if response.Code == "" {
+		response.Code = errorCode(statusCode)
+	}
+	if response.TraceID == "" {
+		response.TraceID = TraceID(c)
+	}
+	if response.RequestID == "" {
+		response.RequestID = RequestID(c)
 	}
 
-	c.JSON(http.StatusCreated, response)
+	setStandardHeaders(c)
+	recordOutcome(c, statusCode, response.Code)
+	c.JSON(statusCode, response)
 }
 
 func SendError(c *gin.Context, statusCode int, message string) {
-	c.JSON(statusCode, models.ErrorResponse{
-		Success: false,
-		Error:   message,
-	})
+	SendErrorResponse(c, statusCode, models.ErrorResponse{Error: message})
 }
 
 func SendBadRequest(c *gin.Context, message string) {
@@ -56,3 +169,67 @@ func SendInternalError(c *gin.Context, message string) {
 func SendConflict(c *gin.Context, message string) {
 	SendError(c, http.StatusConflict, message)
 }
+
+// errorCode returns the error code of responses with statusCode that do not
+// set one of their own
+func errorCode(statusCode int) string {
+	switch statusCode {
+	case http.StatusBadRequest:
+		return models.ErrCodeInvalidRequest
+	case http.StatusUnauthorized:
+		return models.ErrCodeUnauthorized
+	case http.StatusForbidden:
+		return models.ErrCodeForbidden
+	case http.StatusNotFound:
+		return models.ErrCodeNotFound
+	case http.StatusConflict:
+		return models.ErrCodeConflict
+	case http.StatusTooManyRequests:
+		return models.ErrCodeRateLimited
+	case http.StatusServiceUnavailable:
+		return models.ErrCodeServiceUnavailable
+	default:
+		if statusCode >= http.StatusInternalServerError {
+			return models.ErrCodeInternal
+		}
+		return ""
+	}
+}
+
+// setStandardHeaders sets the headers of every response. Responses are not
+// cached unless the handler already set a Cache-Control of its own
+func setStandardHeaders(c *gin.Context) {
+	if c.Writer.Header().Get("Cache-Control") == "" {
+		c.Header("Cache-Control", "no-store")
+	}
+	c.Header("X-Content-Type-Options", "nosniff")
+	if requestID := RequestID(c); requestID != "" {
+		c.Header(RequestIDHeader, requestID)
+	}
+	if traceID := TraceID(c); traceID != "" {
+		c.Header(TraceIDHeader, traceID)
+	}
+}
+
+// recordOutcome adds a "response.sent" event describing the result to the
+// active span
+func recordOutcome(c *gin.Context, statusCode int, errorCode string) {
+	span := trace.SpanFromContext(c.Request.Context())
+	if !span.IsRecording() {
+		return
+	}
+
+	outcome := "success"
+	if statusCode >= http.StatusBadRequest {
+		outcome = "error"
+	}
+
+	attrs := []attribute.KeyValue{
+		attribute.Int("http.response.status_code", statusCode),
+		attribute.String("response.outcome", outcome),
+	}
+	if errorCode != "" {
+		attrs = append(attrs, attribute.String("error.code", errorCode))
+	}
+	span.AddEvent("response.sent", trace.WithAttributes(attrs...))
+}
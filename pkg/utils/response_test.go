@@ -1,13 +1,21 @@
 package utils
 
 import (
+	"context"
 	"encoding/json"
 	"net/http"
 	"net/http/httptest"
+	"strconv"
 	"testing"
 
+	"arquivolivre.com.br/otel/internal/models"
+
 	"github.com/gin-gonic/gin"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/attribute"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
 )
 
 func TestSendHelpers(t *testing.T) {
@@ -15,21 +23,24 @@ func TestSendHelpers(t *testing.T) {
 	r := gin.New()
 	r.GET("/success", func(c *gin.Context) { SendSuccess(c, gin.H{"x": 1}, "ok") })
 	r.GET("/created", func(c *gin.Context) { SendCreated(c, gin.H{"x": 2}, "created") })
+	r.GET("/accepted", func(c *gin.Context) { SendAccepted(c, gin.H{"x": 3}, "accepted") })
 	r.GET("/bad", func(c *gin.Context) { SendBadRequest(c, "bad") })
 	r.GET("/notfound", func(c *gin.Context) { SendNotFound(c, "nf") })
 	r.GET("/conflict", func(c *gin.Context) { SendConflict(c, "cf") })
 	r.GET("/internal", func(c *gin.Context) { SendInternalError(c, "ie") })
 
 	cases := []struct {
-		path string
-		code int
+		path      string
+		code      int
+		errorCode string
 	}{
-		{"/success", http.StatusOK},
-		{"/created", http.StatusCreated},
-		{"/bad", http.StatusBadRequest},
-		{"/notfound", http.StatusNotFound},
-		{"/conflict", http.StatusConflict},
-		{"/internal", http.StatusInternalServerError},
+		{"/success", http.StatusOK, ""},
+		{"/created", http.StatusCreated, ""},
+		{"/accepted", http.StatusAccepted, ""},
+		{"/bad", http.StatusBadRequest, models.ErrCodeInvalidRequest},
+		{"/notfound", http.StatusNotFound, models.ErrCodeNotFound},
+		{"/conflict", http.StatusConflict, models.ErrCodeConflict},
+		{"/internal", http.StatusInternalServerError, models.ErrCodeInternal},
 	}
 	for _, cs := range cases {
 		w := httptest.NewRecorder()
@@ -39,5 +50,88 @@ func TestSendHelpers(t *testing.T) {
 		var m map[string]interface{}
 		_ = json.Unmarshal(w.Body.Bytes(), &m)
 		assert.Contains(t, m, "success")
+		if cs.errorCode != "" {
+			assert.Equal(t, cs.errorCode, m["code"])
+		}
+	}
+}
+
+func TestSendPaginated(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.GET("/items", func(c *gin.Context) {
+		SendPaginated(c, MapSlice([]int{1, 2}, strconv.Itoa), 2, 2, 5)
+	})
+	r.GET("/empty", func(c *gin.Context) { SendPaginated[string](c, nil, 1, 10, 0) })
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/items", nil))
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	var resp models.PaginatedResponse[string]
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	assert.True(t, resp.Success)
+	assert.Equal(t, []string{"1", "2"}, resp.Data)
+	assert.Equal(t, models.Pagination{Page: 2, Limit: 2, Total: 5, TotalPages: 3}, resp.Pagination)
+
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/empty", nil))
+	assert.JSONEq(t, `{"success":true,"data":[],"pagination":{"page":1,"limit":10,"total":0,"total_pages":0}}`, w.Body.String())
+}
+
+func TestSendHelpers_TraceAware(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	recorder := tracetest.NewSpanRecorder()
+	tp := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+	defer func() { _ = tp.Shutdown(context.Background()) }()
+
+	r := gin.New()
+	r.Use(func(c *gin.Context) {
+		ctx, span := tp.Tracer("test").Start(c.Request.Context(), c.Request.URL.Path)
+		defer span.End()
+		c.Request = c.Request.WithContext(ctx)
+		c.Set(RequestIDContextKey, "req-1")
+		c.Next()
+	})
+	r.GET("/ok", func(c *gin.Context) { SendSuccess(c, gin.H{"x": 1}) })
+	r.GET("/fail", func(c *gin.Context) {
+		SendErrorResponse(c, http.StatusConflict, models.ErrorResponse{Error: "taken", Code: models.ErrCodeConflict})
+	})
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/ok", nil))
+
+	var ok models.SuccessResponse
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &ok))
+	assert.Equal(t, "req-1", ok.RequestID)
+	assert.Len(t, ok.TraceID, 32)
+	assert.Equal(t, ok.TraceID, w.Header().Get(TraceIDHeader))
+	assert.Equal(t, "req-1", w.Header().Get(RequestIDHeader))
+	assert.Equal(t, "no-store", w.Header().Get("Cache-Control"))
+	assert.Equal(t, "nosniff", w.Header().Get("X-Content-Type-Options"))
+
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/fail", nil))
+
+	var failed models.ErrorResponse
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &failed))
+	assert.False(t, failed.Success)
+	assert.Equal(t, "req-1", failed.RequestID)
+	assert.NotEmpty(t, failed.TraceID)
+
+	spans := recorder.Ended()
+	require.Len(t, spans, 2)
+	for i, want := range []struct {
+		outcome string
+		status  int64
+	}{{"success", http.StatusOK}, {"error", http.StatusConflict}} {
+		events := spans[i].Events()
+		require.Len(t, events, 1)
+		assert.Equal(t, "response.sent", events[0].Name)
+		attrs := attribute.NewSet(events[0].Attributes...)
+		outcome, _ := attrs.Value("response.outcome")
+		status, _ := attrs.Value("http.response.status_code")
+		assert.Equal(t, want.outcome, outcome.AsString())
+		assert.Equal(t, want.status, status.AsInt64())
 	}
 }